@@ -3,10 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/core"
 	"go.olrik.dev/overseer/internal/db"
 )
 
@@ -35,14 +35,7 @@ func NewBackfillCommand() *cobra.Command {
 }
 
 func runBackfillSleep() {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to get home directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	dbPath := filepath.Join(homeDir, ".config", "overseer", "overseer.db")
-	database, err := db.Open(dbPath)
+	database, err := db.Open(core.GetDatabasePath())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
 		os.Exit(1)
@@ -194,14 +187,7 @@ func loadEvents(database *db.DB) []db.SensorChange {
 }
 
 func undoBackfillSleep() {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: failed to get home directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	dbPath := filepath.Join(homeDir, ".config", "overseer", "overseer.db")
-	database, err := db.Open(dbPath)
+	database, err := db.Open(core.GetDatabasePath())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
 		os.Exit(1)
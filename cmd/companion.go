@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -103,10 +105,13 @@ func NewCompanionCommand() *cobra.Command {
 	companionCmd.AddCommand(
 		newCompanionListCommand(),
 		newCompanionAttachCommand(),
+		newCompanionGrepCommand(),
+		newCompanionTailCommand(),
 		newCompanionStartCommand(),
 		newCompanionStopCommand(),
 		newCompanionRestartCommand(),
 		newCompanionStatusCommand(),
+		newCompanionRunCommand(),
 	)
 
 	return companionCmd
@@ -231,6 +236,17 @@ Press Ctrl+C to detach.`,
 			tunnel, _ := cmd.Flags().GetString("tunnel")
 			name, _ := cmd.Flags().GetString("name")
 			lines, _ := cmd.Flags().GetInt("lines")
+			noTimestamps, _ := cmd.Flags().GetBool("no-timestamps")
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			// Keep stdout pure JSON lines by sending our own notices to stderr
+			notice := func(msg string) {
+				if jsonOutput {
+					fmt.Fprint(os.Stderr, formatDaemonMessage(msg))
+					return
+				}
+				fmt.Print(formatDaemonMessage(msg))
+			}
 
 			daemon.EnsureDaemonIsRunning()
 
@@ -253,7 +269,10 @@ Press Ctrl+C to detach.`,
 				if isReconnect {
 					command = fmt.Sprintf("COMPANION_ATTACH %s %s 0 no_history\n", tunnel, name)
 				} else {
-					command = fmt.Sprintf("COMPANION_ATTACH %s %s %d\n", tunnel, name, lines)
+					command = fmt.Sprintf("COMPANION_ATTACH %s %s %s\n", tunnel, name, attachHistoryArg(lines))
+				}
+				if jsonOutput {
+					command = strings.TrimSuffix(command, "\n") + " json\n"
 				}
 				if _, err := conn.Write([]byte(command)); err != nil {
 					conn.Close()
@@ -279,6 +298,15 @@ Press Ctrl+C to detach.`,
 							return
 						}
 						lastMessage = line
+						if jsonOutput {
+							fmt.Print(line)
+							continue
+						}
+						if noTimestamps {
+							// Raw output: drop the wrapper's timestamp and stream tag
+							fmt.Print(daemon.StripOutputPrefix(line))
+							continue
+						}
 						if colored := colorizeCompanionOutput(line); colored != "" {
 							fmt.Print(colored)
 						}
@@ -289,7 +317,7 @@ Press Ctrl+C to detach.`,
 				select {
 				case <-sigChan:
 					conn.Close()
-					fmt.Print(formatDaemonMessage("Detached from companion."))
+					notice("Detached from companion.")
 					return
 				case <-done:
 					conn.Close()
@@ -299,25 +327,25 @@ Press Ctrl+C to detach.`,
 						// Query tunnel status to determine if we should retry
 						response, err := daemon.SendCommand("STATUS")
 						if err != nil {
-							fmt.Print(formatDaemonMessage("Daemon not available. Exiting."))
+							notice("Daemon not available. Exiting.")
 							return
 						}
 
 						// Check if tunnel exists and is reconnecting
 						tunnelExists, isReconnecting := checkTunnelReconnecting(response, tunnel)
 						if !tunnelExists {
-							fmt.Print(formatDaemonMessage("Tunnel was closed. Exiting."))
+							notice("Tunnel was closed. Exiting.")
 							return
 						}
 						if !isReconnecting {
 							// Tunnel exists but not reconnecting (connected state = config issue)
-							fmt.Print(formatDaemonMessage("Tunnel is connected but companion not found. Exiting."))
+							notice("Tunnel is connected but companion not found. Exiting.")
 							return
 						}
 						// Tunnel is reconnecting - continue to retry
-						fmt.Print(formatDaemonMessage("Tunnel reconnecting. Waiting..."))
+						notice("Tunnel reconnecting. Waiting...")
 					} else {
-						fmt.Print(formatDaemonMessage("Connection lost. Reconnecting..."))
+						notice("Connection lost. Reconnecting...")
 					}
 
 					time.Sleep(500 * time.Millisecond)
@@ -333,7 +361,7 @@ Press Ctrl+C to detach.`,
 					}
 
 					if !reconnected {
-						fmt.Print(formatDaemonMessage("Daemon not available. Exiting."))
+						notice("Daemon not available. Exiting.")
 						return
 					}
 					// Continue loop to reconnect
@@ -344,7 +372,9 @@ Press Ctrl+C to detach.`,
 
 	cmd.Flags().StringP("tunnel", "T", "", "Tunnel alias")
 	cmd.Flags().StringP("name", "N", "", "Companion name")
-	cmd.Flags().IntP("lines", "L", 20, "Number of history lines to show on attach")
+	cmd.Flags().IntP("lines", "L", 20, "Number of history lines to show on attach (-1 for all retained history)")
+	cmd.Flags().Bool("no-timestamps", false, "Print raw output without timestamp and stream prefix")
+	cmd.Flags().Bool("json", false, "Print each output line as a JSON object with ts, stream and line fields")
 	cmd.MarkFlagRequired("tunnel")
 	cmd.MarkFlagRequired("name")
 	cmd.RegisterFlagCompletionFunc("tunnel", tunnelCompletionFunc)
@@ -353,6 +383,129 @@ Press Ctrl+C to detach.`,
 	return cmd
 }
 
+func newCompanionGrepCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grep <tunnel> <name> <pattern>",
+		Short: "Search a companion script's retained output",
+		Long: `Print the lines of a companion script's retained output history that match
+a regular expression. The pattern is matched against the output text only,
+not the timestamp and stream prefix.`,
+		Args:              cobra.ExactArgs(3),
+		ValidArgsFunction: companionArgsCompletionFunc,
+		Run: func(cmd *cobra.Command, args []string) {
+			ignoreCase, _ := cmd.Flags().GetBool("ignore-case")
+			noTimestamps, _ := cmd.Flags().GetBool("no-timestamps")
+
+			pattern := args[2]
+			if ignoreCase {
+				pattern = "(?i)" + pattern
+			}
+
+			daemon.EnsureDaemonIsRunning()
+			daemon.CheckVersionMismatch()
+
+			response, err := daemon.SendCommand(fmt.Sprintf("COMPANION_GREP %s %s %s", args[0], args[1], pattern))
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+
+			lines, err := decodeCompanionLines(response)
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(2)
+			}
+			if len(lines) == 0 {
+				// Exit like grep: 1 for no match, 2 for errors
+				os.Exit(1)
+			}
+
+			printCompanionLines(lines, noTimestamps)
+		},
+	}
+
+	cmd.Flags().BoolP("ignore-case", "i", false, "Match case-insensitively")
+	cmd.Flags().Bool("no-timestamps", false, "Print raw output without timestamp and stream prefix")
+
+	return cmd
+}
+
+func newCompanionTailCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tail <tunnel> <name>",
+		Short: "Print the most recent output of a companion script",
+		Long: `Print the last lines of a companion script's retained output history and
+exit. Unlike attach, this doesn't follow the live output, which makes it
+suitable for scripts.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: companionArgsCompletionFunc,
+		Run: func(cmd *cobra.Command, args []string) {
+			lines, _ := cmd.Flags().GetInt("lines")
+			noTimestamps, _ := cmd.Flags().GetBool("no-timestamps")
+
+			daemon.EnsureDaemonIsRunning()
+			daemon.CheckVersionMismatch()
+
+			response, err := daemon.SendCommand(fmt.Sprintf("COMPANION_TAIL %s %s %s", args[0], args[1], attachHistoryArg(lines)))
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+
+			output, err := decodeCompanionLines(response)
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+
+			printCompanionLines(output, noTimestamps)
+		},
+	}
+
+	cmd.Flags().IntP("lines", "n", 10, "Number of lines to print (-1 for all retained history)")
+	cmd.Flags().Bool("no-timestamps", false, "Print raw output without timestamp and stream prefix")
+
+	return cmd
+}
+
+// printCompanionLines prints retained companion output lines, colorized or
+// with the timestamp and stream prefix stripped
+func printCompanionLines(lines []string, noTimestamps bool) {
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r\n") + "\n"
+		if noTimestamps {
+			fmt.Print(daemon.StripOutputPrefix(line))
+		} else if colored := colorizeCompanionOutput(line); colored != "" {
+			fmt.Print(colored)
+		}
+	}
+}
+
+// decodeCompanionLines decodes a COMPANION_GREP or COMPANION_TAIL response
+// into its lines, returning the daemon's error if the request failed
+func decodeCompanionLines(response daemon.Response) ([]string, error) {
+	for _, msg := range response.Messages {
+		if msg.Status == "ERROR" {
+			return nil, fmt.Errorf("%s", msg.Message)
+		}
+	}
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok || dataMap["lines"] == nil {
+		return nil, nil
+	}
+
+	jsonBytes, err := json.Marshal(dataMap["lines"])
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	if err := json.Unmarshal(jsonBytes, &lines); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
 func newCompanionStartCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "start",
@@ -454,94 +607,260 @@ func newCompanionRestartCommand() *cobra.Command {
 	return cmd
 }
 
-func newCompanionStatusCommand() *cobra.Command {
+func newCompanionRunCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "status",
-		Short: "Show status of companion scripts for a tunnel",
-		Long:  `Display the status of running companion scripts for a specific tunnel.`,
-		Args:  cobra.NoArgs,
+		Use:   "run <tunnel> <name>",
+		Short: "Run a companion script in the foreground",
+		Long: `Start a single companion script for a running tunnel and stream its output.
+
+Useful for trying out a companion before relying on it. Press Ctrl+C to
+detach, which also stops the companion.`,
+		Args: cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return tunnelCompletionFunc(cmd, args, toComplete)
+			case 1:
+				companions := getConfiguredCompanions(args[0])
+				sort.Strings(companions)
+				return companions, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			tunnel, _ := cmd.Flags().GetString("tunnel")
+			lines, _ := cmd.Flags().GetInt("lines")
 
 			daemon.EnsureDaemonIsRunning()
 			daemon.CheckVersionMismatch()
 
-			response, err := daemon.SendCommand("COMPANION_STATUS")
-			if err != nil {
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sigChan)
+
+			if err := runCompanionSession(args[0], args[1], lines, sigChan); err != nil {
 				slog.Error(err.Error())
 				os.Exit(1)
 			}
+		},
+	}
 
-			// Check if there are any companions
-			if response.Data == nil {
-				fmt.Printf("No companion scripts running for tunnel '%s'.\n", tunnel)
-				return
-			}
+	cmd.Flags().IntP("lines", "L", 20, "Number of history lines to show on attach (-1 for all retained history)")
 
-			dataMap, ok := response.Data.(map[string]interface{})
-			if !ok {
-				fmt.Printf("No companion scripts running for tunnel '%s'.\n", tunnel)
+	return cmd
+}
+
+// attachHistoryArg renders the --lines flag for COMPANION_ATTACH, where a
+// negative count asks for all retained history
+func attachHistoryArg(lines int) string {
+	if lines < 0 {
+		return "all"
+	}
+	return strconv.Itoa(lines)
+}
+
+// runCompanionSession starts a single companion, streams its output until
+// detach fires or the daemon closes the stream, then stops the companion
+func runCompanionSession(tunnel, name string, lines int, detach <-chan os.Signal) error {
+	response, err := daemon.SendCommand(fmt.Sprintf("COMPANION_START %s %s", tunnel, name))
+	if err != nil {
+		return err
+	}
+	for _, msg := range response.Messages {
+		if msg.Status == "ERROR" {
+			return fmt.Errorf("%s", msg.Message)
+		}
+	}
+	response.LogMessages()
+
+	// Stop the companion however the session ends
+	defer func() {
+		response, err := daemon.SendCommand(fmt.Sprintf("COMPANION_STOP %s %s", tunnel, name))
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to stop companion: %v", err))
+			return
+		}
+		response.LogMessages()
+	}()
+
+	conn, err := net.Dial("unix", core.GetSocketPath())
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "COMPANION_ATTACH %s %s %s\n", tunnel, name, attachHistoryArg(lines)); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
 				return
 			}
+			if colored := colorizeCompanionOutput(line); colored != "" {
+				fmt.Print(colored)
+			}
+		}
+	}()
+
+	select {
+	case <-detach:
+		conn.Close()
+		<-done
+		fmt.Print(formatDaemonMessage("Detached from companion."))
+	case <-done:
+		fmt.Print(formatDaemonMessage("Companion output closed."))
+	}
+	return nil
+}
 
-			companions, ok := dataMap["companions"]
-			if !ok {
-				fmt.Printf("No companion scripts running for tunnel '%s'.\n", tunnel)
-				return
+func newCompanionStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [tunnel]",
+		Short: "Show state, uptime and exit codes of companion scripts",
+		Long: `Display the status of companion scripts known to the daemon, including
+state, PID, uptime and the last exit code or error. Shows all tunnels unless
+a tunnel alias is given.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: tunnelCompletionFunc,
+		Run: func(cmd *cobra.Command, args []string) {
+			tunnel, _ := cmd.Flags().GetString("tunnel")
+			if len(args) == 1 {
+				tunnel = args[0]
 			}
+			jsonOutput, _ := cmd.Flags().GetBool("json")
 
-			companionMap, ok := companions.(map[string]interface{})
-			if !ok || len(companionMap) == 0 {
-				fmt.Printf("No companion scripts running for tunnel '%s'.\n", tunnel)
-				return
+			daemon.EnsureDaemonIsRunning()
+			daemon.CheckVersionMismatch()
+
+			response, err := daemon.SendCommand("COMPANION_STATUS")
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
 			}
 
-			// Get companions for the specified tunnel
-			comps, ok := companionMap[tunnel]
-			if !ok {
-				fmt.Printf("No companion scripts running for tunnel '%s'.\n", tunnel)
-				return
+			statuses, err := decodeCompanionStatuses(response)
+			if err != nil {
+				slog.Error(fmt.Sprintf("Failed to decode companion status: %v", err))
+				os.Exit(1)
+			}
+			if tunnel != "" {
+				statuses = map[string][]daemon.CompanionStatus{tunnel: statuses[tunnel]}
 			}
 
-			compList, ok := comps.([]interface{})
-			if !ok || len(compList) == 0 {
-				fmt.Printf("No companion scripts running for tunnel '%s'.\n", tunnel)
+			if jsonOutput {
+				out, _ := json.MarshalIndent(statuses, "", "  ")
+				fmt.Println(string(out))
 				return
 			}
 
-			fmt.Printf("Companion status for tunnel '%s':\n", tunnel)
-			for _, c := range compList {
-				comp, ok := c.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				name := comp["name"]
-				state := comp["state"]
-				pid := comp["pid"]
-				command := comp["command"]
-
-				fmt.Printf("  %s:\n", name)
-				fmt.Printf("    State:   %v\n", state)
-				fmt.Printf("    PID:     %v\n", pid)
-				fmt.Printf("    Command: %v\n", command)
-
-				if exitCode, ok := comp["exit_code"]; ok {
-					fmt.Printf("    Exit:    %v\n", exitCode)
-				}
-				if exitErr, ok := comp["exit_error"]; ok && exitErr != "" {
-					fmt.Printf("    Error:   %v\n", exitErr)
-				}
-			}
+			printCompanionStatuses(statuses, time.Now())
 		},
 	}
 
-	cmd.Flags().StringP("tunnel", "T", "", "Tunnel alias")
-	cmd.MarkFlagRequired("tunnel")
+	cmd.Flags().StringP("tunnel", "T", "", "Tunnel alias (optional, shows all if not specified)")
+	cmd.Flags().Bool("json", false, "Output as JSON")
 	cmd.RegisterFlagCompletionFunc("tunnel", tunnelCompletionFunc)
 
 	return cmd
 }
 
+// decodeCompanionStatuses decodes a COMPANION_STATUS response into tunnel -> companions
+func decodeCompanionStatuses(response daemon.Response) (map[string][]daemon.CompanionStatus, error) {
+	statuses := make(map[string][]daemon.CompanionStatus)
+
+	dataMap, ok := response.Data.(map[string]interface{})
+	if !ok || dataMap["companions"] == nil {
+		return statuses, nil
+	}
+
+	jsonBytes, err := json.Marshal(dataMap["companions"])
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonBytes, &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// printCompanionStatuses renders a table of companions per tunnel, sorted by name
+func printCompanionStatuses(statuses map[string][]daemon.CompanionStatus, now time.Time) {
+	tunnels := make([]string, 0, len(statuses))
+	for alias, comps := range statuses {
+		if len(comps) > 0 {
+			tunnels = append(tunnels, alias)
+		}
+	}
+	sort.Strings(tunnels)
+
+	if len(tunnels) == 0 {
+		fmt.Println("No companion scripts running.")
+		return
+	}
+
+	for i, alias := range tunnels {
+		comps := append([]daemon.CompanionStatus{}, statuses[alias]...)
+		sort.Slice(comps, func(a, b int) bool { return comps[a].Name < comps[b].Name })
+
+		nameWidth := len("NAME")
+		for _, comp := range comps {
+			nameWidth = max(nameWidth, len(comp.Name))
+		}
+
+		fmt.Printf("Tunnel '%s':\n", alias)
+		fmt.Printf("  %s%-*s  %-8s  %7s  %-8s  %s%s\n", colorGray, nameWidth, "NAME", "STATE", "PID", "UPTIME", "EXIT", colorReset)
+
+		for _, comp := range comps {
+			var stateColor string
+			switch comp.State {
+			case "running", "ready":
+				stateColor = colorGreen
+			case "waiting", "starting":
+				stateColor = colorYellow
+			case "failed":
+				stateColor = colorRed
+			default:
+				stateColor = colorGray
+			}
+
+			pid := "-"
+			if comp.Pid > 0 {
+				pid = fmt.Sprintf("%d", comp.Pid)
+			}
+
+			uptime := "-"
+			switch comp.State {
+			case "running", "ready", "waiting", "starting":
+				if !comp.StartTime.IsZero() {
+					uptime = formatDuration(now.Sub(comp.StartTime))
+				}
+			}
+
+			exit := "-"
+			if comp.ExitCode != nil {
+				exit = fmt.Sprintf("%d", *comp.ExitCode)
+			}
+			if comp.ExitError != "" {
+				exit += fmt.Sprintf(" %s(%s)%s", colorRed, comp.ExitError, colorReset)
+			}
+
+			fmt.Printf("  %-*s  %s%-8s%s  %7s  %-8s  %s\n",
+				nameWidth, comp.Name,
+				stateColor, comp.State, colorReset,
+				pid, uptime, exit)
+		}
+
+		if i < len(tunnels)-1 {
+			fmt.Println()
+		}
+	}
+}
+
 // Note: companionInfo and getCompanionMap are defined in status.go
 
 // checkTunnelReconnecting checks if the tunnel exists and is in a reconnecting state
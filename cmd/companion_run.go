@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -15,6 +18,7 @@ import (
 
 	"github.com/creack/pty"
 	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/core"
 	"go.olrik.dev/overseer/internal/daemon"
 )
 
@@ -84,6 +88,7 @@ func NewCompanionRunCommand() *cobra.Command {
 //   - OVERSEER_COMPANION_RUN_ALIAS: tunnel alias
 //   - OVERSEER_TUNNEL_TOKEN: authentication token
 //   - OVERSEER_COMPANION_NAME: companion name
+//   - OVERSEER_COMPANION_STOP_SIGNAL: optional signal number forwarded to the companion, KILL included
 func runCompanionWrapper() {
 	// Ignore SIGPIPE - crucial for surviving daemon death
 	signal.Ignore(syscall.SIGPIPE)
@@ -113,25 +118,40 @@ func runCompanionWrapper() {
 		os.Exit(1)
 	}
 
-	// Command is returned in the message (like askpass returns the password)
+	// Command is returned in the message (like askpass returns the password),
+	// and as a list in the data when configured as program and arguments
 	command := response.Messages[0].Message
+	var args []string
+	if response.Data != nil {
+		jsonBytes, _ := json.Marshal(response.Data)
+		json.Unmarshal(jsonBytes, &args)
+	}
 
 	// Derive socket path from alias + name
 	socketPath := filepath.Join(os.TempDir(), fmt.Sprintf("overseer-companion-%s-%s.sock", alias, name))
 
 	// Run the actual wrapper logic
-	executeCompanionWrapper(socketPath, alias, command)
+	executeCompanionWrapper(socketPath, token, companionCommand(command, args, alias))
 }
 
-// executeCompanionWrapper runs the companion script and streams output to the daemon socket
-// Uses a PTY to enable terminal signal delivery (Ctrl+C) which can reach root-owned processes
-func executeCompanionWrapper(socketPath, alias, command string) {
-	// Expand ~ in command path
-	expandedCmd := expandPath(command)
+// companionCommand builds the companion process. A command given as a list is
+// run exactly as listed, a string command is run with the tunnel alias as its
+// only argument. Neither goes through a shell.
+func companionCommand(command string, args []string, alias string) *exec.Cmd {
+	// Expand ~ in the program path
+	if len(args) > 0 {
+		return exec.Command(core.ExpandHomeDir(args[0]), args[1:]...)
+	}
+	return exec.Command(core.ExpandHomeDir(command), alias)
+}
 
-	// Spawn the actual script with tunnel alias as first argument
-	cmd := exec.Command(expandedCmd, alias)
-	cmd.Env = os.Environ()
+// executeCompanionWrapper runs the companion script and streams output to the daemon socket
+// Uses a PTY to enable terminal signal delivery (Ctrl+C) which can reach root-owned processes.
+// The token authenticates the exit code reported to the daemon and is kept from the script.
+func executeCompanionWrapper(socketPath, token string, cmd *exec.Cmd) {
+	cmd.Env = slices.DeleteFunc(os.Environ(), func(entry string) bool {
+		return strings.HasPrefix(entry, "OVERSEER_TUNNEL_TOKEN=")
+	})
 
 	// Start with PTY - this gives us terminal signal delivery capability
 	// When we write Ctrl+C (0x03) to the PTY, the terminal driver sends SIGINT
@@ -151,12 +171,6 @@ func executeCompanionWrapper(socketPath, alias, command string) {
 	wg.Add(1)
 	go readPtyToChannel(ptmx, outputChan, &wg)
 
-	// Close output channel when PTY reading is done
-	go func() {
-		wg.Wait()
-		close(outputChan)
-	}()
-
 	// Create output cache for replay after daemon restart (1000 lines)
 	outputCache := NewOutputCache(1000)
 
@@ -171,6 +185,14 @@ func executeCompanionWrapper(socketPath, alias, command string) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 
+	// A numeric stop_signal is passed on to the companion as-is, when the
+	// daemon sends it or the signal standing in for it if we can't catch it
+	var stopSignal syscall.Signal
+	if number, err := strconv.Atoi(os.Getenv("OVERSEER_COMPANION_STOP_SIGNAL")); err == nil && number > 0 {
+		stopSignal = syscall.Signal(number)
+		signal.Notify(sigChan, daemon.CompanionStopTrigger(stopSignal))
+	}
+
 	childDone := make(chan error, 1)
 	go func() {
 		childDone <- cmd.Wait()
@@ -186,6 +208,9 @@ func executeCompanionWrapper(socketPath, alias, command string) {
 			ptmx.Write([]byte{0x03}) // Ctrl+C
 		} else if sig == syscall.SIGHUP {
 			ptmx.Write([]byte{0x04}) // Ctrl+D (EOF) for SIGHUP
+		} else if stopSignal != 0 && cmd.Process != nil {
+			// The child leads its own session, so its process group has its pid
+			syscall.Kill(-cmd.Process.Pid, stopSignal)
 		}
 
 		// Wait for child with timeout
@@ -223,6 +248,10 @@ func executeCompanionWrapper(socketPath, alias, command string) {
 	// Wait for PTY output to drain so cleanup logs get captured
 	wg.Wait()
 
+	// Report the child's exit code last, the daemon prefers it over ours
+	outputChan <- daemon.CompanionExitMarker(token, exitCode)
+	close(outputChan)
+
 	// Wait for all output to be sent to daemon (with timeout)
 	select {
 	case <-streamingDone:
@@ -234,17 +263,6 @@ func executeCompanionWrapper(socketPath, alias, command string) {
 	os.Exit(exitCode)
 }
 
-// expandPath expands ~ to home directory
-func expandPath(path string) string {
-	if len(path) > 0 && path[0] == '~' {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return filepath.Join(home, path[1:])
-		}
-	}
-	return path
-}
-
 // readPtyToChannel reads lines from a PTY master and sends them to a channel
 // PTY merges stdout and stderr, so we use a single [output] tag
 func readPtyToChannel(ptmx *os.File, output chan<- string, wg *sync.WaitGroup) {
@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func TestDecodeAndPrintCompanionStatuses(t *testing.T) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	exitCode := 2
+
+	// Round-trip through JSON like a real COMPANION_STATUS response
+	raw, _ := json.Marshal(daemon.Response{Data: map[string]interface{}{
+		"companions": map[string][]daemon.CompanionStatus{
+			"vpn": {
+				{Name: "proxy", Pid: 4242, State: "running", StartTime: now.Add(-90 * time.Minute), Command: "proxy"},
+				{Name: "auth", Pid: 0, State: "failed", StartTime: now.Add(-time.Hour), Command: "vpn-auth", ExitCode: &exitCode, ExitError: "exit status 2"},
+			},
+		},
+	}})
+	var response daemon.Response
+	if err := json.Unmarshal(raw, &response); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := decodeCompanionStatuses(response)
+	if err != nil {
+		t.Fatalf("decodeCompanionStatuses failed: %v", err)
+	}
+	if got := statuses["vpn"]; len(got) != 2 || got[1].ExitCode == nil || *got[1].ExitCode != 2 {
+		t.Fatalf("expected exit code to survive decoding, got %+v", got)
+	}
+
+	out := captureStdout(t, func() { printCompanionStatuses(statuses, now) })
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected tunnel header, column header and 2 rows, got:\n%s", out)
+	}
+
+	// Sorted by name: the failed companion comes first
+	failed, running := lines[2], lines[3]
+	for _, want := range []string{"auth", "failed", "2", "exit status 2"} {
+		if !strings.Contains(failed, want) {
+			t.Errorf("failed companion row missing %q:\n%s", want, failed)
+		}
+	}
+	if strings.Contains(failed, "1h") {
+		t.Errorf("failed companion should not report uptime:\n%s", failed)
+	}
+	for _, want := range []string{"proxy", "running", "4242", "1h30m"} {
+		if !strings.Contains(running, want) {
+			t.Errorf("running companion row missing %q:\n%s", want, running)
+		}
+	}
+}
+
+func TestPrintCompanionStatuses_Empty(t *testing.T) {
+	out := captureStdout(t, func() { printCompanionStatuses(map[string][]daemon.CompanionStatus{"vpn": nil}, time.Now()) })
+	if !strings.Contains(out, "No companion scripts running") {
+		t.Errorf("expected empty message, got:\n%s", out)
+	}
+}
+
+func TestDecodeCompanionLines(t *testing.T) {
+	var response daemon.Response
+	response.AddData(map[string]interface{}{"lines": []string{"2024-01-12 15:04:06 [stderr] error: refused\n"}})
+	response.AddMessage("1 matching lines", "INFO")
+	roundTripped := daemon.Response{}
+	if err := json.Unmarshal([]byte(response.ToJSON()), &roundTripped); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := decodeCompanionLines(roundTripped)
+	if err != nil {
+		t.Fatalf("decodeCompanionLines() error: %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"2024-01-12 15:04:06 [stderr] error: refused\n"}) {
+		t.Errorf("unexpected lines %q", lines)
+	}
+
+	var failed daemon.Response
+	failed.AddMessage("Failed to search companion output: invalid pattern", "ERROR")
+	if _, err := decodeCompanionLines(failed); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("expected the daemon's error, got %v", err)
+	}
+}
+
+// fakeCompanionDaemon serves the companion IPC commands on the daemon socket
+// and records every command it receives. A non-empty startErr makes
+// COMPANION_START fail with that message.
+type fakeCompanionDaemon struct {
+	mu       sync.Mutex
+	commands []string
+	attached chan struct{}
+	startErr string
+}
+
+func startFakeCompanionDaemon(t *testing.T, startErr string) *fakeCompanionDaemon {
+	t.Helper()
+
+	// Short path to stay within the Unix socket path limit
+	dir, err := os.MkdirTemp("/tmp", "ov-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	original := core.Config
+	t.Cleanup(func() { core.Config = original })
+	core.Config = &core.Configuration{ConfigPath: dir}
+
+	listener, err := net.Listen("unix", core.GetSocketPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	f := &fakeCompanionDaemon{attached: make(chan struct{}), startErr: startErr}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeCompanionDaemon) serve(conn net.Conn) {
+	defer conn.Close()
+
+	command, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	command = strings.TrimSpace(command)
+	f.mu.Lock()
+	f.commands = append(f.commands, command)
+	f.mu.Unlock()
+
+	var response daemon.Response
+	switch {
+	case strings.HasPrefix(command, "COMPANION_START") && f.startErr != "":
+		response.AddMessage(f.startErr, "ERROR")
+	case strings.HasPrefix(command, "COMPANION_ATTACH"):
+		fmt.Fprintln(conn, "2025-12-01 12:00:00 [stdout] proxy listening")
+		close(f.attached)
+		// Stream until the client detaches
+		conn.Read(make([]byte, 1))
+		return
+	default:
+		response.AddMessage("OK", "INFO")
+	}
+	conn.Write([]byte(response.ToJSON()))
+}
+
+func (f *fakeCompanionDaemon) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestRunCompanionSession(t *testing.T) {
+	f := startFakeCompanionDaemon(t, "")
+
+	detach := make(chan os.Signal, 1)
+	go func() {
+		<-f.attached
+		// Give the client a moment to print the streamed line
+		time.Sleep(100 * time.Millisecond)
+		detach <- syscall.SIGINT
+	}()
+
+	var err error
+	out := captureStdout(t, func() { err = runCompanionSession("vpn", "proxy", 5, detach) })
+	if err != nil {
+		t.Fatalf("runCompanionSession() error: %v", err)
+	}
+	if !strings.Contains(out, "proxy listening") {
+		t.Errorf("expected companion output to be streamed, got:\n%s", out)
+	}
+
+	want := []string{
+		"COMPANION_START vpn proxy",
+		"COMPANION_ATTACH vpn proxy 5",
+		"COMPANION_STOP vpn proxy",
+	}
+	if got := f.received(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("IPC sequence = %q, want %q", got, want)
+	}
+}
+
+func TestRunCompanionSession_StartFails(t *testing.T) {
+	f := startFakeCompanionDaemon(t, "Tunnel 'vpn' is not running")
+
+	err := runCompanionSession("vpn", "proxy", 5, make(chan os.Signal))
+	if err == nil || !strings.Contains(err.Error(), "is not running") {
+		t.Fatalf("expected start error, got %v", err)
+	}
+	if got := f.received(); len(got) != 1 {
+		t.Errorf("expected only COMPANION_START to be sent, got %q", got)
+	}
+}
+
+func TestCompanionCommand(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	t.Run("string form gets the alias as argument", func(t *testing.T) {
+		cmd := companionCommand("~/bin/start-vpn.sh", nil, "server1")
+		want := []string{home + "/bin/start-vpn.sh", "server1"}
+		if !reflect.DeepEqual(cmd.Args, want) {
+			t.Errorf("Args = %q, want %q", cmd.Args, want)
+		}
+	})
+
+	t.Run("list form is run as listed", func(t *testing.T) {
+		cmd := companionCommand(`/usr/bin/proxy --config "/etc/my proxy.conf"`, []string{"/usr/bin/proxy", "--config", "/etc/my proxy.conf"}, "server1")
+		want := []string{"/usr/bin/proxy", "--config", "/etc/my proxy.conf"}
+		if !reflect.DeepEqual(cmd.Args, want) {
+			t.Errorf("Args = %q, want %q", cmd.Args, want)
+		}
+		if cmd.Path != "/usr/bin/proxy" {
+			t.Errorf("Path = %q, want /usr/bin/proxy", cmd.Path)
+		}
+	})
+}
+
+func TestCompanionWrapper_ForwardsKill(t *testing.T) {
+	// Run as the wrapper when started by the test below
+	if socketPath := os.Getenv("OVERSEER_TEST_COMPANION_SOCKET"); socketPath != "" {
+		executeCompanionWrapper(socketPath, os.Getenv("OVERSEER_TUNNEL_TOKEN"), exec.Command("sh", "-c", os.Getenv("OVERSEER_TEST_COMPANION_SCRIPT")))
+		return
+	}
+
+	// Unix socket paths are short, so stay out of the test's temp dir
+	dir, err := os.MkdirTemp("", "ovs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "companion.sock")
+	pidFile := filepath.Join(dir, "pid")
+
+	// Swallow the wrapper's output like the daemon does
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	// A companion that ignores every signal a graceful stop would send
+	wrapper := exec.Command(os.Args[0], "-test.run=^TestCompanionWrapper_ForwardsKill$")
+	wrapper.Env = append(os.Environ(),
+		"OVERSEER_TEST_COMPANION_SOCKET="+socketPath,
+		fmt.Sprintf("OVERSEER_TEST_COMPANION_SCRIPT=trap '' INT TERM HUP USR1; echo $$ > %s; while :; do sleep 0.1; done", pidFile),
+		fmt.Sprintf("OVERSEER_COMPANION_STOP_SIGNAL=%d", int(syscall.SIGKILL)),
+	)
+	wrapper.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := wrapper.Start(); err != nil {
+		t.Fatalf("failed to start wrapper: %v", err)
+	}
+	t.Cleanup(func() { syscall.Kill(-wrapper.Process.Pid, syscall.SIGKILL) })
+
+	var childPid int
+	for deadline := time.Now().Add(5 * time.Second); childPid == 0; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("companion didn't start")
+		}
+		data, _ := os.ReadFile(pidFile)
+		childPid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	t.Cleanup(func() { syscall.Kill(-childPid, syscall.SIGKILL) })
+
+	// Stop it the way the daemon does, signalling the wrapper's process group
+	syscall.Kill(-wrapper.Process.Pid, daemon.CompanionStopTrigger(syscall.SIGKILL))
+
+	// Without the 5 second grace period the wrapper gives a companion
+	done := make(chan struct{})
+	go func() {
+		wrapper.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(4 * time.Second):
+		t.Fatal("wrapper didn't pass SIGKILL on to the companion")
+	}
+	if err := syscall.Kill(childPid, 0); err != syscall.ESRCH {
+		t.Errorf("expected the companion to be gone, got %v", err)
+	}
+}
+
+func TestCompanionWrapper_ReportsExitWithToken(t *testing.T) {
+	// Run as the wrapper when started by the test below
+	if socketPath := os.Getenv("OVERSEER_TEST_COMPANION_SOCKET"); socketPath != "" {
+		executeCompanionWrapper(socketPath, os.Getenv("OVERSEER_TUNNEL_TOKEN"), exec.Command("sh", "-c", os.Getenv("OVERSEER_TEST_COMPANION_SCRIPT")))
+		return
+	}
+
+	// Unix socket paths are short, so stay out of the test's temp dir
+	dir, err := os.MkdirTemp("", "ovs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socketPath := filepath.Join(dir, "companion.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	output := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(conn)
+		output <- string(data)
+	}()
+
+	// A companion that tries to see the token and to fake its exit code
+	wrapper := exec.Command(os.Args[0], "-test.run=^TestCompanionWrapper_ReportsExitWithToken$")
+	wrapper.Env = append(os.Environ(),
+		"OVERSEER_TEST_COMPANION_SOCKET="+socketPath,
+		"OVERSEER_TUNNEL_TOKEN=secret",
+		`OVERSEER_TEST_COMPANION_SCRIPT=echo "token=[$OVERSEER_TUNNEL_TOKEN]"; echo "EXIT 0"; exit 3`,
+	)
+	if err := wrapper.Run(); err == nil {
+		t.Error("expected the wrapper to exit with the companion's exit code")
+	}
+
+	var lines []string
+	select {
+	case data := <-output:
+		lines = strings.Split(strings.TrimSpace(strings.ReplaceAll(data, "\r", "")), "\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the wrapper's output")
+	}
+	if !slices.ContainsFunc(lines, func(line string) bool { return strings.HasSuffix(line, "token=[]") }) {
+		t.Errorf("expected the companion not to get the token, got %q", lines)
+	}
+	if last := lines[len(lines)-1]; last != daemon.CompanionExitMarker("secret", 3) {
+		t.Errorf("expected the exit marker with the token last, got %q", last)
+	}
+}
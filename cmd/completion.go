@@ -144,6 +144,22 @@ func companionCompletionFunc(cmd *cobra.Command, args []string, toComplete strin
 	return companions, cobra.ShellCompDirectiveNoFileComp
 }
 
+// companionArgsCompletionFunc completes a tunnel alias followed by one of its
+// companion names as positional arguments
+func companionArgsCompletionFunc(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		tunnels := getConfiguredTunnels()
+		sort.Strings(tunnels)
+		return tunnels, cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		companions := getConfiguredCompanions(args[0])
+		sort.Strings(companions)
+		return companions, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
 // getConfiguredTunnels returns all tunnel aliases from the overseer config
 func getConfiguredTunnels() []string {
 	if core.Config == nil || core.Config.Tunnels == nil {
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func NewConfigCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the loaded configuration",
+		Long:  `Inspect the configuration as loaded from config.hcl and config.d/ fragments.`,
+	}
+
+	configCmd.AddCommand(newConfigDumpCommand())
+	configCmd.AddCommand(newConfigTunnelCommand())
+	configCmd.AddCommand(newConfigValidateCommand())
+
+	return configCmd
+}
+
+func newConfigDumpCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Show the locations, contexts and tunnels of the loaded configuration",
+		Long: `Show the locations, contexts and tunnels of the configuration after config.d/
+fragments have been merged. Contexts are listed in evaluation order.
+
+With --provenance, each entry also shows the files it was defined in. A context
+defined in several files is deep-merged, and lists every file that contributed.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			provenance, _ := cmd.Flags().GetBool("provenance")
+			printConfigDump(os.Stdout, core.Config, provenance)
+		},
+	}
+
+	cmd.Flags().Bool("provenance", false, "Show the config files each entry was defined in")
+
+	return cmd
+}
+
+func newConfigTunnelCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tunnel <alias>",
+		Short: "Show the effective configuration of a tunnel as JSON",
+		Long: `Show the configuration a tunnel connects with, as resolved by the daemon: the
+tunnel block with the global SSH settings, the keepalive profile and the SSH
+overrides of the context applied, along with its environment, ssh arguments and
+local forwards.
+
+A connected tunnel shows the settings it was connected with. Otherwise the SSH
+overrides of the active context apply if it connects the tunnel.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			daemon.EnsureDaemonIsRunning()
+			daemon.CheckVersionMismatch()
+
+			response, err := daemon.SendCommand("TUNNEL_CONFIG " + args[0])
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+
+			out, _ := json.MarshalIndent(response.Data, "", "  ")
+			fmt.Println(string(out))
+		},
+	}
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration for errors and likely mistakes",
+		Long: `Load config.hcl and the config.d/ fragments the same way the daemon does and
+report errors and warnings, such as a context connecting a tunnel that is
+neither a tunnel block nor a host of the SSH config.
+
+Exits with status 1 when the configuration has errors. Warnings are printed but
+don't fail validation.`,
+		Args: cobra.NoArgs,
+		// Override the root pre-run: it exits on an invalid config, which is
+		// exactly what validate must report.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			configDir, _ := cmd.Flags().GetString("config-path")
+			configFile, _ := cmd.Flags().GetString("config")
+			configDir, configPath := core.ResolveConfigLocation(configDir, configFile)
+
+			core.Config = core.GetDefaultConfig()
+			core.Config.ConfigPath = configDir
+			core.Config.ConfigFile = configPath
+
+			if err := validateConfig(os.Stderr); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Configuration has errors\n  %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Configuration is valid")
+		},
+	}
+}
+
+// printConfigDump prints the locations, contexts and tunnels of cfg, one per
+// line, optionally followed by the files they were defined in
+func printConfigDump(w io.Writer, cfg *core.Configuration, provenance bool) {
+	base := cfg.ConfigPath
+	if cfg.ConfigFile != "" {
+		base = filepath.Dir(cfg.ConfigFile)
+	}
+
+	type entry struct {
+		name    string
+		details string
+		sources []string
+	}
+	printSection := func(title string, entries []entry) {
+		fmt.Fprintf(w, "%s%s%s\n", colorBold, title, colorReset)
+		if len(entries) == 0 {
+			fmt.Fprintf(w, "  %s(none)%s\n", colorGray, colorReset)
+			return
+		}
+
+		nameWidth, detailsWidth := 0, 0
+		for _, e := range entries {
+			nameWidth = max(nameWidth, len(e.name))
+			detailsWidth = max(detailsWidth, len(e.details))
+		}
+		for _, e := range entries {
+			line := fmt.Sprintf("  %-*s", nameWidth, e.name)
+			if provenance {
+				line += fmt.Sprintf("  %-*s  %s%s%s", detailsWidth, e.details, colorGray, formatSources(e.sources, base), colorReset)
+			} else if e.details != "" {
+				line += "  " + e.details
+			}
+			fmt.Fprintln(w, strings.TrimRight(line, " "))
+		}
+	}
+
+	var locations []entry
+	for name, loc := range cfg.Locations {
+		locations = append(locations, entry{name, loc.DisplayName, loc.Sources})
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].name < locations[j].name })
+	printSection("Locations", locations)
+
+	var contexts []entry
+	for _, ctx := range cfg.Contexts {
+		var details []string
+		if len(ctx.Locations) > 0 {
+			details = append(details, "locations: "+strings.Join(ctx.Locations, ", "))
+		}
+		if len(ctx.Actions.Connect) > 0 {
+			details = append(details, "connect: "+strings.Join(ctx.Actions.Connect, ", "))
+		}
+		contexts = append(contexts, entry{ctx.Name, strings.Join(details, "; "), ctx.Sources})
+	}
+	printSection("Contexts", contexts)
+
+	var tunnels []entry
+	for name, tunnel := range cfg.Tunnels {
+		tunnels = append(tunnels, entry{name, tunnel.Description, tunnel.Sources})
+	}
+	sort.Slice(tunnels, func(i, j int) bool { return tunnels[i].name < tunnels[j].name })
+	printSection("Tunnels", tunnels)
+}
+
+// formatSources lists config files relative to base when they live below it
+func formatSources(sources []string, base string) string {
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		if rel, err := filepath.Rel(base, source); err == nil && !strings.HasPrefix(rel, "..") {
+			source = rel
+		}
+		names = append(names, source)
+	}
+	return strings.Join(names, ", ")
+}
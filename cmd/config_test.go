@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.olrik.dev/overseer/internal/core"
+)
+
+func TestPrintConfigDump(t *testing.T) {
+	cfg := &core.Configuration{
+		ConfigPath: "/home/me/.config/overseer",
+		Locations: map[string]*core.Location{
+			"home": {Name: "home", DisplayName: "Home", Sources: []string{"/home/me/.config/overseer/config.hcl"}},
+		},
+		Contexts: []*core.ContextRule{
+			{
+				Name:      "client",
+				Locations: []string{"home"},
+				Actions:   core.ContextActions{Connect: []string{"jump"}},
+				Sources: []string{
+					"/home/me/.config/overseer/config.hcl",
+					"/home/me/.config/overseer/config.d/client.hcl",
+				},
+			},
+		},
+		Tunnels: map[string]*core.TunnelConfig{},
+	}
+
+	var buf bytes.Buffer
+	printConfigDump(&buf, cfg, false)
+	want := colorBold + "Locations" + colorReset + "\n" +
+		"  home  Home\n" +
+		colorBold + "Contexts" + colorReset + "\n" +
+		"  client  locations: home; connect: jump\n" +
+		colorBold + "Tunnels" + colorReset + "\n" +
+		"  " + colorGray + "(none)" + colorReset + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printConfigDump() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	printConfigDump(&buf, cfg, true)
+	if !strings.Contains(buf.String(), colorGray+"config.hcl, config.d/client.hcl"+colorReset) {
+		t.Errorf("expected the merged context to list both files, got %q", buf.String())
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -8,22 +9,28 @@ import (
 
 	"github.com/spf13/cobra"
 	"go.olrik.dev/overseer/internal/daemon"
+	"go.olrik.dev/overseer/internal/keyring"
 )
 
 func NewConnectCommand() *cobra.Command {
 	var envVars []string
 	var force bool
+	var otp bool
 
 	connectCmd := &cobra.Command{
-		Use:               "connect",
-		Aliases:           []string{"c"},
-		Short:             "Connect SSH tunnel",
-		Long:              `Connect SSH tunnel`,
-		Args:              cobra.ExactArgs(1),
+		Use:     "connect <alias>...",
+		Aliases: []string{"c"},
+		Short:   "Connect SSH tunnels",
+		Long: `Connect one or more SSH tunnels.
+
+Tunnels are connected one after another. The command exits non-zero if any of them failed.
+
+With --otp you are prompted for a one-time password (e.g. an MFA code) for each
+tunnel. It is answered once to SSH, after the stored password if there is one,
+and is never stored.`,
+		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: sshHostCompletionFunc,
 		Run: func(cmd *cobra.Command, args []string) {
-			alias := args[0]
-
 			// Validate env var format
 			for _, e := range envVars {
 				idx := strings.Index(e, "=")
@@ -44,17 +51,10 @@ func NewConnectCommand() *cobra.Command {
 				force = !isStdinTerminal()
 			}
 
-			command := "SSH_CONNECT " + alias
-			if force {
-				command += " --force"
-			}
-			for _, e := range envVars {
-				command += " --env=" + e
-			}
-
-			// Use streaming to show companion startup progress in real-time
-			if err := daemon.SendCommandStreaming(command); err != nil {
-				slog.Error(err.Error())
+			if failed := connectTunnels(args, force, otp, envVars); len(failed) > 0 {
+				if len(args) > 1 {
+					slog.Error(fmt.Sprintf("Failed to connect %d of %d tunnels: %s", len(failed), len(args), strings.Join(failed, ", ")))
+				}
 				os.Exit(1)
 			}
 		},
@@ -64,6 +64,43 @@ func NewConnectCommand() *cobra.Command {
 		"Set environment variable on the SSH process (repeatable, format: KEY=VALUE)")
 	connectCmd.Flags().BoolVarP(&force, "force", "F", false,
 		"Evict a conflicting SSH ControlMaster before connecting (default: auto — on when stdin is not a terminal)")
+	connectCmd.Flags().BoolVar(&otp, "otp", false,
+		"Prompt for a one-time password to answer SSH with, e.g. an MFA code")
 
 	return connectCmd
 }
+
+// connectTunnels sends SSH_CONNECT for each alias in turn, streaming the
+// daemon's progress, and returns the aliases that failed to connect. With otp
+// set, a one-time password is prompted for before each connect.
+func connectTunnels(aliases []string, force, otp bool, envVars []string) []string {
+	var failed []string
+	for _, alias := range aliases {
+		command := "SSH_CONNECT " + alias
+		if force {
+			command += " --force"
+		}
+		for _, e := range envVars {
+			command += " --env=" + e
+		}
+		if otp {
+			code, err := keyring.PromptOTP(alias)
+			if err != nil {
+				slog.Error(err.Error())
+				failed = append(failed, alias)
+				continue
+			}
+			command += " --otp=" + code
+		}
+
+		// Use streaming to show companion startup progress in real-time
+		if err := daemon.SendCommandStreaming(command); err != nil {
+			// A daemon-reported error has already been logged
+			if !errors.Is(err, daemon.ErrCommandFailed) {
+				slog.Error(err.Error())
+			}
+			failed = append(failed, alias)
+		}
+	}
+	return failed
+}
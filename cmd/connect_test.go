@@ -1,7 +1,18 @@
 package cmd
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+
+	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/daemon"
 )
 
 // connectForceDefault mirrors the default-resolution logic in connect.go and
@@ -55,3 +66,140 @@ func TestIsStdinTerminal_OverridableForTests(t *testing.T) {
 		t.Error("expected isStdinTerminal to return false when hook returns false")
 	}
 }
+
+// fakeTunnelDaemon answers SSH_CONNECT (streamed) and SSH_DISCONNECT on the
+// daemon socket, failing for the aliases in fail, and records every command
+type fakeTunnelDaemon struct {
+	mu       sync.Mutex
+	commands []string
+	fail     map[string]bool
+}
+
+func startFakeTunnelDaemon(t *testing.T, fail ...string) *fakeTunnelDaemon {
+	t.Helper()
+
+	// Short path to stay within the Unix socket path limit
+	dir, err := os.MkdirTemp("/tmp", "ov-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	original := core.Config
+	t.Cleanup(func() { core.Config = original })
+	core.Config = &core.Configuration{ConfigPath: dir}
+
+	listener, err := net.Listen("unix", core.GetSocketPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	f := &fakeTunnelDaemon{fail: make(map[string]bool)}
+	for _, alias := range fail {
+		f.fail[alias] = true
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeTunnelDaemon) serve(conn net.Conn) {
+	defer conn.Close()
+
+	command, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(command)
+	f.mu.Lock()
+	f.commands = append(f.commands, strings.Join(fields, " "))
+	f.mu.Unlock()
+
+	status := "INFO"
+	if len(fields) > 1 && f.fail[fields[1]] {
+		status = "ERROR"
+	}
+
+	switch fields[0] {
+	case "SSH_CONNECT":
+		// Streamed: one JSON message per line
+		for _, msg := range []daemon.ResponseMessage{{Message: "Connecting...", Status: "INFO"}, {Message: "done", Status: status}} {
+			data, _ := json.Marshal(msg)
+			fmt.Fprintf(conn, "%s\n", data)
+		}
+	default:
+		var response daemon.Response
+		response.AddMessage("done", status)
+		conn.Write([]byte(response.ToJSON()))
+	}
+}
+
+func (f *fakeTunnelDaemon) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestConnectTunnels_MultipleAliases(t *testing.T) {
+	f := startFakeTunnelDaemon(t, "db")
+
+	failed := connectTunnels([]string{"vpn", "db", "jump"}, true, false, []string{"TAG=work"})
+
+	want := []string{
+		"SSH_CONNECT vpn --force --env=TAG=work",
+		"SSH_CONNECT db --force --env=TAG=work",
+		"SSH_CONNECT jump --force --env=TAG=work",
+	}
+	if got := f.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("IPC commands = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(failed, []string{"db"}) {
+		t.Errorf("failed = %q, want [db]", failed)
+	}
+}
+
+func TestConnectTunnels_AllSucceed(t *testing.T) {
+	startFakeTunnelDaemon(t)
+
+	if failed := connectTunnels([]string{"vpn", "jump"}, false, false, nil); len(failed) != 0 {
+		t.Errorf("expected no failures, got %q", failed)
+	}
+}
+
+func TestDisconnectTunnels_MultipleAliases(t *testing.T) {
+	f := startFakeTunnelDaemon(t, "jump")
+
+	failed, err := disconnectTunnels([]string{"vpn", "db", "jump"}, false)
+	if err != nil {
+		t.Fatalf("disconnectTunnels() error: %v", err)
+	}
+
+	want := []string{"SSH_DISCONNECT vpn", "SSH_DISCONNECT db", "SSH_DISCONNECT jump"}
+	if got := f.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("IPC commands = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(failed, []string{"jump"}) {
+		t.Errorf("failed = %q, want [jump]", failed)
+	}
+}
+
+func TestDisconnectTunnels_Force(t *testing.T) {
+	f := startFakeTunnelDaemon(t)
+
+	if _, err := disconnectTunnels([]string{"vpn"}, true); err != nil {
+		t.Fatalf("disconnectTunnels() error: %v", err)
+	}
+
+	want := []string{"SSH_DISCONNECT vpn --force"}
+	if got := f.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("IPC commands = %q, want %q", got, want)
+	}
+}
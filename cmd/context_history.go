@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/db"
+)
+
+func newContextHistoryCommand() *cobra.Command {
+	var sinceStr string
+	var days int
+	var disableColor bool
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show context and location changes with what triggered them",
+		Long: `Display the recorded context and location transitions from the database,
+along with the sensor or action that triggered each change.
+
+Examples:
+  overseer context history                # Today only
+  overseer context history -S yesterday   # Just yesterday
+  overseer context history -D 7           # Last 7 days`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			noColor = disableColor || os.Getenv("NO_COLOR") != ""
+
+			start, end, label := parseDateRange(sinceStr, days, cmd.Flags().Changed("since"))
+			runContextHistory(start, end, label)
+		},
+	}
+
+	historyCmd.Flags().StringVarP(&sinceStr, "since", "S", "today", "Start date: today, yesterday, or YYYY-MM-DD")
+	historyCmd.Flags().IntVarP(&days, "days", "D", 1, "Number of days to include")
+	historyCmd.Flags().BoolVar(&disableColor, "no-color", false, "Disable colored output (also honors NO_COLOR)")
+
+	return historyCmd
+}
+
+func runContextHistory(start, end time.Time, label string) {
+	database := openStatsDatabase()
+	defer database.Close()
+
+	changes, err := database.GetContextChanges(start, end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%sError:%s Failed to query database: %v\n", ansiColor(colorRed), ansiColor(colorReset), err)
+		os.Exit(1)
+	}
+
+	printContextHistory(changes, label)
+}
+
+// printContextHistory prints one line per context change, oldest first
+func printContextHistory(changes []db.ContextChange, label string) {
+	fmt.Printf("%s%sContext History:%s (%s)\n\n", ansiColor(colorBold), ansiColor(colorCyan), ansiColor(colorReset), label)
+
+	if len(changes) == 0 {
+		fmt.Printf("  %s(no context changes in range)%s\n", ansiColor(colorGray), ansiColor(colorReset))
+		return
+	}
+
+	for _, c := range changes {
+		line := fmt.Sprintf("  %s%s%s ", ansiColor(colorGray), c.Timestamp.Local().Format("Jan 2 15:04:05"), ansiColor(colorReset))
+		if c.FromContext != c.ToContext {
+			line += fmt.Sprintf("%s → %s%s%s",
+				contextName(c.FromContext), ansiColor(colorBoldGreen), contextName(c.ToContext), ansiColor(colorReset))
+		} else {
+			line += fmt.Sprintf("%s%s%s", ansiColor(colorWhite), contextName(c.ToContext), ansiColor(colorReset))
+		}
+		if c.FromLocation != c.ToLocation {
+			line += fmt.Sprintf(" %slocation:%s %s → %s",
+				ansiColor(colorGray), ansiColor(colorReset), contextName(c.FromLocation), contextName(c.ToLocation))
+		}
+		if c.Trigger != "" {
+			line += fmt.Sprintf(" %s(%s)%s", ansiColor(colorGray), c.Trigger, ansiColor(colorReset))
+		}
+		fmt.Println(line)
+	}
+}
+
+// contextName returns a printable name for a context or location ("-" if empty)
+func contextName(name string) string {
+	if name == "" {
+		return "-"
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/db"
+)
+
+func TestPrintContextHistory(t *testing.T) {
+	t.Cleanup(func() { noColor = false })
+	noColor = true
+
+	at := time.Date(2025, 12, 1, 9, 30, 0, 0, time.Local)
+	changes := []db.ContextChange{
+		{FromContext: "untrusted", ToContext: "home", FromLocation: "", ToLocation: "home", Trigger: "public_ipv4", Timestamp: at},
+		{FromContext: "home", ToContext: "home", FromLocation: "home", ToLocation: "garden", Trigger: "local_ipv4", Timestamp: at.Add(time.Hour)},
+	}
+
+	out := captureStdout(t, func() { printContextHistory(changes, "today") })
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header, blank line and 2 changes, got:\n%s", out)
+	}
+	for _, want := range []string{"Dec 1 09:30:00", "untrusted → home", "location: - → home", "(public_ipv4)"} {
+		if !strings.Contains(lines[2], want) {
+			t.Errorf("first change missing %q:\n%s", want, lines[2])
+		}
+	}
+	for _, want := range []string{"Dec 1 10:30:00", "location: home → garden", "(local_ipv4)"} {
+		if !strings.Contains(lines[3], want) {
+			t.Errorf("second change missing %q:\n%s", want, lines[3])
+		}
+	}
+	if strings.Contains(lines[3], "→ home") {
+		t.Errorf("unchanged context should not be shown as a transition:\n%s", lines[3])
+	}
+}
+
+func TestPrintContextHistory_Empty(t *testing.T) {
+	out := captureStdout(t, func() { printContextHistory(nil, "today") })
+	if !strings.Contains(out, "no context changes") {
+		t.Errorf("expected empty-range message, got:\n%s", out)
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func newContextIsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "is <context>",
+		Short: "Exit 0 if the current context is <context>, 1 otherwise",
+		Long: `Check the current security context, for use in scripts. Exits 0 if it is
+<context> and 1 otherwise, also when the daemon isn't running. Prints nothing
+unless --verbose is given.
+
+Examples:
+  overseer context is office && echo "On the corporate network"`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runIsCommand(cmd, "context", args[0], false)
+		},
+	}
+}
+
+func NewLocationCommand() *cobra.Command {
+	locationCmd := &cobra.Command{
+		Use:   "location",
+		Short: "Inspect the current location",
+		Long:  `Inspect the location the sensors currently match.`,
+	}
+
+	locationCmd.AddCommand(&cobra.Command{
+		Use:   "is <location>",
+		Short: "Exit 0 if the current location is <location>, 1 otherwise",
+		Long: `Check the current location, for use in scripts. Exits 0 if it is <location>
+and 1 otherwise, also when the daemon isn't running. Prints nothing unless
+--verbose is given.
+
+Examples:
+  overseer location is home || echo "Not at home"`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runIsCommand(cmd, "location", args[0], true)
+		},
+	})
+
+	return locationCmd
+}
+
+// runIsCommand exits with the result of comparing the current context or
+// location to name
+func runIsCommand(cmd *cobra.Command, kind, name string, location bool) {
+	verbose, _ := cmd.Flags().GetCount("verbose")
+
+	response, err := daemon.SendCommand("CONTEXT_STATUS 0")
+	if err != nil {
+		if verbose > 0 {
+			fmt.Fprintln(os.Stderr, "Daemon is not running")
+		}
+		os.Exit(1)
+	}
+
+	current := contextStatusValue(response.Data, location)
+	code := isExitCode(current, name)
+	if verbose > 0 {
+		if code == 0 {
+			fmt.Printf("Current %s is %s\n", kind, current)
+		} else {
+			fmt.Printf("Current %s is %s, not %s\n", kind, current, name)
+		}
+	}
+	os.Exit(code)
+}
+
+// contextStatusValue returns the context, or with location the location, of
+// the data of a CONTEXT_STATUS response
+func contextStatusValue(data interface{}, location bool) string {
+	jsonBytes, _ := json.Marshal(data)
+	var status daemon.ContextStatus
+	json.Unmarshal(jsonBytes, &status)
+
+	if location {
+		return status.Location
+	}
+	return status.Context
+}
+
+// isExitCode returns 0 when current is name and 1 otherwise
+func isExitCode(current, name string) int {
+	if current != "" && current == name {
+		return 0
+	}
+	return 1
+}
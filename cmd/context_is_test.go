@@ -0,0 +1,38 @@
+package cmd
+
+import "testing"
+
+func TestContextIsExitCode(t *testing.T) {
+	// Data as decoded from a CONTEXT_STATUS response
+	data := map[string]interface{}{
+		"context":  "office",
+		"location": "hq",
+		"sensors":  map[string]interface{}{"online": "true"},
+	}
+
+	tests := []struct {
+		name     string
+		data     interface{}
+		location bool
+		want     string
+		code     int
+	}{
+		{"current context", data, false, "office", 0},
+		{"other context", data, false, "home", 1},
+		{"context name is case sensitive", data, false, "Office", 1},
+		{"location is not the context", data, false, "hq", 1},
+		{"current location", data, true, "hq", 0},
+		{"other location", data, true, "home", 1},
+		{"no data", nil, false, "office", 1},
+		{"empty name without context", nil, false, "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := contextStatusValue(tt.data, tt.location)
+			if got := isExitCode(current, tt.want); got != tt.code {
+				t.Errorf("isExitCode(%q, %q) = %d, want %d", current, tt.want, got, tt.code)
+			}
+		})
+	}
+}
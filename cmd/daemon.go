@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"fmt"
+	"log/slog"
+	"os"
+
 	"github.com/spf13/cobra"
 	"go.olrik.dev/overseer/internal/daemon"
 )
 
 func NewDaemonCommand() *cobra.Command {
+	var foreground bool
+	var logFormat string
+
 	daemonCmd := &cobra.Command{
 		Use:     "daemon",
 		Aliases: []string{"server"},
@@ -15,13 +22,36 @@ func NewDaemonCommand() *cobra.Command {
 The daemon will be started automatically, so you rarely have to call this directly.
 
 If you need to debug a connection, or just want to have the daemon running in the
-foreground use this command.`,
+foreground use this command.
+
+Use -v to override the configured verbosity for this run only, e.g.
+'overseer daemon -vv' (or --verbose=2). The override survives config reloads.
+
+To run the daemon as a systemd service or in a container, use --foreground. The
+daemon logs, combined with the sensor and state events shown by 'overseer logs',
+are then written to stderr without colors, as text or with --log-format=json as
+JSON lines, and the command exits non-zero if the daemon can't be started.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			d := daemon.New()
-			d.Run()
+			if verbose, err := cmd.Flags().GetCount("verbose"); err == nil {
+				d.SetVerboseOverride(verbose)
+			}
+			if foreground {
+				if err := d.SetForeground(logFormat); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if err := d.Run(); err != nil {
+				slog.Error(fmt.Sprintf("Fatal: %v", err))
+				os.Exit(1)
+			}
 		},
 	}
 
+	daemonCmd.Flags().BoolVar(&foreground, "foreground", false, "Run as a service, writing combined logs to stderr")
+	daemonCmd.Flags().StringVar(&logFormat, "log-format", daemon.LogFormatText, "Format of the --foreground logs (text or json)")
+
 	daemonCmd.Flags().String("overseer-daemon", "", "Process marker for pgrep detection (value is the process tag)")
 	daemonCmd.Flags().MarkHidden("overseer-daemon")
 
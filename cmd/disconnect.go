@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"go.olrik.dev/overseer/internal/daemon"
@@ -34,23 +36,29 @@ func activeHostCompletionFunc(cmd *cobra.Command, args []string, toComplete stri
 
 func NewDisconnectCommand() *cobra.Command {
 	disconnectCmd := &cobra.Command{
-		Use:               "disconnect <alias>",
-		Aliases:           []string{"d"},
-		Short:             "Disconnect SSH tunnel",
-		Long:              `Disconnect SSH tunnel`,
-		Args:              cobra.RangeArgs(0, 1),
+		Use:     "disconnect [alias]...",
+		Aliases: []string{"d"},
+		Short:   "Disconnect SSH tunnels",
+		Long: `Disconnect the given SSH tunnels, or all tunnels when no alias is given.
+
+The command exits non-zero if any of the given tunnels could not be disconnected.`,
+		Args:              cobra.ArbitraryArgs,
 		ValidArgsFunction: activeHostCompletionFunc,
 		Run: func(cmd *cobra.Command, args []string) {
 			daemon.CheckVersionMismatch()
-			if len(args) == 1 {
-				alias := args[0]
-				response, err := daemon.SendCommand("SSH_DISCONNECT " + alias)
+			if len(args) > 0 {
+				failed, err := disconnectTunnels(args, false)
 				if err != nil {
 					// This typically means the daemon wasn't running in the first place.
 					slog.Error("Could not connect to daemon. Nothing to disconnect.")
 					os.Exit(1)
 				}
-				response.LogMessages()
+				if len(failed) > 0 {
+					if len(args) > 1 {
+						slog.Error(fmt.Sprintf("Failed to disconnect %d of %d tunnels: %s", len(failed), len(args), strings.Join(failed, ", ")))
+					}
+					os.Exit(1)
+				}
 			} else {
 				response, err := daemon.SendCommand("SSH_DISCONNECT_ALL")
 				if err != nil {
@@ -66,3 +74,29 @@ func NewDisconnectCommand() *cobra.Command {
 
 	return disconnectCmd
 }
+
+// disconnectTunnels sends SSH_DISCONNECT for each alias in turn, logging the
+// daemon's replies, and returns the aliases it reported an error for. With
+// force, persistent companions are stopped as well. An error is returned if
+// the daemon can't be reached at all.
+func disconnectTunnels(aliases []string, force bool) ([]string, error) {
+	var failed []string
+	for _, alias := range aliases {
+		command := "SSH_DISCONNECT " + alias
+		if force {
+			command += " --force"
+		}
+		response, err := daemon.SendCommand(command)
+		if err != nil {
+			return nil, err
+		}
+		response.LogMessages()
+		for _, msg := range response.Messages {
+			if msg.Status == "ERROR" {
+				failed = append(failed, alias)
+				break
+			}
+		}
+	}
+	return failed, nil
+}
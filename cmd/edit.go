@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lmittmann/tint"
+	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func NewEditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the configuration file and reload the daemon",
+		Long: `Open config.hcl in $VISUAL or $EDITOR (falls back to vi).
+
+When the editor exits, the configuration (including config.d/ fragments) is
+validated. If it has errors you are offered to edit it again; declining
+restores the previous file so a broken configuration is never left behind.
+When the configuration is valid and the daemon is running, it is hot reloaded.`,
+		Args: cobra.NoArgs,
+		// Override the root pre-run: it exits on an invalid config, which is
+		// exactly the situation edit must be able to fix.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			slog.SetDefault(slog.New(
+				tint.NewHandler(os.Stderr, &tint.Options{
+					Level:      slog.LevelDebug,
+					TimeFormat: time.DateTime,
+				}),
+			))
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			configDir, _ := cmd.Flags().GetString("config-path")
+			configFile, _ := cmd.Flags().GetString("config")
+			configDir, configPath := core.ResolveConfigLocation(configDir, configFile)
+
+			// The daemon client needs the config path to find the socket
+			core.Config = core.GetDefaultConfig()
+			core.Config.ConfigPath = configDir
+			core.Config.ConfigFile = configPath
+
+			original, err := os.ReadFile(configPath)
+			if err != nil {
+				slog.Error(fmt.Sprintf("Failed to read config: %v", err))
+				os.Exit(1)
+			}
+
+			err = editUntilValid(configPath, original,
+				runEditor,
+				func() error { return validateConfig(os.Stderr) },
+				func(err error) bool { return askReEdit(os.Stdin, err) },
+			)
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+
+			slog.Info("Configuration is valid")
+			if err := reloadIfRunning(); err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+// editUntilValid opens the editor on path and validates the result. While the
+// configuration is invalid, askRetry decides whether to edit again. If the user
+// gives up, the original content is restored and the validation error returned.
+func editUntilValid(path string, original []byte, edit func(string) error, validate func() error, askRetry func(error) bool) error {
+	for {
+		if err := edit(path); err != nil {
+			return fmt.Errorf("editor failed: %w", err)
+		}
+
+		validationErr := validate()
+		if validationErr == nil {
+			return nil
+		}
+
+		if !askRetry(validationErr) {
+			if err := os.WriteFile(path, original, 0644); err != nil {
+				return fmt.Errorf("configuration is invalid and restoring the original failed: %w", err)
+			}
+			return fmt.Errorf("configuration is invalid, original restored: %w", validationErr)
+		}
+	}
+}
+
+// validateConfig loads the main config file and config.d/ fragments the same way the daemon does.
+// Warnings don't fail validation, they are printed to w so they can be fixed right away.
+func validateConfig(w io.Writer) error {
+	cfg, err := core.LoadConfigDir(core.GetConfigFilePath(), core.GetConfigDPath())
+	if err != nil {
+		return err
+	}
+
+	// Tunnels without a tunnel block are hosts of the SSH config
+	var hosts []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		sshConfig, _ := recursivelyReadAllSSHConfigs(filepath.Join(homeDir, ".ssh", "config"), make(map[string]bool))
+		hosts = extractHostPatterns(sshConfig)
+	}
+	warnings := append(cfg.Warnings, core.ActionTunnelWarnings(cfg, func(alias string) bool {
+		return sshHostMatches(hosts, alias)
+	})...)
+
+	printConfigWarnings(w, warnings)
+	return nil
+}
+
+// extractHostPatterns returns the patterns of the SSH config's Host lines,
+// wildcards included. The catch-all * and negated patterns are left out, as
+// they don't tell whether a host is meant to exist.
+func extractHostPatterns(fullConfig string) []string {
+	var patterns []string
+	for _, line := range strings.Split(fullConfig, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "Host") {
+			continue
+		}
+		for _, pattern := range fields[1:] {
+			if strings.HasPrefix(pattern, "#") {
+				break
+			}
+			if pattern != "*" && !strings.HasPrefix(pattern, "!") {
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	return patterns
+}
+
+// sshHostMatches reports whether alias matches one of the Host patterns
+func sshHostMatches(patterns []string, alias string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, alias); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// printConfigWarnings prints configuration warnings in the same format as validation errors
+func printConfigWarnings(w io.Writer, warnings []core.Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Warning: Configuration has warnings\n")
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  %s\n", warning)
+	}
+}
+
+// reloadIfRunning hot reloads the daemon so a new configuration takes effect.
+// Nothing is done if the daemon isn't running.
+func reloadIfRunning() error {
+	if _, err := daemon.SendCommand("STATUS"); err != nil {
+		slog.Info("Daemon is not running, configuration will be used on next start")
+		return nil
+	}
+	if err := reloadDaemon(false); err != nil {
+		return err
+	}
+	slog.Info("Daemon reloaded successfully (tunnels preserved)")
+	return nil
+}
+
+// askReEdit prints the validation error and asks whether to edit again (default
+// yes). Without a terminal there is nobody to ask, so the answer is no.
+func askReEdit(in io.Reader, validationErr error) bool {
+	fmt.Fprintf(os.Stderr, "Error: Configuration has errors\n  %v\n", validationErr)
+	if !isStdinTerminal() {
+		return false
+	}
+	fmt.Fprint(os.Stderr, "Edit again? [Y/n] ")
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// editorCommand returns the user's preferred editor
+func editorCommand() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+	return "vi"
+}
+
+// runEditor opens path in the user's editor and waits for it to exit.
+// The editor is run through the shell so values like "code --wait" work.
+func runEditor(path string) error {
+	editor := exec.Command("sh", "-c", editorCommand()+` "$1"`, "sh", path)
+	editor.Stdin = os.Stdin
+	editor.Stdout = os.Stdout
+	editor.Stderr = os.Stderr
+	return editor.Run()
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.olrik.dev/overseer/internal/core"
+)
+
+func TestEditUntilValid(t *testing.T) {
+	errInvalid := errors.New("config.hcl:1,1-2: Invalid block definition")
+
+	tests := []struct {
+		name        string
+		editorErr   error
+		validations []error // Result of each successive validation
+		retry       bool
+		wantErr     string
+		wantEdits   int
+		wantAsks    int
+		wantContent string
+	}{
+		{
+			name:        "valid on first edit",
+			validations: []error{nil},
+			wantEdits:   1,
+			wantContent: "edited 1",
+		},
+		{
+			name:        "invalid then valid after re-edit",
+			validations: []error{errInvalid, nil},
+			retry:       true,
+			wantEdits:   2,
+			wantAsks:    1,
+			wantContent: "edited 2",
+		},
+		{
+			name:        "invalid and re-edit declined restores original",
+			validations: []error{errInvalid},
+			retry:       false,
+			wantErr:     "original restored",
+			wantEdits:   1,
+			wantAsks:    1,
+			wantContent: "original",
+		},
+		{
+			name:        "editor failure aborts",
+			editorErr:   errors.New("exit status 1"),
+			wantErr:     "editor failed",
+			wantEdits:   1,
+			wantContent: "edited 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.hcl")
+			original := []byte("original")
+			if err := os.WriteFile(path, original, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			edits, validations, asks := 0, 0, 0
+			edit := func(p string) error {
+				edits++
+				if err := os.WriteFile(p, []byte(fmt.Sprintf("edited %d", edits)), 0644); err != nil {
+					return err
+				}
+				return tt.editorErr
+			}
+			validate := func() error {
+				err := tt.validations[validations]
+				validations++
+				return err
+			}
+			askRetry := func(error) bool {
+				asks++
+				return tt.retry
+			}
+
+			err := editUntilValid(path, original, edit, validate, askRetry)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+			}
+			if edits != tt.wantEdits {
+				t.Errorf("edits = %d, want %d", edits, tt.wantEdits)
+			}
+			if asks != tt.wantAsks {
+				t.Errorf("asks = %d, want %d", asks, tt.wantAsks)
+			}
+
+			content, _ := os.ReadFile(path)
+			if string(content) != tt.wantContent {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestAskReEdit(t *testing.T) {
+	orig := isStdinTerminalFn
+	defer func() { isStdinTerminalFn = orig }()
+
+	tests := []struct {
+		name     string
+		terminal bool
+		input    string
+		want     bool
+	}{
+		{"enter defaults to yes", true, "\n", true},
+		{"explicit yes", true, "y\n", true},
+		{"explicit no", true, "n\n", false},
+		{"eof means no", true, "", false},
+		{"no terminal means no", false, "y\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isStdinTerminalFn = func() bool { return tt.terminal }
+			if got := askReEdit(strings.NewReader(tt.input), errors.New("bad")); got != tt.want {
+				t.Errorf("askReEdit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintConfigWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	printConfigWarnings(&buf, nil)
+	if buf.Len() != 0 {
+		t.Errorf("expected no output without warnings, got %q", buf.String())
+	}
+
+	printConfigWarnings(&buf, []core.Warning{{Message: `context "home" references undefined location "hq"`}})
+	want := "Warning: Configuration has warnings\n  context \"home\" references undefined location \"hq\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printConfigWarnings() = %q, want %q", got, want)
+	}
+}
+
+func TestExtractHostPatterns(t *testing.T) {
+	patterns := extractHostPatterns(`
+Host *
+  ServerAliveInterval 30
+
+Host bastion jump-* !jump-old # comment
+  User admin
+
+Match host db
+  User postgres
+`)
+	want := []string{"bastion", "jump-*"}
+	if !reflect.DeepEqual(patterns, want) {
+		t.Errorf("extractHostPatterns() = %v, want %v", patterns, want)
+	}
+
+	for alias, want := range map[string]bool{"bastion": true, "jump-eu": true, "db": false, "nas": false} {
+		if got := sshHostMatches(patterns, alias); got != want {
+			t.Errorf("sshHostMatches(%q) = %v, want %v", alias, got, want)
+		}
+	}
+}
+
+func TestValidateConfig_UnknownTunnels(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "config"), []byte("Host bastion\n  User admin\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configDir := t.TempDir()
+	config := `
+context "office" {
+  actions {
+    connect = ["bastion", "typo", "corp-*"]
+  }
+}
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.hcl"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = core.GetDefaultConfig()
+	core.Config.ConfigPath = configDir
+
+	var buf bytes.Buffer
+	if err := validateConfig(&buf); err != nil {
+		t.Fatalf("validateConfig() failed: %v", err)
+	}
+	want := "Warning: Configuration has warnings\n  context \"office\": connect references tunnel \"typo\", which is neither a tunnel block nor an SSH host\n"
+	if got := buf.String(); got != want {
+		t.Errorf("validateConfig() printed %q, want %q", got, want)
+	}
+}
+
+func TestValidateConfig_FragmentDefiningNothing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "config.hcl"), []byte("verbose = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(configDir, "config.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fragment := filepath.Join(configDir, "config.d", "settings.hcl")
+	if err := os.WriteFile(fragment, []byte("verbose = 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = core.GetDefaultConfig()
+	core.Config.ConfigPath = configDir
+
+	var buf bytes.Buffer
+	if err := validateConfig(&buf); err != nil {
+		t.Fatalf("validateConfig() failed: %v", err)
+	}
+	if want := fmt.Sprintf("config fragment %s defines nothing", fragment); !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q to be printed, got %q", want, buf.String())
+	}
+}
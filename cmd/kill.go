@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func NewKillCommand() *cobra.Command {
+	killCmd := &cobra.Command{
+		Use:   "kill <alias>...",
+		Short: "Disconnect SSH tunnels and stop all their companions",
+		Long: `Disconnect the given SSH tunnels and stop all their companions, including
+persistent ones that keep running after a normal disconnect.
+
+Also stops the persistent companions of tunnels that are no longer connected.
+The command exits non-zero if any of the given tunnels could not be killed.`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: activeHostCompletionFunc,
+		Run: func(cmd *cobra.Command, args []string) {
+			daemon.CheckVersionMismatch()
+			failed, err := disconnectTunnels(args, true)
+			if err != nil {
+				// This typically means the daemon wasn't running in the first place.
+				slog.Error("Could not connect to daemon. Nothing to kill.")
+				os.Exit(1)
+			}
+			if len(failed) > 0 {
+				if len(args) > 1 {
+					slog.Error(fmt.Sprintf("Failed to kill %d of %d tunnels: %s", len(failed), len(args), strings.Join(failed, ", ")))
+				}
+				os.Exit(1)
+			}
+		},
+	}
+
+	return killCmd
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func NewMetricsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "metrics",
+		Short: "Print the daemon's metrics in the Prometheus text format",
+		Long: `Print the daemon's metrics in the Prometheus text exposition format.
+
+The output can be written to the directory of node_exporter's textfile
+collector, e.g. from a cron job, to have Prometheus scrape it:
+
+  overseer metrics > /var/lib/node_exporter/overseer.prom
+
+Metrics:
+  overseer_reconnect_backoff_seconds  Histogram of the backoff delay before
+                                      each reconnect attempt, per tunnel`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			response, err := daemon.SendCommand("METRICS")
+			if err != nil {
+				slog.Error("Daemon is not running")
+				os.Exit(1)
+			}
+
+			if dataMap, ok := response.Data.(map[string]interface{}); ok {
+				if metrics, ok := dataMap["metrics"].(string); ok {
+					fmt.Print(metrics)
+				}
+			}
+		},
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/core"
 	"go.olrik.dev/overseer/internal/keyring"
 )
 
@@ -34,6 +35,12 @@ Use --stdin to read password from stdin, useful for piping from password manager
 		Run: func(cmd *cobra.Command, args []string) {
 			alias := args[0]
 
+			// A stored password would conflict with the tunnel's password_command
+			if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil && tunnelConfig.PasswordCommand != "" {
+				slog.Error(fmt.Sprintf("Tunnel '%s' gets its password from password_command, remove it from the config to store a password instead", alias))
+				os.Exit(1)
+			}
+
 			var password string
 			var err error
 
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"log/slog"
 	"os"
 
@@ -34,7 +35,10 @@ func NewReconnectCommand() *cobra.Command {
 
 			// Use SSH_RECONNECT to preserve companion attach connections
 			if err := daemon.SendCommandStreaming(command); err != nil {
-				slog.Error("Failed to reconnect", "error", err)
+				// A daemon-reported error has already been logged
+				if !errors.Is(err, daemon.ErrCommandFailed) {
+					slog.Error("Failed to reconnect", "error", err)
+				}
 				os.Exit(1)
 			}
 		},
@@ -1,15 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.olrik.dev/overseer/internal/daemon"
 )
 
 func NewReloadCommand() *cobra.Command {
-	var quiet bool
+	var quiet, status bool
 
 	cmd := &cobra.Command{
 		Use:   "reload",
@@ -26,51 +29,20 @@ zero downtime. This is ideal for upgrading overseer without interrupting
 active connections.
 
 If the hot reload fails (e.g., PIDs can't be validated), tunnels will be
-reconnected automatically based on security context rules.`,
+reconnected automatically based on security context rules.
+
+With --status, nothing is reloaded. Instead the result of the daemon's last
+configuration reload is shown, e.g. why edits to the config aren't taking
+effect. Exits 1 when the last reload failed.`,
 		Args: cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			// Check if daemon is running
-			_, err := daemon.SendCommand("STATUS")
-			if err != nil {
-				if !quiet {
-					slog.Error("Daemon is not running. Use 'overseer start' instead.")
-				}
-				return
-			}
-
-			if !quiet {
-				slog.Info("Reloading daemon (hot reload - tunnels will be preserved)...")
+			if status {
+				os.Exit(showReloadStatus())
 			}
 
-			// Send RELOAD command to trigger state save before shutdown
-			_, err = daemon.SendCommand("RELOAD")
-			if err != nil {
+			if err := reloadDaemon(quiet); err != nil {
 				if !quiet {
-					slog.Error(fmt.Sprintf("Failed to send reload command: %v", err))
-				}
-				return
-			}
-
-			// Wait for daemon to fully stop
-			if err := daemon.WaitForDaemonStop(); err != nil {
-				if !quiet {
-					slog.Warn(fmt.Sprintf("Daemon stop verification failed: %v", err))
-				}
-			}
-
-			// Start new daemon (uses same logic as 'overseer start')
-			daemonCmd, err := daemon.StartDaemon()
-			if err != nil {
-				if !quiet {
-					slog.Error(fmt.Sprintf("Failed to start daemon: %v", err))
-				}
-				return
-			}
-
-			// Wait for daemon to be ready
-			if err := daemon.WaitForDaemon(daemonCmd); err != nil {
-				if !quiet {
-					slog.Error(fmt.Sprintf("Daemon failed to start: %v", err))
+					slog.Error(err.Error())
 				}
 				return
 			}
@@ -82,6 +54,90 @@ reconnected automatically based on security context rules.`,
 	}
 
 	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Suppress output")
+	cmd.Flags().BoolVar(&status, "status", false, "Show the result of the last configuration reload instead of reloading")
 
 	return cmd
 }
+
+// reloadDaemon performs a hot reload: the running daemon saves its tunnel state
+// and exits, and a new daemon is started that adopts the existing tunnels.
+func reloadDaemon(quiet bool) error {
+	// Check if daemon is running
+	if _, err := daemon.SendCommand("STATUS"); err != nil {
+		return fmt.Errorf("Daemon is not running. Use 'overseer start' instead.")
+	}
+
+	if !quiet {
+		slog.Info("Reloading daemon (hot reload - tunnels will be preserved)...")
+	}
+
+	// Send RELOAD command to trigger state save before shutdown
+	if _, err := daemon.SendCommand("RELOAD"); err != nil {
+		return fmt.Errorf("Failed to send reload command: %v", err)
+	}
+
+	// Wait for daemon to fully stop
+	if err := daemon.WaitForDaemonStop(); err != nil {
+		if !quiet {
+			slog.Warn(fmt.Sprintf("Daemon stop verification failed: %v", err))
+		}
+	}
+
+	// Start new daemon (uses same logic as 'overseer start')
+	daemonCmd, err := daemon.StartDaemon()
+	if err != nil {
+		return fmt.Errorf("Failed to start daemon: %v", err)
+	}
+
+	// Wait for daemon to be ready
+	if err := daemon.WaitForDaemon(daemonCmd); err != nil {
+		return fmt.Errorf("Daemon failed to start: %v", err)
+	}
+
+	return nil
+}
+
+// showReloadStatus prints the result of the daemon's last configuration
+// reload and returns the exit code: 1 when it failed or the daemon isn't
+// running
+func showReloadStatus() int {
+	response, err := daemon.SendCommand("RELOAD_STATUS")
+	if err != nil {
+		slog.Error("Daemon is not running")
+		return 1
+	}
+	if response.Data == nil {
+		for _, message := range response.Messages {
+			fmt.Println(message.Message)
+		}
+		return 0
+	}
+
+	jsonBytes, _ := json.Marshal(response.Data)
+	var status daemon.ReloadStatus
+	json.Unmarshal(jsonBytes, &status)
+
+	fmt.Println(formatReloadStatus(status))
+	if !status.Success {
+		return 1
+	}
+	return 0
+}
+
+// formatReloadStatus describes the result of a configuration reload
+func formatReloadStatus(status daemon.ReloadStatus) string {
+	when := status.Time
+	if t, err := time.Parse(time.RFC3339, status.Time); err == nil {
+		when = t.Local().Format("2006-01-02 15:04:05")
+	}
+
+	if status.Success {
+		return fmt.Sprintf("Configuration reloaded at %s from %s", when, status.File)
+	}
+
+	location := status.File
+	if status.Line > 0 {
+		location = fmt.Sprintf("%s:%d,%d", status.File, status.Line, status.Column)
+	}
+	return fmt.Sprintf("Configuration reload failed at %s, the previous configuration is still in use\n  %s: %s", when, location, status.Error)
+}
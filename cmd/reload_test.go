@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func TestFormatReloadStatus(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	when := at.Local().Format("2006-01-02 15:04:05")
+
+	tests := []struct {
+		name   string
+		status daemon.ReloadStatus
+		want   string
+	}{
+		{
+			"success",
+			daemon.ReloadStatus{Time: at.Format(time.RFC3339), Success: true, File: "/cfg/config.hcl"},
+			"Configuration reloaded at " + when + " from /cfg/config.hcl",
+		},
+		{
+			"failure with position",
+			daemon.ReloadStatus{Time: at.Format(time.RFC3339), File: "/cfg/config.d/work.hcl", Line: 4, Column: 7, Error: "Missing expression"},
+			"Configuration reload failed at " + when + ", the previous configuration is still in use\n  /cfg/config.d/work.hcl:4,7: Missing expression",
+		},
+		{
+			"failure without position",
+			daemon.ReloadStatus{Time: at.Format(time.RFC3339), File: "/cfg/config.hcl", Error: "state orchestrator failed"},
+			"Configuration reload failed at " + when + ", the previous configuration is still in use\n  /cfg/config.hcl: state orchestrator failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatReloadStatus(tt.status); got != tt.want {
+				t.Errorf("formatReloadStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
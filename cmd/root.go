@@ -16,6 +16,7 @@ import (
 
 func NewRootCommand() *cobra.Command {
 	var configPath string
+	var configFile string
 	var verbose int
 
 	homeDir, _ := os.UserHomeDir()
@@ -94,7 +95,15 @@ func NewRootCommand() *cobra.Command {
 		&configPath, "config-path", fmt.Sprintf("%s/%s", homeDir, core.BaseDirName),
 		"config path",
 	)
+	rootCmd.PersistentFlags().StringVar(
+		&configFile, "config", "",
+		"config file or directory (overrides --config-path, config.d is read next to it)",
+	)
 	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "more output, repeat for even more")
+	rootCmd.PersistentFlags().DurationVar(
+		&daemon.CommandTimeout, "timeout", daemon.CommandTimeout,
+		"how long to wait for the daemon to respond, 0 to wait forever",
+	)
 
 	rootCmd.AddCommand(
 		NewAskpassCommand(),
@@ -102,15 +111,21 @@ func NewRootCommand() *cobra.Command {
 		NewBackfillCommand(),
 		NewCompanionCommand(),
 		NewCompanionRunCommand(),
+		NewConfigCommand(),
 		NewConnectCommand(),
 		NewDaemonCommand(),
 		NewDisconnectCommand(),
+		NewEditCommand(),
+		NewKillCommand(),
+		NewLocationCommand(),
 		NewLogsCommand(),
+		NewMetricsCommand(),
 		NewPasswordCommand(),
 		NewReconnectCommand(),
 		NewReloadCommand(),
 		NewResetCommand(),
 		NewRestartCommand(),
+		NewSensorsCommand(),
 		NewStartCommand(),
 		NewStatsCommand(),
 		NewStatusCommand(),
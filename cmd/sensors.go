@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func NewSensorsCommand() *cobra.Command {
+	sensorsCmd := &cobra.Command{
+		Use:   "sensors",
+		Short: "Show the current raw value of every sensor",
+		Long: `Display the current value of every sensor known to the daemon, as used
+when matching location and context conditions. Sensors that haven't been read
+yet are shown without a value.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+
+			daemon.EnsureDaemonIsRunning()
+			daemon.CheckVersionMismatch()
+
+			response, err := daemon.SendCommand("SENSORS")
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
+
+			sensors, err := decodeSensors(response)
+			if err != nil {
+				slog.Error(fmt.Sprintf("Failed to decode sensors: %v", err))
+				os.Exit(1)
+			}
+
+			if jsonOutput {
+				out, _ := json.MarshalIndent(sensors, "", "  ")
+				fmt.Println(string(out))
+				return
+			}
+
+			printSensors(os.Stdout, sensors)
+		},
+	}
+
+	sensorsCmd.Flags().Bool("json", false, "Output as JSON")
+
+	return sensorsCmd
+}
+
+// decodeSensors decodes a SENSORS response into sensor name -> value
+func decodeSensors(response daemon.Response) (map[string]string, error) {
+	sensors := make(map[string]string)
+	if response.Data == nil {
+		return sensors, nil
+	}
+
+	jsonBytes, err := json.Marshal(response.Data)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonBytes, &sensors); err != nil {
+		return nil, err
+	}
+	return sensors, nil
+}
+
+// printSensors prints one sensor per line, sorted by name
+func printSensors(w io.Writer, sensors map[string]string) {
+	names := make([]string, 0, len(sensors))
+	nameWidth := 0
+	for name := range sensors {
+		names = append(names, name)
+		nameWidth = max(nameWidth, len(name))
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := sensors[name]
+		if value == "" {
+			value = colorGray + "(not read)" + colorReset
+		}
+		fmt.Fprintf(w, "%-*s  %s\n", nameWidth, name, value)
+	}
+}
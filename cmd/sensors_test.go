@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"go.olrik.dev/overseer/internal/daemon"
+)
+
+func TestDecodeSensors(t *testing.T) {
+	response := daemon.Response{Data: map[string]interface{}{"online": "true", "public_ipv4": ""}}
+
+	sensors, err := decodeSensors(response)
+	if err != nil {
+		t.Fatalf("decodeSensors() error: %v", err)
+	}
+	if len(sensors) != 2 || sensors["online"] != "true" || sensors["public_ipv4"] != "" {
+		t.Errorf("decodeSensors() = %v", sensors)
+	}
+}
+
+func TestPrintSensors(t *testing.T) {
+	var buf bytes.Buffer
+	printSensors(&buf, map[string]string{
+		"public_ipv4": "203.0.113.7",
+		"online":      "true",
+		"env:HOME":    "",
+	})
+
+	want := "env:HOME     " + colorGray + "(not read)" + colorReset + "\n" +
+		"online       true\n" +
+		"public_ipv4  203.0.113.7\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printSensors() = %q, want %q", got, want)
+	}
+}
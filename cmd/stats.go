@@ -1,17 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
 	"net"
 	"os"
-	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/spf13/cobra"
 	"go.olrik.dev/overseer/internal/core"
 	"go.olrik.dev/overseer/internal/db"
 	"go.olrik.dev/overseer/internal/awareness"
+	"go.olrik.dev/overseer/internal/awareness/state"
 )
 
 // ANSI color codes
@@ -31,6 +33,17 @@ const (
 	colorBoldRed   = "\033[1;31m"
 )
 
+// noColor disables ANSI colors in stats output (--no-color or NO_COLOR)
+var noColor bool
+
+// ansiColor returns the escape code, or an empty string when colors are disabled
+func ansiColor(code string) string {
+	if noColor {
+		return ""
+	}
+	return code
+}
+
 // Predefined vibrant 24-bit colors for IPs (easily distinguishable)
 // Avoids cyan, red, green, yellow which are used in UI indicators
 var ipColors = []struct{ r, g, b uint8 }{
@@ -56,8 +69,11 @@ var (
 
 // getIPColor returns a consistent 24-bit color for an IP address
 func getIPColor(ip string) string {
+	if noColor {
+		return ""
+	}
 	if ip == "" || ip == "unknown" {
-		return colorGray
+		return ansiColor(colorGray)
 	}
 
 	// Check cache first
@@ -144,7 +160,23 @@ type IPStats struct {
 	Sessions      []OnlineSession
 	TotalOnline   time.Duration
 	SessionCount  int
-	ShortSessions int // Sessions < 5 minutes
+	ShortSessions int      // Sessions shorter than the short-session threshold
+	Quality       string   // Quality label, set by computeIPQuality
+	Issues        []string // Instability patterns behind the quality label
+}
+
+// QualityThresholds tunes what the quality heuristics consider unstable
+type QualityThresholds struct {
+	ShortSession   time.Duration // Sessions shorter than this count as brief
+	ExcellentAfter time.Duration // A single session lasting this long rates Excellent
+}
+
+// DefaultQualityThresholds returns the thresholds used when no flags are given
+func DefaultQualityThresholds() QualityThresholds {
+	return QualityThresholds{
+		ShortSession:   5 * time.Minute,
+		ExcellentAfter: 4 * time.Hour,
+	}
 }
 
 // getLocationForIP finds the location name that matches a given IP address
@@ -192,8 +224,8 @@ func getLocationForIP(ip string, config *core.Configuration) string {
 					}
 					return location.Name
 				}
-			} else if pattern == ip {
-				// Exact IP match
+			} else if pattern == ip || state.MatchesHostname(parsedIP, pattern) {
+				// Exact IP match, or a hostname resolving to the IP
 				if location.DisplayName != "" {
 					return location.DisplayName
 				}
@@ -208,6 +240,11 @@ func getLocationForIP(ip string, config *core.Configuration) string {
 func NewStatsCommand() *cobra.Command {
 	var sinceStr string
 	var days int
+	var tunnel string
+	var disableColor bool
+	thresholds := DefaultQualityThresholds()
+	var excellentHours float64
+	var qualityJSON bool
 
 	statsCmd := &cobra.Command{
 		Use:     "qa",
@@ -224,18 +261,37 @@ Examples:
   overseer stats -s yesterday -d 2   # Yesterday and today
   overseer stats -d 7                # Last 7 days
   overseer stats -s 2025-12-01       # Just Dec 1st
-  overseer stats -s 2025-12-01 -d 3  # Dec 1-3`,
+  overseer stats -s 2025-12-01 -d 3  # Dec 1-3
+  overseer stats -T my-server -d 7   # Uptime of one tunnel over the last week
+  overseer stats --quality-json      # Quality per IP as JSON, e.g. for alerting`,
 		Args: cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
+			// https://no-color.org: any non-empty NO_COLOR disables colors
+			noColor = disableColor || os.Getenv("NO_COLOR") != ""
+
 			// If -d is specified but -s is not, go backwards from today
 			sinceChanged := cmd.Flags().Changed("since")
 			start, end, label := parseDateRange(sinceStr, days, sinceChanged)
-			runStats(start, end, label)
+			if tunnel != "" {
+				runTunnelStats(tunnel, start, end, label)
+				return
+			}
+			thresholds.ExcellentAfter = time.Duration(excellentHours * float64(time.Hour))
+			if qualityJSON {
+				runQualityJSON(start, end, thresholds)
+				return
+			}
+			runStats(start, end, label, thresholds)
 		},
 	}
 
 	statsCmd.Flags().StringVarP(&sinceStr, "since", "S", "today", "Start date: today, yesterday, or YYYY-MM-DD")
 	statsCmd.Flags().IntVarP(&days, "days", "D", 1, "Number of days to include")
+	statsCmd.Flags().StringVarP(&tunnel, "tunnel", "T", "", "Show uptime, reconnects and MTBF for a single tunnel")
+	statsCmd.Flags().BoolVar(&disableColor, "no-color", false, "Disable colored output (also honors NO_COLOR)")
+	statsCmd.Flags().DurationVar(&thresholds.ShortSession, "short-threshold", thresholds.ShortSession, "Sessions shorter than this count as brief (unstable)")
+	statsCmd.Flags().Float64Var(&excellentHours, "excellent-hours", thresholds.ExcellentAfter.Hours(), "Hours a single session must last to rate Excellent")
+	statsCmd.Flags().BoolVar(&qualityJSON, "quality-json", false, "Print the quality of each IP as JSON")
 
 	return statsCmd
 }
@@ -264,7 +320,7 @@ func parseDateRange(sinceStr string, days int, sinceSpecified bool) (start, end
 		if t, err := time.ParseInLocation("2006-01-02", sinceStr, now.Location()); err == nil {
 			start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
 		} else {
-			fmt.Fprintf(os.Stderr, "%sWarning:%s Invalid date '%s', using today%s\n", colorYellow, colorReset, sinceStr, colorReset)
+			fmt.Fprintf(os.Stderr, "%sWarning:%s Invalid date '%s', using today%s\n", ansiColor(colorYellow), ansiColor(colorReset), sinceStr, ansiColor(colorReset))
 			start = startOfToday
 		}
 	}
@@ -297,36 +353,33 @@ func parseDateRange(sinceStr string, days int, sinceSpecified bool) (start, end
 	return start, end, label
 }
 
-func runStats(start, end time.Time, label string) {
-	// Open database directly
-	homeDir, err := os.UserHomeDir()
+// openStatsDatabase opens the overseer database directly (the daemon need not be running)
+func openStatsDatabase() *db.DB {
+	database, err := db.Open(core.GetDatabasePath())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%sError:%s Failed to get home directory: %v\n", colorRed, colorReset, err)
+		fmt.Fprintf(os.Stderr, "%sError:%s Failed to open database: %v\n", ansiColor(colorRed), ansiColor(colorReset), err)
 		os.Exit(1)
 	}
+	return database
+}
 
-	dbPath := filepath.Join(homeDir, ".config", "overseer", "overseer.db")
-	database, err := db.Open(dbPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%sError:%s Failed to open database: %v\n", colorRed, colorReset, err)
-		os.Exit(1)
-	}
+func runStats(start, end time.Time, label string, thresholds QualityThresholds) {
+	// Open database directly
+	database := openStatsDatabase()
 	defer database.Close()
 
 	// Load config to get location names for IPs
-	configPath := filepath.Join(homeDir, ".config", "overseer", "config.hcl")
-	configDPath := filepath.Join(homeDir, ".config", "overseer", "config.d")
-	config, _ := core.LoadConfigDir(configPath, configDPath) // Ignore error - location names are optional
+	config, _ := core.LoadConfigDir(core.GetConfigFilePath(), core.GetConfigDPath()) // Ignore error - location names are optional
 
 	// Get online and IP sensor changes
 	onlineChanges, ipChanges, err := getSensorChanges(database, start, end)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%sError:%s Failed to query database: %v\n", colorRed, colorReset, err)
+		fmt.Fprintf(os.Stderr, "%sError:%s Failed to query database: %v\n", ansiColor(colorRed), ansiColor(colorReset), err)
 		os.Exit(1)
 	}
 
 	if len(onlineChanges) == 0 {
-		fmt.Printf("%sNo online/offline events found%s\n", colorGray, colorReset)
+		fmt.Printf("%sNo online/offline events found%s\n", ansiColor(colorGray), ansiColor(colorReset))
 		return
 	}
 
@@ -334,25 +387,90 @@ func runStats(start, end time.Time, label string) {
 	sessions := parseOnlineSessions(onlineChanges, ipChanges, start, end)
 
 	// Print header
-	fmt.Printf("%s%sConnectivity Statistics%s (%s)\n\n", colorBold, colorCyan, colorReset, label)
+	fmt.Printf("%s%sConnectivity Statistics%s (%s)\n\n", ansiColor(colorBold), ansiColor(colorCyan), ansiColor(colorReset), label)
 
 	// Print overall summary
 	printSummary(sessions, start, end)
 
 	// Group sessions by IP and print per-network stats
-	ipStats := groupSessionsByIP(sessions, start, end, config)
+	ipStats := groupSessionsByIP(sessions, start, end, config, thresholds)
 	if len(ipStats) > 0 {
-		fmt.Printf("\n%s%sNetwork Quality by IP:%s\n", colorBold, colorWhite, colorReset)
-		printIPStats(ipStats, start, end)
+		fmt.Printf("\n%s%sNetwork Quality by IP:%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset))
+		printIPStats(ipStats, start, end, thresholds)
 	}
 
+	// Print hourly connectivity sparkline for each day
+	fmt.Printf("\n%s%sConnectivity:%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset))
+	printSparklines(onlineRanges(sessions, start, end), start, end)
+
 	// Print sessions grouped by day
-	fmt.Printf("\n%s%sOnline Sessions:%s\n", colorBold, colorWhite, colorReset)
+	fmt.Printf("\n%s%sOnline Sessions:%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset))
 	printSessions(sessions)
 
 	// Print overall network quality assessment
 	fmt.Println()
-	printNetworkQuality(sessions, start, end)
+	printNetworkQuality(sessions, start, end, thresholds)
+}
+
+// ipQualityJSON is the --quality-json representation of an IP's statistics
+type ipQualityJSON struct {
+	IP            string   `json:"ip"`
+	Location      string   `json:"location,omitempty"`
+	Quality       string   `json:"quality"`
+	Issues        []string `json:"issues"`
+	OnlineSeconds int64    `json:"online_seconds"`
+	Sessions      int      `json:"sessions"`
+	ShortSessions int      `json:"short_sessions"`
+}
+
+// runQualityJSON prints the quality of each IP in the range as a JSON array
+func runQualityJSON(start, end time.Time, thresholds QualityThresholds) {
+	database := openStatsDatabase()
+	defer database.Close()
+
+	config, _ := core.LoadConfigDir(core.GetConfigFilePath(), core.GetConfigDPath()) // Ignore error - location names are optional
+
+	ipStats, err := QualityByIP(database, start, end, config, thresholds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%sError:%s Failed to query database: %v\n", ansiColor(colorRed), ansiColor(colorReset), err)
+		os.Exit(1)
+	}
+
+	output := make([]ipQualityJSON, 0, len(ipStats))
+	for _, stats := range ipStats {
+		output = append(output, ipQualityJSON{
+			IP:            stats.IP,
+			Location:      stats.LocationName,
+			Quality:       stats.Quality,
+			Issues:        append([]string{}, stats.Issues...),
+			OnlineSeconds: int64(stats.TotalOnline.Seconds()),
+			Sessions:      stats.SessionCount,
+			ShortSessions: stats.ShortSessions,
+		})
+	}
+	out, _ := json.MarshalIndent(output, "", "  ")
+	fmt.Println(string(out))
+}
+
+// QualityByIP returns the statistics and quality rating of each IP that was
+// online between start and end, ordered by time online
+func QualityByIP(database *db.DB, start, end time.Time, config *core.Configuration, thresholds QualityThresholds) ([]IPStats, error) {
+	onlineChanges, ipChanges, err := getSensorChanges(database, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return computeIPQuality(onlineChanges, ipChanges, start, end, config, thresholds), nil
+}
+
+// computeIPQuality groups the online sessions in the sensor changes by IP and
+// rates each of them
+func computeIPQuality(onlineChanges, ipChanges []db.SensorChange, start, end time.Time, config *core.Configuration, thresholds QualityThresholds) []IPStats {
+	sessions := parseOnlineSessions(onlineChanges, ipChanges, start, end)
+	ipStats := groupSessionsByIP(sessions, start, end, config, thresholds)
+	for i := range ipStats {
+		ipStats[i].Quality, ipStats[i].Issues = assessIPQuality(ipStats[i], thresholds)
+	}
+	return ipStats
 }
 
 // getSensorChanges queries the database for online and IP sensor changes within a date range
@@ -577,7 +695,7 @@ func parseOnlineSessions(onlineChanges, ipChanges []db.SensorChange, start, end
 }
 
 // groupSessionsByIP groups sessions by their IP address
-func groupSessionsByIP(sessions []OnlineSession, start, end time.Time, config *core.Configuration) []IPStats {
+func groupSessionsByIP(sessions []OnlineSession, start, end time.Time, config *core.Configuration, thresholds QualityThresholds) []IPStats {
 	ipMap := make(map[string]*IPStats)
 
 	for _, s := range sessions {
@@ -612,7 +730,7 @@ func groupSessionsByIP(sessions []OnlineSession, start, end time.Time, config *c
 		stats.Sessions = append(stats.Sessions, s)
 		stats.TotalOnline += clippedDuration
 		stats.SessionCount++
-		if clippedDuration < 5*time.Minute {
+		if clippedDuration < thresholds.ShortSession {
 			stats.ShortSessions++
 		}
 	}
@@ -636,7 +754,7 @@ func groupSessionsByIP(sessions []OnlineSession, start, end time.Time, config *c
 }
 
 // countMaxConsecutiveShort returns the maximum streak of consecutive short sessions
-func countMaxConsecutiveShort(sessions []OnlineSession) int {
+func countMaxConsecutiveShort(sessions []OnlineSession, shortSession time.Duration) int {
 	if len(sessions) == 0 {
 		return 0
 	}
@@ -645,7 +763,7 @@ func countMaxConsecutiveShort(sessions []OnlineSession) int {
 	currentStreak := 0
 
 	for _, s := range sessions {
-		if s.Duration < 5*time.Minute {
+		if s.Duration < shortSession {
 			currentStreak++
 			if currentStreak > maxStreak {
 				maxStreak = currentStreak
@@ -659,8 +777,8 @@ func countMaxConsecutiveShort(sessions []OnlineSession) int {
 }
 
 // assessIPQuality determines the quality rating for a network based on session patterns
-// Returns quality label, color, and any issues detected
-func assessIPQuality(stats IPStats) (quality, qualityColor string, issues []string) {
+// Returns quality label and any issues detected
+func assessIPQuality(stats IPStats, thresholds QualityThresholds) (quality string, issues []string) {
 	// Calculate base metrics
 	avgDuration := time.Duration(0)
 	if stats.SessionCount > 0 {
@@ -668,7 +786,7 @@ func assessIPQuality(stats IPStats) (quality, qualityColor string, issues []stri
 	}
 
 	// Count consecutive short sessions - the clearest instability indicator
-	maxConsecutiveShort := countMaxConsecutiveShort(stats.Sessions)
+	maxConsecutiveShort := countMaxConsecutiveShort(stats.Sessions, thresholds.ShortSession)
 
 	// Calculate reconnect rate per hour of online time
 	// (more meaningful than per calendar day)
@@ -688,16 +806,16 @@ func assessIPQuality(stats IPStats) (quality, qualityColor string, issues []stri
 		if len(stats.Sessions) == 1 {
 			singleSessionDuration = stats.Sessions[0].Duration
 		}
-		if singleSessionDuration >= 4*time.Hour {
-			return "Excellent", colorBoldGreen, nil
+		if singleSessionDuration >= thresholds.ExcellentAfter {
+			return "Excellent", nil
 		}
 		if singleSessionDuration >= 1*time.Hour {
-			return "Stable", colorGreen, nil
+			return "Stable", nil
 		}
 		if singleSessionDuration >= 10*time.Minute {
-			return "New", colorWhite, nil
+			return "New", nil
 		}
-		return "New", colorGray, nil
+		return "New", nil
 	}
 
 	// === POOR - Clear instability patterns ===
@@ -705,33 +823,33 @@ func assessIPQuality(stats IPStats) (quality, qualityColor string, issues []stri
 	// Multiple consecutive short sessions is definitive instability
 	if maxConsecutiveShort >= 3 {
 		issues = append(issues, fmt.Sprintf("%d consecutive brief sessions", maxConsecutiveShort))
-		return "Poor", colorBoldRed, issues
+		return "Poor", issues
 	}
 
 	// High reconnect rate with meaningful sample size
 	if stats.SessionCount >= 4 && reconnectsPerHour > 2 {
 		issues = append(issues, fmt.Sprintf("High reconnect rate (%.1f/hr)", reconnectsPerHour))
-		return "Poor", colorBoldRed, issues
+		return "Poor", issues
 	}
 
 	// Many short sessions (absolute count, not percentage)
 	if stats.ShortSessions >= 4 {
 		issues = append(issues, fmt.Sprintf("%d brief sessions", stats.ShortSessions))
-		return "Poor", colorBoldRed, issues
+		return "Poor", issues
 	}
 
 	// === EXCELLENT - Very stable ===
 	if maxConsecutiveShort == 0 &&
 		stats.ShortSessions <= 1 &&
 		avgDuration >= 30*time.Minute {
-		return "Excellent", colorBoldGreen, nil
+		return "Excellent", nil
 	}
 
 	// === GOOD - Mostly stable ===
 	if maxConsecutiveShort <= 1 &&
 		stats.ShortSessions <= 2 &&
 		avgDuration >= 10*time.Minute {
-		return "Good", colorGreen, nil
+		return "Good", nil
 	}
 
 	// === FAIR - Some issues but not terrible ===
@@ -745,11 +863,30 @@ func assessIPQuality(stats IPStats) (quality, qualityColor string, issues []stri
 		issues = append(issues, fmt.Sprintf("Frequent reconnects (%.1f/hr)", reconnectsPerHour))
 	}
 
-	return "Fair", colorYellow, issues
+	return "Fair", issues
+}
+
+// ipQualityColor returns the color a quality label is displayed in. New
+// networks are dimmed until their session has lasted ten minutes.
+func ipQualityColor(stats IPStats, quality string) string {
+	switch quality {
+	case "Excellent":
+		return ansiColor(colorBoldGreen)
+	case "Stable", "Good":
+		return ansiColor(colorGreen)
+	case "Fair":
+		return ansiColor(colorYellow)
+	case "Poor":
+		return ansiColor(colorBoldRed)
+	}
+	if len(stats.Sessions) == 1 && stats.Sessions[0].Duration >= 10*time.Minute {
+		return ansiColor(colorWhite)
+	}
+	return ansiColor(colorGray)
 }
 
 // printIPStats prints statistics for each IP/network
-func printIPStats(ipStats []IPStats, start, end time.Time) {
+func printIPStats(ipStats []IPStats, start, end time.Time, thresholds QualityThresholds) {
 	for _, stats := range ipStats {
 		// Calculate average duration for display
 		avgDuration := time.Duration(0)
@@ -758,42 +895,63 @@ func printIPStats(ipStats []IPStats, start, end time.Time) {
 		}
 
 		// Assess quality using the new logic
-		quality, qualityColor, issues := assessIPQuality(stats)
+		quality, issues := assessIPQuality(stats, thresholds)
+		qualityColor := ipQualityColor(stats, quality)
 
 		// Print IP header with quality dot and optional location name
 		if stats.LocationName != "" {
 			fmt.Printf("\n  %s●%s %s%s%s %s%s%s %s%s%s\n",
-				qualityColor, colorReset,
-				colorBold, stats.LocationName, colorReset,
-				getIPColor(stats.IP), stats.IP, colorReset,
-				qualityColor, quality, colorReset)
+				qualityColor, ansiColor(colorReset),
+				ansiColor(colorBold), stats.LocationName, ansiColor(colorReset),
+				getIPColor(stats.IP), stats.IP, ansiColor(colorReset),
+				qualityColor, quality, ansiColor(colorReset))
 		} else {
 			fmt.Printf("\n  %s●%s %s%s%s %s%s%s\n",
-				qualityColor, colorReset,
-				getIPColor(stats.IP), stats.IP, colorReset,
-				qualityColor, quality, colorReset)
+				qualityColor, ansiColor(colorReset),
+				getIPColor(stats.IP), stats.IP, ansiColor(colorReset),
+				qualityColor, quality, ansiColor(colorReset))
 		}
 
 		// Print stats (use IP's color for consistency)
 		ipColor := getIPColor(stats.IP)
 		fmt.Printf("    Online: %s%s%s  Sessions: %s%d%s  Avg: %s%s%s\n",
-			colorGreen, formatDuration(stats.TotalOnline), colorReset,
-			ipColor, stats.SessionCount, colorReset,
-			ipColor, formatDuration(avgDuration), colorReset)
+			ansiColor(colorGreen), formatDuration(stats.TotalOnline), ansiColor(colorReset),
+			ipColor, stats.SessionCount, ansiColor(colorReset),
+			ipColor, formatDuration(avgDuration), ansiColor(colorReset))
 
 		// Print any issues detected
 		if len(issues) > 0 {
 			for _, issue := range issues {
-				fmt.Printf("    %s⚠ %s%s\n", colorYellow, issue, colorReset)
+				fmt.Printf("    %s⚠ %s%s\n", ansiColor(colorYellow), issue, ansiColor(colorReset))
 			}
 		}
 	}
 }
 
-func printSummary(sessions []OnlineSession, start, end time.Time) {
-	// Calculate total online time by merging overlapping periods within query range
-	// This ensures we never exceed the query period duration
-	type timeRange struct{ start, end time.Time }
+// timeRange is a half-open period of time
+type timeRange struct{ start, end time.Time }
+
+// mergeTimeRanges sorts ranges by start time and merges overlapping or touching ones
+func mergeTimeRanges(ranges []timeRange) []timeRange {
+	sorted := make([]timeRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].start.Before(sorted[j].start)
+	})
+
+	var merged []timeRange
+	for _, r := range sorted {
+		if len(merged) == 0 || r.start.After(merged[len(merged)-1].end) {
+			merged = append(merged, r)
+		} else if r.end.After(merged[len(merged)-1].end) {
+			merged[len(merged)-1].end = r.end
+		}
+	}
+	return merged
+}
+
+// onlineRanges clips sessions to the query period and merges overlapping periods
+func onlineRanges(sessions []OnlineSession, start, end time.Time) []timeRange {
 	var ranges []timeRange
 
 	for _, s := range sessions {
@@ -811,26 +969,14 @@ func printSummary(sessions []OnlineSession, start, end time.Time) {
 		}
 	}
 
-	// Sort ranges by start time
-	for i := 0; i < len(ranges)-1; i++ {
-		for j := i + 1; j < len(ranges); j++ {
-			if ranges[i].start.After(ranges[j].start) {
-				ranges[i], ranges[j] = ranges[j], ranges[i]
-			}
-		}
-	}
+	return mergeTimeRanges(ranges)
+}
 
-	// Merge overlapping ranges and calculate total
+func printSummary(sessions []OnlineSession, start, end time.Time) {
+	// Calculate total online time by merging overlapping periods within query range
+	// This ensures we never exceed the query period duration
 	var totalOnline time.Duration
-	var merged []timeRange
-	for _, r := range ranges {
-		if len(merged) == 0 || r.start.After(merged[len(merged)-1].end) {
-			merged = append(merged, r)
-		} else if r.end.After(merged[len(merged)-1].end) {
-			merged[len(merged)-1].end = r.end
-		}
-	}
-	for _, r := range merged {
+	for _, r := range onlineRanges(sessions, start, end) {
 		totalOnline += r.end.Sub(r.start)
 	}
 
@@ -849,10 +995,10 @@ func printSummary(sessions []OnlineSession, start, end time.Time) {
 	}
 
 	// Print summary box
-	fmt.Printf("%sSummary:%s\n", colorBold, colorReset)
-	fmt.Printf("  Total Online Time:    %s%s%s\n", colorGreen, formatDuration(totalOnline), colorReset)
-	fmt.Printf("  Sessions:             %s%d%s\n", colorWhite, len(sessions), colorReset)
-	fmt.Printf("  Avg Session Duration: %s%s%s\n", colorWhite, formatDuration(avgSessionDuration), colorReset)
+	fmt.Printf("%sSummary:%s\n", ansiColor(colorBold), ansiColor(colorReset))
+	fmt.Printf("  Total Online Time:    %s%s%s\n", ansiColor(colorGreen), formatDuration(totalOnline), ansiColor(colorReset))
+	fmt.Printf("  Sessions:             %s%d%s\n", ansiColor(colorWhite), len(sessions), ansiColor(colorReset))
+	fmt.Printf("  Avg Session Duration: %s%s%s\n", ansiColor(colorWhite), formatDuration(avgSessionDuration), ansiColor(colorReset))
 }
 
 // sessionEntry represents a session or part of a session for display in a day
@@ -867,7 +1013,7 @@ type sessionEntry struct {
 
 func printSessions(sessions []OnlineSession) {
 	if len(sessions) == 0 {
-		fmt.Printf("  %s(no sessions)%s\n", colorGray, colorReset)
+		fmt.Printf("  %s(no sessions)%s\n", ansiColor(colorGray), ansiColor(colorReset))
 		return
 	}
 
@@ -975,13 +1121,13 @@ func printSessions(sessions []OnlineSession) {
 		}
 
 		// Day header with color based on whether it's today
-		dayColor := colorBlue
+		dayColor := ansiColor(colorBlue)
 		if g.date == time.Now().Format("2006-01-02") {
-			dayColor = colorBoldGreen
+			dayColor = ansiColor(colorBoldGreen)
 		}
 		fmt.Printf("\n  %s%s %s%s %s(%s total, %d entries)%s\n",
-			dayColor, dayName, g.date, colorReset,
-			colorGray, formatDuration(dayTotal), len(entries), colorReset)
+			dayColor, dayName, g.date, ansiColor(colorReset),
+			ansiColor(colorGray), formatDuration(dayTotal), len(entries), ansiColor(colorReset))
 
 		// Print entries for this day (oldest first)
 		for j := 0; j < len(entries); j++ {
@@ -993,7 +1139,7 @@ func printSessions(sessions []OnlineSession) {
 			if e.continuesPrev {
 				// Show actual start time from previous day in gray
 				actualStart := s.Start.Local()
-				startTime = fmt.Sprintf("%s%s%s", colorGray, actualStart.Format("15:04:05"), colorReset)
+				startTime = fmt.Sprintf("%s%s%s", ansiColor(colorGray), actualStart.Format("15:04:05"), ansiColor(colorReset))
 			} else {
 				startTime = e.displayStart.Format("15:04:05")
 			}
@@ -1007,10 +1153,10 @@ func printSessions(sessions []OnlineSession) {
 					endTime = e.displayEnd.Format("15:04:05")
 				} else {
 					// We have the actual end time on the next day
-					endTime = fmt.Sprintf("%s%s%s", colorGray, actualEnd.Format("15:04:05"), colorReset)
+					endTime = fmt.Sprintf("%s%s%s", ansiColor(colorGray), actualEnd.Format("15:04:05"), ansiColor(colorReset))
 				}
 			} else if e.isActive {
-				endTime = fmt.Sprintf("%snow%s", colorGreen, colorReset)
+				endTime = fmt.Sprintf("%snow%s", ansiColor(colorGreen), ansiColor(colorReset))
 			} else {
 				endTime = e.displayEnd.Format("15:04:05")
 			}
@@ -1022,43 +1168,43 @@ func printSessions(sessions []OnlineSession) {
 			// Format IP
 			ipStr := ""
 			if s.IP != "" && s.IP != "unknown" {
-				ipStr = fmt.Sprintf(" %s[%s]%s", getIPColor(s.IP), s.IP, colorReset)
+				ipStr = fmt.Sprintf(" %s[%s]%s", getIPColor(s.IP), s.IP, ansiColor(colorReset))
 			}
 
 			// Choose indicator
-			indicator := fmt.Sprintf("%s○%s", colorGray, colorReset)
+			indicator := fmt.Sprintf("%s○%s", ansiColor(colorGray), ansiColor(colorReset))
 			if e.isActive {
-				indicator = fmt.Sprintf("%s●%s", colorGreen, colorReset)
+				indicator = fmt.Sprintf("%s●%s", ansiColor(colorGreen), ansiColor(colorReset))
 			} else if e.continuesPrev || e.continuesNext {
-				indicator = fmt.Sprintf("%s◐%s", colorBlue, colorReset)
+				indicator = fmt.Sprintf("%s◐%s", ansiColor(colorBlue), ansiColor(colorReset))
 			}
 
 			fmt.Printf("    %s %s - %s  %s%s%s%s",
 				indicator,
 				startTime, endTime,
-				durationColor, formatDuration(entryDuration), colorReset,
+				durationColor, formatDuration(entryDuration), ansiColor(colorReset),
 				ipStr)
 
 			if e.continuesPrev || e.continuesNext {
-				fmt.Printf(" %s(%s)%s", colorGray, formatDuration(s.Duration), colorReset)
+				fmt.Printf(" %s(%s)%s", ansiColor(colorGray), formatDuration(s.Duration), ansiColor(colorReset))
 			}
 
 			if e.isActive {
-				fmt.Printf(" %s(active)%s", colorGreen, colorReset)
+				fmt.Printf(" %s(active)%s", ansiColor(colorGreen), ansiColor(colorReset))
 			}
 			fmt.Println()
 		}
 	}
 }
 
-func printNetworkQuality(sessions []OnlineSession, start, end time.Time) {
+func printNetworkQuality(sessions []OnlineSession, start, end time.Time, thresholds QualityThresholds) {
 	if len(sessions) == 0 {
 		return
 	}
 
 	// Calculate metrics for quality assessment (clipped to query period)
 	var totalOnline time.Duration
-	var shortSessions int // Sessions < 5 minutes
+	var shortSessions int // Sessions shorter than the short-session threshold
 	clippedSessions := make([]OnlineSession, 0, len(sessions))
 
 	for _, s := range sessions {
@@ -1076,7 +1222,7 @@ func printNetworkQuality(sessions []OnlineSession, start, end time.Time) {
 			clippedDuration = sessionEnd.Sub(sessionStart)
 		}
 		totalOnline += clippedDuration
-		if clippedDuration < 5*time.Minute {
+		if clippedDuration < thresholds.ShortSession {
 			shortSessions++
 		}
 		// Store clipped session for consecutive analysis
@@ -1100,7 +1246,7 @@ func printNetworkQuality(sessions []OnlineSession, start, end time.Time) {
 	}
 
 	// Count consecutive short sessions
-	maxConsecutiveShort := countMaxConsecutiveShort(clippedSessions)
+	maxConsecutiveShort := countMaxConsecutiveShort(clippedSessions, thresholds.ShortSession)
 
 	// Calculate reconnect rate per hour of online time
 	reconnectsPerHour := float64(0)
@@ -1118,21 +1264,21 @@ func printNetworkQuality(sessions []OnlineSession, start, end time.Time) {
 
 	// === SINGLE SESSION ===
 	if sessionCount == 1 {
-		if avgDuration >= 4*time.Hour {
-			// 4+ hours without disconnection is excellent
+		if avgDuration >= thresholds.ExcellentAfter {
+			// A long session without disconnection is excellent
 			quality = "Excellent"
-			qualityColor = colorBoldGreen
+			qualityColor = ansiColor(colorBoldGreen)
 		} else if avgDuration >= 1*time.Hour {
 			quality = "Stable"
-			qualityColor = colorGreen
+			qualityColor = ansiColor(colorGreen)
 		} else if avgDuration >= 10*time.Minute {
 			quality = "New"
-			qualityColor = colorWhite
+			qualityColor = ansiColor(colorWhite)
 		} else {
 			quality = "New"
-			qualityColor = colorGray
+			qualityColor = ansiColor(colorGray)
 		}
-		fmt.Printf("%s%sOverall Network Quality:%s %s%s%s\n", colorBold, colorWhite, colorReset, qualityColor, quality, colorReset)
+		fmt.Printf("%s%sOverall Network Quality:%s %s%s%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset), qualityColor, quality, ansiColor(colorReset))
 		return
 	}
 
@@ -1142,29 +1288,29 @@ func printNetworkQuality(sessions []OnlineSession, start, end time.Time) {
 	if maxConsecutiveShort >= 3 {
 		issues = append(issues, fmt.Sprintf("%d consecutive brief sessions", maxConsecutiveShort))
 		quality = "Poor"
-		qualityColor = colorBoldRed
+		qualityColor = ansiColor(colorBoldRed)
 	} else if sessionCount >= 4 && reconnectsPerHour > 2 {
 		// High reconnect rate with meaningful sample
 		issues = append(issues, fmt.Sprintf("High reconnect rate (%.1f/hr)", reconnectsPerHour))
 		quality = "Poor"
-		qualityColor = colorBoldRed
+		qualityColor = ansiColor(colorBoldRed)
 	} else if shortSessions >= 4 {
 		// Many short sessions (absolute count)
 		issues = append(issues, fmt.Sprintf("%d brief sessions", shortSessions))
 		quality = "Poor"
-		qualityColor = colorBoldRed
+		qualityColor = ansiColor(colorBoldRed)
 	} else if maxConsecutiveShort == 0 && shortSessions <= 1 && avgDuration >= 30*time.Minute {
 		// === EXCELLENT - Very stable ===
 		quality = "Excellent"
-		qualityColor = colorBoldGreen
+		qualityColor = ansiColor(colorBoldGreen)
 	} else if maxConsecutiveShort <= 1 && shortSessions <= 2 && avgDuration >= 10*time.Minute {
 		// === GOOD - Mostly stable ===
 		quality = "Good"
-		qualityColor = colorGreen
+		qualityColor = ansiColor(colorGreen)
 	} else {
 		// === FAIR - Some issues but not terrible ===
 		quality = "Fair"
-		qualityColor = colorYellow
+		qualityColor = ansiColor(colorYellow)
 
 		// Collect warnings for Fair rating
 		if maxConsecutiveShort >= 2 {
@@ -1178,12 +1324,12 @@ func printNetworkQuality(sessions []OnlineSession, start, end time.Time) {
 		}
 	}
 
-	fmt.Printf("%s%sOverall Network Quality:%s %s%s%s\n", colorBold, colorWhite, colorReset, qualityColor, quality, colorReset)
+	fmt.Printf("%s%sOverall Network Quality:%s %s%s%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset), qualityColor, quality, ansiColor(colorReset))
 
 	if len(issues) > 0 {
-		fmt.Printf("  %sIssues detected:%s\n", colorYellow, colorReset)
+		fmt.Printf("  %sIssues detected:%s\n", ansiColor(colorYellow), ansiColor(colorReset))
 		for _, issue := range issues {
-			fmt.Printf("    %s⚠%s %s\n", colorYellow, colorReset, issue)
+			fmt.Printf("    %s⚠%s %s\n", ansiColor(colorYellow), ansiColor(colorReset), issue)
 		}
 	}
 }
@@ -1218,11 +1364,11 @@ func formatDuration(d time.Duration) string {
 
 func sessionDurationColor(d time.Duration) string {
 	if d < time.Minute {
-		return colorRed // Very short - likely connection issue
+		return ansiColor(colorRed) // Very short - likely connection issue
 	} else if d < 5*time.Minute {
-		return colorYellow // Short session
+		return ansiColor(colorYellow) // Short session
 	} else if d < time.Hour {
-		return colorWhite // Normal session
+		return ansiColor(colorWhite) // Normal session
 	}
-	return colorGreen // Long stable session
+	return ansiColor(colorGreen) // Long stable session
 }
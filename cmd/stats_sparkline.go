@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sparkLevels are the block characters for increasing online fractions
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+const (
+	sparkOffline = '·' // Bucket had no online time
+	sparkFuture  = ' ' // Bucket is outside the measured period
+)
+
+// buildSparkline maps online ranges to one rune per bucket, starting at start.
+// Each rune shows the fraction of the bucket that was online. Buckets that
+// begin at or after end (e.g. later today) are left blank.
+func buildSparkline(ranges []timeRange, start, end time.Time, bucket time.Duration, buckets int) string {
+	var sb strings.Builder
+
+	for i := 0; i < buckets; i++ {
+		bucketStart := start.Add(time.Duration(i) * bucket)
+		bucketEnd := bucketStart.Add(bucket)
+
+		if !bucketStart.Before(end) {
+			sb.WriteRune(sparkFuture)
+			continue
+		}
+		if bucketEnd.After(end) {
+			bucketEnd = end
+		}
+
+		var online time.Duration
+		for _, r := range ranges {
+			overlapStart, overlapEnd := r.start, r.end
+			if overlapStart.Before(bucketStart) {
+				overlapStart = bucketStart
+			}
+			if overlapEnd.After(bucketEnd) {
+				overlapEnd = bucketEnd
+			}
+			if overlapEnd.After(overlapStart) {
+				online += overlapEnd.Sub(overlapStart)
+			}
+		}
+
+		if online <= 0 {
+			sb.WriteRune(sparkOffline)
+			continue
+		}
+
+		// Any online time shows at least the lowest block
+		fraction := float64(online) / float64(bucketEnd.Sub(bucketStart))
+		level := int(fraction*float64(len(sparkLevels))+0.5) - 1
+		if level < 0 {
+			level = 0
+		}
+		if level >= len(sparkLevels) {
+			level = len(sparkLevels) - 1
+		}
+		sb.WriteRune(sparkLevels[level])
+	}
+
+	return sb.String()
+}
+
+// printSparklines prints an hourly sparkline for each day in the period
+func printSparklines(ranges []timeRange, start, end time.Time) {
+	fmt.Printf("  %s%-10s 00    06    12    18   %s\n", ansiColor(colorGray), "", ansiColor(colorReset))
+
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	for day.Before(end) {
+		nextDay := day.AddDate(0, 0, 1)
+		hours := int(nextDay.Sub(day).Hours()) // 23 or 25 on DST changes
+		line := buildSparkline(ranges, day, end, time.Hour, hours)
+		fmt.Printf("  %s%-10s%s %s%s%s\n",
+			ansiColor(colorBlue), day.Format("Mon Jan 2"), ansiColor(colorReset),
+			ansiColor(colorGreen), line, ansiColor(colorReset))
+		day = nextDay
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildSparkline(t *testing.T) {
+	day := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+
+	tests := []struct {
+		name   string
+		ranges []timeRange
+		end    time.Time
+		want   string
+	}{
+		{
+			name:   "offline all day",
+			ranges: nil,
+			end:    day.Add(24 * time.Hour),
+			want:   "························",
+		},
+		{
+			name:   "online all day",
+			ranges: []timeRange{{day, day.Add(24 * time.Hour)}},
+			end:    day.Add(24 * time.Hour),
+			want:   "████████████████████████",
+		},
+		{
+			name: "day with gaps",
+			ranges: []timeRange{
+				{at(0, 0), at(8, 0)},   // Online overnight
+				{at(9, 30), at(12, 0)}, // Half of 09, full 10-11
+				{at(13, 0), at(13, 1)}, // Brief blip still shows
+				{at(14, 0), at(18, 0)},
+			},
+			end:  day.Add(24 * time.Hour),
+			want: "████████·▄██·▁████······",
+		},
+		{
+			name:   "hours after end are blank",
+			ranges: []timeRange{{at(0, 0), at(3, 0)}},
+			end:    at(3, 0),
+			want:   "███                     ",
+		},
+		{
+			name:   "partial bucket at end is measured against elapsed time",
+			ranges: []timeRange{{at(0, 0), at(1, 30)}},
+			end:    at(1, 30),
+			want:   "██                      ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildSparkline(tt.ranges, day, tt.end, time.Hour, 24)
+			if got != tt.want {
+				t.Errorf("buildSparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/db"
+)
+
+// captureStdout returns everything fn writes to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	fn()
+
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestStatsOutput_NoColor(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-6 * time.Hour)
+	sessions := []OnlineSession{
+		{Start: start, End: start.Add(2 * time.Minute), Duration: 2 * time.Minute, IP: "203.0.113.10"},
+		{Start: start.Add(time.Hour), End: end, Duration: end.Sub(start.Add(time.Hour)), IP: "198.51.100.7"},
+	}
+	ipStats := groupSessionsByIP(sessions, start, end, nil, DefaultQualityThresholds())
+
+	render := func() string {
+		return captureStdout(t, func() {
+			printSummary(sessions, start, end)
+			printIPStats(ipStats, start, end, DefaultQualityThresholds())
+			printSparklines(onlineRanges(sessions, start, end), start, end)
+			printSessions(sessions)
+			printNetworkQuality(sessions, start, end, DefaultQualityThresholds())
+		})
+	}
+
+	t.Cleanup(func() { noColor = false })
+
+	noColor = false
+	if out := render(); !strings.Contains(out, "\033[") {
+		t.Fatal("expected escape sequences with colors enabled")
+	}
+
+	noColor = true
+	out := render()
+	if strings.Contains(out, "\033") {
+		t.Errorf("expected no escape sequences with colors disabled, got:\n%q", out)
+	}
+	if !strings.Contains(out, "203.0.113.10") || !strings.Contains(out, "Summary:") {
+		t.Errorf("expected content to be preserved without colors, got:\n%s", out)
+	}
+}
+
+func TestAssessIPQuality_Thresholds(t *testing.T) {
+	start := time.Date(2025, 12, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(8 * time.Hour)
+
+	// Three 3-minute sessions in a row on an intermittent link
+	var sessions []OnlineSession
+	for i := 0; i < 3; i++ {
+		s := start.Add(time.Duration(i) * 10 * time.Minute)
+		sessions = append(sessions, OnlineSession{Start: s, End: s.Add(3 * time.Minute), Duration: 3 * time.Minute, IP: "203.0.113.10"})
+	}
+
+	tests := []struct {
+		name       string
+		thresholds QualityThresholds
+		wantShort  int
+		wantPoor   bool
+	}{
+		{"default 5m threshold", DefaultQualityThresholds(), 3, true},
+		{"2m threshold", QualityThresholds{ShortSession: 2 * time.Minute, ExcellentAfter: 4 * time.Hour}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipStats := groupSessionsByIP(sessions, start, end, nil, tt.thresholds)
+			if len(ipStats) != 1 {
+				t.Fatalf("expected 1 IP, got %d", len(ipStats))
+			}
+			if ipStats[0].ShortSessions != tt.wantShort {
+				t.Errorf("ShortSessions = %d, want %d", ipStats[0].ShortSessions, tt.wantShort)
+			}
+			quality, _ := assessIPQuality(ipStats[0], tt.thresholds)
+			if (quality == "Poor") != tt.wantPoor {
+				t.Errorf("quality = %q, wantPoor %v", quality, tt.wantPoor)
+			}
+		})
+	}
+}
+
+func TestAssessIPQuality_ExcellentAfter(t *testing.T) {
+	start := time.Date(2025, 12, 1, 8, 0, 0, 0, time.UTC)
+	session := OnlineSession{Start: start, End: start.Add(2 * time.Hour), Duration: 2 * time.Hour, IP: "203.0.113.10"}
+	stats := IPStats{IP: session.IP, Sessions: []OnlineSession{session}, TotalOnline: session.Duration, SessionCount: 1}
+
+	if quality, _ := assessIPQuality(stats, DefaultQualityThresholds()); quality != "Stable" {
+		t.Errorf("default thresholds: quality = %q, want Stable", quality)
+	}
+
+	thresholds := DefaultQualityThresholds()
+	thresholds.ExcellentAfter = 90 * time.Minute
+	if quality, _ := assessIPQuality(stats, thresholds); quality != "Excellent" {
+		t.Errorf("90m threshold: quality = %q, want Excellent", quality)
+	}
+}
+
+func TestComputeIPQuality(t *testing.T) {
+	start := time.Date(2025, 12, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(8 * time.Hour)
+	at := func(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+
+	online := func(ts time.Time, value string) db.SensorChange {
+		return db.SensorChange{SensorName: "online", NewValue: value, Timestamp: ts}
+	}
+	onlineChanges := []db.SensorChange{
+		// Home drops out every few minutes
+		online(at(0), "true"), online(at(2), "false"),
+		online(at(5), "true"), online(at(7), "false"),
+		online(at(10), "true"), online(at(12), "false"),
+		// Then a long session at the office
+		online(at(60), "true"), online(at(420), "false"),
+	}
+	ipChanges := []db.SensorChange{
+		{SensorName: "public_ipv4", NewValue: "203.0.113.10", Timestamp: at(0)},
+		{SensorName: "public_ipv4", NewValue: "198.51.100.7", Timestamp: at(60)},
+	}
+
+	ipStats := computeIPQuality(onlineChanges, ipChanges, start, end, nil, DefaultQualityThresholds())
+	if len(ipStats) != 2 {
+		t.Fatalf("expected 2 IPs, got %+v", ipStats)
+	}
+
+	office, home := ipStats[0], ipStats[1]
+	if office.IP != "198.51.100.7" || office.Quality != "Excellent" {
+		t.Errorf("office: got %s rated %q, want 198.51.100.7 rated Excellent", office.IP, office.Quality)
+	}
+	if home.IP != "203.0.113.10" || home.Quality != "Poor" {
+		t.Errorf("home: got %s rated %q, want 203.0.113.10 rated Poor", home.IP, home.Quality)
+	}
+	if len(home.Issues) == 0 {
+		t.Error("expected the issues behind the Poor rating")
+	}
+}
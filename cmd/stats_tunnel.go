@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.olrik.dev/overseer/internal/db"
+)
+
+// TunnelStats holds availability statistics for a single tunnel over a date range
+type TunnelStats struct {
+	Alias      string
+	Sessions   []timeRange      // Connected periods, clipped to the range and merged
+	Events     []db.TunnelEvent // Events within the range (chronological)
+	Period     time.Duration    // Length of the range
+	Uptime     time.Duration    // Total connected time within the range
+	Reconnects int              // Successful automatic reconnects
+	Failures   int              // Unplanned disconnects (excludes manual stops and daemon shutdown)
+
+	FailedAttempts []db.FailureCount // Failed connect and reconnect attempts by kind of failure
+}
+
+// UptimePercent returns the share of the range the tunnel was connected
+func (s TunnelStats) UptimePercent() float64 {
+	if s.Period <= 0 {
+		return 0
+	}
+	return float64(s.Uptime) / float64(s.Period) * 100
+}
+
+// MTBF returns the mean time between failures (0 if the tunnel never failed)
+func (s TunnelStats) MTBF() time.Duration {
+	if s.Failures == 0 {
+		return 0
+	}
+	return s.Uptime / time.Duration(s.Failures)
+}
+
+// tunnelEventIsUp reports whether the event leaves the tunnel connected
+func tunnelEventIsUp(e db.TunnelEvent) bool {
+	switch e.EventType {
+	case "connect", "reconnect", "tunnel_adopted":
+		return true
+	}
+	return false
+}
+
+// tunnelEventIsDown reports whether the event leaves the tunnel disconnected
+func tunnelEventIsDown(e db.TunnelEvent) bool {
+	switch e.EventType {
+	case "disconnect", "manual_disconnect", "context_disconnect", "companion_disconnect",
+		"max_retries_exceeded", "reconnect_abandoned":
+		return true
+	}
+	return false
+}
+
+// tunnelEventIsFailure reports whether the event is an unplanned loss of the tunnel
+func tunnelEventIsFailure(e db.TunnelEvent) bool {
+	if e.EventType != "disconnect" {
+		return false
+	}
+	return e.Reason != "shutdown" && e.Details != "Daemon shutdown"
+}
+
+// computeTunnelStats derives uptime, reconnects and failures for one tunnel.
+// events must be chronological and may include events before start, which
+// determine whether the tunnel was already connected when the range began.
+func computeTunnelStats(alias string, events []db.TunnelEvent, start, end time.Time) TunnelStats {
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+
+	stats := TunnelStats{Alias: alias, Period: end.Sub(start)}
+
+	var ranges []timeRange
+	up := false
+	var upSince time.Time
+
+	for _, e := range events {
+		if !e.Timestamp.Before(end) {
+			break
+		}
+
+		inRange := !e.Timestamp.Before(start)
+		if inRange {
+			stats.Events = append(stats.Events, e)
+			if e.EventType == "reconnect" {
+				stats.Reconnects++
+			}
+			if tunnelEventIsFailure(e) {
+				stats.Failures++
+			}
+		}
+
+		switch {
+		case tunnelEventIsUp(e) && !up:
+			up = true
+			upSince = e.Timestamp
+		case tunnelEventIsDown(e) && up:
+			up = false
+			ranges = append(ranges, timeRange{upSince, e.Timestamp})
+		}
+	}
+	if up {
+		ranges = append(ranges, timeRange{upSince, end})
+	}
+
+	// Clip to the query range
+	var clipped []timeRange
+	for _, r := range ranges {
+		if r.start.Before(start) {
+			r.start = start
+		}
+		if r.end.After(end) {
+			r.end = end
+		}
+		if r.end.After(r.start) {
+			clipped = append(clipped, r)
+		}
+	}
+
+	stats.Sessions = mergeTimeRanges(clipped)
+	for _, r := range stats.Sessions {
+		stats.Uptime += r.end.Sub(r.start)
+	}
+
+	return stats
+}
+
+func runTunnelStats(alias string, start, end time.Time, label string) {
+	database := openStatsDatabase()
+	defer database.Close()
+
+	events, err := database.GetTunnelEvents(alias, end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%sError:%s Failed to query database: %v\n", ansiColor(colorRed), ansiColor(colorReset), err)
+		os.Exit(1)
+	}
+
+	if len(events) == 0 {
+		fmt.Printf("%sNo events found for tunnel '%s'%s\n", ansiColor(colorGray), alias, ansiColor(colorReset))
+		return
+	}
+
+	stats := computeTunnelStats(alias, events, start, end)
+	stats.FailedAttempts, err = database.GetConnectionFailureCounts(alias, start, end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%sError:%s Failed to query database: %v\n", ansiColor(colorRed), ansiColor(colorReset), err)
+		os.Exit(1)
+	}
+
+	printTunnelStats(stats, label)
+}
+
+func printTunnelStats(stats TunnelStats, label string) {
+	fmt.Printf("%s%sTunnel Statistics:%s %s%s%s (%s)\n\n",
+		ansiColor(colorBold), ansiColor(colorCyan), ansiColor(colorReset), ansiColor(colorBold), stats.Alias, ansiColor(colorReset), label)
+
+	uptimeColor := ansiColor(colorBoldGreen)
+	switch pct := stats.UptimePercent(); {
+	case pct < 90:
+		uptimeColor = ansiColor(colorBoldRed)
+	case pct < 99:
+		uptimeColor = ansiColor(colorYellow)
+	}
+
+	mtbf := "n/a (no failures)"
+	if stats.Failures > 0 {
+		mtbf = formatDuration(stats.MTBF())
+	}
+
+	fmt.Printf("%sSummary:%s\n", ansiColor(colorBold), ansiColor(colorReset))
+	fmt.Printf("  Uptime:      %s%.1f%%%s %s(%s of %s)%s\n",
+		uptimeColor, stats.UptimePercent(), ansiColor(colorReset),
+		ansiColor(colorGray), formatDuration(stats.Uptime), formatDuration(stats.Period), ansiColor(colorReset))
+	fmt.Printf("  Reconnects:  %s%d%s\n", ansiColor(colorWhite), stats.Reconnects, ansiColor(colorReset))
+	fmt.Printf("  Failures:    %s%d%s\n", ansiColor(colorWhite), stats.Failures, ansiColor(colorReset))
+	fmt.Printf("  MTBF:        %s%s%s\n", ansiColor(colorWhite), mtbf, ansiColor(colorReset))
+
+	if len(stats.FailedAttempts) > 0 {
+		fmt.Printf("\n%s%sFailed Attempts:%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset))
+		for _, failure := range stats.FailedAttempts {
+			fmt.Printf("  %s%4d%s  %s\n", ansiColor(colorRed), failure.Count, ansiColor(colorReset), failure.Reason)
+		}
+	}
+
+	fmt.Printf("\n%s%sConnected Periods:%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset))
+	if len(stats.Sessions) == 0 {
+		fmt.Printf("  %s(none)%s\n", ansiColor(colorGray), ansiColor(colorReset))
+	}
+	for _, r := range stats.Sessions {
+		duration := r.end.Sub(r.start)
+		fmt.Printf("  %s - %s  %s%s%s\n",
+			r.start.Local().Format("Jan 2 15:04:05"), r.end.Local().Format("Jan 2 15:04:05"),
+			sessionDurationColor(duration), formatDuration(duration), ansiColor(colorReset))
+	}
+
+	fmt.Printf("\n%s%sTimeline:%s\n", ansiColor(colorBold), ansiColor(colorWhite), ansiColor(colorReset))
+	if len(stats.Events) == 0 {
+		fmt.Printf("  %s(no events in range)%s\n", ansiColor(colorGray), ansiColor(colorReset))
+	}
+	for _, e := range stats.Events {
+		eventColor := ansiColor(colorWhite)
+		switch {
+		case tunnelEventIsFailure(e) || e.EventType == "max_retries_exceeded" || e.EventType == "reconnect_abandoned":
+			eventColor = ansiColor(colorRed)
+		case tunnelEventIsUp(e):
+			eventColor = ansiColor(colorGreen)
+		case tunnelEventIsDown(e):
+			eventColor = ansiColor(colorYellow)
+		}
+
+		line := fmt.Sprintf("  %s%s%s %s%s%s",
+			ansiColor(colorGray), e.Timestamp.Local().Format("Jan 2 15:04:05"), ansiColor(colorReset),
+			eventColor, e.EventType, ansiColor(colorReset))
+		if e.Details != "" {
+			line += " - " + e.Details
+		}
+		if e.Reason != "" {
+			line += fmt.Sprintf(" %s(%s)%s", ansiColor(colorGray), e.Reason, ansiColor(colorReset))
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/db"
+)
+
+func TestComputeTunnelStats(t *testing.T) {
+	start := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	at := func(h, m int) time.Time { return start.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+
+	events := []db.TunnelEvent{
+		// Connected since the day before - counts from the start of the range
+		{TunnelAlias: "vpn", EventType: "connect", Timestamp: start.Add(-2 * time.Hour), Reason: "manual"},
+		{TunnelAlias: "vpn", EventType: "disconnect", Timestamp: at(6, 0), Details: "Error: exit status 255"},
+		{TunnelAlias: "vpn", EventType: "reconnect_failed", Timestamp: at(6, 1)},
+		{TunnelAlias: "vpn", EventType: "reconnect", Timestamp: at(7, 0), Reason: "reconnect"},
+		{TunnelAlias: "vpn", EventType: "disconnect", Timestamp: at(12, 0), Details: "Error: exit status 255"},
+		{TunnelAlias: "vpn", EventType: "reconnect", Timestamp: at(13, 0), Reason: "reconnect"},
+		{TunnelAlias: "vpn", EventType: "manual_disconnect", Timestamp: at(18, 0), Reason: "manual"},
+		{TunnelAlias: "vpn", EventType: "connect", Timestamp: at(20, 0), Reason: "context:office"},
+		{TunnelAlias: "vpn", EventType: "disconnect", Timestamp: at(22, 0), Details: "Daemon shutdown", Reason: "shutdown"},
+	}
+
+	stats := computeTunnelStats("vpn", events, start, end)
+
+	// Connected 00-06, 07-12, 13-18, 20-22 = 6 + 5 + 5 + 2 hours
+	if want := 18 * time.Hour; stats.Uptime != want {
+		t.Errorf("Uptime = %v, want %v", stats.Uptime, want)
+	}
+	if len(stats.Sessions) != 4 {
+		t.Errorf("expected 4 connected periods, got %d", len(stats.Sessions))
+	}
+	if got := stats.UptimePercent(); got != 75 {
+		t.Errorf("UptimePercent() = %v, want 75", got)
+	}
+	if stats.Reconnects != 2 {
+		t.Errorf("Reconnects = %d, want 2", stats.Reconnects)
+	}
+	// Manual stop and daemon shutdown are not failures
+	if stats.Failures != 2 {
+		t.Errorf("Failures = %d, want 2", stats.Failures)
+	}
+	if want := 9 * time.Hour; stats.MTBF() != want {
+		t.Errorf("MTBF() = %v, want %v", stats.MTBF(), want)
+	}
+	// The connect from before the range is not part of the timeline
+	if len(stats.Events) != len(events)-1 {
+		t.Errorf("expected %d events in range, got %d", len(events)-1, len(stats.Events))
+	}
+}
+
+func TestComputeTunnelStats_NoFailures(t *testing.T) {
+	start := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	events := []db.TunnelEvent{
+		{TunnelAlias: "vpn", EventType: "connect", Timestamp: start.Add(30 * time.Minute)},
+	}
+
+	stats := computeTunnelStats("vpn", events, start, end)
+
+	if stats.Uptime != 30*time.Minute {
+		t.Errorf("Uptime = %v, want 30m", stats.Uptime)
+	}
+	if stats.MTBF() != 0 {
+		t.Errorf("MTBF() = %v, want 0 without failures", stats.MTBF())
+	}
+}
+
+func TestPrintTunnelStats_FailedAttempts(t *testing.T) {
+	t.Cleanup(func() { noColor = false })
+	noColor = true
+
+	stats := TunnelStats{
+		Alias:  "vpn",
+		Period: time.Hour,
+		FailedAttempts: []db.FailureCount{
+			{Reason: "connection timed out", Count: 12},
+			{Reason: "authentication failed", Count: 1},
+		},
+	}
+
+	out := captureStdout(t, func() { printTunnelStats(stats, "today") })
+
+	for _, want := range []string{"Failed Attempts:", "  12  connection timed out", "   1  authentication failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"os"
@@ -27,9 +28,17 @@ and rules defined in your configuration. Context changes automatically connect o
 		Run: func(cmd *cobra.Command, args []string) {
 			daemon.CheckVersionMismatch()
 
+			plain, _ := cmd.Flags().GetBool("plain")
+			required, _ := cmd.Flags().GetStringSlice("require")
+			checkExit := plain || len(required) > 0
+
 			// Get tunnel status
 			response, err := daemon.SendCommand("STATUS")
 			if err != nil {
+				if checkExit {
+					// Nothing can be connected without a daemon
+					os.Exit(1)
+				}
 				slog.Warn("No active tunnels (daemon is not running).")
 				return
 			}
@@ -43,6 +52,19 @@ and rules defined in your configuration. Context changes automatically connect o
 				return statuses[i].Hostname < statuses[j].Hostname
 			})
 
+			if checkExit {
+				defer func() {
+					if code := statusExitCode(statuses, required); code != 0 {
+						os.Exit(code)
+					}
+				}()
+			}
+
+			if plain {
+				printPlainStatus(os.Stdout, statuses)
+				return
+			}
+
 			// Get context status with event limit
 			eventLimit, _ := cmd.Flags().GetInt("events")
 			contextResponse, err := daemon.SendCommand(fmt.Sprintf("CONTEXT_STATUS %d", eventLimit))
@@ -84,6 +106,9 @@ and rules defined in your configuration. Context changes automatically connect o
 				// Combine tunnel and context status for JSON output
 				output := make(map[string]interface{})
 				output["tunnels"] = statuses
+				if response.Daemon != nil {
+					output["daemon"] = response.Daemon
+				}
 				if err == nil && contextResponse.Data != nil {
 					output["context"] = contextResponse.Data
 				}
@@ -98,10 +123,49 @@ and rules defined in your configuration. Context changes automatically connect o
 	statusCmd.Flags().StringP("format", "F", "text", "Format to use (text/json)")
 	statusCmd.Flags().IntP("events", "E", 20, "Number of recent events to show")
 	statusCmd.Flags().BoolP("resolve", "R", false, "Resolve IPs in jump chain to hostnames via reverse DNS")
+	statusCmd.Flags().Bool("plain", false, "Print one 'alias state pid' line per tunnel and exit non-zero unless all are connected")
+	statusCmd.Flags().StringSlice("require", nil, "Exit non-zero unless these tunnels are connected (instead of all)")
+	statusCmd.RegisterFlagCompletionFunc("require", tunnelCompletionFunc)
+
+	statusCmd.AddCommand(newContextHistoryCommand())
+	statusCmd.AddCommand(newContextIsCommand())
 
 	return statusCmd
 }
 
+// printPlainStatus writes one uncolored "alias state pid" line per tunnel
+func printPlainStatus(w io.Writer, statuses []daemon.DaemonStatus) {
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%s %s %d\n", status.Hostname, status.State, status.Pid)
+	}
+}
+
+// statusExitCode returns 0 when the tunnels that matter are connected and 1
+// otherwise. Without required aliases every tunnel must be connected; with
+// them only those must, and a required tunnel that isn't running fails.
+func statusExitCode(statuses []daemon.DaemonStatus, required []string) int {
+	states := make(map[string]daemon.TunnelState, len(statuses))
+	for _, status := range statuses {
+		states[status.Hostname] = status.State
+	}
+
+	if len(required) == 0 {
+		for _, state := range states {
+			if state != daemon.StateConnected {
+				return 1
+			}
+		}
+		return 0
+	}
+
+	for _, alias := range required {
+		if states[alias] != daemon.StateConnected {
+			return 1
+		}
+	}
+	return 0
+}
+
 // resolveHop takes a "host:port" string and, if host is an IP, attempts
 // reverse DNS resolution. Returns the original string unchanged if host
 // is not an IP or lookup fails.
@@ -206,6 +270,35 @@ func getCompanionMap(response daemon.Response) map[string][]companionInfo {
 	return result
 }
 
+// nextRetryIn returns the time until a reconnecting tunnel's next attempt.
+// The countdown computed by the daemon is preferred as it is immune to clock
+// differences; older daemons only report the timestamp.
+func nextRetryIn(status daemon.DaemonStatus) (time.Duration, bool) {
+	if status.NextRetryIn != nil {
+		return time.Duration(*status.NextRetryIn) * time.Second, true
+	}
+	if status.NextRetry != "" {
+		if nextRetry, err := time.Parse(time.RFC3339, status.NextRetry); err == nil {
+			return time.Until(nextRetry), true
+		}
+	}
+	return 0, false
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "1.5 MiB"
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 // displayTunnels renders the active tunnels section with companion tree display
 func displayTunnels(statuses []daemon.DaemonStatus, companionMap map[string][]companionInfo) {
 	fmt.Println("Active Tunnels:")
@@ -262,15 +355,11 @@ func displayTunnels(statuses []daemon.DaemonStatus, companionMap map[string][]co
 			} else {
 				timeInfo = fmt.Sprintf("%sReconnecting%s", colorGray, colorReset)
 			}
-			if status.NextRetry != "" {
-				nextRetry, err := time.Parse(time.RFC3339, status.NextRetry)
-				if err == nil {
-					timeUntil := time.Until(nextRetry)
-					if timeUntil > 0 {
-						extraInfo = fmt.Sprintf(" %s(next attempt in %s)%s", colorGray, timeUntil.Round(time.Second), colorReset)
-					} else {
-						extraInfo = fmt.Sprintf(" %s(attempting now)%s", colorGray, colorReset)
-					}
+			if timeUntil, ok := nextRetryIn(status); ok {
+				if timeUntil > 0 {
+					extraInfo = fmt.Sprintf(" %s(next attempt in %s)%s", colorGray, timeUntil.Round(time.Second), colorReset)
+				} else {
+					extraInfo = fmt.Sprintf(" %s(attempting now)%s", colorGray, colorReset)
 				}
 			}
 			if status.RetryCount > 0 {
@@ -283,9 +372,16 @@ func displayTunnels(statuses []daemon.DaemonStatus, companionMap map[string][]co
 		if status.TotalReconnects > 0 {
 			reconnectInfo = fmt.Sprintf(", %sReconnects:%s %s%d%s", colorGray, colorReset, colorYellow, status.TotalReconnects, colorReset)
 		}
+		if status.BytesIn != nil && status.BytesOut != nil {
+			reconnectInfo += fmt.Sprintf(", %sTraffic:%s ↓%s ↑%s", colorGray, colorReset, formatBytes(*status.BytesIn), formatBytes(*status.BytesOut))
+		}
 
 		envInfo := formatEnvInfo(status.Environment)
 
+		if status.Description != "" {
+			extraInfo += fmt.Sprintf(" %s- %s%s", colorGray, status.Description, colorReset)
+		}
+
 		fmt.Printf(
 			"  %s%s%s %s%s%s%s %s(PID:%s %d, %s%s%s)%s%s\n",
 			color, icon, colorReset,
@@ -387,9 +483,10 @@ func displayContextBanner(data interface{}) {
 	}
 
 	var status struct {
-		Context  string            `json:"context"`
-		Location string            `json:"location,omitempty"`
-		Sensors  map[string]string `json:"sensors"`
+		Context           string            `json:"context"`
+		Location          string            `json:"location,omitempty"`
+		LocationConflicts []string          `json:"location_conflicts,omitempty"`
+		Sensors           map[string]string `json:"sensors"`
 	}
 
 	if err := json.Unmarshal(jsonData, &status); err != nil {
@@ -434,6 +531,13 @@ func displayContextBanner(data interface{}) {
 	}
 
 	fmt.Println()
+
+	// Overlapping locations make the location depend on evaluation order
+	if len(status.LocationConflicts) > 0 {
+		fmt.Printf("%sWarning: locations %s all match, make their conditions exclusive%s\n",
+			colorYellow, strings.Join(status.LocationConflicts, ", "), colorReset)
+	}
+
 	fmt.Println()
 }
 
@@ -589,6 +693,7 @@ func displayRecentEvents(data interface{}) {
 			TunnelAlias string `json:"tunnel_alias"`
 			EventType   string `json:"event_type"`
 			Details     string `json:"details,omitempty"`
+			Reason      string `json:"reason,omitempty"`
 			Timestamp   string `json:"timestamp"`
 		} `json:"tunnel_events"`
 		DaemonEvents []struct {
@@ -696,6 +801,9 @@ func displayRecentEvents(data interface{}) {
 		if te.Details != "" {
 			eventDesc = fmt.Sprintf("%s - %s", te.EventType, te.Details)
 		}
+		if te.Reason != "" {
+			eventDesc = fmt.Sprintf("%s %s(%s)%s", eventDesc, colorGray, te.Reason, colorReset)
+		}
 
 		// Use orange for companion events, amber for hook events, yellow for tunnel events
 		aliasColor := colorYellow
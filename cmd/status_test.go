@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"bytes"
 	"testing"
+
+	"go.olrik.dev/overseer/internal/daemon"
 )
 
 func TestFormatEnvInfo(t *testing.T) {
@@ -65,3 +68,67 @@ func TestFormatEnvInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes uint64
+		want  string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.bytes); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
+
+func TestStatusExitCode(t *testing.T) {
+	tunnel := func(alias string, state daemon.TunnelState) daemon.DaemonStatus {
+		return daemon.DaemonStatus{Hostname: alias, State: state}
+	}
+
+	tests := []struct {
+		name     string
+		statuses []daemon.DaemonStatus
+		required []string
+		want     int
+	}{
+		{"no tunnels", nil, nil, 0},
+		{"all connected", []daemon.DaemonStatus{tunnel("a", daemon.StateConnected), tunnel("b", daemon.StateConnected)}, nil, 0},
+		{"connecting", []daemon.DaemonStatus{tunnel("a", daemon.StateConnected), tunnel("b", daemon.StateConnecting)}, nil, 1},
+		{"reconnecting", []daemon.DaemonStatus{tunnel("a", daemon.StateReconnecting)}, nil, 1},
+		{"disconnected", []daemon.DaemonStatus{tunnel("a", daemon.StateDisconnected)}, nil, 1},
+		{"required connected", []daemon.DaemonStatus{tunnel("a", daemon.StateConnected), tunnel("b", daemon.StateReconnecting)}, []string{"a"}, 0},
+		{"required reconnecting", []daemon.DaemonStatus{tunnel("a", daemon.StateConnected), tunnel("b", daemon.StateReconnecting)}, []string{"a", "b"}, 1},
+		{"required not running", []daemon.DaemonStatus{tunnel("a", daemon.StateConnected)}, []string{"missing"}, 1},
+		{"required without tunnels", nil, []string{"a"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusExitCode(tt.statuses, tt.required); got != tt.want {
+				t.Errorf("statusExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintPlainStatus(t *testing.T) {
+	var buf bytes.Buffer
+	printPlainStatus(&buf, []daemon.DaemonStatus{
+		{Hostname: "dev-server", State: daemon.StateConnected, Pid: 74917},
+		{Hostname: "gateway", State: daemon.StateReconnecting},
+	})
+
+	want := "dev-server connected 74917\ngateway reconnecting 0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printPlainStatus() = %q, want %q", got, want)
+	}
+}
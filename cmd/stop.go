@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	"log/slog"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -21,6 +23,10 @@ This will gracefully shutdown the daemon, cleaning up all resources.`,
 			daemon.CheckVersionMismatch()
 
 			response, err := daemon.SendCommand("STOP")
+			if errors.Is(err, daemon.ErrDaemonNotResponding) {
+				slog.Error(err.Error())
+				os.Exit(1)
+			}
 			if err != nil {
 				slog.Warn("Daemon is not running")
 				return
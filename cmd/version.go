@@ -41,6 +41,9 @@ func NewVersionCommand() *cobra.Command {
 							slog.Warn(fmt.Sprintf("Version mismatch! Client %s and daemon %s versions differ. Consider restarting the daemon.", clientFormatted, daemonFormatted))
 						}
 					}
+					if sshVersion, ok := dataMap["ssh_version"].(string); ok {
+						fmt.Fprintf(os.Stderr, "SSH client: %s\n", sshVersion)
+					}
 				}
 			}
 		},
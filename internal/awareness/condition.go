@@ -128,6 +128,25 @@ func (c *GroupCondition) String() string {
 	return fmt.Sprintf("%s{%s}", c.Operator, strings.Join(parts, ", "))
 }
 
+// NotCondition negates a condition
+type NotCondition struct {
+	Condition Condition // Condition that must not be satisfied
+}
+
+// Evaluate checks that the negated condition is not satisfied
+func (c *NotCondition) Evaluate(ctx context.Context, sensors map[string]Sensor) (bool, error) {
+	match, err := c.Condition.Evaluate(ctx, sensors)
+	if err != nil {
+		return false, err
+	}
+	return !match, nil
+}
+
+// String returns a string representation of the condition
+func (c *NotCondition) String() string {
+	return fmt.Sprintf("not{%v}", c.Condition)
+}
+
 // NewSensorCondition creates a condition for a string sensor with pattern matching
 func NewSensorCondition(sensorName, pattern string) *SensorCondition {
 	return &SensorCondition{
@@ -161,6 +180,11 @@ func NewAnyCondition(conditions ...Condition) *GroupCondition {
 	}
 }
 
+// NewNotCondition creates a condition that is satisfied when cond is not
+func NewNotCondition(cond Condition) *NotCondition {
+	return &NotCondition{Condition: cond}
+}
+
 // mapConditionKeyToSensor maps condition keys from config to actual sensor names
 // This allows users to use "public_ip" in config while the actual sensor is "public_ipv4"
 func mapConditionKeyToSensor(conditionKey string) string {
@@ -244,6 +268,8 @@ func extractSensorsRecursive(cond Condition, sensors map[string]bool) {
 		for _, child := range c.Conditions {
 			extractSensorsRecursive(child, sensors)
 		}
+	case *NotCondition:
+		extractSensorsRecursive(c.Condition, sensors)
 	}
 }
 
@@ -260,7 +286,8 @@ func ExtractPatternsForSensor(cond Condition, sensorName string) []string {
 	return result
 }
 
-// extractPatternsRecursive is the internal recursive implementation
+// extractPatternsRecursive is the internal recursive implementation. Patterns
+// inside a not condition are left out, they are the values that must not match.
 func extractPatternsRecursive(cond Condition, sensorName string, patterns map[string]bool) {
 	switch c := cond.(type) {
 	case *SensorCondition:
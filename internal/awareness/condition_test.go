@@ -2,6 +2,7 @@ package awareness
 
 import (
 	"context"
+	"fmt"
 	"testing"
 )
 
@@ -637,6 +638,54 @@ func TestGroupCondition_String(t *testing.T) {
 	}
 }
 
+func TestNotCondition(t *testing.T) {
+	// all { online true, any { ssid HomeNet, not { public_ip 10.0.0.0/8 } } }
+	condition := NewAllCondition(
+		NewBooleanCondition("online", true),
+		NewAnyCondition(
+			NewSensorCondition("ssid", "HomeNet"),
+			NewNotCondition(NewSensorCondition("public_ip", "10.0.0.0/8")),
+		),
+	)
+	if want := "all{online=true, any{ssid~HomeNet, not{public_ip~10.0.0.0/8}}}"; fmt.Sprintf("%v", condition) != want {
+		t.Errorf("String() = %v, want %s", condition, want)
+	}
+
+	sensors := func(ip string) map[string]Sensor {
+		return map[string]Sensor{
+			"online":    &MockSensor{name: "online", sensorType: SensorTypeBoolean, value: true},
+			"ssid":      &MockSensor{name: "ssid", sensorType: SensorTypeString, value: "Cafe"},
+			"public_ip": &MockSensor{name: "public_ip", sensorType: SensorTypeString, value: ip},
+		}
+	}
+
+	tests := []struct {
+		name string
+		ip   string
+		want bool
+	}{
+		{"outside the negated range", "203.0.113.5", true},
+		{"inside the negated range", "10.1.2.3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := condition.Evaluate(context.Background(), sensors(tt.ip))
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Errors are passed on rather than negated
+	notBool := NewNotCondition(NewBooleanCondition("ssid", true))
+	if _, err := notBool.Evaluate(context.Background(), sensors("1.2.3.4")); err == nil {
+		t.Error("expected the error of the negated condition")
+	}
+}
+
 func TestSensorCondition_Evaluate_ErrorCases(t *testing.T) {
 	ctx := context.Background()
 
@@ -765,6 +814,14 @@ func TestExtractRequiredSensors(t *testing.T) {
 			),
 			want: map[string]bool{"public_ip": true, "ssid": true},
 		},
+		{
+			name: "negated sensor",
+			cond: NewAllCondition(
+				NewBooleanCondition("online", true),
+				NewNotCondition(NewSensorCondition("ssid", "Cafe")),
+			),
+			want: map[string]bool{"online": true, "ssid": true},
+		},
 	}
 
 	for _, tt := range tests {
@@ -829,6 +886,15 @@ func TestExtractPatternsForSensor(t *testing.T) {
 			sensorName: "online",
 			want:       map[string]bool{},
 		},
+		{
+			name: "negated pattern excluded",
+			cond: NewAllCondition(
+				NewSensorCondition("public_ip", "1.2.3.4"),
+				NewNotCondition(NewSensorCondition("public_ip", "10.0.0.0/8")),
+			),
+			sensorName: "public_ip",
+			want:       map[string]bool{"1.2.3.4": true},
+		},
 	}
 
 	for _, tt := range tests {
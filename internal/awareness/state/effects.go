@@ -1,15 +1,20 @@
 package state
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/template"
 	"time"
 )
 
@@ -18,6 +23,10 @@ type EffectsProcessorConfig struct {
 	// EnvWriters are the file writers for environment exports
 	EnvWriters []EnvWriter
 
+	// ContextEnvWriters maps context names to writers whose files only exist
+	// while that context is active
+	ContextEnvWriters map[string][]EnvWriter
+
 	// TrackedEnvVars are variable names to unset when switching contexts
 	TrackedEnvVars []string
 
@@ -33,6 +42,10 @@ type EffectsProcessorConfig struct {
 	// DatabaseLogger logs transitions to the database
 	DatabaseLogger DatabaseLogger
 
+	// SensorDebounce is how long a sensor value must hold before its change
+	// is logged to the database (0 = log every change)
+	SensorDebounce time.Duration
+
 	// LogStreamer broadcasts events to connected clients
 	LogStreamer *LogStreamer
 
@@ -62,6 +75,10 @@ type EnvWriter interface {
 
 	// Path returns the file path
 	Path() string
+
+	// SetInPlace makes the writer truncate and rewrite the file in place
+	// instead of atomically replacing it
+	SetInPlace(inPlace bool)
 }
 
 // EnvExportData contains the data to export to environment files
@@ -82,6 +99,9 @@ type DatabaseLogger interface {
 	// LogSensorChange logs a sensor value change
 	LogSensorChange(sensor, sensorType, oldValue, newValue string) error
 
+	// LogSensorChangeAt logs a sensor value change that happened at the given time
+	LogSensorChangeAt(sensor, sensorType, oldValue, newValue string, at time.Time) error
+
 	// LogContextChange logs a context/location change
 	LogContextChange(fromContext, toContext, fromLocation, toLocation, trigger string) error
 }
@@ -103,9 +123,14 @@ type EffectsProcessor struct {
 	globalLocationHooks *HooksConfig
 	globalContextHooks  *HooksConfig
 
+	// Holds back sensor changes until they settle
+	sensorChanges *sensorChangeDebouncer
+
 	// Track last IPv4 written to env files (used to avoid race with in-memory state)
 	lastWrittenIPv4 atomic.Value
 
+	contextEnvMu sync.Mutex // Guards config.ContextEnvWriters, which reloads replace
+
 	// Lifecycle
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -133,7 +158,7 @@ func NewEffectsProcessor(transitions <-chan StateTransition, config EffectsProce
 		contextHooks = make(map[string]*HooksConfig)
 	}
 
-	return &EffectsProcessor{
+	ep := &EffectsProcessor{
 		config:              config,
 		logger:              config.Logger,
 		transitions:         transitions,
@@ -145,6 +170,8 @@ func NewEffectsProcessor(transitions <-chan StateTransition, config EffectsProce
 		ctx:                 ctx,
 		cancel:              cancel,
 	}
+	ep.sensorChanges = newSensorChangeDebouncer(config.SensorDebounce, ep.writeSensorChange)
+	return ep
 }
 
 // SetHookEventLogger sets the callback function for logging hook events to the database
@@ -163,6 +190,8 @@ func (ep *EffectsProcessor) Start() {
 func (ep *EffectsProcessor) Stop() {
 	ep.cancel()
 	ep.wg.Wait()
+	// Don't lose a change that was still settling
+	ep.sensorChanges.Flush()
 	ep.logger.Info("Effects processor stopped")
 }
 
@@ -205,7 +234,7 @@ func (ep *EffectsProcessor) processTransition(t StateTransition) {
 	}
 
 	// 4. Write environment files
-	if len(ep.config.EnvWriters) > 0 {
+	if ep.hasEnvWriters() {
 		ep.writeEnvFiles(t)
 	}
 
@@ -324,76 +353,57 @@ func (ep *EffectsProcessor) emitTransitionLogs(t StateTransition) {
 	}
 }
 
-// logToDatabase logs the transition to the database
+// logToDatabase logs the transition to the database. Sensor changes go
+// through the debouncer so flaps shorter than SensorDebounce are collapsed.
 func (ep *EffectsProcessor) logToDatabase(t StateTransition) {
 	start := time.Now()
 
 	// Log online state changes
 	if t.HasChanged("online") {
-		err := ep.config.DatabaseLogger.LogSensorChange(
-			"online",
-			"boolean",
-			fmt.Sprintf("%v", t.From.Online),
-			fmt.Sprintf("%v", t.To.Online),
-		)
-		ep.emitEffectLog("db_log", "online_change", err, time.Since(start))
+		ep.sensorChanges.Record(sensorChange{
+			Effect:     "online_change",
+			Sensor:     "online",
+			SensorType: "boolean",
+			From:       fmt.Sprintf("%v", t.From.Online),
+			To:         fmt.Sprintf("%v", t.To.Online),
+			At:         t.To.Timestamp,
+		})
 	}
 
 	// Log IPv4 changes
 	if t.HasChanged("ipv4") {
-		fromIP := ""
-		toIP := ""
-		if t.From.PublicIPv4 != nil {
-			fromIP = t.From.PublicIPv4.String()
-		}
-		if t.To.PublicIPv4 != nil {
-			toIP = t.To.PublicIPv4.String()
-		}
-		err := ep.config.DatabaseLogger.LogSensorChange(
-			"public_ipv4",
-			"string",
-			fromIP,
-			toIP,
-		)
-		ep.emitEffectLog("db_log", "ipv4_change", err, time.Since(start))
+		ep.sensorChanges.Record(sensorChange{
+			Effect:     "ipv4_change",
+			Sensor:     "public_ipv4",
+			SensorType: "string",
+			From:       ipString(t.From.PublicIPv4),
+			To:         ipString(t.To.PublicIPv4),
+			At:         t.To.Timestamp,
+		})
 	}
 
 	// Log IPv6 changes
 	if t.HasChanged("ipv6") {
-		fromIP := ""
-		toIP := ""
-		if t.From.PublicIPv6 != nil {
-			fromIP = t.From.PublicIPv6.String()
-		}
-		if t.To.PublicIPv6 != nil {
-			toIP = t.To.PublicIPv6.String()
-		}
-		err := ep.config.DatabaseLogger.LogSensorChange(
-			"public_ipv6",
-			"string",
-			fromIP,
-			toIP,
-		)
-		ep.emitEffectLog("db_log", "ipv6_change", err, time.Since(start))
+		ep.sensorChanges.Record(sensorChange{
+			Effect:     "ipv6_change",
+			Sensor:     "public_ipv6",
+			SensorType: "string",
+			From:       ipString(t.From.PublicIPv6),
+			To:         ipString(t.To.PublicIPv6),
+			At:         t.To.Timestamp,
+		})
 	}
 
 	// Log local IPv4 changes
 	if t.HasChanged("local_ipv4") {
-		fromIP := ""
-		toIP := ""
-		if t.From.LocalIPv4 != nil {
-			fromIP = t.From.LocalIPv4.String()
-		}
-		if t.To.LocalIPv4 != nil {
-			toIP = t.To.LocalIPv4.String()
-		}
-		err := ep.config.DatabaseLogger.LogSensorChange(
-			"local_ipv4",
-			"string",
-			fromIP,
-			toIP,
-		)
-		ep.emitEffectLog("db_log", "local_ipv4_change", err, time.Since(start))
+		ep.sensorChanges.Record(sensorChange{
+			Effect:     "local_ipv4_change",
+			Sensor:     "local_ipv4",
+			SensorType: "string",
+			From:       ipString(t.From.LocalIPv4),
+			To:         ipString(t.To.LocalIPv4),
+			At:         t.To.Timestamp,
+		})
 	}
 
 	// Log context/location changes
@@ -410,6 +420,25 @@ func (ep *EffectsProcessor) logToDatabase(t StateTransition) {
 	}
 }
 
+// writeSensorChange persists a settled sensor change
+func (ep *EffectsProcessor) writeSensorChange(change sensorChange) {
+	start := time.Now()
+	at := change.At
+	if at.IsZero() {
+		at = start
+	}
+	err := ep.config.DatabaseLogger.LogSensorChangeAt(change.Sensor, change.SensorType, change.From, change.To, at)
+	ep.emitEffectLog("db_log", change.Effect, err, time.Since(start))
+}
+
+// ipString renders an optional IP, empty when unknown
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
 // writeEnvFiles writes to all configured environment files
 func (ep *EffectsProcessor) writeEnvFiles(t StateTransition) {
 	// Prepare export data
@@ -449,8 +478,19 @@ func (ep *EffectsProcessor) writeEnvFiles(t StateTransition) {
 		CustomEnvironment:   t.To.Environment,
 	}
 
+	ep.contextEnvMu.Lock()
+	defer ep.contextEnvMu.Unlock()
+
+	// Context-scoped files of the context being left go away
+	if t.HasChanged("context") && t.From.Context != "" {
+		for _, writer := range ep.config.ContextEnvWriters[t.From.Context] {
+			ep.removeEnvFile(writer)
+		}
+	}
+
 	// Write to each writer
-	for _, writer := range ep.config.EnvWriters {
+	writers := slices.Concat(ep.config.EnvWriters, ep.config.ContextEnvWriters[t.To.Context])
+	for _, writer := range writers {
 		start := time.Now()
 		err := writer.Write(data, ep.config.TrackedEnvVars)
 		ep.emitEffectLog("env_write", writer.Path(), err, time.Since(start))
@@ -473,6 +513,67 @@ func (ep *EffectsProcessor) writeEnvFiles(t StateTransition) {
 	ep.lastWrittenIPv4.Store(publicIPv4)
 }
 
+// hasEnvWriters reports whether there are any env files to write
+func (ep *EffectsProcessor) hasEnvWriters() bool {
+	ep.contextEnvMu.Lock()
+	defer ep.contextEnvMu.Unlock()
+	return len(ep.config.EnvWriters) > 0 || len(ep.config.ContextEnvWriters) > 0
+}
+
+// SetContextEnvWriters replaces the writers of context-scoped exports, e.g.
+// on reload. The files of exports that are gone, or now belong to another
+// context, are removed.
+func (ep *EffectsProcessor) SetContextEnvWriters(writers map[string][]EnvWriter) {
+	ep.contextEnvMu.Lock()
+	defer ep.contextEnvMu.Unlock()
+
+	for context, previous := range ep.config.ContextEnvWriters {
+		for _, writer := range previous {
+			kept := slices.ContainsFunc(writers[context], func(w EnvWriter) bool {
+				return w.Path() == writer.Path()
+			})
+			if !kept {
+				ep.removeEnvFile(writer)
+			}
+		}
+	}
+	ep.config.ContextEnvWriters = writers
+}
+
+// RemoveContextEnvFiles removes the files of all context-scoped exports, e.g.
+// on shutdown, as they only exist while their context is active
+func (ep *EffectsProcessor) RemoveContextEnvFiles() {
+	ep.contextEnvMu.Lock()
+	defer ep.contextEnvMu.Unlock()
+
+	for _, writers := range ep.config.ContextEnvWriters {
+		for _, writer := range writers {
+			ep.removeEnvFile(writer)
+		}
+	}
+}
+
+// removeEnvFile removes the file of a context-scoped writer
+func (ep *EffectsProcessor) removeEnvFile(writer EnvWriter) {
+	start := time.Now()
+	err := os.Remove(writer.Path())
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	ep.emitEffectLog("env_remove", writer.Path(), err, time.Since(start))
+
+	if err != nil {
+		ep.logger.Error("Failed to remove env file",
+			"writer", writer.Name(),
+			"path", writer.Path(),
+			"error", err)
+	} else {
+		ep.logger.Debug("Removed env file",
+			"writer", writer.Name(),
+			"path", writer.Path())
+	}
+}
+
 // LastWrittenPublicIPv4 returns the IPv4 string most recently written to env files.
 // Returns "" if no write has occurred yet.
 func (ep *EffectsProcessor) LastWrittenPublicIPv4() string {
@@ -610,6 +711,18 @@ func (ep *EffectsProcessor) executeEnterHooks(t StateTransition) {
 	}
 }
 
+// ExecuteReloadHooks runs hooks after the configuration has been reloaded,
+// with the given state in their environment like enter/leave hooks
+func (ep *EffectsProcessor) ExecuteReloadHooks(hooks []HookConfig, state StateSnapshot) {
+	ep.hookExecutor.Execute(ep.ctx, HookEvent{
+		Type:       "reload",
+		TargetType: "context",
+		TargetName: state.Context,
+		Hooks:      hooks,
+		Env:        ep.buildHookEnv(state),
+	})
+}
+
 // buildHookEnv creates the environment map for hook execution
 func (ep *EffectsProcessor) buildHookEnv(state StateSnapshot) map[string]string {
 	env := make(map[string]string)
@@ -666,9 +779,43 @@ func (ep *EffectsProcessor) emitEffectLog(effectName, target string, err error,
 	})
 }
 
+// exportFile is the file behind an export writer. By default it is replaced
+// atomically through a temporary file and a rename, so readers never see a
+// partial write. That gives the path a new inode on every write, which
+// confuses consumers watching the file with inotify; in place, the file is
+// truncated and rewritten instead.
+type exportFile struct {
+	path    string
+	inPlace bool
+}
+
+func (f *exportFile) SetInPlace(inPlace bool) { f.inPlace = inPlace }
+
+// write replaces the content of the file
+func (f *exportFile) write(content []byte) error {
+	if f.inPlace {
+		if err := os.WriteFile(f.path, content, 0o644); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		return nil
+	}
+
+	tempFile := f.path + ".tmp"
+	if err := os.WriteFile(tempFile, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := os.Rename(tempFile, f.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
+
 // DotenvWriter writes environment exports in shell-sourceable format
 type DotenvWriter struct {
-	path string
+	exportFile
 }
 
 // NewDotenvWriter creates a new dotenv writer
@@ -693,7 +840,7 @@ func NewDotenvWriter(path string) (*DotenvWriter, error) {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	return &DotenvWriter{path: absPath}, nil
+	return &DotenvWriter{exportFile{path: absPath}}, nil
 }
 
 func (w *DotenvWriter) Name() string { return "dotenv" }
@@ -767,23 +914,12 @@ func (w *DotenvWriter) Write(data EnvExportData, trackedVars []string) error {
 
 	content := strings.Join(lines, "\n") + "\n"
 
-	// Atomic write
-	tempFile := w.path + ".tmp"
-	if err := os.WriteFile(tempFile, []byte(content), 0o644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
-	}
-
-	if err := os.Rename(tempFile, w.path); err != nil {
-		os.Remove(tempFile)
-		return fmt.Errorf("failed to rename temp file: %w", err)
-	}
-
-	return nil
+	return w.write([]byte(content))
 }
 
 // ContextWriter writes just the context name
 type ContextWriter struct {
-	path string
+	exportFile
 }
 
 func NewContextWriter(path string) (*ContextWriter, error) {
@@ -805,27 +941,19 @@ func NewContextWriter(path string) (*ContextWriter, error) {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	return &ContextWriter{path: absPath}, nil
+	return &ContextWriter{exportFile{path: absPath}}, nil
 }
 
 func (w *ContextWriter) Name() string { return "context" }
 func (w *ContextWriter) Path() string { return w.path }
 
 func (w *ContextWriter) Write(data EnvExportData, _ []string) error {
-	tempFile := w.path + ".tmp"
-	if err := os.WriteFile(tempFile, []byte(data.Context+"\n"), 0o644); err != nil {
-		return err
-	}
-	if err := os.Rename(tempFile, w.path); err != nil {
-		os.Remove(tempFile)
-		return err
-	}
-	return nil
+	return w.write([]byte(data.Context + "\n"))
 }
 
 // LocationWriter writes just the location name
 type LocationWriter struct {
-	path string
+	exportFile
 }
 
 func NewLocationWriter(path string) (*LocationWriter, error) {
@@ -847,30 +975,31 @@ func NewLocationWriter(path string) (*LocationWriter, error) {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	return &LocationWriter{path: absPath}, nil
+	return &LocationWriter{exportFile{path: absPath}}, nil
 }
 
 func (w *LocationWriter) Name() string { return "location" }
 func (w *LocationWriter) Path() string { return w.path }
 
 func (w *LocationWriter) Write(data EnvExportData, _ []string) error {
-	tempFile := w.path + ".tmp"
-	if err := os.WriteFile(tempFile, []byte(data.Location+"\n"), 0o644); err != nil {
-		return err
-	}
-	if err := os.Rename(tempFile, w.path); err != nil {
-		os.Remove(tempFile)
-		return err
-	}
-	return nil
+	return w.write([]byte(data.Location + "\n"))
 }
 
-// PublicIPWriter writes just the public IP
+// PublicIPWriter writes just the public IP, either the preferred one or that
+// of one address family
 type PublicIPWriter struct {
-	path string
+	exportFile
+	family string // "ipv4" or "ipv6", empty for the preferred IP
 }
 
 func NewPublicIPWriter(path string) (*PublicIPWriter, error) {
+	return NewPublicIPFamilyWriter(path, "")
+}
+
+// NewPublicIPFamilyWriter creates a writer for the public IP of family, "ipv4"
+// or "ipv6", regardless of the preferred IP. An empty family writes the
+// preferred IP.
+func NewPublicIPFamilyWriter(path, family string) (*PublicIPWriter, error) {
 	if path[0] == '~' {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -889,20 +1018,100 @@ func NewPublicIPWriter(path string) (*PublicIPWriter, error) {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	return &PublicIPWriter{path: absPath}, nil
+	return &PublicIPWriter{exportFile: exportFile{path: absPath}, family: family}, nil
 }
 
-func (w *PublicIPWriter) Name() string { return "public_ip" }
+func (w *PublicIPWriter) Name() string { return "public_" + cmp.Or(w.family, "ip") }
 func (w *PublicIPWriter) Path() string { return w.path }
 
 func (w *PublicIPWriter) Write(data EnvExportData, _ []string) error {
-	tempFile := w.path + ".tmp"
-	if err := os.WriteFile(tempFile, []byte(data.PublicIP+"\n"), 0o644); err != nil {
-		return err
+	ip := data.PublicIP
+	switch w.family {
+	case "ipv4":
+		ip = data.PublicIPv4
+	case "ipv6":
+		ip = data.PublicIPv6
+	}
+	return w.write([]byte(ip + "\n"))
+}
+
+// TemplateData is what the template of a template export is rendered with.
+// It only holds plain values, so a template can't reach into the daemon.
+type TemplateData struct {
+	Context             string
+	ContextDisplayName  string
+	Location            string
+	LocationDisplayName string
+	PublicIP            string // Preferred IP (ipv4 or ipv6)
+	PublicIPv4          string
+	PublicIPv6          string
+	LocalIP             string
+	Env                 map[string]string // Custom environment of the active location and context
+}
+
+// TemplateWriter renders a Go text/template with the current state
+type TemplateWriter struct {
+	exportFile
+	tmpl *template.Template
+}
+
+// NewTemplateWriter creates a writer that renders the template file at
+// templatePath to path. The template is read and parsed once, here.
+func NewTemplateWriter(templatePath, path string) (*TemplateWriter, error) {
+	source, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
 	}
-	if err := os.Rename(tempFile, w.path); err != nil {
-		os.Remove(tempFile)
-		return err
+	tmpl, err := template.New(filepath.Base(templatePath)).Option("missingkey=zero").Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
-	return nil
+
+	if path[0] == '~' {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(absPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return &TemplateWriter{exportFile: exportFile{path: absPath}, tmpl: tmpl}, nil
+}
+
+func (w *TemplateWriter) Name() string { return "template" }
+func (w *TemplateWriter) Path() string { return w.path }
+
+func (w *TemplateWriter) Write(data EnvExportData, _ []string) error {
+	env := make(map[string]string, len(data.CustomEnvironment))
+	for key, value := range data.CustomEnvironment {
+		env[key] = value
+	}
+
+	var buf bytes.Buffer
+	err := w.tmpl.Execute(&buf, TemplateData{
+		Context:             data.Context,
+		ContextDisplayName:  data.ContextDisplayName,
+		Location:            data.Location,
+		LocationDisplayName: data.LocationDisplayName,
+		PublicIP:            data.PublicIP,
+		PublicIPv4:          data.PublicIPv4,
+		PublicIPv6:          data.PublicIPv6,
+		LocalIP:             data.LocalIPv4,
+		Env:                 env,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return w.write(buf.Bytes())
 }
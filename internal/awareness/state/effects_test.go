@@ -296,6 +296,107 @@ func TestPublicIPWriterCreateAndWrite(t *testing.T) {
 	}
 }
 
+func TestPublicIPFamilyWriters(t *testing.T) {
+	dir := t.TempDir()
+	ip4Path := filepath.Join(dir, "ip4")
+	ip6Path := filepath.Join(dir, "ip6")
+
+	ip4Writer, err := NewPublicIPFamilyWriter(ip4Path, "ipv4")
+	if err != nil {
+		t.Fatalf("NewPublicIPFamilyWriter() error: %v", err)
+	}
+	ip6Writer, err := NewPublicIPFamilyWriter(ip6Path, "ipv6")
+	if err != nil {
+		t.Fatalf("NewPublicIPFamilyWriter() error: %v", err)
+	}
+	if ip4Writer.Name() != "public_ipv4" || ip6Writer.Name() != "public_ipv6" {
+		t.Errorf("Name() = %q, %q, want public_ipv4, public_ipv6", ip4Writer.Name(), ip6Writer.Name())
+	}
+
+	// Each file gets its own family, whichever IP is preferred
+	ep := NewEffectsProcessor(make(chan StateTransition), EffectsProcessorConfig{
+		EnvWriters:  []EnvWriter{ip4Writer, ip6Writer},
+		PreferredIP: "ipv6",
+	})
+	ep.writeEnvFiles(StateTransition{
+		To: StateSnapshot{
+			PublicIPv4: net.ParseIP("203.0.113.7"),
+			PublicIPv6: net.ParseIP("2001:db8::"),
+			Timestamp:  time.Now(),
+		},
+		ChangedFields: []string{"public_ipv4", "public_ipv6"},
+	})
+
+	for path, want := range map[string]string{ip4Path: "203.0.113.7\n", ip6Path: "2001:db8::\n"} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read output: %v", err)
+		}
+		if string(content) != want {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(path), want, string(content))
+		}
+	}
+}
+
+func TestTemplateWriterRender(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "resolv.tmpl")
+	path := filepath.Join(dir, "resolv.conf.overseer")
+
+	source := `# {{.Context}} at {{.LocationDisplayName}} ({{.PublicIP}})
+{{- if eq .Context "office"}}
+nameserver {{index .Env "OFFICE_DNS"}}
+{{- end}}
+search {{or .Env.SEARCH_DOMAIN "local"}}
+`
+	if err := os.WriteFile(templatePath, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewTemplateWriter(templatePath, path)
+	if err != nil {
+		t.Fatalf("NewTemplateWriter() error: %v", err)
+	}
+	if w.Name() != "template" {
+		t.Errorf("Name() = %q, want %q", w.Name(), "template")
+	}
+
+	data := EnvExportData{
+		Context:             "office",
+		Location:            "hq",
+		LocationDisplayName: "Headquarters",
+		PublicIP:            "203.0.113.42",
+		CustomEnvironment:   map[string]string{"OFFICE_DNS": "10.0.0.53"},
+	}
+	if err := w.Write(data, nil); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	want := "# office at Headquarters (203.0.113.42)\nnameserver 10.0.0.53\nsearch local\n"
+	if string(content) != want {
+		t.Errorf("Expected %q, got %q", want, string(content))
+	}
+}
+
+func TestTemplateWriterInvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "broken.tmpl")
+	if err := os.WriteFile(templatePath, []byte("{{.Context"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTemplateWriter(templatePath, filepath.Join(dir, "out")); err == nil || !strings.Contains(err.Error(), "failed to parse template") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	if _, err := NewTemplateWriter(filepath.Join(dir, "missing.tmpl"), filepath.Join(dir, "out")); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+}
+
 // --- Writer Path() coverage ---
 
 func TestContextWriterPath(t *testing.T) {
@@ -662,6 +763,150 @@ func TestEffectsProcessorWriteEnvFiles(t *testing.T) {
 	}
 }
 
+func TestEffectsProcessorContextEnvFiles(t *testing.T) {
+	ch := make(chan StateTransition, 10)
+
+	dir := t.TempDir()
+	officePath := filepath.Join(dir, "office.env")
+	globalPath := filepath.Join(dir, "context.txt")
+
+	officeWriter, _ := NewDotenvWriter(officePath)
+	globalWriter, _ := NewContextWriter(globalPath)
+
+	ep := NewEffectsProcessor(ch, EffectsProcessorConfig{
+		EnvWriters:        []EnvWriter{globalWriter},
+		ContextEnvWriters: map[string][]EnvWriter{"office": {officeWriter}},
+	})
+
+	// Entering the context writes its file
+	ep.writeEnvFiles(StateTransition{
+		From:          StateSnapshot{Context: "home"},
+		To:            StateSnapshot{Context: "office", Timestamp: time.Now()},
+		ChangedFields: []string{"context"},
+	})
+	content, err := os.ReadFile(officePath)
+	if err != nil {
+		t.Fatalf("Expected the context file on enter: %v", err)
+	}
+	if !strings.Contains(string(content), `export OVERSEER_CONTEXT="office"`) {
+		t.Errorf("Expected the context in the file, got %q", string(content))
+	}
+
+	// Leaving removes it, while the global export stays
+	ep.writeEnvFiles(StateTransition{
+		From:          StateSnapshot{Context: "office"},
+		To:            StateSnapshot{Context: "home", Timestamp: time.Now()},
+		ChangedFields: []string{"context"},
+	})
+	if _, err := os.Stat(officePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the context file to be removed on leave, got %v", err)
+	}
+	content, err = os.ReadFile(globalPath)
+	if err != nil {
+		t.Fatalf("Failed to read context file: %v", err)
+	}
+	if string(content) != "home\n" {
+		t.Errorf("Expected context file %q, got %q", "home\n", string(content))
+	}
+}
+
+func TestEffectsProcessorContextEnvFilesReloadAndShutdown(t *testing.T) {
+	tmpDir := t.TempDir()
+	officePath := filepath.Join(tmpDir, "office.env")
+	movedPath := filepath.Join(tmpDir, "moved.env")
+	homePath := filepath.Join(tmpDir, "home.env")
+
+	officeWriter, _ := NewDotenvWriter(officePath)
+	homeWriter, _ := NewDotenvWriter(homePath)
+	ep := NewEffectsProcessor(make(chan StateTransition), EffectsProcessorConfig{
+		ContextEnvWriters: map[string][]EnvWriter{"office": {officeWriter}, "home": {homeWriter}},
+	})
+
+	ep.writeEnvFiles(StateTransition{
+		From:          StateSnapshot{Context: "unknown"},
+		To:            StateSnapshot{Context: "office", Timestamp: time.Now()},
+		ChangedFields: []string{"context"},
+	})
+	if _, err := os.Stat(officePath); err != nil {
+		t.Fatalf("Expected the context file on enter: %v", err)
+	}
+
+	// A reload that keeps the export leaves its file alone
+	keptWriter, _ := NewDotenvWriter(officePath)
+	ep.SetContextEnvWriters(map[string][]EnvWriter{"office": {keptWriter}, "home": {homeWriter}})
+	if _, err := os.Stat(officePath); err != nil {
+		t.Errorf("Expected a kept export's file to stay on reload: %v", err)
+	}
+
+	// A reload that moves the export elsewhere removes the old file
+	movedWriter, _ := NewDotenvWriter(movedPath)
+	ep.SetContextEnvWriters(map[string][]EnvWriter{"office": {movedWriter}})
+	if _, err := os.Stat(officePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the file of a removed export to be removed on reload, got %v", err)
+	}
+
+	ep.writeEnvFiles(StateTransition{
+		From:          StateSnapshot{Context: "office"},
+		To:            StateSnapshot{Context: "office", Timestamp: time.Now()},
+		ChangedFields: []string{"location"},
+	})
+	if _, err := os.Stat(movedPath); err != nil {
+		t.Fatalf("Expected the moved export to be written: %v", err)
+	}
+
+	// Shutdown removes the files of the active context
+	ep.RemoveContextEnvFiles()
+	if _, err := os.Stat(movedPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the context file to be removed on shutdown, got %v", err)
+	}
+}
+
+func TestWriterWriteModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		inPlace  bool
+		sameFile bool
+	}{
+		{"atomic replaces the file", false, false},
+		{"in place keeps the file", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "context.txt")
+			writer, _ := NewContextWriter(path)
+			writer.SetInPlace(tt.inPlace)
+
+			if err := writer.Write(EnvExportData{Context: "home"}, nil); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			before, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Failed to stat file: %v", err)
+			}
+
+			if err := writer.Write(EnvExportData{Context: "office"}, nil); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			after, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Failed to stat file: %v", err)
+			}
+
+			if os.SameFile(before, after) != tt.sameFile {
+				t.Errorf("Expected same inode=%v after rewriting", tt.sameFile)
+			}
+			content, _ := os.ReadFile(path)
+			if string(content) != "office\n" {
+				t.Errorf("Expected %q, got %q", "office\n", string(content))
+			}
+			if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+				t.Error("Expected no temp file to be left behind")
+			}
+		})
+	}
+}
+
 // --- Writer overwrite behavior ---
 
 func TestWritersOverwriteExistingContent(t *testing.T) {
@@ -0,0 +1,153 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How hostnames in IP conditions (e.g. dynamic DNS names) are resolved
+const (
+	hostnameLookupTimeout = 2 * time.Second  // Give up on a lookup after this long
+	hostnameCacheTTL      = 60 * time.Second // Reuse a resolved hostname for this long
+)
+
+// hostnameResolver resolves hostnames used as IP patterns and caches the
+// result briefly, as conditions are evaluated on every sensor reading.
+// Lookups happen in the background so evaluating conditions never waits for
+// DNS, and the listeners are told when a hostname's addresses change. When a
+// lookup fails the last known addresses are used, if any.
+type hostnameResolver struct {
+	lookup  func(ctx context.Context, host string) ([]net.IP, error)
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	cache     map[string]resolvedHostname
+	inflight  map[string]bool // Hosts being looked up
+	listeners map[int]func()
+	nextID    int
+
+	refreshes sync.WaitGroup // Lookups in progress
+}
+
+type resolvedHostname struct {
+	ips      []net.IP
+	resolved time.Time // When the hostname was last looked up, successfully or not
+}
+
+func newHostnameResolver(lookup func(ctx context.Context, host string) ([]net.IP, error)) *hostnameResolver {
+	return &hostnameResolver{
+		lookup:    lookup,
+		timeout:   hostnameLookupTimeout,
+		ttl:       hostnameCacheTTL,
+		cache:     make(map[string]resolvedHostname),
+		inflight:  make(map[string]bool),
+		listeners: make(map[int]func()),
+	}
+}
+
+// hostnames is the resolver used when matching conditions
+var hostnames = newHostnameResolver(func(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+})
+
+// onChange registers a function called when the addresses of a hostname
+// change, so conditions can be evaluated again. It returns a function that
+// removes it.
+func (r *hostnameResolver) onChange(listener func()) (remove func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.nextID
+	r.nextID++
+	r.listeners[id] = listener
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.listeners, id)
+	}
+}
+
+// resolve returns the last known addresses of host without waiting for DNS.
+// A hostname that isn't cached or whose entry has expired is looked up in the
+// background, until then it has no or its previous addresses.
+func (r *hostnameResolver) resolve(host string) []net.IP {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cached, exists := r.cache[host]
+	if (!exists || time.Since(cached.resolved) >= r.ttl) && !r.inflight[host] {
+		r.inflight[host] = true
+		r.refreshes.Go(func() { r.refresh(host) })
+	}
+	return cached.ips
+}
+
+// refresh looks up host and caches its addresses, telling the listeners when
+// they changed
+func (r *hostnameResolver) refresh(host string) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	ips, err := r.lookup(ctx, host)
+
+	r.mu.Lock()
+	previous := r.cache[host].ips
+	if err != nil {
+		// Keep matching on the last known addresses rather than flapping
+		// the location on a transient DNS failure. The failure is cached
+		// too, so a name that doesn't resolve isn't looked up on every
+		// reading.
+		slog.Debug("Failed to resolve hostname in condition", "host", host, "error", err)
+		ips = previous
+	}
+	r.cache[host] = resolvedHostname{ips: ips, resolved: time.Now()}
+	delete(r.inflight, host)
+	listeners := slices.Collect(maps.Values(r.listeners))
+	r.mu.Unlock()
+
+	if !slices.EqualFunc(previous, ips, net.IP.Equal) {
+		for _, listener := range listeners {
+			listener()
+		}
+	}
+}
+
+// matches reports whether ip is one of the addresses host resolves to
+func (r *hostnameResolver) matches(ip net.IP, host string) bool {
+	for _, resolved := range r.resolve(host) {
+		if resolved.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHostnamePattern reports whether an IP pattern is a hostname rather than
+// an IP address, CIDR range or wildcard
+func isHostnamePattern(pattern string) bool {
+	if pattern == "" || net.ParseIP(pattern) != nil || strings.ContainsAny(pattern, "/*") {
+		return false
+	}
+	for _, r := range pattern {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesHostname reports whether ip is one of the addresses pattern resolves
+// to, when pattern is a hostname. Lookups happen in the background, time out
+// after a few seconds and are cached briefly.
+func MatchesHostname(ip net.IP, pattern string) bool {
+	if ip == nil || !isHostnamePattern(pattern) {
+		return false
+	}
+	return hostnames.matches(ip, pattern)
+}
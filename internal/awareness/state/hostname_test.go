@@ -0,0 +1,258 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubResolver answers lookups from a fixed table and counts them
+type stubResolver struct {
+	hosts map[string][]net.IP
+	err   error
+	calls int
+}
+
+func (s *stubResolver) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	ips, exists := s.hosts[host]
+	if !exists {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+// useStubResolver replaces the resolver used by conditions for the test
+func useStubResolver(t *testing.T, stub *stubResolver) *hostnameResolver {
+	t.Helper()
+	old := hostnames
+	hostnames = newHostnameResolver(stub.lookup)
+	t.Cleanup(func() { hostnames = old })
+	return hostnames
+}
+
+func TestIsHostnamePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"home.dyndns.org", true},
+		{"localhost", true},
+		{"203.0.113.42", false},
+		{"2001:db8::1", false},
+		{"198.51.100.0/24", false},
+		{"192.168.*", false},
+		{"", false},
+		{"not a host", false},
+	}
+	for _, tt := range tests {
+		if got := isHostnamePattern(tt.pattern); got != tt.want {
+			t.Errorf("isHostnamePattern(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSensorCondition_Hostname(t *testing.T) {
+	r := useStubResolver(t, &stubResolver{hosts: map[string][]net.IP{
+		"home.dyndns.org": {net.ParseIP("203.0.113.42")},
+	}})
+
+	cond := NewSensorCondition("public_ipv4", "home.dyndns.org")
+	readings := map[string]SensorReading{
+		"public_ipv4": {Sensor: "public_ipv4", IP: net.ParseIP("203.0.113.42")},
+	}
+
+	// The first evaluation doesn't wait for the lookup
+	if cond.Evaluate(readings, true) {
+		t.Error("expected no match before the hostname is resolved")
+	}
+	r.refreshes.Wait()
+	if !cond.Evaluate(readings, true) {
+		t.Error("expected the IP the hostname resolves to to match")
+	}
+
+	readings["public_ipv4"] = SensorReading{Sensor: "public_ipv4", IP: net.ParseIP("198.51.100.1")}
+	if cond.Evaluate(readings, true) {
+		t.Error("expected another IP not to match")
+	}
+
+	unknown := NewSensorCondition("public_ipv4", "gone.dyndns.org")
+	unknown.Evaluate(readings, true)
+	r.refreshes.Wait()
+	if unknown.Evaluate(readings, true) {
+		t.Error("expected a hostname that doesn't resolve not to match")
+	}
+}
+
+func TestHostnameResolver_Cache(t *testing.T) {
+	stub := &stubResolver{hosts: map[string][]net.IP{
+		"home.dyndns.org": {net.ParseIP("203.0.113.42")},
+	}}
+	r := useStubResolver(t, stub)
+	ip := net.ParseIP("203.0.113.42")
+
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	for range 3 {
+		if !r.matches(ip, "home.dyndns.org") {
+			t.Fatal("expected a match")
+		}
+	}
+	r.refreshes.Wait()
+	if stub.calls != 1 {
+		t.Errorf("expected 1 lookup while cached, got %d", stub.calls)
+	}
+
+	// Once expired, the hostname is resolved again and the previous
+	// addresses are used meanwhile
+	r.ttl = 0
+	if !r.matches(ip, "home.dyndns.org") {
+		t.Error("expected the previous addresses to match while resolving again")
+	}
+	r.refreshes.Wait()
+	if stub.calls != 2 {
+		t.Errorf("expected a new lookup after the cache expired, got %d lookups", stub.calls)
+	}
+}
+
+func TestHostnameResolver_FallbackOnFailure(t *testing.T) {
+	stub := &stubResolver{hosts: map[string][]net.IP{
+		"home.dyndns.org": {net.ParseIP("203.0.113.42")},
+	}}
+	r := useStubResolver(t, stub)
+	r.ttl = 0
+	ip := net.ParseIP("203.0.113.42")
+
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	if !r.matches(ip, "home.dyndns.org") {
+		t.Fatal("expected a match")
+	}
+	r.refreshes.Wait()
+
+	// DNS goes away: the last known addresses still match
+	stub.err = errors.New("server misbehaving")
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	if !r.matches(ip, "home.dyndns.org") {
+		t.Error("expected the last known addresses to be used when the lookup fails")
+	}
+	r.refreshes.Wait()
+}
+
+func TestHostnameResolver_CachesFailures(t *testing.T) {
+	stub := &stubResolver{hosts: map[string][]net.IP{}}
+	r := useStubResolver(t, stub)
+	ip := net.ParseIP("203.0.113.42")
+
+	for range 3 {
+		if r.matches(ip, "gone.dyndns.org") {
+			t.Fatal("expected a hostname that doesn't resolve not to match")
+		}
+		r.refreshes.Wait()
+	}
+	if stub.calls != 1 {
+		t.Errorf("expected a failed lookup to be cached, got %d lookups", stub.calls)
+	}
+}
+
+func TestHostnameResolver_NotifiesOnChange(t *testing.T) {
+	stub := &stubResolver{hosts: map[string][]net.IP{
+		"home.dyndns.org": {net.ParseIP("203.0.113.42")},
+	}}
+	r := useStubResolver(t, stub)
+	r.ttl = 0
+	ip := net.ParseIP("203.0.113.42")
+
+	var mu sync.Mutex
+	changes := 0
+	remove := r.onChange(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		changes++
+	})
+	notified := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return changes
+	}
+
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	if got := notified(); got != 1 {
+		t.Fatalf("expected 1 notification once resolved, got %d", got)
+	}
+
+	// The same addresses again don't change anything
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	if got := notified(); got != 1 {
+		t.Errorf("expected no notification for unchanged addresses, got %d", got)
+	}
+
+	// Nor does a failed lookup that keeps the last known addresses
+	stub.err = errors.New("server misbehaving")
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	if got := notified(); got != 1 {
+		t.Errorf("expected no notification for a failed lookup, got %d", got)
+	}
+
+	stub.err = nil
+	stub.hosts["home.dyndns.org"] = []net.IP{net.ParseIP("198.51.100.7")}
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	if got := notified(); got != 2 {
+		t.Errorf("expected a notification when the addresses change, got %d", got)
+	}
+
+	remove()
+	stub.hosts["home.dyndns.org"] = []net.IP{net.ParseIP("203.0.113.42")}
+	r.matches(ip, "home.dyndns.org")
+	r.refreshes.Wait()
+	if got := notified(); got != 2 {
+		t.Errorf("expected no notification once removed, got %d", got)
+	}
+}
+
+func TestHostnameResolver_DoesNotWait(t *testing.T) {
+	release := make(chan struct{})
+	r := newHostnameResolver(func(ctx context.Context, host string) ([]net.IP, error) {
+		<-release
+		return []net.IP{net.ParseIP("203.0.113.42")}, nil
+	})
+
+	// Matching returns while the lookup is still blocked
+	if r.matches(net.ParseIP("203.0.113.42"), "slow.example.com") {
+		t.Error("expected no match while the lookup is pending")
+	}
+	close(release)
+	r.refreshes.Wait()
+	if !r.matches(net.ParseIP("203.0.113.42"), "slow.example.com") {
+		t.Error("expected a match once resolved")
+	}
+}
+
+func TestHostnameResolver_Timeout(t *testing.T) {
+	r := newHostnameResolver(func(ctx context.Context, host string) ([]net.IP, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	r.timeout = 20 * time.Millisecond
+
+	start := time.Now()
+	r.matches(net.ParseIP("203.0.113.42"), "slow.example.com")
+	r.refreshes.Wait()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the lookup to give up after the timeout, took %v", elapsed)
+	}
+	if r.matches(net.ParseIP("203.0.113.42"), "slow.example.com") {
+		t.Error("expected no match when the lookup times out")
+	}
+}
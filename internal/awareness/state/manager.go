@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -50,7 +53,8 @@ type StateManager struct {
 	// alongside readings, preserving the single-writer invariant.
 	evaluatorUpdates chan RuleEvaluator
 
-	// Sensor cache - only accessed by manager goroutine
+	// Sensor cache - only written by the manager goroutine, under stateMu so
+	// GetSensorCache can read it from other goroutines
 	sensorCache map[string]SensorReading
 
 	// Current state - only accessed by manager goroutine
@@ -71,6 +75,9 @@ type StateManager struct {
 	// For external access to current state (read-only)
 	stateMu      sync.RWMutex
 	currentState StateSnapshot
+
+	// Heartbeat - unix nanos of the last finished evaluation (or start)
+	lastEvaluation atomic.Int64
 }
 
 // NewStateManager creates a new state manager with the given configuration
@@ -106,6 +113,7 @@ func NewStateManager(config ManagerConfig) *StateManager {
 
 // Start begins processing sensor readings
 func (m *StateManager) Start() {
+	m.lastEvaluation.Store(time.Now().UnixNano())
 	m.wg.Add(1)
 	go m.run()
 	m.logger.Info("State manager started", "policy", m.policy.Name())
@@ -143,6 +151,17 @@ func (m *StateManager) GetCurrentState() StateSnapshot {
 	return m.currentState
 }
 
+// LastEvaluation returns when the manager last finished evaluating a sensor
+// reading, or when it was started if it hasn't evaluated one yet (thread-safe).
+// A manager that stops advancing this has wedged.
+func (m *StateManager) LastEvaluation() time.Time {
+	nanos := m.lastEvaluation.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
 // GetSensorReading returns the latest reading for a sensor (thread-safe)
 // Returns nil if no reading exists for that sensor
 func (m *StateManager) GetSensorReading(name string) *SensorReading {
@@ -209,9 +228,13 @@ func (m *StateManager) SetRuleEvaluator(eval RuleEvaluator) {
 // processReading handles a single sensor reading
 // This is the only place where state is modified
 func (m *StateManager) processReading(reading SensorReading) {
+	defer func() { m.lastEvaluation.Store(time.Now().UnixNano()) }()
+
 	// 1. Update sensor cache
 	oldReading, hadOld := m.sensorCache[reading.Sensor]
+	m.stateMu.Lock()
 	m.sensorCache[reading.Sensor] = reading
+	m.stateMu.Unlock()
 
 	// Log the reading at debug level
 	m.logger.Debug("Sensor reading received",
@@ -245,6 +268,14 @@ func (m *StateManager) processReading(reading SensorReading) {
 		LocationDisplayName: ruleResult.LocationDisplayName,
 		MatchedRule:         ruleResult.MatchedRule,
 		Environment:         ruleResult.Environment,
+		LocationConflicts:   ruleResult.LocationConflicts,
+	}
+
+	// Overlapping locations silently pick one, so point them out once
+	if len(newSnapshot.LocationConflicts) > 0 && !slices.Equal(newSnapshot.LocationConflicts, m.current.LocationConflicts) {
+		m.logger.Warn("Multiple locations match the current network, make their conditions exclusive",
+			"locations", strings.Join(newSnapshot.LocationConflicts, ", "),
+			"using", newSnapshot.Location)
 	}
 
 	// When online but IP unknown, set to 0.0.0.0/:: to distinguish from offline
@@ -452,7 +483,9 @@ func (m *StateManager) RestoreSensorCache(entries []SensorCacheEntry) {
 			reading.IP = net.ParseIP(entry.IP)
 		}
 
+		m.stateMu.Lock()
 		m.sensorCache[entry.Sensor] = reading
+		m.stateMu.Unlock()
 	}
 
 	// Evaluate state based on restored cache
@@ -476,6 +509,7 @@ func (m *StateManager) RestoreSensorCache(entries []SensorCacheEntry) {
 			LocationDisplayName: ruleResult.LocationDisplayName,
 			MatchedRule:         ruleResult.MatchedRule,
 			Environment:         ruleResult.Environment,
+			LocationConflicts:   ruleResult.LocationConflicts,
 		}
 
 		m.stateMu.Lock()
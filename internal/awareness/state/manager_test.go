@@ -1,11 +1,14 @@
 package state
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -97,6 +100,43 @@ func TestStateManager_StartStop(t *testing.T) {
 	}
 }
 
+func TestStateManager_LastEvaluation(t *testing.T) {
+	m := NewStateManager(ManagerConfig{
+		Logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})),
+	})
+	m.Start()
+	started := m.LastEvaluation()
+	if started.IsZero() {
+		t.Fatal("expected the heartbeat to be set on start")
+	}
+
+	// A subscriber that never returns wedges the manager
+	stall := make(chan struct{})
+	defer close(stall)
+	m.Subscribe(func(StateSnapshot) { <-stall })
+
+	time.Sleep(10 * time.Millisecond)
+	online := true
+	m.Readings() <- SensorReading{Sensor: "tcp", Timestamp: time.Now(), Online: &online}
+	m.Readings() <- SensorReading{Sensor: "test", Timestamp: time.Now(), Value: "hello"}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := m.LastEvaluation(); !got.Equal(started) {
+		t.Errorf("expected the heartbeat to stall at %v while wedged, got %v", started, got)
+	}
+
+	// Unblock so Stop can return
+	stall <- struct{}{}
+	deadline := time.Now().Add(2 * time.Second)
+	for m.LastEvaluation().Equal(started) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !m.LastEvaluation().After(started) {
+		t.Error("expected the heartbeat to advance once the evaluation finished")
+	}
+	m.Stop()
+}
+
 func TestStateManager_TCPReadingProducesOnlineTransition(t *testing.T) {
 	m := NewStateManager(ManagerConfig{
 		Logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})),
@@ -896,3 +936,36 @@ func TestStateManager_TransitionsChannelFull(t *testing.T) {
 		t.Fatal("expected at least one transition")
 	}
 }
+
+func TestStateManager_WarnsAboutLocationConflicts(t *testing.T) {
+	var logs bytes.Buffer
+	engine := NewRuleEngine(nil, map[string]Location{
+		"office": {Name: "office", Conditions: map[string][]string{"public_ipv4": {"203.0.113.0/24"}}},
+		"hq":     {Name: "hq", Conditions: map[string][]string{"public_ipv4": {"203.0.113.10"}}},
+	}, nil)
+	m := NewStateManager(ManagerConfig{
+		RuleEvaluator: engine,
+		Logger:        slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	})
+	m.Start()
+	defer m.Stop()
+
+	m.Readings() <- SensorReading{
+		Sensor:    "public_ipv4",
+		Timestamp: time.Now(),
+		IP:        net.ParseIP("203.0.113.10"),
+	}
+
+	select {
+	case <-m.Transitions():
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out")
+	}
+
+	if got := m.GetCurrentState().LocationConflicts; !slices.Equal(got, []string{"hq", "office"}) {
+		t.Errorf("LocationConflicts = %v, want [hq office]", got)
+	}
+	if !strings.Contains(logs.String(), "Multiple locations match") || !strings.Contains(logs.String(), `locations="hq, office"`) {
+		t.Errorf("expected a warning naming both locations, got %q", logs.String())
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"log/slog"
 	"net"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
 
+// sensorEnvFileInterval is how often the sensor env file is re-read
+var sensorEnvFileInterval = 5 * time.Second
+
 // OrchestratorConfig holds configuration for the state orchestrator
 type OrchestratorConfig struct {
 	// Rules for context evaluation
@@ -22,12 +27,25 @@ type OrchestratorConfig struct {
 	// EnvWriters for exporting state
 	EnvWriters []EnvWriter
 
+	// ContextEnvWriters for exports that only exist while a context is active
+	ContextEnvWriters map[string][]EnvWriter
+
 	// TrackedEnvVars for clean unset on context switch
 	TrackedEnvVars []string
 
 	// PreferredIP is "ipv4" or "ipv6"
 	PreferredIP string
 
+	// PublicIPProviders are tried in order for public IPv4 detection
+	// (empty = built-in parallel consensus)
+	PublicIPProviders []string
+
+	// PublicIPTimeout is the per-provider request timeout
+	PublicIPTimeout time.Duration
+
+	// PublicIPCacheTTL reuses a detected public IP for this long (0 = no caching)
+	PublicIPCacheTTL time.Duration
+
 	// OnContextChange callback with rule info
 	OnContextChange func(from, to StateSnapshot, rule *Rule)
 
@@ -37,6 +55,15 @@ type OrchestratorConfig struct {
 	// DatabaseLogger for audit logging
 	DatabaseLogger DatabaseLogger
 
+	// SensorEnvFile is a dotenv file env conditions are matched against in
+	// addition to the process environment. It is re-read while running, so
+	// other tools can change the values.
+	SensorEnvFile string
+
+	// SensorDebounce is how long a sensor value must hold before its change
+	// is logged to the database (0 = log every change)
+	SensorDebounce time.Duration
+
 	// HistorySize is how many log entries to keep for replay
 	HistorySize int
 
@@ -77,7 +104,10 @@ type Orchestrator struct {
 	ipv6Probe      *IPProbe
 	localIPv4Probe *LocalIPProbe
 	networkProbe   *NetworkMonitorProbe
+	powerProbe     *PowerProbe
 	envProbes      []*EnvProbe
+	sensorEnvFile  string
+	envMu          sync.Mutex // Guards envProbes and sensorEnvFile
 
 	// Readings channel - all probes emit to this
 	readings chan SensorReading
@@ -86,10 +116,14 @@ type Orchestrator struct {
 	currentRule   *Rule
 	currentRuleMu sync.RWMutex
 
+	// Removes the listener for hostnames in conditions resolving anew
+	stopHostnameUpdates func()
+
 	// Lifecycle
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopOnce sync.Once
 }
 
 // NewOrchestrator creates a new state orchestrator
@@ -134,9 +168,10 @@ func NewOrchestrator(config OrchestratorConfig) *Orchestrator {
 
 	// Create effects processor with wrapped callbacks
 	effects := NewEffectsProcessor(manager.Transitions(), EffectsProcessorConfig{
-		EnvWriters:     config.EnvWriters,
-		TrackedEnvVars: config.TrackedEnvVars,
-		PreferredIP:    config.PreferredIP,
+		EnvWriters:        config.EnvWriters,
+		ContextEnvWriters: config.ContextEnvWriters,
+		TrackedEnvVars:    config.TrackedEnvVars,
+		PreferredIP:       config.PreferredIP,
 		OnContextChange: func(from, to StateSnapshot) {
 			if config.OnContextChange != nil {
 				o.currentRuleMu.RLock()
@@ -147,6 +182,7 @@ func NewOrchestrator(config OrchestratorConfig) *Orchestrator {
 		},
 		OnOnlineChange:      config.OnOnlineChange,
 		DatabaseLogger:      config.DatabaseLogger,
+		SensorDebounce:      config.SensorDebounce,
 		LogStreamer:         streamer,
 		Logger:              config.Logger,
 		LocationHooks:       config.LocationHooks,
@@ -207,20 +243,28 @@ func NewOrchestrator(config OrchestratorConfig) *Orchestrator {
 		if config.DatabaseLogger != nil {
 			config.DatabaseLogger.LogSensorChange("system_power", "string", "sleeping", "awake")
 		}
+		// The network may have changed while asleep - don't trust cached public IPs
+		o.networkProbe.invalidatePublicIPs()
 	})
 
 	// Create probes
 	o.tcpProbe = NewTCPProbe(config.Logger, o.sleepMonitor)
 	o.ipv4Probe = NewIPv4Probe(config.Logger)
+	if len(config.PublicIPProviders) > 0 {
+		o.ipv4Probe.SetProviders(config.PublicIPProviders, config.PublicIPTimeout)
+	}
 	o.ipv6Probe = NewIPv6Probe(config.Logger)
+	if config.PublicIPCacheTTL > 0 {
+		o.ipv4Probe.SetCacheTTL(config.PublicIPCacheTTL)
+		o.ipv6Probe.SetCacheTTL(config.PublicIPCacheTTL)
+	}
 	o.localIPv4Probe = NewLocalIPv4Probe(config.Logger)
 	o.networkProbe = NewNetworkMonitorProbe(o.ipv4Probe, o.ipv6Probe, o.localIPv4Probe, o.sleepMonitor, config.Logger)
+	o.powerProbe = NewPowerProbe(config.Logger)
 
 	// Create env probes for any env conditions in the config
-	envVarNames := CollectEnvSensors(config.Rules, config.Locations)
-	for _, varName := range envVarNames {
-		o.envProbes = append(o.envProbes, NewEnvProbe(varName))
-	}
+	o.sensorEnvFile = config.SensorEnvFile
+	o.envProbes = newEnvProbes(config.Rules, config.Locations, o.sensorEnvFile)
 
 	// Subscribe to state changes to track current rule
 	manager.Subscribe(func(snapshot StateSnapshot) {
@@ -257,6 +301,9 @@ func (o *Orchestrator) Start() {
 	// Start the state manager
 	o.manager.Start()
 
+	// Evaluate again once a hostname in a condition resolves to new addresses
+	o.stopHostnameUpdates = hostnames.onChange(func() { o.manager.ForceCheck("hostname_resolved") })
+
 	// Start the effects processor
 	o.effects.Start()
 
@@ -270,12 +317,13 @@ func (o *Orchestrator) Start() {
 	// Start probes
 	o.tcpProbe.Start(o.ctx, o.readings)
 	o.networkProbe.Start(o.ctx, o.readings)
+	o.powerProbe.Start(o.ctx, o.readings)
 
-	// Check env probes once at startup (env vars don't change during process lifetime)
-	for _, envProbe := range o.envProbes {
-		reading := envProbe.Check(o.ctx)
-		o.manager.SubmitReading(reading)
-	}
+	// Check env probes at startup, then keep watching the sensor env file
+	// (the process environment doesn't change during its lifetime)
+	o.checkEnvProbes(true)
+	o.wg.Add(1)
+	go o.watchSensorEnvFile()
 
 	o.logger.Info("State orchestrator started")
 }
@@ -333,8 +381,13 @@ func (o *Orchestrator) emitSensorLog(reading SensorReading) {
 	})
 }
 
-// Stop gracefully shuts down all components
+// Stop gracefully shuts down all components. It's safe to call more than
+// once; later calls wait for the first one to finish.
 func (o *Orchestrator) Stop() {
+	o.stopOnce.Do(o.stop)
+}
+
+func (o *Orchestrator) stop() {
 	o.streamer.Emit(LogEntry{
 		Timestamp: time.Now(),
 		Level:     LogInfo,
@@ -348,6 +401,9 @@ func (o *Orchestrator) Stop() {
 
 	// Cancel context to stop probes
 	o.cancel()
+	if o.stopHostnameUpdates != nil {
+		o.stopHostnameUpdates()
+	}
 
 	// Wait for readings forwarder to stop
 	o.wg.Wait()
@@ -503,6 +559,62 @@ func (o *Orchestrator) HasEnvWriters() bool {
 	return len(o.effects.config.EnvWriters) > 0
 }
 
+// PowerStatus returns the current power source and battery charge
+func (o *Orchestrator) PowerStatus() PowerStatus {
+	if o.powerProbe == nil {
+		return PowerStatus{Level: -1}
+	}
+	return o.powerProbe.Status()
+}
+
+// Sensors lists the sensors feeding the rule engine with the kind of value
+// each reports and its current value, sorted by name
+func (o *Orchestrator) Sensors() []SensorInfo {
+	probes := []Probe{o.tcpProbe, o.ipv4Probe, o.ipv6Probe, o.localIPv4Probe, o.powerProbe}
+	o.envMu.Lock()
+	for _, envProbe := range o.envProbes {
+		probes = append(probes, envProbe)
+	}
+	o.envMu.Unlock()
+
+	cache := make(map[string]SensorCacheEntry)
+	for _, entry := range o.manager.GetSensorCache() {
+		cache[entry.Sensor] = entry
+	}
+
+	sensors := make([]SensorInfo, 0, len(probes))
+	for _, probe := range probes {
+		info := SensorInfo{Name: probe.Name(), Kind: probe.Kind()}
+		if entry, ok := cache[info.Name]; ok {
+			switch info.Kind {
+			case SensorKindBool:
+				if entry.Online != nil {
+					info.Value = strconv.FormatBool(*entry.Online)
+				}
+			case SensorKindIP:
+				info.Value = entry.IP
+			default:
+				info.Value = entry.Value
+			}
+		}
+		sensors = append(sensors, info)
+	}
+
+	sort.Slice(sensors, func(i, j int) bool { return sensors[i].Name < sensors[j].Name })
+	return sensors
+}
+
+// RunReloadHooks runs hooks with the current state in their environment.
+// Used for the on_reload context hooks after a config reload.
+func (o *Orchestrator) RunReloadHooks(hooks []HookConfig) {
+	o.effects.ExecuteReloadHooks(hooks, o.GetCurrentState())
+}
+
+// LastEvaluation returns when the state manager last evaluated a sensor reading
+func (o *Orchestrator) LastEvaluation() time.Time {
+	return o.manager.LastEvaluation()
+}
+
 // IsSuppressed returns true if probes/connections should be suppressed
 // (sleeping or within wake grace period)
 func (o *Orchestrator) IsSuppressed() bool {
@@ -524,17 +636,12 @@ func (o *Orchestrator) Reload(rules []Rule, locations map[string]Location, globa
 	o.manager.SetRuleEvaluator(o.ruleEngine)
 
 	// Recreate env probes for new config
-	o.envProbes = nil
-	envVarNames := CollectEnvSensors(rules, locations)
-	for _, varName := range envVarNames {
-		o.envProbes = append(o.envProbes, NewEnvProbe(varName))
-	}
+	o.envMu.Lock()
+	o.envProbes = newEnvProbes(rules, locations, o.sensorEnvFile)
+	o.envMu.Unlock()
 
 	// Check env probes and submit readings
-	for _, envProbe := range o.envProbes {
-		reading := envProbe.Check(o.ctx)
-		o.manager.SubmitReading(reading)
-	}
+	o.checkEnvProbes(true)
 
 	o.streamer.Emit(LogEntry{
 		Timestamp: time.Now(),
@@ -551,6 +658,95 @@ func (o *Orchestrator) Reload(rules []Rule, locations map[string]Location, globa
 	o.TriggerCheck("config_reload")
 }
 
+// SetContextEnvWriters replaces the writers of context-scoped exports (used
+// on reload), removing the files of exports that are gone
+func (o *Orchestrator) SetContextEnvWriters(writers map[string][]EnvWriter) {
+	o.effects.SetContextEnvWriters(writers)
+}
+
+// RemoveContextEnvFiles removes the files of all context-scoped exports, once
+// the orchestrator is stopped on shutdown
+func (o *Orchestrator) RemoveContextEnvFiles() {
+	o.effects.RemoveContextEnvFiles()
+}
+
+// SetSensorEnvFile replaces the dotenv file env conditions are matched
+// against (used on reload, takes effect when Reload recreates the env probes)
+func (o *Orchestrator) SetSensorEnvFile(path string) {
+	o.envMu.Lock()
+	defer o.envMu.Unlock()
+	o.sensorEnvFile = path
+}
+
+// newEnvProbes creates a probe for every environment variable used in the
+// conditions of rules and locations
+func newEnvProbes(rules []Rule, locations map[string]Location, sensorEnvFile string) []*EnvProbe {
+	var probes []*EnvProbe
+	for _, varName := range CollectEnvSensors(rules, locations) {
+		if sensorEnvFile != "" {
+			probes = append(probes, NewEnvFileProbe(varName, sensorEnvFile))
+		} else {
+			probes = append(probes, NewEnvProbe(varName))
+		}
+	}
+	return probes
+}
+
+// checkEnvProbes submits a reading from every env probe, or with all unset
+// only the readings whose value changed since the last one
+func (o *Orchestrator) checkEnvProbes(all bool) {
+	o.envMu.Lock()
+	probes := o.envProbes
+	o.envMu.Unlock()
+
+	for _, envProbe := range probes {
+		reading := envProbe.Check(o.ctx)
+		if !all {
+			if last := o.manager.GetSensorReading(reading.Sensor); last != nil && last.Value == reading.Value {
+				continue
+			}
+		}
+		o.manager.SubmitReading(reading)
+	}
+}
+
+// watchSensorEnvFile re-checks the env probes while a sensor env file is
+// configured, so a change to the file is evaluated without a reload
+func (o *Orchestrator) watchSensorEnvFile() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(sensorEnvFileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.envMu.Lock()
+			watching := o.sensorEnvFile != ""
+			o.envMu.Unlock()
+			if watching {
+				o.checkEnvProbes(false)
+			}
+		}
+	}
+}
+
+// SetPublicIPProviders replaces the ordered public IPv4 providers (used on reload)
+func (o *Orchestrator) SetPublicIPProviders(providers []string, timeout time.Duration) {
+	o.config.PublicIPProviders = providers
+	o.config.PublicIPTimeout = timeout
+	o.ipv4Probe.SetProviders(providers, timeout)
+}
+
+// SetPublicIPCacheTTL updates how long detected public IPs are reused (used on reload)
+func (o *Orchestrator) SetPublicIPCacheTTL(ttl time.Duration) {
+	o.config.PublicIPCacheTTL = ttl
+	o.ipv4Probe.SetCacheTTL(ttl)
+	o.ipv6Probe.SetCacheTTL(ttl)
+}
+
 // GetSensorCache returns the current sensor cache for persistence
 func (o *Orchestrator) GetSensorCache() []SensorCacheEntry {
 	return o.manager.GetSensorCache()
@@ -1,9 +1,11 @@
 package state
 
 import (
+	"fmt"
 	"log/slog"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -83,6 +85,9 @@ func TestOrchestrator_StartStop(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	o.Stop()
+
+	// Stopping again is a no-op
+	o.Stop()
 }
 
 func TestOrchestrator_GetCurrentState(t *testing.T) {
@@ -305,6 +310,77 @@ func TestOrchestrator_SensorCacheRoundTrip(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_Sensors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.Level(99)}))
+
+	o := NewOrchestrator(OrchestratorConfig{
+		Rules: []Rule{
+			{Name: "vpn", Condition: NewSensorCondition("env:VPN", "on")},
+		},
+		Logger: logger,
+	})
+
+	online := true
+	now := time.Now().Format(time.RFC3339Nano)
+	o.RestoreSensorCache([]SensorCacheEntry{
+		{Sensor: "tcp", Timestamp: now, Online: &online},
+		{Sensor: "public_ipv4", Timestamp: now, IP: "203.0.113.7", Value: "203.0.113.7"},
+		{Sensor: "power", Timestamp: now, Value: PowerAC},
+		{Sensor: "force_check:startup", Timestamp: now},
+	})
+
+	want := []SensorInfo{
+		{Name: "env:VPN", Kind: SensorKindString},
+		{Name: "local_ipv4", Kind: SensorKindIP},
+		{Name: "power", Kind: SensorKindString, Value: PowerAC},
+		{Name: "public_ipv4", Kind: SensorKindIP, Value: "203.0.113.7"},
+		{Name: "public_ipv6", Kind: SensorKindIP},
+		{Name: "tcp", Kind: SensorKindBool, Value: "true"},
+	}
+
+	got := o.Sensors()
+	if len(got) != len(want) {
+		t.Fatalf("Sensors() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Sensors()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOrchestrator_SensorsWhileProcessingReadings(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.Level(99)}))
+
+	o := NewOrchestrator(OrchestratorConfig{Logger: logger})
+	o.manager.Start()
+	defer o.manager.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 200 {
+			o.manager.Readings() <- SensorReading{
+				Sensor:    fmt.Sprintf("env:VAR%d", i%20),
+				Timestamp: time.Now(),
+				Value:     fmt.Sprint(i),
+			}
+		}
+	}()
+
+	// Sensors reads the sensor cache while the manager goroutine writes it;
+	// run with -race to catch unguarded access
+	for {
+		select {
+		case <-done:
+			o.Sensors()
+			return
+		default:
+			o.Sensors()
+		}
+	}
+}
+
 func TestOrchestrator_BuildSSHEnv(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.Level(99)}))
 
@@ -533,3 +609,50 @@ func TestOrchestrator_Reload_AppliesNewLocation(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 	}
 }
+
+func TestOrchestrator_SensorEnvFile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.Level(99)}))
+
+	oldInterval := sensorEnvFileInterval
+	sensorEnvFileInterval = 20 * time.Millisecond
+	t.Cleanup(func() { sensorEnvFileInterval = oldInterval })
+
+	path := filepath.Join(t.TempDir(), "sensors.env")
+	o := NewOrchestrator(OrchestratorConfig{
+		Rules: []Rule{
+			{Name: "corp", Locations: []string{"corp"}},
+			{Name: "untrusted"},
+		},
+		Locations: map[string]Location{
+			"corp": {Name: "corp", Condition: NewSensorCondition("env:OVERSEER_TEST_CORP_NETWORK", "true")},
+		},
+		SensorEnvFile: path,
+		Logger:        logger,
+	})
+	o.Start()
+	t.Cleanup(o.Stop)
+
+	waitForContext := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for o.GetCurrentState().Context != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("context = %q, want %q", o.GetCurrentState().Context, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitForContext("untrusted")
+
+	// Another tool sets the variable while the daemon runs
+	if err := os.WriteFile(path, []byte("OVERSEER_TEST_CORP_NETWORK=true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitForContext("corp")
+
+	if err := os.WriteFile(path, []byte("OVERSEER_TEST_CORP_NETWORK=false\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitForContext("untrusted")
+}
@@ -0,0 +1,182 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Power sensor values
+const (
+	PowerAC      = "ac"
+	PowerBattery = "battery"
+)
+
+// PowerStatus is a single reading of the platform power source
+type PowerStatus struct {
+	Source string // PowerAC, PowerBattery, or "" if unknown
+	Level  int    // Battery charge in percent, -1 if unknown or no battery
+}
+
+// PowerProbe reports whether the machine is running on AC or battery power,
+// along with the battery charge. The sensor value is the power source only,
+// so rules are re-evaluated when the source changes rather than on every
+// percent of charge.
+type PowerProbe struct {
+	name     string
+	interval time.Duration
+	logger   *slog.Logger
+	read     func() (PowerStatus, error)
+
+	mu     sync.RWMutex
+	status PowerStatus
+}
+
+// NewPowerProbe creates a probe that polls the platform power source
+func NewPowerProbe(logger *slog.Logger) *PowerProbe {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PowerProbe{
+		name:     "power",
+		interval: 60 * time.Second,
+		logger:   logger,
+		read:     readPowerStatus,
+		status:   PowerStatus{Level: -1},
+	}
+}
+
+func (p *PowerProbe) Name() string { return p.name }
+
+func (p *PowerProbe) Kind() SensorKind { return SensorKindString }
+
+func (p *PowerProbe) Start(ctx context.Context, output chan<- SensorReading) {
+	go func() {
+		select {
+		case output <- p.Check(ctx):
+		case <-ctx.Done():
+			return
+		}
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				select {
+				case output <- p.Check(ctx):
+				default:
+					// Output buffer full, skip this reading
+				}
+			}
+		}
+	}()
+
+	p.logger.Info("Power probe started", "interval", p.interval)
+}
+
+func (p *PowerProbe) Check(ctx context.Context) SensorReading {
+	status, err := p.read()
+	if err != nil {
+		status = PowerStatus{Level: -1}
+	}
+
+	p.mu.Lock()
+	previous := p.status.Source
+	p.status = status
+	p.mu.Unlock()
+
+	if previous != "" && status.Source != "" && previous != status.Source {
+		p.logger.Info("Power source changed", "from", previous, "to", status.Source, "level", status.Level)
+	}
+
+	return SensorReading{
+		Sensor:    p.name,
+		Timestamp: time.Now(),
+		Value:     status.Source,
+		Error:     err,
+	}
+}
+
+// Status returns the power status from the last check
+func (p *PowerProbe) Status() PowerStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
+
+// pmsetLevelPattern matches the battery charge in `pmset -g batt` output
+var pmsetLevelPattern = regexp.MustCompile(`\t(\d{1,3})%;`)
+
+// parsePmsetPowerStatus extracts the power status from `pmset -g batt` output,
+// whose first line reads "Now drawing from 'AC Power'" or "'Battery Power'"
+// followed by one line per battery with its charge
+func parsePmsetPowerStatus(output string) PowerStatus {
+	status := PowerStatus{Level: -1}
+	switch {
+	case strings.Contains(output, "'Battery Power'"):
+		status.Source = PowerBattery
+	case strings.Contains(output, "'AC Power'"), strings.Contains(output, "'UPS Power'"):
+		status.Source = PowerAC
+	}
+	if m := pmsetLevelPattern.FindStringSubmatch(output); m != nil {
+		status.Level, _ = strconv.Atoi(m[1])
+	}
+	return status
+}
+
+// readSysfsPowerStatus determines the power status from a Linux
+// /sys/class/power_supply directory. Machines without a battery are on AC.
+func readSysfsPowerStatus(root string) (PowerStatus, error) {
+	status := PowerStatus{Level: -1}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return status, err
+	}
+
+	onlineAC := false
+	hasBattery := false
+	for _, entry := range entries {
+		supply := filepath.Join(root, entry.Name())
+		switch readSysfsValue(supply, "type") {
+		case "Mains", "USB":
+			if readSysfsValue(supply, "online") == "1" {
+				onlineAC = true
+			}
+		case "Battery":
+			// Peripheral batteries (mice, headsets) don't power the machine
+			if readSysfsValue(supply, "scope") == "Device" {
+				continue
+			}
+			hasBattery = true
+			if level, err := strconv.Atoi(readSysfsValue(supply, "capacity")); err == nil && status.Level < 0 {
+				status.Level = level
+			}
+		}
+	}
+
+	if hasBattery && !onlineAC {
+		status.Source = PowerBattery
+	} else {
+		status.Source = PowerAC
+	}
+	return status, nil
+}
+
+func readSysfsValue(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
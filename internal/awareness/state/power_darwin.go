@@ -0,0 +1,12 @@
+package state
+
+import "os/exec"
+
+// readPowerStatus asks pmset which power source is in use
+func readPowerStatus() (PowerStatus, error) {
+	output, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return PowerStatus{Level: -1}, err
+	}
+	return parsePmsetPowerStatus(string(output)), nil
+}
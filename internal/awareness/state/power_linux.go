@@ -0,0 +1,6 @@
+package state
+
+// readPowerStatus reads the power status from sysfs
+func readPowerStatus() (PowerStatus, error) {
+	return readSysfsPowerStatus("/sys/class/power_supply")
+}
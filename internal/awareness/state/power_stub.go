@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+
+package state
+
+// readPowerStatus is not supported on this platform; the status is unknown
+func readPowerStatus() (PowerStatus, error) {
+	return PowerStatus{Level: -1}, nil
+}
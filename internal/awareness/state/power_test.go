@@ -0,0 +1,133 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePmsetPowerStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   PowerStatus
+	}{
+		{"battery", "Now drawing from 'Battery Power'\n -InternalBattery-0 (id=1234)\t87%; discharging; 5:12 remaining present: true\n", PowerStatus{PowerBattery, 87}},
+		{"ac", "Now drawing from 'AC Power'\n -InternalBattery-0 (id=1234)\t100%; charged; 0:00 remaining present: true\n", PowerStatus{PowerAC, 100}},
+		{"desktop", "Now drawing from 'AC Power'\n", PowerStatus{PowerAC, -1}},
+		{"unknown", "", PowerStatus{"", -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePmsetPowerStatus(tt.output); got != tt.want {
+				t.Errorf("parsePmsetPowerStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writePowerSupply creates a fake /sys/class/power_supply entry
+func writePowerSupply(t *testing.T, root, name string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadSysfsPowerStatus(t *testing.T) {
+	t.Run("laptop on battery", func(t *testing.T) {
+		root := t.TempDir()
+		writePowerSupply(t, root, "AC", map[string]string{"type": "Mains", "online": "0"})
+		writePowerSupply(t, root, "BAT0", map[string]string{"type": "Battery", "status": "Discharging", "capacity": "42"})
+
+		want := PowerStatus{PowerBattery, 42}
+		if got, err := readSysfsPowerStatus(root); err != nil || got != want {
+			t.Errorf("readSysfsPowerStatus() = %+v, %v; want %+v", got, err, want)
+		}
+	})
+
+	t.Run("laptop plugged in", func(t *testing.T) {
+		root := t.TempDir()
+		writePowerSupply(t, root, "AC", map[string]string{"type": "Mains", "online": "1"})
+		writePowerSupply(t, root, "BAT0", map[string]string{"type": "Battery", "status": "Charging", "capacity": "80"})
+
+		want := PowerStatus{PowerAC, 80}
+		if got, err := readSysfsPowerStatus(root); err != nil || got != want {
+			t.Errorf("readSysfsPowerStatus() = %+v, %v; want %+v", got, err, want)
+		}
+	})
+
+	t.Run("desktop with a wireless mouse", func(t *testing.T) {
+		root := t.TempDir()
+		writePowerSupply(t, root, "hidpp_battery_0", map[string]string{"type": "Battery", "scope": "Device", "capacity": "15"})
+
+		want := PowerStatus{PowerAC, -1}
+		if got, err := readSysfsPowerStatus(root); err != nil || got != want {
+			t.Errorf("readSysfsPowerStatus() = %+v, %v; want %+v", got, err, want)
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		if _, err := readSysfsPowerStatus(filepath.Join(t.TempDir(), "missing")); err == nil {
+			t.Error("expected error for missing power_supply directory")
+		}
+	})
+}
+
+func TestPowerProbe_Check(t *testing.T) {
+	probe := NewPowerProbe(nil)
+	probe.read = func() (PowerStatus, error) { return PowerStatus{PowerBattery, 55}, nil }
+
+	reading := probe.Check(context.Background())
+	if reading.Sensor != "power" || reading.Value != PowerBattery {
+		t.Errorf("unexpected reading: %+v", reading)
+	}
+	if got := probe.Status(); got.Source != PowerBattery || got.Level != 55 {
+		t.Errorf("Status() = %+v, want battery at 55%%", got)
+	}
+
+	// A failed read leaves the status unknown
+	probe.read = func() (PowerStatus, error) { return PowerStatus{PowerAC, 100}, errors.New("pmset failed") }
+	if reading := probe.Check(context.Background()); reading.Error == nil || reading.Value != "" {
+		t.Errorf("expected reading to carry the error and no value, got %+v", reading)
+	}
+	if got := probe.Status(); got.Source != "" || got.Level != -1 {
+		t.Errorf("Status() = %+v, want unknown", got)
+	}
+}
+
+func TestPowerProbe_RuleCondition(t *testing.T) {
+	probe := NewPowerProbe(nil)
+	source := PowerAC
+	probe.read = func() (PowerStatus, error) { return PowerStatus{source, 90}, nil }
+
+	rules := []Rule{
+		{Name: "plugged-in", Condition: NewSensorCondition("power", PowerAC)},
+		{Name: "untrusted"},
+	}
+	engine := NewRuleEngine(rules, nil, nil)
+
+	readings := map[string]SensorReading{}
+	evaluate := func() string {
+		reading := probe.Check(context.Background())
+		readings[reading.Sensor] = reading
+		return engine.Evaluate(readings, true).Context
+	}
+
+	if got := evaluate(); got != "plugged-in" {
+		t.Errorf("on AC: context = %q, want plugged-in", got)
+	}
+	source = PowerBattery
+	if got := evaluate(); got != "untrusted" {
+		t.Errorf("on battery: context = %q, want untrusted", got)
+	}
+}
@@ -20,6 +20,9 @@ type Probe interface {
 	// Name returns the probe name
 	Name() string
 
+	// Kind returns the type of value the probe reports
+	Kind() SensorKind
+
 	// Start begins the probe's polling/monitoring
 	Start(ctx context.Context, output chan<- SensorReading)
 
@@ -79,6 +82,8 @@ func NewTCPProbe(logger *slog.Logger, sleepMonitor *SleepMonitor) *TCPProbe {
 
 func (p *TCPProbe) Name() string { return p.name }
 
+func (p *TCPProbe) Kind() SensorKind { return SensorKindBool }
+
 func (p *TCPProbe) Start(ctx context.Context, output chan<- SensorReading) {
 	go func() {
 		// Do an initial check immediately (skip if suppressed)
@@ -170,8 +175,26 @@ type IPProbe struct {
 	pendingIP      string
 	pendingCount   int
 	stabilityCount int // e.g., 2
+
+	// Ordered providers - when set, these are tried one at a time instead of
+	// the parallel consensus check over httpURLs
+	providers       []string
+	providerTimeout time.Duration
+
+	// Last IP returned by a provider, reused briefly when all providers fail
+	lastGoodIP   string
+	lastGoodTime time.Time
+
+	// Result cache - when cacheTTL > 0, checks within the TTL reuse the last
+	// detected IP instead of querying services again
+	cacheTTL time.Duration
+	cachedIP string
+	cachedAt time.Time
 }
 
+// lastGoodIPMaxAge is how long the last provider result is reused when every provider fails
+const lastGoodIPMaxAge = 30 * time.Second
+
 // IPResolver defines a DNS resolver for public IP detection
 type IPResolver struct {
 	ResolverAddr string // DNS server address (direct IP, e.g., "208.67.222.222:53")
@@ -258,6 +281,46 @@ func NewIPv6Probe(logger *slog.Logger) *IPProbe {
 
 func (p *IPProbe) Name() string { return p.name }
 
+func (p *IPProbe) Kind() SensorKind { return SensorKindIP }
+
+// SetProviders configures ordered "what's my IP" providers. Providers are tried
+// in order and the first valid answer wins. An empty list restores the default
+// parallel consensus check.
+func (p *IPProbe) SetProviders(urls []string, timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.providers = append([]string(nil), urls...)
+	p.providerTimeout = timeout
+	p.lastGoodIP = ""
+	p.lastGoodTime = time.Time{}
+}
+
+// SetCacheTTL sets how long a detected IP is reused before querying again (0 disables caching)
+func (p *IPProbe) SetCacheTTL(ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cacheTTL = ttl
+}
+
+// Invalidate drops the cached IP so the next check queries the services again.
+// Called when the network changes (e.g., local IP changed or system woke up).
+func (p *IPProbe) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cachedIP = ""
+	p.cachedAt = time.Time{}
+}
+
+// cachedReading returns the cached IP if caching is enabled and it hasn't expired
+func (p *IPProbe) cachedReading() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cacheTTL <= 0 || p.cachedIP == "" || time.Since(p.cachedAt) >= p.cacheTTL {
+		return "", false
+	}
+	return p.cachedIP, true
+}
+
 func (p *IPProbe) Start(ctx context.Context, output chan<- SensorReading) {
 	// IP probes don't poll continuously - they're checked on demand
 	// or when triggered by network changes
@@ -293,90 +356,94 @@ func (p *IPProbe) normalizeToPrefix(ipStr string) string {
 	return network.String()
 }
 
-// checkHTTP queries HTTP "what's my IP" services and returns consensus IP.
-// Queries all services in parallel and returns the IP that 2+ services agree on.
-func (p *IPProbe) checkHTTP(ctx context.Context) string {
-	if len(p.httpURLs) == 0 {
-		return ""
-	}
-
-	// Create HTTP client with appropriate transport for IPv4/IPv6
+// newHTTPClient creates an HTTP client that dials using the probe's address family
+func (p *IPProbe) newHTTPClient(timeout time.Duration) *http.Client {
 	transport := &http.Transport{
 		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
 			// Force IPv4 or IPv6 based on probe type
-			dialer := &net.Dialer{Timeout: 5 * time.Second}
+			dialer := &net.Dialer{Timeout: timeout}
 			if p.network == "udp6" {
 				return dialer.DialContext(ctx, "tcp6", addr)
 			}
 			return dialer.DialContext(ctx, "tcp4", addr)
 		},
 	}
-	client := &http.Client{
+	return &http.Client{
 		Transport: transport,
-		Timeout:   5 * time.Second,
+		Timeout:   timeout,
 	}
+}
 
-	// Query all services in parallel
-	type result struct {
-		ip  string
-		url string
+// fetchIP queries a single HTTP service and returns the IP it reports,
+// or an empty string if the request fails or the answer is not a valid IP
+// of the probe's address family.
+func (p *IPProbe) fetchIP(ctx context.Context, client *http.Client, url string) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return ""
 	}
-	results := make(chan result, len(p.httpURLs))
 
-	for _, url := range p.httpURLs {
-		go func(url string) {
-			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-			if err != nil {
-				results <- result{url: url} // empty ip signals failure
-				return
-			}
+	resp, err := client.Do(req)
+	if err != nil {
+		p.logger.Debug("HTTP IP check failed", "url", url, "error", err)
+		return ""
+	}
+	defer resp.Body.Close()
 
-			resp, err := client.Do(req)
-			if err != nil {
-				p.logger.Debug("HTTP IP check failed", "url", url, "error", err)
-				results <- result{url: url}
-				return
-			}
-			defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		p.logger.Debug("HTTP IP check non-200", "url", url, "status", resp.StatusCode)
+		return ""
+	}
 
-			if resp.StatusCode != http.StatusOK {
-				p.logger.Debug("HTTP IP check non-200", "url", url, "status", resp.StatusCode)
-				results <- result{url: url}
-				return
-			}
+	// Read response (limit to 64 bytes - an IP address is much smaller)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return ""
+	}
 
-			// Read response (limit to 64 bytes - an IP address is much smaller)
-			body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
-			if err != nil {
-				results <- result{url: url}
-				return
-			}
+	ipStr := strings.TrimSpace(string(body))
 
-			ipStr := strings.TrimSpace(string(body))
+	// Validate it's a proper IP address
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		p.logger.Debug("HTTP IP check invalid response", "url", url, "response", ipStr)
+		return ""
+	}
 
-			// Validate it's a proper IP address
-			ip := net.ParseIP(ipStr)
-			if ip == nil {
-				p.logger.Debug("HTTP IP check invalid response", "url", url, "response", ipStr)
-				results <- result{url: url}
-				return
-			}
+	// For IPv4 probe, ensure we got an IPv4 address
+	if p.network == "udp4" && ip.To4() == nil {
+		p.logger.Debug("HTTP IP check returned IPv6 for IPv4 probe", "url", url, "ip", ipStr)
+		return ""
+	}
 
-			// For IPv4 probe, ensure we got an IPv4 address
-			if p.network == "udp4" && ip.To4() == nil {
-				p.logger.Debug("HTTP IP check returned IPv6 for IPv4 probe", "url", url, "ip", ipStr)
-				results <- result{url: url}
-				return
-			}
+	// For IPv6 probe, ensure we got an IPv6 address
+	if p.network == "udp6" && ip.To4() != nil {
+		p.logger.Debug("HTTP IP check returned IPv4 for IPv6 probe", "url", url, "ip", ipStr)
+		return ""
+	}
 
-			// For IPv6 probe, ensure we got an IPv6 address
-			if p.network == "udp6" && ip.To4() != nil {
-				p.logger.Debug("HTTP IP check returned IPv4 for IPv6 probe", "url", url, "ip", ipStr)
-				results <- result{url: url}
-				return
-			}
+	return ipStr
+}
+
+// checkHTTP queries HTTP "what's my IP" services and returns consensus IP.
+// Queries all services in parallel and returns the IP that 2+ services agree on.
+func (p *IPProbe) checkHTTP(ctx context.Context) string {
+	if len(p.httpURLs) == 0 {
+		return ""
+	}
+
+	client := p.newHTTPClient(5 * time.Second)
 
-			results <- result{ip: ipStr, url: url}
+	// Query all services in parallel
+	type result struct {
+		ip  string
+		url string
+	}
+	results := make(chan result, len(p.httpURLs))
+
+	for _, url := range p.httpURLs {
+		go func(url string) {
+			results <- result{ip: p.fetchIP(ctx, client, url), url: url}
 		}(url)
 	}
 
@@ -412,6 +479,44 @@ collect:
 	return ""
 }
 
+// checkProviders tries the configured providers in order and returns the first
+// valid IP. If every provider fails, the last good value is reused for a short
+// while so a flaky or rate-limiting provider doesn't break location detection.
+func (p *IPProbe) checkProviders(ctx context.Context) string {
+	p.mu.Lock()
+	providers := p.providers
+	timeout := p.providerTimeout
+	p.mu.Unlock()
+
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	client := p.newHTTPClient(timeout)
+
+	for _, url := range providers {
+		if ctx.Err() != nil {
+			break
+		}
+		if ip := p.fetchIP(ctx, client, url); ip != "" {
+			p.logger.Debug("Public IP provider answered", "url", url, "ip", ip)
+			p.mu.Lock()
+			p.lastGoodIP = ip
+			p.lastGoodTime = time.Now()
+			p.mu.Unlock()
+			return ip
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lastGoodIP != "" && time.Since(p.lastGoodTime) < lastGoodIPMaxAge {
+		p.logger.Debug("All public IP providers failed, reusing last good value",
+			"ip", p.lastGoodIP, "age", time.Since(p.lastGoodTime))
+		return p.lastGoodIP
+	}
+	return ""
+}
+
 // checkDNS queries DNS resolvers and returns consensus IP.
 // Uses direct IP addresses for DNS servers, so works even when system DNS is broken.
 func (p *IPProbe) checkDNS(ctx context.Context) string {
@@ -574,10 +679,30 @@ func (p *IPProbe) applyHysteresis(newIP string) string {
 func (p *IPProbe) Check(ctx context.Context) SensorReading {
 	start := time.Now()
 
+	// Reuse a recent result to avoid hammering services on rapid triggers
+	if cachedIP, ok := p.cachedReading(); ok {
+		return SensorReading{
+			Sensor:    p.name,
+			Timestamp: time.Now(),
+			IP:        net.ParseIP(cachedIP),
+			Value:     cachedIP,
+			Latency:   time.Since(start),
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, p.timeout)
 	defer cancel()
 
-	detectedIP := p.checkHTTP(ctx)
+	p.mu.Lock()
+	useProviders := len(p.providers) > 0
+	p.mu.Unlock()
+
+	var detectedIP string
+	if useProviders {
+		detectedIP = p.checkProviders(ctx)
+	} else {
+		detectedIP = p.checkHTTP(ctx)
+	}
 
 	if detectedIP == "" {
 		// Fall back to DNS if HTTP failed (e.g., DNS resolution broken)
@@ -607,6 +732,13 @@ func (p *IPProbe) Check(ctx context.Context) SensorReading {
 		}
 	}
 
+	p.mu.Lock()
+	if p.cacheTTL > 0 {
+		p.cachedIP = detectedIP
+		p.cachedAt = time.Now()
+	}
+	p.mu.Unlock()
+
 	return SensorReading{
 		Sensor:    p.name,
 		Timestamp: time.Now(),
@@ -633,6 +765,7 @@ type NetworkMonitorProbe struct {
 	mu            sync.Mutex
 	lastCheckTime time.Time
 	minInterval   time.Duration // Minimum time between checks (debounce)
+	lastLocalIP   string        // Last local IPv4, a change means the network changed
 }
 
 // NewNetworkMonitorProbe creates a new network monitor probe
@@ -654,6 +787,8 @@ func NewNetworkMonitorProbe(ipv4 *IPProbe, ipv6 *IPProbe, localIPv4 *LocalIPProb
 
 func (p *NetworkMonitorProbe) Name() string { return p.name }
 
+func (p *NetworkMonitorProbe) Kind() SensorKind { return SensorKindString }
+
 func (p *NetworkMonitorProbe) Start(ctx context.Context, output chan<- SensorReading) {
 	go func() {
 		// Do an initial check immediately so IP sensors are populated early
@@ -696,6 +831,17 @@ func (p *NetworkMonitorProbe) checkAndEmit(ctx context.Context, output chan<- Se
 	// Check local IPv4 first (fastest, no network needed)
 	if p.localIPv4Probe != nil {
 		reading := p.localIPv4Probe.Check(ctx)
+
+		// A changed LAN address means we moved networks - drop cached public IPs
+		p.mu.Lock()
+		changed := p.lastLocalIP != "" && reading.Value != p.lastLocalIP
+		p.lastLocalIP = reading.Value
+		p.mu.Unlock()
+		if changed {
+			p.logger.Debug("Local IP changed, refreshing public IP", "local_ip", reading.Value)
+			p.invalidatePublicIPs()
+		}
+
 		select {
 		case output <- reading:
 		default:
@@ -729,6 +875,16 @@ func (p *NetworkMonitorProbe) Check(ctx context.Context) SensorReading {
 	}
 }
 
+// invalidatePublicIPs drops cached public IP results so the next check is fresh
+func (p *NetworkMonitorProbe) invalidatePublicIPs() {
+	if p.ipv4Probe != nil {
+		p.ipv4Probe.Invalidate()
+	}
+	if p.ipv6Probe != nil {
+		p.ipv6Probe.Invalidate()
+	}
+}
+
 // TriggerCheck forces an immediate IP check (called externally)
 func (p *NetworkMonitorProbe) TriggerCheck(ctx context.Context, output chan<- SensorReading) {
 	p.checkAndEmit(ctx, output)
@@ -767,6 +923,8 @@ func NewLocalIPv6Probe(logger *slog.Logger) *LocalIPProbe {
 
 func (p *LocalIPProbe) Name() string { return p.name }
 
+func (p *LocalIPProbe) Kind() SensorKind { return SensorKindIP }
+
 func (p *LocalIPProbe) Start(ctx context.Context, output chan<- SensorReading) {
 	// Local IP probes are checked on demand, not continuously polled
 	p.logger.Debug("Local IP probe ready", "name", p.name)
@@ -822,6 +980,7 @@ func (p *LocalIPProbe) getLocalIP() string {
 type EnvProbe struct {
 	name    string
 	varName string
+	file    string // Dotenv file whose values override the process environment
 }
 
 // NewEnvProbe creates a probe that reads an environment variable
@@ -832,17 +991,67 @@ func NewEnvProbe(varName string) *EnvProbe {
 	}
 }
 
+// NewEnvFileProbe creates a probe that reads a variable from a dotenv file,
+// falling back to the process environment when the file doesn't set it
+func NewEnvFileProbe(varName, file string) *EnvProbe {
+	probe := NewEnvProbe(varName)
+	probe.file = file
+	return probe
+}
+
 func (p *EnvProbe) Name() string { return p.name }
 
+func (p *EnvProbe) Kind() SensorKind { return SensorKindString }
+
 func (p *EnvProbe) Start(ctx context.Context, output chan<- SensorReading) {
 	// Env probes are checked on demand, not polled
 }
 
 func (p *EnvProbe) Check(ctx context.Context) SensorReading {
 	value := os.Getenv(p.varName)
+	if p.file != "" {
+		// A missing or unreadable file leaves the process environment in effect
+		if env, err := ReadEnvFile(p.file); err == nil {
+			if fileValue, ok := env[p.varName]; ok {
+				value = fileValue
+			}
+		}
+	}
 	return SensorReading{
 		Sensor:    p.name,
 		Value:     value,
 		Timestamp: time.Now(),
 	}
 }
+
+// ReadEnvFile reads KEY=VALUE pairs from a dotenv file. Blank lines and
+// comments are skipped, an "export " prefix is allowed, and matching single
+// or double quotes around a value are removed.
+func ReadEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, i+1)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	return env, nil
+}
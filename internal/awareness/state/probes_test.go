@@ -2,9 +2,15 @@ package state
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestEnvProbe_Check_ReadsSetEnvVar(t *testing.T) {
@@ -52,6 +58,44 @@ func TestEnvProbe_Check_ReturnsEmptyForUnsetVar(t *testing.T) {
 	}
 }
 
+func TestEnvFileProbe_Check(t *testing.T) {
+	t.Setenv("OVERSEER_TEST_CORP_NETWORK", "false")
+	t.Setenv("OVERSEER_TEST_DOCKED", "yes")
+
+	path := filepath.Join(t.TempDir(), "sensors.env")
+	probe := NewEnvFileProbe("OVERSEER_TEST_CORP_NETWORK", path)
+
+	// Without the file the process environment is used
+	if got := probe.Check(context.Background()).Value; got != "false" {
+		t.Errorf("missing file: value = %q, want %q", got, "false")
+	}
+
+	// The file overrides the process environment
+	content := "# Set by the VPN client\nexport OVERSEER_TEST_CORP_NETWORK=\"true\"\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := probe.Check(context.Background()).Value; got != "true" {
+		t.Errorf("value = %q, want %q from the file", got, "true")
+	}
+
+	// Variables the file doesn't set come from the process environment
+	docked := NewEnvFileProbe("OVERSEER_TEST_DOCKED", path)
+	if got := docked.Check(context.Background()).Value; got != "yes" {
+		t.Errorf("value = %q, want %q from the process environment", got, "yes")
+	}
+}
+
+func TestReadEnvFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sensors.env")
+	if err := os.WriteFile(path, []byte("CORP_NETWORK\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadEnvFile(path); err == nil {
+		t.Error("expected an error for a line without =")
+	}
+}
+
 func TestCollectEnvSensors_FromRuleConditions(t *testing.T) {
 	rules := []Rule{
 		{
@@ -498,3 +542,252 @@ func TestNetworkMonitorProbe_Check_ReturnsEmptyReading(t *testing.T) {
 		t.Error("expected non-zero timestamp")
 	}
 }
+
+// stubIPProvider starts an HTTP server that answers with the given body and status,
+// counting the requests it receives.
+func stubIPProvider(t *testing.T, status int, body string, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			hits.Add(1)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestProviderProbe returns an IPv4 probe with DNS fallback disabled
+func newTestProviderProbe(providers ...string) *IPProbe {
+	probe := NewIPv4Probe(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+	probe.resolvers = nil
+	probe.SetProviders(providers, time.Second)
+	return probe
+}
+
+func TestIPProbe_Providers_FirstSuccessWins(t *testing.T) {
+	var firstHits, secondHits atomic.Int32
+	first := stubIPProvider(t, http.StatusOK, "203.0.113.10\n", &firstHits)
+	second := stubIPProvider(t, http.StatusOK, "198.51.100.20", &secondHits)
+
+	probe := newTestProviderProbe(first.URL, second.URL)
+	reading := probe.Check(context.Background())
+
+	if reading.Value != "203.0.113.10" {
+		t.Errorf("expected IP from first provider, got %q", reading.Value)
+	}
+	if secondHits.Load() != 0 {
+		t.Errorf("expected second provider not to be queried, got %d hits", secondHits.Load())
+	}
+}
+
+func TestIPProbe_Providers_FallsBackInOrder(t *testing.T) {
+	var hits atomic.Int32
+	down := stubIPProvider(t, http.StatusServiceUnavailable, "", nil)
+	garbage := stubIPProvider(t, http.StatusOK, "<html>rate limited</html>", nil)
+	ipv6 := stubIPProvider(t, http.StatusOK, "2001:db8::1", nil)
+	good := stubIPProvider(t, http.StatusOK, "198.51.100.20", &hits)
+
+	probe := newTestProviderProbe(down.URL, garbage.URL, ipv6.URL, good.URL)
+	reading := probe.Check(context.Background())
+
+	if reading.Value != "198.51.100.20" {
+		t.Errorf("expected IP from last provider, got %q", reading.Value)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected working provider to be queried once, got %d", hits.Load())
+	}
+}
+
+func TestIPProbe_Providers_SlowProviderTimesOut(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(slow.Close)
+	good := stubIPProvider(t, http.StatusOK, "198.51.100.20", nil)
+
+	probe := newTestProviderProbe(slow.URL, good.URL)
+	probe.SetProviders([]string{slow.URL, good.URL}, 200*time.Millisecond)
+
+	start := time.Now()
+	reading := probe.Check(context.Background())
+	if reading.Value != "198.51.100.20" {
+		t.Errorf("expected IP from second provider, got %q", reading.Value)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected slow provider to time out quickly, took %v", elapsed)
+	}
+}
+
+func TestIPProbe_Providers_AllFail(t *testing.T) {
+	down := stubIPProvider(t, http.StatusInternalServerError, "", nil)
+	garbage := stubIPProvider(t, http.StatusOK, "not an ip", nil)
+
+	probe := newTestProviderProbe(down.URL, garbage.URL)
+	reading := probe.Check(context.Background())
+
+	if reading.Value != "169.254.0.0" {
+		t.Errorf("expected offline IP when all providers fail, got %q", reading.Value)
+	}
+}
+
+func TestIPProbe_Providers_ReusesLastGoodValueBriefly(t *testing.T) {
+	var failing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "203.0.113.10")
+	}))
+	t.Cleanup(srv.Close)
+
+	probe := newTestProviderProbe(srv.URL)
+	if reading := probe.Check(context.Background()); reading.Value != "203.0.113.10" {
+		t.Fatalf("expected initial IP, got %q", reading.Value)
+	}
+
+	// Provider starts rate-limiting: last good value is reused
+	failing.Store(true)
+	if reading := probe.Check(context.Background()); reading.Value != "203.0.113.10" {
+		t.Errorf("expected last good IP to be reused, got %q", reading.Value)
+	}
+
+	// Once the last good value is too old, report offline
+	probe.mu.Lock()
+	probe.lastGoodTime = time.Now().Add(-lastGoodIPMaxAge - time.Second)
+	probe.mu.Unlock()
+	if reading := probe.Check(context.Background()); reading.Value != "169.254.0.0" {
+		t.Errorf("expected offline IP after last good value expired, got %q", reading.Value)
+	}
+}
+
+func TestIPProbe_SetProviders_EmptyRestoresConsensus(t *testing.T) {
+	probe := newTestProviderProbe("https://example.com/ip")
+	probe.SetProviders(nil, 0)
+
+	probe.mu.Lock()
+	defer probe.mu.Unlock()
+	if len(probe.providers) != 0 {
+		t.Errorf("expected providers to be cleared, got %v", probe.providers)
+	}
+	if len(probe.httpURLs) == 0 {
+		t.Error("expected default HTTP URLs to remain for consensus check")
+	}
+}
+
+func TestIPProbe_CacheTTL_SecondCheckWithinTTLSkipsProvider(t *testing.T) {
+	var hits atomic.Int32
+	srv := stubIPProvider(t, http.StatusOK, "203.0.113.10", &hits)
+
+	probe := newTestProviderProbe(srv.URL)
+	probe.SetCacheTTL(time.Minute)
+
+	first := probe.Check(context.Background())
+	second := probe.Check(context.Background())
+
+	if first.Value != "203.0.113.10" || second.Value != "203.0.113.10" {
+		t.Errorf("expected cached IP on both checks, got %q and %q", first.Value, second.Value)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("expected provider to be queried once within TTL, got %d", hits.Load())
+	}
+}
+
+func TestIPProbe_CacheTTL_ExpiredCacheQueriesAgain(t *testing.T) {
+	var hits atomic.Int32
+	srv := stubIPProvider(t, http.StatusOK, "203.0.113.10", &hits)
+
+	probe := newTestProviderProbe(srv.URL)
+	probe.SetCacheTTL(time.Minute)
+	probe.Check(context.Background())
+
+	probe.mu.Lock()
+	probe.cachedAt = time.Now().Add(-2 * time.Minute)
+	probe.mu.Unlock()
+
+	probe.Check(context.Background())
+	if hits.Load() != 2 {
+		t.Errorf("expected provider to be queried again after TTL, got %d", hits.Load())
+	}
+}
+
+func TestIPProbe_CacheTTL_InvalidateForcesRefresh(t *testing.T) {
+	var hits atomic.Int32
+	srv := stubIPProvider(t, http.StatusOK, "203.0.113.10", &hits)
+
+	probe := newTestProviderProbe(srv.URL)
+	probe.SetCacheTTL(time.Minute)
+	probe.Check(context.Background())
+
+	probe.Invalidate()
+	probe.Check(context.Background())
+	if hits.Load() != 2 {
+		t.Errorf("expected provider to be queried after invalidation, got %d", hits.Load())
+	}
+}
+
+func TestIPProbe_CacheTTL_DisabledByDefault(t *testing.T) {
+	var hits atomic.Int32
+	srv := stubIPProvider(t, http.StatusOK, "203.0.113.10", &hits)
+
+	probe := newTestProviderProbe(srv.URL)
+	probe.Check(context.Background())
+	probe.Check(context.Background())
+	if hits.Load() != 2 {
+		t.Errorf("expected every check to query the provider without a TTL, got %d", hits.Load())
+	}
+}
+
+func TestIPProbe_CacheTTL_OfflineResultNotCached(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "203.0.113.10")
+	}))
+	t.Cleanup(srv.Close)
+
+	probe := newTestProviderProbe(srv.URL)
+	probe.SetCacheTTL(time.Minute)
+
+	if reading := probe.Check(context.Background()); reading.Value != "169.254.0.0" {
+		t.Fatalf("expected offline IP, got %q", reading.Value)
+	}
+
+	failing.Store(false)
+	if reading := probe.Check(context.Background()); reading.Value != "203.0.113.10" {
+		t.Errorf("expected fresh IP after offline result, got %q", reading.Value)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("expected provider queried twice, got %d", hits.Load())
+	}
+}
+
+func TestNetworkMonitorProbe_InvalidatePublicIPs(t *testing.T) {
+	ipv4 := NewIPv4Probe(nil)
+	ipv6 := NewIPv6Probe(nil)
+	for _, p := range []*IPProbe{ipv4, ipv6} {
+		p.SetCacheTTL(time.Minute)
+		p.cachedIP = "203.0.113.10"
+		p.cachedAt = time.Now()
+	}
+
+	monitor := NewNetworkMonitorProbe(ipv4, ipv6, nil, nil, nil)
+	monitor.invalidatePublicIPs()
+
+	for _, p := range []*IPProbe{ipv4, ipv6} {
+		if _, ok := p.cachedReading(); ok {
+			t.Errorf("expected %s cache to be invalidated", p.Name())
+		}
+	}
+}
@@ -2,6 +2,8 @@ package state
 
 import (
 	"net"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 )
@@ -26,18 +28,31 @@ type Location struct {
 	Condition   Condition           // Structured condition (supports nesting)
 	Environment map[string]string   // Custom environment variables
 	Hooks       *HooksConfig        // Enter/leave hooks
+
+	OverrideContextEnvironment bool // Environment wins over the context's on conflicting keys
 }
 
+// How a rule's locations are combined
+const (
+	LocationsModeAny = "any" // Match when in any of the listed locations (default)
+	LocationsModeAll = "all" // Match only when all listed locations match at once
+)
+
 // Rule represents a context rule that maps conditions to actions
 type Rule struct {
-	Name        string              // Context name (e.g., "home", "office")
-	DisplayName string              // Human-friendly display name
-	Locations   []string            // Location names this context can match
-	Conditions  map[string][]string // Simple sensor conditions
-	Condition   Condition           // Structured condition (supports nesting)
-	Actions     RuleActions         // Actions to take when matched
-	Environment map[string]string   // Custom environment variables
-	Hooks       *HooksConfig        // Enter/leave hooks
+	Name          string              // Context name (e.g., "home", "office")
+	DisplayName   string              // Human-friendly display name
+	Locations     []string            // Location names this context can match
+	LocationsMode string              // LocationsModeAny (default) or LocationsModeAll
+	Conditions    map[string][]string // Simple sensor conditions
+	Condition     Condition           // Structured condition (supports nesting)
+	Actions       RuleActions         // Actions to take when matched
+	Environment   map[string]string   // Custom environment variables
+	Hooks         *HooksConfig        // Enter/leave hooks
+
+	PreserveExisting bool // Skip the disconnect actions when entering this context
+	Reassert         bool // Reconnect dropped tunnels of the connect list while this context is active
+	DryRun           bool // Log the connect/disconnect actions instead of running them
 }
 
 // RuleActions defines what to do when a rule matches
@@ -54,6 +69,7 @@ type RuleResult struct {
 	LocationDisplayName string
 	MatchedRule         string
 	Environment         map[string]string
+	LocationConflicts   []string // Locations that match at the same time, see locationConflicts
 }
 
 // Condition represents a rule condition that can be evaluated
@@ -110,7 +126,12 @@ func (c *SensorCondition) Evaluate(readings map[string]SensorReading, online boo
 		return false
 	}
 
-	return matchesPattern(value, c.Pattern)
+	if matchesPattern(value, c.Pattern) {
+		return true
+	}
+
+	// IP sensors may also be matched against a hostname, e.g. a dynamic DNS name
+	return MatchesHostname(reading.IP, c.Pattern)
 }
 
 // GroupCondition combines multiple conditions with AND or OR logic
@@ -148,6 +169,16 @@ func (c *GroupCondition) Evaluate(readings map[string]SensorReading, online bool
 	}
 }
 
+// NotCondition negates a condition
+type NotCondition struct {
+	Condition Condition // Condition that must not be satisfied
+}
+
+// Evaluate checks that the negated condition is not satisfied
+func (c *NotCondition) Evaluate(readings map[string]SensorReading, online bool) bool {
+	return !c.Condition.Evaluate(readings, online)
+}
+
 // NewSensorCondition creates a condition for a string sensor
 func NewSensorCondition(sensorName, pattern string) *SensorCondition {
 	return &SensorCondition{
@@ -180,6 +211,11 @@ func NewAnyCondition(conditions ...Condition) *GroupCondition {
 	}
 }
 
+// NewNotCondition creates a condition that is satisfied when cond is not
+func NewNotCondition(cond Condition) *NotCondition {
+	return &NotCondition{Condition: cond}
+}
+
 // ConditionFromMap creates conditions from simple map format
 func ConditionFromMap(conditions map[string][]string) Condition {
 	if len(conditions) == 0 {
@@ -244,25 +280,45 @@ func NewRuleEngine(rules []Rule, locations map[string]Location, globalEnv map[st
 
 // Evaluate implements RuleEvaluator interface
 func (re *RuleEngine) Evaluate(readings map[string]SensorReading, online bool) RuleResult {
+	result := re.evaluateRules(readings, online)
+	result.LocationConflicts = re.locationConflicts(readings, online)
+	return result
+}
+
+// evaluateRules returns the result of the first matching rule
+func (re *RuleEngine) evaluateRules(readings map[string]SensorReading, online bool) RuleResult {
 	// Try each rule in order (first match wins)
 	for i := range re.rules {
 		rule := &re.rules[i]
 
-		// Check if any locations match
-		for _, locationName := range rule.Locations {
-			location, exists := re.locations[locationName]
-			if !exists {
-				continue
-			}
-
-			if re.locationMatches(&location, readings, online) {
+		// Check if the rule's locations match
+		if rule.LocationsMode == LocationsModeAll {
+			if location, ok := re.allLocationsMatch(rule, readings, online); ok {
 				return RuleResult{
 					Context:             rule.Name,
 					ContextDisplayName:  rule.DisplayName,
 					Location:            location.Name,
 					LocationDisplayName: location.DisplayName,
-					MatchedRule:         rule.Name + " (location: " + location.Name + ")",
-					Environment:         re.mergeEnvironment(rule, &location),
+					MatchedRule:         rule.Name + " (locations: " + strings.Join(rule.Locations, " + ") + ")",
+					Environment:         re.mergeEnvironment(rule, location),
+				}
+			}
+		} else {
+			for _, locationName := range rule.Locations {
+				location, exists := re.locations[locationName]
+				if !exists {
+					continue
+				}
+
+				if re.locationMatches(&location, readings, online) {
+					return RuleResult{
+						Context:             rule.Name,
+						ContextDisplayName:  rule.DisplayName,
+						Location:            location.Name,
+						LocationDisplayName: location.DisplayName,
+						MatchedRule:         rule.Name + " (location: " + location.Name + ")",
+						Environment:         re.mergeEnvironment(rule, &location),
+					}
 				}
 			}
 		}
@@ -304,6 +360,26 @@ func (re *RuleEngine) Evaluate(readings map[string]SensorReading, online bool) R
 	}
 }
 
+// allLocationsMatch reports whether every location listed by the rule
+// matches. The first listed location is returned as the current location.
+// An undefined location can never match, so it fails the whole rule.
+func (re *RuleEngine) allLocationsMatch(rule *Rule, readings map[string]SensorReading, online bool) (*Location, bool) {
+	if len(rule.Locations) == 0 {
+		return nil, false
+	}
+	var first *Location
+	for _, locationName := range rule.Locations {
+		location, exists := re.locations[locationName]
+		if !exists || !re.locationMatches(&location, readings, online) {
+			return nil, false
+		}
+		if first == nil {
+			first = &location
+		}
+	}
+	return first, true
+}
+
 // locationMatches checks if a location's conditions are satisfied
 func (re *RuleEngine) locationMatches(loc *Location, readings map[string]SensorReading, online bool) bool {
 	if loc.Condition != nil {
@@ -350,6 +426,53 @@ func (re *RuleEngine) determineLocation(readings map[string]SensorReading, onlin
 	return "unknown"
 }
 
+// locationConflicts returns the names of the locations that match at the
+// same time, sorted, or nil when at most one does. Overlapping conditions
+// make the location depend on evaluation order, which is most likely a
+// mistake in the config. The offline and unknown locations are fallbacks and
+// never conflict, and neither do locations a context requires together with
+// locations_mode = "all".
+func (re *RuleEngine) locationConflicts(readings map[string]SensorReading, online bool) []string {
+	var matching []string
+	for name, location := range re.locations {
+		if name == "offline" || name == "unknown" {
+			continue
+		}
+		if re.locationMatches(&location, readings, online) {
+			matching = append(matching, name)
+		}
+	}
+	sort.Strings(matching)
+
+	var conflicts []string
+	for i, a := range matching {
+		for _, b := range matching[i+1:] {
+			if re.locationsCombined(a, b) {
+				continue
+			}
+			if !slices.Contains(conflicts, a) {
+				conflicts = append(conflicts, a)
+			}
+			if !slices.Contains(conflicts, b) {
+				conflicts = append(conflicts, b)
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// locationsCombined reports whether a context with locations_mode = "all"
+// lists both locations, i.e. they are meant to match at the same time
+func (re *RuleEngine) locationsCombined(a, b string) bool {
+	for _, rule := range re.rules {
+		if rule.LocationsMode == LocationsModeAll && slices.Contains(rule.Locations, a) && slices.Contains(rule.Locations, b) {
+			return true
+		}
+	}
+	return false
+}
+
 // getLocation returns a location by name
 func (re *RuleEngine) getLocation(name string) *Location {
 	if loc, exists := re.locations[name]; exists {
@@ -367,7 +490,8 @@ func (re *RuleEngine) getLocationDisplayName(name string) string {
 }
 
 // mergeEnvironment merges global, location, and rule environment variables.
-// Merge priority (lowest → highest): Global → Location → Context (rule).
+// Merge priority (lowest → highest): Global → Location → Context (rule), or
+// Global → Context → Location when the location sets OverrideContextEnvironment.
 func (re *RuleEngine) mergeEnvironment(rule *Rule, location *Location) map[string]string {
 	env := make(map[string]string)
 
@@ -376,16 +500,22 @@ func (re *RuleEngine) mergeEnvironment(rule *Rule, location *Location) map[strin
 		env[k] = v
 	}
 
-	// Location environment overrides global
-	if location != nil && location.Environment != nil {
-		for k, v := range location.Environment {
-			env[k] = v
-		}
+	var locationEnv, ruleEnv map[string]string
+	if location != nil {
+		locationEnv = location.Environment
+	}
+	if rule != nil {
+		ruleEnv = rule.Environment
 	}
 
-	// Rule (context) environment overrides location and global (highest priority)
-	if rule != nil && rule.Environment != nil {
-		for k, v := range rule.Environment {
+	// Rule (context) environment overrides location and global (highest
+	// priority), unless the location asks to take precedence
+	layers := []map[string]string{locationEnv, ruleEnv}
+	if location != nil && location.OverrideContextEnvironment {
+		layers = []map[string]string{ruleEnv, locationEnv}
+	}
+	for _, layer := range layers {
+		for k, v := range layer {
 			env[k] = v
 		}
 	}
@@ -488,6 +618,8 @@ func extractSensorsRecursive(cond Condition, sensors map[string]bool) {
 		for _, child := range c.Conditions {
 			extractSensorsRecursive(child, sensors)
 		}
+	case *NotCondition:
+		extractSensorsRecursive(c.Condition, sensors)
 	}
 }
 
@@ -545,6 +677,8 @@ func collectEnvSensorsFromCondition(cond Condition, sensors map[string]bool) {
 		for _, child := range c.Conditions {
 			collectEnvSensorsFromCondition(child, sensors)
 		}
+	case *NotCondition:
+		collectEnvSensorsFromCondition(c.Condition, sensors)
 	}
 }
 
@@ -2,6 +2,7 @@ package state
 
 import (
 	"net"
+	"slices"
 	"testing"
 	"time"
 )
@@ -285,6 +286,41 @@ func TestGroupConditionAny(t *testing.T) {
 	}
 }
 
+func TestNotCondition(t *testing.T) {
+	readings := map[string]SensorReading{
+		"env:A": {Sensor: "env:A", Value: "x"},
+	}
+
+	if NewNotCondition(NewSensorCondition("env:A", "x")).Evaluate(readings, true) {
+		t.Error("NOT should fail when the condition matches")
+	}
+	if !NewNotCondition(NewSensorCondition("env:A", "y")).Evaluate(readings, true) {
+		t.Error("NOT should pass when the condition doesn't match")
+	}
+
+	// all{any{not{...}}} nests to any depth
+	cond := NewAllCondition(
+		NewSensorCondition("env:A", "x"),
+		NewAnyCondition(
+			NewSensorCondition("env:B", "y"),
+			NewNotCondition(NewSensorCondition("env:C", "z")),
+		),
+	)
+	if !cond.Evaluate(readings, true) {
+		t.Error("expected nested condition to pass when env:C is not set")
+	}
+	readings["env:C"] = SensorReading{Sensor: "env:C", Value: "z"}
+	if cond.Evaluate(readings, true) {
+		t.Error("expected nested condition to fail when env:C matches")
+	}
+
+	sensors := ExtractRequiredSensors(cond)
+	slices.Sort(sensors)
+	if want := []string{"env:A", "env:B", "env:C"}; !slices.Equal(sensors, want) {
+		t.Errorf("ExtractRequiredSensors() = %v, want %v", sensors, want)
+	}
+}
+
 func TestGroupConditionUnknownOperator(t *testing.T) {
 	cond := &GroupCondition{
 		Operator:   "invalid",
@@ -384,6 +420,132 @@ func TestRuleEngineLocationMatch(t *testing.T) {
 	}
 }
 
+func TestRuleEngineLocationConflicts(t *testing.T) {
+	locations := map[string]Location{
+		"office":  {Name: "office", Conditions: map[string][]string{"public_ipv4": {"203.0.113.0/24"}}},
+		"hq":      {Name: "hq", Conditions: map[string][]string{"public_ipv4": {"203.0.113.10"}}},
+		"docked":  {Name: "docked", Conditions: map[string][]string{"env:DOCKED": {"yes"}}},
+		"offline": {Name: "offline", Condition: NewBooleanCondition("online", false)},
+	}
+	readings := func(ip, docked string) map[string]SensorReading {
+		return map[string]SensorReading{
+			"public_ipv4": {Sensor: "public_ipv4", IP: net.ParseIP(ip)},
+			"env:DOCKED":  {Sensor: "env:DOCKED", Value: docked},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		readings map[string]SensorReading
+		online   bool
+		want     []string
+	}{
+		{"single match", nil, readings("203.0.113.20", "no"), true, nil},
+		{"overlapping locations", nil, readings("203.0.113.10", "no"), true, []string{"hq", "office"}},
+		{"all three overlap", nil, readings("203.0.113.10", "yes"), true, []string{"docked", "hq", "office"}},
+		{"offline is a fallback", nil, readings("203.0.113.20", "no"), false, nil},
+		{
+			"combined by locations_mode all",
+			[]Rule{{Name: "desk", Locations: []string{"office", "docked"}, LocationsMode: LocationsModeAll}},
+			readings("203.0.113.20", "yes"), true, nil,
+		},
+		{
+			"combined pair still conflicts with others",
+			[]Rule{{Name: "desk", Locations: []string{"office", "docked"}, LocationsMode: LocationsModeAll}},
+			readings("203.0.113.10", "yes"), true, []string{"docked", "hq", "office"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewRuleEngine(tt.rules, locations, nil)
+
+			result := engine.Evaluate(tt.readings, tt.online)
+			if !slices.Equal(result.LocationConflicts, tt.want) {
+				t.Errorf("LocationConflicts = %v, want %v", result.LocationConflicts, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleEngineLocationsMode(t *testing.T) {
+	locations := map[string]Location{
+		"office": {
+			Name:        "office",
+			DisplayName: "Office",
+			Conditions:  map[string][]string{"public_ipv4": {"5.6.7.8"}},
+		},
+		"docked": {
+			Name:        "docked",
+			DisplayName: "Docked",
+			Conditions:  map[string][]string{"env:DOCKED": {"yes"}},
+		},
+	}
+
+	officeOnly := map[string]SensorReading{
+		"public_ipv4": {Sensor: "public_ipv4", Value: "5.6.7.8"},
+	}
+	dockedOnly := map[string]SensorReading{
+		"env:DOCKED": {Sensor: "env:DOCKED", Value: "yes"},
+	}
+	both := map[string]SensorReading{
+		"public_ipv4": {Sensor: "public_ipv4", Value: "5.6.7.8"},
+		"env:DOCKED":  {Sensor: "env:DOCKED", Value: "yes"},
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		readings map[string]SensorReading
+		want     string
+	}{
+		{"any matches first location", LocationsModeAny, officeOnly, "desk"},
+		{"any matches second location", LocationsModeAny, dockedOnly, "desk"},
+		{"empty mode behaves as any", "", dockedOnly, "desk"},
+		{"all requires every location", LocationsModeAll, officeOnly, "untrusted"},
+		{"all rejects partial match", LocationsModeAll, dockedOnly, "untrusted"},
+		{"all matches when every location matches", LocationsModeAll, both, "desk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []Rule{
+				{Name: "desk", Locations: []string{"office", "docked"}, LocationsMode: tt.mode},
+				{Name: "untrusted"},
+			}
+			engine := NewRuleEngine(rules, locations, nil)
+
+			result := engine.Evaluate(tt.readings, true)
+			if result.Context != tt.want {
+				t.Errorf("Expected context %q, got %q", tt.want, result.Context)
+			}
+		})
+	}
+
+	t.Run("all reports the first listed location", func(t *testing.T) {
+		rules := []Rule{{Name: "desk", Locations: []string{"docked", "office"}, LocationsMode: LocationsModeAll}}
+		engine := NewRuleEngine(rules, locations, nil)
+
+		result := engine.Evaluate(both, true)
+		if result.Location != "docked" || result.LocationDisplayName != "Docked" {
+			t.Errorf("Expected location %q, got %q (%q)", "docked", result.Location, result.LocationDisplayName)
+		}
+	})
+
+	t.Run("all fails on an undefined location", func(t *testing.T) {
+		rules := []Rule{
+			{Name: "desk", Locations: []string{"office", "missing"}, LocationsMode: LocationsModeAll},
+			{Name: "untrusted"},
+		}
+		engine := NewRuleEngine(rules, locations, nil)
+
+		if result := engine.Evaluate(both, true); result.Context != "untrusted" {
+			t.Errorf("Expected context %q, got %q", "untrusted", result.Context)
+		}
+	})
+}
+
 func TestRuleEngineFallbackRule(t *testing.T) {
 	// A rule with no conditions and no locations = fallback
 	rules := []Rule{
@@ -495,6 +657,45 @@ func TestRuleEngineEnvironmentMerge(t *testing.T) {
 	}
 }
 
+func TestRuleEngineEnvironmentMerge_LocationOverridesContext(t *testing.T) {
+	locations := map[string]Location{
+		"home": {
+			Name:       "home",
+			Conditions: map[string][]string{"env:HOST": {"laptop"}},
+			Environment: map[string]string{
+				"OVERSEER_CONTEXT_BG": "#00ff00",
+				"FROM_LOCATION":       "loc-value",
+			},
+			OverrideContextEnvironment: true,
+		},
+	}
+
+	rules := []Rule{
+		{
+			Name:      "trusted",
+			Locations: []string{"home"},
+			Environment: map[string]string{
+				"OVERSEER_CONTEXT_BG": "#ff0000",
+				"FROM_RULE":           "rule-value",
+			},
+		},
+	}
+
+	global := map[string]string{"OVERSEER_CONTEXT_BG": "#3a579a"}
+	engine := NewRuleEngine(rules, locations, global)
+	readings := map[string]SensorReading{
+		"env:HOST": {Sensor: "env:HOST", Value: "laptop"},
+	}
+
+	result := engine.Evaluate(readings, true)
+	if got := result.Environment["OVERSEER_CONTEXT_BG"]; got != "#00ff00" {
+		t.Errorf("expected location to override context env var, got %q", got)
+	}
+	if result.Environment["FROM_LOCATION"] != "loc-value" || result.Environment["FROM_RULE"] != "rule-value" {
+		t.Errorf("expected non-conflicting keys from both, got %v", result.Environment)
+	}
+}
+
 func TestRuleEngineGetLocation(t *testing.T) {
 	locations := map[string]Location{
 		"home": {Name: "home", DisplayName: "Home"},
@@ -0,0 +1,98 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// sensorChange is a sensor value change waiting to be persisted
+type sensorChange struct {
+	Effect     string // Effect name reported when the change is written
+	Sensor     string
+	SensorType string
+	From       string
+	To         string
+	At         time.Time // When the value changed to To
+}
+
+// sensorChangeDebouncer holds back sensor changes until the new value has
+// been stable for the debounce window, so a flapping link records one row
+// per settled change instead of one per flap. A change that reverts within
+// the window (a blip) is not recorded at all.
+type sensorChangeDebouncer struct {
+	window time.Duration
+	write  func(sensorChange)
+
+	mu      sync.Mutex
+	pending map[string]*pendingSensorChange
+}
+
+type pendingSensorChange struct {
+	change sensorChange
+	timer  *time.Timer
+}
+
+func newSensorChangeDebouncer(window time.Duration, write func(sensorChange)) *sensorChangeDebouncer {
+	return &sensorChangeDebouncer{
+		window:  window,
+		write:   write,
+		pending: make(map[string]*pendingSensorChange),
+	}
+}
+
+// Record queues a change. While a change for the same sensor is pending, the
+// original From is kept and only To is updated, restarting the window.
+func (d *sensorChangeDebouncer) Record(change sensorChange) {
+	if d.window <= 0 {
+		d.write(change)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Each change gets its own entry, so a timer of a superseded change that
+	// already fired finds it replaced in settle and doesn't write it early
+	if previous, exists := d.pending[change.Sensor]; exists {
+		previous.timer.Stop()
+		change.From = previous.change.From
+	}
+	p := &pendingSensorChange{change: change}
+	d.pending[change.Sensor] = p
+	p.timer = time.AfterFunc(d.window, func() { d.settle(p) })
+}
+
+// settle writes a pending change once its window has passed without another change
+func (d *sensorChangeDebouncer) settle(p *pendingSensorChange) {
+	d.mu.Lock()
+	if d.pending[p.change.Sensor] != p {
+		// Superseded or flushed in the meantime
+		d.mu.Unlock()
+		return
+	}
+	delete(d.pending, p.change.Sensor)
+	change := p.change
+	d.mu.Unlock()
+
+	if change.From != change.To {
+		d.write(change)
+	}
+}
+
+// Flush writes all pending changes immediately, e.g. on shutdown
+func (d *sensorChangeDebouncer) Flush() {
+	d.mu.Lock()
+	changes := make([]sensorChange, 0, len(d.pending))
+	for sensor, p := range d.pending {
+		p.timer.Stop()
+		delete(d.pending, sensor)
+		if p.change.From != p.change.To {
+			changes = append(changes, p.change)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, change := range changes {
+		d.write(change)
+	}
+}
@@ -0,0 +1,154 @@
+package state
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDBLogger records sensor change rows instead of writing them
+type recordingDBLogger struct {
+	mu   sync.Mutex
+	rows []sensorChange
+}
+
+func (r *recordingDBLogger) LogSensorChange(sensor, sensorType, oldValue, newValue string) error {
+	return r.LogSensorChangeAt(sensor, sensorType, oldValue, newValue, time.Now())
+}
+
+func (r *recordingDBLogger) LogSensorChangeAt(sensor, sensorType, oldValue, newValue string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rows = append(r.rows, sensorChange{Sensor: sensor, SensorType: sensorType, From: oldValue, To: newValue, At: at})
+	return nil
+}
+
+func (r *recordingDBLogger) LogContextChange(fromContext, toContext, fromLocation, toLocation, trigger string) error {
+	return nil
+}
+
+func (r *recordingDBLogger) Rows() []sensorChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]sensorChange(nil), r.rows...)
+}
+
+func onlineTransition(from, to bool, at time.Time) StateTransition {
+	return StateTransition{
+		From:          StateSnapshot{Online: from},
+		To:            StateSnapshot{Online: to, Timestamp: at},
+		Trigger:       "test",
+		ChangedFields: []string{"online"},
+	}
+}
+
+func TestEffectsProcessorSensorDebounce_BlipNotRecorded(t *testing.T) {
+	dbLogger := &recordingDBLogger{}
+	ep := NewEffectsProcessor(make(chan StateTransition), EffectsProcessorConfig{
+		DatabaseLogger: dbLogger,
+		SensorDebounce: 50 * time.Millisecond,
+	})
+
+	// online -> offline -> online within the window
+	now := time.Now()
+	ep.logToDatabase(onlineTransition(true, false, now))
+	ep.logToDatabase(onlineTransition(false, true, now.Add(10*time.Millisecond)))
+
+	time.Sleep(150 * time.Millisecond)
+	if rows := dbLogger.Rows(); len(rows) != 0 {
+		t.Errorf("expected a blip to record no rows, got %+v", rows)
+	}
+}
+
+func TestEffectsProcessorSensorDebounce_FlapsCollapse(t *testing.T) {
+	dbLogger := &recordingDBLogger{}
+	ep := NewEffectsProcessor(make(chan StateTransition), EffectsProcessorConfig{
+		DatabaseLogger: dbLogger,
+		SensorDebounce: 50 * time.Millisecond,
+	})
+
+	// The IP flaps through several values before settling on a new one
+	ips := []string{"1.1.1.1", "2.2.2.2", "1.1.1.1", "3.3.3.3"}
+	settledAt := time.Now()
+	for i := 1; i < len(ips); i++ {
+		settledAt = time.Now()
+		ep.logToDatabase(StateTransition{
+			From:          StateSnapshot{PublicIPv4: net.ParseIP(ips[i-1])},
+			To:            StateSnapshot{PublicIPv4: net.ParseIP(ips[i]), Timestamp: settledAt},
+			ChangedFields: []string{"ipv4"},
+		})
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	rows := dbLogger.Rows()
+	if len(rows) != 1 {
+		t.Fatalf("expected flaps to collapse into 1 row, got %+v", rows)
+	}
+	want := sensorChange{Sensor: "public_ipv4", SensorType: "string", From: "1.1.1.1", To: "3.3.3.3", At: settledAt}
+	if rows[0] != want {
+		t.Errorf("row = %+v, want %+v", rows[0], want)
+	}
+}
+
+func TestEffectsProcessorSensorDebounce_FlushOnStop(t *testing.T) {
+	dbLogger := &recordingDBLogger{}
+	ep := NewEffectsProcessor(make(chan StateTransition), EffectsProcessorConfig{
+		DatabaseLogger: dbLogger,
+		SensorDebounce: time.Hour,
+	})
+	ep.Start()
+
+	ep.logToDatabase(onlineTransition(true, false, time.Now()))
+	ep.Stop()
+
+	if rows := dbLogger.Rows(); len(rows) != 1 || rows[0].To != "false" {
+		t.Errorf("expected the settling change to be written on stop, got %+v", rows)
+	}
+}
+
+func TestEffectsProcessorSensorDebounce_Disabled(t *testing.T) {
+	dbLogger := &recordingDBLogger{}
+	ep := NewEffectsProcessor(make(chan StateTransition), EffectsProcessorConfig{
+		DatabaseLogger: dbLogger,
+	})
+
+	now := time.Now()
+	ep.logToDatabase(onlineTransition(true, false, now))
+	ep.logToDatabase(onlineTransition(false, true, now))
+
+	if rows := dbLogger.Rows(); len(rows) != 2 {
+		t.Errorf("expected every change to be recorded without debouncing, got %+v", rows)
+	}
+}
+
+func TestSensorChangeDebouncer_StaleTimerIgnored(t *testing.T) {
+	var mu sync.Mutex
+	var written []sensorChange
+	d := newSensorChangeDebouncer(time.Hour, func(change sensorChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, change)
+	})
+
+	d.Record(sensorChange{Sensor: "online", From: "true", To: "false"})
+	d.mu.Lock()
+	first := d.pending["online"]
+	d.mu.Unlock()
+	d.Record(sensorChange{Sensor: "online", From: "false", To: "unknown"})
+
+	// The first timer fired just before the second change stopped it
+	d.settle(first)
+	mu.Lock()
+	if len(written) != 0 {
+		t.Errorf("expected a superseded change not to be written, got %+v", written)
+	}
+	mu.Unlock()
+
+	d.Flush()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(written) != 1 || written[0].From != "true" || written[0].To != "unknown" {
+		t.Errorf("expected one change from the original value to the latest, got %+v", written)
+	}
+}
@@ -34,6 +34,22 @@ type SensorReading struct {
 	Latency time.Duration
 }
 
+// SensorKind describes the type of value a sensor reports
+type SensorKind string
+
+const (
+	SensorKindBool   SensorKind = "bool"   // Reported in SensorReading.Online
+	SensorKindString SensorKind = "string" // Reported in SensorReading.Value
+	SensorKindIP     SensorKind = "ip"     // Reported in SensorReading.IP
+)
+
+// SensorInfo describes a sensor and its current value
+type SensorInfo struct {
+	Name  string
+	Kind  SensorKind
+	Value string // Current value formatted as a string ("" if not read yet)
+}
+
 // StateSnapshot represents the authoritative state at a point in time.
 // Snapshots are immutable and safe to share across goroutines.
 type StateSnapshot struct {
@@ -72,6 +88,9 @@ type StateSnapshot struct {
 
 	// Environment contains the merged environment variables for this state
 	Environment map[string]string
+
+	// LocationConflicts lists the locations that all matched, when more than one did
+	LocationConflicts []string
 }
 
 // StateTransition represents a change from one state to another.
@@ -11,9 +11,12 @@ import (
 )
 
 const (
-	BaseDirName = ".config/overseer"
-	PidFileName = "daemon.pid"
-	SocketName  = "daemon.sock"
+	BaseDirName    = ".config/overseer"
+	PidFileName    = "daemon.pid"
+	SocketName     = "daemon.sock"
+	ConfigFileName = "config.hcl"
+	ConfigDDirName = "config.d"
+	DatabaseName   = "overseer.db"
 )
 
 // ProcessTag returns an 8-char hex tag derived from Config.ConfigPath.
@@ -34,6 +37,52 @@ func GetPIDFilePath() string {
 	return filepath.Join(Config.ConfigPath, PidFileName)
 }
 
+// GetConfigFilePath returns the path to the main config file
+func GetConfigFilePath() string {
+	if Config.ConfigFile != "" {
+		return Config.ConfigFile
+	}
+	return filepath.Join(Config.ConfigPath, ConfigFileName)
+}
+
+// GetConfigDPath returns the path to the config.d fragment directory
+func GetConfigDPath() string {
+	return filepath.Join(Config.ConfigPath, ConfigDDirName)
+}
+
+// GetDatabasePath returns the path to the event database
+func GetDatabasePath() string {
+	return filepath.Join(Config.ConfigPath, DatabaseName)
+}
+
+// ExpandHomeDir expands a leading ~ or ~/ to the user's home directory
+func ExpandHomeDir(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// ResolveConfigLocation returns the config directory and main config file.
+// configFile is the value of --config and may name either a file or a
+// directory; it takes precedence over configDir (--config-path) when set.
+func ResolveConfigLocation(configDir, configFile string) (dir, file string) {
+	if configFile == "" {
+		return configDir, filepath.Join(configDir, ConfigFileName)
+	}
+	if abs, err := filepath.Abs(configFile); err == nil {
+		configFile = abs
+	}
+	if info, err := os.Stat(configFile); err == nil && info.IsDir() {
+		return configFile, filepath.Join(configFile, ConfigFileName)
+	}
+	return filepath.Dir(configFile), configFile
+}
+
 // InitializeConfig loads the configuration from the HCL file
 func InitializeConfig(cmd *cobra.Command) ([]string, error) {
 	// Get config path from user input.
@@ -46,25 +95,17 @@ func InitializeConfig(cmd *cobra.Command) ([]string, error) {
 	if err != nil {
 		panic("Unable to determine config path")
 	}
+	configFile, _ := flagSource.Flags().GetString("config")
 
 	// Load HCL config
-	hclPath := filepath.Join(configDir, "config.hcl")
-	configDPath := filepath.Join(configDir, "config.d")
+	configDir, hclPath := ResolveConfigLocation(configDir, configFile)
+	configDPath := filepath.Join(configDir, ConfigDDirName)
 	if _, err := os.Stat(hclPath); err == nil {
 		// HCL file exists, parse it (along with any config.d/ fragments)
 		Config, err = LoadConfigDir(hclPath, configDPath)
 		if err != nil {
-			// Clean up the error message
-			errMsg := err.Error()
-			errMsg = strings.TrimPrefix(errMsg, "failed to parse HCL config: ")
-
-			// Remove the visual pointer (everything after the line/column info)
-			if idx := strings.Index(errMsg, ":\n"); idx != -1 {
-				errMsg = errMsg[:idx]
-			}
-
 			fmt.Fprintf(os.Stderr, "Error: Configuration has errors\n")
-			fmt.Fprintf(os.Stderr, "  %s\n", errMsg)
+			fmt.Fprintf(os.Stderr, "  %s\n", err)
 			os.Exit(1)
 		}
 	} else {
@@ -88,6 +129,7 @@ func InitializeConfig(cmd *cobra.Command) ([]string, error) {
 
 	// Set the config path
 	Config.ConfigPath = configDir
+	Config.ConfigFile = hclPath
 
 	// Override verbose from command-line flag if provided
 	if cmd != nil {
@@ -113,6 +155,7 @@ verbose = 0
 #   context   = "/path/to/context.txt"       # Context name only
 #   location  = "/path/to/location.txt"      # Location name only
 #   public_ip = "/path/to/public_ip.txt"     # Public IP only
+#   write_mode = "atomic"                  # atomic (default) or inplace for inotify watchers
 # }
 
 # SSH connection settings
@@ -127,6 +170,7 @@ ssh {
   max_backoff       = "5m"    # Maximum delay between retries
   backoff_factor    = 2       # Multiplier for each retry
   max_retries       = 10      # Give up after this many attempts
+  stable_reset      = "0s"    # Keep backing off until connected this long (0s = start over on every reconnect)
 }
 
 # Location definitions - reusable network/physical locations
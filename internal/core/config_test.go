@@ -3,8 +3,11 @@ package core
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/spf13/cobra"
 )
 
 func TestGetSocketPath(t *testing.T) {
@@ -105,6 +108,106 @@ func TestProcessTag(t *testing.T) {
 	})
 }
 
+func TestExpandHomeDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~/test", filepath.Join(home, "test")},
+		{"~/some/path", filepath.Join(home, "some/path")},
+		{"~", home},
+		{"~other/path", "~other/path"},
+		{"/absolute/path", "/absolute/path"},
+		{"relative/path", "relative/path"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ExpandHomeDir(tt.path); got != tt.want {
+			t.Errorf("ExpandHomeDir(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResolveConfigLocation(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "custom.hcl")
+
+	tests := []struct {
+		name       string
+		configDir  string
+		configFile string
+		wantDir    string
+		wantFile   string
+	}{
+		{"default", "/home/alice/.config/overseer", "", "/home/alice/.config/overseer", "/home/alice/.config/overseer/config.hcl"},
+		{"explicit file", "/home/alice/.config/overseer", file, dir, file},
+		{"explicit directory", "/home/alice/.config/overseer", dir, dir, filepath.Join(dir, ConfigFileName)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDir, gotFile := ResolveConfigLocation(tt.configDir, tt.configFile)
+			if gotDir != tt.wantDir || gotFile != tt.wantFile {
+				t.Errorf("ResolveConfigLocation() = (%q, %q), want (%q, %q)", gotDir, gotFile, tt.wantDir, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestInitializeConfig_ConfigFlag(t *testing.T) {
+	original := Config
+	defer func() { Config = original }()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "overseer.hcl")
+	if err := os.WriteFile(file, []byte("verbose = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ConfigDDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fragment := "environment = {\n  FROM_FRAGMENT = \"yes\"\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, ConfigDDirName, "extra.hcl"), []byte(fragment), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{Use: "overseer"}
+	cmd.Flags().String("config-path", filepath.Join(t.TempDir(), "unused"), "")
+	cmd.Flags().String("config", file, "")
+	cmd.Flags().CountP("verbose", "v", "")
+
+	if _, err := InitializeConfig(cmd); err != nil {
+		t.Fatalf("InitializeConfig() error: %v", err)
+	}
+
+	// Loaded from the overridden file and its sibling config.d
+	if Config.Verbose != 2 {
+		t.Errorf("Verbose = %d, want 2 from %s", Config.Verbose, file)
+	}
+	if Config.Environment["FROM_FRAGMENT"] != "yes" {
+		t.Errorf("expected config.d fragment next to %s to be merged", file)
+	}
+
+	// Everything else is derived from the same directory
+	if Config.ConfigPath != dir {
+		t.Errorf("ConfigPath = %q, want %q", Config.ConfigPath, dir)
+	}
+	if got := GetConfigFilePath(); got != file {
+		t.Errorf("GetConfigFilePath() = %q, want %q", got, file)
+	}
+	if got, want := GetSocketPath(), filepath.Join(dir, SocketName); got != want {
+		t.Errorf("GetSocketPath() = %q, want %q", got, want)
+	}
+	if got, want := GetDatabasePath(), filepath.Join(dir, DatabaseName); got != want {
+		t.Errorf("GetDatabasePath() = %q, want %q", got, want)
+	}
+}
+
 func TestWriteDefaultHCLConfig(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.hcl")
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Warning is a likely mistake in the configuration that doesn't stop it from
+// loading, such as a context referencing a location that isn't defined
+type Warning struct {
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// locationWarnings checks the locations and the contexts referencing them in
+// a converted configuration
+func locationWarnings(cfg *Configuration) []Warning {
+	var warnings []Warning
+
+	for _, rule := range cfg.Contexts {
+		for _, name := range rule.Locations {
+			// The daemon always provides the offline location
+			if _, exists := cfg.Locations[name]; !exists && name != "offline" {
+				warnings = append(warnings, Warning{fmt.Sprintf("context %q references undefined location %q", rule.Name, name)})
+			}
+		}
+	}
+
+	// Locations are checked in no particular order, so which of two
+	// identical locations is detected is undefined
+	names := make([]string, 0, len(cfg.Locations))
+	for name := range cfg.Locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, a := range names {
+		if cfg.Locations[a].Condition == nil {
+			continue
+		}
+		for _, b := range names[i+1:] {
+			if reflect.DeepEqual(cfg.Locations[a].Condition, cfg.Locations[b].Condition) {
+				warnings = append(warnings, Warning{fmt.Sprintf("locations %q and %q have identical conditions, which of them is detected is undefined", a, b)})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// ActionTunnelWarnings checks that the tunnels named in the contexts' connect
+// and disconnect lists exist. A tunnel doesn't need a tunnel block, so known
+// reports whether a name without one is a host of the SSH config. Glob
+// patterns are expanded when the context is entered, and warned about there
+// when they match nothing.
+func ActionTunnelWarnings(cfg *Configuration, known func(alias string) bool) []Warning {
+	var warnings []Warning
+
+	for _, rule := range cfg.Contexts {
+		for _, list := range []struct {
+			name    string
+			entries []string
+		}{
+			{"connect", rule.Actions.Connect},
+			{"disconnect", rule.Actions.Disconnect},
+		} {
+			for _, entry := range list.entries {
+				if _, exists := cfg.Tunnels[entry]; exists || IsTunnelPattern(entry) || known(entry) {
+					continue
+				}
+				warnings = append(warnings, Warning{fmt.Sprintf("context %q: %s references tunnel %q, which is neither a tunnel block nor an SSH host", rule.Name, list.name, entry)})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// IsTunnelPattern reports whether an entry of a connect or disconnect list is
+// a glob pattern rather than a tunnel name
+func IsTunnelPattern(entry string) bool {
+	return strings.ContainsAny(entry, "*?[")
+}
+
+// hclConfigEmpty reports whether a parsed file contributes nothing to the
+// configuration: no field is set, so new settings and blocks are covered
+// without listing them here. The decoder always sets EnvironmentExpr, the
+// environment resolved from it is checked instead.
+func hclConfigEmpty(hclCfg *hclConfig) bool {
+	v := reflect.ValueOf(*hclCfg)
+	for i := range v.NumField() {
+		if v.Type().Field(i).Name == "EnvironmentExpr" {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Slice, reflect.Map:
+			if field.Len() > 0 {
+				return false
+			}
+		default:
+			if !field.IsZero() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hclFileBlank reports whether a file holds nothing but comments and
+// whitespace. Such a file is an intentional no-op, unlike a file whose
+// content doesn't add anything.
+func hclFileBlank(filename string) bool {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+	tokens, _ := hclsyntax.LexConfig(src, filename, hcl.InitialPos)
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenComment, hclsyntax.TokenNewline, hclsyntax.TokenEOF:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -1,35 +1,57 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
 	"go.olrik.dev/overseer/internal/awareness"
 )
 
 // Config is the global configuration instance
 var Config *Configuration
 
+// defaultSensorDebounce collapses sensor flaps shorter than this into no database row
+const defaultSensorDebounce = 5 * time.Second
+
+// defaultSensorWatchdog is how long the state manager may go without
+// evaluating a sensor reading before it is restarted. The TCP probe alone
+// reports every 10 seconds.
+const defaultSensorWatchdog = 2 * time.Minute
+
 // ExportConfig represents a single export configuration
 type ExportConfig struct {
-	Type string // Export type: "dotenv", "context", "location", "public_ip"
-	Path string // File path to write to
+	Type     string // Export type: "dotenv", "context", "location", "public_ip", "public_ipv4", "public_ipv6", "template"
+	Path     string // File path to write to
+	Template string // Go text/template file rendered to Path (template exports only)
+	InPlace  bool   // Truncate and rewrite the file instead of replacing it atomically
 }
 
 // Configuration represents the complete Overseer configuration
 type Configuration struct {
 	ConfigPath  string                   // Directory containing config files
+	ConfigFile  string                   // Main config file (empty = config.hcl in ConfigPath)
 	Verbose     int                      // Verbosity level
 	Environment map[string]string        // Global default environment variables
 	Exports     []ExportConfig           // Export configurations
 	PreferredIP string                   // Preferred IP version for OVERSEER_PUBLIC_IP: "ipv4" (default) or "ipv6"
 	SSH         SSHConfig                // SSH connection settings (including reconnect)
+	PublicIP    PublicIPSettings         // Public IP detection settings
 	Companion   CompanionSettings        // Global companion script settings
+	Remote      RemoteSettings           // Settings for a daemon running in an SSH session
 	Locations   map[string]*Location     // Location definitions keyed by location name
 	Contexts    []*ContextRule           // Context rules in evaluation order (first match wins)
 	Tunnels     map[string]*TunnelConfig // Per-tunnel configurations keyed by tunnel name
@@ -40,17 +62,88 @@ type Configuration struct {
 	// Context behavior settings
 	CheckOnStartup       bool
 	CheckOnNetworkChange bool
+	// How long a sensor value must hold before its change is recorded in the database (0 = record every change)
+	SensorDebounce time.Duration
+	// Restart the state manager when it hasn't evaluated a sensor reading for this long (0 = never)
+	SensorWatchdog time.Duration
+	// Dotenv file env conditions are matched against, overriding the daemon's environment
+	SensorEnvFile string
+	// Coalesce tunnel events of the same type and alias logged closer together than this (0 = log every event)
+	EventMinInterval time.Duration
+	// Likely mistakes found while loading, reported at daemon start
+	Warnings []Warning
+	// Files pulled in by include, from the main file and config.d alike, in the order they were merged
+	IncludedFiles []string
 }
 
 // SSHConfig represents SSH connection settings
 type SSHConfig struct {
-	ServerAliveInterval int    // Send keepalive every N seconds (0 to disable)
-	ServerAliveCountMax int    // Exit after N failed keepalives
-	ReconnectEnabled    bool   // Enable/disable auto-reconnect
-	InitialBackoff      string // First retry delay
-	MaxBackoff          string // Maximum delay between retries
-	BackoffFactor       int    // Multiplier for each retry
-	MaxRetries          int    // Give up after this many attempts
+	ServerAliveInterval int           // Send keepalive every N seconds (0 to disable)
+	ServerAliveCountMax int           // Exit after N failed keepalives
+	ReconnectEnabled    bool          // Enable/disable auto-reconnect
+	InitialBackoff      string        // First retry delay
+	MaxBackoff          string        // Maximum delay between retries
+	BackoffFactor       int           // Multiplier for each retry
+	MaxRetries          int           // Give up after this many attempts
+	StableReset         time.Duration // Reset the retry count once a connection has held this long (0 = on every reconnect)
+	Askpass             string        // Custom askpass helper program (empty = overseer answers with stored passwords)
+	NoControlMaster     bool          // Pass ControlMaster=no, never creating or joining an SSH multiplexing master
+	QuietAfterConnect   bool          // Discard SSH's debug output once a tunnel is connected instead of logging it
+	// Keepalive settings keyed by power source ("on_battery", "on_ac")
+	KeepaliveProfiles map[string]KeepaliveProfile
+}
+
+// KeepaliveProfile overrides the keepalive settings while a sensor is in a given state (0 = inherit)
+type KeepaliveProfile struct {
+	Interval int // Send keepalive every N seconds
+	CountMax int // Exit after N failed keepalives
+}
+
+// WithKeepaliveProfile returns a copy of the SSH settings with the keepalive
+// profile for the given power sensor value ("ac" or "battery") applied
+// (unchanged if the source is unknown or has no profile)
+func (c SSHConfig) WithKeepaliveProfile(powerSource string) SSHConfig {
+	if powerSource == "" {
+		return c
+	}
+	profile, ok := c.KeepaliveProfiles[keepaliveProfileKey(powerSource)]
+	if !ok {
+		return c
+	}
+	if profile.Interval > 0 {
+		c.ServerAliveInterval = profile.Interval
+	}
+	if profile.CountMax > 0 {
+		c.ServerAliveCountMax = profile.CountMax
+	}
+	return c
+}
+
+// SSHOverrides represents per-context overrides of the global SSH settings (0 = inherit)
+type SSHOverrides struct {
+	ServerAliveInterval int // Send keepalive every N seconds
+	ServerAliveCountMax int // Exit after N failed keepalives
+}
+
+// WithOverrides returns a copy of the SSH settings with the non-zero overrides applied
+func (c SSHConfig) WithOverrides(o *SSHOverrides) SSHConfig {
+	if o == nil {
+		return c
+	}
+	if o.ServerAliveInterval > 0 {
+		c.ServerAliveInterval = o.ServerAliveInterval
+	}
+	if o.ServerAliveCountMax > 0 {
+		c.ServerAliveCountMax = o.ServerAliveCountMax
+	}
+	return c
+}
+
+// PublicIPSettings represents public IP detection settings
+type PublicIPSettings struct {
+	Providers []string      // HTTP providers tried in order (empty = built-in consensus check)
+	Timeout   time.Duration // Per-provider request timeout (default 3s)
+	CacheTTL  time.Duration // Reuse a detected IP for this long (0 = always query)
 }
 
 // CompanionSettings represents global companion script settings
@@ -58,6 +151,22 @@ type CompanionSettings struct {
 	HistorySize int // Ring buffer size for output history (default 1000)
 }
 
+// RemoteSettings represents settings for a daemon running in an SSH session
+type RemoteSettings struct {
+	ShutdownSignals    []string      // Signals that shut the daemon down, without the SIG prefix (default HUP)
+	ParentPollInterval time.Duration // How often to check the process the daemon was started from (0 = never)
+}
+
+// defaultParentPollInterval is how often a remote daemon checks that the
+// process it was started from is still alive
+const defaultParentPollInterval = 5 * time.Second
+
+// remoteShutdownSignals are the signals remote.shutdown_signals may list.
+// TERM and INT always shut the daemon down, and USR1 reloads the configuration.
+// PIPE isn't allowed, as any write to a dropped connection raises it, e.g.
+// when a client quits `overseer logs`.
+var remoteShutdownSignals = []string{"HUP", "QUIT", "USR2"}
+
 // Location represents a physical or network location with sensor conditions
 type Location struct {
 	Name        string              // Location name (e.g., "hq", "home")
@@ -66,18 +175,29 @@ type Location struct {
 	Condition   interface{}         // Structured condition (supports nesting with any/all) - will be awareness.Condition
 	Environment map[string]string   // Custom environment variables to export
 	Hooks       *HooksConfig        // Enter/leave hooks
+	Sources     []string            // Config files the location was defined in
+
+	OverrideContextEnvironment bool // Location environment wins over the context's on conflicting keys
 }
 
 // ContextRule represents a context rule
 type ContextRule struct {
-	Name        string              // Context name (e.g., "home", "office")
-	DisplayName string              // Human-friendly display name
-	Locations   []string            // Location names this context applies to
-	Conditions  map[string][]string // Simple sensor conditions (e.g., "public_ip": ["1.2.3.4", "5.6.7.0/24"])
-	Condition   interface{}         // Structured condition (supports nesting with any/all) - will be awareness.Condition
-	Actions     ContextActions      // Actions to take when entering this context
-	Environment map[string]string   // Custom environment variables to export
-	Hooks       *HooksConfig        // Enter/leave hooks
+	Name             string              // Context name (e.g., "home", "office")
+	DisplayName      string              // Human-friendly display name
+	Locations        []string            // Location names this context applies to
+	LocationsMode    string              // "any" (default) or "all" - whether one or every location must match
+	Conditions       map[string][]string // Simple sensor conditions (e.g., "public_ip": ["1.2.3.4", "5.6.7.0/24"])
+	Condition        interface{}         // Structured condition (supports nesting with any/all) - will be awareness.Condition
+	Actions          ContextActions      // Actions to take when entering this context
+	PreserveExisting bool                // Skip the disconnect actions, only ever add tunnels
+	Reassert         bool                // Periodically reconnect dropped tunnels of the connect list while active
+	Disabled         bool                // Skipped during rule evaluation, as if it weren't defined
+	DryRun           bool                // Log the connect/disconnect actions instead of running them
+	Environment      map[string]string   // Custom environment variables to export
+	Exports          []ExportConfig      // Exports written only while this context is active
+	Hooks            *HooksConfig        // Enter/leave hooks
+	SSH              *SSHOverrides       // SSH overrides for tunnels connected by this context
+	Sources          []string            // Config files the context was defined (and merged) from
 }
 
 // ContextActions represents actions for a context
@@ -88,33 +208,45 @@ type ContextActions struct {
 
 // TunnelConfig represents per-tunnel configuration
 type TunnelConfig struct {
-	Name        string             // Tunnel name (matches SSH alias)
-	Environment map[string]string  // Environment variables set on the SSH process (used with Match exec in ssh_config)
-	Companions  []CompanionConfig  // Companion scripts to run before tunnel starts
-	Hooks       *TunnelHooksConfig // Lifecycle hooks for tunnel connection
+	Name             string             // Tunnel name (matches SSH alias)
+	Description      string             // Free-form note shown in status (informational only)
+	Environment      map[string]string  // Environment variables set on the SSH process (used with Match exec in ssh_config)
+	Companions       []CompanionConfig  // Companion scripts to run before tunnel starts
+	Hooks            *TunnelHooksConfig // Lifecycle hooks for tunnel connection
+	MaxLifetime      time.Duration      // Reconnect proactively once connected this long (0 = never)
+	PasswordCommand  string             // Command printing the SSH password, used instead of the keyring
+	RemoteCommand    string             // Command run on the server instead of connecting with -N
+	Disabled         bool               // Refuse to connect, neither manually nor by contexts
+	ConnectOnStartup bool               // Connect when the daemon starts, regardless of contexts
+	Sources          []string           // Config files the tunnel was defined in
 }
 
 // TunnelHooksConfig represents hooks for tunnel lifecycle events
 type TunnelHooksConfig struct {
 	BeforeConnect []HookConfig // Commands to run before SSH connection attempt
 	AfterConnect  []HookConfig // Commands to run after successful connection
+	OnGiveUp      []HookConfig // Commands to run when reconnecting is given up after max_retries
 }
 
 // CompanionConfig represents a companion script configuration
 type CompanionConfig struct {
 	Name        string            // Unique identifier within tunnel
-	Command     string            // Command to execute
+	Command     string            // Command to execute, or a display form of Args when given as a list
+	Args        []string          // Program and arguments when command is given as a list, run as-is without a shell
+	PreStart    string            // Command run to completion before Command (failure aborts the start)
 	Workdir     string            // Working directory
 	Environment map[string]string // Environment variables
+	EnvFile     string            // Dotenv file loaded at start (Environment overrides its values)
 	WaitMode    string            // "completion" or "string"
 	WaitFor     string            // String to wait for (if WaitMode = "string")
 	Timeout     time.Duration     // Wait timeout
 	ReadyDelay  time.Duration     // Delay after ready before proceeding with tunnel startup
-	OnFailure   string            // "block" or "continue"
+	OnFailure   string            // "block", "continue" or "restart_tunnel"
+	Phase       string            // "pre_connect" (start before SSH) or "post_connect" (start once connected)
 	KeepAlive   bool              // Keep running after tunnel connects
 	AutoRestart bool              // Automatically restart if exits unexpectedly
 	Persistent  bool              // Keep running when tunnel stops (don't stop with tunnel)
-	StopSignal  string            // Signal to send on stop: "INT" (default), "TERM", "HUP"
+	StopSignal  string            // Signal to send on stop: "INT" (default), "TERM", "HUP", "KILL" or a signal number
 }
 
 // HookConfig represents a single hook command
@@ -125,24 +257,34 @@ type HookConfig struct {
 
 // HooksConfig represents hooks for a location or context
 type HooksConfig struct {
-	OnEnter []HookConfig // Commands to run when entering
-	OnLeave []HookConfig // Commands to run when leaving
+	OnEnter  []HookConfig // Commands to run when entering
+	OnLeave  []HookConfig // Commands to run when leaving
+	OnReload []HookConfig // Commands to run after a config reload (context_hooks only)
 }
 
 // HCL parsing structs
 
 type hclConfig struct {
-	Verbose       int                   `hcl:"verbose,optional"`
-	Environment   map[string]string     `hcl:"environment,optional"`
-	Exports       *hclExports           `hcl:"exports,block"`
-	SSH           *hclSSH               `hcl:"ssh,block"`
-	Companion     *hclCompanionSettings `hcl:"companion,block"`
-	LocationHooks *hclHooks             `hcl:"location_hooks,block"`
-	ContextHooks  *hclHooks             `hcl:"context_hooks,block"`
-	TunnelHooks   *hclTunnelHooks       `hcl:"tunnel_hooks,block"`
-	Locations     []hclLocation         `hcl:"location,block"`
-	Contexts      []hclContext          `hcl:"context,block"`
-	Tunnels       []hclTunnel           `hcl:"tunnel,block"`
+	Include          []string              `hcl:"include,optional"` // Files merged in after this one, see parseHCLFileWithIncludes
+	Verbose          int                   `hcl:"verbose,optional"`
+	SensorDebounce   string                `hcl:"sensor_debounce,optional"`
+	SensorWatchdog   string                `hcl:"sensor_watchdog,optional"`
+	SensorEnvFile    string                `hcl:"sensor_env_file,optional"`
+	EventMinInterval string                `hcl:"event_min_interval,optional"`
+	EnvironmentExpr  hcl.Expression        `hcl:"environment,optional"`
+	Exports          *hclExports           `hcl:"exports,block"`
+	SSH              *hclSSH               `hcl:"ssh,block"`
+	PublicIP         *hclPublicIP          `hcl:"public_ip,block"`
+	Companion        *hclCompanionSettings `hcl:"companion,block"`
+	Remote           *hclRemote            `hcl:"remote,block"`
+	LocationHooks    *hclHooks             `hcl:"location_hooks,block"`
+	ContextHooks     *hclHooks             `hcl:"context_hooks,block"`
+	TunnelHooks      *hclTunnelHooks       `hcl:"tunnel_hooks,block"`
+	Locations        []hclLocation         `hcl:"location,block"`
+	Contexts         []hclContext          `hcl:"context,block"`
+	Tunnels          []hclTunnel           `hcl:"tunnel,block"`
+
+	Environment map[string]string // Resolved from EnvironmentExpr by resolveHCLEnvironments
 }
 
 type hclExports struct {
@@ -150,7 +292,12 @@ type hclExports struct {
 	Context     string `hcl:"context,optional"`
 	Location    string `hcl:"location,optional"`
 	PublicIP    string `hcl:"public_ip,optional"`
+	PublicIPv4  string `hcl:"public_ipv4,optional"`
+	PublicIPv6  string `hcl:"public_ipv6,optional"`
 	PreferredIP string `hcl:"preferred_ip,optional"`
+	WriteMode   string `hcl:"write_mode,optional"`
+	Template    string `hcl:"template,optional"`
+	Output      string `hcl:"output,optional"`
 }
 
 type hclSSH struct {
@@ -161,42 +308,82 @@ type hclSSH struct {
 	MaxBackoff          string `hcl:"max_backoff,optional"`
 	BackoffFactor       int    `hcl:"backoff_factor,optional"`
 	MaxRetries          int    `hcl:"max_retries,optional"`
+	StableReset         string `hcl:"stable_reset,optional"`
+	Askpass             string `hcl:"askpass,optional"`
+	ControlMaster       *bool  `hcl:"control_master,optional"`
+	VerboseAfterConnect *bool  `hcl:"verbose_after_connect,optional"`
+
+	KeepaliveProfiles map[string]map[string]int `hcl:"keepalive_profiles,optional"`
+}
+
+type hclPublicIP struct {
+	Providers []string `hcl:"providers,optional"`
+	Timeout   string   `hcl:"timeout,optional"`
+	CacheTTL  string   `hcl:"cache_ttl,optional"`
 }
 
 type hclCompanionSettings struct {
 	HistorySize int `hcl:"history_size,optional"`
 }
 
+type hclRemote struct {
+	ShutdownSignals    []string `hcl:"shutdown_signals,optional"`
+	ParentPollInterval string   `hcl:"parent_poll_interval,optional"`
+}
+
 type hclHooks struct {
-	OnEnter []string `hcl:"on_enter,optional"`
-	OnLeave []string `hcl:"on_leave,optional"`
-	Timeout string   `hcl:"timeout,optional"`
+	OnEnter  []string `hcl:"on_enter,optional"`
+	OnLeave  []string `hcl:"on_leave,optional"`
+	OnReload []string `hcl:"on_reload,optional"`
+	Timeout  string   `hcl:"timeout,optional"`
 }
 
 type hclLocation struct {
-	Name        string            `hcl:"name,label"`
-	DisplayName string            `hcl:"display_name,optional"`
-	Conditions  *hclConditions    `hcl:"conditions,block"`
-	Environment map[string]string `hcl:"environment,optional"`
-	Hooks       *hclHooks         `hcl:"hooks,block"`
+	Name            string         `hcl:"name,label"`
+	DisplayName     string         `hcl:"display_name,optional"`
+	Conditions      *hclConditions `hcl:"conditions,block"`
+	EnvironmentExpr hcl.Expression `hcl:"environment,optional"`
+	Hooks           *hclHooks      `hcl:"hooks,block"`
+
+	OverrideContextEnvironment bool `hcl:"override_context_environment,optional"`
+
+	Environment map[string]string // Resolved from EnvironmentExpr
+	Sources     []string          // Files the block was defined in, set by parseHCLFile
 }
 
 type hclContext struct {
-	Name        string            `hcl:"name,label"`
-	DisplayName string            `hcl:"display_name,optional"`
-	Locations   []string          `hcl:"locations,optional"`
-	Conditions  *hclConditions    `hcl:"conditions,block"`
-	Actions     *hclActions       `hcl:"actions,block"`
-	Environment map[string]string `hcl:"environment,optional"`
-	Hooks       *hclHooks         `hcl:"hooks,block"`
+	Name             string         `hcl:"name,label"`
+	DisplayName      string         `hcl:"display_name,optional"`
+	Locations        []string       `hcl:"locations,optional"`
+	LocationsMode    string         `hcl:"locations_mode,optional"`
+	Conditions       *hclConditions `hcl:"conditions,block"`
+	Actions          *hclActions    `hcl:"actions,block"`
+	PreserveExisting bool           `hcl:"preserve_existing,optional"`
+	Reassert         bool           `hcl:"reassert,optional"`
+	Disabled         bool           `hcl:"disabled,optional"`
+	DryRun           bool           `hcl:"dry_run,optional"`
+	EnvironmentExpr  hcl.Expression `hcl:"environment,optional"`
+	Exports          *hclExports    `hcl:"exports,block"`
+	Hooks            *hclHooks      `hcl:"hooks,block"`
+	SSH              *hclContextSSH `hcl:"ssh,block"`
+
+	Environment map[string]string // Resolved from EnvironmentExpr
+	Sources     []string          // Files the block was defined in, set by parseHCLFile
+}
+
+type hclContextSSH struct {
+	ServerAliveInterval int `hcl:"server_alive_interval,optional"`
+	ServerAliveCountMax int `hcl:"server_alive_count_max,optional"`
 }
 
 type hclConditions struct {
 	PublicIP []string          `hcl:"public_ip,optional"`
 	Online   *bool             `hcl:"online,optional"`
+	Power    []string          `hcl:"power,optional"`
 	Env      map[string]string `hcl:"env,optional"`
 	Any      []hclConditions   `hcl:"any,block"`
 	All      []hclConditions   `hcl:"all,block"`
+	Not      []hclConditions   `hcl:"not,block"`
 }
 
 type hclActions struct {
@@ -205,15 +392,25 @@ type hclActions struct {
 }
 
 type hclTunnel struct {
-	Name        string            `hcl:"name,label"`
-	Environment map[string]string `hcl:"environment,optional"`
-	Companions  []hclCompanion    `hcl:"companion,block"`
-	Hooks       *hclTunnelHooks   `hcl:"hooks,block"`
+	Name             string          `hcl:"name,label"`
+	Description      string          `hcl:"description,optional"`
+	EnvironmentExpr  hcl.Expression  `hcl:"environment,optional"`
+	Companions       []hclCompanion  `hcl:"companion,block"`
+	Hooks            *hclTunnelHooks `hcl:"hooks,block"`
+	MaxLifetime      string          `hcl:"max_lifetime,optional"`
+	PasswordCommand  string          `hcl:"password_command,optional"`
+	RemoteCommand    string          `hcl:"remote_command,optional"`
+	Disabled         bool            `hcl:"disabled,optional"`
+	ConnectOnStartup bool            `hcl:"connect_on_startup,optional"`
+
+	Environment map[string]string // Resolved from EnvironmentExpr
+	Sources     []string          // Files the block was defined in, set by parseHCLFile
 }
 
 type hclTunnelHooks struct {
 	BeforeConnect []hclTunnelHook `hcl:"before_connect,block"`
 	AfterConnect  []hclTunnelHook `hcl:"after_connect,block"`
+	OnGiveUp      []hclTunnelHook `hcl:"on_give_up,block"`
 }
 
 type hclTunnelHook struct {
@@ -222,19 +419,24 @@ type hclTunnelHook struct {
 }
 
 type hclCompanion struct {
-	Name        string            `hcl:"name,label"`
-	Command     string            `hcl:"command"`
-	Workdir     string            `hcl:"workdir,optional"`
-	Environment map[string]string `hcl:"environment,optional"`
-	WaitMode    string            `hcl:"wait_mode,optional"`
-	WaitFor     string            `hcl:"wait_for,optional"`
-	Timeout     string            `hcl:"timeout,optional"`
-	ReadyDelay  string            `hcl:"ready_delay,optional"`
-	OnFailure   string            `hcl:"on_failure,optional"`
-	KeepAlive   *bool             `hcl:"keep_alive,optional"`
-	AutoRestart *bool             `hcl:"auto_restart,optional"`
-	Persistent  *bool             `hcl:"persistent,optional"`
-	StopSignal  string            `hcl:"stop_signal,optional"`
+	Name            string         `hcl:"name,label"`
+	Command         cty.Value      `hcl:"command"` // String or list of strings
+	PreStart        string         `hcl:"pre_start,optional"`
+	Workdir         string         `hcl:"workdir,optional"`
+	EnvironmentExpr hcl.Expression `hcl:"environment,optional"`
+	EnvFile         string         `hcl:"env_file,optional"`
+	WaitMode        string         `hcl:"wait_mode,optional"`
+	WaitFor         string         `hcl:"wait_for,optional"`
+	Timeout         string         `hcl:"timeout,optional"`
+	ReadyDelay      string         `hcl:"ready_delay,optional"`
+	OnFailure       string         `hcl:"on_failure,optional"`
+	Phase           string         `hcl:"phase,optional"`
+	KeepAlive       *bool          `hcl:"keep_alive,optional"`
+	AutoRestart     *bool          `hcl:"auto_restart,optional"`
+	Persistent      *bool          `hcl:"persistent,optional"`
+	StopSignal      string         `hcl:"stop_signal,optional"`
+
+	Environment map[string]string // Resolved from EnvironmentExpr
 }
 
 // parseHCLFile decodes a single HCL file into the intermediate hclConfig struct
@@ -242,11 +444,127 @@ func parseHCLFile(filename string) (*hclConfig, error) {
 	var hclCfg hclConfig
 	err := hclsimple.DecodeFile(filename, nil, &hclCfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HCL config: %w", err)
+		return nil, newConfigError(filename, err)
+	}
+	if diags := resolveHCLEnvironments(&hclCfg); diags.HasErrors() {
+		return nil, newConfigError(filename, diags)
 	}
+	annotateHCLSources(&hclCfg, filename)
 	return &hclCfg, nil
 }
 
+// parseHCLFileWithIncludes parses filename and merges the files listed in its
+// include attribute, in order and each right after the file including it.
+// Included files can include further files. Relative paths are resolved
+// against the directory of the including file. It also returns the paths of
+// all included files, in the order they were merged.
+func parseHCLFileWithIncludes(filename string) (*hclConfig, []string, error) {
+	return parseHCLIncludes(filename, nil)
+}
+
+// parseHCLIncludes does the work of parseHCLFileWithIncludes. chain holds the
+// absolute paths of the files that led to filename, to detect include cycles.
+func parseHCLIncludes(filename string, chain []string) (*hclConfig, []string, error) {
+	hclCfg, err := parseHCLFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		chain = append(slices.Clone(chain), abs)
+	}
+
+	var includedFiles []string
+	for _, include := range hclCfg.Include {
+		includePath := ExpandHomeDir(include)
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(filename), includePath)
+		}
+		includePath = filepath.Clean(includePath)
+
+		if abs, err := filepath.Abs(includePath); err == nil && slices.Contains(chain, abs) {
+			return nil, nil, &ConfigError{File: filename, Message: fmt.Sprintf("include cycle: %s", strings.Join(append(chain, abs), " -> "))}
+		}
+		if _, err := os.Stat(includePath); err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil, &ConfigError{File: filename, Message: fmt.Sprintf("included file %q does not exist", include)}
+			}
+			return nil, nil, &ConfigError{File: filename, Message: fmt.Sprintf("include %q: %v", include, err)}
+		}
+
+		included, nested, err := parseHCLIncludes(includePath, chain)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := mergeHCLConfig(hclCfg, included); err != nil {
+			return nil, nil, &ConfigError{File: includePath, Message: err.Error()}
+		}
+		includedFiles = append(includedFiles, includePath)
+		includedFiles = append(includedFiles, nested...)
+	}
+	return hclCfg, includedFiles, nil
+}
+
+// annotateHCLSources records filename as the source of every location,
+// context and tunnel block in the file
+func annotateHCLSources(hclCfg *hclConfig, filename string) {
+	for i := range hclCfg.Locations {
+		hclCfg.Locations[i].Sources = []string{filename}
+	}
+	for i := range hclCfg.Contexts {
+		hclCfg.Contexts[i].Sources = []string{filename}
+	}
+	for i := range hclCfg.Tunnels {
+		hclCfg.Tunnels[i].Sources = []string{filename}
+	}
+}
+
+// ConfigError is returned by the config loaders. File is the file the problem
+// was found in, and Line and Column (1-based) point at it when the problem
+// comes from a parse error. File is empty when the problem can't be tied to a
+// single file, and Line and Column are 0 when there's no position.
+type ConfigError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d,%d: %s", e.File, e.Line, e.Column, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	default:
+		return e.Message
+	}
+}
+
+// newConfigError describes err as a problem in file. HCL diagnostics provide
+// the position and message of the first error; the source snippet HCL adds to
+// some details is dropped.
+func newConfigError(file string, err error) *ConfigError {
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		for _, diag := range diags {
+			if diag.Severity != hcl.DiagError {
+				continue
+			}
+			cfgErr := &ConfigError{File: file, Message: diag.Summary}
+			if detail, _, _ := strings.Cut(diag.Detail, ":\n"); detail != "" {
+				cfgErr.Message += "; " + detail
+			}
+			if diag.Subject != nil {
+				cfgErr.File = diag.Subject.Filename
+				cfgErr.Line = diag.Subject.Start.Line
+				cfgErr.Column = diag.Subject.Start.Column
+			}
+			return cfgErr
+		}
+	}
+	return &ConfigError{File: file, Message: err.Error()}
+}
+
 // convertHCLConfig converts an hclConfig struct into the final Configuration
 func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 	// Convert to our clean Configuration struct
@@ -261,26 +579,22 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 		PreferredIP:          "ipv4", // Default to IPv4
 		CheckOnStartup:       true,   // Default
 		CheckOnNetworkChange: true,   // Default
+		SensorDebounce:       defaultSensorDebounce,
+		SensorWatchdog:       defaultSensorWatchdog,
 		Locations:            make(map[string]*Location),
 		Contexts:             make([]*ContextRule, 0),
 		Tunnels:              make(map[string]*TunnelConfig),
 		Exports:              make([]ExportConfig, 0),
 	}
+	var warnings []Warning
 
 	// Convert exports
 	if hclCfg.Exports != nil {
-		if hclCfg.Exports.Dotenv != "" {
-			cfg.Exports = append(cfg.Exports, ExportConfig{Type: "dotenv", Path: hclCfg.Exports.Dotenv})
-		}
-		if hclCfg.Exports.Context != "" {
-			cfg.Exports = append(cfg.Exports, ExportConfig{Type: "context", Path: hclCfg.Exports.Context})
-		}
-		if hclCfg.Exports.Location != "" {
-			cfg.Exports = append(cfg.Exports, ExportConfig{Type: "location", Path: hclCfg.Exports.Location})
-		}
-		if hclCfg.Exports.PublicIP != "" {
-			cfg.Exports = append(cfg.Exports, ExportConfig{Type: "public_ip", Path: hclCfg.Exports.PublicIP})
+		exports, err := convertHCLExports(hclCfg.Exports)
+		if err != nil {
+			return nil, fmt.Errorf("exports: %w", err)
 		}
+		cfg.Exports = append(cfg.Exports, exports...)
 		if hclCfg.Exports.PreferredIP == "ipv6" {
 			cfg.PreferredIP = "ipv6"
 		}
@@ -295,6 +609,16 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 			MaxBackoff:          hclCfg.SSH.MaxBackoff,
 			BackoffFactor:       hclCfg.SSH.BackoffFactor,
 			MaxRetries:          hclCfg.SSH.MaxRetries,
+			Askpass:             ExpandHomeDir(hclCfg.SSH.Askpass),
+		}
+		if cfg.SSH.Askpass != "" && !filepath.IsAbs(cfg.SSH.Askpass) {
+			return nil, fmt.Errorf("ssh: askpass must be an absolute path, got %q", hclCfg.SSH.Askpass)
+		}
+		if hclCfg.SSH.ControlMaster != nil {
+			cfg.SSH.NoControlMaster = !*hclCfg.SSH.ControlMaster
+		}
+		if hclCfg.SSH.VerboseAfterConnect != nil {
+			cfg.SSH.QuietAfterConnect = !*hclCfg.SSH.VerboseAfterConnect
 		}
 		if hclCfg.SSH.ReconnectEnabled != nil {
 			cfg.SSH.ReconnectEnabled = *hclCfg.SSH.ReconnectEnabled
@@ -320,6 +644,21 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 		if cfg.SSH.MaxRetries == 0 {
 			cfg.SSH.MaxRetries = 10
 		}
+		if hclCfg.SSH.StableReset != "" {
+			stableReset, err := time.ParseDuration(hclCfg.SSH.StableReset)
+			if err != nil {
+				return nil, fmt.Errorf("ssh: invalid stable_reset %q: %w", hclCfg.SSH.StableReset, err)
+			}
+			if stableReset < 0 {
+				return nil, fmt.Errorf("ssh: stable_reset must not be negative, got %q", hclCfg.SSH.StableReset)
+			}
+			cfg.SSH.StableReset = stableReset
+		}
+		profiles, err := convertKeepaliveProfiles(hclCfg.SSH.KeepaliveProfiles)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SSH.KeepaliveProfiles = profiles
 	} else {
 		// Defaults
 		cfg.SSH = SSHConfig{
@@ -333,14 +672,109 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 		}
 	}
 
+	// Convert public IP settings
+	cfg.PublicIP = PublicIPSettings{Timeout: 3 * time.Second} // Default
+	if hclCfg.PublicIP != nil {
+		for _, provider := range hclCfg.PublicIP.Providers {
+			if err := validateProviderURL(provider); err != nil {
+				return nil, fmt.Errorf("public_ip: invalid provider %q: %w", provider, err)
+			}
+		}
+		cfg.PublicIP.Providers = hclCfg.PublicIP.Providers
+		if hclCfg.PublicIP.Timeout != "" {
+			timeout, err := time.ParseDuration(hclCfg.PublicIP.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("public_ip: invalid timeout %q: %w", hclCfg.PublicIP.Timeout, err)
+			}
+			if timeout <= 0 {
+				return nil, fmt.Errorf("public_ip: timeout must be positive, got %q", hclCfg.PublicIP.Timeout)
+			}
+			cfg.PublicIP.Timeout = timeout
+		}
+		if hclCfg.PublicIP.CacheTTL != "" {
+			cacheTTL, err := time.ParseDuration(hclCfg.PublicIP.CacheTTL)
+			if err != nil {
+				return nil, fmt.Errorf("public_ip: invalid cache_ttl %q: %w", hclCfg.PublicIP.CacheTTL, err)
+			}
+			if cacheTTL < 0 {
+				return nil, fmt.Errorf("public_ip: cache_ttl must not be negative, got %q", hclCfg.PublicIP.CacheTTL)
+			}
+			cfg.PublicIP.CacheTTL = cacheTTL
+		}
+	}
+
+	if hclCfg.SensorDebounce != "" {
+		debounce, err := time.ParseDuration(hclCfg.SensorDebounce)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensor_debounce %q: %w", hclCfg.SensorDebounce, err)
+		}
+		if debounce < 0 {
+			return nil, fmt.Errorf("sensor_debounce must not be negative, got %q", hclCfg.SensorDebounce)
+		}
+		cfg.SensorDebounce = debounce
+	}
+
+	if hclCfg.SensorWatchdog != "" {
+		watchdog, err := time.ParseDuration(hclCfg.SensorWatchdog)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensor_watchdog %q: %w", hclCfg.SensorWatchdog, err)
+		}
+		if watchdog < 0 {
+			return nil, fmt.Errorf("sensor_watchdog must not be negative, got %q", hclCfg.SensorWatchdog)
+		}
+		cfg.SensorWatchdog = watchdog
+	}
+
+	cfg.SensorEnvFile = ExpandHomeDir(hclCfg.SensorEnvFile)
+
+	if hclCfg.EventMinInterval != "" {
+		interval, err := time.ParseDuration(hclCfg.EventMinInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event_min_interval %q: %w", hclCfg.EventMinInterval, err)
+		}
+		if interval < 0 {
+			return nil, fmt.Errorf("event_min_interval must not be negative, got %q", hclCfg.EventMinInterval)
+		}
+		cfg.EventMinInterval = interval
+	}
+
 	// Convert companion settings
 	cfg.Companion = CompanionSettings{HistorySize: 1000} // Default
 	if hclCfg.Companion != nil && hclCfg.Companion.HistorySize > 0 {
 		cfg.Companion.HistorySize = hclCfg.Companion.HistorySize
 	}
 
+	// Convert remote settings
+	cfg.Remote = RemoteSettings{ShutdownSignals: []string{"HUP"}, ParentPollInterval: defaultParentPollInterval} // Default
+	if hclCfg.Remote != nil && hclCfg.Remote.ShutdownSignals != nil {
+		signals := make([]string, 0, len(hclCfg.Remote.ShutdownSignals))
+		for _, name := range hclCfg.Remote.ShutdownSignals {
+			signal := strings.TrimPrefix(strings.ToUpper(name), "SIG")
+			if !slices.Contains(remoteShutdownSignals, signal) {
+				return nil, fmt.Errorf("remote: unsupported shutdown signal %q (supported: %s)", name, strings.Join(remoteShutdownSignals, ", "))
+			}
+			if !slices.Contains(signals, signal) {
+				signals = append(signals, signal)
+			}
+		}
+		cfg.Remote.ShutdownSignals = signals
+	}
+	if hclCfg.Remote != nil && hclCfg.Remote.ParentPollInterval != "" {
+		interval, err := time.ParseDuration(hclCfg.Remote.ParentPollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("remote: invalid parent_poll_interval %q: %w", hclCfg.Remote.ParentPollInterval, err)
+		}
+		if interval < 0 {
+			return nil, fmt.Errorf("remote: parent_poll_interval must not be negative, got %q", hclCfg.Remote.ParentPollInterval)
+		}
+		cfg.Remote.ParentPollInterval = interval
+	}
+
 	// Convert global location hooks
 	if hclCfg.LocationHooks != nil {
+		if len(hclCfg.LocationHooks.OnReload) > 0 {
+			return nil, fmt.Errorf("location_hooks: on_reload can only be set in the top-level context_hooks block")
+		}
 		hooks, err := parseHCLHooks(hclCfg.LocationHooks)
 		if err != nil {
 			return nil, fmt.Errorf("location_hooks: %w", err)
@@ -369,10 +803,12 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 	// Convert location definitions
 	for _, hclLoc := range hclCfg.Locations {
 		loc := &Location{
-			Name:        hclLoc.Name,
-			DisplayName: hclLoc.DisplayName,
-			Conditions:  make(map[string][]string),
-			Environment: hclLoc.Environment,
+			Name:                       hclLoc.Name,
+			DisplayName:                hclLoc.DisplayName,
+			Conditions:                 make(map[string][]string),
+			Environment:                hclLoc.Environment,
+			Sources:                    hclLoc.Sources,
+			OverrideContextEnvironment: hclLoc.OverrideContextEnvironment,
 		}
 		if loc.Environment == nil {
 			loc.Environment = make(map[string]string)
@@ -388,6 +824,9 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 
 		// Parse hooks
 		if hclLoc.Hooks != nil {
+			if len(hclLoc.Hooks.OnReload) > 0 {
+				return nil, fmt.Errorf("location %q: on_reload can only be set in the top-level context_hooks block", hclLoc.Name)
+			}
 			hooks, err := parseHCLHooks(hclLoc.Hooks)
 			if err != nil {
 				return nil, fmt.Errorf("location %q: %w", hclLoc.Name, err)
@@ -401,16 +840,30 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 	// Convert context rules (preserving order from HCL file)
 	for _, hclCtx := range hclCfg.Contexts {
 		rule := &ContextRule{
-			Name:        hclCtx.Name,
-			DisplayName: hclCtx.DisplayName,
-			Locations:   hclCtx.Locations,
-			Conditions:  make(map[string][]string),
-			Environment: hclCtx.Environment,
+			Name:             hclCtx.Name,
+			DisplayName:      hclCtx.DisplayName,
+			Locations:        hclCtx.Locations,
+			Conditions:       make(map[string][]string),
+			Environment:      hclCtx.Environment,
+			PreserveExisting: hclCtx.PreserveExisting,
+			Reassert:         hclCtx.Reassert,
+			Disabled:         hclCtx.Disabled,
+			DryRun:           hclCtx.DryRun,
+			Sources:          hclCtx.Sources,
 		}
 		if rule.Environment == nil {
 			rule.Environment = make(map[string]string)
 		}
 
+		// Parse locations_mode
+		rule.LocationsMode = hclCtx.LocationsMode
+		if rule.LocationsMode == "" {
+			rule.LocationsMode = "any" // Default
+		}
+		if rule.LocationsMode != "any" && rule.LocationsMode != "all" {
+			return nil, fmt.Errorf("context %q: locations_mode must be 'any' or 'all', got %q", hclCtx.Name, hclCtx.LocationsMode)
+		}
+
 		// Parse conditions
 		if hclCtx.Conditions != nil {
 			cond := parseHCLConditions(hclCtx.Conditions)
@@ -425,10 +878,33 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 				Connect:    hclCtx.Actions.Connect,
 				Disconnect: hclCtx.Actions.Disconnect,
 			}
+			for _, entry := range slices.Concat(rule.Actions.Connect, rule.Actions.Disconnect) {
+				if _, err := path.Match(entry, ""); err != nil {
+					return nil, fmt.Errorf("context %q: invalid tunnel pattern %q in actions: %w", hclCtx.Name, entry, err)
+				}
+			}
+			if len(rule.Actions.Connect) == 0 && (len(rule.Actions.Disconnect) == 0 || rule.PreserveExisting) {
+				warnings = append(warnings, Warning{fmt.Sprintf("context %q: actions have an empty connect list and nothing to disconnect", hclCtx.Name)})
+			}
+		}
+
+		// Convert context-scoped exports
+		if hclCtx.Exports != nil {
+			if hclCtx.Exports.PreferredIP != "" {
+				return nil, fmt.Errorf("context %q: preferred_ip can only be set in the top-level exports block", hclCtx.Name)
+			}
+			exports, err := convertHCLExports(hclCtx.Exports)
+			if err != nil {
+				return nil, fmt.Errorf("context %q: exports: %w", hclCtx.Name, err)
+			}
+			rule.Exports = exports
 		}
 
 		// Parse hooks
 		if hclCtx.Hooks != nil {
+			if len(hclCtx.Hooks.OnReload) > 0 {
+				return nil, fmt.Errorf("context %q: on_reload can only be set in the top-level context_hooks block", hclCtx.Name)
+			}
 			hooks, err := parseHCLHooks(hclCtx.Hooks)
 			if err != nil {
 				return nil, fmt.Errorf("context %q: %w", hclCtx.Name, err)
@@ -436,6 +912,17 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 			rule.Hooks = hooks
 		}
 
+		// Convert SSH overrides
+		if hclCtx.SSH != nil {
+			if hclCtx.SSH.ServerAliveInterval < 0 || hclCtx.SSH.ServerAliveCountMax < 0 {
+				return nil, fmt.Errorf("context %q: ssh settings must not be negative", hclCtx.Name)
+			}
+			rule.SSH = &SSHOverrides{
+				ServerAliveInterval: hclCtx.SSH.ServerAliveInterval,
+				ServerAliveCountMax: hclCtx.SSH.ServerAliveCountMax,
+			}
+		}
+
 		cfg.Contexts = append(cfg.Contexts, rule)
 	}
 
@@ -446,11 +933,42 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 			tunnelEnv = make(map[string]string)
 		}
 		tunnel := &TunnelConfig{
-			Name:        hclTun.Name,
-			Environment: tunnelEnv,
-			Companions:  make([]CompanionConfig, 0, len(hclTun.Companions)),
+			Name:             hclTun.Name,
+			Description:      hclTun.Description,
+			Environment:      tunnelEnv,
+			Companions:       make([]CompanionConfig, 0, len(hclTun.Companions)),
+			Disabled:         hclTun.Disabled,
+			ConnectOnStartup: hclTun.ConnectOnStartup,
+			Sources:          hclTun.Sources,
 		}
 
+		if hclTun.MaxLifetime != "" {
+			maxLifetime, err := time.ParseDuration(hclTun.MaxLifetime)
+			if err != nil {
+				return nil, fmt.Errorf("tunnel %q: invalid max_lifetime %q: %w", hclTun.Name, hclTun.MaxLifetime, err)
+			}
+			if maxLifetime <= 0 {
+				return nil, fmt.Errorf("tunnel %q: max_lifetime must be positive, got %q", hclTun.Name, hclTun.MaxLifetime)
+			}
+			tunnel.MaxLifetime = maxLifetime
+		}
+
+		if hclTun.PasswordCommand != "" {
+			if strings.TrimSpace(hclTun.PasswordCommand) == "" {
+				return nil, fmt.Errorf("tunnel %q: password_command must contain more than whitespace", hclTun.Name)
+			}
+			// A custom askpass helper answers every prompt, so the command would never run
+			if cfg.SSH.Askpass != "" {
+				return nil, fmt.Errorf("tunnel %q: password_command can't be used together with a custom ssh askpass helper", hclTun.Name)
+			}
+			tunnel.PasswordCommand = hclTun.PasswordCommand
+		}
+
+		if hclTun.RemoteCommand != "" && strings.TrimSpace(hclTun.RemoteCommand) == "" {
+			return nil, fmt.Errorf("tunnel %q: remote_command must contain more than whitespace", hclTun.Name)
+		}
+		tunnel.RemoteCommand = hclTun.RemoteCommand
+
 		// Track companion names for uniqueness validation
 		companionNames := make(map[string]bool)
 
@@ -462,10 +980,19 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 			companionNames[hclComp.Name] = true
 
 			// Validate command is required
-			if len(hclComp.Command) == 0 {
+			command, args, err := decodeCompanionCommand(hclComp.Command)
+			if err != nil {
+				return nil, fmt.Errorf("tunnel %q companion %q: %w", hclTun.Name, hclComp.Name, err)
+			}
+			if command == "" || (args != nil && args[0] == "") {
 				return nil, fmt.Errorf("tunnel %q companion %q: command is required", hclTun.Name, hclComp.Name)
 			}
 
+			// pre_start is optional, but an all-whitespace value is almost certainly a mistake
+			if hclComp.PreStart != "" && strings.TrimSpace(hclComp.PreStart) == "" {
+				return nil, fmt.Errorf("tunnel %q companion %q: pre_start must contain more than whitespace", hclTun.Name, hclComp.Name)
+			}
+
 			// Parse wait mode and validate
 			waitMode := hclComp.WaitMode
 			if waitMode == "" {
@@ -479,6 +1006,13 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 			if waitMode == "string" && hclComp.WaitFor == "" {
 				return nil, fmt.Errorf("tunnel %q companion %q: wait_for is required when wait_mode is 'string'", hclTun.Name, hclComp.Name)
 			}
+			if waitMode == "string" && strings.TrimSpace(hclComp.WaitFor) == "" {
+				return nil, fmt.Errorf("tunnel %q companion %q: wait_for must contain more than whitespace", hclTun.Name, hclComp.Name)
+			}
+			// Output is matched one line at a time, so a line break can never match
+			if strings.ContainsAny(hclComp.WaitFor, "\r\n") {
+				return nil, fmt.Errorf("tunnel %q companion %q: wait_for cannot contain line breaks (output is matched line by line)", hclTun.Name, hclComp.Name)
+			}
 
 			// Parse timeout
 			timeout := 30 * time.Second // Default
@@ -505,8 +1039,17 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 			if onFailure == "" {
 				onFailure = "block" // Default
 			}
-			if onFailure != "block" && onFailure != "continue" {
-				return nil, fmt.Errorf("tunnel %q companion %q: on_failure must be 'block' or 'continue', got %q", hclTun.Name, hclComp.Name, onFailure)
+			if onFailure != "block" && onFailure != "continue" && onFailure != "restart_tunnel" {
+				return nil, fmt.Errorf("tunnel %q companion %q: on_failure must be 'block', 'continue' or 'restart_tunnel', got %q", hclTun.Name, hclComp.Name, onFailure)
+			}
+
+			// Parse phase
+			phase := hclComp.Phase
+			if phase == "" {
+				phase = "pre_connect" // Default
+			}
+			if phase != "pre_connect" && phase != "post_connect" {
+				return nil, fmt.Errorf("tunnel %q companion %q: phase must be 'pre_connect' or 'post_connect', got %q", hclTun.Name, hclComp.Name, phase)
 			}
 
 			// Parse keep_alive
@@ -531,18 +1074,25 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 			stopSignal := "INT"
 			if hclComp.StopSignal != "" {
 				stopSignal = strings.ToUpper(hclComp.StopSignal)
+				if !validStopSignal(stopSignal) {
+					return nil, fmt.Errorf("tunnel %q companion %q: stop_signal must be 'INT', 'TERM', 'HUP', 'KILL' or a signal number from 1 to %d, got %q", hclTun.Name, hclComp.Name, maxStopSignal, hclComp.StopSignal)
+				}
 			}
 
 			companion := CompanionConfig{
 				Name:        hclComp.Name,
-				Command:     hclComp.Command,
+				Command:     command,
+				Args:        args,
+				PreStart:    hclComp.PreStart,
 				Workdir:     hclComp.Workdir,
 				Environment: hclComp.Environment,
+				EnvFile:     ExpandHomeDir(hclComp.EnvFile),
 				WaitMode:    waitMode,
 				WaitFor:     hclComp.WaitFor,
 				Timeout:     timeout,
 				ReadyDelay:  readyDelay,
 				OnFailure:   onFailure,
+				Phase:       phase,
 				KeepAlive:   keepAlive,
 				AutoRestart: autoRestart,
 				Persistent:  persistent,
@@ -568,24 +1118,91 @@ func convertHCLConfig(hclCfg *hclConfig) (*Configuration, error) {
 		cfg.Tunnels[hclTun.Name] = tunnel
 	}
 
+	cfg.Warnings = append(warnings, locationWarnings(cfg)...)
+
 	return cfg, nil
 }
 
+// decodeCompanionCommand decodes a companion command given either as a string
+// or as a list of program and arguments. For the list form, args holds the
+// list and command a display form of it.
+func decodeCompanionCommand(val cty.Value) (command string, args []string, err error) {
+	if val.IsNull() {
+		return "", nil, nil
+	}
+	ty := val.Type()
+	if ty == cty.String {
+		return val.AsString(), nil, nil
+	}
+	if !ty.IsListType() && !ty.IsTupleType() {
+		return "", nil, errors.New("command must be a string or a list of strings")
+	}
+
+	args = []string{}
+	for it := val.ElementIterator(); it.Next(); {
+		_, elem := it.Element()
+		str, err := convert.Convert(elem, cty.String)
+		if err != nil || str.IsNull() {
+			return "", nil, errors.New("command list must only contain strings")
+		}
+		args = append(args, str.AsString())
+	}
+	if len(args) == 0 {
+		return "", nil, nil
+	}
+	return formatCommandArgs(args), args, nil
+}
+
+// formatCommandArgs joins a command's arguments for display, quoting the ones
+// that would otherwise be ambiguous
+func formatCommandArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// validStopSignal reports whether an upper-cased stop_signal is a signal name
+// companions can be stopped with, with or without the SIG prefix, or a
+// signal number
+func validStopSignal(signal string) bool {
+	switch strings.TrimPrefix(signal, "SIG") {
+	case "INT", "TERM", "HUP", "KILL":
+		return true
+	}
+	number, err := strconv.Atoi(signal)
+	return err == nil && number >= 1 && number <= maxStopSignal
+}
+
 // LoadConfig loads the HCL configuration file and returns a Configuration struct
 func LoadConfig(filename string) (*Configuration, error) {
-	hclCfg, err := parseHCLFile(filename)
+	hclCfg, includedFiles, err := parseHCLFileWithIncludes(filename)
 	if err != nil {
 		return nil, err
 	}
-	return convertHCLConfig(hclCfg)
+
+	// Once includes are merged, problems can't be tied to a single file
+	if len(includedFiles) > 0 {
+		filename = ""
+	}
+	cfg, err := convertHCLConfigFile(hclCfg, filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg.IncludedFiles = includedFiles
+	return cfg, nil
 }
 
 // LoadConfigDir loads the main config file and merges any .hcl files from configDir.
 // The configDir is optional — if it doesn't exist, only the main file is loaded.
 // Files in configDir are loaded in alphabetical order. Non-.hcl files and subdirectories
-// are ignored.
+// are ignored. Files included by a file are merged right after it.
 func LoadConfigDir(mainFile string, configDir string) (*Configuration, error) {
-	merged, err := parseHCLFile(mainFile)
+	merged, includedFiles, err := parseHCLFileWithIncludes(mainFile)
 	if err != nil {
 		return nil, err
 	}
@@ -595,9 +1212,18 @@ func LoadConfigDir(mainFile string, configDir string) (*Configuration, error) {
 	if err != nil {
 		if os.IsNotExist(err) {
 			// No config.d directory — just convert the main config
-			return convertHCLConfig(merged)
+			file := mainFile
+			if len(includedFiles) > 0 {
+				file = ""
+			}
+			cfg, err := convertHCLConfigFile(merged, file)
+			if err != nil {
+				return nil, err
+			}
+			cfg.IncludedFiles = includedFiles
+			return cfg, nil
 		}
-		return nil, fmt.Errorf("reading config directory %s: %w", configDir, err)
+		return nil, &ConfigError{File: configDir, Message: err.Error()}
 	}
 
 	// Collect .hcl filenames, sort alphabetically
@@ -614,18 +1240,50 @@ func LoadConfigDir(mainFile string, configDir string) (*Configuration, error) {
 	sort.Strings(hclFiles)
 
 	// Parse and merge each fragment
+	var warnings []Warning
 	for _, name := range hclFiles {
 		fragPath := filepath.Join(configDir, name)
-		fragCfg, err := parseHCLFile(fragPath)
+		fragCfg, fragIncludes, err := parseHCLFileWithIncludes(fragPath)
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", name, err)
+			return nil, err
+		}
+		// A fragment that parses without adding anything is skipped, but
+		// unless it's blank it was likely meant to define something
+		if hclConfigEmpty(fragCfg) {
+			slog.Debug("Config fragment defines nothing, skipping it", "file", fragPath)
+			if !hclFileBlank(fragPath) {
+				warnings = append(warnings, Warning{fmt.Sprintf("config fragment %s defines nothing, check it for mistakes", fragPath)})
+			}
+			continue
 		}
 		if err := mergeHCLConfig(merged, fragCfg); err != nil {
-			return nil, fmt.Errorf("%s: %w", name, err)
+			return nil, &ConfigError{File: fragPath, Message: err.Error()}
 		}
+		includedFiles = append(includedFiles, fragIncludes...)
 	}
 
-	return convertHCLConfig(merged)
+	// Once fragments or includes are merged, problems can't be tied to a single file
+	file := mainFile
+	if len(hclFiles) > 0 || len(includedFiles) > 0 {
+		file = ""
+	}
+	cfg, err := convertHCLConfigFile(merged, file)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Warnings = append(cfg.Warnings, warnings...)
+	cfg.IncludedFiles = includedFiles
+	return cfg, nil
+}
+
+// convertHCLConfigFile converts a parsed config, reporting problems as a
+// ConfigError for file (empty if the config came from several files)
+func convertHCLConfigFile(hclCfg *hclConfig, file string) (*Configuration, error) {
+	cfg, err := convertHCLConfig(hclCfg)
+	if err != nil {
+		return nil, &ConfigError{File: file, Message: err.Error()}
+	}
+	return cfg, nil
 }
 
 // mergeHCLConfig merges src into dst at the hclConfig level.
@@ -639,6 +1297,26 @@ func mergeHCLConfig(dst, src *hclConfig) error {
 		dst.Verbose = src.Verbose
 	}
 
+	// SensorDebounce: last non-empty wins
+	if src.SensorDebounce != "" {
+		dst.SensorDebounce = src.SensorDebounce
+	}
+
+	// SensorWatchdog: last non-empty wins
+	if src.SensorWatchdog != "" {
+		dst.SensorWatchdog = src.SensorWatchdog
+	}
+
+	// SensorEnvFile: last non-empty wins
+	if src.SensorEnvFile != "" {
+		dst.SensorEnvFile = src.SensorEnvFile
+	}
+
+	// EventMinInterval: last non-empty wins
+	if src.EventMinInterval != "" {
+		dst.EventMinInterval = src.EventMinInterval
+	}
+
 	// Environment: singleton — error if defined in both
 	if dst.Environment != nil && src.Environment != nil {
 		return fmt.Errorf("environment block defined in multiple files")
@@ -662,6 +1340,13 @@ func mergeHCLConfig(dst, src *hclConfig) error {
 		dst.SSH = src.SSH
 	}
 
+	if dst.PublicIP != nil && src.PublicIP != nil {
+		return fmt.Errorf("public_ip block defined in multiple files")
+	}
+	if src.PublicIP != nil {
+		dst.PublicIP = src.PublicIP
+	}
+
 	if dst.Companion != nil && src.Companion != nil {
 		return fmt.Errorf("companion block defined in multiple files")
 	}
@@ -669,6 +1354,13 @@ func mergeHCLConfig(dst, src *hclConfig) error {
 		dst.Companion = src.Companion
 	}
 
+	if dst.Remote != nil && src.Remote != nil {
+		return fmt.Errorf("remote block defined in multiple files")
+	}
+	if src.Remote != nil {
+		dst.Remote = src.Remote
+	}
+
 	if dst.LocationHooks != nil && src.LocationHooks != nil {
 		return fmt.Errorf("location_hooks block defined in multiple files")
 	}
@@ -691,28 +1383,28 @@ func mergeHCLConfig(dst, src *hclConfig) error {
 	}
 
 	// Locations: accumulate, error on duplicate name
-	existingLocations := make(map[string]bool, len(dst.Locations))
+	existingLocations := make(map[string][]string, len(dst.Locations))
 	for _, loc := range dst.Locations {
-		existingLocations[loc.Name] = true
+		existingLocations[loc.Name] = loc.Sources
 	}
 	for _, loc := range src.Locations {
-		if existingLocations[loc.Name] {
-			return fmt.Errorf("duplicate location %q defined in multiple files", loc.Name)
+		if sources, exists := existingLocations[loc.Name]; exists {
+			return fmt.Errorf("duplicate location %q defined in multiple files%s", loc.Name, describeSources(sources))
 		}
-		existingLocations[loc.Name] = true
+		existingLocations[loc.Name] = loc.Sources
 		dst.Locations = append(dst.Locations, loc)
 	}
 
 	// Tunnels: accumulate, error on duplicate name
-	existingTunnels := make(map[string]bool, len(dst.Tunnels))
+	existingTunnels := make(map[string][]string, len(dst.Tunnels))
 	for _, tun := range dst.Tunnels {
-		existingTunnels[tun.Name] = true
+		existingTunnels[tun.Name] = tun.Sources
 	}
 	for _, tun := range src.Tunnels {
-		if existingTunnels[tun.Name] {
-			return fmt.Errorf("duplicate tunnel %q defined in multiple files", tun.Name)
+		if sources, exists := existingTunnels[tun.Name]; exists {
+			return fmt.Errorf("duplicate tunnel %q defined in multiple files%s", tun.Name, describeSources(sources))
 		}
-		existingTunnels[tun.Name] = true
+		existingTunnels[tun.Name] = tun.Sources
 		dst.Tunnels = append(dst.Tunnels, tun)
 	}
 
@@ -733,8 +1425,26 @@ func mergeHCLConfig(dst, src *hclConfig) error {
 	return nil
 }
 
-// parseHCLConditions converts HCL conditions to an awareness.Condition
+// describeSources names the files an earlier definition came from, for
+// duplicate errors
+func describeSources(sources []string) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (first defined in %s)", strings.Join(sources, ", "))
+}
+
+// parseHCLConditions converts HCL conditions to an awareness.Condition.
+// Sibling conditions inside a conditions block are AND'ed together, so
+// `online = true` next to `public_ip = [...]` requires both to match.
+// Values within a single public_ip list are OR'ed, as are the members of
+// a nested any block. all, any and not blocks nest to any depth.
 func parseHCLConditions(cond *hclConditions) awareness.Condition {
+	return combineHCLConditions("all", collectHCLConditions(cond))
+}
+
+// collectHCLConditions converts each condition in a block without combining them
+func collectHCLConditions(cond *hclConditions) []awareness.Condition {
 	var conditions []awareness.Condition
 
 	// Handle public_ip conditions
@@ -756,38 +1466,124 @@ func parseHCLConditions(cond *hclConditions) awareness.Condition {
 		conditions = append(conditions, awareness.NewBooleanCondition("online", *cond.Online))
 	}
 
-	// Handle env conditions
-	for varName, pattern := range cond.Env {
+	// Handle power conditions - multiple sources are OR'ed
+	if len(cond.Power) > 0 {
+		if len(cond.Power) == 1 {
+			conditions = append(conditions, awareness.NewSensorCondition("power", cond.Power[0]))
+		} else {
+			powerConds := make([]awareness.Condition, len(cond.Power))
+			for i, source := range cond.Power {
+				powerConds[i] = awareness.NewSensorCondition("power", source)
+			}
+			conditions = append(conditions, awareness.NewAnyCondition(powerConds...))
+		}
+	}
+
+	// Handle env conditions (sorted for deterministic ordering)
+	envVars := make([]string, 0, len(cond.Env))
+	for varName := range cond.Env {
+		envVars = append(envVars, varName)
+	}
+	sort.Strings(envVars)
+	for _, varName := range envVars {
 		sensorName := "env:" + varName
-		conditions = append(conditions, awareness.NewSensorCondition(sensorName, pattern))
+		conditions = append(conditions, awareness.NewSensorCondition(sensorName, cond.Env[varName]))
 	}
 
-	// Handle nested any blocks
+	// Handle nested any blocks - members are OR'ed
 	for _, anyBlock := range cond.Any {
-		anyCond := parseHCLConditions(&anyBlock)
-		if anyCond != nil {
+		if anyCond := combineHCLConditions("any", collectHCLConditions(&anyBlock)); anyCond != nil {
 			conditions = append(conditions, anyCond)
 		}
 	}
 
-	// Handle nested all blocks
+	// Handle nested all blocks - members are AND'ed
 	for _, allBlock := range cond.All {
-		allCond := parseHCLConditions(&allBlock)
-		if allCond != nil {
-			// Wrap in an all condition
-			conditions = append(conditions, awareness.NewAllCondition(allCond))
+		if allCond := combineHCLConditions("all", collectHCLConditions(&allBlock)); allCond != nil {
+			conditions = append(conditions, allCond)
 		}
 	}
 
-	// Return based on number of conditions
+	// Handle nested not blocks - members are AND'ed, then negated
+	for _, notBlock := range cond.Not {
+		if notCond := combineHCLConditions("all", collectHCLConditions(&notBlock)); notCond != nil {
+			conditions = append(conditions, awareness.NewNotCondition(notCond))
+		}
+	}
+
+	return conditions
+}
+
+// combineHCLConditions joins conditions with the given operator ("all" or "any").
+// A single condition is returned as-is and no conditions yields nil.
+func combineHCLConditions(operator string, conditions []awareness.Condition) awareness.Condition {
 	if len(conditions) == 0 {
 		return nil
 	}
 	if len(conditions) == 1 {
 		return conditions[0]
 	}
-	// Multiple conditions at same level = OR (any)
-	return awareness.NewAnyCondition(conditions...)
+	if operator == "any" {
+		return awareness.NewAnyCondition(conditions...)
+	}
+	return awareness.NewAllCondition(conditions...)
+}
+
+// validateProviderURL checks that a public IP provider is an absolute http(s) URL
+func validateProviderURL(provider string) error {
+	u, err := url.Parse(provider)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+// convertHCLExports converts an HCL exports block to export configurations
+func convertHCLExports(exports *hclExports) ([]ExportConfig, error) {
+	var inPlace bool
+	switch exports.WriteMode {
+	case "", "atomic":
+	case "inplace":
+		inPlace = true
+	default:
+		return nil, fmt.Errorf("write_mode must be 'atomic' or 'inplace', got %q", exports.WriteMode)
+	}
+
+	var result []ExportConfig
+	if exports.Dotenv != "" {
+		result = append(result, ExportConfig{Type: "dotenv", Path: exports.Dotenv})
+	}
+	if exports.Context != "" {
+		result = append(result, ExportConfig{Type: "context", Path: exports.Context})
+	}
+	if exports.Location != "" {
+		result = append(result, ExportConfig{Type: "location", Path: exports.Location})
+	}
+	if exports.PublicIP != "" {
+		result = append(result, ExportConfig{Type: "public_ip", Path: exports.PublicIP})
+	}
+	if exports.PublicIPv4 != "" {
+		result = append(result, ExportConfig{Type: "public_ipv4", Path: exports.PublicIPv4})
+	}
+	if exports.PublicIPv6 != "" {
+		result = append(result, ExportConfig{Type: "public_ipv6", Path: exports.PublicIPv6})
+	}
+	if (exports.Template == "") != (exports.Output == "") {
+		return nil, fmt.Errorf("template and output must be set together")
+	}
+	if exports.Template != "" {
+		result = append(result, ExportConfig{Type: "template", Path: exports.Output, Template: ExpandHomeDir(exports.Template)})
+	}
+	for i := range result {
+		result[i].InPlace = inPlace
+	}
+	return result, nil
 }
 
 // parseHCLHooks converts HCL hooks block to HooksConfig
@@ -824,6 +1620,14 @@ func parseHCLHooks(hooks *hclHooks) (*HooksConfig, error) {
 		})
 	}
 
+	// Convert on_reload hooks
+	for _, cmd := range hooks.OnReload {
+		result.OnReload = append(result.OnReload, HookConfig{
+			Command: cmd,
+			Timeout: timeout,
+		})
+	}
+
 	return result, nil
 }
 
@@ -833,40 +1637,45 @@ func parseHCLTunnelHooks(hooks *hclTunnelHooks) (*TunnelHooksConfig, error) {
 		return nil, nil
 	}
 
-	result := &TunnelHooksConfig{}
+	beforeConnect, err := convertHCLTunnelHooks("before_connect", hooks.BeforeConnect)
+	if err != nil {
+		return nil, err
+	}
+	afterConnect, err := convertHCLTunnelHooks("after_connect", hooks.AfterConnect)
+	if err != nil {
+		return nil, err
+	}
+	onGiveUp, err := convertHCLTunnelHooks("on_give_up", hooks.OnGiveUp)
+	if err != nil {
+		return nil, err
+	}
 
-	// Convert before_connect hooks
-	for _, h := range hooks.BeforeConnect {
-		timeout := 30 * time.Second // Default
-		if h.Timeout != "" {
-			var err error
-			timeout, err = time.ParseDuration(h.Timeout)
-			if err != nil {
-				return nil, fmt.Errorf("before_connect hook: invalid timeout %q: %w", h.Timeout, err)
-			}
-		}
-		result.BeforeConnect = append(result.BeforeConnect, HookConfig{
-			Command: h.Command,
-			Timeout: timeout,
-		})
+	result := &TunnelHooksConfig{
+		BeforeConnect: beforeConnect,
+		AfterConnect:  afterConnect,
+		OnGiveUp:      onGiveUp,
 	}
+	return result, nil
+}
 
-	// Convert after_connect hooks
-	for _, h := range hooks.AfterConnect {
+// convertHCLTunnelHooks converts the tunnel hooks of one kind, defaulting
+// their timeout to 30s
+func convertHCLTunnelHooks(kind string, hooks []hclTunnelHook) ([]HookConfig, error) {
+	var result []HookConfig
+	for _, h := range hooks {
 		timeout := 30 * time.Second // Default
 		if h.Timeout != "" {
 			var err error
 			timeout, err = time.ParseDuration(h.Timeout)
 			if err != nil {
-				return nil, fmt.Errorf("after_connect hook: invalid timeout %q: %w", h.Timeout, err)
+				return nil, fmt.Errorf("%s hook: invalid timeout %q: %w", kind, h.Timeout, err)
 			}
 		}
-		result.AfterConnect = append(result.AfterConnect, HookConfig{
+		result = append(result, HookConfig{
 			Command: h.Command,
 			Timeout: timeout,
 		})
 	}
-
 	return result, nil
 }
 
@@ -901,6 +1710,11 @@ func mergeHCLContext(dst, src *hclContext) {
 	// locations: append + deduplicate
 	dst.Locations = appendUnique(dst.Locations, src.Locations)
 
+	// locations_mode: first-non-empty wins
+	if dst.LocationsMode == "" {
+		dst.LocationsMode = src.LocationsMode
+	}
+
 	// conditions: first-non-nil wins
 	if dst.Conditions == nil {
 		dst.Conditions = src.Conditions
@@ -914,6 +1728,18 @@ func mergeHCLContext(dst, src *hclContext) {
 		dst.Actions.Disconnect = appendUnique(dst.Actions.Disconnect, src.Actions.Disconnect)
 	}
 
+	// preserve_existing: set if any fragment sets it
+	dst.PreserveExisting = dst.PreserveExisting || src.PreserveExisting
+
+	// reassert: set if any fragment sets it
+	dst.Reassert = dst.Reassert || src.Reassert
+
+	// disabled: set if any fragment sets it
+	dst.Disabled = dst.Disabled || src.Disabled
+
+	// dry_run: set if any fragment sets it
+	dst.DryRun = dst.DryRun || src.DryRun
+
 	// environment: merge keys; first-defined value wins on conflicts
 	if dst.Environment == nil && src.Environment != nil {
 		dst.Environment = src.Environment
@@ -925,16 +1751,30 @@ func mergeHCLContext(dst, src *hclContext) {
 		}
 	}
 
+	// sources: every fragment that contributed
+	dst.Sources = appendUnique(dst.Sources, src.Sources)
+
+	// exports: first-non-nil wins
+	if dst.Exports == nil {
+		dst.Exports = src.Exports
+	}
+
 	// hooks: append + deduplicate lists; timeout first-non-empty wins
 	if dst.Hooks == nil && src.Hooks != nil {
 		dst.Hooks = src.Hooks
 	} else if dst.Hooks != nil && src.Hooks != nil {
 		dst.Hooks.OnEnter = appendUnique(dst.Hooks.OnEnter, src.Hooks.OnEnter)
 		dst.Hooks.OnLeave = appendUnique(dst.Hooks.OnLeave, src.Hooks.OnLeave)
+		dst.Hooks.OnReload = appendUnique(dst.Hooks.OnReload, src.Hooks.OnReload)
 		if dst.Hooks.Timeout == "" {
 			dst.Hooks.Timeout = src.Hooks.Timeout
 		}
 	}
+
+	// ssh: first-non-nil wins
+	if dst.SSH == nil {
+		dst.SSH = src.SSH
+	}
 }
 
 // GetDefaultConfig returns a Configuration with default values
@@ -944,6 +1784,8 @@ func GetDefaultConfig() *Configuration {
 		Environment:          make(map[string]string),
 		CheckOnStartup:       true,
 		CheckOnNetworkChange: true,
+		SensorDebounce:       defaultSensorDebounce,
+		SensorWatchdog:       defaultSensorWatchdog,
 		SSH: SSHConfig{
 			ServerAliveInterval: 15,
 			ServerAliveCountMax: 3,
@@ -953,7 +1795,9 @@ func GetDefaultConfig() *Configuration {
 			BackoffFactor:       2,
 			MaxRetries:          10,
 		},
+		PublicIP:  PublicIPSettings{Timeout: 3 * time.Second},
 		Companion: CompanionSettings{HistorySize: 1000},
+		Remote:    RemoteSettings{ShutdownSignals: []string{"HUP"}, ParentPollInterval: defaultParentPollInterval},
 		Locations: make(map[string]*Location),
 		Contexts:  make([]*ContextRule, 0),
 		Tunnels:   make(map[string]*TunnelConfig),
@@ -965,3 +1809,46 @@ func ConfigExists(configPath string) bool {
 	_, err := os.Stat(configPath)
 	return err == nil
 }
+
+// Power sources the daemon passes to WithKeepaliveProfile, the values of the
+// power sensor
+const (
+	powerSourceAC      = "ac"
+	powerSourceBattery = "battery"
+)
+
+// keepaliveProfileKey returns the keepalive_profiles key for a power source
+func keepaliveProfileKey(powerSource string) string {
+	return "on_" + powerSource
+}
+
+// convertKeepaliveProfiles validates the keepalive_profiles map from the ssh block
+func convertKeepaliveProfiles(raw map[string]map[string]int) (map[string]KeepaliveProfile, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	profiles := make(map[string]KeepaliveProfile, len(raw))
+	for source, settings := range raw {
+		onBattery, onAC := keepaliveProfileKey(powerSourceBattery), keepaliveProfileKey(powerSourceAC)
+		if source != onBattery && source != onAC {
+			return nil, fmt.Errorf("ssh keepalive_profiles: unknown state %q (must be %q or %q)", source, onBattery, onAC)
+		}
+		var profile KeepaliveProfile
+		for key, value := range settings {
+			if value < 0 {
+				return nil, fmt.Errorf("ssh keepalive_profiles %q: %s must not be negative", source, key)
+			}
+			switch key {
+			case "interval":
+				profile.Interval = value
+			case "count_max":
+				profile.CountMax = value
+			default:
+				return nil, fmt.Errorf("ssh keepalive_profiles %q: unknown setting %q (must be interval or count_max)", source, key)
+			}
+		}
+		profiles[source] = profile
+	}
+	return profiles, nil
+}
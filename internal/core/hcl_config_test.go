@@ -1,9 +1,12 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -480,6 +483,68 @@ ssh {
 			t.Error("expected ReconnectEnabled=false")
 		}
 	})
+
+	t.Run("control_master defaults to ssh_config", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+ssh {
+  control_master = true
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		if config.SSH.NoControlMaster {
+			t.Error("expected NoControlMaster=false")
+		}
+	})
+
+	t.Run("control_master can be disabled", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+ssh {
+  control_master = false
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		if !config.SSH.NoControlMaster {
+			t.Error("expected NoControlMaster=true")
+		}
+	})
+}
+
+func TestLoadConfig_VerboseAfterConnect(t *testing.T) {
+	t.Run("verbose by default", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+ssh {
+  max_retries = 3
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if config.SSH.QuietAfterConnect {
+			t.Error("expected QuietAfterConnect=false")
+		}
+	})
+
+	t.Run("verbose_after_connect can be disabled", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+ssh {
+  verbose_after_connect = false
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if !config.SSH.QuietAfterConnect {
+			t.Error("expected QuietAfterConnect=true")
+		}
+	})
 }
 
 func TestLoadConfig_Hooks(t *testing.T) {
@@ -646,6 +711,44 @@ tunnel "vpn" {
 			t.Errorf("expected default timeout=30s, got %v", tun.Hooks.AfterConnect[0].Timeout)
 		}
 	})
+
+	t.Run("on_give_up hooks", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+
+tunnel_hooks {
+  on_give_up {
+    command = "notify-send 'tunnel gave up'"
+  }
+}
+
+tunnel "vpn" {
+  hooks {
+    on_give_up {
+      command = "page-me.sh"
+      timeout = "5s"
+    }
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		hooks := config.Tunnels["vpn"].Hooks
+		if hooks == nil || len(hooks.OnGiveUp) != 1 {
+			t.Fatalf("expected 1 on_give_up hook, got %+v", hooks)
+		}
+		if hooks.OnGiveUp[0].Command != "page-me.sh" || hooks.OnGiveUp[0].Timeout != 5*time.Second {
+			t.Errorf("unexpected on_give_up hook: %+v", hooks.OnGiveUp[0])
+		}
+		if config.GlobalTunnelHooks == nil || len(config.GlobalTunnelHooks.OnGiveUp) != 1 {
+			t.Fatalf("expected 1 global on_give_up hook, got %+v", config.GlobalTunnelHooks)
+		}
+		if config.GlobalTunnelHooks.OnGiveUp[0].Timeout != 30*time.Second {
+			t.Errorf("expected default timeout=30s, got %v", config.GlobalTunnelHooks.OnGiveUp[0].Timeout)
+		}
+	})
 }
 
 func TestLoadConfig_Companions(t *testing.T) {
@@ -686,6 +789,9 @@ tunnel "vpn" {
 		if comp.OnFailure != "block" {
 			t.Errorf("expected on_failure='block' (default), got %q", comp.OnFailure)
 		}
+		if comp.Phase != "pre_connect" {
+			t.Errorf("expected phase='pre_connect' (default), got %q", comp.Phase)
+		}
 		if !comp.KeepAlive {
 			t.Error("expected keep_alive=true (default)")
 		}
@@ -700,6 +806,134 @@ tunnel "vpn" {
 		}
 	})
 
+	t.Run("companion with pre_start", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+tunnel "vpn" {
+  companion "setup" {
+    pre_start = "mkdir -p /tmp/vpn"
+    command   = "vpn-up"
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		if got := config.Tunnels["vpn"].Companions[0].PreStart; got != "mkdir -p /tmp/vpn" {
+			t.Errorf("expected pre_start='mkdir -p /tmp/vpn', got %q", got)
+		}
+	})
+
+	t.Run("companion with env_file", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+tunnel "vpn" {
+  companion "setup" {
+    command  = "vpn-up"
+    env_file = "~/.config/overseer/vpn.env"
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		home, _ := os.UserHomeDir()
+		want := filepath.Join(home, ".config/overseer/vpn.env")
+		if got := config.Tunnels["vpn"].Companions[0].EnvFile; got != want {
+			t.Errorf("expected env_file=%q, got %q", want, got)
+		}
+	})
+
+	t.Run("tunnel with description", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+tunnel "prod-db" {
+  description = "Prod DB via bastion"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if got := config.Tunnels["prod-db"].Description; got != "Prod DB via bastion" {
+			t.Errorf("expected description='Prod DB via bastion', got %q", got)
+		}
+	})
+
+	t.Run("tunnel with max_lifetime", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+tunnel "flaky" {
+  max_lifetime = "6h"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if got := config.Tunnels["flaky"].MaxLifetime; got != 6*time.Hour {
+			t.Errorf("expected max_lifetime=6h, got %v", got)
+		}
+	})
+
+	t.Run("invalid max_lifetime", func(t *testing.T) {
+		for _, value := range []string{"soon", "0s", "-1h"} {
+			_, err := loadTestConfig(t, fmt.Sprintf("tunnel \"flaky\" {\n  max_lifetime = %q\n}\n", value))
+			if err == nil || !strings.Contains(err.Error(), "max_lifetime") {
+				t.Errorf("max_lifetime = %q: expected max_lifetime error, got %v", value, err)
+			}
+		}
+	})
+
+	t.Run("tunnel with password_command", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+tunnel "vpn" {
+  password_command = "pass show ssh/vpn"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if got := config.Tunnels["vpn"].PasswordCommand; got != "pass show ssh/vpn" {
+			t.Errorf("expected password_command='pass show ssh/vpn', got %q", got)
+		}
+	})
+
+	t.Run("invalid password_command", func(t *testing.T) {
+		_, err := loadTestConfig(t, "tunnel \"vpn\" {\n  password_command = \"  \"\n}\n")
+		if err == nil || !strings.Contains(err.Error(), "password_command must contain more than whitespace") {
+			t.Errorf("expected whitespace error, got %v", err)
+		}
+
+		_, err = loadTestConfig(t, `
+ssh {
+  askpass = "/usr/bin/ssh-askpass"
+}
+
+tunnel "vpn" {
+  password_command = "pass show ssh/vpn"
+}
+`)
+		if err == nil || !strings.Contains(err.Error(), "custom ssh askpass helper") {
+			t.Errorf("expected askpass conflict error, got %v", err)
+		}
+	})
+
+	t.Run("tunnel with remote_command", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+tunnel "logs" {
+  remote_command = "tail -f /var/log/app.log"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if got := config.Tunnels["logs"].RemoteCommand; got != "tail -f /var/log/app.log" {
+			t.Errorf("expected remote_command='tail -f /var/log/app.log', got %q", got)
+		}
+
+		_, err = loadTestConfig(t, "tunnel \"logs\" {\n  remote_command = \" \"\n}\n")
+		if err == nil || !strings.Contains(err.Error(), "remote_command must contain more than whitespace") {
+			t.Errorf("expected whitespace error, got %v", err)
+		}
+	})
+
 	t.Run("companion with string wait mode", func(t *testing.T) {
 		config, err := loadTestConfig(t, `
 verbose = 0
@@ -819,6 +1053,49 @@ tunnel "vpn" {
 		}
 	})
 
+	t.Run("empty command list", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+tunnel "vpn" {
+  companion "empty" {
+    command = []
+  }
+}
+`)
+		if err == nil || !strings.Contains(err.Error(), "command is required") {
+			t.Errorf("expected 'command is required' error, got: %v", err)
+		}
+	})
+
+	t.Run("command of the wrong type", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+tunnel "vpn" {
+  companion "bad" {
+    command = { program = "/usr/bin/proxy" }
+  }
+}
+`)
+		if err == nil || !strings.Contains(err.Error(), "command must be a string or a list of strings") {
+			t.Errorf("expected 'command must be a string or a list of strings' error, got: %v", err)
+		}
+	})
+
+	t.Run("whitespace pre_start", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+tunnel "vpn" {
+  companion "bad" {
+    command   = "echo hello"
+    pre_start = "   "
+  }
+}
+`)
+		if err == nil {
+			t.Fatal("expected error for whitespace pre_start")
+		}
+		if !strings.Contains(err.Error(), "pre_start must contain more than whitespace") {
+			t.Errorf("expected 'pre_start must contain more than whitespace' error, got: %v", err)
+		}
+	})
+
 	t.Run("invalid wait_mode", func(t *testing.T) {
 		_, err := loadTestConfig(t, `
 verbose = 0
@@ -857,116 +1134,248 @@ tunnel "vpn" {
 		}
 	})
 
-	t.Run("invalid on_failure", func(t *testing.T) {
+	t.Run("whitespace-only wait_for", func(t *testing.T) {
 		_, err := loadTestConfig(t, `
-verbose = 0
-
 tunnel "vpn" {
   companion "bad" {
-    command    = "echo hello"
-    on_failure = "explode"
+    command   = "echo hello"
+    wait_mode = "string"
+    wait_for  = "   "
   }
 }
 `)
 		if err == nil {
-			t.Fatal("expected error for invalid on_failure")
+			t.Fatal("expected error for whitespace-only wait_for")
 		}
-		if !strings.Contains(err.Error(), "on_failure must be") {
-			t.Errorf("expected 'on_failure must be' error, got: %v", err)
+		if !strings.Contains(err.Error(), "more than whitespace") {
+			t.Errorf("expected whitespace error, got: %v", err)
 		}
 	})
 
-	t.Run("invalid timeout duration", func(t *testing.T) {
+	t.Run("wait_for with line break", func(t *testing.T) {
 		_, err := loadTestConfig(t, `
-verbose = 0
-
 tunnel "vpn" {
   companion "bad" {
-    command = "echo hello"
-    timeout = "not-a-duration"
+    command   = "echo hello"
+    wait_mode = "string"
+    wait_for  = "Connected\nReady"
   }
 }
 `)
 		if err == nil {
-			t.Fatal("expected error for invalid timeout")
+			t.Fatal("expected error for wait_for containing a newline")
 		}
-		if !strings.Contains(err.Error(), "invalid timeout") {
-			t.Errorf("expected 'invalid timeout' error, got: %v", err)
+		if !strings.Contains(err.Error(), "line breaks") {
+			t.Errorf("expected line break error, got: %v", err)
 		}
 	})
 
-	t.Run("invalid ready_delay duration", func(t *testing.T) {
+	t.Run("invalid on_failure", func(t *testing.T) {
 		_, err := loadTestConfig(t, `
 verbose = 0
 
 tunnel "vpn" {
   companion "bad" {
-    command     = "echo hello"
-    ready_delay = "not-a-duration"
+    command    = "echo hello"
+    on_failure = "explode"
   }
 }
 `)
 		if err == nil {
-			t.Fatal("expected error for invalid ready_delay")
+			t.Fatal("expected error for invalid on_failure")
 		}
-		if !strings.Contains(err.Error(), "invalid ready_delay") {
-			t.Errorf("expected 'invalid ready_delay' error, got: %v", err)
+		if !strings.Contains(err.Error(), "on_failure must be") {
+			t.Errorf("expected 'on_failure must be' error, got: %v", err)
 		}
 	})
-}
 
-func TestLoadConfig_NestedConditions(t *testing.T) {
-	t.Run("any block inside context", func(t *testing.T) {
+	t.Run("on_failure restart_tunnel", func(t *testing.T) {
 		config, err := loadTestConfig(t, `
 verbose = 0
 
-context "multi" {
-  display_name = "Multi Location"
-
-  conditions {
-    any {
-      public_ip = ["1.2.3.4"]
-    }
-    any {
-      public_ip = ["5.6.7.8"]
-    }
-  }
-
-  actions {
-    connect = ["vpn"]
+tunnel "vpn" {
+  companion "auth" {
+    command    = "echo hello"
+    on_failure = "restart_tunnel"
   }
 }
 `)
 		if err != nil {
 			t.Fatalf("Failed to load: %v", err)
 		}
-
-		ctx := config.Contexts[0]
-		if ctx.Condition == nil {
-			t.Fatal("expected condition to be parsed")
-		}
-		condStr := fmt.Sprintf("%v", ctx.Condition)
-		if !strings.Contains(condStr, "1.2.3.4") {
-			t.Errorf("expected condition to contain '1.2.3.4', got %s", condStr)
-		}
-		if !strings.Contains(condStr, "5.6.7.8") {
-			t.Errorf("expected condition to contain '5.6.7.8', got %s", condStr)
+		if got := config.Tunnels["vpn"].Companions[0].OnFailure; got != "restart_tunnel" {
+			t.Errorf("OnFailure = %q, want %q", got, "restart_tunnel")
 		}
 	})
 
-	t.Run("online boolean condition", func(t *testing.T) {
+	t.Run("phase post_connect", func(t *testing.T) {
 		config, err := loadTestConfig(t, `
 verbose = 0
 
-context "offline" {
-  display_name = "Offline"
-
-  conditions {
-    online = false
-  }
-
-  actions {
-    disconnect = ["all"]
+tunnel "vpn" {
+  companion "forwarder" {
+    command = "echo hello"
+    phase   = "post_connect"
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if got := config.Tunnels["vpn"].Companions[0].Phase; got != "post_connect" {
+			t.Errorf("Phase = %q, want %q", got, "post_connect")
+		}
+	})
+
+	t.Run("invalid phase", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+verbose = 0
+
+tunnel "vpn" {
+  companion "bad" {
+    command = "echo hello"
+    phase   = "whenever"
+  }
+}
+`)
+		if err == nil {
+			t.Fatal("expected error for invalid phase")
+		}
+		if !strings.Contains(err.Error(), "phase must be") {
+			t.Errorf("expected 'phase must be' error, got: %v", err)
+		}
+	})
+
+	t.Run("stop_signal KILL and numbers", func(t *testing.T) {
+		for _, signal := range []string{"KILL", "sigkill", "9", strconv.Itoa(maxStopSignal)} {
+			config, err := loadTestConfig(t, fmt.Sprintf(`
+verbose = 0
+
+tunnel "vpn" {
+  companion "proxy" {
+    command     = "echo hello"
+    stop_signal = %q
+  }
+}
+`, signal))
+			if err != nil {
+				t.Fatalf("stop_signal %q: failed to load: %v", signal, err)
+			}
+			if got, want := config.Tunnels["vpn"].Companions[0].StopSignal, strings.ToUpper(signal); got != want {
+				t.Errorf("StopSignal = %q, want %q", got, want)
+			}
+		}
+	})
+
+	t.Run("invalid stop_signal", func(t *testing.T) {
+		for _, signal := range []string{"QUIT", "0", strconv.Itoa(maxStopSignal + 1), "-9"} {
+			_, err := loadTestConfig(t, fmt.Sprintf(`
+verbose = 0
+
+tunnel "vpn" {
+  companion "bad" {
+    command     = "echo hello"
+    stop_signal = %q
+  }
+}
+`, signal))
+			if err == nil || !strings.Contains(err.Error(), "stop_signal must be") {
+				t.Errorf("stop_signal %q: expected 'stop_signal must be' error, got: %v", signal, err)
+			}
+		}
+	})
+
+	t.Run("invalid timeout duration", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+verbose = 0
+
+tunnel "vpn" {
+  companion "bad" {
+    command = "echo hello"
+    timeout = "not-a-duration"
+  }
+}
+`)
+		if err == nil {
+			t.Fatal("expected error for invalid timeout")
+		}
+		if !strings.Contains(err.Error(), "invalid timeout") {
+			t.Errorf("expected 'invalid timeout' error, got: %v", err)
+		}
+	})
+
+	t.Run("invalid ready_delay duration", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+verbose = 0
+
+tunnel "vpn" {
+  companion "bad" {
+    command     = "echo hello"
+    ready_delay = "not-a-duration"
+  }
+}
+`)
+		if err == nil {
+			t.Fatal("expected error for invalid ready_delay")
+		}
+		if !strings.Contains(err.Error(), "invalid ready_delay") {
+			t.Errorf("expected 'invalid ready_delay' error, got: %v", err)
+		}
+	})
+}
+
+func TestLoadConfig_NestedConditions(t *testing.T) {
+	t.Run("any block inside context", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+
+context "multi" {
+  display_name = "Multi Location"
+
+  conditions {
+    any {
+      public_ip = ["1.2.3.4"]
+    }
+    any {
+      public_ip = ["5.6.7.8"]
+    }
+  }
+
+  actions {
+    connect = ["vpn"]
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		ctx := config.Contexts[0]
+		if ctx.Condition == nil {
+			t.Fatal("expected condition to be parsed")
+		}
+		condStr := fmt.Sprintf("%v", ctx.Condition)
+		if !strings.Contains(condStr, "1.2.3.4") {
+			t.Errorf("expected condition to contain '1.2.3.4', got %s", condStr)
+		}
+		if !strings.Contains(condStr, "5.6.7.8") {
+			t.Errorf("expected condition to contain '5.6.7.8', got %s", condStr)
+		}
+	})
+
+	t.Run("online boolean condition", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+
+context "offline" {
+  display_name = "Offline"
+
+  conditions {
+    online = false
+  }
+
+  actions {
+    disconnect = ["all"]
   }
 }
 `)
@@ -1024,6 +1433,27 @@ exports {
 		}
 	})
 
+	t.Run("public_ipv4 and public_ipv6 exports", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+exports {
+  public_ipv4  = "/tmp/ip4"
+  public_ipv6  = "/tmp/ip6"
+  preferred_ip = "ipv6"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		want := []ExportConfig{
+			{Type: "public_ipv4", Path: "/tmp/ip4"},
+			{Type: "public_ipv6", Path: "/tmp/ip6"},
+		}
+		if !reflect.DeepEqual(config.Exports, want) {
+			t.Errorf("expected exports %+v, got %+v", want, config.Exports)
+		}
+	})
+
 	t.Run("preferred_ip ipv6", func(t *testing.T) {
 		config, err := loadTestConfig(t, `
 verbose = 0
@@ -1057,6 +1487,144 @@ exports {}
 	})
 }
 
+func TestLoadConfig_ExportWriteMode(t *testing.T) {
+	t.Run("atomic by default", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+
+exports {
+  dotenv = "/tmp/overseer.env"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if config.Exports[0].InPlace {
+			t.Error("expected atomic writes by default")
+		}
+	})
+
+	t.Run("inplace", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+
+exports {
+  dotenv     = "/tmp/overseer.env"
+  context    = "/tmp/context.txt"
+  write_mode = "inplace"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		for _, export := range config.Exports {
+			if !export.InPlace {
+				t.Errorf("expected %s export to be written in place", export.Type)
+			}
+		}
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+verbose = 0
+
+exports {
+  write_mode = "append"
+}
+`)
+		if err == nil || !strings.Contains(err.Error(), "write_mode must be 'atomic' or 'inplace'") {
+			t.Errorf("expected a write_mode error, got %v", err)
+		}
+	})
+}
+
+func TestLoadConfig_TemplateExport(t *testing.T) {
+	t.Run("template and output", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+
+exports {
+  template = "/etc/overseer/resolv.tmpl"
+  output   = "/etc/resolv.conf.overseer"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if len(config.Exports) != 1 {
+			t.Fatalf("expected 1 export, got %d", len(config.Exports))
+		}
+		export := config.Exports[0]
+		if export.Type != "template" || export.Template != "/etc/overseer/resolv.tmpl" || export.Path != "/etc/resolv.conf.overseer" {
+			t.Errorf("unexpected export: %+v", export)
+		}
+	})
+
+	t.Run("output missing", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+verbose = 0
+
+exports {
+  template = "/etc/overseer/resolv.tmpl"
+}
+`)
+		if err == nil || !strings.Contains(err.Error(), "template and output must be set together") {
+			t.Errorf("expected a template error, got %v", err)
+		}
+	})
+}
+
+func TestLoadConfig_ContextExports(t *testing.T) {
+	t.Run("exports scoped to a context", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+verbose = 0
+
+exports {
+  dotenv = "/tmp/overseer.env"
+}
+
+context "office" {
+  exports {
+    dotenv  = "/tmp/office.env"
+    context = "/tmp/office-context.txt"
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		if len(config.Exports) != 1 {
+			t.Errorf("expected the global exports to be unaffected, got %d", len(config.Exports))
+		}
+		office := config.Contexts[0]
+		if len(office.Exports) != 2 {
+			t.Fatalf("expected 2 context exports, got %d", len(office.Exports))
+		}
+		if office.Exports[0] != (ExportConfig{Type: "dotenv", Path: "/tmp/office.env"}) {
+			t.Errorf("unexpected first export %+v", office.Exports[0])
+		}
+		if office.Exports[1] != (ExportConfig{Type: "context", Path: "/tmp/office-context.txt"}) {
+			t.Errorf("unexpected second export %+v", office.Exports[1])
+		}
+	})
+
+	t.Run("preferred_ip is rejected in a context", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+verbose = 0
+
+context "office" {
+  exports {
+    preferred_ip = "ipv6"
+  }
+}
+`)
+		if err == nil || !strings.Contains(err.Error(), "preferred_ip can only be set in the top-level exports block") {
+			t.Errorf("expected a preferred_ip error, got %v", err)
+		}
+	})
+}
+
 func TestLoadConfig_CompanionSettings(t *testing.T) {
 	t.Run("custom history size", func(t *testing.T) {
 		config, err := loadTestConfig(t, `
@@ -1630,36 +2198,353 @@ func TestLoadConfigDir_SyntaxErrorReportsFilename(t *testing.T) {
 	}
 }
 
-func TestLoadConfigDir_DuplicateLocationAcrossFiles(t *testing.T) {
+func TestLoadConfigDir_SyntaxErrorIsConfigError(t *testing.T) {
 	mainFile, configDir := setupConfigDir(t,
-		`
-location "home" {
-  conditions {
-    public_ip = ["1.1.1.1"]
-  }
-}
-`,
+		`verbose = 0`,
 		map[string]string{
-			"dup.hcl": `
-location "home" {
-  conditions {
-    public_ip = ["2.2.2.2"]
-  }
-}
-`,
+			"bad.hcl": "verbose = 1\n\nlocation \"home\" {\n  display_name = \n}\n",
 		},
 	)
 
 	_, err := LoadConfigDir(mainFile, configDir)
-	if err == nil {
-		t.Fatal("expected error for duplicate location across files")
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected *ConfigError, got %T: %v", err, err)
 	}
-	if !strings.Contains(err.Error(), "duplicate location") {
-		t.Errorf("expected 'duplicate location' error, got: %v", err)
+	if cfgErr.File != filepath.Join(configDir, "bad.hcl") {
+		t.Errorf("File = %q, want bad.hcl in %s", cfgErr.File, configDir)
+	}
+	if cfgErr.Line != 4 || cfgErr.Column == 0 {
+		t.Errorf("position = %d,%d; want line 4 with a column", cfgErr.Line, cfgErr.Column)
+	}
+	if cfgErr.Message == "" || strings.Contains(cfgErr.Message, "\n") {
+		t.Errorf("expected a single line message, got %q", cfgErr.Message)
+	}
+}
+
+func TestLoadConfig_UnknownFieldsRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		hcl     string
+		wantErr string
+	}{
+		{"misspelled ssh attribute", "ssh {\n  reconnect_enabld = true\n}\n", `Did you mean "reconnect_enabled"?`},
+		{"misspelled condition", "context \"home\" {\n  conditions {\n    publicip = [\"1.2.3.4\"]\n  }\n}\n", `Did you mean "public_ip"?`},
+		{"unknown companion attribute", "tunnel \"db\" {\n  companion \"vpn\" {\n    command = \"vpn\"\n    bogus   = 1\n  }\n}\n", `argument named "bogus"`},
+		{"unknown block", "tunnels \"db\" {\n}\n", "Unsupported block type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, tt.hcl)
+			var cfgErr *ConfigError
+			if !errors.As(err, &cfgErr) || cfgErr.Line == 0 {
+				t.Fatalf("expected a ConfigError with a position, got %v", err)
+			}
+			if !strings.Contains(cfgErr.Message, tt.wantErr) {
+				t.Errorf("expected %q in %q", tt.wantErr, cfgErr.Message)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDir_ConfigErrorWithoutPosition(t *testing.T) {
+	t.Run("fragment merge conflict names the fragment", func(t *testing.T) {
+		mainFile, configDir := setupConfigDir(t,
+			"environment = {\n  A = \"1\"\n}\n",
+			map[string]string{"env.hcl": "environment = {\n  B = \"2\"\n}\n"},
+		)
+
+		_, err := LoadConfigDir(mainFile, configDir)
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Fatalf("expected *ConfigError, got %T: %v", err, err)
+		}
+		if cfgErr.File != filepath.Join(configDir, "env.hcl") || cfgErr.Line != 0 {
+			t.Errorf("unexpected error fields: %+v", cfgErr)
+		}
+	})
+
+	t.Run("validation error in the main file", func(t *testing.T) {
+		mainFile, configDir := setupConfigDir(t,
+			"tunnel \"db\" {\n  max_lifetime = \"soon\"\n}\n",
+			nil,
+		)
+
+		_, err := LoadConfigDir(mainFile, configDir)
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Fatalf("expected *ConfigError, got %T: %v", err, err)
+		}
+		if cfgErr.File != mainFile || !strings.Contains(cfgErr.Message, "max_lifetime") {
+			t.Errorf("unexpected error fields: %+v", cfgErr)
+		}
+	})
+}
+
+func TestLoadConfigDir_Include(t *testing.T) {
+	secretsDir := t.TempDir()
+	secretsFile := filepath.Join(secretsDir, "secrets.hcl")
+	if err := os.WriteFile(secretsFile, []byte(`
+verbose = 2
+
+tunnel "db" {
+  environment = {
+    DB_TOKEN = "hunter2"
+  }
+}
+`), 0644); err != nil {
+		t.Fatalf("Failed to write include: %v", err)
+	}
+
+	mainFile, configDir := setupConfigDir(t, fmt.Sprintf(`
+include = [%q]
+verbose = 1
+
+tunnel "web" {}
+`, secretsFile), map[string]string{"home.hcl": "verbose = 3\n"})
+
+	cfg, err := LoadConfigDir(mainFile, configDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Tunnels["db"] == nil || cfg.Tunnels["db"].Environment["DB_TOKEN"] != "hunter2" {
+		t.Errorf("expected tunnel from the included file, got %+v", cfg.Tunnels["db"])
+	}
+	if cfg.Tunnels["web"] == nil {
+		t.Error("expected tunnel from the main file")
+	}
+	if got := cfg.Tunnels["db"].Sources; !reflect.DeepEqual(got, []string{secretsFile}) {
+		t.Errorf("Sources = %v, want the included file", got)
+	}
+	// The include is merged right after the main file, before config.d
+	if cfg.Verbose != 3 {
+		t.Errorf("expected Verbose=3 from config.d, got %d", cfg.Verbose)
+	}
+	if !reflect.DeepEqual(cfg.IncludedFiles, []string{secretsFile}) {
+		t.Errorf("IncludedFiles = %v, want the included file", cfg.IncludedFiles)
+	}
+}
+
+func TestLoadConfigDir_IncludeFromFragment(t *testing.T) {
+	sharedDir := t.TempDir()
+	sharedFile := filepath.Join(sharedDir, "shared.hcl")
+	if err := os.WriteFile(sharedFile, []byte("tunnel \"db\" {\n  tag = [1]\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write include: %v", err)
+	}
+
+	mainFile, configDir := setupConfigDir(t, `verbose = 1`, map[string]string{
+		"work.hcl": fmt.Sprintf("include = [%q]\n", sharedFile),
+	})
+
+	// An error in a file included by a fragment isn't blamed on the main file
+	_, err := LoadConfigDir(mainFile, configDir)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected *ConfigError, got %T: %v", err, err)
+	}
+	if cfgErr.File == mainFile {
+		t.Errorf("expected the error not to be blamed on the main file, got %+v", cfgErr)
+	}
+
+	if err := os.WriteFile(sharedFile, []byte("tunnel \"db\" {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write include: %v", err)
+	}
+	cfg, err := LoadConfigDir(mainFile, configDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Tunnels["db"] == nil {
+		t.Error("expected tunnel from the file included by the fragment")
+	}
+	if !reflect.DeepEqual(cfg.IncludedFiles, []string{sharedFile}) {
+		t.Errorf("IncludedFiles = %v, want the file included by the fragment", cfg.IncludedFiles)
+	}
+}
+
+func TestLoadConfig_IncludeRelativeAndNested(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "private"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"config.hcl":          "include = [\"private/secrets.hcl\"]\n",
+		"private/secrets.hcl": "include = [\"tunnels.hcl\"]\nverbose = 2\n",
+		"private/tunnels.hcl": "tunnel \"db\" {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg, err := LoadConfig(filepath.Join(tmpDir, "config.hcl"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Verbose != 2 || cfg.Tunnels["db"] == nil {
+		t.Errorf("expected includes relative to the including file to be merged, got verbose=%d tunnels=%v", cfg.Verbose, cfg.Tunnels)
+	}
+	want := []string{filepath.Join(tmpDir, "private", "secrets.hcl"), filepath.Join(tmpDir, "private", "tunnels.hcl")}
+	if !reflect.DeepEqual(cfg.IncludedFiles, want) {
+		t.Errorf("IncludedFiles = %v, want %v", cfg.IncludedFiles, want)
+	}
+}
+
+func TestLoadConfig_IncludeErrors(t *testing.T) {
+	t.Run("cycle", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		files := map[string]string{
+			"config.hcl": "include = [\"a.hcl\"]\n",
+			"a.hcl":      "include = [\"b.hcl\"]\n",
+			"b.hcl":      "include = [\"a.hcl\"]\n",
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+
+		_, err := LoadConfig(filepath.Join(tmpDir, "config.hcl"))
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Fatalf("expected *ConfigError, got %T: %v", err, err)
+		}
+		if cfgErr.File != filepath.Join(tmpDir, "b.hcl") || !strings.Contains(cfgErr.Message, "include cycle") {
+			t.Errorf("unexpected error fields: %+v", cfgErr)
+		}
+	})
+
+	t.Run("file including itself", func(t *testing.T) {
+		_, err := loadTestConfig(t, `include = ["config.hcl"]`)
+		if err == nil || !strings.Contains(err.Error(), "include cycle") {
+			t.Errorf("expected include cycle error, got: %v", err)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		mainFile, configDir := setupConfigDir(t, `include = ["secrets.hcl"]`, nil)
+
+		_, err := LoadConfigDir(mainFile, configDir)
+		var cfgErr *ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Fatalf("expected *ConfigError, got %T: %v", err, err)
+		}
+		if cfgErr.File != mainFile || !strings.Contains(cfgErr.Message, `included file "secrets.hcl" does not exist`) {
+			t.Errorf("unexpected error fields: %+v", cfgErr)
+		}
+	})
+}
+
+func TestLoadConfigDir_FragmentDefiningNothing(t *testing.T) {
+	mainFile, configDir := setupConfigDir(t, `verbose = 1`, map[string]string{
+		"blank.hcl":    "# Nothing here yet\n\n",
+		"settings.hcl": "verbose = 0\n",
+		"work.hcl":     "tunnel \"db\" {}\n",
+	})
+
+	cfg, err := LoadConfigDir(mainFile, configDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Verbose != 1 || cfg.Tunnels["db"] == nil {
+		t.Errorf("expected the other files to load, got verbose=%d tunnels=%v", cfg.Verbose, cfg.Tunnels)
+	}
+
+	// A comment-only fragment is a deliberate no-op and isn't flagged
+	want := []Warning{{fmt.Sprintf("config fragment %s defines nothing, check it for mistakes", filepath.Join(configDir, "settings.hcl"))}}
+	if !reflect.DeepEqual(cfg.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", cfg.Warnings, want)
+	}
+}
+
+func TestHCLConfigEmpty_CoversEveryField(t *testing.T) {
+	if !hclConfigEmpty(&hclConfig{}) {
+		t.Fatal("expected a config without settings to be empty")
+	}
+
+	typ := reflect.TypeOf(hclConfig{})
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.Name == "EnvironmentExpr" {
+			continue
+		}
+
+		// Give the field a value, whatever its type
+		var cfg hclConfig
+		v := reflect.ValueOf(&cfg).Elem().Field(i)
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		case reflect.Map:
+			v.Set(reflect.MakeMap(v.Type()))
+			v.SetMapIndex(reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem())
+		case reflect.Pointer:
+			v.Set(reflect.New(v.Type().Elem()))
+		case reflect.String:
+			v.SetString("1s")
+		case reflect.Int:
+			v.SetInt(1)
+		default:
+			t.Fatalf("field %s has unhandled kind %s", field.Name, v.Kind())
+		}
+
+		if hclConfigEmpty(&cfg) {
+			t.Errorf("expected a config with %s set not to be empty", field.Name)
+		}
+	}
+}
+
+func TestLoadConfigDir_FragmentWithMisnamedBlock(t *testing.T) {
+	mainFile, configDir := setupConfigDir(t, `verbose = 1`, map[string]string{
+		"work.hcl": "tunel \"db\" {}\n",
+	})
+
+	// HCL rejects block types it doesn't know, so the typo fails the load
+	// rather than leaving an empty fragment behind
+	_, err := LoadConfigDir(mainFile, configDir)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected *ConfigError, got %T: %v", err, err)
+	}
+	if cfgErr.File != filepath.Join(configDir, "work.hcl") || !strings.Contains(cfgErr.Message, `Did you mean "tunnel"?`) {
+		t.Errorf("unexpected error fields: %+v", cfgErr)
+	}
+}
+
+func TestLoadConfigDir_DuplicateLocationAcrossFiles(t *testing.T) {
+	mainFile, configDir := setupConfigDir(t,
+		`
+location "home" {
+  conditions {
+    public_ip = ["1.1.1.1"]
+  }
+}
+`,
+		map[string]string{
+			"dup.hcl": `
+location "home" {
+  conditions {
+    public_ip = ["2.2.2.2"]
+  }
+}
+`,
+		},
+	)
+
+	_, err := LoadConfigDir(mainFile, configDir)
+	if err == nil {
+		t.Fatal("expected error for duplicate location across files")
+	}
+	if !strings.Contains(err.Error(), "duplicate location") {
+		t.Errorf("expected 'duplicate location' error, got: %v", err)
 	}
 	if !strings.Contains(err.Error(), "dup.hcl") {
 		t.Errorf("expected error to mention 'dup.hcl', got: %v", err)
 	}
+	if !strings.Contains(err.Error(), "first defined in "+mainFile) {
+		t.Errorf("expected error to mention where it was first defined, got: %v", err)
+	}
 }
 
 func TestLoadConfigDir_DuplicateTunnelAcrossFiles(t *testing.T) {
@@ -1781,6 +2666,193 @@ environment = {
 	})
 }
 
+func TestLoadConfig_EnvironmentReferences(t *testing.T) {
+	t.Run("resolves earlier keys in definition order", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+environment = {
+  BASE = "/opt"
+  BIN  = "${BASE}/bin"
+  MAN  = "${BIN}/../man"
+}
+
+context "work" {
+  environment = {
+    HOST = "work.example.com"
+    URL  = "https://${HOST}:8443"
+  }
+}
+
+tunnel "dev" {
+  environment = {
+    USER  = "deploy"
+    LOGIN = "${USER}@dev"
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		if got := config.Environment["BIN"]; got != "/opt/bin" {
+			t.Errorf("expected BIN='/opt/bin', got %q", got)
+		}
+		if got := config.Environment["MAN"]; got != "/opt/bin/../man" {
+			t.Errorf("expected MAN='/opt/bin/../man', got %q", got)
+		}
+		if got := config.Contexts[0].Environment["URL"]; got != "https://work.example.com:8443" {
+			t.Errorf("expected context URL to be resolved, got %q", got)
+		}
+		if got := config.Tunnels["dev"].Environment["LOGIN"]; got != "deploy@dev" {
+			t.Errorf("expected tunnel LOGIN='deploy@dev', got %q", got)
+		}
+	})
+
+	t.Run("companions reference the tunnel's environment", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+tunnel "dev" {
+  environment = {
+    DB_PORT = "5432"
+  }
+
+  companion "proxy" {
+    command = "proxy"
+    environment = {
+      HOST = "localhost"
+      DSN  = "postgres://${HOST}:${tunnel.DB_PORT}/app"
+    }
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		env := config.Tunnels["dev"].Companions[0].Environment
+		if got := env["DSN"]; got != "postgres://localhost:5432/app" {
+			t.Errorf("expected DSN with the tunnel's DB_PORT, got %q", got)
+		}
+	})
+
+	t.Run("escaped references are kept literally", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+environment = {
+  PROMPT = "$${USER}"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if got := config.Environment["PROMPT"]; got != "${USER}" {
+			t.Errorf("expected PROMPT='${USER}', got %q", got)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		hcl     string
+		wantErr string
+	}{
+		{
+			name: "forward reference",
+			hcl: `
+environment = {
+  BIN  = "${BASE}/bin"
+  BASE = "/opt"
+}
+`,
+			wantErr: `BIN references "BASE" before it is defined`,
+		},
+		{
+			name: "self reference",
+			hcl: `
+environment = {
+  PATH = "${PATH}:/opt/bin"
+}
+`,
+			wantErr: "PATH is part of a reference cycle: PATH -> PATH",
+		},
+		{
+			name: "cycle",
+			hcl: `
+location "home" {
+  environment = {
+    A = "${B}"
+    B = "${A}"
+  }
+}
+`,
+			wantErr: "A is part of a reference cycle: A -> B -> A",
+		},
+		{
+			name: "undefined key",
+			hcl: `
+environment = {
+  BIN = "${HOME}/bin"
+}
+`,
+			wantErr: `BIN references "HOME", which is not defined in this environment block`,
+		},
+		{
+			name: "undefined tunnel key",
+			hcl: `
+tunnel "dev" {
+  environment = {
+    DB_PORT = "5432"
+  }
+
+  companion "proxy" {
+    command = "proxy"
+    environment = {
+      PORT = "${tunnel.PORT}"
+    }
+  }
+}
+`,
+			wantErr: `PORT references "tunnel.PORT", which is not defined in the tunnel's environment block`,
+		},
+		{
+			name: "tunnel reference without a key",
+			hcl: `
+tunnel "dev" {
+  companion "proxy" {
+    command = "proxy"
+    environment = {
+      ENV = "${tunnel}"
+    }
+  }
+}
+`,
+			wantErr: "ENV references the tunnel's environment, use ${tunnel.KEY}",
+		},
+		{
+			name: "tunnel reference outside a companion",
+			hcl: `
+tunnel "dev" {
+  environment = {
+    PORT = "${tunnel.PORT}"
+  }
+}
+`,
+			wantErr: `PORT references "tunnel", which is not defined in this environment block`,
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, tt.hcl)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+			var cfgErr *ConfigError
+			if !errors.As(err, &cfgErr) || cfgErr.Line == 0 {
+				t.Errorf("expected a ConfigError with a position, got: %#v", err)
+			}
+		})
+	}
+}
+
 func TestMergeHCLConfig_EnvironmentSingleton(t *testing.T) {
 	t.Run("error when environment defined in both files", func(t *testing.T) {
 		dst := &hclConfig{
@@ -2318,12 +3390,53 @@ context "client" {
 	}
 }
 
-func TestLoadConfigDir_ContextDeepMergeMultipleFragments(t *testing.T) {
+func TestLoadConfigDir_Provenance(t *testing.T) {
 	mainFile, configDir := setupConfigDir(t,
 		`
-context "client" {
-  display_name = "Client"
-  locations    = ["andel"]
+location "home" {
+  conditions {
+    public_ip = ["1.1.1.1"]
+  }
+}
+
+context "client" {
+  locations = ["home"]
+}
+`,
+		map[string]string{
+			"client.hcl": `
+context "client" {
+  actions { connect = ["jump"] }
+}
+
+tunnel "jump" {}
+`,
+		},
+	)
+	fragment := filepath.Join(configDir, "client.hcl")
+
+	cfg, err := LoadConfigDir(mainFile, configDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := cfg.Contexts[0].Sources; !reflect.DeepEqual(got, []string{mainFile, fragment}) {
+		t.Errorf("expected the merged context to list both files, got %v", got)
+	}
+	if got := cfg.Locations["home"].Sources; !reflect.DeepEqual(got, []string{mainFile}) {
+		t.Errorf("expected location sources [%s], got %v", mainFile, got)
+	}
+	if got := cfg.Tunnels["jump"].Sources; !reflect.DeepEqual(got, []string{fragment}) {
+		t.Errorf("expected tunnel sources [%s], got %v", fragment, got)
+	}
+}
+
+func TestLoadConfigDir_ContextDeepMergeMultipleFragments(t *testing.T) {
+	mainFile, configDir := setupConfigDir(t,
+		`
+context "client" {
+  display_name = "Client"
+  locations    = ["andel"]
   environment = { "OVERSEER_CONTEXT_BG" = "#3a579a" }
   actions { connect = ["jump-zero"] }
   hooks {
@@ -2431,3 +3544,1038 @@ context "client" {
 		t.Errorf("expected second context='other', got %q", cfg.Contexts[1].Name)
 	}
 }
+
+func TestParseHCLConditions_Precedence(t *testing.T) {
+	online := true
+
+	tests := []struct {
+		name     string
+		cond     hclConditions
+		expected string
+	}{
+		{
+			name:     "single public_ip",
+			cond:     hclConditions{PublicIP: []string{"1.2.3.4"}},
+			expected: "public_ipv4~1.2.3.4",
+		},
+		{
+			name:     "public_ip list is OR",
+			cond:     hclConditions{PublicIP: []string{"1.2.3.4", "5.6.7.8"}},
+			expected: "any{public_ipv4~1.2.3.4, public_ipv4~5.6.7.8}",
+		},
+		{
+			name:     "online and public_ip siblings are AND",
+			cond:     hclConditions{Online: &online, PublicIP: []string{"1.2.3.4", "5.6.7.8"}},
+			expected: "all{any{public_ipv4~1.2.3.4, public_ipv4~5.6.7.8}, online=true}",
+		},
+		{
+			name: "online AND nested any",
+			cond: hclConditions{
+				Online: &online,
+				Any: []hclConditions{
+					{PublicIP: []string{"1.2.3.4"}, Env: map[string]string{"VPN": "on"}},
+				},
+			},
+			expected: "all{online=true, any{public_ipv4~1.2.3.4, env:VPN~on}}",
+		},
+		{
+			name: "online, public_ip and nested any",
+			cond: hclConditions{
+				Online:   &online,
+				PublicIP: []string{"1.2.3.4"},
+				Any: []hclConditions{
+					{Env: map[string]string{"A": "1", "B": "2"}},
+				},
+			},
+			expected: "all{public_ipv4~1.2.3.4, online=true, any{env:A~1, env:B~2}}",
+		},
+		{
+			name: "nested all inside any",
+			cond: hclConditions{
+				Any: []hclConditions{
+					{
+						PublicIP: []string{"1.2.3.4"},
+						All: []hclConditions{
+							{Online: &online, Env: map[string]string{"VPN": "on"}},
+						},
+					},
+				},
+			},
+			expected: "any{public_ipv4~1.2.3.4, all{online=true, env:VPN~on}}",
+		},
+		{
+			name:     "single power source",
+			cond:     hclConditions{Power: []string{"ac"}},
+			expected: "power~ac",
+		},
+		{
+			name:     "power and online siblings are AND",
+			cond:     hclConditions{Online: &online, Power: []string{"ac", "battery"}},
+			expected: "all{online=true, any{power~ac, power~battery}}",
+		},
+		{
+			name:     "empty any block is dropped",
+			cond:     hclConditions{Online: &online, Any: []hclConditions{{}}},
+			expected: "online=true",
+		},
+		{
+			name:     "not block",
+			cond:     hclConditions{Online: &online, Not: []hclConditions{{Power: []string{"battery"}}}},
+			expected: "all{online=true, not{power~battery}}",
+		},
+		{
+			name:     "not block members are AND'ed",
+			cond:     hclConditions{Not: []hclConditions{{Online: &online, Env: map[string]string{"VPN": "on"}}}},
+			expected: "not{all{online=true, env:VPN~on}}",
+		},
+		{
+			name:     "empty not block is dropped",
+			cond:     hclConditions{Online: &online, Not: []hclConditions{{}}},
+			expected: "online=true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := parseHCLConditions(&tt.cond)
+			if cond == nil {
+				t.Fatal("expected condition to be parsed")
+			}
+			if got := fmt.Sprintf("%v", cond); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_NotCondition(t *testing.T) {
+	config, err := loadTestConfig(t, `
+location "cafe" {
+  conditions {
+    all {
+      online = true
+      any {
+        env = { "TETHERED" = "yes" }
+        not {
+          public_ip = ["198.51.100.0/24", "203.0.113.0/24"]
+          all {
+            power = ["ac"]
+          }
+        }
+      }
+    }
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	want := "all{online=true, any{env:TETHERED~yes, not{all{any{public_ipv4~198.51.100.0/24, public_ipv4~203.0.113.0/24}, power~ac}}}}"
+	if got := fmt.Sprintf("%v", config.Locations["cafe"].Condition); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestLoadConfig_PowerCondition(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context "plugged-in" {
+  conditions {
+    power = ["ac"]
+  }
+
+  actions {
+    connect = ["backup"]
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if len(config.Contexts) != 1 || config.Contexts[0].Condition == nil {
+		t.Fatalf("expected one context with a condition, got %+v", config.Contexts)
+	}
+	if got := fmt.Sprintf("%v", config.Contexts[0].Condition); got != "power~ac" {
+		t.Errorf("expected power~ac, got %s", got)
+	}
+}
+
+func TestLoadConfig_SensorDebounce(t *testing.T) {
+	tests := []struct {
+		name    string
+		hcl     string
+		want    time.Duration
+		wantErr string
+	}{
+		{"default", ``, 5 * time.Second, ""},
+		{"custom", `sensor_debounce = "30s"`, 30 * time.Second, ""},
+		{"disabled", `sensor_debounce = "0s"`, 0, ""},
+		{"invalid", `sensor_debounce = "soon"`, 0, "invalid sensor_debounce"},
+		{"negative", `sensor_debounce = "-1s"`, 0, "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, tt.hcl)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			if config.SensorDebounce != tt.want {
+				t.Errorf("SensorDebounce = %v, want %v", config.SensorDebounce, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_SensorWatchdog(t *testing.T) {
+	tests := []struct {
+		name    string
+		hcl     string
+		want    time.Duration
+		wantErr string
+	}{
+		{"default", ``, 2 * time.Minute, ""},
+		{"custom", `sensor_watchdog = "5m"`, 5 * time.Minute, ""},
+		{"disabled", `sensor_watchdog = "0s"`, 0, ""},
+		{"invalid", `sensor_watchdog = "soon"`, 0, "invalid sensor_watchdog"},
+		{"negative", `sensor_watchdog = "-1s"`, 0, "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, tt.hcl)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			if config.SensorWatchdog != tt.want {
+				t.Errorf("SensorWatchdog = %v, want %v", config.SensorWatchdog, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_SensorEnvFile(t *testing.T) {
+	config, err := loadTestConfig(t, `
+sensor_env_file = "~/.config/overseer/sensors.env"
+
+location "corp" {
+  conditions {
+    env = { CORP_NETWORK = "true" }
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	if want := filepath.Join(home, ".config/overseer/sensors.env"); config.SensorEnvFile != want {
+		t.Errorf("SensorEnvFile = %q, want %q", config.SensorEnvFile, want)
+	}
+
+	cond, ok := config.Locations["corp"].Condition.(fmt.Stringer)
+	if !ok {
+		t.Fatalf("expected an env condition, got %T", config.Locations["corp"].Condition)
+	}
+	if got := cond.String(); got != "env:CORP_NETWORK~true" {
+		t.Errorf("condition = %q, want %q", got, "env:CORP_NETWORK~true")
+	}
+}
+
+func TestLoadConfig_EventMinInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		hcl     string
+		want    time.Duration
+		wantErr string
+	}{
+		{"default", ``, 0, ""},
+		{"custom", `event_min_interval = "30s"`, 30 * time.Second, ""},
+		{"invalid", `event_min_interval = "often"`, 0, "invalid event_min_interval"},
+		{"negative", `event_min_interval = "-1s"`, 0, "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, tt.hcl)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			if config.EventMinInterval != tt.want {
+				t.Errorf("EventMinInterval = %v, want %v", config.EventMinInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_RemoteShutdownSignals(t *testing.T) {
+	tests := []struct {
+		name    string
+		hcl     string
+		want    []string
+		wantErr string
+	}{
+		{"default", ``, []string{"HUP"}, ""},
+		{"custom", `remote { shutdown_signals = ["HUP", "sigquit", "QUIT"] }`, []string{"HUP", "QUIT"}, ""},
+		{"none", `remote { shutdown_signals = [] }`, []string{}, ""},
+		{"always graceful", `remote { shutdown_signals = ["TERM"] }`, nil, "unsupported shutdown signal"},
+		{"unknown", `remote { shutdown_signals = ["KILL"] }`, nil, "unsupported shutdown signal"},
+		{"broken pipe", `remote { shutdown_signals = ["PIPE"] }`, nil, "unsupported shutdown signal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, tt.hcl)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			if !reflect.DeepEqual(config.Remote.ShutdownSignals, tt.want) {
+				t.Errorf("ShutdownSignals = %v, want %v", config.Remote.ShutdownSignals, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_RemoteParentPollInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		hcl     string
+		want    time.Duration
+		wantErr string
+	}{
+		{"default", ``, 5 * time.Second, ""},
+		{"custom", `remote { parent_poll_interval = "1s" }`, time.Second, ""},
+		{"disabled", `remote { parent_poll_interval = "0s" }`, 0, ""},
+		{"invalid", `remote { parent_poll_interval = "often" }`, 0, "invalid parent_poll_interval"},
+		{"negative", `remote { parent_poll_interval = "-1s" }`, 0, "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := loadTestConfig(t, tt.hcl)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			if config.Remote.ParentPollInterval != tt.want {
+				t.Errorf("ParentPollInterval = %v, want %v", config.Remote.ParentPollInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_PublicIP(t *testing.T) {
+	t.Run("defaults when block omitted", func(t *testing.T) {
+		config, err := loadTestConfig(t, `verbose = 0`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if len(config.PublicIP.Providers) != 0 {
+			t.Errorf("expected no providers, got %v", config.PublicIP.Providers)
+		}
+		if config.PublicIP.Timeout != 3*time.Second {
+			t.Errorf("expected default timeout 3s, got %v", config.PublicIP.Timeout)
+		}
+		if config.PublicIP.CacheTTL != 0 {
+			t.Errorf("expected caching disabled by default, got %v", config.PublicIP.CacheTTL)
+		}
+	})
+
+	t.Run("cache_ttl", func(t *testing.T) {
+		config, err := loadTestConfig(t, `public_ip { cache_ttl = "30s" }`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if config.PublicIP.CacheTTL != 30*time.Second {
+			t.Errorf("expected cache_ttl 30s, got %v", config.PublicIP.CacheTTL)
+		}
+	})
+
+	t.Run("providers and timeout", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+public_ip {
+  providers = ["https://api.ipify.org", "http://ifconfig.me/ip"]
+  timeout   = "2s"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		expected := []string{"https://api.ipify.org", "http://ifconfig.me/ip"}
+		if len(config.PublicIP.Providers) != len(expected) {
+			t.Fatalf("expected %d providers, got %v", len(expected), config.PublicIP.Providers)
+		}
+		for i, p := range expected {
+			if config.PublicIP.Providers[i] != p {
+				t.Errorf("provider %d: expected %q, got %q", i, p, config.PublicIP.Providers[i])
+			}
+		}
+		if config.PublicIP.Timeout != 2*time.Second {
+			t.Errorf("expected timeout 2s, got %v", config.PublicIP.Timeout)
+		}
+	})
+
+	invalid := []struct {
+		name     string
+		hcl      string
+		contains string
+	}{
+		{"missing scheme", `public_ip { providers = ["api.ipify.org"] }`, "invalid provider"},
+		{"unsupported scheme", `public_ip { providers = ["ftp://example.com/ip"] }`, "invalid provider"},
+		{"missing host", `public_ip { providers = ["https:///ip"] }`, "invalid provider"},
+		{"invalid timeout", `public_ip { timeout = "soon" }`, "invalid timeout"},
+		{"non-positive timeout", `public_ip { timeout = "0s" }`, "timeout must be positive"},
+		{"invalid cache_ttl", `public_ip { cache_ttl = "later" }`, "invalid cache_ttl"},
+		{"negative cache_ttl", `public_ip { cache_ttl = "-5s" }`, "cache_ttl must not be negative"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, tt.hcl)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("expected error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestMergeHCLConfig_PublicIPSingleton(t *testing.T) {
+	dst := &hclConfig{PublicIP: &hclPublicIP{Providers: []string{"https://a.example"}}}
+	src := &hclConfig{PublicIP: &hclPublicIP{Providers: []string{"https://b.example"}}}
+	err := mergeHCLConfig(dst, src)
+	if err == nil || !strings.Contains(err.Error(), "public_ip block defined in multiple files") {
+		t.Errorf("expected singleton error, got %v", err)
+	}
+}
+
+func TestLoadConfig_ContextLocationsMode(t *testing.T) {
+	config, err := loadTestConfig(t, `
+location "office" {
+  conditions { public_ip = ["5.6.7.8"] }
+}
+
+location "docked" {
+  conditions {
+    env = { DOCKED = "yes" }
+  }
+}
+
+context "office-desk" {
+  locations      = ["office", "docked"]
+  locations_mode = "all"
+}
+
+context "office" {
+  locations = ["office"]
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if got := config.Contexts[0].LocationsMode; got != "all" {
+		t.Errorf("expected locations_mode='all', got %q", got)
+	}
+	if got := config.Contexts[1].LocationsMode; got != "any" {
+		t.Errorf("expected locations_mode='any' (default), got %q", got)
+	}
+
+	_, err = loadTestConfig(t, `
+context "bad" {
+  locations      = ["office"]
+  locations_mode = "most"
+}
+`)
+	if err == nil {
+		t.Fatal("expected error for invalid locations_mode")
+	}
+	if !strings.Contains(err.Error(), "locations_mode must be 'any' or 'all'") {
+		t.Errorf("expected locations_mode error, got: %v", err)
+	}
+}
+
+func TestLoadConfig_LocationOverrideContextEnvironment(t *testing.T) {
+	config, err := loadTestConfig(t, `
+location "home" {
+  conditions { public_ip = ["1.2.3.4"] }
+  override_context_environment = true
+}
+
+location "office" {
+  conditions { public_ip = ["5.6.7.8"] }
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if !config.Locations["home"].OverrideContextEnvironment {
+		t.Error("expected override_context_environment=true for home")
+	}
+	if config.Locations["office"].OverrideContextEnvironment {
+		t.Error("expected override_context_environment=false (default) for office")
+	}
+}
+
+func TestLoadConfig_ContextPreserveExisting(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context "travel" {
+  preserve_existing = true
+  actions {
+    connect    = ["vpn"]
+    disconnect = ["home-lab"]
+  }
+}
+
+context "office" {}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if !config.Contexts[0].PreserveExisting {
+		t.Error("expected preserve_existing=true for travel")
+	}
+	if config.Contexts[1].PreserveExisting {
+		t.Error("expected preserve_existing=false (default) for office")
+	}
+}
+
+func TestLoadConfig_ContextReassert(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context "office" {
+  reassert = true
+  actions {
+    connect = ["db"]
+  }
+}
+
+context "home" {}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if !config.Contexts[0].Reassert {
+		t.Error("expected reassert=true for office")
+	}
+	if config.Contexts[1].Reassert {
+		t.Error("expected reassert=false (default) for home")
+	}
+}
+
+func TestLoadConfig_Disabled(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context "testing" {
+  disabled = true
+}
+
+context "office" {}
+
+tunnel "db" {
+  disabled = true
+}
+
+tunnel "web" {}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if !config.Contexts[0].Disabled {
+		t.Error("expected disabled=true for context testing")
+	}
+	if config.Contexts[1].Disabled {
+		t.Error("expected disabled=false (default) for context office")
+	}
+	if !config.Tunnels["db"].Disabled {
+		t.Error("expected disabled=true for tunnel db")
+	}
+	if config.Tunnels["web"].Disabled {
+		t.Error("expected disabled=false (default) for tunnel web")
+	}
+}
+
+func TestLoadConfig_ConnectOnStartup(t *testing.T) {
+	config, err := loadTestConfig(t, `
+tunnel "vpn" {
+  connect_on_startup = true
+}
+
+tunnel "web" {}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if !config.Tunnels["vpn"].ConnectOnStartup {
+		t.Error("expected connect_on_startup=true for tunnel vpn")
+	}
+	if config.Tunnels["web"].ConnectOnStartup {
+		t.Error("expected connect_on_startup=false (default) for tunnel web")
+	}
+}
+
+func TestLoadConfig_StableReset(t *testing.T) {
+	t.Run("defaults to 0 (start over on every reconnect)", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+ssh {
+  max_retries = 5
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if config.SSH.StableReset != 0 {
+			t.Errorf("expected stable_reset=0, got %v", config.SSH.StableReset)
+		}
+	})
+
+	t.Run("custom value", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+ssh {
+  stable_reset = "30m"
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if config.SSH.StableReset != 30*time.Minute {
+			t.Errorf("expected stable_reset=30m, got %v", config.SSH.StableReset)
+		}
+	})
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		for _, value := range []string{"soon", "-1m"} {
+			_, err := loadTestConfig(t, fmt.Sprintf("ssh {\n  stable_reset = %q\n}\n", value))
+			if err == nil || !strings.Contains(err.Error(), "stable_reset") {
+				t.Errorf("stable_reset = %q: expected an error, got %v", value, err)
+			}
+		}
+	})
+}
+
+func TestLoadConfig_DryRun(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context "office" {
+  dry_run = true
+}
+
+context "home" {}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if !config.Contexts[0].DryRun {
+		t.Error("expected dry_run=true for office")
+	}
+	if config.Contexts[1].DryRun {
+		t.Error("expected dry_run=false (default) for home")
+	}
+}
+
+func TestLoadConfig_OnReloadHooks(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context_hooks {
+  on_reload = ["~/bin/verify-context.sh"]
+  timeout   = "5s"
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	hooks := config.GlobalContextHooks
+	if hooks == nil || len(hooks.OnReload) != 1 {
+		t.Fatalf("expected 1 on_reload hook, got %+v", hooks)
+	}
+	if hooks.OnReload[0].Command != "~/bin/verify-context.sh" || hooks.OnReload[0].Timeout != 5*time.Second {
+		t.Errorf("unexpected on_reload hook %+v", hooks.OnReload[0])
+	}
+
+	for name, content := range map[string]string{
+		"context":        "context \"home\" {\n  hooks {\n    on_reload = [\"x\"]\n  }\n}\n",
+		"location":       "location \"home\" {\n  hooks {\n    on_reload = [\"x\"]\n  }\n}\n",
+		"location_hooks": "location_hooks {\n  on_reload = [\"x\"]\n}\n",
+	} {
+		if _, err := loadTestConfig(t, content); err == nil || !strings.Contains(err.Error(), "on_reload can only be set") {
+			t.Errorf("%s: expected on_reload to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestLoadConfig_ContextSSHOverrides(t *testing.T) {
+	config, err := loadTestConfig(t, `
+ssh {
+  server_alive_interval = 15
+  server_alive_count_max = 3
+}
+
+context "office" {
+  ssh {
+    server_alive_interval = 5
+  }
+}
+
+context "home" {}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	office := config.Contexts[0]
+	if office.SSH == nil {
+		t.Fatal("expected ssh overrides on office context")
+	}
+	effective := config.SSH.WithOverrides(office.SSH)
+	if effective.ServerAliveInterval != 5 {
+		t.Errorf("expected overridden interval 5, got %d", effective.ServerAliveInterval)
+	}
+	if effective.ServerAliveCountMax != 3 {
+		t.Errorf("expected inherited count max 3, got %d", effective.ServerAliveCountMax)
+	}
+
+	home := config.Contexts[1]
+	if home.SSH != nil {
+		t.Errorf("expected no ssh overrides on home context, got %+v", home.SSH)
+	}
+	if got := config.SSH.WithOverrides(home.SSH); !reflect.DeepEqual(got, config.SSH) {
+		t.Errorf("expected global settings without overrides, got %+v", got)
+	}
+
+	t.Run("negative values rejected", func(t *testing.T) {
+		_, err := loadTestConfig(t, `
+context "office" {
+  ssh {
+    server_alive_interval = -1
+  }
+}
+`)
+		if err == nil {
+			t.Fatal("expected error for negative server_alive_interval")
+		}
+	})
+}
+
+func TestLoadConfig_SSHAskpass(t *testing.T) {
+	config, err := loadTestConfig(t, `
+ssh {
+  askpass = "/usr/bin/ssh-askpass"
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if config.SSH.Askpass != "/usr/bin/ssh-askpass" {
+		t.Errorf("expected askpass /usr/bin/ssh-askpass, got %q", config.SSH.Askpass)
+	}
+
+	home, _ := os.UserHomeDir()
+	config, err = loadTestConfig(t, `
+ssh {
+  askpass = "~/bin/askpass"
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if want := filepath.Join(home, "bin/askpass"); config.SSH.Askpass != want {
+		t.Errorf("expected askpass %q, got %q", want, config.SSH.Askpass)
+	}
+
+	if _, err := loadTestConfig(t, `
+ssh {
+  askpass = "ssh-askpass"
+}
+`); err == nil || !strings.Contains(err.Error(), "absolute path") {
+		t.Errorf("expected an absolute path error for a relative askpass, got %v", err)
+	}
+}
+
+func TestLoadConfig_KeepaliveProfiles(t *testing.T) {
+	config, err := loadTestConfig(t, `
+ssh {
+  server_alive_interval  = 15
+  server_alive_count_max = 3
+
+  keepalive_profiles = {
+    "on_battery" = { interval = 60 }
+    "on_ac"      = { interval = 5, count_max = 2 }
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		power        string
+		wantInterval int
+		wantCountMax int
+	}{
+		{"on battery", "battery", 60, 3},
+		{"on ac", "ac", 5, 2},
+		{"unknown state", "", 15, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := config.SSH.WithKeepaliveProfile(tt.power)
+			if got.ServerAliveInterval != tt.wantInterval || got.ServerAliveCountMax != tt.wantCountMax {
+				t.Errorf("WithKeepaliveProfile(%q) = interval %d, count max %d; want %d, %d",
+					tt.power, got.ServerAliveInterval, got.ServerAliveCountMax, tt.wantInterval, tt.wantCountMax)
+			}
+		})
+	}
+
+	t.Run("context overrides win over the profile", func(t *testing.T) {
+		got := config.SSH.WithKeepaliveProfile("battery").WithOverrides(&SSHOverrides{ServerAliveInterval: 10})
+		if got.ServerAliveInterval != 10 {
+			t.Errorf("expected context interval 10, got %d", got.ServerAliveInterval)
+		}
+	})
+
+	invalid := []struct {
+		name    string
+		profile string
+		wantErr string
+	}{
+		{"unknown state rejected", `"charging" = { interval = 60 }`, "unknown state"},
+		{"unknown setting rejected", `"on_battery" = { timeout = 60 }`, "unknown setting"},
+		{"negative interval rejected", `"on_battery" = { interval = -1 }`, "must not be negative"},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, fmt.Sprintf("ssh {\n  keepalive_profiles = {\n    %s\n  }\n}\n", tt.profile))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Warnings(t *testing.T) {
+	t.Run("dangling location reference", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+location "home" {
+  conditions {
+    public_ip = ["203.0.113.1"]
+  }
+}
+
+context "home" {
+  locations = ["home", "hq", "offline"]
+  actions {
+    connect = ["nas"]
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		want := []Warning{{`context "home" references undefined location "hq"`}}
+		if !reflect.DeepEqual(config.Warnings, want) {
+			t.Errorf("expected warnings %v, got %v", want, config.Warnings)
+		}
+	})
+
+	t.Run("identical location conditions", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+location "home" {
+  conditions {
+    public_ip = ["203.0.113.1"]
+  }
+}
+
+location "cabin" {
+  conditions {
+    public_ip = ["203.0.113.1"]
+  }
+}
+
+location "hq" {
+  conditions {
+    public_ip = ["198.51.100.7"]
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		want := []Warning{{`locations "cabin" and "home" have identical conditions, which of them is detected is undefined`}}
+		if !reflect.DeepEqual(config.Warnings, want) {
+			t.Errorf("expected warnings %v, got %v", want, config.Warnings)
+		}
+	})
+
+	t.Run("empty connect list", func(t *testing.T) {
+		config, err := loadTestConfig(t, `
+context "cafe" {
+  actions {
+    connect = []
+  }
+}
+
+context "hotel" {
+  preserve_existing = true
+  actions {
+    disconnect = ["nas"]
+  }
+}
+
+context "office" {
+  actions {
+    disconnect = ["nas"]
+  }
+}
+`)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+
+		want := []Warning{
+			{`context "cafe": actions have an empty connect list and nothing to disconnect`},
+			{`context "hotel": actions have an empty connect list and nothing to disconnect`},
+		}
+		if !reflect.DeepEqual(config.Warnings, want) {
+			t.Errorf("expected warnings %v, got %v", want, config.Warnings)
+		}
+	})
+
+	t.Run("default config has no warnings", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.hcl")
+		if err := writeDefaultHCLConfig(path); err != nil {
+			t.Fatalf("Failed to write default config: %v", err)
+		}
+		config, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if len(config.Warnings) != 0 {
+			t.Errorf("expected no warnings, got %v", config.Warnings)
+		}
+	})
+}
+
+func TestActionTunnelWarnings(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context "office" {
+  actions {
+    connect    = ["nas", "jump", "bastion"]
+    disconnect = ["home-*", "media"]
+  }
+}
+
+tunnel "nas" {}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	// nas has a tunnel block, bastion is an SSH host and patterns are only
+	// checked once they are expanded
+	got := ActionTunnelWarnings(config, func(alias string) bool { return alias == "bastion" })
+	want := []Warning{
+		{`context "office": connect references tunnel "jump", which is neither a tunnel block nor an SSH host`},
+		{`context "office": disconnect references tunnel "media", which is neither a tunnel block nor an SSH host`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected warnings %v, got %v", want, got)
+	}
+}
+
+func TestLoadConfig_ActionTunnelPatterns(t *testing.T) {
+	config, err := loadTestConfig(t, `
+context "office" {
+  actions {
+    connect    = ["corp-*"]
+    disconnect = ["home-?", "*"]
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	actions := config.Contexts[0].Actions
+	if !reflect.DeepEqual(actions.Connect, []string{"corp-*"}) || !reflect.DeepEqual(actions.Disconnect, []string{"home-?", "*"}) {
+		t.Errorf("expected patterns to be kept as written, got %+v", actions)
+	}
+
+	_, err = loadTestConfig(t, `
+context "office" {
+  actions {
+    disconnect = ["corp-[a"]
+  }
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), `context "office": invalid tunnel pattern "corp-[a"`) {
+		t.Errorf("expected invalid pattern error, got %v", err)
+	}
+}
+
+func TestLoadConfig_CompanionCommandForms(t *testing.T) {
+	config, err := loadTestConfig(t, `
+tunnel "vpn" {
+  companion "script" {
+    command = "~/bin/start-vpn.sh"
+  }
+  companion "proxy" {
+    command = ["/usr/bin/proxy", "--config", "/etc/my proxy.conf", "--port", 8080]
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	script := config.Tunnels["vpn"].Companions[0]
+	if script.Command != "~/bin/start-vpn.sh" || script.Args != nil {
+		t.Errorf("expected string command without args, got command=%q args=%q", script.Command, script.Args)
+	}
+
+	proxy := config.Tunnels["vpn"].Companions[1]
+	wantArgs := []string{"/usr/bin/proxy", "--config", "/etc/my proxy.conf", "--port", "8080"}
+	if !reflect.DeepEqual(proxy.Args, wantArgs) {
+		t.Errorf("expected args %q, got %q", wantArgs, proxy.Args)
+	}
+	if want := `/usr/bin/proxy --config "/etc/my proxy.conf" --port 8080`; proxy.Command != want {
+		t.Errorf("expected display command %q, got %q", want, proxy.Command)
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// resolveHCLEnvironments resolves every environment attribute in a decoded
+// file into its Environment map. See decodeEnvironment.
+func resolveHCLEnvironments(cfg *hclConfig) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	resolve := func(expr hcl.Expression, dst *map[string]string) {
+		env, envDiags := decodeEnvironment(expr, nil)
+		diags = append(diags, envDiags...)
+		*dst = env
+	}
+
+	resolve(cfg.EnvironmentExpr, &cfg.Environment)
+	for i := range cfg.Locations {
+		resolve(cfg.Locations[i].EnvironmentExpr, &cfg.Locations[i].Environment)
+	}
+	for i := range cfg.Contexts {
+		resolve(cfg.Contexts[i].EnvironmentExpr, &cfg.Contexts[i].Environment)
+	}
+	for i := range cfg.Tunnels {
+		tunnel := &cfg.Tunnels[i]
+		resolve(tunnel.EnvironmentExpr, &tunnel.Environment)
+
+		// Companions can reference the tunnel's environment as ${tunnel.KEY}
+		tunnelEnv := tunnel.Environment
+		if tunnelEnv == nil {
+			tunnelEnv = map[string]string{}
+		}
+		for j := range tunnel.Companions {
+			companion := &tunnel.Companions[j]
+			env, envDiags := decodeEnvironment(companion.EnvironmentExpr, tunnelEnv)
+			diags = append(diags, envDiags...)
+			companion.Environment = env
+		}
+	}
+	return diags
+}
+
+// decodeEnvironment evaluates an environment map. Values may reference keys
+// defined earlier in the same map, e.g. { BASE = "/opt", BIN = "${BASE}/bin" },
+// and are resolved in definition order. References to keys defined later, to
+// themselves (cycles) or to names that aren't keys of the map are errors.
+// When tunnelEnv is given, values may also reference its keys as
+// ${tunnel.KEY}, which is how companions use their tunnel's environment.
+// Returns nil if the attribute is not set.
+func decodeEnvironment(expr hcl.Expression, tunnelEnv map[string]string) (map[string]string, hcl.Diagnostics) {
+	if expr == nil {
+		return nil, nil
+	}
+	if len(expr.Variables()) == 0 {
+		val, diags := expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		if val.IsNull() {
+			return nil, nil
+		}
+	}
+
+	pairs, diags := hcl.ExprMap(expr)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	keys := make([]string, len(pairs))
+	index := make(map[string]int, len(pairs))
+	for i, pair := range pairs {
+		key, keyDiags := evalEnvironmentString(pair.Key, nil)
+		if keyDiags.HasErrors() {
+			return nil, keyDiags
+		}
+		keys[i] = key
+		index[key] = i
+	}
+
+	// Each key's references, checked up front so errors name the actual problem
+	deps := make([][]string, len(pairs))
+	for i, pair := range pairs {
+		for _, traversal := range pair.Value.Variables() {
+			if tunnelEnv != nil && traversal.RootName() == "tunnel" {
+				if diags := checkTunnelReference(keys[i], traversal, tunnelEnv); diags.HasErrors() {
+					return nil, diags
+				}
+				continue
+			}
+			deps[i] = append(deps[i], traversal.RootName())
+		}
+	}
+	for i, pair := range pairs {
+		for _, name := range deps[i] {
+			j, defined := index[name]
+			var detail string
+			switch {
+			case !defined:
+				detail = fmt.Sprintf("%s references %q, which is not defined in this environment block", keys[i], name)
+			case j < i:
+				continue
+			default:
+				if cycle := environmentCycle(keys, index, deps, i, j); cycle != nil {
+					detail = fmt.Sprintf("%s is part of a reference cycle: %s", keys[i], strings.Join(cycle, " -> "))
+				} else {
+					detail = fmt.Sprintf("%s references %q before it is defined", keys[i], name)
+				}
+			}
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid environment reference",
+				Detail:   detail,
+				Subject:  pair.Value.Range().Ptr(),
+			}}
+		}
+	}
+
+	env := make(map[string]string, len(pairs))
+	vars := make(map[string]cty.Value, len(pairs))
+	if tunnelEnv != nil {
+		tunnelVars := make(map[string]cty.Value, len(tunnelEnv))
+		for k, v := range tunnelEnv {
+			tunnelVars[k] = cty.StringVal(v)
+		}
+		vars["tunnel"] = cty.ObjectVal(tunnelVars)
+	}
+	for i, pair := range pairs {
+		value, valueDiags := evalEnvironmentString(pair.Value, &hcl.EvalContext{Variables: vars})
+		if valueDiags.HasErrors() {
+			return nil, valueDiags
+		}
+		env[keys[i]] = value
+		vars[keys[i]] = cty.StringVal(value)
+	}
+	return env, nil
+}
+
+// checkTunnelReference checks that a ${tunnel.KEY} reference in the value of
+// key names a key of the tunnel's environment
+func checkTunnelReference(key string, traversal hcl.Traversal, tunnelEnv map[string]string) hcl.Diagnostics {
+	var attr hcl.TraverseAttr
+	if len(traversal) == 2 {
+		attr, _ = traversal[1].(hcl.TraverseAttr)
+	}
+
+	var detail string
+	if attr.Name == "" {
+		detail = fmt.Sprintf("%s references the tunnel's environment, use ${tunnel.KEY}", key)
+	} else if _, defined := tunnelEnv[attr.Name]; !defined {
+		detail = fmt.Sprintf("%s references \"tunnel.%s\", which is not defined in the tunnel's environment block", key, attr.Name)
+	} else {
+		return nil
+	}
+	return hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid environment reference",
+		Detail:   detail,
+		Subject:  traversal.SourceRange().Ptr(),
+	}}
+}
+
+// environmentCycle returns the reference chain from key i through key j back
+// to key i, or nil if j doesn't lead back to i
+func environmentCycle(keys []string, index map[string]int, deps [][]string, i, j int) []string {
+	visited := make(map[int]bool)
+	var walk func(k int) []string
+	walk = func(k int) []string {
+		if k == i {
+			return []string{keys[i]}
+		}
+		if visited[k] {
+			return nil
+		}
+		visited[k] = true
+		for _, name := range deps[k] {
+			if next, ok := index[name]; ok {
+				if path := walk(next); path != nil {
+					return append([]string{keys[k]}, path...)
+				}
+			}
+		}
+		return nil
+	}
+
+	if path := walk(j); path != nil {
+		return append([]string{keys[i]}, path...)
+	}
+	return nil
+}
+
+// evalEnvironmentString evaluates an environment key or value as a string
+func evalEnvironmentString(expr hcl.Expression, ctx *hcl.EvalContext) (string, hcl.Diagnostics) {
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	str, err := convert.Convert(val, cty.String)
+	if err != nil || str.IsNull() || !str.IsKnown() {
+		return "", hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid environment value",
+			Detail:   "Environment keys and values must be strings",
+			Subject:  expr.Range().Ptr(),
+		}}
+	}
+	return str.AsString(), nil
+}
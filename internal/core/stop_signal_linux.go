@@ -0,0 +1,5 @@
+package core
+
+// maxStopSignal is the highest signal number a companion's stop_signal can
+// be, the last real-time signal on Linux
+const maxStopSignal = 64
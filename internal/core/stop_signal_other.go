@@ -0,0 +1,7 @@
+//go:build !linux
+
+package core
+
+// maxStopSignal is the highest signal number a companion's stop_signal can
+// be, macOS and the BSDs have no real-time signals and stop at SIGUSR2
+const maxStopSignal = 31
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"encoding/json"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -237,3 +238,80 @@ func TestAdoptCompanions_CommandMismatch(t *testing.T) {
 		t.Errorf("expected 0 adopted (command mismatch), got %d", count)
 	}
 }
+
+func TestCleanupStaleSockets(t *testing.T) {
+	quietLogger(t)
+
+	// Short path, unix socket paths are limited in length
+	dir, err := os.MkdirTemp("/tmp", "ov-sock-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// Orphan: socket file left behind with nothing listening
+	orphanPath := filepath.Join(dir, "overseer-companion-old-tunnel-comp.sock")
+	orphan, err := net.Listen("unix", orphanPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan.(*net.UnixListener).SetUnlinkOnClose(false)
+	orphan.Close()
+
+	// Active: socket with a live listener
+	activePath := filepath.Join(dir, "overseer-companion-live-tunnel-comp.sock")
+	active, err := net.Listen("unix", activePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer active.Close()
+
+	// Unrelated files are never touched
+	otherPath := filepath.Join(dir, "something-else.sock")
+	if err := os.WriteFile(otherPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cm := NewCompanionManager()
+	if removed := cm.cleanupStaleSockets(dir); removed != 1 {
+		t.Errorf("expected 1 socket removed, got %d", removed)
+	}
+
+	if _, err := os.Stat(orphanPath); !os.IsNotExist(err) {
+		t.Error("expected orphan socket to be removed")
+	}
+	if _, err := os.Stat(activePath); err != nil {
+		t.Errorf("expected active socket to be kept: %v", err)
+	}
+	if _, err := os.Stat(otherPath); err != nil {
+		t.Errorf("expected unrelated file to be kept: %v", err)
+	}
+}
+
+func TestCleanupStaleSockets_KeepsKnownCompanion(t *testing.T) {
+	quietLogger(t)
+
+	dir, err := os.MkdirTemp("/tmp", "ov-sock-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// A known companion's socket is kept even if nothing answers on it
+	socketPath := filepath.Join(dir, "overseer-companion-tunnel-comp.sock")
+	if err := os.WriteFile(socketPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cm := NewCompanionManager()
+	cm.companions["tunnel"] = map[string]*CompanionProcess{
+		"comp": {TunnelAlias: "tunnel", Name: "comp", socketPath: socketPath},
+	}
+
+	if removed := cm.cleanupStaleSockets(dir); removed != 0 {
+		t.Errorf("expected no sockets removed, got %d", removed)
+	}
+	if _, err := os.Stat(socketPath); err != nil {
+		t.Errorf("expected known companion socket to be kept: %v", err)
+	}
+}
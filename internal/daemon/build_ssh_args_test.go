@@ -1,9 +1,14 @@
 package daemon
 
 import (
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
+
+	"go.olrik.dev/overseer/internal/awareness/state"
+	"go.olrik.dev/overseer/internal/core"
 )
 
 // containsOption checks that args has a matching "-o key=value" pair in order.
@@ -25,7 +30,7 @@ func containsOption(args []string, key, value string) bool {
 // detach fork while still leaving the mux master socket live for the duration
 // of the tunnel, so interactive sessions, scp, and rsync keep multiplexing.
 func TestBuildTunnelSSHArgs_ForcesControlPersistNo(t *testing.T) {
-	args := buildTunnelSSHArgs("b1.fibianet.dk", "", 0, 0)
+	args := buildTunnelSSHArgs("b1.fibianet.dk", "", 0, 0, false)
 
 	if !containsOption(args, "ControlPersist", "no") {
 		t.Fatalf("expected args to contain -o ControlPersist=no, got %v", args)
@@ -33,7 +38,7 @@ func TestBuildTunnelSSHArgs_ForcesControlPersistNo(t *testing.T) {
 }
 
 func TestBuildTunnelSSHArgs_IncludesCoreOptions(t *testing.T) {
-	args := buildTunnelSSHArgs("myhost", "", 0, 0)
+	args := buildTunnelSSHArgs("myhost", "", 0, 0, false)
 
 	// Alias must be present
 	if !slices.Contains(args, "myhost") {
@@ -68,7 +73,7 @@ func TestBuildTunnelSSHArgs_IncludesCoreOptions(t *testing.T) {
 }
 
 func TestBuildTunnelSSHArgs_PrependsConfigFile(t *testing.T) {
-	args := buildTunnelSSHArgs("myhost", "/tmp/custom_ssh_config", 0, 0)
+	args := buildTunnelSSHArgs("myhost", "/tmp/custom_ssh_config", 0, 0, false)
 
 	if len(args) < 2 || args[0] != "-F" || args[1] != "/tmp/custom_ssh_config" {
 		t.Errorf("expected args to start with -F /tmp/custom_ssh_config, got %v", args[:min(2, len(args))])
@@ -76,7 +81,7 @@ func TestBuildTunnelSSHArgs_PrependsConfigFile(t *testing.T) {
 }
 
 func TestBuildTunnelSSHArgs_OmitsConfigFileWhenEmpty(t *testing.T) {
-	args := buildTunnelSSHArgs("myhost", "", 0, 0)
+	args := buildTunnelSSHArgs("myhost", "", 0, 0, false)
 
 	if slices.Contains(args, "-F") {
 		t.Errorf("expected no -F flag when sshConfigFile is empty, got %v", args)
@@ -84,7 +89,7 @@ func TestBuildTunnelSSHArgs_OmitsConfigFileWhenEmpty(t *testing.T) {
 }
 
 func TestBuildTunnelSSHArgs_AddsServerAliveWhenConfigured(t *testing.T) {
-	args := buildTunnelSSHArgs("myhost", "", 30, 3)
+	args := buildTunnelSSHArgs("myhost", "", 30, 3, false)
 
 	if !containsOption(args, "ServerAliveInterval", "30") {
 		t.Errorf("expected ServerAliveInterval=30, got %v", args)
@@ -95,7 +100,7 @@ func TestBuildTunnelSSHArgs_AddsServerAliveWhenConfigured(t *testing.T) {
 }
 
 func TestBuildTunnelSSHArgs_OmitsServerAliveWhenZero(t *testing.T) {
-	args := buildTunnelSSHArgs("myhost", "", 0, 3)
+	args := buildTunnelSSHArgs("myhost", "", 0, 3, false)
 
 	for _, a := range args {
 		if strings.HasPrefix(a, "ServerAliveInterval=") {
@@ -103,3 +108,103 @@ func TestBuildTunnelSSHArgs_OmitsServerAliveWhenZero(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildTunnelSSHArgs_NoControlMaster(t *testing.T) {
+	if args := buildTunnelSSHArgs("myhost", "", 0, 0, false); containsOption(args, "ControlMaster", "no") {
+		t.Errorf("expected ControlMaster to be left to ssh_config by default, got %v", args)
+	}
+
+	args := buildTunnelSSHArgs("myhost", "", 0, 0, true)
+	if !containsOption(args, "ControlMaster", "no") {
+		t.Errorf("expected ControlMaster=no, got %v", args)
+	}
+}
+
+func TestBuildTunnelSSHArgs_RemoteCommand(t *testing.T) {
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{Tunnels: map[string]*core.TunnelConfig{
+		"myhost": {Name: "myhost", RemoteCommand: "tail -f /var/log/app.log"},
+	}}
+
+	args := buildTunnelSSHArgs("myhost", "", 30, 3, true)
+	if slices.Contains(args, "-N") {
+		t.Errorf("expected no -N with a remote command, got %v", args)
+	}
+	// The command must come last, after the destination and all options
+	if args[len(args)-1] != "tail -f /var/log/app.log" {
+		t.Errorf("expected the remote command as the last argument, got %v", args)
+	}
+	if !containsOption(args, "ControlMaster", "no") {
+		t.Errorf("expected options to be kept, got %v", args)
+	}
+
+	if args := buildTunnelSSHArgs("otherhost", "", 0, 0, false); !slices.Contains(args, "-N") {
+		t.Errorf("expected -N for a tunnel without a remote command, got %v", args)
+	}
+}
+
+// TestKeepaliveProfiles_PowerSensorValues keeps the keepalive_profiles keys
+// core accepts in line with the values of the power sensor, which the daemon
+// passes to WithKeepaliveProfile
+func TestKeepaliveProfiles_PowerSensorValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.hcl")
+	config := `
+ssh {
+  server_alive_interval = 15
+  keepalive_profiles = {
+    "on_battery" = { interval = 60 }
+    "on_ac"      = { interval = 5 }
+  }
+}
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := core.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	for source, want := range map[string]int{state.PowerBattery: 60, state.PowerAC: 5} {
+		if got := cfg.SSH.WithKeepaliveProfile(source).ServerAliveInterval; got != want {
+			t.Errorf("power %q: ServerAliveInterval = %d, want %d", source, got, want)
+		}
+	}
+}
+
+func TestBuildTunnelSSHArgs_ContextOverride(t *testing.T) {
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		SSH: core.SSHConfig{ServerAliveInterval: 15, ServerAliveCountMax: 3},
+		Contexts: []*core.ContextRule{
+			{Name: "office", SSH: &core.SSHOverrides{ServerAliveInterval: 5}},
+			{Name: "home"},
+		},
+	}
+
+	tests := []struct {
+		context      string
+		wantInterval string
+		wantCountMax string
+	}{
+		{"office", "5", "3"},
+		{"home", "15", "3"},
+		{"unknown", "15", "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.context, func(t *testing.T) {
+			settings := core.Config.SSH.WithOverrides(contextSSHOverrides(tt.context))
+			args := buildTunnelSSHArgs("myhost", "", settings.ServerAliveInterval, settings.ServerAliveCountMax, false)
+
+			if !containsOption(args, "ServerAliveInterval", tt.wantInterval) {
+				t.Errorf("expected ServerAliveInterval=%s, got %v", tt.wantInterval, args)
+			}
+			if !containsOption(args, "ServerAliveCountMax", tt.wantCountMax) {
+				t.Errorf("expected ServerAliveCountMax=%s, got %v", tt.wantCountMax, args)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -16,38 +17,40 @@ import (
 	"go.olrik.dev/overseer/internal/core"
 )
 
+// ProtocolVersion is the version of the socket protocol between client and
+// daemon. Bump it when a command or response changes incompatibly.
+const ProtocolVersion = 1
+
 var (
 	versionCheckOnce sync.Once
 	versionWarned    bool
 )
 
-// SendCommand connects to the daemon, sends a command, and returns the response.
-func SendCommand(command string) (Response, error) {
-	response := Response{}
+// ErrCommandFailed is returned by SendCommandStreaming when the daemon
+// streamed an ERROR message. The message itself has already been logged.
+var ErrCommandFailed = errors.New("daemon reported an error")
 
-	conn, err := net.Dial("unix", core.GetSocketPath())
-	if err != nil {
-		return response, err
-	}
-	defer conn.Close()
+// ErrDaemonNotResponding is returned when the daemon accepted a command but
+// didn't answer within CommandTimeout, e.g. because it is wedged.
+var ErrDaemonNotResponding = errors.New("daemon not responding")
 
-	if _, err := conn.Write([]byte(command + "\n")); err != nil {
-		return response, fmt.Errorf("failed to send command to daemon: %w", err)
-	}
-	bytes, err := io.ReadAll(conn)
-	if err != nil {
-		return response, fmt.Errorf("failed to read response from daemon: %w", err)
-	}
-
-	if err := json.Unmarshal(bytes, &response); err != nil {
-		return response, fmt.Errorf("failed to parse response from daemon: %w", err)
-	}
+// CommandTimeout is how long a client command waits for the daemon. Streaming
+// commands only wait this long for the first message: once the daemon has
+// accepted the command it may stay silent for as long as connecting takes,
+// e.g. while a password_command runs. 0 waits forever. Set by the global
+// --timeout flag.
+var CommandTimeout = 30 * time.Second
 
-	return response, nil
+// SendCommand connects to the daemon, sends a command, and returns the response.
+// It gives up with ErrDaemonNotResponding when the daemon doesn't answer
+// within CommandTimeout.
+func SendCommand(command string) (Response, error) {
+	return sendCommandWithTimeout(command, CommandTimeout)
 }
 
 // sendCommandWithTimeout connects to the daemon with a timeout, preventing the polling loop
 // from blocking indefinitely if the socket exists but Accept hasn't been called yet.
+// A timeout of 0 waits forever.
 func sendCommandWithTimeout(command string, timeout time.Duration) (Response, error) {
 	response := Response{}
 
@@ -57,15 +60,14 @@ func sendCommandWithTimeout(command string, timeout time.Duration) (Response, er
 	}
 	defer conn.Close()
 
-	deadline := time.Now().Add(timeout)
-	conn.SetDeadline(deadline)
+	setCommandDeadline(conn, timeout)
 
 	if _, err := conn.Write([]byte(command + "\n")); err != nil {
-		return response, fmt.Errorf("failed to send command to daemon: %w", err)
+		return response, commandError("failed to send command to daemon", err, timeout)
 	}
 	bytes, err := io.ReadAll(conn)
 	if err != nil {
-		return response, fmt.Errorf("failed to read response from daemon: %w", err)
+		return response, commandError("failed to read response from daemon", err, timeout)
 	}
 
 	if err := json.Unmarshal(bytes, &response); err != nil {
@@ -75,30 +77,61 @@ func sendCommandWithTimeout(command string, timeout time.Duration) (Response, er
 	return response, nil
 }
 
+// setCommandDeadline makes reads and writes on conn fail once timeout has
+// passed. A timeout of 0 clears the deadline.
+func setCommandDeadline(conn net.Conn, timeout time.Duration) {
+	if timeout <= 0 {
+		conn.SetDeadline(time.Time{})
+		return
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+}
+
+// commandError wraps a socket error, reporting an expired deadline as
+// ErrDaemonNotResponding
+func commandError(action string, err error, timeout time.Duration) error {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w within %s", ErrDaemonNotResponding, timeout)
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
 // SendCommandStreaming connects to the daemon, sends a command, and streams response messages.
 // Each message is logged as it arrives, allowing real-time progress feedback.
-// Returns an error if the connection or command fails.
+// Returns an error if the connection fails, or ErrCommandFailed if the daemon reported an error.
+// Returns ErrDaemonNotResponding if the first message doesn't arrive within CommandTimeout.
 func SendCommandStreaming(command string) error {
-	conn, err := net.Dial("unix", core.GetSocketPath())
+	timeout := CommandTimeout
+	conn, err := net.DialTimeout("unix", core.GetSocketPath(), timeout)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
+	setCommandDeadline(conn, timeout)
 	if _, err := conn.Write([]byte(command + "\n")); err != nil {
-		return fmt.Errorf("failed to send command to daemon: %w", err)
+		return commandError("failed to send command to daemon", err, timeout)
 	}
 
 	// Read response line by line - each line is a JSON message
 	reader := bufio.NewReader(conn)
+	failed := false
 	for {
 		line, err := reader.ReadBytes('\n')
 		if err != nil {
 			if err == io.EOF {
-				return nil // Normal end of stream
+				// Normal end of stream
+				if failed {
+					return ErrCommandFailed
+				}
+				return nil
 			}
-			return fmt.Errorf("failed to read response from daemon: %w", err)
+			return commandError("failed to read response from daemon", err, timeout)
 		}
+		// The daemon answered, so it isn't wedged. Connecting may take a while
+		// without progress messages (askpass prompts, companion waits,
+		// password commands), so don't cut it off from here on.
+		setCommandDeadline(conn, 0)
 
 		// Skip empty lines
 		if len(line) <= 1 {
@@ -120,6 +153,7 @@ func SendCommandStreaming(command string) error {
 			slog.Warn(msg.Message)
 		case "ERROR":
 			slog.Error(msg.Message)
+			failed = true
 		default:
 			slog.Info(msg.Message)
 		}
@@ -128,9 +162,15 @@ func SendCommandStreaming(command string) error {
 
 // EnsureDaemonIsRunning handles the auto-start logic.
 func EnsureDaemonIsRunning() {
-	if _, err := SendCommand("STATUS"); err == nil {
+	_, err := SendCommand("STATUS")
+	if err == nil {
 		return // Daemon is running
 	}
+	if errors.Is(err, ErrDaemonNotResponding) {
+		// Starting another daemon next to a wedged one would only make things worse
+		slog.Error(fmt.Sprintf("Fatal: %v", err))
+		os.Exit(1)
+	}
 
 	slog.Info("Daemon not running. Starting it now...")
 	cmd, err := StartDaemon()
@@ -147,15 +187,42 @@ func EnsureDaemonIsRunning() {
 	slog.Info("Daemon is ready.")
 }
 
-// CheckVersionMismatch checks if the client and daemon versions match and warns if they don't.
+// NegotiateProtocol sends the client's protocol version to the daemon and
+// returns the protocol the daemon speaks. Daemons from before the handshake
+// don't know HELLO and are reported as protocol 0.
+func NegotiateProtocol() (int, error) {
+	response, err := SendCommand(fmt.Sprintf("HELLO %d", ProtocolVersion))
+	if err != nil {
+		return 0, err
+	}
+	if dataMap, ok := response.Data.(map[string]interface{}); ok {
+		if protocol, ok := dataMap["protocol"].(float64); ok {
+			return int(protocol), nil
+		}
+	}
+	return 0, nil
+}
+
+// CheckVersionMismatch checks if the client and daemon versions and protocols match and warns if they don't.
 // This check is done only once per command execution.
 func CheckVersionMismatch() {
 	versionCheckOnce.Do(func() {
-		response, err := SendCommand("VERSION")
+		protocol, err := NegotiateProtocol()
 		if err != nil {
 			// Daemon not running, no need to check version
 			return
 		}
+		if protocol != ProtocolVersion {
+			slog.Warn(fmt.Sprintf("Protocol mismatch! Client speaks protocol %d, daemon protocol %d. Commands may fail.", ProtocolVersion, protocol))
+			slog.Warn("The daemon may be running an outdated version. Run 'overseer stop' and try again.")
+			versionWarned = true
+			return
+		}
+
+		response, err := SendCommand("VERSION")
+		if err != nil {
+			return
+		}
 
 		if response.Data != nil {
 			// Data comes back as map[string]interface{} from JSON unmarshaling
@@ -180,7 +247,8 @@ func CheckVersionMismatch() {
 // StartDaemon starts the daemon process in the background and returns the
 // exec.Cmd so callers can monitor the subprocess for early crashes.
 func StartDaemon() (*exec.Cmd, error) {
-	cmd := exec.Command(os.Args[0], "daemon", "--overseer-daemon="+core.ProcessTag())
+	// Pass the config file on so the daemon keys off the same config location
+	cmd := exec.Command(os.Args[0], "daemon", "--overseer-daemon="+core.ProcessTag(), "--config="+core.GetConfigFilePath())
 
 	// Pass the parent PID (shell/SSH session) to the daemon
 	// The daemon will monitor this PID instead of its own parent (which will be PID 1)
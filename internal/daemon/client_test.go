@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -131,6 +132,95 @@ func TestSendCommandWithTimeout_Timeout(t *testing.T) {
 	}
 }
 
+// TestCommandTimeout_WedgedDaemon verifies that commands give up on a daemon
+// that accepts the connection but never replies
+func TestCommandTimeout_WedgedDaemon(t *testing.T) {
+	quietLogger(t)
+
+	oldTimeout := CommandTimeout
+	t.Cleanup(func() { CommandTimeout = oldTimeout })
+	CommandTimeout = 200 * time.Millisecond
+
+	tests := []struct {
+		name string
+		send func() error
+	}{
+		{"SendCommand", func() error {
+			_, err := SendCommand("STATUS")
+			return err
+		}},
+		{"SendCommandStreaming", func() error {
+			return SendCommandStreaming("SSH_CONNECT test")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := setupSocketServer(t)
+
+			// Server: accept the connection and read the command, but never reply
+			done := make(chan struct{})
+			t.Cleanup(func() { close(done) })
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				conn.Read(buf)
+				<-done
+			}()
+
+			start := time.Now()
+			err := tt.send()
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, ErrDaemonNotResponding) {
+				t.Fatalf("expected ErrDaemonNotResponding, got %v", err)
+			}
+			if elapsed > 5*time.Second {
+				t.Errorf("expected quick timeout, took %s", elapsed)
+			}
+		})
+	}
+}
+
+// TestSendCommandStreaming_NoTimeoutOnceAccepted verifies that a streaming
+// command outlives the timeout once the daemon has sent its first message,
+// even when it then stays silent for longer than the timeout
+func TestSendCommandStreaming_NoTimeoutOnceAccepted(t *testing.T) {
+	quietLogger(t)
+
+	oldTimeout := CommandTimeout
+	t.Cleanup(func() { CommandTimeout = oldTimeout })
+	CommandTimeout = 200 * time.Millisecond
+
+	listener := setupSocketServer(t)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 1024)
+		conn.Read(buf)
+
+		// The second message comes well after the timeout, e.g. once a
+		// password_command has finished
+		for i, delay := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond} {
+			time.Sleep(delay)
+			data, _ := json.Marshal(ResponseMessage{Message: fmt.Sprintf("Step %d", i), Status: "INFO"})
+			fmt.Fprintf(conn, "%s\n", data)
+		}
+	}()
+
+	if err := SendCommandStreaming("SSH_CONNECT test"); err != nil {
+		t.Fatalf("SendCommandStreaming failed: %v", err)
+	}
+}
+
 func TestSendCommandWithTimeout_Success(t *testing.T) {
 	quietLogger(t)
 
@@ -197,6 +287,36 @@ func TestSendCommandStreaming_MultipleMessages(t *testing.T) {
 	}
 }
 
+func TestSendCommandStreaming_ErrorMessage(t *testing.T) {
+	quietLogger(t)
+
+	listener := setupSocketServer(t)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 1024)
+		conn.Read(buf)
+
+		for _, msg := range []ResponseMessage{
+			{Message: "Connecting...", Status: "INFO"},
+			{Message: "failed to connect", Status: "ERROR"},
+		} {
+			data, _ := json.Marshal(msg)
+			fmt.Fprintf(conn, "%s\n", data)
+		}
+	}()
+
+	err := SendCommandStreaming("SSH_CONNECT test")
+	if !errors.Is(err, ErrCommandFailed) {
+		t.Fatalf("expected ErrCommandFailed, got %v", err)
+	}
+}
+
 func TestSendCommandStreaming_ConnectionRefused(t *testing.T) {
 	quietLogger(t)
 
@@ -315,6 +435,79 @@ func TestSendCommand_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestNegotiateProtocol(t *testing.T) {
+	quietLogger(t)
+
+	t.Run("matching daemon", func(t *testing.T) {
+		listener := setupSocketServer(t)
+		d := &Daemon{
+			tunnels:       make(map[string]Tunnel),
+			askpassTokens: make(map[string]string),
+			logBroadcast:  NewLogBroadcaster(100),
+			companionMgr:  NewCompanionManager(),
+		}
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			d.handleConnection(conn)
+		}()
+
+		protocol, err := NegotiateProtocol()
+		if err != nil {
+			t.Fatalf("NegotiateProtocol failed: %v", err)
+		}
+		if protocol != ProtocolVersion {
+			t.Errorf("protocol = %d, want %d", protocol, ProtocolVersion)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		response Response
+		want     int
+	}{
+		{
+			name: "newer daemon",
+			response: Response{
+				Messages: []ResponseMessage{{Message: "Protocol mismatch", Status: "WARN"}},
+				Data:     map[string]interface{}{"protocol": ProtocolVersion + 1},
+			},
+			want: ProtocolVersion + 1,
+		},
+		{
+			name:     "daemon without handshake",
+			response: Response{Messages: []ResponseMessage{{Message: "Unknown command.", Status: "ERROR"}}},
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := setupSocketServer(t)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				conn.Read(buf)
+				data, _ := json.Marshal(tt.response)
+				conn.Write(data)
+			}()
+
+			protocol, err := NegotiateProtocol()
+			if err != nil {
+				t.Fatalf("NegotiateProtocol failed: %v", err)
+			}
+			if protocol != tt.want {
+				t.Errorf("protocol = %d, want %d", protocol, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetSocketPath(t *testing.T) {
 	tmpDir := t.TempDir()
 	oldConfig := core.Config
@@ -4,20 +4,27 @@ import (
 	"bufio"
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"go.olrik.dev/overseer/internal/awareness/state"
 	"go.olrik.dev/overseer/internal/core"
 )
 
@@ -61,6 +68,9 @@ type CompanionProcess struct {
 	State        CompanionState
 	ExitCode     *int
 	ExitError    string
+	reportedExit *int            // Child exit code reported by the wrapper, preferred over the wrapper's own
+	exitReported chan struct{}   // Closed once the wrapper reported the exit code or its connection ended
+	token        string          // Token of the wrapper, which authenticates its exit marker
 	output       *LogBroadcaster // For streaming combined stdout/stderr
 	socketPath   string          // Unix socket for wrapper communication
 	socketListen net.Listener    // Socket listener
@@ -75,13 +85,18 @@ type CompanionManager struct {
 	mu            sync.RWMutex
 	registerToken func(token, alias string)                    // Callback to register tokens with daemon
 	logEvent      func(alias, eventType, details string) error // Callback to log events to database
+	restart       func(proc *CompanionProcess) error           // Restarts a companion in place (replaceable in tests)
+	restartTunnel func(alias, name string)                     // Callback to restart a tunnel whose companion failed
+	reloadMu      sync.Mutex                                   // Serializes RestartChangedCompanions runs
 }
 
 // NewCompanionManager creates a new companion manager
 func NewCompanionManager() *CompanionManager {
-	return &CompanionManager{
+	cm := &CompanionManager{
 		companions: make(map[string]map[string]*CompanionProcess),
 	}
+	cm.restart = cm.restartCompanionInPlace
+	return cm
 }
 
 // SetTokenRegistrar sets the callback for registering tokens with the daemon
@@ -94,6 +109,12 @@ func (cm *CompanionManager) SetEventLogger(logger func(alias, eventType, details
 	cm.logEvent = logger
 }
 
+// SetTunnelRestarter sets the callback for restarting a tunnel when one of its
+// companions with on_failure = "restart_tunnel" fails
+func (cm *CompanionManager) SetTunnelRestarter(restarter func(alias, name string)) {
+	cm.restartTunnel = restarter
+}
+
 // logCompanionEvent logs a companion event if the logger is set
 func (cm *CompanionManager) logCompanionEvent(alias, name, eventType, details string) {
 	if cm.logEvent == nil {
@@ -115,6 +136,39 @@ type CompanionProgress struct {
 // ProgressCallback is called for each progress message during companion startup
 type ProgressCallback func(CompanionProgress)
 
+// companionPhase returns when a companion starts relative to the SSH
+// connection, companions without a phase start before it
+func companionPhase(config core.CompanionConfig) string {
+	if config.Phase == "post_connect" {
+		return "post_connect"
+	}
+	return "pre_connect"
+}
+
+// companionsInPhase returns the companions of configs that start in phase
+func companionsInPhase(configs []core.CompanionConfig, phase string) []core.CompanionConfig {
+	var selected []core.CompanionConfig
+	for _, config := range configs {
+		if companionPhase(config) == phase {
+			selected = append(selected, config)
+		}
+	}
+	return selected
+}
+
+// companionConfigDrifted reports whether config changes how the process of a
+// companion started with running is run: its command, pre_start, working
+// directory or environment. Changes to settings the daemon only consults while
+// supervising, such as timeouts or auto_restart, don't need a restart.
+func companionConfigDrifted(running, config core.CompanionConfig) bool {
+	return running.Command != config.Command ||
+		!slices.Equal(running.Args, config.Args) ||
+		running.PreStart != config.PreStart ||
+		running.Workdir != config.Workdir ||
+		running.EnvFile != config.EnvFile ||
+		!maps.Equal(running.Environment, config.Environment)
+}
+
 // StartCompanions starts all companion scripts for a tunnel
 // The optional onProgress callback is called for each progress message as it occurs,
 // allowing callers to stream progress to clients in real-time.
@@ -142,33 +196,51 @@ func (cm *CompanionManager) StartCompanions(alias string, configs []core.Compani
 			pid := existing.Pid
 			existing.mu.RUnlock()
 
+			started := "started"
 			if state == CompanionStateRunning || state == CompanionStateReady {
-				// Already running, skip
-				slog.Info("Companion already running (adopted), skipping start",
+				existing.mu.RLock()
+				drifted := companionConfigDrifted(existing.Config, config)
+				existing.mu.RUnlock()
+
+				if !drifted {
+					// Already running, skip
+					slog.Info("Companion already running (adopted), skipping start",
+						"tunnel", alias,
+						"companion", config.Name,
+						"pid", pid)
+					sendProgress(CompanionProgress{
+						Name:    config.Name,
+						Message: fmt.Sprintf("Companion '%s' already running (PID: %d)", config.Name, pid),
+					})
+					continue
+				}
+
+				// Running with a stale config - restart in place to pick up the new one
+				slog.Info("Companion configuration changed since it was started, restarting",
 					"tunnel", alias,
 					"companion", config.Name,
 					"pid", pid)
 				sendProgress(CompanionProgress{
 					Name:    config.Name,
-					Message: fmt.Sprintf("Companion '%s' already running (PID: %d)", config.Name, pid),
+					Message: fmt.Sprintf("Companion '%s' configuration changed, restarting...", config.Name),
+				})
+				started = "restarted"
+			} else {
+				// Existing entry but not running - restart in place to preserve broadcaster
+				sendProgress(CompanionProgress{
+					Name:    config.Name,
+					Message: fmt.Sprintf("Starting companion '%s'...", config.Name),
 				})
-				continue
 			}
 
-			// Existing entry but not running - restart in place to preserve broadcaster
-			sendProgress(CompanionProgress{
-				Name:    config.Name,
-				Message: fmt.Sprintf("Starting companion '%s'...", config.Name),
-			})
-
 			// Update config in case it changed
 			existing.mu.Lock()
 			existing.Config = config
 			existing.mu.Unlock()
 
-			if err := cm.restartCompanionInPlace(existing); err != nil {
-				if config.OnFailure == "block" {
-					cm.StopCompanions(alias)
+			if err := cm.restart(existing); err != nil {
+				if config.OnFailure != "continue" {
+					cm.StopCompanions(alias, false)
 					sendProgress(CompanionProgress{
 						Name:    config.Name,
 						Message: fmt.Sprintf("Companion '%s' failed: %v", config.Name, err),
@@ -190,7 +262,7 @@ func (cm *CompanionManager) StartCompanions(alias string, configs []core.Compani
 
 			sendProgress(CompanionProgress{
 				Name:    config.Name,
-				Message: fmt.Sprintf("Companion '%s' started", config.Name),
+				Message: fmt.Sprintf("Companion '%s' %s", config.Name, started),
 			})
 			continue
 		}
@@ -203,9 +275,9 @@ func (cm *CompanionManager) StartCompanions(alias string, configs []core.Compani
 
 		proc, readyMsg, err := cm.runCompanion(alias, config)
 		if err != nil {
-			if config.OnFailure == "block" {
+			if config.OnFailure != "continue" {
 				// Stop any companions we already started
-				cm.StopCompanions(alias)
+				cm.StopCompanions(alias, false)
 				sendProgress(CompanionProgress{
 					Name:    config.Name,
 					Message: fmt.Sprintf("Companion '%s' failed: %v", config.Name, err),
@@ -242,8 +314,9 @@ func (cm *CompanionManager) StartCompanions(alias string, configs []core.Compani
 
 // StopCompanions terminates all companions for a tunnel but keeps entries in map
 // This allows attach to work even when tunnel isn't running
-// Persistent companions are not stopped - they keep running across tunnel restarts
-func (cm *CompanionManager) StopCompanions(alias string) {
+// Persistent companions are not stopped - they keep running across tunnel restarts -
+// unless includePersistent is set, as done by a forced disconnect
+func (cm *CompanionManager) StopCompanions(alias string, includePersistent bool) {
 	cm.mu.RLock()
 	companions := cm.companions[alias]
 	cm.mu.RUnlock()
@@ -258,7 +331,7 @@ func (cm *CompanionManager) StopCompanions(alias string) {
 		persistent := proc.Config.Persistent
 		proc.mu.RUnlock()
 
-		if persistent {
+		if persistent && !includePersistent {
 			slog.Debug("Skipping stop for persistent companion",
 				"tunnel", alias,
 				"companion", name)
@@ -284,8 +357,10 @@ func (cm *CompanionManager) ClearCompanionHistory(alias string) {
 	}
 }
 
-// RestartCompanions restarts all companions for a tunnel in-place, preserving attach connections
-func (cm *CompanionManager) RestartCompanions(alias string) error {
+// RestartCompanions restarts the companions for a tunnel in-place, preserving attach connections.
+// Only companions of the given phase are restarted, or all of them when phase is empty.
+// The optional onProgress callback is called for each progress message, like in StartCompanions.
+func (cm *CompanionManager) RestartCompanions(alias, phase string, onProgress ProgressCallback) error {
 	cm.mu.RLock()
 	companions := cm.companions[alias]
 	cm.mu.RUnlock()
@@ -294,12 +369,35 @@ func (cm *CompanionManager) RestartCompanions(alias string) error {
 		return nil
 	}
 
+	// Helper to send progress
+	sendProgress := func(p CompanionProgress) {
+		if onProgress != nil {
+			onProgress(p)
+		}
+	}
+
 	for name, proc := range companions {
+		proc.mu.RLock()
+		procPhase := companionPhase(proc.Config)
+		proc.mu.RUnlock()
+		if phase != "" && procPhase != phase {
+			continue
+		}
+
 		proc.output.Broadcast(formatDaemonMessage("Restarting companion '%s'...\n", name))
+		sendProgress(CompanionProgress{
+			Name:    name,
+			Message: fmt.Sprintf("Restarting companion '%s'...", name),
+		})
 
 		// Restart in place (preserves broadcaster)
-		if err := cm.restartCompanionInPlace(proc); err != nil {
+		if err := cm.restart(proc); err != nil {
 			proc.output.Broadcast(formatDaemonMessage("Failed to restart: %v\n", err))
+			sendProgress(CompanionProgress{
+				Name:    name,
+				Message: fmt.Sprintf("Companion '%s' failed to restart: %v", name, err),
+				IsError: true,
+			})
 			continue
 		}
 
@@ -319,6 +417,11 @@ func (cm *CompanionManager) RestartCompanions(alias string) error {
 			proc.ExitError = waitErr.Error()
 			proc.mu.Unlock()
 			proc.output.Broadcast(formatDaemonMessage("Failed to become ready: %v\n", waitErr))
+			sendProgress(CompanionProgress{
+				Name:    name,
+				Message: fmt.Sprintf("Companion '%s' failed to become ready: %v", name, waitErr),
+				IsError: true,
+			})
 			continue
 		}
 
@@ -336,10 +439,76 @@ func (cm *CompanionManager) RestartCompanions(alias string) error {
 		}
 
 		proc.output.Broadcast(formatDaemonMessage("Companion '%s' ready.\n", name))
+		sendProgress(CompanionProgress{
+			Name:    name,
+			Message: fmt.Sprintf("Companion '%s' ready", name),
+		})
 	}
 	return nil
 }
 
+// RestartChangedCompanions compares each known companion with its definition in
+// tunnels (keyed by tunnel alias and companion name) and adopts the new config.
+// Active companions whose config changed are restarted in place; unchanged ones
+// keep running. Returns the "alias/name" keys of the restarted companions.
+//
+// Runs are serialized: a reload that lands while an earlier one is still
+// waiting for restarted companions to become ready waits its turn, so a
+// companion is never restarted by two reloads at once and ends up with the
+// config of the latest one.
+func (cm *CompanionManager) RestartChangedCompanions(tunnels map[string]*core.TunnelConfig) []string {
+	cm.reloadMu.Lock()
+	defer cm.reloadMu.Unlock()
+
+	var changed []*CompanionProcess
+
+	cm.mu.RLock()
+	for alias, companions := range cm.companions {
+		tunnelConfig := tunnels[alias]
+		if tunnelConfig == nil {
+			continue
+		}
+		for _, newConfig := range tunnelConfig.Companions {
+			proc := companions[newConfig.Name]
+			if proc == nil {
+				continue
+			}
+
+			proc.mu.Lock()
+			if reflect.DeepEqual(proc.Config, newConfig) {
+				proc.mu.Unlock()
+				continue
+			}
+			proc.Config = newConfig
+			active := proc.State == CompanionStateRunning || proc.State == CompanionStateReady || proc.State == CompanionStateWaiting
+			proc.mu.Unlock()
+
+			if active {
+				changed = append(changed, proc)
+			}
+		}
+	}
+	cm.mu.RUnlock()
+
+	var restarted []string
+	for _, proc := range changed {
+		slog.Info("Companion configuration changed, restarting",
+			"tunnel", proc.TunnelAlias,
+			"companion", proc.Name)
+		proc.output.Broadcast(formatDaemonMessage("Configuration changed, restarting companion '%s'...\n", proc.Name))
+
+		if err := cm.restart(proc); err != nil {
+			slog.Warn("Failed to restart companion after config change",
+				"tunnel", proc.TunnelAlias,
+				"companion", proc.Name,
+				"error", err)
+			continue
+		}
+		restarted = append(restarted, proc.TunnelAlias+"/"+proc.Name)
+	}
+	return restarted
+}
+
 // StopAllCompanions terminates all running companions
 func (cm *CompanionManager) StopAllCompanions() {
 	cm.mu.Lock()
@@ -354,6 +523,46 @@ func (cm *CompanionManager) StopAllCompanions() {
 	}
 }
 
+// companionStopSignal maps a companion's stop_signal to the signal it is
+// stopped with, SIGINT when it isn't set or recognised
+func companionStopSignal(stopSignal string) syscall.Signal {
+	switch stopSignal {
+	case "TERM", "SIGTERM":
+		return syscall.SIGTERM
+	case "HUP", "SIGHUP":
+		return syscall.SIGHUP
+	case "KILL", "SIGKILL":
+		return syscall.SIGKILL
+	}
+	if number, err := strconv.Atoi(stopSignal); err == nil && number > 0 {
+		return syscall.Signal(number)
+	}
+	return syscall.SIGINT
+}
+
+// companionStopSignalEnv tells the wrapper to forward KILL or a numeric
+// stop_signal to the companion. INT, TERM and HUP are always turned into
+// terminal input for it.
+func companionStopSignalEnv(config core.CompanionConfig) []string {
+	switch sig := companionStopSignal(config.StopSignal); sig {
+	case syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP:
+		return nil
+	default:
+		return []string{fmt.Sprintf("OVERSEER_COMPANION_STOP_SIGNAL=%d", int(sig))}
+	}
+}
+
+// CompanionStopTrigger is the signal a companion's wrapper is sent to stop
+// the companion with sig. The companion runs in a session of its own, so the
+// wrapper passes sig on to it. KILL and STOP can't be caught by the wrapper,
+// which is asked for them with SIGUSR1 instead.
+func CompanionStopTrigger(sig syscall.Signal) syscall.Signal {
+	if sig == syscall.SIGKILL || sig == syscall.SIGSTOP {
+		return syscall.SIGUSR1
+	}
+	return sig
+}
+
 // stopProcess gracefully stops a companion process
 func (cm *CompanionManager) stopProcess(proc *CompanionProcess, name, alias string) {
 	if proc == nil {
@@ -375,15 +584,7 @@ func (cm *CompanionManager) stopProcess(proc *CompanionProcess, name, alias stri
 		stopSignal = "INT"
 	}
 
-	var sig syscall.Signal
-	switch stopSignal {
-	case "TERM", "SIGTERM":
-		sig = syscall.SIGTERM
-	case "HUP", "SIGHUP":
-		sig = syscall.SIGHUP
-	default:
-		sig = syscall.SIGINT
-	}
+	sig := CompanionStopTrigger(companionStopSignal(stopSignal))
 
 	slog.Info("Stopping companion", "tunnel", alias, "companion", name, "pid", pid, "signal", stopSignal)
 	cm.logCompanionEvent(alias, name, "companion_stopped", fmt.Sprintf("PID: %d, signal: %s", pid, stopSignal))
@@ -470,6 +671,12 @@ func (cm *CompanionManager) runCompanion(alias string, config core.CompanionConf
 		return nil, "", fmt.Errorf("failed to get executable path: %w", err)
 	}
 
+	userEnv, err := companionEnvironment(config)
+	if err != nil {
+		cancel()
+		return nil, "", err
+	}
+
 	// Generate authentication token
 	token, err := generateCompanionToken()
 	if err != nil {
@@ -499,7 +706,7 @@ func (cm *CompanionManager) runCompanion(alias string, config core.CompanionConf
 	// Resolve working directory (wrapper will inherit it and run child in it)
 	workdir := ""
 	if config.Workdir != "" {
-		workdir = expandPath(config.Workdir)
+		workdir = core.ExpandHomeDir(config.Workdir)
 		if _, err := os.Stat(workdir); os.IsNotExist(err) {
 			listener.Close()
 			os.Remove(socketPath)
@@ -515,7 +722,8 @@ func (cm *CompanionManager) runCompanion(alias string, config core.CompanionConf
 		fmt.Sprintf("OVERSEER_TUNNEL_TOKEN=%s", token),
 		fmt.Sprintf("OVERSEER_COMPANION_NAME=%s", config.Name),
 	)
-	for k, v := range config.Environment {
+	env = append(env, companionStopSignalEnv(config)...)
+	for k, v := range userEnv {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
@@ -524,6 +732,14 @@ func (cm *CompanionManager) runCompanion(alias string, config core.CompanionConf
 		"companion", config.Name,
 		"command", config.Command)
 
+	if err := runPreStart(config, env, workdir); err != nil {
+		listener.Close()
+		os.Remove(socketPath)
+		cancel()
+		cm.logCompanionEvent(alias, config.Name, "companion_failed", err.Error())
+		return nil, "", err
+	}
+
 	// Wrapper is invoked as "overseer daemon" (env vars trigger companion-run
 	// injection in main.go) and spawned via spawnCompanionWrapper — on Darwin
 	// this disclaims responsibility so `op`/TCC dialogs resolve to the wrapper's
@@ -544,6 +760,8 @@ func (cm *CompanionManager) runCompanion(alias string, config core.CompanionConf
 		Pid:          cmd.Process.Pid,
 		StartTime:    time.Now(),
 		State:        CompanionStateStarting,
+		exitReported: make(chan struct{}),
+		token:        token,
 		output:       broadcaster,
 		socketPath:   socketPath,
 		socketListen: listener,
@@ -577,15 +795,7 @@ func (cm *CompanionManager) runCompanion(alias string, config core.CompanionConf
 		return nil, "", waitErr
 	}
 
-	proc.mu.Lock()
-	proc.State = CompanionStateReady
-	proc.mu.Unlock()
-
-	slog.Info("Companion ready",
-		"tunnel", alias,
-		"companion", config.Name,
-		"pid", proc.Pid)
-	cm.logCompanionEvent(alias, config.Name, "companion_ready", fmt.Sprintf("PID: %d", proc.Pid))
+	cm.markReady(proc, "Companion ready")
 
 	// Apply ready_delay if configured (allows time for networking to stabilize)
 	if config.ReadyDelay > 0 {
@@ -617,6 +827,23 @@ func (cm *CompanionManager) runCompanion(alias string, config core.CompanionConf
 	return proc, readyMsg, nil
 }
 
+// markReady moves proc to the ready state and records how long it took to
+// become ready since its process started, to help tune timeout and ready_delay
+func (cm *CompanionManager) markReady(proc *CompanionProcess, message string) {
+	proc.mu.Lock()
+	proc.State = CompanionStateReady
+	pid := proc.Pid
+	readyIn := time.Since(proc.StartTime).Round(time.Millisecond)
+	proc.mu.Unlock()
+
+	slog.Info(message,
+		"tunnel", proc.TunnelAlias,
+		"companion", proc.Name,
+		"pid", pid,
+		"ready_in", readyIn)
+	cm.logCompanionEvent(proc.TunnelAlias, proc.Name, "companion_ready", fmt.Sprintf("PID: %d, ready in %s", pid, readyIn))
+}
+
 // listenForWrapperOutput accepts connections from wrapper and streams to LogBroadcaster
 func (cm *CompanionManager) listenForWrapperOutput(proc *CompanionProcess) {
 	for {
@@ -651,6 +878,18 @@ func (cm *CompanionManager) listenForWrapperOutput(proc *CompanionProcess) {
 func (cm *CompanionManager) handleWrapperConnection(proc *CompanionProcess, conn net.Conn) {
 	defer conn.Close()
 
+	// The connection belongs to the wrapper running when it was made, which
+	// reports the exit code last: once the connection ends there's nothing
+	// more to wait for
+	proc.mu.RLock()
+	token, reported := proc.token, proc.exitReported
+	proc.mu.RUnlock()
+	defer func() {
+		proc.mu.Lock()
+		closeExitReported(reported)
+		proc.mu.Unlock()
+	}()
+
 	reader := bufio.NewReader(conn)
 	inHistoryReplay := false
 
@@ -681,6 +920,15 @@ func (cm *CompanionManager) handleWrapperConnection(proc *CompanionProcess, conn
 			inHistoryReplay = false
 			continue
 		}
+		if code, ok := parseExitMarker(trimmed, token); ok {
+			slog.Debug("Companion wrapper reported exit code",
+				"tunnel", proc.TunnelAlias, "companion", proc.Name, "exit_code", code)
+			proc.mu.Lock()
+			proc.reportedExit = &code
+			closeExitReported(reported)
+			proc.mu.Unlock()
+			continue
+		}
 
 		if inHistoryReplay {
 			// History replay - only add to buffer, don't broadcast to existing subscribers
@@ -692,6 +940,63 @@ func (cm *CompanionManager) handleWrapperConnection(proc *CompanionProcess, conn
 	}
 }
 
+// CompanionExitMarker is the line a companion's wrapper sends once its child
+// has exited. It carries the wrapper's token, which the child doesn't get, so
+// neither the child's output nor another process can fake it.
+func CompanionExitMarker(token string, code int) string {
+	return fmt.Sprintf("EXIT %s %d", token, code)
+}
+
+// parseExitMarker parses the marker made by CompanionExitMarker, accepting it
+// only with the wrapper's token
+func parseExitMarker(line, token string) (int, bool) {
+	rest, ok := strings.CutPrefix(line, "EXIT ")
+	if !ok || token == "" {
+		return 0, false
+	}
+	markerToken, codeText, ok := strings.Cut(rest, " ")
+	if !ok || subtle.ConstantTimeCompare([]byte(markerToken), []byte(token)) != 1 {
+		return 0, false
+	}
+	code, err := strconv.Atoi(codeText)
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// closeExitReported tells monitorCompanion there's no exit code left to wait
+// for. The caller must hold the companion's mu.
+func closeExitReported(reported chan struct{}) {
+	if reported == nil {
+		return
+	}
+	select {
+	case <-reported:
+	default:
+		close(reported)
+	}
+}
+
+// companionExitReportWait is how long monitorCompanion waits for the wrapper
+// to report its child's exit code once the wrapper has exited
+const companionExitReportWait = 2 * time.Second
+
+// awaitExitReport waits until the wrapper, which has exited, has reported its
+// child's exit code or can't anymore
+func (proc *CompanionProcess) awaitExitReport() {
+	proc.mu.RLock()
+	reported := proc.exitReported
+	proc.mu.RUnlock()
+	if reported == nil {
+		return
+	}
+	select {
+	case <-reported:
+	case <-time.After(companionExitReportWait):
+	}
+}
+
 // waitForCompletion waits for the script to exit successfully
 func (cm *CompanionManager) waitForCompletion(proc *CompanionProcess, timeout time.Duration) error {
 	done := make(chan error, 1)
@@ -764,11 +1069,31 @@ func parseOutputTimestamp(line string) (time.Time, bool) {
 	return t, true
 }
 
+// StripOutputPrefix removes the leading "2006-01-02 15:04:05 [tag] " prefix that
+// the wrapper adds to companion output. Lines without the prefix are returned unchanged.
+func StripOutputPrefix(line string) string {
+	if _, ok := parseOutputTimestamp(line); !ok {
+		return line
+	}
+	rest := line[19:]
+	if !strings.HasPrefix(rest, " [") {
+		return line
+	}
+	end := strings.Index(rest, "]")
+	if end < 0 {
+		return line
+	}
+	return strings.TrimPrefix(rest[end+1:], " ")
+}
+
 // monitorCompanion watches a companion process after it's ready
 func (cm *CompanionManager) monitorCompanion(proc *CompanionProcess) {
 	for {
 		err := proc.Cmd.Wait()
 
+		// The exit code sent by the wrapper may still be on its way
+		proc.awaitExitReport()
+
 		proc.mu.Lock()
 		if proc.State == CompanionStateStopped {
 			// We stopped it intentionally
@@ -780,8 +1105,23 @@ func (cm *CompanionManager) monitorCompanion(proc *CompanionProcess) {
 		name := proc.Name
 		autoRestart := proc.Config.AutoRestart
 
+		// The wrapper reports its child's exit code, which waiting on the
+		// wrapper can mask, e.g. when the wrapper itself is killed
+		reported := proc.reportedExit
+		proc.reportedExit = nil
+
 		var exitDetails string
-		if err != nil {
+		failed := true
+		if reported != nil && *reported != 0 {
+			exitCode := *reported
+			proc.ExitCode = &exitCode
+			proc.ExitError = fmt.Sprintf("exit status %d", exitCode)
+			exitDetails = fmt.Sprintf("exit code %d", exitCode)
+			slog.Warn("Companion exited with error",
+				"tunnel", alias,
+				"companion", name,
+				"exit_code", exitCode)
+		} else if err != nil && reported == nil {
 			if exitErr, ok := err.(*exec.ExitError); ok {
 				exitCode := exitErr.ExitCode()
 				proc.ExitCode = &exitCode
@@ -799,11 +1139,21 @@ func (cm *CompanionManager) monitorCompanion(proc *CompanionProcess) {
 			exitCode := 0
 			proc.ExitCode = &exitCode
 			exitDetails = "exit code 0"
+			failed = false
 			slog.Info("Companion exited normally",
 				"tunnel", alias,
 				"companion", name)
 		}
 
+		// Recycle the whole tunnel, which runs its companions anew
+		if failed && proc.Config.OnFailure == "restart_tunnel" && cm.restartTunnel != nil {
+			proc.State = CompanionStateExited
+			proc.mu.Unlock()
+			cm.logCompanionEvent(alias, name, "companion_exited", exitDetails+" (restarting tunnel)")
+			go cm.restartTunnel(alias, name)
+			return
+		}
+
 		if !autoRestart {
 			proc.State = CompanionStateExited
 			proc.mu.Unlock()
@@ -852,6 +1202,12 @@ func (cm *CompanionManager) restartCompanionInPlace(proc *CompanionProcess) erro
 	alias := proc.TunnelAlias
 	config := proc.Config
 
+	// Read the env file first so a broken file leaves the running process alone
+	userEnv, err := companionEnvironment(config)
+	if err != nil {
+		return err
+	}
+
 	// Stop the existing process if it's still running
 	// IMPORTANT: Set state to Stopped BEFORE killing so the old monitoring goroutine exits
 	proc.mu.Lock()
@@ -939,7 +1295,7 @@ func (cm *CompanionManager) restartCompanionInPlace(proc *CompanionProcess) erro
 	// Resolve working directory
 	workdir := ""
 	if config.Workdir != "" {
-		workdir = expandPath(config.Workdir)
+		workdir = core.ExpandHomeDir(config.Workdir)
 	}
 
 	// Build environment
@@ -949,10 +1305,17 @@ func (cm *CompanionManager) restartCompanionInPlace(proc *CompanionProcess) erro
 		fmt.Sprintf("OVERSEER_TUNNEL_TOKEN=%s", token),
 		fmt.Sprintf("OVERSEER_COMPANION_NAME=%s", config.Name),
 	)
-	for k, v := range config.Environment {
+	env = append(env, companionStopSignalEnv(config)...)
+	for k, v := range userEnv {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	if err := runPreStart(config, env, workdir); err != nil {
+		listener.Close()
+		os.Remove(socketPath)
+		return err
+	}
+
 	// Spawn with responsibility disclaimed on Darwin (see runCompanion comment).
 	cmd, err := spawnCompanionWrapper(execPath, []string{execPath, "daemon"}, env, workdir)
 	if err != nil {
@@ -972,6 +1335,9 @@ func (cm *CompanionManager) restartCompanionInPlace(proc *CompanionProcess) erro
 	proc.State = CompanionStateWaiting // Start in waiting state until ready criteria met
 	proc.ExitCode = nil
 	proc.ExitError = ""
+	proc.reportedExit = nil
+	proc.exitReported = make(chan struct{})
+	proc.token = token
 	proc.ctx = ctx
 	proc.cancel = cancel
 	proc.socketPath = socketPath
@@ -1010,15 +1376,7 @@ func (cm *CompanionManager) restartCompanionInPlace(proc *CompanionProcess) erro
 		return waitErr
 	}
 
-	proc.mu.Lock()
-	proc.State = CompanionStateReady
-	proc.mu.Unlock()
-
-	slog.Info("Companion ready after restart",
-		"tunnel", alias,
-		"companion", config.Name,
-		"pid", cmd.Process.Pid)
-	cm.logCompanionEvent(alias, config.Name, "companion_ready", fmt.Sprintf("PID: %d", cmd.Process.Pid))
+	cm.markReady(proc, "Companion ready after restart")
 
 	// Apply ready_delay if configured
 	if config.ReadyDelay > 0 {
@@ -1082,12 +1440,78 @@ type CompanionStatus struct {
 	ExitError string    `json:"exit_error,omitempty"`
 }
 
+// parseCompanionAttachArgs parses the optional arguments after
+// COMPANION_ATTACH <tunnel> <name>: a line count (default 20) or "all" for
+// every retained line, "no_history" for reconnects and "json" to receive
+// each output line as a JSON object
+func parseCompanionAttachArgs(args []string) (showHistory bool, historyLines int, jsonLines bool) {
+	showHistory = true
+	historyLines = 20
+	for _, arg := range args {
+		switch arg {
+		case "all":
+			historyLines = HistoryAll
+		case "no_history":
+			showHistory = false
+		case "json":
+			jsonLines = true
+		default:
+			if n, err := strconv.Atoi(arg); err == nil {
+				historyLines = n
+			}
+		}
+	}
+	return showHistory, historyLines, jsonLines
+}
+
+// companionJSONLine is a companion output line as sent to JSON attach clients
+type companionJSONLine struct {
+	TS     time.Time `json:"ts"`
+	Stream string    `json:"stream"` // output, stdout, stderr or daemon
+	Line   string    `json:"line"`
+}
+
+// formatCompanionJSONLine converts a "2006-01-02 15:04:05 [stream] message"
+// output line into a JSON line. Lines without the wrapper's prefix are
+// reported as output at the current time.
+func formatCompanionJSONLine(line string) string {
+	entry := companionJSONLine{Stream: "output"}
+	ts, ok := parseOutputTimestamp(line)
+	if ok {
+		entry.TS = ts
+		if rest := line[19:]; strings.HasPrefix(rest, " [") {
+			if end := strings.Index(rest, "]"); end > 0 {
+				entry.Stream = strings.ToLower(rest[2:end])
+			}
+		}
+	} else {
+		entry.TS = time.Now()
+	}
+	entry.Line = strings.TrimRight(StripOutputPrefix(line), "\r\n")
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return ""
+	}
+	return string(data) + "\n"
+}
+
 // HandleCompanionAttach streams companion output to client via LogBroadcaster
 // showHistory controls whether to send recent history on attach (false for reconnects)
-// historyLines controls how many lines of history to show (default 20)
-func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, name string, showHistory bool, historyLines int) {
+// historyLines controls how many lines of history to show (default 20, HistoryAll for everything retained)
+// jsonLines sends every line as a JSON object (see formatCompanionJSONLine) instead of plain text
+func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, name string, showHistory bool, historyLines int, jsonLines bool) {
 	defer conn.Close()
 
+	// send writes an output line to the client in the requested format
+	send := func(line string) error {
+		if jsonLines {
+			line = formatCompanionJSONLine(line)
+		}
+		_, err := conn.Write([]byte(line))
+		return err
+	}
+
 	cm.mu.Lock()
 	companions := cm.companions[alias]
 	var proc *CompanionProcess
@@ -1119,8 +1543,7 @@ func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, n
 		tunnelConfig := core.Config.Tunnels[alias]
 		if tunnelConfig == nil {
 			cm.mu.Unlock()
-			errMsg := fmt.Sprintf("Tunnel %q not found in configuration\n", alias)
-			conn.Write([]byte(errMsg))
+			send(fmt.Sprintf("Tunnel %q not found in configuration\n", alias))
 			return
 		}
 
@@ -1135,8 +1558,7 @@ func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, n
 
 		if companionConfig == nil {
 			cm.mu.Unlock()
-			errMsg := fmt.Sprintf("Companion %q not configured for tunnel %q\n", name, alias)
-			conn.Write([]byte(errMsg))
+			send(fmt.Sprintf("Companion %q not configured for tunnel %q\n", name, alias))
 			return
 		}
 
@@ -1169,14 +1591,16 @@ func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, n
 	// Send initial message
 	initialMsg := fmt.Sprintf("Attached to companion %q for tunnel %q (pid: %d). Press Ctrl+C to detach.\n",
 		name, alias, pid)
-	if _, err := conn.Write([]byte(initialMsg)); err != nil {
+	if jsonLines {
+		initialMsg = formatDaemonMessage("%s", initialMsg)
+	}
+	if err := send(initialMsg); err != nil {
 		return
 	}
 
 	// Notify if companion isn't currently running
 	if state != CompanionStateRunning && state != CompanionStateReady {
-		stateMsg := formatDaemonMessage("Companion is not currently running (state: %s)\n", state)
-		conn.Write([]byte(stateMsg))
+		send(formatDaemonMessage("Companion is not currently running (state: %s)\n", state))
 	}
 
 	// Subscribe to output - with history on first connect, without on reconnect
@@ -1186,7 +1610,7 @@ func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, n
 		outputChan, history = proc.output.SubscribeWithHistory(historyLines)
 		// Send history before streaming live output
 		for _, line := range history {
-			if _, err := conn.Write([]byte(line)); err != nil {
+			if err := send(line); err != nil {
 				proc.output.Unsubscribe(outputChan)
 				return
 			}
@@ -1196,7 +1620,9 @@ func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, n
 	}
 	defer proc.output.Unsubscribe(outputChan)
 
-	conn.Write([]byte("\n"))
+	if !jsonLines {
+		conn.Write([]byte("\n"))
+	}
 
 	// Detect when client disconnects
 	done := make(chan bool)
@@ -1212,13 +1638,17 @@ func (cm *CompanionManager) HandleCompanionAttach(conn net.Conn, alias string, n
 		case <-done:
 			return
 		case <-proc.ctx.Done():
-			conn.Write([]byte("\nCompanion process terminated.\n"))
+			if jsonLines {
+				send(formatDaemonMessage("Companion process terminated.\n"))
+			} else {
+				conn.Write([]byte("\nCompanion process terminated.\n"))
+			}
 			return
 		case line, ok := <-outputChan:
 			if !ok {
 				return
 			}
-			if _, err := conn.Write([]byte(line)); err != nil {
+			if err := send(line); err != nil {
 				return
 			}
 		}
@@ -1327,6 +1757,34 @@ func (cm *CompanionManager) GetCompanion(alias, name string) *CompanionProcess {
 	return nil
 }
 
+// GrepCompanionOutput returns the retained output lines of a companion whose
+// text, without the wrapper's timestamp and stream prefix, matches pattern
+func (cm *CompanionManager) GrepCompanionOutput(alias, name, pattern string) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	proc := cm.GetCompanion(alias, name)
+	if proc == nil || proc.output == nil {
+		return nil, fmt.Errorf("companion '%s' not found for tunnel '%s'", name, alias)
+	}
+
+	return proc.output.FilterHistory(func(line string) bool {
+		return re.MatchString(strings.TrimRight(StripOutputPrefix(line), "\r\n"))
+	}), nil
+}
+
+// TailCompanionOutput returns the last n retained output lines of a
+// companion, or all of them for HistoryAll
+func (cm *CompanionManager) TailCompanionOutput(alias, name string, n int) ([]string, error) {
+	proc := cm.GetCompanion(alias, name)
+	if proc == nil || proc.output == nil {
+		return nil, fmt.Errorf("companion '%s' not found for tunnel '%s'", name, alias)
+	}
+	return proc.output.TailHistory(n), nil
+}
+
 // HasCompanions returns true if the tunnel has any companions in the map (running or dormant)
 func (cm *CompanionManager) HasCompanions(alias string) bool {
 	cm.mu.RLock()
@@ -1356,16 +1814,64 @@ func (cm *CompanionManager) HasRunningCompanions(alias string) bool {
 	return false
 }
 
-// expandPath expands ~ to home directory
-func expandPath(path string) string {
-	if strings.HasPrefix(path, "~/") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return path
+// loadEnvFile reads KEY=VALUE pairs from a dotenv file, see state.ReadEnvFile
+func loadEnvFile(path string) (map[string]string, error) {
+	return state.ReadEnvFile(core.ExpandHomeDir(path))
+}
+
+// companionEnvironment returns the user-defined environment for a companion:
+// values from env_file, overridden by the environment block
+func companionEnvironment(config core.CompanionConfig) (map[string]string, error) {
+	if config.EnvFile == "" {
+		return config.Environment, nil
+	}
+
+	env, err := loadEnvFile(config.EnvFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load env_file: %w", err)
+	}
+	for k, v := range config.Environment {
+		env[k] = v
+	}
+	return env, nil
+}
+
+// runPreStart runs the companion's pre_start command to completion in the
+// companion's working directory and environment. It is bounded by the
+// companion's timeout, and a non-zero exit aborts the companion start.
+func runPreStart(config core.CompanionConfig, env []string, workdir string) error {
+	if config.PreStart == "" {
+		return nil
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", config.PreStart)
+	cmd.Env = env
+	cmd.Dir = workdir
+	// Own process group so a timeout also kills anything the command spawned
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("pre_start timed out after %s", timeout)
+	}
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("pre_start failed: %w: %s", err, out)
 		}
-		return home + path[1:]
+		return fmt.Errorf("pre_start failed: %w", err)
 	}
-	return path
+	return nil
 }
 
 // =============================================================================
@@ -1633,6 +2139,59 @@ func (cm *CompanionManager) AdoptCompanions() int {
 	return adoptedCount
 }
 
+// companionSocketPattern matches the socket files created by getCompanionSocketPath
+const companionSocketPattern = "overseer-companion-*.sock"
+
+// CleanupStaleSockets removes companion sockets left behind in the temp
+// directory by daemons that crashed. Must be called after AdoptCompanions so
+// the sockets of adopted companions are kept.
+// Returns the number of sockets removed.
+func (cm *CompanionManager) CleanupStaleSockets() int {
+	return cm.cleanupStaleSockets(os.TempDir())
+}
+
+// cleanupStaleSockets removes companion sockets in dir that don't belong to a
+// known companion and have nothing listening on them
+func (cm *CompanionManager) cleanupStaleSockets(dir string) int {
+	matches, err := filepath.Glob(filepath.Join(dir, companionSocketPattern))
+	if err != nil {
+		return 0
+	}
+
+	known := make(map[string]bool)
+	cm.mu.RLock()
+	for _, companions := range cm.companions {
+		for _, proc := range companions {
+			if proc.socketPath != "" {
+				known[proc.socketPath] = true
+			}
+		}
+	}
+	cm.mu.RUnlock()
+
+	removed := 0
+	for _, socketPath := range matches {
+		if known[socketPath] {
+			continue
+		}
+
+		// Someone is still listening (e.g. another daemon instance), leave it alone
+		if conn, err := net.DialTimeout("unix", socketPath, 500*time.Millisecond); err == nil {
+			conn.Close()
+			continue
+		}
+
+		if err := os.Remove(socketPath); err != nil {
+			slog.Debug("Failed to remove stale companion socket", "path", socketPath, "error", err)
+			continue
+		}
+		slog.Debug("Removed stale companion socket", "path", socketPath)
+		removed++
+	}
+
+	return removed
+}
+
 // monitorAdoptedCompanion monitors an adopted companion process
 func (cm *CompanionManager) monitorAdoptedCompanion(proc *CompanionProcess, osProc *os.Process) {
 	slog.Debug("Started monitoring adopted companion",
@@ -3,9 +3,11 @@ package daemon
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"go.olrik.dev/overseer/internal/core"
 )
@@ -50,7 +52,7 @@ func TestHandleCompanionAttach_StoppedCompanionResetsCtx(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", false, 5)
+		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", false, 5, false)
 	}()
 
 	reader := bufio.NewReader(client)
@@ -114,7 +116,7 @@ func TestHandleCompanionAttach_FailedCompanionResetsCtx(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", false, 5)
+		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", false, 5, false)
 	}()
 
 	reader := bufio.NewReader(client)
@@ -152,7 +154,7 @@ func TestHandleCompanionAttach_CreatesNewDormantEntry(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		cm.HandleCompanionAttach(server, "my-tunnel", "new-comp", false, 5)
+		cm.HandleCompanionAttach(server, "my-tunnel", "new-comp", false, 5, false)
 	}()
 
 	reader := bufio.NewReader(client)
@@ -215,7 +217,7 @@ func TestHandleCompanionAttach_ReadyCompanionStreamsHistory(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", true, 10)
+		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", true, 10, false)
 	}()
 
 	reader := bufio.NewReader(client)
@@ -279,7 +281,7 @@ func TestHandleCompanionAttach_CompanionTerminates(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", false, 5)
+		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", false, 5, false)
 	}()
 
 	reader := bufio.NewReader(client)
@@ -316,3 +318,77 @@ func TestHandleCompanionAttach_CompanionTerminates(t *testing.T) {
 
 	<-done
 }
+
+func TestHandleCompanionAttach_JSONLines(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	cm := NewCompanionManager()
+	broadcaster := NewLogBroadcaster(100)
+	broadcaster.Broadcast("2024-03-15 10:30:45 [output] hello \"world\"\n")
+	broadcaster.Broadcast("2024-03-15 10:30:46 [stderr] warning: disk full\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cm.companions["my-tunnel"] = map[string]*CompanionProcess{
+		"my-comp": {
+			Name:        "my-comp",
+			TunnelAlias: "my-tunnel",
+			Pid:         12345,
+			State:       CompanionStateReady,
+			output:      broadcaster,
+			ctx:         ctx,
+			cancel:      cancel,
+		},
+	}
+
+	client, server := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cm.HandleCompanionAttach(server, "my-tunnel", "my-comp", true, 10, true)
+	}()
+
+	reader := bufio.NewReader(client)
+	readEntry := func() companionJSONLine {
+		t.Helper()
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		var entry companionJSONLine
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected a JSON line, got %q: %v", line, err)
+		}
+		return entry
+	}
+
+	if entry := readEntry(); entry.Stream != "daemon" || !strings.Contains(entry.Line, "Attached to companion") {
+		t.Errorf("expected attach message from the daemon, got %+v", entry)
+	}
+
+	entry := readEntry()
+	wantTS := time.Date(2024, 3, 15, 10, 30, 45, 0, time.Local)
+	if !entry.TS.Equal(wantTS) || entry.Stream != "output" || entry.Line != `hello "world"` {
+		t.Errorf("unexpected history entry: %+v", entry)
+	}
+
+	if entry := readEntry(); entry.Stream != "stderr" || entry.Line != "warning: disk full" {
+		t.Errorf("unexpected history entry: %+v", entry)
+	}
+
+	// Live output is converted as well
+	broadcaster.Broadcast("2024-03-15 10:30:47 [output] live\n")
+	if entry := readEntry(); entry.Stream != "output" || entry.Line != "live" {
+		t.Errorf("unexpected live entry: %+v", entry)
+	}
+
+	client.Close()
+	<-done
+}
@@ -2,9 +2,14 @@ package daemon
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"syscall"
 	"testing"
@@ -78,6 +83,63 @@ func TestParseOutputTimestamp_TimeFields(t *testing.T) {
 	}
 }
 
+func TestStripOutputPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "output tag",
+			line: "2024-03-15 10:30:45 [output] some message\n",
+			want: "some message\n",
+		},
+		{
+			name: "stderr tag",
+			line: "2024-03-15 10:30:45 [stderr] warning: disk full\n",
+			want: "warning: disk full\n",
+		},
+		{
+			name: "daemon tag",
+			line: "2024-03-15 10:30:45 [DAEMON] Companion 'vpn' ready.\n",
+			want: "Companion 'vpn' ready.\n",
+		},
+		{
+			name: "preserves indentation of message",
+			line: "2024-03-15 10:30:45 [output]   indented\n",
+			want: "  indented\n",
+		},
+		{
+			name: "empty message",
+			line: "2024-03-15 10:30:45 [output]\n",
+			want: "\n",
+		},
+		{
+			name: "no timestamp",
+			line: "Attached to companion \"vpn\"\n",
+			want: "Attached to companion \"vpn\"\n",
+		},
+		{
+			name: "timestamp without tag",
+			line: "2024-03-15 10:30:45 plain text\n",
+			want: "2024-03-15 10:30:45 plain text\n",
+		},
+		{
+			name: "unterminated tag",
+			line: "2024-03-15 10:30:45 [output\n",
+			want: "2024-03-15 10:30:45 [output\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripOutputPrefix(tt.line); got != tt.want {
+				t.Errorf("StripOutputPrefix(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetCompanionSocketPath(t *testing.T) {
 	path := getCompanionSocketPath("myalias", "myname")
 
@@ -102,7 +164,7 @@ func TestGetCompanionSocketPath(t *testing.T) {
 func TestStopCompanions(t *testing.T) {
 	t.Run("nil alias does not panic", func(t *testing.T) {
 		cm := NewCompanionManager()
-		cm.StopCompanions("nonexistent")
+		cm.StopCompanions("nonexistent", false)
 	})
 
 	t.Run("skips persistent companions", func(t *testing.T) {
@@ -117,12 +179,34 @@ func TestStopCompanions(t *testing.T) {
 			},
 		}
 
-		cm.StopCompanions("server1")
+		cm.StopCompanions("server1", false)
 
 		// Persistent companion should still be in the map
 		if cm.companions["server1"]["persistent-comp"] == nil {
 			t.Error("expected persistent companion to remain in map")
 		}
+		if state := cm.companions["server1"]["persistent-comp"].State; state != CompanionStateRunning {
+			t.Errorf("expected persistent companion to keep running, got state %q", state)
+		}
+	})
+
+	t.Run("stops persistent companions when forced", func(t *testing.T) {
+		quietLogger(t)
+		cm := NewCompanionManager()
+
+		cm.companions["server1"] = map[string]*CompanionProcess{
+			"persistent-comp": {
+				Name:   "persistent-comp",
+				State:  CompanionStateRunning,
+				Config: core.CompanionConfig{Persistent: true},
+			},
+		}
+
+		cm.StopCompanions("server1", true)
+
+		if state := cm.companions["server1"]["persistent-comp"].State; state != CompanionStateStopped {
+			t.Errorf("expected persistent companion to be stopped, got state %q", state)
+		}
 	})
 }
 
@@ -318,6 +402,129 @@ func TestStopProcess_SignalVariants(t *testing.T) {
 	}
 }
 
+func TestCompanionStopSignal(t *testing.T) {
+	tests := []struct {
+		stopSignal string
+		want       syscall.Signal
+	}{
+		{"", syscall.SIGINT},
+		{"INT", syscall.SIGINT},
+		{"TERM", syscall.SIGTERM},
+		{"SIGHUP", syscall.SIGHUP},
+		{"KILL", syscall.SIGKILL},
+		{"SIGKILL", syscall.SIGKILL},
+		{"9", syscall.SIGKILL},
+		{"34", syscall.Signal(34)},
+		{"BOGUS", syscall.SIGINT},
+	}
+	for _, tt := range tests {
+		if got := companionStopSignal(tt.stopSignal); got != tt.want {
+			t.Errorf("companionStopSignal(%q) = %v, want %v", tt.stopSignal, got, tt.want)
+		}
+	}
+
+	if env := companionStopSignalEnv(core.CompanionConfig{StopSignal: "TERM"}); env != nil {
+		t.Errorf("expected no forwarding for TERM, got %v", env)
+	}
+	if env := companionStopSignalEnv(core.CompanionConfig{StopSignal: "34"}); !reflect.DeepEqual(env, []string{"OVERSEER_COMPANION_STOP_SIGNAL=34"}) {
+		t.Errorf("expected forwarding of signal 34, got %v", env)
+	}
+	if env := companionStopSignalEnv(core.CompanionConfig{StopSignal: "KILL"}); !reflect.DeepEqual(env, []string{"OVERSEER_COMPANION_STOP_SIGNAL=9"}) {
+		t.Errorf("expected forwarding of KILL, got %v", env)
+	}
+
+	for sig, want := range map[syscall.Signal]syscall.Signal{
+		syscall.SIGKILL: syscall.SIGUSR1,
+		syscall.SIGSTOP: syscall.SIGUSR1,
+		syscall.SIGUSR2: syscall.SIGUSR2,
+		syscall.SIGINT:  syscall.SIGINT,
+	} {
+		if got := CompanionStopTrigger(sig); got != want {
+			t.Errorf("CompanionStopTrigger(%v) = %v, want %v", sig, got, want)
+		}
+	}
+}
+
+// startTrappingProcess starts a shell script in its own session and waits
+// until it prints that its traps are set
+func startTrappingProcess(t *testing.T, cmd *exec.Cmd) {
+	t.Helper()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to create stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	t.Cleanup(func() { syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) })
+
+	line := make([]byte, len("ready\n"))
+	if _, err := io.ReadFull(stdout, line); err != nil {
+		t.Fatalf("process didn't become ready: %v", err)
+	}
+}
+
+func TestStopProcess_SendsConfiguredSignal(t *testing.T) {
+	quietLogger(t)
+
+	t.Run("numeric", func(t *testing.T) {
+		cm := NewCompanionManager()
+		marker := filepath.Join(t.TempDir(), "signal")
+
+		// Only exits on SIGUSR1, configured by its number
+		cmd := exec.Command("sh", "-c", fmt.Sprintf(`trap 'echo USR1 > %s; exit 0' USR1; trap '' INT; echo ready; while :; do sleep 0.1; done`, marker))
+		startTrappingProcess(t, cmd)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		proc := &CompanionProcess{
+			Name:   "test-comp",
+			Cmd:    cmd,
+			Pid:    cmd.Process.Pid,
+			State:  CompanionStateRunning,
+			Config: core.CompanionConfig{StopSignal: strconv.Itoa(int(syscall.SIGUSR1))},
+			ctx:    ctx,
+			cancel: cancel,
+		}
+		cm.stopProcess(proc, "test-comp", "test-alias")
+
+		if got, err := os.ReadFile(marker); err != nil || strings.TrimSpace(string(got)) != "USR1" {
+			t.Errorf("expected the process to receive SIGUSR1, marker = %q, %v", got, err)
+		}
+	})
+
+	t.Run("KILL", func(t *testing.T) {
+		cm := NewCompanionManager()
+		marker := filepath.Join(t.TempDir(), "signal")
+
+		// Stands in for the wrapper, which can't catch SIGKILL and is asked
+		// to pass it on with SIGUSR1
+		cmd := exec.Command("sh", "-c", fmt.Sprintf(`trap 'echo USR1 > %s; exit 0' USR1; trap '' INT TERM HUP; echo ready; while :; do sleep 0.1; done`, marker))
+		startTrappingProcess(t, cmd)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		proc := &CompanionProcess{
+			Name:   "test-comp",
+			Cmd:    cmd,
+			Pid:    cmd.Process.Pid,
+			State:  CompanionStateRunning,
+			Config: core.CompanionConfig{StopSignal: "KILL"},
+			ctx:    ctx,
+			cancel: cancel,
+		}
+
+		// Without the grace period a forced kill takes
+		start := time.Now()
+		cm.stopProcess(proc, "test-comp", "test-alias")
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("stopProcess took %v, expected the wrapper to be asked right away", elapsed)
+		}
+		if got, err := os.ReadFile(marker); err != nil || strings.TrimSpace(string(got)) != "USR1" {
+			t.Errorf("expected the wrapper to receive SIGUSR1, marker = %q, %v", got, err)
+		}
+	})
+}
+
 func TestHandleCompanionAttach_NoSuchCompanion(t *testing.T) {
 	quietLogger(t)
 
@@ -333,7 +540,7 @@ func TestHandleCompanionAttach_NoSuchCompanion(t *testing.T) {
 	client, server := net.Pipe()
 	defer client.Close()
 
-	go cm.HandleCompanionAttach(server, "nonexistent", "comp1", false, 10)
+	go cm.HandleCompanionAttach(server, "nonexistent", "comp1", false, 10, false)
 
 	// Read the error message
 	buf := make([]byte, 1024)
@@ -363,7 +570,7 @@ func TestHandleCompanionAttach_CompanionNotConfigured(t *testing.T) {
 	client, server := net.Pipe()
 	defer client.Close()
 
-	go cm.HandleCompanionAttach(server, "my-tunnel", "nonexistent-comp", false, 10)
+	go cm.HandleCompanionAttach(server, "my-tunnel", "nonexistent-comp", false, 10, false)
 
 	buf := make([]byte, 1024)
 	n, _ := client.Read(buf)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -17,7 +18,7 @@ func TestRestartCompanions_NilCompanions(t *testing.T) {
 
 	cm := NewCompanionManager()
 
-	err := cm.RestartCompanions("nonexistent-tunnel")
+	err := cm.RestartCompanions("nonexistent-tunnel", "", nil)
 	if err != nil {
 		t.Errorf("expected nil error for nil companions, got: %v", err)
 	}
@@ -29,7 +30,7 @@ func TestRestartCompanions_EmptyCompanions(t *testing.T) {
 	cm := NewCompanionManager()
 	cm.companions["my-tunnel"] = make(map[string]*CompanionProcess)
 
-	err := cm.RestartCompanions("my-tunnel")
+	err := cm.RestartCompanions("my-tunnel", "", nil)
 	if err != nil {
 		t.Errorf("expected nil error for empty companions, got: %v", err)
 	}
@@ -184,3 +185,156 @@ func TestStopSingleCompanion_WithRunningProcess(t *testing.T) {
 		t.Error("expected companion to be removed from map after stop")
 	}
 }
+
+func TestRestartChangedCompanions(t *testing.T) {
+	quietLogger(t)
+
+	cm := NewCompanionManager()
+	var restarted []string
+	cm.restart = func(proc *CompanionProcess) error {
+		restarted = append(restarted, proc.TunnelAlias+"/"+proc.Name+":"+proc.Config.Command)
+		return nil
+	}
+
+	running := func(alias string, config core.CompanionConfig) *CompanionProcess {
+		return &CompanionProcess{
+			TunnelAlias: alias,
+			Name:        config.Name,
+			Config:      config,
+			State:       CompanionStateRunning,
+			output:      NewLogBroadcaster(10),
+		}
+	}
+
+	vpnAuth := core.CompanionConfig{Name: "auth", Command: "vpn-auth", Environment: map[string]string{}}
+	vpnProxy := core.CompanionConfig{Name: "proxy", Command: "proxy --port 8080", Environment: map[string]string{}}
+	dbTunnel := core.CompanionConfig{Name: "setup", Command: "db-setup", Environment: map[string]string{}}
+
+	cm.companions["vpn"] = map[string]*CompanionProcess{
+		"auth":  running("vpn", vpnAuth),
+		"proxy": running("vpn", vpnProxy),
+	}
+	cm.companions["db"] = map[string]*CompanionProcess{
+		"setup": running("db", dbTunnel),
+	}
+
+	changedAuth := vpnAuth
+	changedAuth.Command = "vpn-auth --mfa"
+	tunnels := map[string]*core.TunnelConfig{
+		"vpn": {Name: "vpn", Companions: []core.CompanionConfig{changedAuth, vpnProxy}},
+		"db":  {Name: "db", Companions: []core.CompanionConfig{dbTunnel}},
+	}
+
+	got := cm.RestartChangedCompanions(tunnels)
+
+	if len(got) != 1 || got[0] != "vpn/auth" {
+		t.Fatalf("expected only vpn/auth to be restarted, got %v", got)
+	}
+	// The restart must see the new config
+	if len(restarted) != 1 || restarted[0] != "vpn/auth:vpn-auth --mfa" {
+		t.Errorf("expected restart with new command, got %v", restarted)
+	}
+
+	// Nothing left to restart once the new config is in place
+	if got := cm.RestartChangedCompanions(tunnels); len(got) != 0 {
+		t.Errorf("expected no restarts for unchanged config, got %v", got)
+	}
+}
+
+func TestRestartChangedCompanions_Serialized(t *testing.T) {
+	quietLogger(t)
+
+	cm := NewCompanionManager()
+	var (
+		mu        sync.Mutex
+		active    int
+		overlap   bool
+		restarted []string
+	)
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	cm.restart = func(proc *CompanionProcess) error {
+		mu.Lock()
+		active++
+		if active > 1 {
+			overlap = true
+		}
+		restarted = append(restarted, proc.Config.Command)
+		mu.Unlock()
+
+		started <- struct{}{}
+		<-release
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	}
+
+	cm.companions["vpn"] = map[string]*CompanionProcess{
+		"auth": {
+			TunnelAlias: "vpn",
+			Name:        "auth",
+			Config:      core.CompanionConfig{Name: "auth", Command: "vpn-auth"},
+			State:       CompanionStateRunning,
+			output:      NewLogBroadcaster(10),
+		},
+	}
+	reload := func(command string) map[string]*core.TunnelConfig {
+		return map[string]*core.TunnelConfig{
+			"vpn": {Name: "vpn", Companions: []core.CompanionConfig{{Name: "auth", Command: command}}},
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() { cm.RestartChangedCompanions(reload("vpn-auth --mfa")) })
+	<-started
+
+	// A second reload while the first restart is still waiting for readiness
+	wg.Go(func() { cm.RestartChangedCompanions(reload("vpn-auth --mfa --verbose")) })
+	select {
+	case <-started:
+		t.Fatal("expected the second reload to wait for the first one's restart")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+
+	if overlap {
+		t.Error("expected restarts of the same companion not to overlap")
+	}
+	if len(restarted) != 2 || restarted[1] != "vpn-auth --mfa --verbose" {
+		t.Errorf("expected the latest config to be restarted last, got %v", restarted)
+	}
+}
+
+func TestRestartChangedCompanions_StoppedCompanionNotStarted(t *testing.T) {
+	quietLogger(t)
+
+	cm := NewCompanionManager()
+	cm.restart = func(proc *CompanionProcess) error {
+		t.Errorf("unexpected restart of stopped companion %q", proc.Name)
+		return nil
+	}
+
+	cm.companions["vpn"] = map[string]*CompanionProcess{
+		"auth": {
+			TunnelAlias: "vpn",
+			Name:        "auth",
+			Config:      core.CompanionConfig{Name: "auth", Command: "vpn-auth"},
+			State:       CompanionStateStopped,
+			output:      NewLogBroadcaster(10),
+		},
+	}
+
+	tunnels := map[string]*core.TunnelConfig{
+		"vpn": {Name: "vpn", Companions: []core.CompanionConfig{{Name: "auth", Command: "vpn-auth --mfa"}}},
+	}
+	cm.RestartChangedCompanions(tunnels)
+
+	// The new config is still adopted for the next start
+	if cmd := cm.companions["vpn"]["auth"].Config.Command; cmd != "vpn-auth --mfa" {
+		t.Errorf("expected stopped companion to adopt new command, got %q", cmd)
+	}
+}
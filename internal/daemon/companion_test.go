@@ -3,6 +3,7 @@ package daemon
 import (
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -75,6 +76,46 @@ func TestCompanionManager_SetEventLogger(t *testing.T) {
 	}
 }
 
+func TestCompanionManager_MarkReadyLogsDuration(t *testing.T) {
+	quietLogger(t)
+
+	cm := NewCompanionManager()
+
+	var eventType, details string
+	cm.SetEventLogger(func(alias, event, d string) error {
+		eventType, details = event, d
+		return nil
+	})
+
+	proc := &CompanionProcess{
+		TunnelAlias: "server1",
+		Name:        "comp1",
+		Pid:         4242,
+		StartTime:   time.Now().Add(-1500 * time.Millisecond),
+		State:       CompanionStateWaiting,
+	}
+	cm.markReady(proc, "Companion ready")
+
+	if proc.State != CompanionStateReady {
+		t.Errorf("expected state ready, got %q", proc.State)
+	}
+	if eventType != "companion_ready" {
+		t.Fatalf("expected companion_ready event, got %q", eventType)
+	}
+
+	prefix := "[comp1] PID: 4242, ready in "
+	if !strings.HasPrefix(details, prefix) {
+		t.Fatalf("expected details to start with %q, got %q", prefix, details)
+	}
+	readyIn, err := time.ParseDuration(strings.TrimPrefix(details, prefix))
+	if err != nil {
+		t.Fatalf("expected a duration in %q: %v", details, err)
+	}
+	if readyIn < 1500*time.Millisecond || readyIn > 10*time.Second {
+		t.Errorf("expected ready duration of about 1.5s, got %s", readyIn)
+	}
+}
+
 func TestCompanionManager_GetCompanionStatus_Empty(t *testing.T) {
 	cm := NewCompanionManager()
 
@@ -296,28 +337,87 @@ func TestCompanionManager_GetCompanion(t *testing.T) {
 	}
 }
 
-func TestExpandPath(t *testing.T) {
-	t.Run("tilde prefix", func(t *testing.T) {
-		result := expandPath("~/test")
-		if strings.HasPrefix(result, "~/") {
-			t.Error("expected ~ to be expanded")
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vpn.env")
+	content := `# VPN settings
+VPN_USER=alice
+export VPN_SERVER = vpn.example.com
+VPN_PASSWORD="s3cret=1"
+VPN_GROUP='staff'
+
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := loadEnvFile(path)
+	if err != nil {
+		t.Fatalf("loadEnvFile failed: %v", err)
+	}
+
+	want := map[string]string{
+		"VPN_USER":     "alice",
+		"VPN_SERVER":   "vpn.example.com",
+		"VPN_PASSWORD": "s3cret=1",
+		"VPN_GROUP":    "staff",
+	}
+	if len(env) != len(want) {
+		t.Errorf("expected %d variables, got %d: %v", len(want), len(env), env)
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, env[k])
+		}
+	}
+}
+
+func TestLoadEnvFile_InvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.env")
+	if err := os.WriteFile(path, []byte("OK=1\nnot a variable\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := loadEnvFile(path)
+	if err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("expected error pointing at line 2, got %v", err)
+	}
+}
+
+func TestCompanionEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vpn.env")
+	if err := os.WriteFile(path, []byte("VPN_USER=from-file\nVPN_SERVER=vpn.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("environment overrides env_file", func(t *testing.T) {
+		env, err := companionEnvironment(core.CompanionConfig{
+			EnvFile:     path,
+			Environment: map[string]string{"VPN_USER": "from-config"},
+		})
+		if err != nil {
+			t.Fatalf("companionEnvironment failed: %v", err)
+		}
+		if env["VPN_USER"] != "from-config" {
+			t.Errorf("expected config value to win, got VPN_USER=%q", env["VPN_USER"])
 		}
-		if !strings.HasSuffix(result, "/test") {
-			t.Errorf("expected path to end with /test, got %q", result)
+		if env["VPN_SERVER"] != "vpn.example.com" {
+			t.Errorf("expected file value to be kept, got VPN_SERVER=%q", env["VPN_SERVER"])
 		}
 	})
 
-	t.Run("no tilde", func(t *testing.T) {
-		result := expandPath("/absolute/path")
-		if result != "/absolute/path" {
-			t.Errorf("expected '/absolute/path', got %q", result)
+	t.Run("missing env_file", func(t *testing.T) {
+		_, err := companionEnvironment(core.CompanionConfig{
+			EnvFile: filepath.Join(t.TempDir(), "missing.env"),
+		})
+		if err == nil || !strings.Contains(err.Error(), "env_file") {
+			t.Errorf("expected env_file error, got %v", err)
 		}
 	})
 
-	t.Run("relative path", func(t *testing.T) {
-		result := expandPath("relative/path")
-		if result != "relative/path" {
-			t.Errorf("expected 'relative/path', got %q", result)
+	t.Run("without env_file", func(t *testing.T) {
+		env, err := companionEnvironment(core.CompanionConfig{Environment: map[string]string{"A": "1"}})
+		if err != nil || env["A"] != "1" {
+			t.Errorf("expected config environment unchanged, got %v, %v", env, err)
 		}
 	})
 }
@@ -463,3 +563,32 @@ func TestRemoveCompanionStateFile(t *testing.T) {
 		}
 	})
 }
+
+func TestParseCompanionAttachArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantHistory bool
+		wantLines   int
+		wantJSON    bool
+	}{
+		{"defaults", nil, true, 20, false},
+		{"line count", []string{"50"}, true, 50, false},
+		{"all", []string{"all"}, true, HistoryAll, false},
+		{"reconnect", []string{"0", "no_history"}, false, 0, false},
+		{"no_history only", []string{"no_history"}, false, 20, false},
+		{"garbage keeps default", []string{"many"}, true, 20, false},
+		{"json", []string{"50", "json"}, true, 50, true},
+		{"json reconnect", []string{"0", "no_history", "json"}, false, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			showHistory, lines, jsonLines := parseCompanionAttachArgs(tt.args)
+			if showHistory != tt.wantHistory || lines != tt.wantLines || jsonLines != tt.wantJSON {
+				t.Errorf("parseCompanionAttachArgs(%q) = (%v, %d, %v), want (%v, %d, %v)",
+					tt.args, showHistory, lines, jsonLines, tt.wantHistory, tt.wantLines, tt.wantJSON)
+			}
+		})
+	}
+}
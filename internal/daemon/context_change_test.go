@@ -1,8 +1,11 @@
 package daemon
 
 import (
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"go.olrik.dev/overseer/internal/awareness/state"
 	"go.olrik.dev/overseer/internal/core"
@@ -25,6 +28,19 @@ func TestLogContextChange_BothChanged(t *testing.T) {
 	if err != nil {
 		t.Errorf("LogContextChange failed: %v", err)
 	}
+
+	changes, err := database.GetContextChanges(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GetContextChanges failed: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 context change row, got %d", len(changes))
+	}
+	c := changes[0]
+	if c.FromContext != "trusted" || c.ToContext != "untrusted" ||
+		c.FromLocation != "home" || c.ToLocation != "office" || c.Trigger != "ip_change" {
+		t.Errorf("unexpected context change row: %+v", c)
+	}
 }
 
 func TestLogContextChange_OnlyContextChanged(t *testing.T) {
@@ -159,3 +175,374 @@ func TestHandleNewContextChange_LocationChangeResetsRetriesAllTunnels(t *testing
 		t.Errorf("expected RetryCount reset to 0 for tunnel 2, got %d", tunnel2.RetryCount)
 	}
 }
+
+func TestHandleNewContextChange_ManuallyStoppedNotReconnected(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		Tunnels: map[string]*core.TunnelConfig{
+			"stopped-tunnel": {Name: "stopped-tunnel"},
+		},
+	}
+
+	d := New()
+
+	// The user disconnected the tunnel while in the trusted context
+	d.manuallyStopped["stopped-tunnel"] = "trusted"
+
+	trusted := &state.Rule{
+		Name:    "trusted",
+		Actions: state.RuleActions{Connect: []string{"stopped-tunnel"}},
+	}
+
+	// Dropping offline and coming back re-enters the same context
+	d.handleNewContextChange(
+		state.StateSnapshot{Context: "trusted", Location: "home", Online: true},
+		state.StateSnapshot{Context: "offline", Location: "offline", Online: false},
+		&state.Rule{Name: "offline"},
+	)
+	d.handleNewContextChange(
+		state.StateSnapshot{Context: "offline", Location: "offline", Online: false},
+		state.StateSnapshot{Context: "trusted", Location: "home", Online: true},
+		trusted,
+	)
+
+	d.mu.Lock()
+	_, exists := d.tunnels["stopped-tunnel"]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected manually stopped tunnel not to be auto-connected")
+	}
+	if !d.isManuallyStopped("stopped-tunnel") {
+		t.Error("expected tunnel to stay manually stopped after the online transition")
+	}
+
+	// Entering another context that lists the tunnel doesn't lift the stop either
+	d.handleNewContextChange(
+		state.StateSnapshot{Context: "trusted", Location: "home", Online: true},
+		state.StateSnapshot{Context: "untrusted", Location: "unknown", Online: true},
+		&state.Rule{
+			Name:    "untrusted",
+			Actions: state.RuleActions{Connect: []string{"stopped-tunnel"}},
+		},
+	)
+	d.mu.Lock()
+	_, exists = d.tunnels["stopped-tunnel"]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected manually stopped tunnel not to be auto-connected by another context")
+	}
+	if !d.isManuallyStopped("stopped-tunnel") {
+		t.Error("expected manual stop to stay until an explicit connect")
+	}
+}
+
+func TestHandleNewContextChange_PreserveExistingSkipsDisconnects(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	go cmd.Wait()
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	d := New()
+	d.tunnels["home-lab"] = Tunnel{
+		Hostname: "home-lab",
+		Pid:      cmd.Process.Pid,
+		Cmd:      cmd,
+		State:    StateConnected,
+	}
+
+	from := state.StateSnapshot{Context: "home", Location: "home"}
+	to := state.StateSnapshot{Context: "travel", Location: "unknown"}
+	rule := &state.Rule{
+		Name:             "travel",
+		Actions:          state.RuleActions{Disconnect: []string{"home-lab"}},
+		PreserveExisting: true,
+	}
+
+	d.handleNewContextChange(from, to, rule)
+
+	d.mu.Lock()
+	_, exists := d.tunnels["home-lab"]
+	d.mu.Unlock()
+	if !exists {
+		t.Error("expected tunnel to be kept when the context preserves existing tunnels")
+	}
+
+	// Without the flag the listed tunnel is disconnected
+	rule.PreserveExisting = false
+	d.handleNewContextChange(from, to, rule)
+
+	d.mu.Lock()
+	_, exists = d.tunnels["home-lab"]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected tunnel to be disconnected without preserve_existing")
+	}
+}
+
+func TestHandleNewContextChange_DisconnectPattern(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	d := New()
+	for _, alias := range []string{"corp-db", "corp-git", "home-lab"} {
+		cmd := exec.Command("sleep", "60")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		go cmd.Wait()
+		t.Cleanup(func() { cmd.Process.Kill() })
+
+		d.tunnels[alias] = Tunnel{
+			Hostname: alias,
+			Pid:      cmd.Process.Pid,
+			Cmd:      cmd,
+			State:    StateConnected,
+		}
+	}
+
+	from := state.StateSnapshot{Context: "office", Location: "hq"}
+	to := state.StateSnapshot{Context: "home", Location: "home"}
+	rule := &state.Rule{
+		Name:    "home",
+		Actions: state.RuleActions{Disconnect: []string{"corp-*"}},
+	}
+
+	d.handleNewContextChange(from, to, rule)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, alias := range []string{"corp-db", "corp-git"} {
+		if _, exists := d.tunnels[alias]; exists {
+			t.Errorf("expected %s to be disconnected by corp-*", alias)
+		}
+	}
+	if _, exists := d.tunnels["home-lab"]; !exists {
+		t.Error("expected home-lab to be kept, it doesn't match corp-*")
+	}
+}
+
+func TestExpandTunnelPatterns(t *testing.T) {
+	candidates := []string{"corp-db", "corp-git", "home-lab", "nas"}
+
+	tests := []struct {
+		name          string
+		entries       []string
+		exclude       []string
+		wantAliases   []string
+		wantUnmatched []string
+	}{
+		{"plain names are kept", []string{"nas", "vpn"}, nil, []string{"nas", "vpn"}, nil},
+		{"star", []string{"corp-*"}, nil, []string{"corp-db", "corp-git"}, nil},
+		{"question mark", []string{"corp-??"}, nil, []string{"corp-db"}, nil},
+		{"character class", []string{"[hn]*"}, nil, []string{"home-lab", "nas"}, nil},
+		{"no duplicates", []string{"corp-db", "corp-*"}, nil, []string{"corp-db", "corp-git"}, nil},
+		{"unmatched pattern", []string{"lab-*", "nas"}, nil, []string{"nas"}, []string{"lab-*"}},
+		{"exclude applies to patterns", []string{"*"}, []string{"nas", "corp-db"}, []string{"corp-git", "home-lab"}, nil},
+		{"exclude doesn't apply to names", []string{"nas"}, []string{"nas"}, []string{"nas"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aliases, unmatched := expandTunnelPatterns(tt.entries, candidates, tt.exclude)
+			if !slices.Equal(aliases, tt.wantAliases) {
+				t.Errorf("aliases = %v, want %v", aliases, tt.wantAliases)
+			}
+			if !slices.Equal(unmatched, tt.wantUnmatched) {
+				t.Errorf("unmatched = %v, want %v", unmatched, tt.wantUnmatched)
+			}
+		})
+	}
+}
+
+func TestHandleNewContextChange_DryRun(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	go cmd.Wait()
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	d := New()
+	d.database = database
+	d.tunnels["home-lab"] = Tunnel{
+		Hostname: "home-lab",
+		Pid:      cmd.Process.Pid,
+		Cmd:      cmd,
+		State:    StateConnected,
+	}
+
+	from := state.StateSnapshot{Context: "home", Location: "home", Online: true}
+	to := state.StateSnapshot{Context: "office", Location: "office", Online: true}
+	rule := &state.Rule{
+		Name: "office",
+		Actions: state.RuleActions{
+			Connect:    []string{"office-db"},
+			Disconnect: []string{"home-lab"},
+		},
+		DryRun: true,
+	}
+
+	d.handleNewContextChange(from, to, rule)
+
+	d.mu.Lock()
+	_, homeLab := d.tunnels["home-lab"]
+	_, officeDB := d.tunnels["office-db"]
+	d.mu.Unlock()
+	if !homeLab {
+		t.Error("expected home-lab to be left connected in a dry run")
+	}
+	if officeDB {
+		t.Error("expected office-db not to be started in a dry run")
+	}
+
+	events, err := database.GetRecentTunnelEvents(10)
+	if err != nil {
+		t.Fatalf("GetRecentTunnelEvents failed: %v", err)
+	}
+	got := make(map[string]string)
+	for _, e := range events {
+		got[e.TunnelAlias] = e.EventType
+		if e.Reason != ContextReason("office") {
+			t.Errorf("expected reason %q, got %q", ContextReason("office"), e.Reason)
+		}
+	}
+	if got["home-lab"] != "dry_run_disconnect" || got["office-db"] != "dry_run_connect" || len(events) != 2 {
+		t.Errorf("expected dry run events for both tunnels, got %+v", events)
+	}
+}
+
+func TestReassertContext_ReconnectsDroppedTunnel(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+
+	current := state.StateSnapshot{Context: "office", Location: "office", Online: true}
+	rule := &state.Rule{
+		Name:     "office",
+		Actions:  state.RuleActions{Connect: []string{alias}},
+		Reassert: true,
+	}
+
+	d.handleNewContextChange(state.StateSnapshot{Context: "untrusted"}, current, rule)
+	d.mu.Lock()
+	_, exists := d.tunnels[alias]
+	d.mu.Unlock()
+	if !exists {
+		t.Fatal("expected tunnel to be connected when entering the context")
+	}
+
+	// The tunnel drops out while the context stays active
+	d.stopTunnel(alias, false, ReasonReconnect)
+
+	// Without reassert nothing brings it back
+	rule.Reassert = false
+	d.reassertContext(current, rule)
+	d.mu.Lock()
+	_, exists = d.tunnels[alias]
+	d.mu.Unlock()
+	if exists {
+		t.Fatal("expected tunnel to stay down without reassert")
+	}
+
+	rule.Reassert = true
+	d.reassertContext(current, rule)
+	d.mu.Lock()
+	tunnel, exists := d.tunnels[alias]
+	d.mu.Unlock()
+	if !exists || tunnel.State != StateConnected {
+		t.Fatalf("expected reassert to reconnect the dropped tunnel, got exists=%v state=%q", exists, tunnel.State)
+	}
+	if tunnel.ConnectReason != ContextReason("office") {
+		t.Errorf("expected reason %q, got %q", ContextReason("office"), tunnel.ConnectReason)
+	}
+
+	// Manually stopped tunnels are not reasserted
+	d.stopTunnel(alias, false, ReasonManual)
+	d.reassertContext(current, rule)
+	d.mu.Lock()
+	_, exists = d.tunnels[alias]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected manually stopped tunnel not to be reasserted")
+	}
+}
+
+func TestManualDisconnect_StickyAcrossOfflineOnline(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+
+	home := &state.Rule{
+		Name:    "home",
+		Actions: state.RuleActions{Connect: []string{alias}},
+	}
+	online := state.StateSnapshot{Context: "home", Location: "home", Online: true}
+	offline := state.StateSnapshot{Context: "offline", Location: "offline", Online: false}
+
+	d.handleNewContextChange(state.StateSnapshot{Context: "untrusted"}, online, home)
+	d.mu.Lock()
+	_, exists := d.tunnels[alias]
+	d.mu.Unlock()
+	if !exists {
+		t.Fatal("expected tunnel to be connected when entering home")
+	}
+
+	// The network drops and the user disconnects while offline
+	d.handleNewContextChange(online, offline, &state.Rule{Name: "offline"})
+	d.stopTunnel(alias, false, ReasonManual)
+
+	// Coming back online in the same context doesn't reconnect it
+	d.handleNewContextChange(offline, online, home)
+	d.mu.Lock()
+	_, exists = d.tunnels[alias]
+	d.mu.Unlock()
+	if exists {
+		t.Fatal("expected manually disconnected tunnel to stay down after coming back online")
+	}
+	if !d.isManuallyStopped(alias) {
+		t.Error("expected manual stop to survive the offline to online transition")
+	}
+
+	// An explicit connect lifts the stop
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
+	for _, msg := range resp.Messages {
+		if msg.Status == "ERROR" {
+			t.Fatalf("startTunnel returned error: %s", msg.Message)
+		}
+	}
+	if d.isManuallyStopped(alias) {
+		t.Error("expected explicit connect to lift the manual stop")
+	}
+	d.stopTunnel(alias, false, ReasonShutdown)
+}
@@ -0,0 +1,153 @@
+package daemon
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tunnelEvent is a tunnel event waiting to be written to the database
+type tunnelEvent struct {
+	Alias     string
+	EventType string
+	Details   string
+	Reason    string
+	At        time.Time // When the event happened
+}
+
+// tunnelEventThrottle limits how often events of each type are written per
+// tunnel, so a flapping tunnel records a few representative rows instead of
+// one per flap. An event arriving within the interval of the last write of
+// its type is held back and replaced by any later event of that type, and the
+// latest one is written once the interval has passed. Events of one type
+// never replace another, so a streak of failures followed by a reconnect
+// records both, and each carries how many events were coalesced into it.
+type tunnelEventThrottle struct {
+	write func(tunnelEvent)
+
+	mu     sync.Mutex
+	events map[throttleKey]*throttledEvents
+}
+
+// throttleKey identifies the events that are coalesced with each other
+type throttleKey struct {
+	alias     string
+	eventType string
+}
+
+type throttledEvents struct {
+	lastWrite time.Time
+	pending   *tunnelEvent
+	coalesced int // Events replaced by pending since the last write
+	timer     *time.Timer
+}
+
+func newTunnelEventThrottle(write func(tunnelEvent)) *tunnelEventThrottle {
+	return &tunnelEventThrottle{
+		write:  write,
+		events: make(map[throttleKey]*throttledEvents),
+	}
+}
+
+// Record writes an event right away when the last write of its type for its
+// tunnel is at least interval ago, and holds it back otherwise. An interval of
+// 0 writes every event.
+func (t *tunnelEventThrottle) Record(event tunnelEvent, interval time.Duration) {
+	if interval <= 0 {
+		t.Flush(event.Alias)
+		t.write(event)
+		return
+	}
+
+	t.mu.Lock()
+	key := throttleKey{alias: event.Alias, eventType: event.EventType}
+	tt, exists := t.events[key]
+	if !exists {
+		tt = &throttledEvents{}
+		t.events[key] = tt
+	}
+	if tt.pending == nil && event.At.Sub(tt.lastWrite) >= interval {
+		tt.lastWrite = event.At
+		t.mu.Unlock()
+		t.write(event)
+		return
+	}
+
+	if tt.pending != nil {
+		tt.coalesced++
+	}
+	tt.pending = &event
+	if tt.timer == nil {
+		tt.timer = time.AfterFunc(time.Until(tt.lastWrite.Add(interval)), func() { t.settle(key, tt) })
+	}
+	t.mu.Unlock()
+}
+
+// settle writes a pending event once its interval has passed
+func (t *tunnelEventThrottle) settle(key throttleKey, tt *throttledEvents) {
+	t.mu.Lock()
+	if t.events[key] != tt {
+		t.mu.Unlock()
+		return
+	}
+	event, ok := t.takePending(tt)
+	t.mu.Unlock()
+
+	if ok {
+		t.write(event)
+	}
+}
+
+// takePending removes a pending event, noting the events that were coalesced
+// into it. Must be called with mu held.
+func (t *tunnelEventThrottle) takePending(tt *throttledEvents) (tunnelEvent, bool) {
+	if tt.timer != nil {
+		tt.timer.Stop()
+		tt.timer = nil
+	}
+	if tt.pending == nil {
+		return tunnelEvent{}, false
+	}
+
+	event := *tt.pending
+	if tt.coalesced > 0 {
+		event.Details = strings.TrimSpace(fmt.Sprintf("%s (%d earlier events coalesced)", event.Details, tt.coalesced))
+	}
+	tt.pending = nil
+	tt.coalesced = 0
+	tt.lastWrite = time.Now()
+	return event, true
+}
+
+// Flush writes the pending events of a tunnel immediately, so events that end
+// a flapping streak (e.g. giving up on the tunnel) are recorded after them
+func (t *tunnelEventThrottle) Flush(alias string) {
+	t.flush(func(key throttleKey) bool { return key.alias == alias })
+}
+
+// FlushAll writes all pending events immediately, e.g. on shutdown
+func (t *tunnelEventThrottle) FlushAll() {
+	t.flush(func(throttleKey) bool { return true })
+}
+
+// flush writes the pending events matching match in the order they happened
+func (t *tunnelEventThrottle) flush(match func(throttleKey) bool) {
+	t.mu.Lock()
+	var events []tunnelEvent
+	for key, tt := range t.events {
+		if !match(key) {
+			continue
+		}
+		if event, ok := t.takePending(tt); ok {
+			events = append(events, event)
+		}
+	}
+	t.mu.Unlock()
+
+	slices.SortFunc(events, func(a, b tunnelEvent) int { return a.At.Compare(b.At) })
+	for _, event := range events {
+		t.write(event)
+	}
+}
@@ -0,0 +1,174 @@
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/db"
+)
+
+// recordedEvents collects the events written by a throttle
+type recordedEvents struct {
+	mu     sync.Mutex
+	events []tunnelEvent
+}
+
+func (r *recordedEvents) write(event tunnelEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordedEvents) get() []tunnelEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]tunnelEvent(nil), r.events...)
+}
+
+// flap records alternating disconnect and reconnect events, ending with a reconnect
+func flap(throttle *tunnelEventThrottle, alias string, times int, interval time.Duration) {
+	for i := range times {
+		eventType := "disconnect"
+		if i%2 == 1 {
+			eventType = "reconnect"
+		}
+		throttle.Record(tunnelEvent{Alias: alias, EventType: eventType, At: time.Now()}, interval)
+	}
+}
+
+func TestTunnelEventThrottle_Disabled(t *testing.T) {
+	recorded := &recordedEvents{}
+	throttle := newTunnelEventThrottle(recorded.write)
+
+	flap(throttle, "db", 10, 0)
+
+	if got := len(recorded.get()); got != 10 {
+		t.Errorf("expected every event to be written without an interval, got %d", got)
+	}
+}
+
+func TestTunnelEventThrottle_CoalescesFlaps(t *testing.T) {
+	recorded := &recordedEvents{}
+	throttle := newTunnelEventThrottle(recorded.write)
+	interval := 50 * time.Millisecond
+
+	flap(throttle, "db", 10, interval)
+	flap(throttle, "web", 1, interval)
+
+	// The first event of each type and tunnel is written right away, the
+	// rest held back
+	if got := len(recorded.get()); got != 3 {
+		t.Fatalf("expected 3 events before the interval passed, got %d", got)
+	}
+
+	time.Sleep(3 * interval)
+	events := recorded.get()
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events after the interval, got %d: %+v", len(events), events)
+	}
+
+	settled := map[string]tunnelEvent{}
+	for _, event := range events[3:] {
+		if event.Alias != "db" {
+			t.Errorf("expected only db events to be held back, got %s %s", event.Alias, event.EventType)
+		}
+		settled[event.EventType] = event
+	}
+	for _, eventType := range []string{"disconnect", "reconnect"} {
+		if got := settled[eventType].Details; got != "(3 earlier events coalesced)" {
+			t.Errorf("%s Details = %q, want %q", eventType, got, "(3 earlier events coalesced)")
+		}
+	}
+	if !settled["reconnect"].At.After(settled["disconnect"].At) {
+		t.Error("expected the final state (db reconnect) to happen last")
+	}
+}
+
+func TestTunnelEventThrottle_KeepsEachEventType(t *testing.T) {
+	recorded := &recordedEvents{}
+	throttle := newTunnelEventThrottle(recorded.write)
+
+	// A reconnect right after a streak of failures doesn't replace them
+	throttle.Record(tunnelEvent{Alias: "db", EventType: "reconnect", At: time.Now()}, time.Hour)
+	for range 3 {
+		throttle.Record(tunnelEvent{Alias: "db", EventType: "connection_failure", At: time.Now()}, time.Hour)
+	}
+	throttle.Record(tunnelEvent{Alias: "db", EventType: "reconnect", At: time.Now()}, time.Hour)
+	throttle.Flush("db")
+
+	var types []string
+	for _, event := range recorded.get() {
+		types = append(types, event.EventType)
+	}
+	want := []string{"reconnect", "connection_failure", "connection_failure", "reconnect"}
+	if !slices.Equal(types, want) {
+		t.Errorf("written events = %v, want %v", types, want)
+	}
+}
+
+func TestTunnelEventThrottle_Flush(t *testing.T) {
+	recorded := &recordedEvents{}
+	throttle := newTunnelEventThrottle(recorded.write)
+
+	flap(throttle, "db", 4, time.Hour)
+	throttle.Flush("db")
+
+	events := recorded.get()
+	if len(events) != 4 {
+		t.Fatalf("expected the held back events to be written on flush, got %d events", len(events))
+	}
+	if events[2].EventType != "disconnect" || events[3].EventType != "reconnect" {
+		t.Errorf("expected disconnect and reconnect to be flushed in order, got %s and %s", events[2].EventType, events[3].EventType)
+	}
+
+	// Nothing is left to flush
+	throttle.FlushAll()
+	if got := len(recorded.get()); got != 4 {
+		t.Errorf("expected no more events, got %d", got)
+	}
+}
+
+func TestDaemon_LogTunnelEvent_RapidFlaps(t *testing.T) {
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{EventMinInterval: 50 * time.Millisecond}
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	d := New()
+	d.database = database
+
+	for i := range 5 {
+		d.logTunnelEvent("db", "disconnect", "Exit code 255", "")
+		d.logTunnelEvent("db", "reconnect", fmt.Sprintf("PID: %d", 100+i), ReasonReconnect)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	events, err := database.GetTunnelEvents("db", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GetTunnelEvents failed: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 10 flaps to be logged as 4 rows, got %d: %+v", len(events), events)
+	}
+	if events[0].EventType != "disconnect" || events[1].EventType != "reconnect" {
+		t.Errorf("expected the first flap to be logged as is, got %s and %s", events[0].EventType, events[1].EventType)
+	}
+	last := events[3]
+	if last.EventType != "reconnect" || last.Reason != ReasonReconnect {
+		t.Errorf("expected the final reconnect to be logged, got %s (%s)", last.EventType, last.Reason)
+	}
+	if !strings.HasPrefix(last.Details, "PID: 104 ") || !strings.Contains(last.Details, "3 earlier events coalesced") {
+		t.Errorf("unexpected details of the final event: %q", last.Details)
+	}
+}
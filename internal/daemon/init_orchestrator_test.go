@@ -4,6 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"go.olrik.dev/overseer/internal/core"
 	"go.olrik.dev/overseer/internal/db"
@@ -160,26 +161,116 @@ func TestInitStateOrchestrator_WithDatabase(t *testing.T) {
 	}
 }
 
-func TestExpandPath_HomeTilde(t *testing.T) {
-	result := expandPath("~/some/path")
-	if result == "~/some/path" {
-		t.Error("expected tilde to be expanded")
+func TestCheckSensorWatchdog(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath:     t.TempDir(),
+		Companion:      core.CompanionSettings{HistorySize: 50},
+		Locations:      map[string]*core.Location{},
+		Contexts:       []*core.ContextRule{},
+		SensorWatchdog: 2 * time.Minute,
 	}
-	if result == "" {
-		t.Error("expected non-empty path")
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+
+	d := &Daemon{
+		tunnels: make(map[string]Tunnel),
 	}
-}
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	t.Cleanup(d.cancelFunc)
+	d.companionMgr = NewCompanionManager()
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+	orch := stateOrchestrator
+	// The restart stops orch in the background; wait for it before the config
+	// is restored, as its callbacks read core.Config
+	t.Cleanup(orch.Stop)
 
-func TestExpandPath_NoTilde(t *testing.T) {
-	result := expandPath("/absolute/path")
-	if result != "/absolute/path" {
-		t.Errorf("expected unchanged path, got %q", result)
+	// Freshly started, the heartbeat is recent
+	if d.checkSensorWatchdog(time.Now()) || stateOrchestrator != orch {
+		t.Fatal("expected a healthy state manager to be left alone")
+	}
+
+	// Disabled, a stale heartbeat is ignored
+	core.Config.SensorWatchdog = 0
+	if d.checkSensorWatchdog(time.Now().Add(time.Hour)) || stateOrchestrator != orch {
+		t.Fatal("expected no restart with the watchdog disabled")
+	}
+
+	// No evaluation for longer than the watchdog allows: the manager is stalled
+	core.Config.SensorWatchdog = 2 * time.Minute
+	if !d.checkSensorWatchdog(orch.LastEvaluation().Add(3 * time.Minute)) {
+		t.Fatal("expected a stalled state manager to be restarted")
+	}
+	if stateOrchestrator == nil || stateOrchestrator == orch {
+		t.Error("expected a new state orchestrator after the restart")
 	}
 }
 
-func TestExpandPath_RelativePath(t *testing.T) {
-	result := expandPath("relative/path")
-	if result != "relative/path" {
-		t.Errorf("expected unchanged path, got %q", result)
+func TestRestartStateOrchestrator_SerializedWithReload(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: t.TempDir(),
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+
+	d := &Daemon{
+		tunnels: make(map[string]Tunnel),
+	}
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	t.Cleanup(d.cancelFunc)
+	d.companionMgr = NewCompanionManager()
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+	orch := GetStateOrchestrator()
+	t.Cleanup(orch.Stop)
+
+	// A reload in progress holds off the restart
+	d.reloadMu.Lock()
+	restarted := make(chan error, 1)
+	go func() { restarted <- d.restartStateOrchestrator() }()
+
+	// Meanwhile the orchestrator stays in place for everyone using it
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if GetStateOrchestrator() != orch {
+			t.Fatal("expected the restart to wait for the reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	d.reloadMu.Unlock()
+
+	select {
+	case err := <-restarted:
+		if err != nil {
+			t.Fatalf("restartStateOrchestrator failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("restart did not finish after the reload")
+	}
+	if current := GetStateOrchestrator(); current == nil || current == orch {
+		t.Error("expected a new state orchestrator after the restart")
 	}
 }
@@ -30,7 +30,7 @@ func TestStartTunnelWhenIPReady_TunnelAlreadyExists(t *testing.T) {
 	}
 
 	// This should return immediately because the tunnel already exists
-	d.startTunnelWhenIPReady("existing-tunnel", "")
+	d.startTunnelWhenIPReady("existing-tunnel", "", nil, ReasonManual)
 }
 
 func TestStartTunnelWhenIPReady_IPAlreadyKnown(t *testing.T) {
@@ -55,5 +55,5 @@ func TestStartTunnelWhenIPReady_IPAlreadyKnown(t *testing.T) {
 	// With nil orchestrator, isPublicIPKnown returns true immediately
 	// startTunnelWhenIPReady will call startTunnel which will fail (no SSH)
 	// but the code path is exercised
-	d.startTunnelWhenIPReady("test-tunnel", "")
+	d.startTunnelWhenIPReady("test-tunnel", "", nil, ReasonManual)
 }
@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -370,6 +373,127 @@ func TestHandleConnection_IPC_CompanionStatus(t *testing.T) {
 	}
 }
 
+func TestHandleConnection_IPC_CompanionGrep(t *testing.T) {
+	quietLoggerIPC(t)
+
+	oldConfig := core.Config
+	defer func() { core.Config = oldConfig }()
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	d := New()
+	output := NewLogBroadcaster(50)
+	output.AddToHistory("2024-01-12 15:04:05 [stdout] listening on :8080\n")
+	output.AddToHistory("2024-01-12 15:04:06 [stderr] error: connection refused\n")
+	output.AddToHistory("2024-01-12 15:04:07 [stdout] request served\n")
+	output.AddToHistory("2024-01-12 15:04:08 [stderr] error: timeout waiting for upstream\n")
+	d.companionMgr.companions["my-tunnel"] = map[string]*CompanionProcess{
+		"my-comp": {Name: "my-comp", TunnelAlias: "my-tunnel", State: CompanionStateRunning, output: output},
+	}
+
+	resp := sendIPCCommand(t, d, "COMPANION_GREP my-tunnel my-comp error: .*(refused|upstream)")
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected data in response, got %+v", resp)
+	}
+	lines, _ := data["lines"].([]interface{})
+	want := []string{
+		"2024-01-12 15:04:06 [stderr] error: connection refused\n",
+		"2024-01-12 15:04:08 [stderr] error: timeout waiting for upstream\n",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d matching lines, got %v", len(want), lines)
+	}
+	for i, line := range lines {
+		if line != want[i] {
+			t.Errorf("line %d = %q, want %q", i, line, want[i])
+		}
+	}
+
+	// The timestamp and stream prefix aren't searched
+	resp = sendIPCCommand(t, d, "COMPANION_GREP my-tunnel my-comp stdout")
+	data, _ = resp.Data.(map[string]interface{})
+	if lines, _ := data["lines"].([]interface{}); len(lines) != 0 {
+		t.Errorf("expected the stream prefix not to match, got %v", data["lines"])
+	}
+
+	resp = sendIPCCommand(t, d, "COMPANION_GREP my-tunnel missing error")
+	if len(resp.Messages) == 0 || resp.Messages[0].Status != "ERROR" {
+		t.Errorf("expected ERROR for an unknown companion, got %+v", resp.Messages)
+	}
+
+	resp = sendIPCCommand(t, d, "COMPANION_GREP my-tunnel my-comp (")
+	if len(resp.Messages) == 0 || resp.Messages[0].Status != "ERROR" {
+		t.Errorf("expected ERROR for an invalid pattern, got %+v", resp.Messages)
+	}
+}
+
+func TestHandleConnection_IPC_CompanionTail(t *testing.T) {
+	quietLoggerIPC(t)
+
+	oldConfig := core.Config
+	defer func() { core.Config = oldConfig }()
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	d := New()
+	output := NewLogBroadcaster(50)
+	history := []string{
+		"2024-01-12 15:04:05 [stdout] listening on :8080\n",
+		"2024-01-12 15:04:06 [stderr] error: connection refused\n",
+		"2024-01-12 15:04:07 [stdout] request served\n",
+		"2024-01-12 15:04:08 [stdout] request served\n",
+	}
+	for _, line := range history {
+		output.AddToHistory(line)
+	}
+	d.companionMgr.companions["my-tunnel"] = map[string]*CompanionProcess{
+		"my-comp": {Name: "my-comp", TunnelAlias: "my-tunnel", State: CompanionStateRunning, output: output},
+	}
+
+	tests := []struct {
+		lines string
+		want  []string
+	}{
+		{"2", history[2:]},
+		{"10", history},
+		{"all", history},
+		{"0", nil},
+	}
+	for _, tt := range tests {
+		// sendIPCCommand reads until EOF, so returning at all means the
+		// daemon closed the connection instead of streaming live output
+		resp := sendIPCCommand(t, d, "COMPANION_TAIL my-tunnel my-comp "+tt.lines)
+		data, ok := resp.Data.(map[string]interface{})
+		if !ok {
+			t.Fatalf("lines %s: expected data in response, got %+v", tt.lines, resp)
+		}
+		lines, _ := data["lines"].([]interface{})
+		if len(lines) != len(tt.want) {
+			t.Fatalf("lines %s: expected %d lines, got %v", tt.lines, len(tt.want), lines)
+		}
+		for i, line := range lines {
+			if line != tt.want[i] {
+				t.Errorf("lines %s: line %d = %q, want %q", tt.lines, i, line, tt.want[i])
+			}
+		}
+	}
+
+	for _, command := range []string{
+		"COMPANION_TAIL my-tunnel missing 10",
+		"COMPANION_TAIL my-tunnel my-comp many",
+		"COMPANION_TAIL my-tunnel my-comp -5",
+		"COMPANION_TAIL my-tunnel my-comp",
+	} {
+		resp := sendIPCCommand(t, d, command)
+		if len(resp.Messages) == 0 || resp.Messages[0].Status != "ERROR" {
+			t.Errorf("%s: expected ERROR, got %+v", command, resp.Messages)
+		}
+	}
+}
+
 func TestHandleConnection_IPC_CompanionInitInvalid(t *testing.T) {
 	quietLoggerIPC(t)
 
@@ -911,6 +1035,53 @@ func TestHandleConnection_IPC_VersionCommand(t *testing.T) {
 	}
 }
 
+func TestHandleConnection_IPC_Hello(t *testing.T) {
+	quietLoggerIPC(t)
+
+	oldConfig := core.Config
+	defer func() { core.Config = oldConfig }()
+	core.Config = &core.Configuration{}
+
+	d := &Daemon{
+		tunnels:       make(map[string]Tunnel),
+		askpassTokens: make(map[string]string),
+		logBroadcast:  NewLogBroadcaster(100),
+		companionMgr:  NewCompanionManager(),
+	}
+
+	tests := []struct {
+		name       string
+		command    string
+		wantStatus string
+		wantData   bool
+	}{
+		{"matching", fmt.Sprintf("HELLO %d", ProtocolVersion), "INFO", true},
+		{"mismatched", fmt.Sprintf("HELLO %d", ProtocolVersion+1), "WARN", true},
+		{"invalid", "HELLO soon", "ERROR", false},
+		{"missing", "HELLO", "ERROR", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := sendIPCCommand(t, d, tt.command)
+			if len(resp.Messages) != 1 || resp.Messages[0].Status != tt.wantStatus {
+				t.Fatalf("expected a %s message, got %+v", tt.wantStatus, resp.Messages)
+			}
+			if !tt.wantData {
+				return
+			}
+
+			data, ok := resp.Data.(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected data in response, got %+v", resp)
+			}
+			// The daemon always reports the protocol it speaks
+			if protocol, _ := data["protocol"].(float64); int(protocol) != ProtocolVersion {
+				t.Errorf("protocol = %v, want %d", data["protocol"], ProtocolVersion)
+			}
+		})
+	}
+}
+
 func TestHandleConnection_IPC_StatusCommand(t *testing.T) {
 	quietLoggerIPC(t)
 
@@ -936,6 +1107,47 @@ func TestHandleConnection_IPC_StatusCommand(t *testing.T) {
 	}
 }
 
+func TestHandleConnection_IPC_JSONRequest(t *testing.T) {
+	quietLoggerIPC(t)
+
+	oldConfig := core.Config
+	defer func() { core.Config = oldConfig }()
+	core.Config = &core.Configuration{}
+
+	d := &Daemon{
+		tunnels:       make(map[string]Tunnel),
+		askpassTokens: make(map[string]string),
+		logBroadcast:  NewLogBroadcaster(100),
+		companionMgr:  NewCompanionManager(),
+	}
+	d.tunnels["db"] = Tunnel{Hostname: "db", Pid: 4242, State: StateConnected, StartDate: time.Now()}
+
+	text := sendIPCCommand(t, d, "STATUS")
+	jsonResp := sendIPCCommand(t, d, `{"command":"STATUS","args":[]}`)
+	if !reflect.DeepEqual(jsonResp, text) {
+		t.Errorf("JSON STATUS response differs from text response:\njson: %+v\ntext: %+v", jsonResp, text)
+	}
+	if statuses, _ := jsonResp.Data.([]interface{}); len(statuses) != 1 {
+		t.Errorf("expected one tunnel in the STATUS data, got %+v", jsonResp.Data)
+	}
+
+	// Arguments are passed on as given
+	resp := sendIPCCommand(t, d, fmt.Sprintf(`{"command":"HELLO","args":["%d"]}`, ProtocolVersion))
+	if len(resp.Messages) != 1 || resp.Messages[0].Status != "INFO" {
+		t.Errorf("expected HELLO to succeed, got %+v", resp.Messages)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if formats, _ := data["request_formats"].([]interface{}); !slices.Contains(formats, interface{}("json")) {
+		t.Errorf("expected HELLO to advertise json requests, got %v", data["request_formats"])
+	}
+
+	// A malformed request is answered with an error
+	resp = sendIPCCommand(t, d, `{"command":"STATUS"`)
+	if len(resp.Messages) != 1 || resp.Messages[0].Status != "ERROR" {
+		t.Errorf("expected an error for malformed JSON, got %+v", resp.Messages)
+	}
+}
+
 func TestHandleConnection_IPC_CompanionStatusCommand(t *testing.T) {
 	quietLoggerIPC(t)
 
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/lmittmann/tint"
 	"go.olrik.dev/overseer/internal/awareness/state"
+	"go.olrik.dev/overseer/internal/core"
 )
 
 // LogBroadcaster manages streaming logs to multiple clients
@@ -44,6 +46,9 @@ func (lb *LogBroadcaster) Subscribe() chan string {
 	return ch
 }
 
+// HistoryAll requests every retained history line from SubscribeWithHistory
+const HistoryAll = -1
+
 // SubscribeWithHistory adds a new client and returns recent history
 // The history slice is returned separately to avoid blocking the channel
 func (lb *LogBroadcaster) SubscribeWithHistory(historyLines int) (chan string, []string) {
@@ -53,6 +58,10 @@ func (lb *LogBroadcaster) SubscribeWithHistory(historyLines int) (chan string, [
 	ch := make(chan string, 100) // Buffer to prevent blocking
 	lb.clients[ch] = true
 
+	if historyLines == HistoryAll {
+		historyLines = len(lb.history)
+	}
+
 	// Return the last N lines from history
 	var history []string
 	if historyLines > 0 && len(lb.history) > 0 {
@@ -110,6 +119,34 @@ func (lb *LogBroadcaster) AddToHistory(message string) {
 	lb.history = append(lb.history, message)
 }
 
+// FilterHistory returns the history lines for which match returns true, oldest first
+func (lb *LogBroadcaster) FilterHistory(match func(line string) bool) []string {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	lines := make([]string, 0)
+	for _, line := range lb.history {
+		if match(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// TailHistory returns the last n history lines, oldest first, or every
+// retained line for HistoryAll
+func (lb *LogBroadcaster) TailHistory(n int) []string {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+
+	if n == HistoryAll || n > len(lb.history) {
+		n = len(lb.history)
+	}
+	lines := make([]string, max(n, 0))
+	copy(lines, lb.history[len(lb.history)-len(lines):])
+	return lines
+}
+
 // ClearHistory clears the history buffer
 func (lb *LogBroadcaster) ClearHistory() {
 	lb.mu.Lock()
@@ -130,18 +167,130 @@ func (lw *LogWriter) Write(p []byte) (n int, err error) {
 
 // setupLogging configures the daemon's logger to broadcast to connected clients
 func (d *Daemon) setupLogging() {
-	// Create a multi-writer that writes to both stderr and the broadcaster
 	logWriter := &LogWriter{broadcaster: d.logBroadcast}
+	if d.foreground {
+		// Clients keep getting the usual output, the log output gets the chosen format
+		slog.SetDefault(slog.New(slog.NewMultiHandler(
+			tint.NewHandler(logWriter, &tint.Options{
+				Level:      &d.logLevel,
+				TimeFormat: time.DateTime,
+			}),
+			d.foregroundHandler(),
+		)))
+		d.applyVerbosity()
+		return
+	}
+
+	// Create a multi-writer that writes to both stderr and the broadcaster
 	multiWriter := io.MultiWriter(os.Stderr, logWriter)
 
 	// Set up tint handler with the multi-writer
 	handler := tint.NewHandler(multiWriter, &tint.Options{
-		Level:      slog.LevelDebug,
+		Level:      &d.logLevel,
 		TimeFormat: time.DateTime,
 	})
 
 	// Set as the default logger
 	slog.SetDefault(slog.New(handler))
+	d.applyVerbosity()
+}
+
+// foregroundHandler returns the handler writing the foreground log output
+func (d *Daemon) foregroundHandler() slog.Handler {
+	out := d.logOutput
+	if out == nil {
+		out = os.Stderr
+	}
+	if d.logFormat == LogFormatJSON {
+		return slog.NewJSONHandler(out, &slog.HandlerOptions{Level: &d.logLevel})
+	}
+	return tint.NewHandler(out, &tint.Options{
+		Level:      &d.logLevel,
+		TimeFormat: time.DateTime,
+		NoColor:    true,
+	})
+}
+
+// streamStateLogs writes the events of orchestrator to the foreground log
+// output, replacing the stream of a previous orchestrator
+func (d *Daemon) streamStateLogs(orchestrator *state.Orchestrator) {
+	if d.stopStateLogs != nil {
+		d.stopStateLogs()
+	}
+
+	handler := d.foregroundHandler()
+	id, entries := orchestrator.SubscribeLogs(true)
+	d.stopStateLogs = func() { orchestrator.UnsubscribeLogs(id) }
+
+	go func() {
+		ctx := context.Background()
+		for entry := range entries {
+			level := stateLogLevel(entry.Level)
+			if !handler.Enabled(ctx, level) {
+				continue
+			}
+			record := slog.NewRecord(entry.Timestamp, level, entry.Message, 0)
+			record.AddAttrs(stateLogAttrs(entry)...)
+			handler.Handle(ctx, record)
+		}
+	}()
+}
+
+// stateLogLevel maps the level of a state event to a log level
+func stateLogLevel(level state.LogLevel) slog.Level {
+	switch level {
+	case state.LogDebug:
+		return slog.LevelDebug
+	case state.LogWarn:
+		return slog.LevelWarn
+	case state.LogError:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// stateLogAttrs returns the details of a state event as log attributes
+func stateLogAttrs(entry state.LogEntry) []slog.Attr {
+	attrs := []slog.Attr{slog.String("category", entry.Category.String())}
+	var errMsg string
+	switch {
+	case entry.Sensor != nil:
+		if entry.Sensor.Online != nil {
+			attrs = append(attrs, slog.Bool("online", *entry.Sensor.Online))
+		} else if entry.Sensor.IP != "" {
+			attrs = append(attrs, slog.String("ip", entry.Sensor.IP))
+		} else if entry.Sensor.Value != "" {
+			attrs = append(attrs, slog.String("value", entry.Sensor.Value))
+		}
+		errMsg = entry.Sensor.Error
+	case entry.Effect != nil:
+		errMsg = entry.Effect.Error
+	case entry.Hook != nil:
+		errMsg = entry.Hook.Error
+	}
+	if errMsg != "" {
+		attrs = append(attrs, slog.String("error", errMsg))
+	}
+	return attrs
+}
+
+// applyVerbosity sets the daemon log level from the command line override,
+// falling back to the config's verbose setting
+func (d *Daemon) applyVerbosity() {
+	verbose := d.verbose
+	if verbose == 0 && core.Config != nil {
+		verbose = core.Config.Verbose
+	}
+	d.logLevel.Set(verbosityLevel(verbose))
+}
+
+// verbosityLevel maps a verbose setting to a log level, more verbose settings
+// logging more. 0 (the default) and 1 log info and above, 2 and up debug too.
+func verbosityLevel(verbose int) slog.Level {
+	if verbose >= 2 {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
 }
 
 // handleLogs streams daemon logs to the client until they disconnect
@@ -156,7 +305,7 @@ func (d *Daemon) handleLogsWithHistory(conn net.Conn, showHistory bool, historyL
 	defer conn.Close()
 
 	// Use handleLogsWithState which includes both slog and state events
-	if stateOrchestrator != nil {
+	if GetStateOrchestrator() != nil {
 		d.handleLogsWithStateAndHistory(conn, showHistory, historyLines, minLevel)
 		return
 	}
@@ -271,14 +420,15 @@ func (d *Daemon) handleLogsWithState(conn net.Conn) {
 // minLevel controls which entries count toward the history line limit.
 func (d *Daemon) handleLogsWithStateAndHistory(conn net.Conn, showHistory bool, historyLines int, minLevel state.LogLevel) {
 	// Subscribe to state log channel (which includes all events)
+	orch := GetStateOrchestrator()
 	var stateID uint64
 	var stateChan <-chan state.LogEntry
 	if showHistory {
-		stateID, stateChan = stateOrchestrator.SubscribeLogsWithHistory(true, historyLines, minLevel)
+		stateID, stateChan = orch.SubscribeLogsWithHistory(true, historyLines, minLevel)
 	} else {
-		stateID, stateChan = stateOrchestrator.SubscribeLogsWithHistory(false, 0, minLevel)
+		stateID, stateChan = orch.SubscribeLogsWithHistory(false, 0, minLevel)
 	}
-	defer stateOrchestrator.UnsubscribeLogs(stateID)
+	defer orch.UnsubscribeLogs(stateID)
 
 	// Send initial message
 	initialMsg := "Connected to overseer daemon logs. Press Ctrl+C to exit.\n"
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"fmt"
+	"log/slog"
 	"sync"
 	"testing"
 	"time"
@@ -117,6 +118,28 @@ func TestLogBroadcasterSubscribeWithHistoryMoreThanAvailable(t *testing.T) {
 	}
 }
 
+func TestLogBroadcasterSubscribeWithHistoryAll(t *testing.T) {
+	lb := NewLogBroadcaster(5)
+
+	// Overflow the buffer so only the last 5 lines are retained
+	for i := 1; i <= 8; i++ {
+		lb.Broadcast(fmt.Sprintf("msg%d", i))
+	}
+
+	ch, history := lb.SubscribeWithHistory(HistoryAll)
+	defer lb.Unsubscribe(ch)
+
+	want := []string{"msg4", "msg5", "msg6", "msg7", "msg8"}
+	if len(history) != len(want) {
+		t.Fatalf("Expected %d history entries, got %d: %v", len(want), len(history), history)
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Errorf("history[%d] = %q, want %q", i, history[i], want[i])
+		}
+	}
+}
+
 func TestLogBroadcasterHistoryRingBuffer(t *testing.T) {
 	lb := NewLogBroadcaster(3) // Only 3 entries max
 
@@ -332,3 +355,27 @@ func TestLogBroadcaster_ConcurrentSubscribeUnsubscribe(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestVerbosityLevel(t *testing.T) {
+	tests := []struct {
+		verbose int
+		want    slog.Level
+	}{
+		{0, slog.LevelInfo},
+		{1, slog.LevelInfo},
+		{2, slog.LevelDebug},
+		{3, slog.LevelDebug},
+	}
+	for _, tt := range tests {
+		if got := verbosityLevel(tt.verbose); got != tt.want {
+			t.Errorf("verbosityLevel(%d) = %v, want %v", tt.verbose, got, tt.want)
+		}
+	}
+
+	// More verbose never logs less
+	for verbose := 1; verbose <= 5; verbose++ {
+		if verbosityLevel(verbose) > verbosityLevel(verbose-1) {
+			t.Errorf("verbose=%d logs less than verbose=%d", verbose, verbose-1)
+		}
+	}
+}
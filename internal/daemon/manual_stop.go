@@ -0,0 +1,35 @@
+package daemon
+
+// A tunnel the user disconnected by hand is not auto-connected again by any
+// context until the user connects it explicitly. Without this, flapping
+// between offline and online re-enters the context and reconnects the tunnel
+// the user just stopped.
+//
+// Only tunnels the user stopped are marked. A tunnel that drops because the
+// network went away stays known as disconnected and is reconnected when the
+// context is entered again.
+
+// markManuallyStopped records that alias was stopped by the user, along with
+// the context it was stopped in. Must be called with d.mu held.
+func (d *Daemon) markManuallyStopped(alias string) {
+	if d.manuallyStopped == nil {
+		d.manuallyStopped = make(map[string]string)
+	}
+	context, _ := d.getContextStatusNew()
+	d.manuallyStopped[alias] = context
+}
+
+// clearManuallyStopped re-enables auto-connect for alias.
+// Must be called with d.mu held.
+func (d *Daemon) clearManuallyStopped(alias string) {
+	delete(d.manuallyStopped, alias)
+}
+
+// isManuallyStopped reports whether auto-connect is suppressed for alias
+func (d *Daemon) isManuallyStopped(alias string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, stopped := d.manuallyStopped[alias]
+	return stopped
+}
@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.olrik.dev/overseer/internal/awareness/state"
+)
+
+// backoffBuckets are the upper bounds, in seconds, of the buckets of the
+// reconnect backoff histogram. They span the default initial_backoff (1s) to
+// twice the default max_backoff (5m).
+var backoffBuckets = []float64{1, 2, 5, 10, 30, 60, 120, 300, 600}
+
+// histogram counts observations into buckets by upper bound, like a
+// Prometheus histogram
+type histogram struct {
+	bounds []float64 // Upper bounds of the buckets, ascending
+	counts []uint64  // Observations per bucket, the last one is +Inf
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// observe adds a value to the first bucket whose upper bound is at least value
+func (h *histogram) observe(value float64) {
+	i, _ := slices.BinarySearch(h.bounds, value)
+	h.counts[i]++
+	h.count++
+	h.sum += value
+}
+
+// cumulative returns the number of observations at or below each upper
+// bound, ending with +Inf, as Prometheus buckets are reported
+func (h *histogram) cumulative() []uint64 {
+	buckets := make([]uint64, len(h.counts))
+	var total uint64
+	for i, count := range h.counts {
+		total += count
+		buckets[i] = total
+	}
+	return buckets
+}
+
+// observeBackoff records the backoff delay before a reconnect attempt of
+// alias. The caller must hold d.mu.
+func (d *Daemon) observeBackoff(alias string, backoff time.Duration) {
+	h := d.backoffHistograms[alias]
+	if h == nil {
+		h = newHistogram(backoffBuckets)
+		d.backoffHistograms[alias] = h
+	}
+	h.observe(backoff.Seconds())
+}
+
+// metricsText returns the daemon's metrics in the Prometheus text exposition format
+func (d *Daemon) metricsText() string {
+	var b strings.Builder
+	if orch := GetStateOrchestrator(); orch != nil {
+		writeSensorMetrics(&b, orch.Sensors())
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	const name = "overseer_reconnect_backoff_seconds"
+	fmt.Fprintf(&b, "# HELP %s Backoff delay before each reconnect attempt of a tunnel.\n", name)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+	for _, alias := range slices.Sorted(maps.Keys(d.backoffHistograms)) {
+		h := d.backoffHistograms[alias]
+		tunnel := strconv.Quote(alias)
+		for i, count := range h.cumulative() {
+			le := "+Inf"
+			if i < len(h.bounds) {
+				le = strconv.FormatFloat(h.bounds[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(&b, "%s_bucket{tunnel=%s,le=%q} %d\n", name, tunnel, le, count)
+		}
+		fmt.Fprintf(&b, "%s_sum{tunnel=%s} %s\n", name, tunnel, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{tunnel=%s} %d\n", name, tunnel, h.count)
+	}
+	return b.String()
+}
+
+// writeSensorMetrics renders the sensors that have been read: boolean sensors
+// as a 0/1 gauge, string and IP sensors as an info metric carrying the value
+// in a label
+func writeSensorMetrics(b *strings.Builder, sensors []state.SensorInfo) {
+	const gauge = "overseer_sensor"
+	const info = "overseer_sensor_info"
+	fmt.Fprintf(b, "# HELP %s Value of a boolean sensor, 1 when true.\n", gauge)
+	fmt.Fprintf(b, "# TYPE %s gauge\n", gauge)
+	for _, sensor := range sensors {
+		if sensor.Kind != state.SensorKindBool {
+			continue
+		}
+		value, err := strconv.ParseBool(sensor.Value)
+		if err != nil {
+			continue // Not read yet
+		}
+		gaugeValue := 0
+		if value {
+			gaugeValue = 1
+		}
+		fmt.Fprintf(b, "%s{sensor=%s} %d\n", gauge, strconv.Quote(sensor.Name), gaugeValue)
+	}
+
+	fmt.Fprintf(b, "# HELP %s Value of a string or IP sensor, in the value label.\n", info)
+	fmt.Fprintf(b, "# TYPE %s gauge\n", info)
+	for _, sensor := range sensors {
+		if sensor.Kind == state.SensorKindBool || sensor.Value == "" {
+			continue
+		}
+		fmt.Fprintf(b, "%s{sensor=%s,kind=%q,value=%s} 1\n", info, strconv.Quote(sensor.Name), sensor.Kind, strconv.Quote(sensor.Value))
+	}
+}
+
+// getMetrics answers METRICS
+func (d *Daemon) getMetrics() Response {
+	response := Response{}
+	response.AddMessage("OK", "INFO")
+	response.AddData(map[string]string{"metrics": d.metricsText()})
+	return response
+}
@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/awareness/state"
+	"go.olrik.dev/overseer/internal/core"
+)
+
+func TestHistogram_Buckets(t *testing.T) {
+	h := newHistogram([]float64{1, 5, 30})
+	for _, value := range []float64{0.5, 1, 2, 5, 30, 31, 300} {
+		h.observe(value)
+	}
+
+	// An observation lands in the first bucket whose bound is at least its value
+	if want := []uint64{2, 2, 1, 2}; !reflect.DeepEqual(h.counts, want) {
+		t.Errorf("counts = %v, want %v", h.counts, want)
+	}
+	if want := []uint64{2, 4, 5, 7}; !reflect.DeepEqual(h.cumulative(), want) {
+		t.Errorf("cumulative() = %v, want %v", h.cumulative(), want)
+	}
+	if h.count != 7 || h.sum != 369.5 {
+		t.Errorf("count = %d, sum = %v, want 7, 369.5", h.count, h.sum)
+	}
+}
+
+func TestMetricsText_ReconnectBackoff(t *testing.T) {
+	d := New()
+
+	d.mu.Lock()
+	d.observeBackoff("corp", 1*time.Second)
+	d.observeBackoff("corp", 2*time.Second)
+	d.observeBackoff("corp", 5*time.Minute)
+	d.observeBackoff("corp", 5*time.Minute)
+	d.observeBackoff("home", 500*time.Millisecond)
+	d.mu.Unlock()
+
+	text := d.metricsText()
+	for _, line := range []string{
+		"# TYPE overseer_reconnect_backoff_seconds histogram",
+		`overseer_reconnect_backoff_seconds_bucket{tunnel="corp",le="1"} 1`,
+		`overseer_reconnect_backoff_seconds_bucket{tunnel="corp",le="2"} 2`,
+		`overseer_reconnect_backoff_seconds_bucket{tunnel="corp",le="120"} 2`,
+		`overseer_reconnect_backoff_seconds_bucket{tunnel="corp",le="300"} 4`,
+		`overseer_reconnect_backoff_seconds_bucket{tunnel="corp",le="+Inf"} 4`,
+		`overseer_reconnect_backoff_seconds_sum{tunnel="corp"} 603`,
+		`overseer_reconnect_backoff_seconds_count{tunnel="corp"} 4`,
+		`overseer_reconnect_backoff_seconds_bucket{tunnel="home",le="1"} 1`,
+		`overseer_reconnect_backoff_seconds_count{tunnel="home"} 1`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("metrics missing %q:\n%s", line, text)
+		}
+	}
+}
+
+func TestWriteSensorMetrics(t *testing.T) {
+	var b strings.Builder
+	writeSensorMetrics(&b, []state.SensorInfo{
+		{Name: "env:VPN", Kind: state.SensorKindString, Value: "on"},
+		{Name: "online", Kind: state.SensorKindBool, Value: "true"},
+		{Name: "power", Kind: state.SensorKindString},
+		{Name: "public_ipv4", Kind: state.SensorKindIP, Value: "203.0.113.1"},
+		{Name: "tcp", Kind: state.SensorKindBool, Value: "false"},
+		{Name: "wifi", Kind: state.SensorKindBool},
+	})
+
+	text := b.String()
+	for _, line := range []string{
+		"# TYPE overseer_sensor gauge",
+		`overseer_sensor{sensor="online"} 1`,
+		`overseer_sensor{sensor="tcp"} 0`,
+		"# TYPE overseer_sensor_info gauge",
+		`overseer_sensor_info{sensor="env:VPN",kind="string",value="on"} 1`,
+		`overseer_sensor_info{sensor="public_ipv4",kind="ip",value="203.0.113.1"} 1`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("metrics missing %q:\n%s", line, text)
+		}
+	}
+
+	// Sensors that haven't been read yet are left out
+	for _, name := range []string{`"power"`, `"wifi"`} {
+		if strings.Contains(text, name) {
+			t.Errorf("expected unread sensor %s to be left out:\n%s", name, text)
+		}
+	}
+}
+
+func TestMetricsText_Sensors(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: t.TempDir(),
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+	stateOrchestrator = nil
+
+	d := New()
+	if strings.Contains(d.metricsText(), "overseer_sensor") {
+		t.Error("expected no sensor metrics without an orchestrator")
+	}
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+
+	// The probes report in the background
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		text := d.metricsText()
+		if strings.Contains(text, `overseer_sensor{sensor="tcp"} `) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the tcp sensor in the metrics:\n%s", text)
+		}
+	}
+}
@@ -126,6 +126,117 @@ func TestMonitorCompanion_AutoRestart_Failed(t *testing.T) {
 	}
 }
 
+func TestMonitorCompanion_RestartTunnelOnFailure(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	cm := NewCompanionManager()
+	restarted := make(chan string, 1)
+	cm.SetTunnelRestarter(func(alias, name string) {
+		restarted <- alias + "/" + name
+	})
+
+	// A companion that fails after it was started
+	cmd := exec.Command("sh", "-c", "exit 1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	proc := &CompanionProcess{
+		Name:        "auth",
+		TunnelAlias: "test-tunnel",
+		Cmd:         cmd,
+		Pid:         cmd.Process.Pid,
+		State:       CompanionStateRunning,
+		Config: core.CompanionConfig{
+			Name:        "auth",
+			Command:     "exit 1",
+			OnFailure:   "restart_tunnel",
+			AutoRestart: true,
+		},
+		output: NewLogBroadcaster(100),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	cm.monitorCompanion(proc)
+
+	select {
+	case got := <-restarted:
+		if got != "test-tunnel/auth" {
+			t.Errorf("expected test-tunnel/auth to be restarted, got %s", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the failing companion to restart its tunnel")
+	}
+
+	// The tunnel restart runs the companion again, so it isn't auto-restarted
+	proc.mu.RLock()
+	state := proc.State
+	proc.mu.RUnlock()
+	if state != CompanionStateExited {
+		t.Errorf("expected state Exited, got %v", state)
+	}
+}
+
+func TestMonitorCompanion_NoTunnelRestartOnCleanExit(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	cm := NewCompanionManager()
+	restarted := make(chan string, 1)
+	cm.SetTunnelRestarter(func(alias, name string) {
+		restarted <- alias + "/" + name
+	})
+
+	cmd := exec.Command("true")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	proc := &CompanionProcess{
+		Name:        "auth",
+		TunnelAlias: "test-tunnel",
+		Cmd:         cmd,
+		Pid:         cmd.Process.Pid,
+		State:       CompanionStateRunning,
+		Config: core.CompanionConfig{
+			Name:      "auth",
+			Command:   "true",
+			OnFailure: "restart_tunnel",
+		},
+		output: NewLogBroadcaster(100),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	cm.monitorCompanion(proc)
+
+	select {
+	case got := <-restarted:
+		t.Errorf("expected no tunnel restart after a clean exit, got %s", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestMonitorTunnel_ProcessExitsMaxRetriesWithDB(t *testing.T) {
 	quietLogger(t)
 
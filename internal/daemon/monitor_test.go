@@ -2,9 +2,11 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -115,6 +117,74 @@ func TestMonitorTunnel_ProcessExitsMaxRetries(t *testing.T) {
 	}
 }
 
+func TestMonitorTunnel_StableConnectionResetsRetries(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		SSH:       core.SSHConfig{MaxRetries: 3, StableReset: 5 * time.Minute},
+	}
+
+	tests := []struct {
+		name         string
+		connectedFor time.Duration
+		wantKept     bool
+	}{
+		{"drop after a stable period starts over", 10 * time.Minute, true},
+		{"drop before stable_reset keeps escalating", time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New()
+			d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+			t.Cleanup(d.cancelFunc)
+
+			cmd := exec.Command("true")
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("failed to start process: %v", err)
+			}
+
+			d.tunnels["flaky"] = Tunnel{
+				Hostname:          "test.example.com",
+				Pid:               cmd.Process.Pid,
+				Cmd:               cmd,
+				State:             StateConnected,
+				AutoReconnect:     true,
+				RetryCount:        3, // At max retries from earlier reconnects
+				LastConnectedTime: time.Now().Add(-tt.connectedFor),
+			}
+
+			// monitorTunnel stops short of reconnecting because there is no
+			// online sensor in this test
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				d.monitorTunnel("flaky")
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("monitorTunnel did not return in time")
+			}
+
+			d.mu.Lock()
+			tunnel, exists := d.tunnels["flaky"]
+			d.mu.Unlock()
+			if exists != tt.wantKept {
+				t.Fatalf("tunnel kept = %v, want %v", exists, tt.wantKept)
+			}
+			if exists && tunnel.RetryCount != 0 {
+				t.Errorf("RetryCount = %d, want 0 after a stable connection", tunnel.RetryCount)
+			}
+		})
+	}
+}
+
 func TestMonitorTunnel_ProcessExitsWithDatabase(t *testing.T) {
 	quietLogger(t)
 
@@ -546,3 +616,196 @@ func TestMonitorAdoptedCompanion_StoppedBeforeCheck(t *testing.T) {
 		t.Fatal("monitorAdoptedCompanion did not return for stopped state")
 	}
 }
+
+func TestMonitorTunnel_MaxLifetimeRecycle(t *testing.T) {
+	quietLogger(t)
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		// With no retries left, any ordinary disconnect would give up on the tunnel
+		SSH: core.SSHConfig{MaxRetries: 0},
+		Tunnels: map[string]*core.TunnelConfig{
+			"short-lived": {Name: "short-lived", MaxLifetime: 300 * time.Millisecond},
+		},
+	}
+
+	d := New()
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	t.Cleanup(d.cancelFunc)
+	d.database = database
+
+	cmd := exec.Command("sleep", "60")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	d.tunnels["short-lived"] = Tunnel{
+		Hostname:          "test.example.com",
+		Pid:               cmd.Process.Pid,
+		Cmd:               cmd,
+		State:             StateConnected,
+		LastConnectedTime: time.Now(),
+		AutoReconnect:     true,
+	}
+
+	// monitorTunnel recycles the process once the lifetime is up, then stops
+	// short of reconnecting because there is no online sensor in this test
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.monitorTunnel("short-lived")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("monitorTunnel did not recycle the tunnel in time")
+	}
+
+	if err := cmd.Process.Signal(syscall.Signal(0)); err == nil {
+		t.Error("expected the SSH process to be terminated")
+	}
+
+	// The recycle must not be treated as a failure that exhausts max_retries
+	d.mu.Lock()
+	tunnel, exists := d.tunnels["short-lived"]
+	d.mu.Unlock()
+	if !exists {
+		t.Fatal("expected tunnel to be kept for reconnection after a lifetime recycle")
+	}
+	if tunnel.RetryCount != 0 {
+		t.Errorf("RetryCount = %d, want 0", tunnel.RetryCount)
+	}
+
+	events, err := database.GetTunnelEvents("short-lived", time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("GetTunnelEvents failed: %v", err)
+	}
+	var types []string
+	for _, e := range events {
+		types = append(types, e.EventType)
+	}
+	if len(types) != 1 || types[0] != "lifetime_recycle" {
+		t.Errorf("events = %v, want [lifetime_recycle]", types)
+	}
+}
+
+// sshFailureFor returns the failure verifyConnection reports for SSH output
+func sshFailureFor(t *testing.T, output string) error {
+	t.Helper()
+	for _, f := range sshFailures {
+		if f.output == output {
+			return f.failure
+		}
+	}
+	t.Fatalf("no SSH failure for %q", output)
+	return nil
+}
+
+func TestAbandonFatalReconnect(t *testing.T) {
+	quietLogger(t)
+
+	// Giving up runs the on_give_up hooks
+	marker := filepath.Join(t.TempDir(), "gave-up")
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		SSH: core.SSHConfig{MaxRetries: 10},
+		GlobalTunnelHooks: &core.TunnelHooksConfig{
+			OnGiveUp: []core.HookConfig{{Command: `echo "$OVERSEER_TUNNEL_ALIAS" > ` + marker, Timeout: 5 * time.Second}},
+		},
+	}
+
+	newDaemon := func() *Daemon {
+		d := New()
+		d.tunnels["denied"] = Tunnel{
+			Hostname:      "denied",
+			State:         StateReconnecting,
+			AutoReconnect: true,
+			RetryCount:    1,
+			AskpassToken:  "tok-1",
+		}
+		d.askpassTokens["tok-1"] = "denied"
+		return d
+	}
+
+	t.Run("permission denied gives up after the first attempt", func(t *testing.T) {
+		d := newDaemon()
+
+		d.mu.Lock()
+		abandoned := d.abandonFatalReconnect("denied", sshFailureFor(t, "Permission denied"))
+		d.mu.Unlock()
+
+		if !abandoned {
+			t.Fatal("expected a permission denied failure to stop retrying")
+		}
+		if _, exists := d.tunnels["denied"]; exists {
+			t.Error("expected tunnel to be removed with 9 of 10 retries left")
+		}
+		if _, exists := d.askpassTokens["tok-1"]; exists {
+			t.Error("expected askpass token to be cleaned up")
+		}
+
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+			if got, err := os.ReadFile(marker); err == nil && strings.TrimSpace(string(got)) == "denied" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for the on_give_up hooks to run")
+			}
+		}
+	})
+
+	t.Run("errors found before the attempt give up the same way", func(t *testing.T) {
+		d := newDaemon()
+		database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { database.Close() })
+		d.database = database
+
+		d.mu.Lock()
+		d.abandonReconnect("denied", errors.New("tunnel 'denied' has a password_command and a password stored in the keyring"))
+		d.mu.Unlock()
+
+		if _, exists := d.tunnels["denied"]; exists {
+			t.Error("expected tunnel to be removed")
+		}
+		if _, exists := d.askpassTokens["tok-1"]; exists {
+			t.Error("expected askpass token to be cleaned up")
+		}
+		events, err := database.GetTunnelEvents("denied", time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatalf("GetTunnelEvents failed: %v", err)
+		}
+		if len(events) != 1 || events[0].EventType != "reconnect_abandoned" {
+			t.Errorf("expected a reconnect_abandoned event, got %+v", events)
+		}
+	})
+
+	t.Run("timeout keeps retrying", func(t *testing.T) {
+		d := newDaemon()
+
+		d.mu.Lock()
+		abandoned := d.abandonFatalReconnect("denied", sshFailureFor(t, "Connection timed out"))
+		d.mu.Unlock()
+
+		if abandoned {
+			t.Error("expected a timeout to be retried")
+		}
+		if _, exists := d.tunnels["denied"]; !exists {
+			t.Error("expected tunnel to be kept for the next retry")
+		}
+	})
+}
@@ -8,18 +8,23 @@ import (
 	"strconv"
 	"syscall"
 	"time"
+
+	"go.olrik.dev/overseer/internal/core"
 )
 
 // ParentMonitor monitors a process and triggers shutdown when it dies
 type ParentMonitor struct {
-	monitoredPID int // The PID to monitor (might not be our direct parent)
+	monitoredPID int           // The PID to monitor (might not be our direct parent)
+	pollInterval time.Duration // How often to check the monitored PID (0 = don't poll)
 	daemon       *Daemon
 	logger       *slog.Logger
+	alive        func(pid int) error // Checks that a process exists (replaceable in tests)
 }
 
 // NewParentMonitor creates a new parent process monitor
 // If OVERSEER_MONITOR_PID env var is set, monitors that PID (SSH session)
-// Otherwise monitors the daemon's actual parent PID
+// Otherwise monitors the daemon's actual parent PID. The PID is polled as often
+// as remote.parent_poll_interval says.
 func NewParentMonitor(daemon *Daemon) *ParentMonitor {
 	// Check if we should monitor a specific PID (set by 'overseer start')
 	monitorPID := os.Getppid() // Default to actual parent
@@ -35,8 +40,13 @@ func NewParentMonitor(daemon *Daemon) *ParentMonitor {
 
 	return &ParentMonitor{
 		monitoredPID: monitorPID,
+		pollInterval: core.Config.Remote.ParentPollInterval,
 		daemon:       daemon,
 		logger:       slog.Default(),
+		alive: func(pid int) error {
+			// Kill with signal 0 doesn't send a signal, just checks if the process exists
+			return syscall.Kill(pid, 0)
+		},
 	}
 }
 
@@ -65,12 +75,17 @@ func (pm *ParentMonitor) Start(ctx context.Context) {
 
 	// Layer 3: Process existence polling - works for any PID
 	// This catches cases where SIGHUP doesn't work (nohup, screen, tmux, etc.)
+	if pm.pollInterval <= 0 {
+		pm.logger.Info("Parent process polling disabled, relying on signals only",
+			"monitor_pid", pm.monitoredPID)
+		return
+	}
 	go pm.pollParentStatus(ctx)
 }
 
 // pollParentStatus periodically checks if the monitored process is still alive
 func (pm *ParentMonitor) pollParentStatus(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(pm.pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -81,8 +96,7 @@ func (pm *ParentMonitor) pollParentStatus(ctx context.Context) {
 
 		case <-ticker.C:
 			// Check if the monitored process still exists
-			// syscall.Kill(pid, 0) doesn't send a signal, just checks if process exists
-			err := syscall.Kill(pm.monitoredPID, 0)
+			err := pm.alive(pm.monitoredPID)
 
 			if err != nil {
 				// Process doesn't exist or we can't signal it
@@ -3,10 +3,16 @@ package daemon
 import (
 	"context"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/db"
 )
 
 func TestMain(m *testing.M) {
@@ -128,3 +134,109 @@ func TestParentMonitorStartContextCancellation(t *testing.T) {
 
 	// If we get here without hanging, the test passes
 }
+
+// TestParentMonitorPollInterval verifies the monitored PID is checked as often
+// as configured, and not at all when polling is disabled
+func TestParentMonitorPollInterval(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		minChecks int32
+		maxChecks int32
+	}{
+		{"configured interval", 20 * time.Millisecond, 5, 15},
+		{"disabled", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core.Config = core.GetDefaultConfig()
+			core.Config.Remote.ParentPollInterval = tt.interval
+
+			monitor := NewParentMonitor(New())
+			if monitor.pollInterval != tt.interval {
+				t.Fatalf("pollInterval = %v, want %v", monitor.pollInterval, tt.interval)
+			}
+
+			var checks atomic.Int32
+			monitor.alive = func(pid int) error {
+				checks.Add(1)
+				return nil
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			monitor.Start(ctx)
+			time.Sleep(250 * time.Millisecond)
+			cancel()
+
+			if got := checks.Load(); got < tt.minChecks || got > tt.maxChecks {
+				t.Errorf("monitored PID checked %d times in 250ms, want %d-%d", got, tt.minChecks, tt.maxChecks)
+			}
+		})
+	}
+}
+
+func TestRemoteShutdownSignals(t *testing.T) {
+	quietLogger(t)
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	d := New()
+	d.database = database
+	d.isRemote = true
+
+	// Register the configured signals like Run() does and deliver one
+	shutdownSignals := parseShutdownSignals([]string{"QUIT", "USR2"})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, shutdownSignals...)
+	t.Cleanup(func() { signal.Stop(sigChan) })
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("failed to send signal: %v", err)
+	}
+	var sig os.Signal
+	select {
+	case sig = <-sigChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("configured signal was not delivered")
+	}
+
+	if !d.remoteShutdownRequested(sig, shutdownSignals) {
+		t.Errorf("expected %s to shut down the remote daemon", sig)
+	}
+	events, err := database.GetRecentDaemonEvents(1)
+	if err != nil || len(events) != 1 || events[0].EventType != "ssh_disconnect" {
+		t.Errorf("expected an ssh_disconnect event, got %+v (err: %v)", events, err)
+	}
+
+	// SIGHUP is caught but ignored when it isn't configured
+	if d.remoteShutdownRequested(syscall.SIGHUP, shutdownSignals) {
+		t.Error("expected SIGHUP to be ignored when not in shutdown_signals")
+	}
+
+	// Outside remote mode nothing shuts the daemon down
+	d.isRemote = false
+	if d.remoteShutdownRequested(syscall.SIGUSR2, shutdownSignals) {
+		t.Error("expected signals to be ignored outside remote mode")
+	}
+}
+
+// TestParseShutdownSignals_RejectsPipe guards against notifying SIGPIPE,
+// which every write to a dropped client connection would raise
+func TestParseShutdownSignals_RejectsPipe(t *testing.T) {
+	quietLogger(t)
+
+	got := parseShutdownSignals([]string{"HUP", "PIPE", "USR2"})
+	want := []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseShutdownSignals() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/keyring"
+)
+
+// passwordCommandTimeout is how long a tunnel's password_command may run
+const passwordCommandTimeout = 30 * time.Second
+
+// tunnelPasswordCommand returns the password_command of alias ("" if none)
+func tunnelPasswordCommand(alias string) string {
+	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil {
+		return tunnelConfig.PasswordCommand
+	}
+	return ""
+}
+
+// tunnelHasPassword reports whether overseer answers password prompts for
+// alias, either by running its password_command or with a password stored in
+// the keyring. Having both is an error, as it's unclear which one is meant.
+func tunnelHasPassword(alias string) (bool, error) {
+	stored := keyring.HasPassword(alias)
+	if tunnelPasswordCommand(alias) == "" {
+		return stored, nil
+	}
+	if stored {
+		return false, fmt.Errorf("tunnel '%s' has a password_command and a password stored in the keyring, remove one of them (overseer password delete %s)", alias, alias)
+	}
+	return true, nil
+}
+
+// tunnelPassword returns the password of alias from its password_command, or
+// from the keyring when it has none
+func tunnelPassword(ctx context.Context, alias string) (string, error) {
+	if command := tunnelPasswordCommand(alias); command != "" {
+		return runPasswordCommand(ctx, alias, command)
+	}
+	return keyring.GetPassword(alias)
+}
+
+// runPasswordCommand runs a password_command through the shell and returns
+// its output with surrounding whitespace trimmed
+func runPasswordCommand(ctx context.Context, alias, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, passwordCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), "OVERSEER_TUNNEL_ALIAS="+alias)
+	// Run in its own process group so a timeout kills the whole pipeline
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("password_command timed out after %s", passwordCommandTimeout)
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("password_command failed: %w: %s", err, message)
+		}
+		return "", fmt.Errorf("password_command failed: %w", err)
+	}
+
+	password := strings.TrimSpace(string(output))
+	if password == "" {
+		return "", fmt.Errorf("password_command printed no password")
+	}
+	return password, nil
+}
@@ -46,6 +46,12 @@ func (d *Daemon) reportConnectFailure(alias string, env map[string]string, sshEr
 	} else {
 		emit(fmt.Sprintf("Tunnel '%s' failed to connect: %v", alias, sshErr), "ERROR")
 	}
+
+	// A failure not recognised in SSH's output may be down to the client
+	var failure *sshFailure
+	if d.sshVersion.Raw != "" && !d.sshVersion.compatible() && !errors.As(sshErr, &failure) {
+		emit(fmt.Sprintf("SSH client %s is not OpenSSH, its output may not be recognised", d.sshVersion), "WARN")
+	}
 }
 
 // emitToUserLog sends a message to the user-facing log stream (visible via
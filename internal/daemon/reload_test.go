@@ -2,10 +2,18 @@ package daemon
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"go.olrik.dev/overseer/internal/core"
 )
 
@@ -54,6 +62,127 @@ func TestReloadConfig_ValidConfig(t *testing.T) {
 	}
 }
 
+func TestReloadConfig_VerboseOverrideWins(t *testing.T) {
+	quietLogger(t)
+
+	tmpDir := t.TempDir()
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: tmpDir,
+		Verbose:    1,
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+
+	d := &Daemon{
+		tunnels: make(map[string]Tunnel),
+	}
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	d.companionMgr = NewCompanionManager()
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+
+	// Config alone drops debug output
+	d.applyVerbosity()
+	if got := d.logLevel.Level(); got != slog.LevelInfo {
+		t.Fatalf("expected config verbose=1 to give %v, got %v", slog.LevelInfo, got)
+	}
+
+	// The command line override wins over config
+	d.SetVerboseOverride(2)
+	d.applyVerbosity()
+	if got := d.logLevel.Level(); got != slog.LevelDebug {
+		t.Fatalf("expected override to give %v, got %v", slog.LevelDebug, got)
+	}
+
+	// ...and is not clobbered by reloading a config that sets verbose
+	configContent := `verbose = 1
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.hcl"), []byte(configContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.reloadConfig(); err != nil {
+		t.Fatalf("reloadConfig failed: %v", err)
+	}
+	if core.Config.Verbose != 1 {
+		t.Fatalf("expected reloaded config verbose=1, got %d", core.Config.Verbose)
+	}
+	if got := d.logLevel.Level(); got != slog.LevelDebug {
+		t.Errorf("expected override to survive reload with %v, got %v", slog.LevelDebug, got)
+	}
+}
+
+func TestReloadConfig_RunsReloadHooks(t *testing.T) {
+	quietLogger(t)
+
+	tmpDir := t.TempDir()
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: tmpDir,
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+
+	d := &Daemon{
+		tunnels: make(map[string]Tunnel),
+	}
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	t.Cleanup(d.cancelFunc)
+	d.companionMgr = NewCompanionManager()
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+
+	// The reloaded config adds a fallback context, which the hook should see
+	hookOutput := filepath.Join(tmpDir, "reload-context")
+	configContent := fmt.Sprintf(`context "anywhere" {
+  display_name = "Anywhere"
+}
+
+context_hooks {
+  on_reload = ["echo \"$OVERSEER_CONTEXT\" > %s"]
+}
+`, hookOutput)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.hcl"), []byte(configContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.reloadConfig(); err != nil {
+		t.Fatalf("reloadConfig failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(hookOutput)
+		if err == nil && strings.TrimSpace(string(data)) == "anywhere" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the on_reload hook to run with OVERSEER_CONTEXT=anywhere, got %q (err: %v)", data, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
 func TestReloadConfig_InvalidConfig(t *testing.T) {
 	quietLogger(t)
 
@@ -84,6 +213,74 @@ func TestReloadConfig_InvalidConfig(t *testing.T) {
 	}
 }
 
+func TestReloadConfig_RecordsReloadStatus(t *testing.T) {
+	quietLogger(t)
+
+	tmpDir := t.TempDir()
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: tmpDir,
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+
+	d := &Daemon{
+		tunnels: make(map[string]Tunnel),
+	}
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	d.companionMgr = NewCompanionManager()
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+
+	if response := d.getReloadStatus(); response.Data != nil {
+		t.Errorf("expected no reload status before the first reload, got %+v", response.Data)
+	}
+
+	configFile := filepath.Join(tmpDir, "config.hcl")
+	if err := os.WriteFile(configFile, []byte("companion {\n  history_size = \n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.reloadConfig(); err == nil {
+		t.Fatal("expected error for invalid config")
+	}
+
+	status, ok := d.getReloadStatus().Data.(ReloadStatus)
+	if !ok {
+		t.Fatal("expected a reload status after a failed reload")
+	}
+	if status.Success || status.Error == "" {
+		t.Errorf("expected a failed reload with an error, got %+v", status)
+	}
+	if status.File != configFile || status.Line != 2 {
+		t.Errorf("expected the error at %s line 2, got %s line %d", configFile, status.File, status.Line)
+	}
+	if _, err := time.Parse(time.RFC3339, status.Time); err != nil {
+		t.Errorf("expected an RFC 3339 time, got %q", status.Time)
+	}
+
+	// Fixing the file replaces the failure
+	if err := os.WriteFile(configFile, []byte("companion {\n  history_size = 50\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.reloadConfig(); err != nil {
+		t.Fatalf("reloadConfig failed: %v", err)
+	}
+	status = d.getReloadStatus().Data.(ReloadStatus)
+	if !status.Success || status.Error != "" {
+		t.Errorf("expected a successful reload, got %+v", status)
+	}
+}
+
 func TestReloadConfig_MissingConfig(t *testing.T) {
 	quietLogger(t)
 
@@ -110,6 +307,114 @@ func TestReloadConfig_MissingConfig(t *testing.T) {
 	}
 }
 
+func TestHandleReloadSignal(t *testing.T) {
+	quietLogger(t)
+
+	tmpDir := t.TempDir()
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: tmpDir,
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+
+	d := &Daemon{
+		tunnels: make(map[string]Tunnel),
+	}
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	d.companionMgr = NewCompanionManager()
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+
+	configContent := `companion {
+  history_size = 77
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.hcl"), []byte(configContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d.handleReloadSignal(syscall.SIGUSR1)
+
+	if core.Config.Companion.HistorySize != 77 {
+		t.Errorf("HistorySize = %d, want 77 (config was not reloaded)", core.Config.Companion.HistorySize)
+	}
+	if core.Config.ConfigPath != tmpDir {
+		t.Errorf("ConfigPath = %q, want %q", core.Config.ConfigPath, tmpDir)
+	}
+
+	// An invalid config keeps the previous one
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.hcl"), []byte("{{{invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d.handleReloadSignal(syscall.SIGUSR1)
+
+	if core.Config.Companion.HistorySize != 77 {
+		t.Errorf("HistorySize = %d, want 77 (previous config should be kept)", core.Config.Companion.HistorySize)
+	}
+}
+
+func TestReloadConfig_ConcurrentTriggers(t *testing.T) {
+	quietLogger(t)
+
+	tmpDir := t.TempDir()
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: tmpDir,
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+
+	d := &Daemon{
+		tunnels: make(map[string]Tunnel),
+	}
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	d.companionMgr = NewCompanionManager()
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+
+	configContent := `companion {
+  history_size = 77
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.hcl"), []byte(configContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A SIGUSR1 arriving while the file watcher reloads; run with -race
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() { d.handleReloadSignal(syscall.SIGUSR1) })
+		wg.Go(func() { d.reloadConfig() })
+	}
+	wg.Wait()
+
+	if core.Config.Companion.HistorySize != 77 {
+		t.Errorf("HistorySize = %d, want 77", core.Config.Companion.HistorySize)
+	}
+}
+
 func TestCheckOnlineStatusNew_NilOrchestrator(t *testing.T) {
 	old := stateOrchestrator
 	t.Cleanup(func() { stateOrchestrator = old })
@@ -155,3 +460,55 @@ func TestCheckOnlineStatusNew_WithOrchestrator(t *testing.T) {
 	// Should not panic with orchestrator initialized
 	_ = d.checkOnlineStatusNew()
 }
+
+func TestConfigWatch_WatchIncludes(t *testing.T) {
+	quietLogger(t)
+
+	tmpDir := t.TempDir()
+	secrets := filepath.Join(tmpDir, "secrets.hcl")
+	shared := filepath.Join(tmpDir, "shared.hcl")
+	for _, file := range []string{secrets, shared} {
+		if err := os.WriteFile(file, []byte("verbose = 1\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	w := &configWatch{watcher: watcher, includes: make(map[string]bool)}
+
+	w.watchIncludes([]string{secrets, shared})
+	if got := watcher.WatchList(); !slices.Contains(got, secrets) || !slices.Contains(got, shared) {
+		t.Fatalf("expected both included files to be watched, got %v", got)
+	}
+
+	// Changing an included file is noticed
+	if err := os.WriteFile(secrets, []byte("verbose = 2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-watcher.Events:
+		if event.Name != secrets {
+			t.Errorf("expected an event for %s, got %v", secrets, event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an event when an included file changes")
+	}
+
+	// An atomic save re-adds the included file, anything else the main file
+	if got := w.watched(secrets, "config.hcl"); got != secrets {
+		t.Errorf("watched(%s) = %s, want the included file", secrets, got)
+	}
+	if got := w.watched(filepath.Join(tmpDir, "other.hcl"), "config.hcl"); got != "config.hcl" {
+		t.Errorf("watched(other.hcl) = %s, want the main config file", got)
+	}
+
+	// A file no longer included is no longer watched
+	w.watchIncludes([]string{shared})
+	if got := watcher.WatchList(); slices.Contains(got, secrets) || !slices.Contains(got, shared) {
+		t.Errorf("expected only %s to be watched, got %v", shared, got)
+	}
+}
@@ -0,0 +1,39 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Request is a command sent to the daemon as a single JSON object, e.g.
+// {"command":"STATUS","args":[]}. It is an alternative to the text form
+// "COMMAND arg1 arg2" that keeps arguments containing spaces intact. Daemons
+// that accept it list "json" in the request_formats reported by HELLO.
+type Request struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// parseRequest returns the command and arguments of the first line sent on a
+// connection, either a JSON Request or a text command. An empty line yields
+// an empty command.
+func parseRequest(line string) (command string, args []string, err error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		parts := strings.Fields(line)
+		if len(parts) == 0 {
+			return "", nil, nil
+		}
+		return parts[0], parts[1:], nil
+	}
+
+	var request Request
+	if err := json.Unmarshal([]byte(line), &request); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON request: %w", err)
+	}
+	if request.Command == "" || strings.ContainsFunc(request.Command, func(r rune) bool { return r == ' ' || r == '\t' }) {
+		return "", nil, fmt.Errorf("invalid JSON request: command must be a single word, got %q", request.Command)
+	}
+	return request.Command, request.Args, nil
+}
@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantCommand string
+		wantArgs    []string
+		wantErr     bool
+	}{
+		{"text command", "STATUS", "STATUS", []string{}, false},
+		{"text command with args", "SSH_CONNECT db --force", "SSH_CONNECT", []string{"db", "--force"}, false},
+		{"empty line", "  ", "", nil, false},
+		{"json command", `{"command":"STATUS"}`, "STATUS", nil, false},
+		{"json args keep spaces", `{"command":"SSH_CONNECT","args":["db","--env=GREETING=hello world"]}`, "SSH_CONNECT", []string{"db", "--env=GREETING=hello world"}, false},
+		{"json with surrounding whitespace", ` {"command":"VERSION"} `, "VERSION", nil, false},
+		{"malformed json", `{"command":`, "", nil, true},
+		{"json without command", `{"args":["db"]}`, "", nil, true},
+		{"json command with spaces", `{"command":"SSH_CONNECT db"}`, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, args, err := parseRequest(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if command != tt.wantCommand || !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("parseRequest() = %q %q, want %q %q", command, args, tt.wantCommand, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 type Response struct {
 	Messages []ResponseMessage `json:"messages"`
 	Data     interface{}       `json:"data,omitempty"`
+	Daemon   *DaemonInfo       `json:"daemon,omitempty"` // Daemon metadata (STATUS only)
 }
 
 type ResponseMessage struct {
@@ -27,11 +27,12 @@ func GetSensorStatePath() string {
 
 // SaveSensorState saves the current sensor cache to disk for hot reload
 func SaveSensorState() error {
-	if stateOrchestrator == nil {
+	orch := GetStateOrchestrator()
+	if orch == nil {
 		return nil // Nothing to save
 	}
 
-	sensors := stateOrchestrator.GetSensorCache()
+	sensors := orch.GetSensorCache()
 	if len(sensors) == 0 {
 		return nil // Nothing to save
 	}
@@ -3,14 +3,16 @@ package daemon
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net"
 	"os"
 	"os/exec"
 	"os/signal"
-	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"regexp"
@@ -31,7 +33,8 @@ import (
 // Daemon manages the SSH tunnel processes and security context.
 type Daemon struct {
 	tunnels       map[string]Tunnel
-	askpassTokens map[string]string // Maps token -> alias for validation
+	askpassTokens map[string]string      // Maps token -> alias for validation
+	askpassOTPs   map[string]*askpassOTP // Maps token -> one-time password, answered once
 	mu            sync.Mutex
 	listener      net.Listener
 	shutdownOnce  sync.Once
@@ -43,6 +46,35 @@ type Daemon struct {
 	ctx           context.Context   // Context for lifecycle management
 	cancelFunc    context.CancelFunc
 	sshConfigFile string // Path to SSH config file (empty = use system default)
+	startTime     time.Time // When Run() was called (reported in STATUS)
+	logLevel      slog.LevelVar // Minimum level of the daemon logger
+	verbose       int           // Verbosity from the command line (0 = use config)
+	foreground    bool          // Run for a service manager, see SetForeground
+	logFormat     string        // Format of the foreground log output ("text" or "json")
+	logOutput     io.Writer     // Where foreground logs are written (nil = stderr)
+	stopStateLogs func()        // Ends the stream of state events to the foreground log output
+
+	manuallyStopped map[string]string // Tunnels disconnected by the user -> context they were stopped in
+
+	sensorsSuppressedAt time.Time // Last time the sensor watchdog found probes suppressed by sleep
+
+	tunnelEvents *tunnelEventThrottle // Coalesces events of flapping tunnels (nil = log every event)
+
+	sshVersion sshClientVersion // Version of the ssh client, detected on start
+
+	traffic map[string]*trafficCounter // Bytes transferred per tunnel, see sampleTraffic
+
+	lastReload *ReloadStatus // Result of the last configuration reload (nil = none since start)
+
+	backoffHistograms map[string]*histogram // Reconnect backoff delays per tunnel, see observeBackoff
+
+	companionRestarts map[string]*companionRestart // Restarts after companion failures per tunnel, see restartTunnelForCompanion
+
+	monitors sync.WaitGroup // Running monitorTunnel goroutines
+
+	reloadMu sync.Mutex // Serializes configuration reloads, whatever triggered them
+
+	configWatch *configWatch // Watches the config files (nil = not watching), see watchConfig
 }
 
 type TunnelState string
@@ -54,6 +86,33 @@ const (
 	StateReconnecting TunnelState = "reconnecting"
 )
 
+// Reasons recorded with tunnel events to explain what triggered them
+const (
+	ReasonManual    = "manual"    // Requested by the user via the CLI
+	ReasonReconnect = "reconnect" // Automatic reconnect after the tunnel failed
+	ReasonShutdown  = "shutdown"  // Daemon shutdown
+	ReasonCompanion = "companion" // A companion with on_failure = "restart_tunnel" failed
+	ReasonStartup   = "startup"   // Tunnel has connect_on_startup set
+)
+
+// ContextReason returns the reason for actions taken because a context was entered
+func ContextReason(context string) string {
+	return "context:" + context
+}
+
+// stopEventType returns the event type recorded when a tunnel is stopped for reason
+func stopEventType(reason string) string {
+	switch {
+	case reason == ReasonManual:
+		return "manual_disconnect"
+	case reason == ReasonCompanion:
+		return "companion_disconnect"
+	case strings.HasPrefix(reason, ContextReason("")):
+		return "context_disconnect"
+	}
+	return "disconnect"
+}
+
 type Tunnel struct {
 	Hostname            string
 	Pid                 int
@@ -72,24 +131,34 @@ type Tunnel struct {
 	HealthCheckFailures int         // Consecutive health check failures (requires multiple before killing)
 	ResolvedHost        string      // Actual IP:port from SSH "Authenticated to" output
 	JumpChain           []string    // All resolved IP:port hops in order (jump hosts first, destination last)
+	SSHOverrides        *core.SSHOverrides // SSH overrides from the context that connected the tunnel (kept for reconnects)
+	Process             *sshProcess        // Background waiter for Cmd (nil for adopted tunnels)
+	ConnectReason       string             // What triggered the connection (manual, reconnect, context:<name>)
 }
 
 func New() *Daemon {
 	ctx, cancel := context.WithCancel(context.Background())
 	d := &Daemon{
-		tunnels:       make(map[string]Tunnel),
-		askpassTokens: make(map[string]string),
-		logBroadcast:  NewLogBroadcaster(core.Config.Companion.HistorySize),
-		companionMgr:  NewCompanionManager(),
-		ctx:           ctx,
-		cancelFunc:    cancel,
-	}
+		tunnels:           make(map[string]Tunnel),
+		askpassTokens:     make(map[string]string),
+		askpassOTPs:       make(map[string]*askpassOTP),
+		manuallyStopped:   make(map[string]string),
+		traffic:           make(map[string]*trafficCounter),
+		backoffHistograms: make(map[string]*histogram),
+		companionRestarts: make(map[string]*companionRestart),
+		logBroadcast:      NewLogBroadcaster(core.Config.Companion.HistorySize),
+		companionMgr:      NewCompanionManager(),
+		ctx:               ctx,
+		cancelFunc:        cancel,
+	}
+	d.tunnelEvents = newTunnelEventThrottle(d.writeTunnelEvent)
 	// Set token registrar so companions can register tokens for validation
 	d.companionMgr.SetTokenRegistrar(func(token, alias string) {
 		d.mu.Lock()
 		d.askpassTokens[token] = alias
 		d.mu.Unlock()
 	})
+	d.companionMgr.SetTunnelRestarter(d.restartTunnelForCompanion)
 	return d
 }
 
@@ -99,6 +168,33 @@ func (d *Daemon) SetSSHConfigFile(path string) {
 	d.sshConfigFile = path
 }
 
+// SetVerboseOverride sets a verbosity level that takes precedence over the
+// config's verbose setting, including after config reloads. 0 disables it.
+func (d *Daemon) SetVerboseOverride(level int) {
+	d.verbose = level
+}
+
+// Log formats of the foreground log output
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// ErrDaemonAlreadyRunning is returned by Run when another daemon owns the socket
+var ErrDaemonAlreadyRunning = errors.New("daemon is already running")
+
+// SetForeground makes the daemon write its logs, combined with the state
+// events shown by `overseer logs`, to stderr in format and without colors,
+// for service managers and containers that collect the output.
+func (d *Daemon) SetForeground(format string) error {
+	if format != LogFormatText && format != LogFormatJSON {
+		return fmt.Errorf("invalid log format %q (must be %q or %q)", format, LogFormatText, LogFormatJSON)
+	}
+	d.foreground = true
+	d.logFormat = format
+	return nil
+}
+
 // mergeEnvironment merges user environment variables into default environment
 // User variables take precedence over defaults
 func mergeEnvironment(defaultEnv, userEnv map[string]string) map[string]string {
@@ -163,6 +259,25 @@ func mergeRule(defaultRule, userRule awareness.Rule) awareness.Rule {
 	return merged
 }
 
+// resetRetriesIfStable starts the retry count of a tunnel that just dropped
+// over when it had been connected for at least stable_reset, so a long-lived
+// tunnel doesn't inherit the escalation of reconnects long past.
+func resetRetriesIfStable(alias string, tunnel *Tunnel) {
+	stableReset := core.Config.SSH.StableReset
+	if tunnel.RetryCount == 0 || stableReset == 0 || tunnel.LastConnectedTime.IsZero() {
+		return
+	}
+	connectedFor := tunnel.DisconnectedTime.Sub(tunnel.LastConnectedTime)
+	if connectedFor < stableReset {
+		return
+	}
+	slog.Info("Resetting retry count after a stable connection",
+		"tunnel", alias,
+		"connected_for", connectedFor.Round(time.Second),
+		"previous_retry_count", tunnel.RetryCount)
+	tunnel.RetryCount = 0
+}
+
 // calculateBackoff calculates the exponential backoff duration
 func calculateBackoff(retryCount int) time.Duration {
 	// Parse config values
@@ -200,11 +315,38 @@ func calculateBackoff(retryCount int) time.Duration {
 	return backoff
 }
 
-// Run starts the daemon's main loop.
-func (d *Daemon) Run() {
+// Run starts the daemon's main loop. It returns an error when the daemon
+// can't be started, e.g. because its socket can't be created.
+func (d *Daemon) Run() error {
+	d.startTime = time.Now()
+
 	// Setup custom logger that broadcasts to connected clients
 	d.setupLogging()
 
+	// Surface likely configuration mistakes once, they aren't fatal
+	if core.Config != nil {
+		for _, warning := range core.Config.Warnings {
+			slog.Warn(fmt.Sprintf("Configuration warning: %s", warning))
+		}
+	}
+
+	// Setup PID and socket files and ensure they are cleaned up on exit.
+	// The socket comes first, so nothing is started when it can't be created.
+	socketPath := core.GetSocketPath()
+	pidFilePath := core.GetPIDFilePath()
+
+	listener, err := listenSocket(socketPath)
+	if err != nil {
+		return err
+	}
+
+	os.WriteFile(pidFilePath, []byte(strconv.Itoa(os.Getpid())), 0o644)
+	defer os.Remove(pidFilePath)
+	defer os.Remove(socketPath)
+
+	d.listener = listener
+	slog.Info(fmt.Sprintf("Daemon listening on %s", socketPath))
+
 	// Check if running in remote mode (via SSH)
 	d.isRemote = os.Getenv("SSH_CONNECTION") != ""
 	if d.isRemote {
@@ -224,7 +366,7 @@ func (d *Daemon) Run() {
 	}
 
 	// Initialize database
-	dbPath := filepath.Join(core.Config.ConfigPath, "overseer.db")
+	dbPath := core.GetDatabasePath()
 	database, err := db.Open(dbPath)
 	if err != nil {
 		slog.Error("Failed to open database", "error", err, "path", dbPath)
@@ -246,44 +388,8 @@ func (d *Daemon) Run() {
 		})
 	}
 
-	// Setup PID and socket files and ensure they are cleaned up on exit.
-	socketPath := core.GetSocketPath()
-	pidFilePath := core.GetPIDFilePath()
-
-	// Try to create the socket listener
-	listener, err := net.Listen("unix", socketPath)
-	if err != nil {
-		// Socket creation failed - this could be due to a stale socket file
-		if _, statErr := os.Stat(socketPath); statErr == nil {
-			// Socket file exists, try to connect to it to see if daemon is actually running
-			conn, dialErr := net.Dial("unix", socketPath)
-			if dialErr == nil {
-				// Successfully connected, daemon is running
-				conn.Close()
-				slog.Error("Fatal: Daemon is already running")
-				os.Exit(1)
-			}
-			// Connection failed, socket file is stale - remove it
-			slog.Info(fmt.Sprintf("Removing stale socket file: %s", socketPath))
-			if removeErr := os.Remove(socketPath); removeErr != nil {
-				slog.Error(fmt.Sprintf("Fatal: Could not remove stale socket: %v", removeErr))
-				os.Exit(1)
-			}
-			// Try to create listener again
-			listener, err = net.Listen("unix", socketPath)
-		}
-		if err != nil {
-			slog.Error(fmt.Sprintf("Fatal: Could not create socket listener: %v", err))
-			os.Exit(1)
-		}
-	}
-
-	os.WriteFile(pidFilePath, []byte(strconv.Itoa(os.Getpid())), 0o644)
-	defer os.Remove(pidFilePath)
-	defer os.Remove(socketPath)
-
-	d.listener = listener
-	slog.Info(fmt.Sprintf("Daemon listening on %s", socketPath))
+	// Connection verification depends on the ssh client's output
+	d.detectSSHVersion()
 
 	// Attempt to adopt existing tunnels from previous daemon (hot reload)
 	// IMPORTANT: This must happen BEFORE initializing security manager
@@ -297,6 +403,11 @@ func (d *Daemon) Run() {
 			"adopted_companions", adoptedCompanions)
 	}
 
+	// Remove companion sockets left behind by crashed daemons
+	if removed := d.companionMgr.CleanupStaleSockets(); removed > 0 {
+		slog.Info("Removed stale companion sockets", "count", removed)
+	}
+
 	// Clean up orphan SSH processes from previous daemon instances
 	// This handles cases where:
 	// - Previous daemon was killed without graceful shutdown
@@ -314,17 +425,37 @@ func (d *Daemon) Run() {
 		slog.Info("State orchestrator started")
 	}
 
+	// Connect the tunnels that should be up regardless of context
+	d.startStartupTunnels()
+
 	// Start periodic health check loop for SSH tunnels
 	d.startHealthCheckLoop()
 
+	// Start periodic reassertion of the active context's tunnels
+	d.startReassertLoop()
+
+	// Sample the bytes transferred by each tunnel where the platform supports it
+	d.startTrafficSampler()
+
+	// Restart the state manager if it stops evaluating sensor readings
+	d.startSensorWatchdog()
+
 	// Watch config file for changes
 	d.watchConfig()
 
-	// Handle signals
+	// Handle signals. SIGHUP is always caught, so it doesn't kill a daemon
+	// that isn't in remote mode. The shutdown signals are registered once,
+	// a reload doesn't change them.
 	shutdownChan := make(chan os.Signal, 1)
 	hupChan := make(chan os.Signal, 1)
+	remoteShutdownSignals := parseShutdownSignals(core.Config.Remote.ShutdownSignals)
 	signal.Notify(shutdownChan, syscall.SIGTERM, syscall.SIGINT)
-	signal.Notify(hupChan, syscall.SIGHUP)
+	signal.Notify(hupChan, append([]os.Signal{syscall.SIGHUP}, remoteShutdownSignals...)...)
+
+	// Reload config on SIGUSR1 (SIGHUP is reserved for remote mode shutdown)
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGUSR1)
+	d.watchReloadSignal(reloadChan)
 
 	// Graceful shutdown on SIGTERM/SIGINT
 	go func() {
@@ -337,21 +468,16 @@ func (d *Daemon) Run() {
 		os.Exit(0)
 	}()
 
-	// Handle SIGHUP (SSH disconnect) in remote mode
+	// Handle the signals of an SSH disconnect in remote mode
 	go func() {
-		<-hupChan
-		if d.isRemote {
-			slog.Info("SIGHUP received in remote mode - SSH session disconnected. Shutting down.")
-			if d.database != nil {
-				d.database.LogDaemonEvent("ssh_disconnect", "SSH session ended, shutting down")
-			}
-			d.shutdown()
-			if d.listener != nil {
-				d.listener.Close()
+		for sig := range hupChan {
+			if d.remoteShutdownRequested(sig, remoteShutdownSignals) {
+				d.shutdown()
+				if d.listener != nil {
+					d.listener.Close()
+				}
+				os.Exit(0)
 			}
-			os.Exit(0)
-		} else {
-			slog.Info("SIGHUP received (ignored - not in remote mode)")
 		}
 	}()
 
@@ -366,6 +492,82 @@ func (d *Daemon) Run() {
 		}
 		go d.handleConnection(conn)
 	}
+	return nil
+}
+
+// listenSocket creates the daemon's socket listener, replacing a stale socket
+// file left behind by a daemon that is no longer running
+func listenSocket(socketPath string) (net.Listener, error) {
+	listener, err := net.Listen("unix", socketPath)
+	if err == nil {
+		return listener, nil
+	}
+
+	// Socket creation failed - this could be due to a stale socket file
+	if _, statErr := os.Stat(socketPath); statErr == nil {
+		// Socket file exists, try to connect to it to see if daemon is actually running
+		conn, dialErr := net.Dial("unix", socketPath)
+		if dialErr == nil {
+			// Successfully connected, daemon is running
+			conn.Close()
+			return nil, ErrDaemonAlreadyRunning
+		}
+		// Connection failed, socket file is stale - remove it
+		slog.Info(fmt.Sprintf("Removing stale socket file: %s", socketPath))
+		if removeErr := os.Remove(socketPath); removeErr != nil {
+			return nil, fmt.Errorf("could not remove stale socket: %w", removeErr)
+		}
+		// Try to create listener again
+		listener, err = net.Listen("unix", socketPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create socket listener: %w", err)
+	}
+	return listener, nil
+}
+
+// shutdownSignalsByName maps the names allowed in remote.shutdown_signals to
+// signals. SIGPIPE is left out on purpose: notified, it's delivered for every
+// write to a dropped client connection and would shut the daemon down.
+var shutdownSignalsByName = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"QUIT": syscall.SIGQUIT,
+	"USR2": syscall.SIGUSR2,
+}
+
+// parseShutdownSignals returns the signals named in remote.shutdown_signals,
+// leaving out the ones that may not shut the daemon down
+func parseShutdownSignals(names []string) []os.Signal {
+	var signals []os.Signal
+	for _, name := range names {
+		sig, ok := shutdownSignalsByName[name]
+		if !ok {
+			slog.Warn("Ignoring unsupported remote shutdown signal", "signal", name)
+			continue
+		}
+		signals = append(signals, sig)
+	}
+	return signals
+}
+
+// remoteShutdownRequested reports whether sig should shut the daemon down,
+// which is when it runs in remote mode and sig is one of shutdownSignals.
+// The SSH disconnect is recorded before the caller shuts down.
+func (d *Daemon) remoteShutdownRequested(sig os.Signal, shutdownSignals []os.Signal) bool {
+	if !d.isRemote {
+		slog.Info(fmt.Sprintf("%s received (ignored - not in remote mode)", sig))
+		return false
+	}
+	if !slices.Contains(shutdownSignals, sig) {
+		slog.Info(fmt.Sprintf("%s received (ignored - not in remote.shutdown_signals)", sig))
+		return false
+	}
+
+	slog.Info(fmt.Sprintf("%s received in remote mode - SSH session disconnected. Shutting down.", sig))
+	if d.database != nil {
+		d.database.LogDaemonEvent("ssh_disconnect", fmt.Sprintf("SSH session ended (%s), shutting down", sig))
+	}
+	return true
 }
 
 func (d *Daemon) handleConnection(conn net.Conn) {
@@ -376,14 +578,19 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 		return
 	}
 
-	parts := strings.Fields(scanner.Text())
-	if len(parts) == 0 {
+	command, args, err := parseRequest(scanner.Text())
+	if err != nil {
+		response := Response{}
+		response.AddMessage(err.Error(), "ERROR")
+		conn.Write([]byte(response.ToJSON()))
+		return
+	}
+	if command == "" {
 		return
 	}
-	command, args := parts[0], parts[1:]
 
-	// Log the command execution (skip VERSION as it's automatic, mask tokens in sensitive commands)
-	if command != "VERSION" {
+	// Log the command execution (skip VERSION and HELLO as they're automatic, mask tokens in sensitive commands)
+	if command != "VERSION" && command != "HELLO" {
 		logArgs := args
 		// Mask tokens in commands that contain sensitive auth data
 		switch command {
@@ -394,6 +601,17 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 				copy(logArgs, args)
 				logArgs[1] = "[MASKED]"
 			}
+		case "SSH_CONNECT":
+			// SSH_CONNECT <alias> [--otp=<code>] - mask the one-time password
+			for i, arg := range args {
+				if strings.HasPrefix(arg, "--otp=") {
+					if logArgs == nil {
+						logArgs = make([]string, len(args))
+						copy(logArgs, args)
+					}
+					logArgs[i] = "--otp=[MASKED]"
+				}
+			}
 		case "COMPANION_INIT":
 			// COMPANION_INIT <tunnel> <name> <token> - mask token at index 2
 			if len(args) >= 3 {
@@ -417,8 +635,9 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 			alias := args[0]
 			cliEnv := make(map[string]string)
 			force := false
+			otp := ""
 
-			// Parse optional flags: --env=KEY=VALUE, --force
+			// Parse optional flags: --env=KEY=VALUE, --force, --otp=CODE
 			for _, arg := range args[1:] {
 				switch {
 				case strings.HasPrefix(arg, "--env="):
@@ -428,20 +647,26 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 					}
 				case arg == "--force":
 					force = true
+				case strings.HasPrefix(arg, "--otp="):
+					otp = strings.TrimPrefix(arg, "--otp=")
 				}
 			}
 
 			// Use streaming to send progress messages as they occur
 			stream := NewStreamingResponse(conn)
-			response = d.startTunnelStreaming(alias, cliEnv, stream, force)
+			response = d.startTunnelStreaming(alias, cliEnv, stream, force, otp, nil, ReasonManual)
 		}
 	case "SSH_DISCONNECT":
 		if len(args) > 0 {
-			response = d.stopTunnel(args[0], false)
+			if slices.Contains(args[1:], "--force") {
+				response = d.killTunnel(args[0])
+			} else {
+				response = d.stopTunnel(args[0], false, ReasonManual)
+			}
 		}
 	case "SSH_DISCONNECT_ALL":
 		for alias := range d.tunnels {
-			stopResponse := d.stopTunnel(alias, false)
+			stopResponse := d.stopTunnel(alias, false, ReasonManual)
 			response.AddMessage(stopResponse.Messages[0].Message, stopResponse.Messages[0].Status)
 		}
 	case "SSH_RECONNECT":
@@ -471,14 +696,14 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 				stream.WriteMessage(fmt.Sprintf("Tunnel '%s' is not connected. Connecting...", alias), "WARN")
 			} else {
 				// Stop tunnel but preserve companions
-				stopResponse := d.stopTunnel(alias, true)
+				stopResponse := d.stopTunnel(alias, true, ReasonManual)
 				if len(stopResponse.Messages) > 0 && stopResponse.Messages[0].Status == "ERROR" {
 					response = stopResponse
 					break
 				}
 			}
 
-			response = d.startTunnelStreaming(alias, env, stream, force)
+			response = d.startTunnelStreaming(alias, env, stream, force, "", nil, ReasonManual)
 		}
 	case "RELOAD":
 		// Hot reload: save tunnel, companion, and sensor state before stopping
@@ -557,6 +782,8 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 		response = d.getStatus()
 	case "VERSION":
 		response = d.getVersion()
+	case "HELLO":
+		response = d.hello(args)
 	case "ASKPASS":
 		if len(args) >= 2 {
 			response = d.handleAskpass(args[0], args[1])
@@ -603,6 +830,16 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 		}
 		d.handleAttachWithHistory(conn, showHistory, historyLines)
 		return // Don't send JSON response
+	case "RELOAD_STATUS":
+		response = d.getReloadStatus()
+	case "METRICS":
+		response = d.getMetrics()
+	case "TUNNEL_CONFIG":
+		if len(args) >= 1 {
+			response = d.getTunnelConfig(args[0])
+		} else {
+			response.AddMessage("Usage: TUNNEL_CONFIG <alias>", "ERROR")
+		}
 	case "CONTEXT_STATUS":
 		// Parse optional event limit parameter (default: 20)
 		limit := 20
@@ -612,6 +849,8 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 			}
 		}
 		response = d.getContextStatus(limit)
+	case "SENSORS":
+		response = d.getSensors()
 	case "COMPANION_STATUS":
 		status := d.companionMgr.GetCompanionStatus()
 		response.Data = map[string]interface{}{"companions": status}
@@ -626,22 +865,41 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 		}
 	case "COMPANION_ATTACH":
 		if len(args) >= 2 {
-			// Parse optional lines count and no_history flag
-			historyLines := 20 // default
-			showHistory := true
-			if len(args) >= 3 {
-				if n, err := strconv.Atoi(args[2]); err == nil {
-					historyLines = n
-				}
-				// Check for no_history flag (in 3rd or 4th position)
-				if args[2] == "no_history" || (len(args) >= 4 && args[3] == "no_history") {
-					showHistory = false
-				}
-			}
-			d.companionMgr.HandleCompanionAttach(conn, args[0], args[1], showHistory, historyLines)
+			showHistory, historyLines, jsonLines := parseCompanionAttachArgs(args[2:])
+			d.companionMgr.HandleCompanionAttach(conn, args[0], args[1], showHistory, historyLines, jsonLines)
 			return // Don't send JSON response
 		}
-		response.AddMessage("Usage: COMPANION_ATTACH <tunnel> <name> [lines]", "ERROR")
+		response.AddMessage("Usage: COMPANION_ATTACH <tunnel> <name> [lines|all] [json]", "ERROR")
+	case "COMPANION_GREP":
+		if len(args) >= 3 {
+			// The pattern may have been split on whitespace
+			pattern := strings.Join(args[2:], " ")
+			if lines, err := d.companionMgr.GrepCompanionOutput(args[0], args[1], pattern); err != nil {
+				response.AddMessage(fmt.Sprintf("Failed to search companion output: %v", err), "ERROR")
+			} else {
+				response.AddData(map[string]interface{}{"lines": lines})
+				response.AddMessage(fmt.Sprintf("%d matching lines", len(lines)), "INFO")
+			}
+		} else {
+			response.AddMessage("Usage: COMPANION_GREP <tunnel> <name> <pattern>", "ERROR")
+		}
+	case "COMPANION_TAIL":
+		if len(args) >= 3 {
+			n, err := strconv.Atoi(args[2])
+			if args[2] == "all" {
+				n, err = HistoryAll, nil
+			}
+			if err != nil || n < 0 && n != HistoryAll {
+				response.AddMessage(fmt.Sprintf("Invalid line count: %s", args[2]), "ERROR")
+			} else if lines, err := d.companionMgr.TailCompanionOutput(args[0], args[1], n); err != nil {
+				response.AddMessage(fmt.Sprintf("Failed to read companion output: %v", err), "ERROR")
+			} else {
+				response.AddData(map[string]interface{}{"lines": lines})
+				response.AddMessage(fmt.Sprintf("%d lines", len(lines)), "INFO")
+			}
+		} else {
+			response.AddMessage("Usage: COMPANION_TAIL <tunnel> <name> <lines|all>", "ERROR")
+		}
 	case "COMPANION_START":
 		if len(args) >= 2 {
 			// Check if tunnel is running
@@ -697,7 +955,9 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 // non-overseer ssh/scp/rsync with ControlPersist) is handled: force=true
 // evicts it and proceeds; force=false reports it with a process tree and
 // fails, preserving any active user session.
-func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, stream *StreamingResponse, force bool) Response {
+// sshOverrides are applied on top of the global SSH settings (nil = use global).
+// reason is recorded with the tunnel's database events.
+func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, stream *StreamingResponse, force bool, otp string, sshOverrides *core.SSHOverrides, reason string) Response {
 	// Note: We cannot use defer d.mu.Unlock() here because we need to unlock
 	// early (before waiting for connection verification) and the function continues
 	// to execute afterward. Using defer would cause a double-unlock panic.
@@ -714,6 +974,23 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 		}
 	}
 
+	// An explicit connect lifts any earlier manual stop
+	if reason == ReasonManual {
+		d.clearManuallyStopped(alias)
+	}
+
+	if tunnelDisabled(alias) {
+		d.mu.Unlock()
+		sendMessage(fmt.Sprintf("Tunnel '%s' is disabled in the configuration.", alias), "ERROR")
+		return response
+	}
+
+	if otp != "" && core.Config.SSH.Askpass != "" {
+		d.mu.Unlock()
+		sendMessage("A one-time password can't be used together with a custom askpass helper.", "ERROR")
+		return response
+	}
+
 	if existingTunnel, exists := d.tunnels[alias]; exists {
 		// Check if the existing tunnel process is actually still alive
 		if d.checkTunnelHealth(alias, existingTunnel.Pid) {
@@ -751,27 +1028,34 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 		evictMuxMaster(alias, d.sshConfigFile)
 	}
 
-	// Start or restart companion scripts before establishing SSH tunnel
+	// Forward companion progress to the client as it happens
+	onProgress := func(p CompanionProgress) {
+		if p.IsError {
+			sendMessage(p.Message, "WARN")
+		} else {
+			sendMessage(p.Message, "INFO")
+		}
+	}
+
+	// Start or restart pre_connect companion scripts before establishing SSH tunnel
 	// Unlock mutex during companion startup since it may take time
+	restartCompanions := false
 	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil && len(tunnelConfig.Companions) > 0 {
 		d.mu.Unlock()
 
 		// Check if companions already exist (reconnect case)
 		if d.companionMgr.HasRunningCompanions(alias) {
 			// Reconnect case - restart existing companions in place to preserve attach connections
-			sendMessage("Restarting companion scripts...", "INFO")
-			if err := d.companionMgr.RestartCompanions(alias); err != nil {
-				sendMessage(fmt.Sprintf("Failed to restart companions: %v", err), "WARN")
+			restartCompanions = true
+			if len(companionsInPhase(tunnelConfig.Companions, "pre_connect")) > 0 {
+				sendMessage("Restarting companion scripts...", "INFO")
+				if err := d.companionMgr.RestartCompanions(alias, "pre_connect", onProgress); err != nil {
+					sendMessage(fmt.Sprintf("Failed to restart companions: %v", err), "WARN")
+				}
 			}
 		} else {
 			// Fresh start - start new companions
-			err := d.companionMgr.StartCompanions(alias, tunnelConfig.Companions, func(p CompanionProgress) {
-				if p.IsError {
-					sendMessage(p.Message, "WARN")
-				} else {
-					sendMessage(p.Message, "INFO")
-				}
-			})
+			err := d.companionMgr.StartCompanions(alias, companionsInPhase(tunnelConfig.Companions, "pre_connect"), onProgress)
 			if err != nil {
 				sendMessage(fmt.Sprintf("Companion script failed: %v", err), "ERROR")
 				return response
@@ -789,33 +1073,21 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 		d.executeTunnelHooks(alias, "before_connect", tunnelConfig.Hooks.BeforeConnect, StateConnecting)
 	}
 
-	// Check if a password is stored for this alias
-	hasPassword := keyring.HasPassword(alias)
-
-	// Merge environment variables: state-computed → tunnel config → CLI -E
-	mergedEnv := make(map[string]string)
-	if orch := GetStateOrchestrator(); orch != nil {
-		for k, v := range orch.BuildSSHEnv() {
-			mergedEnv[k] = v
-		}
-	} else {
-		for k, v := range core.Config.Environment {
-			mergedEnv[k] = v
-		}
-	}
-	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil {
-		for k, v := range tunnelConfig.Environment {
-			mergedEnv[k] = v
-		}
-	}
-	for k, v := range cliEnv {
-		mergedEnv[k] = v
+	// Check if a password is stored or comes from a password_command
+	hasPassword, err := tunnelHasPassword(alias)
+	if err != nil {
+		d.mu.Unlock()
+		sendMessage(err.Error(), "ERROR")
+		return response
 	}
 
+	mergedEnv := tunnelEnvironment(alias, cliEnv)
+
 	// Resolve ProxyJump chain from SSH config for multi-hop display
 	jumpChain := resolveJumpChain(alias, mergedEnv, d.sshConfigFile)
 
-	sshArgs := buildTunnelSSHArgs(alias, d.sshConfigFile, core.Config.SSH.ServerAliveInterval, core.Config.SSH.ServerAliveCountMax)
+	sshSettings := effectiveSSHSettings(sshOverrides)
+	sshArgs := buildTunnelSSHArgs(alias, d.sshConfigFile, sshSettings.ServerAliveInterval, sshSettings.ServerAliveCountMax, sshSettings.NoControlMaster)
 
 	cmd := exec.Command("ssh", sshArgs...)
 	cmd.Env = os.Environ()
@@ -831,31 +1103,32 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 	}
 
 	// Capture stderr to monitor connection status
-	stderrPipe, err := cmd.StderrPipe()
+	proc, err := newSSHProcess(cmd)
 	if err != nil {
 		d.mu.Unlock()
 		sendMessage(fmt.Sprintf("Failed to create stderr pipe: %v", err), "ERROR")
 		return response
 	}
 
-	var token string
-	if hasPassword {
-		// Configure SSH to use overseer binary as askpass helper
-		token, err = keyring.ConfigureSSHAskpass(cmd, alias)
-		if err != nil {
-			d.mu.Unlock()
-			sendMessage(fmt.Sprintf("Failed to configure askpass: %v", err), "ERROR")
-			return response
-		}
-
+	token, err := configureAskpass(cmd, alias, hasPassword || otp != "")
+	if err != nil {
+		d.mu.Unlock()
+		sendMessage(fmt.Sprintf("Failed to configure askpass: %v", err), "ERROR")
+		return response
+	}
+	if token != "" {
 		// Store token for validation when askpass command calls back
 		d.askpassTokens[token] = alias
+		if otp != "" {
+			d.askpassOTPs[token] = &askpassOTP{code: otp, afterPassword: hasPassword}
+		}
 	}
 
-	err = cmd.Start()
+	err = proc.start()
 	if err != nil {
 		if token != "" {
 			delete(d.askpassTokens, token)
+			delete(d.askpassOTPs, token)
 		}
 		d.mu.Unlock()
 		sendMessage(fmt.Sprintf("Failed to launch SSH process for '%s': %v", alias, err), "ERROR")
@@ -867,6 +1140,7 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 		Hostname:          alias,
 		Pid:               cmd.Process.Pid,
 		Cmd:               cmd,
+		Process:           proc,
 		StartDate:         now,
 		LastConnectedTime: now,
 		AskpassToken:      token,
@@ -875,6 +1149,8 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 		State:             StateConnecting,                  // Initial state is connecting, updated to connected after verification
 		Environment:       mergedEnv,                         // Store environment for reconnection
 		JumpChain:         jumpChain,
+		SSHOverrides:      sshOverrides,
+		ConnectReason:     reason,
 	}
 	slog.Info(fmt.Sprintf("Attempting to start tunnel for '%s' (PID %d)", alias, cmd.Process.Pid))
 
@@ -884,25 +1160,27 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 
 	// Wait for connection verification (indefinitely until success or failure)
 	connectionResult := make(chan error, 1)
-	go d.verifyConnection(stderrPipe, alias, connectionResult)
+	go d.verifyConnection(proc.Stderr(), alias, connectionResult)
 
-	// Wait for either success or failure - no timeout
-	err = <-connectionResult
+	// Wait for either success or failure - no timeout, but an SSH that
+	// exits before it is verified fails straight away
+	err = proc.awaitVerification(connectionResult)
 	if err != nil {
 		d.reportConnectFailure(alias, mergedEnv, err, sendMessage)
 
-		// Log to database
+		// Log to database, with the kind of failure as reason
 		if d.database != nil {
-			details := fmt.Sprintf("Failed: %v", err)
-			if dbErr := d.database.LogTunnelEvent(alias, "connect_failed", details); dbErr != nil {
+			details := "Failed: " + d.connectionFailureDetails(proc, err)
+			if dbErr := d.database.LogTunnelEventWithReason(alias, "connect_failed", details, sshFailureCategory(err)); dbErr != nil {
 				slog.Error("Failed to log tunnel connect failure", "error", dbErr)
 			}
 		}
 
-		// Clean up the failed tunnel
+		// Clean up the failed tunnel (SSH may already have exited on its own)
+		proc.kill()
 		d.mu.Lock()
+		delete(d.askpassOTPs, token)
 		if tunnel, exists := d.tunnels[alias]; exists {
-			tunnel.Cmd.Process.Kill()
 			if tunnel.AskpassToken != "" {
 				delete(d.askpassTokens, tunnel.AskpassToken)
 			}
@@ -911,7 +1189,7 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 		d.mu.Unlock()
 
 		// Stop companions (unless persistent)
-		d.companionMgr.StopCompanions(alias)
+		d.companionMgr.StopCompanions(alias, false)
 
 		return response
 	}
@@ -919,8 +1197,10 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 	// Log success in daemon
 	slog.Info(fmt.Sprintf("Tunnel '%s' connected successfully (PID %d)", alias, cmd.Process.Pid))
 
-	// Update state to connected now that verification passed
+	// Update state to connected now that verification passed. An unused
+	// one-time password has no further purpose once authentication is done.
 	d.mu.Lock()
+	delete(d.askpassOTPs, token)
 	if t, exists := d.tunnels[alias]; exists {
 		t.State = StateConnected
 		t.LastConnectedTime = time.Now()
@@ -931,15 +1211,33 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 	// Log to database
 	if d.database != nil {
 		details := fmt.Sprintf("PID: %d", cmd.Process.Pid)
-		if err := d.database.LogTunnelEvent(alias, "connect", details); err != nil {
+		d.flushTunnelEvents(alias)
+		if err := d.database.LogTunnelEventWithReason(alias, "connect", details, reason); err != nil {
 			slog.Error("Failed to log tunnel connect event", "error", err)
 		}
 	}
 
 	// Trigger context check after successful SSH connection
 	// Trigger state check after SSH connect
-	if stateOrchestrator != nil {
-		stateOrchestrator.TriggerCheck("ssh_connect")
+	if orch := GetStateOrchestrator(); orch != nil {
+		orch.TriggerCheck("ssh_connect")
+	}
+
+	// Start post_connect companion scripts now that the tunnel is up. On a
+	// reconnect StartCompanions restarts the stopped ones in place and starts
+	// the ones that never ran, e.g. added by a reload. A blocking failure
+	// takes the tunnel down again.
+	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil {
+		if postConnect := companionsInPhase(tunnelConfig.Companions, "post_connect"); len(postConnect) > 0 {
+			if restartCompanions {
+				sendMessage("Restarting post-connect companion scripts...", "INFO")
+			}
+			if err := d.companionMgr.StartCompanions(alias, postConnect, onProgress); err != nil {
+				sendMessage(fmt.Sprintf("Companion script failed: %v", err), "ERROR")
+				d.stopTunnel(alias, false, ReasonCompanion)
+				return response
+			}
+		}
 	}
 
 	// Execute after_connect hooks (after successful connection)
@@ -955,7 +1253,7 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 	sendMessage(fmt.Sprintf("Tunnel '%s' connected successfully.", alias), "INFO")
 
 	// This goroutine monitors the tunnel process and handles reconnection
-	go d.monitorTunnel(alias)
+	d.monitors.Go(func() { d.monitorTunnel(alias) })
 
 	return response
 }
@@ -964,8 +1262,28 @@ func (d *Daemon) startTunnelStreaming(alias string, cliEnv map[string]string, st
 // Always evicts a conflicting mux master (force=true) because the callers are
 // all non-interactive: daemon-driven auto-reconnects, context/IP changes, and
 // adopted-tunnel recovery.
-func (d *Daemon) startTunnel(alias string, env map[string]string) Response {
-	return d.startTunnelStreaming(alias, env, nil, true)
+func (d *Daemon) startTunnel(alias string, env map[string]string, sshOverrides *core.SSHOverrides, reason string) Response {
+	return d.startTunnelStreaming(alias, env, nil, true, "", sshOverrides, reason)
+}
+
+// configureAskpass sets up the askpass helper for an SSH command. A custom
+// helper from the ssh block is always used as-is. Otherwise the overseer binary
+// answers password prompts when a password is stored, authenticating its
+// callback with the returned token ("" if no token is needed).
+func configureAskpass(cmd *exec.Cmd, alias string, hasPassword bool) (string, error) {
+	if program := core.Config.SSH.Askpass; program != "" {
+		return "", keyring.ConfigureCustomAskpass(cmd, program)
+	}
+	if !hasPassword {
+		return "", nil
+	}
+	return keyring.ConfigureSSHAskpass(cmd, alias)
+}
+
+// tunnelDisabled reports whether the tunnel's config block sets disabled = true
+func tunnelDisabled(alias string) bool {
+	tunnel, exists := core.Config.Tunnels[alias]
+	return exists && tunnel.Disabled
 }
 
 // isPublicIPKnown returns true if the public IPv4 has been determined
@@ -988,10 +1306,88 @@ func (d *Daemon) isPublicIPKnown() bool {
 	return ip != "" && ip != "0.0.0.0" && ip != "169.254.0.0"
 }
 
+// isOnline returns true if the online sensor reports connectivity
+func (d *Daemon) isOnline() bool {
+	orch := GetStateOrchestrator()
+	if orch == nil {
+		return true // no orchestrator = no sensors to wait for
+	}
+	return orch.IsOnline()
+}
+
+// startStartupTunnels waits for the daemon to be online, then connects the
+// tunnels with connect_on_startup set
+func (d *Daemon) startStartupTunnels() {
+	var aliases []string
+	for alias, tunnel := range core.Config.Tunnels {
+		if tunnel.ConnectOnStartup {
+			aliases = append(aliases, alias)
+		}
+	}
+	if len(aliases) == 0 {
+		return
+	}
+	sort.Strings(aliases)
+
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+
+		if !d.isOnline() {
+			slog.Info("Deferring startup tunnels until online", "tunnels", aliases)
+		}
+		for !d.isOnline() {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+		d.connectStartupTunnels(aliases)
+	}()
+}
+
+// connectStartupTunnels connects the given tunnels unless they are already
+// running, e.g. adopted from the previous daemon or connected by a context
+func (d *Daemon) connectStartupTunnels(aliases []string) {
+	contextName := ""
+	if orch := GetStateOrchestrator(); orch != nil {
+		contextName = orch.GetCurrentState().Context
+	}
+
+	for _, alias := range aliases {
+		if tunnelDisabled(alias) || d.isManuallyStopped(alias) {
+			continue
+		}
+
+		d.mu.Lock()
+		_, exists := d.tunnels[alias]
+		d.mu.Unlock()
+		if exists {
+			slog.Debug("Skipping startup tunnel - already running", "tunnel", alias)
+			continue
+		}
+
+		slog.Info("Connecting tunnel on startup", "tunnel", alias)
+		if !d.isPublicIPKnown() {
+			go d.startTunnelWhenIPReady(alias, contextName, nil, ReasonStartup)
+			continue
+		}
+		resp := d.startTunnel(alias, nil, nil, ReasonStartup)
+		for _, msg := range resp.Messages {
+			if msg.Status == "ERROR" {
+				slog.Error("Failed to start tunnel on startup",
+					"tunnel", alias,
+					"error", msg.Message)
+			}
+		}
+	}
+}
+
 // startTunnelWhenIPReady waits for the public IP to be determined,
 // then starts the tunnel. This avoids starting tunnels before the env
 // file has OVERSEER_PUBLIC_IP populated (SSH config depends on it).
-func (d *Daemon) startTunnelWhenIPReady(alias, ctx string) {
+func (d *Daemon) startTunnelWhenIPReady(alias, ctx string, sshOverrides *core.SSHOverrides, reason string) {
 	slog.Info("Deferring tunnel until public IP is known", "alias", alias, "context", ctx)
 
 	deadline := time.Now().Add(10 * time.Second)
@@ -1010,7 +1406,7 @@ func (d *Daemon) startTunnelWhenIPReady(alias, ctx string) {
 
 		if d.isPublicIPKnown() {
 			slog.Info("Public IP now known, starting deferred tunnel", "alias", alias)
-			resp := d.startTunnel(alias, nil)
+			resp := d.startTunnel(alias, nil, sshOverrides, reason)
 			for _, msg := range resp.Messages {
 				if msg.Status == "ERROR" {
 					slog.Error("Deferred tunnel failed to start",
@@ -1023,7 +1419,7 @@ func (d *Daemon) startTunnelWhenIPReady(alias, ctx string) {
 
 	slog.Warn("Timed out waiting for public IP, starting tunnel with current state",
 		"alias", alias)
-	resp := d.startTunnel(alias, nil)
+	resp := d.startTunnel(alias, nil, sshOverrides, reason)
 	for _, msg := range resp.Messages {
 		if msg.Status == "ERROR" {
 			slog.Error("Deferred tunnel (timeout) failed to start",
@@ -1050,9 +1446,10 @@ func (d *Daemon) monitorTunnel(alias string) {
 			d.mu.Unlock()
 			return
 		}
+		proc := tunnel.Process
 		d.mu.Unlock()
 
-		waitErr := cmd.Wait()
+		waitErr, recycled := d.waitForTunnelExit(alias, cmd, proc)
 
 		d.mu.Lock()
 		tunnel, exists = d.tunnels[alias]
@@ -1069,38 +1466,49 @@ func (d *Daemon) monitorTunnel(alias string) {
 		}
 
 		// Log the exit
-		if waitErr != nil {
+		if recycled {
+			slog.Info(fmt.Sprintf("Tunnel '%s' reached its max_lifetime, reconnecting.", alias))
+		} else if waitErr != nil {
 			slog.Info(fmt.Sprintf("Tunnel process for '%s' exited with an error: %v", alias, waitErr))
 		} else {
 			slog.Info(fmt.Sprintf("Tunnel process for '%s' exited successfully.", alias))
 		}
 
 		// Log to database
-		exitDetails := ""
-		if waitErr != nil {
-			exitDetails = fmt.Sprintf("Error: %v", waitErr)
-		}
-		slog.Debug("Recording tunnel disconnect event",
-			"alias", alias,
-			"pid", tunnel.Pid,
-			"exit_details", exitDetails,
-			"database_available", d.database != nil)
-		if d.database != nil {
-			if err := d.database.LogTunnelEvent(alias, "disconnect", exitDetails); err != nil {
-				slog.Error("Failed to log tunnel disconnect", "error", err)
+		if recycled {
+			if d.database != nil {
+				details := fmt.Sprintf("Connected for %s", time.Since(tunnel.LastConnectedTime).Round(time.Second))
+				if err := d.database.LogTunnelEvent(alias, "lifetime_recycle", details); err != nil {
+					slog.Error("Failed to log tunnel lifetime recycle", "error", err)
+				}
+			}
+		} else {
+			exitDetails := ""
+			if waitErr != nil {
+				exitDetails = fmt.Sprintf("Error: %v", waitErr)
+			}
+			slog.Debug("Recording tunnel disconnect event",
+				"alias", alias,
+				"pid", tunnel.Pid,
+				"exit_details", exitDetails,
+				"database_available", d.database != nil)
+			if d.database != nil {
+				d.logTunnelEvent(alias, "disconnect", exitDetails, "")
 			}
 		}
 
 		// Update state to disconnected
 		tunnel.State = StateDisconnected
 		tunnel.DisconnectedTime = time.Now()
+		resetRetriesIfStable(alias, &tunnel)
 		d.tunnels[alias] = tunnel
 
 		// Get max retries from config
 		maxRetries := core.Config.SSH.MaxRetries
 
-		// Check if auto-reconnect is enabled and we haven't exceeded max retries
-		if !tunnel.AutoReconnect || tunnel.RetryCount >= maxRetries {
+		// Check if auto-reconnect is enabled and we haven't exceeded max retries.
+		// A lifetime recycle always reconnects - the tunnel didn't fail.
+		if !recycled && (!tunnel.AutoReconnect || tunnel.RetryCount >= maxRetries) {
 			// Clean up and don't reconnect
 			if tunnel.AskpassToken != "" {
 				delete(d.askpassTokens, tunnel.AskpassToken)
@@ -1108,15 +1516,17 @@ func (d *Daemon) monitorTunnel(alias string) {
 			delete(d.tunnels, alias)
 
 			if tunnel.RetryCount >= maxRetries {
-				slog.Info(fmt.Sprintf("Tunnel '%s' exceeded max retry attempts (%d). Giving up.", alias, maxRetries))
+				slog.Warn(fmt.Sprintf("Tunnel '%s' exceeded max retry attempts (%d). Giving up.", alias, maxRetries))
 
 				// Log to database
 				if d.database != nil {
 					details := fmt.Sprintf("Max retries (%d) exceeded", maxRetries)
+					d.flushTunnelEvents(alias)
 					if err := d.database.LogTunnelEvent(alias, "max_retries_exceeded", details); err != nil {
 						slog.Error("Failed to log max retries exceeded", "error", err)
 					}
 				}
+				d.executeGiveUpHooks(alias)
 			} else {
 				slog.Info(fmt.Sprintf("Tunnel '%s' auto-reconnect disabled. Not reconnecting.", alias))
 			}
@@ -1152,9 +1562,14 @@ func (d *Daemon) monitorTunnel(alias string) {
 			return
 		}
 
-		// Calculate backoff delay
-		backoff := calculateBackoff(tunnel.RetryCount)
-		tunnel.RetryCount++
+		// Calculate backoff delay. A lifetime recycle reconnects straight away
+		// and doesn't use up a retry.
+		var backoff time.Duration
+		if !recycled {
+			backoff = calculateBackoff(tunnel.RetryCount)
+			tunnel.RetryCount++
+			d.observeBackoff(alias, backoff)
+		}
 		tunnel.LastRetryTime = time.Now()
 		tunnel.State = StateReconnecting
 		tunnel.NextRetryTime = time.Now().Add(backoff)
@@ -1204,23 +1619,17 @@ func (d *Daemon) monitorTunnel(alias string) {
 			return
 		}
 
-		// Check if a password is stored for this alias
-		hasPassword := keyring.HasPassword(alias)
-
-		// Create new SSH command
-		// Build SSH options from config
-		sshArgs := []string{alias, "-N", "-o", "IgnoreUnknown=overseer-daemon", "-o", "overseer-daemon=" + core.ProcessTag(), "-o", "ExitOnForwardFailure=yes", "-v"}
-
-		if d.sshConfigFile != "" {
-			sshArgs = append([]string{"-F", d.sshConfigFile}, sshArgs...)
+		// Check if a password is stored or comes from a password_command
+		hasPassword, err := tunnelHasPassword(alias)
+		if err != nil {
+			d.abandonReconnect(alias, err)
+			d.mu.Unlock()
+			return
 		}
 
-		// Add ServerAliveInterval if configured (0 means disabled)
-		if core.Config.SSH.ServerAliveInterval > 0 {
-			sshArgs = append(sshArgs,
-				"-o", fmt.Sprintf("ServerAliveInterval=%d", core.Config.SSH.ServerAliveInterval),
-				"-o", fmt.Sprintf("ServerAliveCountMax=%d", core.Config.SSH.ServerAliveCountMax))
-		}
+		// Create new SSH command with the same arguments as the first connect
+		sshSettings := effectiveSSHSettings(tunnel.SSHOverrides)
+		sshArgs := buildTunnelSSHArgs(alias, d.sshConfigFile, sshSettings.ServerAliveInterval, sshSettings.ServerAliveCountMax, sshSettings.NoControlMaster)
 
 		newCmd := exec.Command("ssh", sshArgs...)
 		newCmd.Env = os.Environ()
@@ -1248,7 +1657,7 @@ func (d *Daemon) monitorTunnel(alias string) {
 		}
 
 		// Capture stderr to monitor connection status
-		stderrPipe, err := newCmd.StderrPipe()
+		newProc, err := newSSHProcess(newCmd)
 		if err != nil {
 			slog.Error(fmt.Sprintf("Failed to create stderr pipe for reconnection: %v", err))
 			delete(d.tunnels, alias)
@@ -1256,19 +1665,18 @@ func (d *Daemon) monitorTunnel(alias string) {
 			return
 		}
 
-		var token string
-		if hasPassword {
-			token, err = keyring.ConfigureSSHAskpass(newCmd, alias)
-			if err != nil {
-				slog.Error(fmt.Sprintf("Failed to configure askpass for reconnection: %v", err))
-				delete(d.tunnels, alias)
-				d.mu.Unlock()
-				return
-			}
+		token, err := configureAskpass(newCmd, alias, hasPassword)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to configure askpass for reconnection: %v", err))
+			delete(d.tunnels, alias)
+			d.mu.Unlock()
+			return
+		}
+		if token != "" {
 			d.askpassTokens[token] = alias
 		}
 
-		err = newCmd.Start()
+		err = newProc.start()
 		if err != nil {
 			if token != "" {
 				delete(d.askpassTokens, token)
@@ -1276,6 +1684,7 @@ func (d *Daemon) monitorTunnel(alias string) {
 			slog.Error(fmt.Sprintf("Failed to launch SSH process for reconnection: %v", err))
 			// Continue the loop to retry again
 			tunnel.Cmd = nil // Mark as failed
+			tunnel.Process = nil
 			d.tunnels[alias] = tunnel
 			d.mu.Unlock()
 			continue
@@ -1284,6 +1693,7 @@ func (d *Daemon) monitorTunnel(alias string) {
 		// Update tunnel info
 		tunnel.Pid = newCmd.Process.Pid
 		tunnel.Cmd = newCmd
+		tunnel.Process = newProc
 		tunnel.AskpassToken = token
 		tunnel.State = StateReconnecting // Still reconnecting until verified
 		d.tunnels[alias] = tunnel
@@ -1293,23 +1703,21 @@ func (d *Daemon) monitorTunnel(alias string) {
 
 		// Wait for connection verification
 		connectionResult := make(chan error, 1)
-		go d.verifyConnection(stderrPipe, alias, connectionResult)
+		go d.verifyConnection(newProc.Stderr(), alias, connectionResult)
 
-		err = <-connectionResult
+		err = newProc.awaitVerification(connectionResult)
 		if err != nil {
 			// Port-conflict diagnostics (slog only — no client stream on reconnect).
 			d.reportConnectFailure(alias, reconnectEnv, err, nil)
 
-			// Log to database
+			// Log to database, with the kind of failure as reason
 			if d.database != nil {
-				details := fmt.Sprintf("Attempt %d failed: %v", tunnel.RetryCount, err)
-				if dbErr := d.database.LogTunnelEvent(alias, "reconnect_failed", details); dbErr != nil {
-					slog.Error("Failed to log reconnection failure", "error", dbErr)
-				}
+				details := fmt.Sprintf("Attempt %d failed: %s", tunnel.RetryCount, d.connectionFailureDetails(newProc, err))
+				d.logTunnelEvent(alias, "reconnect_failed", details, sshFailureCategory(err))
 			}
 
 			// Kill the failed reconnection process directly
-			newCmd.Process.Kill()
+			newProc.kill()
 
 			// Check if tunnel was replaced during verification (e.g., by a context change)
 			d.mu.Lock()
@@ -1319,6 +1727,12 @@ func (d *Daemon) monitorTunnel(alias string) {
 				d.mu.Unlock()
 				return
 			}
+
+			// Retrying won't help, e.g. the key was rejected
+			if d.abandonFatalReconnect(alias, err) {
+				d.mu.Unlock()
+				return
+			}
 			d.mu.Unlock()
 			continue
 		}
@@ -1342,13 +1756,15 @@ func (d *Daemon) monitorTunnel(alias string) {
 		if d.database != nil {
 			currentTunnel := d.tunnels[alias]
 			details := fmt.Sprintf("PID: %d, Total reconnects: %d", newCmd.Process.Pid, currentTunnel.TotalReconnects+1)
-			if err := d.database.LogTunnelEvent(alias, "reconnect", details); err != nil {
-				slog.Error("Failed to log tunnel reconnect event", "error", err)
-			}
+			d.logTunnelEvent(alias, "reconnect", details, ReasonReconnect)
 		}
 
 		if t, exists := d.tunnels[alias]; exists {
-			t.RetryCount = 0
+			// With stable_reset set, the retry count is kept until the
+			// connection has held, so a flapping tunnel keeps backing off
+			if core.Config.SSH.StableReset == 0 {
+				t.RetryCount = 0
+			}
 			t.State = StateConnected
 			t.NextRetryTime = time.Time{}    // Clear next retry time
 			t.LastConnectedTime = time.Now() // Reset age to 0
@@ -1359,14 +1775,49 @@ func (d *Daemon) monitorTunnel(alias string) {
 
 		// Trigger context check after successful SSH reconnection
 		// Trigger state check after SSH reconnect
-		if stateOrchestrator != nil {
-			stateOrchestrator.TriggerCheck("ssh_reconnect")
+		if orch := GetStateOrchestrator(); orch != nil {
+			orch.TriggerCheck("ssh_reconnect")
 		}
 
 		// Continue monitoring this tunnel (loop back to Wait())
 	}
 }
 
+// tunnelEnvironment returns the environment for a tunnel's SSH process,
+// merged as state-computed → tunnel config → CLI -E
+func tunnelEnvironment(alias string, cliEnv map[string]string) map[string]string {
+	mergedEnv := make(map[string]string)
+	if orch := GetStateOrchestrator(); orch != nil {
+		for k, v := range orch.BuildSSHEnv() {
+			mergedEnv[k] = v
+		}
+	} else {
+		for k, v := range core.Config.Environment {
+			mergedEnv[k] = v
+		}
+	}
+	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil {
+		for k, v := range tunnelConfig.Environment {
+			mergedEnv[k] = v
+		}
+	}
+	for k, v := range cliEnv {
+		mergedEnv[k] = v
+	}
+	return mergedEnv
+}
+
+// effectiveSSHSettings returns the SSH settings for a new SSH process: the
+// global settings, then the keepalive profile for the current power source,
+// then the overrides from the context that connected the tunnel
+func effectiveSSHSettings(overrides *core.SSHOverrides) core.SSHConfig {
+	settings := core.Config.SSH
+	if orch := GetStateOrchestrator(); orch != nil {
+		settings = settings.WithKeepaliveProfile(orch.PowerStatus().Source)
+	}
+	return settings.WithOverrides(overrides)
+}
+
 // buildTunnelSSHArgs builds the argument vector for a tunnel's `ssh` invocation.
 //
 // ControlPersist=no is forced so that even when the user's ssh config has
@@ -1374,8 +1825,10 @@ func (d *Daemon) monitorTunnel(alias string) {
 // detach-fork into the background (which would leave us tracking the wrong
 // PID). The mux master socket is still set up before the fork decision, so
 // interactive sessions, scp, and rsync still multiplex over our live tunnel —
-// overseer just owns the mux for the tunnel's lifetime.
-func buildTunnelSSHArgs(alias, sshConfigFile string, aliveInterval, aliveCountMax int) []string {
+// overseer just owns the mux for the tunnel's lifetime. With noControlMaster
+// set, ControlMaster=no keeps the tunnel out of multiplexing altogether. A
+// tunnel with a remote_command runs it instead of passing -N.
+func buildTunnelSSHArgs(alias, sshConfigFile string, aliveInterval, aliveCountMax int, noControlMaster bool) []string {
 	args := []string{
 		alias, "-N",
 		"-o", "IgnoreUnknown=overseer-daemon",
@@ -1395,9 +1848,36 @@ func buildTunnelSSHArgs(alias, sshConfigFile string, aliveInterval, aliveCountMa
 			"-o", fmt.Sprintf("ServerAliveCountMax=%d", aliveCountMax))
 	}
 
+	if noControlMaster {
+		args = append(args, "-o", "ControlMaster=no")
+	}
+
+	if command := tunnelRemoteCommand(alias); command != "" {
+		args = withRemoteCommand(args, command)
+	}
+
 	return args
 }
 
+// tunnelRemoteCommand returns the remote_command of alias ("" if none)
+func tunnelRemoteCommand(alias string) string {
+	if core.Config == nil {
+		return ""
+	}
+	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil {
+		return tunnelConfig.RemoteCommand
+	}
+	return ""
+}
+
+// withRemoteCommand turns the arguments of a -N tunnel into ones running
+// command on the server. The command goes last, SSH takes everything after
+// the destination and its options as the command.
+func withRemoteCommand(args []string, command string) []string {
+	args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool { return arg == "-N" })
+	return append(args, command)
+}
+
 // resolveJumpChain uses `ssh -G` to resolve the ProxyJump chain for an alias.
 // Returns a slice of "hostname:port" strings representing each hop in order
 // (first jump host first, final destination last).
@@ -1479,16 +1959,143 @@ func resolveJumpChain(alias string, env map[string]string, sshConfigFile string)
 				walk(info.proxyJump)
 			}
 
-			chain = append(chain, net.JoinHostPort(info.hostname, info.port))
+			chain = append(chain, net.JoinHostPort(info.hostname, info.port))
+		}
+	}
+
+	walk(destInfo.proxyJump)
+
+	// Add the destination as the last hop
+	chain = append(chain, net.JoinHostPort(destInfo.hostname, destInfo.port))
+
+	return chain
+}
+
+// waitForTunnelExit waits for the tunnel's SSH process to exit. If the tunnel
+// has a max_lifetime, the process is terminated once it has been connected
+// that long and recycled is true.
+func (d *Daemon) waitForTunnelExit(alias string, cmd *exec.Cmd, proc *sshProcess) (waitErr error, recycled bool) {
+	exited := make(chan error, 1)
+	go func() {
+		// Reuse the waiter started with the process; cmd.Wait may only be called once
+		if proc != nil && proc.cmd == cmd {
+			exited <- proc.wait()
+		} else {
+			exited <- cmd.Wait()
+		}
+	}()
+
+	tunnelConfig := core.Config.Tunnels[alias]
+	if tunnelConfig == nil || tunnelConfig.MaxLifetime <= 0 {
+		return <-exited, false
+	}
+
+	// Only connected tunnels are recycled; a failed reconnect attempt must
+	// still count against max_retries
+	d.mu.Lock()
+	tunnel := d.tunnels[alias]
+	d.mu.Unlock()
+	if tunnel.State != StateConnected {
+		return <-exited, false
+	}
+	deadline := tunnel.LastConnectedTime.Add(tunnelConfig.MaxLifetime)
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case err := <-exited:
+		return err, false
+	case <-timer.C:
+	}
+
+	d.mu.Lock()
+	tunnel, exists := d.tunnels[alias]
+	d.mu.Unlock()
+	if !exists || tunnel.Cmd != cmd || tunnel.State != StateConnected {
+		// Stopped or replaced meanwhile - whoever did that owns the process
+		return <-exited, false
+	}
+
+	slog.Info(fmt.Sprintf("Tunnel '%s' has been connected for %s (max_lifetime %s), recycling connection",
+		alias, time.Since(tunnel.LastConnectedTime).Round(time.Second), tunnelConfig.MaxLifetime))
+	gracefulTerminate(cmd.Process, 5*time.Second, alias)
+
+	return <-exited, true
+}
+
+// connectionFailureDetails describes a failed connection attempt for
+// post-mortems, including the last lines SSH wrote to stderr
+func (d *Daemon) connectionFailureDetails(proc *sshProcess, err error) string {
+	details := proc.failureDetails(err)
+	var failure *sshFailure
+	if !errors.As(err, &failure) && d.sshVersion.Raw != "" {
+		// Unrecognised failures may be down to the ssh client's output
+		details += fmt.Sprintf(" (ssh client: %s)", d.sshVersion)
+	}
+	return details
+}
+
+// logTunnelEvent logs an event that recurs while a tunnel is flapping. Events
+// of the same type and tunnel closer together than event_min_interval are
+// coalesced, keeping the latest.
+func (d *Daemon) logTunnelEvent(alias, eventType, details, reason string) {
+	event := tunnelEvent{Alias: alias, EventType: eventType, Details: details, Reason: reason, At: time.Now()}
+	if d.tunnelEvents == nil {
+		d.writeTunnelEvent(event)
+		return
+	}
+	d.tunnelEvents.Record(event, core.Config.EventMinInterval)
+}
+
+// flushTunnelEvents writes the events held back for a tunnel, so the event
+// about to be logged comes after them
+func (d *Daemon) flushTunnelEvents(alias string) {
+	if d.tunnelEvents != nil {
+		d.tunnelEvents.Flush(alias)
+	}
+}
+
+// writeTunnelEvent writes a tunnel event to the database
+func (d *Daemon) writeTunnelEvent(event tunnelEvent) {
+	if d.database == nil {
+		return
+	}
+	if err := d.database.LogTunnelEventAt(event.Alias, event.EventType, event.Details, event.Reason, event.At); err != nil {
+		slog.Error("Failed to log tunnel event", "alias", event.Alias, "event", event.EventType, "error", err)
+	}
+}
+
+// abandonFatalReconnect gives up on a tunnel whose reconnect attempt failed in
+// a way retrying can't fix, without using up the remaining retries. Returns
+// false for transient failures, which are retried as usual. Must be called
+// with d.mu held.
+func (d *Daemon) abandonFatalReconnect(alias string, err error) bool {
+	if !isFatalSSHError(err) {
+		return false
+	}
+	d.abandonReconnect(alias, err)
+	return true
+}
+
+// abandonReconnect gives up on reconnecting a tunnel because of err, which
+// retrying won't fix: the tunnel is removed, the abandonment recorded and the
+// on_give_up hooks run. Must be called with d.mu held.
+func (d *Daemon) abandonReconnect(alias string, err error) {
+	tunnel := d.tunnels[alias]
+	if tunnel.AskpassToken != "" {
+		delete(d.askpassTokens, tunnel.AskpassToken)
+	}
+	delete(d.tunnels, alias)
+	slog.Error(fmt.Sprintf("Tunnel '%s' failed to reconnect: %v. Giving up, retrying won't help.", alias, err))
+
+	if d.database != nil {
+		d.flushTunnelEvents(alias)
+		if dbErr := d.database.LogTunnelEvent(alias, "reconnect_abandoned", err.Error()); dbErr != nil {
+			slog.Error("Failed to log abandoned reconnection", "error", dbErr)
 		}
 	}
-
-	walk(destInfo.proxyJump)
-
-	// Add the destination as the last hop
-	chain = append(chain, net.JoinHostPort(destInfo.hostname, destInfo.port))
-
-	return chain
+	d.executeGiveUpHooks(alias)
 }
 
 // verifyConnection monitors SSH stderr output to detect connection success or failure
@@ -1496,10 +2103,11 @@ var authenticatedToRe = regexp.MustCompile(`Authenticated to \S+ \(\[([^\]]+)\]:
 var authenticatingToRe = regexp.MustCompile(`Authenticating to (.+):(\d+) as '`)
 
 func (d *Daemon) verifyConnection(stderr io.ReadCloser, alias string, result chan<- error) {
+	defer stderr.Close()
 	defer func() {
 		// Ensure we always send a result, even if we exit unexpectedly
 		select {
-		case result <- fmt.Errorf("SSH process terminated unexpectedly"):
+		case result <- errSSHTerminated:
 		default:
 			// Channel already has a value, nothing to do
 		}
@@ -1508,9 +2116,21 @@ func (d *Daemon) verifyConnection(stderr io.ReadCloser, alias string, result cha
 	scanner := bufio.NewScanner(stderr)
 	authenticated := false
 	verified := false
+	quietAfterConnect := core.Config != nil && core.Config.SSH.QuietAfterConnect
+	sessionSuccesses := sshSessionSuccesses
+	if tunnelRemoteCommand(alias) != "" {
+		sessionSuccesses = sshCommandSuccesses
+	}
 	var lastAuthenticatingTo string // host:port from "Authenticating to" line (for proxy hops)
 
 	for scanner.Scan() {
+		// With verbose_after_connect = false the rest of the output is only
+		// drained, which must still happen for the reason given below
+		if verified && quietAfterConnect {
+			io.Copy(io.Discard, stderr)
+			break
+		}
+
 		line := scanner.Text()
 		slog.Debug(fmt.Sprintf("[%s] SSH: %s", alias, line))
 
@@ -1555,42 +2175,28 @@ func (d *Daemon) verifyConnection(stderr io.ReadCloser, alias string, result cha
 		}
 
 		// Look for success indicators - session fully established
-		// For -N (no command), look for "pledge: network" or "Entering interactive session"
-		if authenticated && (strings.Contains(line, "Entering interactive session") ||
-			strings.Contains(line, "pledge: network")) {
+		// For -N (no command), look for "pledge: network" or "Entering interactive session",
+		// with a remote command for the command being sent
+		if authenticated && slices.ContainsFunc(sessionSuccesses, func(s string) bool { return strings.Contains(line, s) }) {
 			result <- nil
 			verified = true
 			continue
 		}
 
-		// Look for failure indicators
-		if strings.Contains(line, "Permission denied") {
-			result <- fmt.Errorf("authentication failed")
-			return
-		}
-		if strings.Contains(line, "Connection refused") {
-			result <- fmt.Errorf("connection refused")
-			return
-		}
-		if strings.Contains(line, "No route to host") {
-			result <- fmt.Errorf("no route to host")
-			return
-		}
-		if strings.Contains(line, "Connection timed out") {
-			result <- fmt.Errorf("connection timed out")
-			return
-		}
-		if strings.Contains(line, "Could not resolve hostname") {
-			result <- fmt.Errorf("could not resolve hostname")
-			return
-		}
-		if strings.Contains(line, "Host key verification failed") {
-			result <- fmt.Errorf("host key verification failed")
-			return
+		// Multiplexed over an existing ControlMaster, the session is
+		// established without authenticating
+		if slices.ContainsFunc(sshMuxSuccesses, func(s string) bool { return strings.Contains(line, s) }) {
+			result <- nil
+			verified = true
+			continue
 		}
-		if strings.Contains(line, "Too many authentication failures") {
-			result <- fmt.Errorf("too many authentication failures")
-			return
+
+		// Look for failure indicators
+		for _, f := range sshFailures {
+			if strings.Contains(line, f.output) {
+				result <- f.failure
+				return
+			}
 		}
 	}
 
@@ -1647,7 +2253,8 @@ func gracefulTerminate(process *os.Process, timeout time.Duration, label string)
 	return nil
 }
 
-func (d *Daemon) stopTunnel(alias string, forReconnect bool) Response {
+// stopTunnel terminates a tunnel. reason is recorded with the database event.
+func (d *Daemon) stopTunnel(alias string, forReconnect bool, reason string) Response {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -1659,6 +2266,11 @@ func (d *Daemon) stopTunnel(alias string, forReconnect bool) Response {
 		return response
 	}
 
+	// Keep the current context from connecting it again behind the user's back
+	if reason == ReasonManual && !forReconnect {
+		d.markManuallyStopped(alias)
+	}
+
 	// Gracefully terminate the tunnel process - handle both normal and adopted tunnels
 	const gracefulTimeout = 5 * time.Second
 	var killErr error
@@ -1696,15 +2308,16 @@ func (d *Daemon) stopTunnel(alias string, forReconnect bool) Response {
 
 	// Log to database
 	if d.database != nil {
-		if err := d.database.LogTunnelEvent(alias, "manual_disconnect", ""); err != nil {
-			slog.Error("Failed to log tunnel manual stop", "error", err)
+		d.flushTunnelEvents(alias)
+		if err := d.database.LogTunnelEventWithReason(alias, stopEventType(reason), "", reason); err != nil {
+			slog.Error("Failed to log tunnel stop", "error", err)
 		}
 	}
 
 	// Stop companion scripts unless this is for a reconnect
 	if !forReconnect {
 		// Permanent stop - stop companions
-		d.companionMgr.StopCompanions(alias)
+		d.companionMgr.StopCompanions(alias, false)
 	} else {
 		// For reconnect, companions stay in the map but clear history
 		// to prevent showing stale output on reattach
@@ -1715,8 +2328,90 @@ func (d *Daemon) stopTunnel(alias string, forReconnect bool) Response {
 	return response
 }
 
+// companionRestartWindow is how long a tunnel must run after being restarted
+// for a companion failure before the next restart happens straight away again
+const companionRestartWindow = 5 * time.Minute
+
+// companionRestart tracks the restarts of a tunnel for companion failures
+type companionRestart struct {
+	count int       // Restarts in a row, each less than companionRestartWindow apart
+	last  time.Time // When the last restart was due
+}
+
+// restartTunnelForCompanion stops a tunnel and starts it again after one of
+// its companions with on_failure = "restart_tunnel" failed. The tunnel keeps
+// the SSH overrides of the context that connected it. A companion that keeps
+// failing is restarted with the same exponential backoff as reconnects.
+func (d *Daemon) restartTunnelForCompanion(alias, name string) {
+	d.mu.Lock()
+	tunnel, exists := d.tunnels[alias]
+	if !exists {
+		d.mu.Unlock()
+		return // Stopped in the meantime
+	}
+	restarts := d.companionRestarts[alias]
+	if restarts == nil || time.Since(restarts.last) > companionRestartWindow {
+		restarts = &companionRestart{}
+		d.companionRestarts[alias] = restarts
+	}
+	var delay time.Duration
+	if restarts.count > 0 {
+		delay = calculateBackoff(restarts.count - 1)
+	}
+	restarts.count++
+	restarts.last = time.Now().Add(delay)
+	d.mu.Unlock()
+
+	if delay > 0 {
+		slog.Warn(fmt.Sprintf("Companion '%s' of tunnel '%s' failed again. Restarting the tunnel in %s.", name, alias, delay))
+		select {
+		case <-time.After(delay):
+		case <-d.ctx.Done():
+			return
+		}
+
+		// Leave the tunnel alone if it was stopped or connected anew meanwhile
+		d.mu.Lock()
+		current, exists := d.tunnels[alias]
+		d.mu.Unlock()
+		if !exists || current.Pid != tunnel.Pid {
+			return
+		}
+	} else {
+		slog.Warn(fmt.Sprintf("Companion '%s' of tunnel '%s' failed. Restarting the tunnel.", name, alias))
+	}
+	d.stopTunnel(alias, false, ReasonCompanion)
+
+	response := d.startTunnel(alias, tunnel.Environment, tunnel.SSHOverrides, ReasonCompanion)
+	for _, msg := range response.Messages {
+		if msg.Status == "ERROR" {
+			slog.Error(fmt.Sprintf("Failed to restart tunnel '%s' after companion '%s' failed: %s", alias, name, msg.Message))
+		}
+	}
+}
+
+// killTunnel is a forced stopTunnel that also stops the tunnel's persistent
+// companions. It succeeds when only persistent companions are left running.
+func (d *Daemon) killTunnel(alias string) Response {
+	d.mu.Lock()
+	_, exists := d.tunnels[alias]
+	d.mu.Unlock()
+
+	response := Response{}
+	if exists || !d.companionMgr.HasRunningCompanions(alias) {
+		response = d.stopTunnel(alias, false, ReasonManual)
+	}
+
+	if d.companionMgr.HasRunningCompanions(alias) {
+		d.companionMgr.StopCompanions(alias, true)
+		response.AddMessage(fmt.Sprintf("Stopped all companions for '%s'.", alias), "INFO")
+	}
+	return response
+}
+
 type DaemonStatus struct {
 	Hostname          string      `json:"hostname"`
+	Description       string      `json:"description,omitempty"` // From the tunnel's config
 	Pid               int         `json:"pid"`
 	StartDate         string      `json:"start_date"`                  // Original tunnel creation time
 	LastConnectedTime string      `json:"last_connected_time"`         // Time of last successful connection
@@ -1726,9 +2421,33 @@ type DaemonStatus struct {
 	AutoReconnect     bool        `json:"auto_reconnect"`
 	State             TunnelState `json:"state"`
 	NextRetry         string      `json:"next_retry,omitempty"` // ISO 8601 format
+	NextRetryIn       *int        `json:"next_retry_in_seconds,omitempty"` // Seconds until next retry (0 = due now), computed by the daemon
 	Environment       map[string]string `json:"environment,omitempty"`
 	ResolvedHost      string            `json:"resolved_host,omitempty"`
 	JumpChain         []string    `json:"jump_chain,omitempty"`
+
+	// Bytes received from and sent to the SSH server, nil when the tunnel's
+	// traffic isn't sampled
+	BytesIn  *uint64 `json:"bytes_in,omitempty"`
+	BytesOut *uint64 `json:"bytes_out,omitempty"`
+}
+
+// DaemonInfo describes the running daemon process itself
+type DaemonInfo struct {
+	Version   string `json:"version"`
+	Pid       int    `json:"pid"`
+	IsRemote  bool   `json:"is_remote"`
+	StartTime string `json:"start_time"` // ISO 8601 format
+}
+
+// daemonInfo returns metadata about the running daemon
+func (d *Daemon) daemonInfo() *DaemonInfo {
+	return &DaemonInfo{
+		Version:   core.Version,
+		Pid:       os.Getpid(),
+		IsRemote:  d.isRemote,
+		StartTime: d.startTime.Format(time.RFC3339),
+	}
 }
 
 func (d *Daemon) getStatus() Response {
@@ -1737,7 +2456,7 @@ func (d *Daemon) getStatus() Response {
 
 	// Build statuses json
 	statuses := []DaemonStatus{}
-	response := Response{}
+	response := Response{Daemon: d.daemonInfo()}
 
 	// No tunnels
 	if len(d.tunnels) == 0 {
@@ -1762,6 +2481,13 @@ func (d *Daemon) getStatus() Response {
 			ResolvedHost:      tunnel.ResolvedHost,
 			JumpChain:         tunnel.JumpChain,
 		}
+		if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil {
+			status.Description = tunnelConfig.Description
+		}
+		if bytesIn, bytesOut, ok := d.tunnelTraffic(alias); ok {
+			status.BytesIn = &bytesIn
+			status.BytesOut = &bytesOut
+		}
 
 		// Add disconnected time if tunnel is disconnected or reconnecting
 		if (tunnel.State == StateDisconnected || tunnel.State == StateReconnecting) && !tunnel.DisconnectedTime.IsZero() {
@@ -1771,6 +2497,8 @@ func (d *Daemon) getStatus() Response {
 		// Add next retry time if tunnel is in reconnecting state
 		if tunnel.State == StateReconnecting && !tunnel.NextRetryTime.IsZero() {
 			status.NextRetry = tunnel.NextRetryTime.Format(time.RFC3339)
+			seconds := max(int(math.Ceil(time.Until(tunnel.NextRetryTime).Seconds())), 0)
+			status.NextRetryIn = &seconds
 		}
 
 		statuses = append(statuses, status)
@@ -1796,15 +2524,53 @@ func (d *Daemon) getVersion() Response {
 		data["monitored_pid"] = d.parentMonitor.monitoredPID
 	}
 
+	if d.sshVersion.Raw != "" {
+		data["ssh_version"] = d.sshVersion.Raw
+	}
+
 	response.AddData(data)
 
 	return response
 }
 
+// hello answers the protocol handshake a client starts with HELLO <protocol>.
+// The daemon reports the protocol it speaks either way, and warns when the
+// client speaks another one, as commands or responses may have changed.
+func (d *Daemon) hello(args []string) Response {
+	response := Response{}
+	if len(args) != 1 {
+		response.AddMessage("Usage: HELLO <protocol>", "ERROR")
+		return response
+	}
+	clientProtocol, err := strconv.Atoi(args[0])
+	if err != nil {
+		response.AddMessage(fmt.Sprintf("Invalid protocol version: %s", args[0]), "ERROR")
+		return response
+	}
+
+	if clientProtocol == ProtocolVersion {
+		response.AddMessage("OK", "INFO")
+	} else {
+		response.AddMessage(fmt.Sprintf("Protocol mismatch: client speaks protocol %d, daemon speaks protocol %d", clientProtocol, ProtocolVersion), "WARN")
+	}
+	response.AddData(map[string]interface{}{
+		"protocol":        ProtocolVersion,
+		"version":         core.Version,
+		"request_formats": []string{"text", "json"}, // Accepted forms of a command, see Request
+	})
+	return response
+}
+
+// askpassOTP is a one-time password given on connect, handed to SSH by the
+// first askpass call that wants it and then forgotten
+type askpassOTP struct {
+	code          string
+	afterPassword bool // A stored password is answered first, e.g. password + MFA code
+}
+
 // handleAskpass validates the token and returns the password
 func (d *Daemon) handleAskpass(alias, token string) Response {
 	d.mu.Lock()
-	defer d.mu.Unlock()
 
 	response := Response{}
 
@@ -1812,12 +2578,30 @@ func (d *Daemon) handleAskpass(alias, token string) Response {
 	storedAlias, exists := d.askpassTokens[token]
 	if !exists || storedAlias != alias {
 		// Invalid token or alias mismatch
+		d.mu.Unlock()
 		response.AddMessage("", "ERROR")
 		return response
 	}
 
-	// Token is valid, retrieve password from keyring
-	password, err := keyring.GetPassword(alias)
+	// A one-time password is never stored, so it's answered exactly once
+	if otp, exists := d.askpassOTPs[token]; exists {
+		if !otp.afterPassword {
+			delete(d.askpassOTPs, token)
+			d.mu.Unlock()
+			response.AddMessage(otp.code, "INFO")
+			return response
+		}
+		otp.afterPassword = false
+	}
+
+	// Unlock before retrieving the password, a password_command may take a while
+	d.mu.Unlock()
+
+	// Token is valid, retrieve password from the password_command or keyring
+	password, err := tunnelPassword(d.ctx, alias)
+	if err != nil {
+		slog.Error("Failed to retrieve password", "alias", alias, "error", err)
+	}
 	if err != nil || password == "" {
 		response.AddMessage("", "ERROR")
 		return response
@@ -1855,9 +2639,11 @@ func (d *Daemon) handleCompanionInit(alias, name, token string) Response {
 	}
 
 	var command string
+	var args []string
 	for _, comp := range tunnelConfig.Companions {
 		if comp.Name == name {
 			command = comp.Command
+			args = comp.Args
 			break
 		}
 	}
@@ -1867,8 +2653,12 @@ func (d *Daemon) handleCompanionInit(alias, name, token string) Response {
 		return response
 	}
 
-	// Return the command to execute
+	// Return the command to execute, a list form is passed as data so it
+	// reaches the wrapper argument by argument
 	response.AddMessage(command, "INFO")
+	if len(args) > 0 {
+		response.Data = args
+	}
 	return response
 }
 
@@ -1915,8 +2705,12 @@ func (d *Daemon) shutdown() {
 	d.shutdownOnce.Do(func() {
 		slog.Info("Executing shutdown sequence...")
 
-		// Stop state orchestrator
-		stopStateOrchestrator()
+		// Stop state orchestrator. Context-scoped exports only exist while
+		// their context is active, which it no longer is.
+		if orch := GetStateOrchestrator(); orch != nil {
+			stopStateOrchestrator()
+			orch.RemoveContextEnvFiles()
+		}
 
 		// Stop all companion scripts
 		slog.Debug("Stopping all companion scripts...")
@@ -1930,13 +2724,17 @@ func (d *Daemon) shutdown() {
 		d.mu.Lock()
 		defer d.mu.Unlock()
 
-		// Disconnect and kill all tunnels first
+		// Disconnect and kill all tunnels first, after the events held back
+		// for flapping tunnels
+		if d.tunnelEvents != nil {
+			d.tunnelEvents.FlushAll()
+		}
 		tunnelCount := len(d.tunnels)
 		for alias, tunnel := range d.tunnels {
 			// Log disconnect event before killing
 			if d.database != nil {
 				slog.Debug("Logging disconnect event for tunnel during shutdown", "alias", alias)
-				if err := d.database.LogTunnelEvent(alias, "disconnect", "Daemon shutdown"); err != nil {
+				if err := d.database.LogTunnelEventWithReason(alias, "disconnect", "Daemon shutdown", ReasonShutdown); err != nil {
 					slog.Error("Failed to log tunnel disconnect during shutdown", "error", err, "alias", alias)
 				} else {
 					slog.Debug("Successfully logged disconnect event", "alias", alias)
@@ -2001,8 +2799,8 @@ func (d *Daemon) shutdown() {
 
 // checkOnlineStatus checks if we're currently online
 func (d *Daemon) checkOnlineStatus() bool {
-	if stateOrchestrator != nil {
-		return stateOrchestrator.IsOnline()
+	if orch := GetStateOrchestrator(); orch != nil {
+		return orch.IsOnline()
 	}
 	return false
 }
@@ -2176,6 +2974,46 @@ func (d *Daemon) startHealthCheckLoop() {
 	slog.Info("Started tunnel health check loop", "interval", "30s")
 }
 
+// startReassertLoop starts a goroutine that periodically reconnects dropped
+// tunnels of the active context when it has reassert enabled
+func (d *Daemon) startReassertLoop() {
+	go func() {
+		ticker := time.NewTicker(60 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-ticker.C:
+				if orch := GetStateOrchestrator(); orch != nil {
+					d.reassertContext(orch.GetCurrentState(), orch.GetCurrentRule())
+				}
+			}
+		}
+	}()
+	slog.Info("Started context reassert loop", "interval", "60s")
+}
+
+// startSensorWatchdog periodically checks that the state manager is still
+// evaluating sensor readings, see checkSensorWatchdog
+func (d *Daemon) startSensorWatchdog() {
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-ticker.C:
+				d.checkSensorWatchdog(time.Now())
+			}
+		}
+	}()
+	slog.Info("Started sensor watchdog", "interval", "30s")
+}
+
 // handleOnlineChange is called when the online sensor changes state
 // This triggers health checks when coming back online to detect dead SSH connections
 func (d *Daemon) handleOnlineChange(wasOnline, isOnline bool) {
@@ -2193,15 +3031,17 @@ func (d *Daemon) handleOnlineChange(wasOnline, isOnline bool) {
 
 // ContextStatus represents the current security context information
 type ContextStatus struct {
-	Context       string              `json:"context"`
-	Location      string              `json:"location,omitempty"`
-	LastChange    string              `json:"last_change"`
-	Uptime        string              `json:"uptime"`
-	Sensors       map[string]string   `json:"sensors"`
-	ChangeHistory []ContextChangeInfo `json:"change_history,omitempty"`
-	SensorChanges []SensorChangeInfo  `json:"sensor_changes,omitempty"`
-	TunnelEvents  []TunnelEventInfo   `json:"tunnel_events,omitempty"`
-	DaemonEvents  []DaemonEventInfo   `json:"daemon_events,omitempty"`
+	Context           string              `json:"context"`
+	Location          string              `json:"location,omitempty"`
+	LocationConflicts []string            `json:"location_conflicts,omitempty"` // Locations that match the current network at the same time
+	LastChange        string              `json:"last_change"`
+	Uptime            string              `json:"uptime"`
+	Sensors           map[string]string   `json:"sensors"`
+	SensorKinds       map[string]string   `json:"sensor_kinds,omitempty"` // Value kind (bool, string, ip) of sensors read by probes
+	ChangeHistory     []ContextChangeInfo `json:"change_history,omitempty"`
+	SensorChanges     []SensorChangeInfo  `json:"sensor_changes,omitempty"`
+	TunnelEvents      []TunnelEventInfo   `json:"tunnel_events,omitempty"`
+	DaemonEvents      []DaemonEventInfo   `json:"daemon_events,omitempty"`
 }
 
 // ContextChangeInfo represents a context change event
@@ -2228,6 +3068,7 @@ type TunnelEventInfo struct {
 	TunnelAlias string `json:"tunnel_alias"`
 	EventType   string `json:"event_type"`
 	Details     string `json:"details,omitempty"`
+	Reason      string `json:"reason,omitempty"`
 	Timestamp   string `json:"timestamp"`
 }
 
@@ -2238,20 +3079,9 @@ type DaemonEventInfo struct {
 	Timestamp string `json:"timestamp"`
 }
 
-// getContextStatus returns the current security context status
-func (d *Daemon) getContextStatus(eventLimit int) Response {
-	response := Response{}
-
-	// Check if state orchestrator is initialized
-	if stateOrchestrator == nil {
-		response.AddMessage("State orchestrator not initialized", "ERROR")
-		return response
-	}
-
-	// Get current state
-	currentState := stateOrchestrator.GetCurrentState()
-
-	// Build sensor map from state
+// stateSensorValues builds the sensor map shown in the status from the
+// current state and power status
+func stateSensorValues(orch *state.Orchestrator, currentState state.StateSnapshot) map[string]string {
 	sensors := make(map[string]string)
 	sensors["process_tag"] = core.ProcessTag()
 	sensors["online"] = fmt.Sprintf("%v", currentState.Online)
@@ -2266,6 +3096,59 @@ func (d *Daemon) getContextStatus(eventLimit int) Response {
 	if currentState.LocalIPv4 != nil {
 		sensors["local_ipv4"] = currentState.LocalIPv4.String()
 	}
+	power := orch.PowerStatus()
+	if power.Source != "" {
+		sensors["power"] = power.Source
+	}
+	if power.Level >= 0 {
+		sensors["battery_level"] = fmt.Sprintf("%d%%", power.Level)
+	}
+	return sensors
+}
+
+// getSensors returns the current value of every sensor as a string, keyed
+// by sensor name. Sensors that haven't been read yet have an empty value.
+func (d *Daemon) getSensors() Response {
+	response := Response{}
+
+	orch := GetStateOrchestrator()
+	if orch == nil {
+		response.AddMessage("State orchestrator not initialized", "ERROR")
+		return response
+	}
+
+	sensors := stateSensorValues(orch, orch.GetCurrentState())
+	for _, sensor := range orch.Sensors() {
+		if _, exists := sensors[sensor.Name]; !exists {
+			sensors[sensor.Name] = sensor.Value
+		}
+	}
+
+	response.AddMessage("OK", "INFO")
+	response.AddData(sensors)
+	return response
+}
+
+// getContextStatus returns the current security context status
+func (d *Daemon) getContextStatus(eventLimit int) Response {
+	response := Response{}
+
+	// Check if state orchestrator is initialized
+	orch := GetStateOrchestrator()
+	if orch == nil {
+		response.AddMessage("State orchestrator not initialized", "ERROR")
+		return response
+	}
+
+	// Get current state
+	currentState := orch.GetCurrentState()
+
+	// Build sensor map from state
+	sensors := stateSensorValues(orch, currentState)
+	sensorKinds := make(map[string]string)
+	for _, sensor := range orch.Sensors() {
+		sensorKinds[sensor.Name] = string(sensor.Kind)
+	}
 
 	// Change history is no longer maintained in-memory
 	// It can be retrieved from the database if needed
@@ -2306,6 +3189,7 @@ func (d *Daemon) getContextStatus(eventLimit int) Response {
 					TunnelAlias: te.TunnelAlias,
 					EventType:   te.EventType,
 					Details:     te.Details,
+					Reason:      te.Reason,
 					Timestamp:   te.Timestamp.Format(time.RFC3339Nano),
 				})
 			}
@@ -2406,15 +3290,17 @@ func (d *Daemon) getContextStatus(eventLimit int) Response {
 	}
 
 	status := ContextStatus{
-		Context:       contextName,
-		Location:      locationName,
-		LastChange:    currentState.Timestamp.Format(time.RFC3339),
-		Uptime:        time.Since(currentState.Timestamp).Round(time.Second).String(),
-		Sensors:       sensors,
-		ChangeHistory: changeHistory,
-		SensorChanges: sensorChanges,
-		TunnelEvents:  tunnelEvents,
-		DaemonEvents:  daemonEvents,
+		Context:           contextName,
+		Location:          locationName,
+		LocationConflicts: currentState.LocationConflicts,
+		LastChange:        currentState.Timestamp.Format(time.RFC3339),
+		Uptime:            time.Since(currentState.Timestamp).Round(time.Second).String(),
+		Sensors:           sensors,
+		SensorKinds:       sensorKinds,
+		ChangeHistory:     changeHistory,
+		SensorChanges:     sensorChanges,
+		TunnelEvents:      tunnelEvents,
+		DaemonEvents:      daemonEvents,
 	}
 
 	response.AddMessage("OK", "INFO")
@@ -2441,50 +3327,186 @@ func (d *Daemon) stopDaemon() Response {
 
 // reloadConfig reloads the configuration and restarts the state orchestrator
 func (d *Daemon) reloadConfig() error {
+	// The file watcher and SIGUSR1 can trigger a reload at the same time
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
 	// Save the old config in case we need to roll back
 	oldConfig := core.Config
 
 	// Reload the configuration (main file + config.d/ fragments)
-	configPath := filepath.Join(core.Config.ConfigPath, "config.hcl")
-	configDPath := filepath.Join(core.Config.ConfigPath, "config.d")
+	configPath := core.GetConfigFilePath()
+	configDPath := core.GetConfigDPath()
 	newConfig, err := core.LoadConfigDir(configPath, configDPath)
 	if err != nil {
 		// Config parsing failed - keep the old config and log error
-		errMsg := err.Error()
-		errMsg = strings.TrimPrefix(errMsg, "failed to parse HCL config: ")
-
-		if idx := strings.Index(errMsg, ":\n"); idx != -1 {
-			errMsg = errMsg[:idx]
+		var cfgErr *core.ConfigError
+		if errors.As(err, &cfgErr) && cfgErr.File != "" {
+			slog.Error("Configuration has errors, keeping previous configuration",
+				"file", cfgErr.File, "line", cfgErr.Line, "column", cfgErr.Column, "error", cfgErr.Message)
+			d.recordReload(ReloadStatus{File: cfgErr.File, Line: cfgErr.Line, Column: cfgErr.Column, Error: cfgErr.Message})
+		} else {
+			slog.Error("Configuration has errors, keeping previous configuration",
+				"error", err)
+			d.recordReload(ReloadStatus{File: configPath, Error: err.Error()})
 		}
-
-		slog.Error("Configuration has errors, keeping previous configuration",
-			"error", errMsg)
 		return fmt.Errorf("config parse error")
 	}
 
-	// Preserve the config path
+	// Preserve the config location
 	newConfig.ConfigPath = oldConfig.ConfigPath
+	newConfig.ConfigFile = oldConfig.ConfigFile
+
+	if !slices.Equal(newConfig.Remote.ShutdownSignals, oldConfig.Remote.ShutdownSignals) {
+		slog.Warn("remote.shutdown_signals changed, restart the daemon to apply it")
+	}
 
 	// Update the global config
 	core.Config = newConfig
+	d.applyVerbosity()
 
 	// Reload the state orchestrator with new config
+	reloaded := time.Now()
 	if err := d.reloadStateOrchestrator(); err != nil {
 		// Rollback to old config
 		core.Config = oldConfig
+		d.applyVerbosity()
 		slog.Error("Failed to reload state orchestrator", "error", err)
+		d.recordReload(ReloadStatus{File: configPath, Error: err.Error()})
 		return fmt.Errorf("state orchestrator reload failed")
 	}
 
+	// Follow the files the new config includes
+	if d.configWatch != nil {
+		d.configWatch.watchIncludes(core.Config.IncludedFiles)
+	}
+
+	// Restart companions whose definition changed (waits for readiness, so don't block the reload)
+	if d.companionMgr != nil {
+		go d.companionMgr.RestartChangedCompanions(core.Config.Tunnels)
+	}
+
+	// Run the on_reload hooks once the new rules have been evaluated
+	if hooks := core.Config.GlobalContextHooks; hooks != nil && len(hooks.OnReload) > 0 {
+		go d.runReloadHooks(convertHookConfigs(hooks.OnReload), reloaded)
+	}
+
 	slog.Info("Configuration reloaded successfully")
+	d.recordReload(ReloadStatus{File: configPath, Success: true})
 	return nil
 }
 
+// ReloadStatus is the result of the last configuration reload, as returned
+// by RELOAD_STATUS
+type ReloadStatus struct {
+	Time    string `json:"time"` // ISO 8601 format
+	Success bool   `json:"success"`
+	File    string `json:"file"` // File with the error, or the main config file
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// recordReload stores the result of a configuration reload for RELOAD_STATUS
+func (d *Daemon) recordReload(status ReloadStatus) {
+	status.Time = time.Now().Format(time.RFC3339)
+	d.mu.Lock()
+	d.lastReload = &status
+	d.mu.Unlock()
+}
+
+// getReloadStatus answers RELOAD_STATUS with the result of the last
+// configuration reload
+func (d *Daemon) getReloadStatus() Response {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	response := Response{}
+	if d.lastReload == nil {
+		response.AddMessage("No configuration reload since the daemon started", "INFO")
+		return response
+	}
+	response.AddMessage("OK", "INFO")
+	response.AddData(*d.lastReload)
+	return response
+}
+
+// watchReloadSignal reloads the configuration every time a signal arrives on sigChan
+func (d *Daemon) watchReloadSignal(sigChan <-chan os.Signal) {
+	go func() {
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case sig := <-sigChan:
+				d.handleReloadSignal(sig)
+			}
+		}
+	}()
+}
+
+// handleReloadSignal reloads the configuration using the same path as the file watcher
+func (d *Daemon) handleReloadSignal(sig os.Signal) {
+	slog.Info(fmt.Sprintf("%s received, reloading configuration...", sig))
+	if d.database != nil {
+		d.database.LogDaemonEvent("config_reload", fmt.Sprintf("Reload requested by %s", sig))
+	}
+	if err := d.reloadConfig(); err != nil {
+		// Error already logged in reloadConfig() with details
+		slog.Debug("Config reload failed", "error", err)
+	}
+}
+
+// configWatch is the watcher of the config files, which also follows the
+// files pulled in by include as they come and go across reloads
+type configWatch struct {
+	watcher  *fsnotify.Watcher
+	mu       sync.Mutex
+	includes map[string]bool // Included files being watched
+}
+
+// watchIncludes makes the watched included files match files
+func (w *configWatch) watchIncludes(files []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wanted := make(map[string]bool, len(files))
+	for _, file := range files {
+		wanted[file] = true
+		if w.includes[file] {
+			continue
+		}
+		if err := w.watcher.Add(file); err != nil {
+			slog.Warn("Failed to watch included config file", "error", err, "path", file)
+			continue
+		}
+		w.includes[file] = true
+	}
+	for file := range w.includes {
+		if !wanted[file] {
+			w.watcher.Remove(file)
+			delete(w.includes, file)
+		}
+	}
+}
+
+// watched returns the path to watch again after an event on file, which
+// editors saving atomically remove from the watch list: file itself when it
+// is an included file, the main config file otherwise
+func (w *configWatch) watched(file, configPath string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.includes[file] {
+		return file
+	}
+	return configPath
+}
+
 // watchConfig sets up automatic config file watching
 func (d *Daemon) watchConfig() {
 	// Watch the config file manually using fsnotify
-	configPath := filepath.Join(core.Config.ConfigPath, "config.hcl")
-	configDPath := filepath.Join(core.Config.ConfigPath, "config.d")
+	configPath := core.GetConfigFilePath()
+	configDPath := core.GetConfigDPath()
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -2507,6 +3529,10 @@ func (d *Daemon) watchConfig() {
 		}
 	}
 
+	// Also watch the files the config includes, which can live anywhere
+	d.configWatch = &configWatch{watcher: watcher, includes: make(map[string]bool)}
+	d.configWatch.watchIncludes(core.Config.IncludedFiles)
+
 	// Set up a debounced reload handler
 	var reloadTimer *time.Timer
 	var reloadMutex sync.Mutex
@@ -2530,6 +3556,7 @@ func (d *Daemon) watchConfig() {
 				// Editors using atomic writes remove the original from the watch list.
 				// We may need to retry if the file doesn't exist yet during the atomic operation.
 				if event.Op&(fsnotify.Rename|fsnotify.Remove|fsnotify.Create) != 0 {
+					path := d.configWatch.watched(event.Name, configPath)
 					go func() {
 						// Retry with exponential backoff (10ms, 20ms, 40ms, 80ms, 160ms)
 						for attempt := 0; attempt < 5; attempt++ {
@@ -2539,15 +3566,15 @@ func (d *Daemon) watchConfig() {
 							}
 
 							// Remove old watch (ignore errors - it might not exist)
-							watcher.Remove(configPath)
+							watcher.Remove(path)
 
 							// Try to add the watch
-							if err := watcher.Add(configPath); err == nil {
-								slog.Debug("Successfully re-added watch", "path", configPath, "attempt", attempt+1)
+							if err := watcher.Add(path); err == nil {
+								slog.Debug("Successfully re-added watch", "path", path, "attempt", attempt+1)
 								return
 							} else if attempt == 4 {
 								// Only log error on final attempt
-								slog.Error("Failed to re-add watch after multiple attempts", "error", err, "path", configPath)
+								slog.Error("Failed to re-add watch after multiple attempts", "error", err, "path", path)
 							}
 						}
 					}()
@@ -2864,12 +3891,13 @@ func (d *Daemon) monitorAdoptedTunnel(alias string, pid int) {
 
 				// Log disconnect event
 				if d.database != nil {
-					d.database.LogTunnelEvent(alias, "disconnect", "Adopted tunnel process died")
+					d.logTunnelEvent(alias, "disconnect", "Adopted tunnel process died", "")
 				}
 
 				// Mark as disconnected
 				tunnel.State = StateDisconnected
 				tunnel.DisconnectedTime = time.Now()
+				resetRetriesIfStable(alias, &tunnel)
 				d.tunnels[alias] = tunnel
 
 				// Get max retries from config
@@ -2881,14 +3909,16 @@ func (d *Daemon) monitorAdoptedTunnel(alias string, pid int) {
 					delete(d.tunnels, alias)
 
 					if tunnel.RetryCount >= maxRetries {
-						slog.Info("Adopted tunnel exceeded max retry attempts, giving up",
+						slog.Warn("Adopted tunnel exceeded max retry attempts, giving up",
 							"alias", alias,
 							"max_retries", maxRetries)
 
 						if d.database != nil {
 							details := fmt.Sprintf("Max retries (%d) exceeded", maxRetries)
+							d.flushTunnelEvents(alias)
 							d.database.LogTunnelEvent(alias, "max_retries_exceeded", details)
 						}
+						d.executeGiveUpHooks(alias)
 					} else {
 						slog.Info("Adopted tunnel auto-reconnect disabled, not reconnecting", "alias", alias)
 					}
@@ -2909,6 +3939,7 @@ func (d *Daemon) monitorAdoptedTunnel(alias string, pid int) {
 				// Calculate backoff delay
 				backoff := calculateBackoff(tunnel.RetryCount)
 				tunnel.RetryCount++
+				d.observeBackoff(alias, backoff)
 				tunnel.LastRetryTime = time.Now()
 				tunnel.State = StateReconnecting
 				tunnel.NextRetryTime = time.Now().Add(backoff)
@@ -2962,11 +3993,12 @@ func (d *Daemon) monitorAdoptedTunnel(alias string, pid int) {
 				// startTunnel() will create a fresh entry with proper monitoring
 				// Preserve environment from the existing tunnel
 				env := tunnel.Environment
+				sshOverrides := tunnel.SSHOverrides
 				delete(d.tunnels, alias)
 				d.mu.Unlock()
 
 				// Start the tunnel (this creates a new SSH process)
-				response := d.startTunnel(alias, env)
+				response := d.startTunnel(alias, env, sshOverrides, ReasonReconnect)
 
 				// Check if reconnection succeeded
 				hasError := false
@@ -3009,6 +4041,19 @@ func (d *Daemon) executeTunnelHooks(alias, hookType string, hooks []core.HookCon
 	}
 }
 
+// executeGiveUpHooks runs the on_give_up hooks once a tunnel won't be
+// reconnected, because it exceeded max_retries or failed in a way retrying
+// can't fix.
+// Order: specific hooks first, then global hooks, like after_connect.
+func (d *Daemon) executeGiveUpHooks(alias string) {
+	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil && tunnelConfig.Hooks != nil {
+		d.executeTunnelHooks(alias, "on_give_up", tunnelConfig.Hooks.OnGiveUp, StateDisconnected)
+	}
+	if core.Config.GlobalTunnelHooks != nil {
+		d.executeTunnelHooks(alias, "on_give_up", core.Config.GlobalTunnelHooks.OnGiveUp, StateDisconnected)
+	}
+}
+
 // executeSingleTunnelHook executes a single tunnel hook with timeout
 func (d *Daemon) executeSingleTunnelHook(alias, hookType string, hook core.HookConfig, tunnelState TunnelState) {
 	startTime := time.Now()
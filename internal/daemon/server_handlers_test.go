@@ -1,15 +1,21 @@
 package daemon
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"go.olrik.dev/overseer/internal/awareness"
 	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/keyring"
 )
 
 // quietLogger suppresses default slog output during tests and restores it after.
@@ -420,10 +426,115 @@ func TestGetStatus(t *testing.T) {
 		if statuses[0].NextRetry == "" {
 			t.Error("expected next retry time to be set")
 		}
+		if statuses[0].NextRetryIn == nil {
+			t.Fatal("expected next retry countdown to be set")
+		}
+		if got := *statuses[0].NextRetryIn; got <= 0 || got > 30 {
+			t.Errorf("expected countdown within (0, 30] seconds, got %d", got)
+		}
+		at, err := time.Parse(time.RFC3339, statuses[0].NextRetry)
+		if err != nil {
+			t.Fatalf("invalid next retry timestamp: %v", err)
+		}
+		if diff := time.Until(at) - time.Duration(*statuses[0].NextRetryIn)*time.Second; diff < -2*time.Second || diff > 2*time.Second {
+			t.Errorf("countdown %ds does not match timestamp %s", *statuses[0].NextRetryIn, statuses[0].NextRetry)
+		}
 		if statuses[0].DisconnectedTime == "" {
 			t.Error("expected disconnected time to be set for reconnecting tunnel")
 		}
 	})
+
+	t.Run("overdue retry counts down to zero", func(t *testing.T) {
+		d := &Daemon{
+			tunnels: map[string]Tunnel{
+				"server1": {
+					State:         StateReconnecting,
+					NextRetryTime: time.Now().Add(-5 * time.Second),
+				},
+			},
+		}
+
+		statuses := d.getStatus().Data.([]DaemonStatus)
+		if statuses[0].NextRetryIn == nil || *statuses[0].NextRetryIn != 0 {
+			t.Errorf("expected countdown of 0, got %v", statuses[0].NextRetryIn)
+		}
+	})
+
+	t.Run("description from tunnel config round-trips", func(t *testing.T) {
+		oldConfig := core.Config
+		t.Cleanup(func() { core.Config = oldConfig })
+		core.Config = core.GetDefaultConfig()
+		core.Config.Tunnels["prod-db"] = &core.TunnelConfig{Name: "prod-db", Description: "Prod DB via bastion"}
+
+		d := &Daemon{
+			tunnels: map[string]Tunnel{
+				"prod-db": {State: StateConnected},
+				"other":   {State: StateConnected},
+			},
+		}
+
+		data, err := json.Marshal(d.getStatus().Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var statuses []DaemonStatus
+		if err := json.Unmarshal(data, &statuses); err != nil {
+			t.Fatal(err)
+		}
+
+		descriptions := map[string]string{}
+		for _, status := range statuses {
+			descriptions[status.Hostname] = status.Description
+		}
+		if descriptions["prod-db"] != "Prod DB via bastion" {
+			t.Errorf("expected description to round-trip, got %q", descriptions["prod-db"])
+		}
+		if descriptions["other"] != "" {
+			t.Errorf("expected no description for unconfigured tunnel, got %q", descriptions["other"])
+		}
+	})
+}
+
+func TestGetStatus_DaemonInfo(t *testing.T) {
+	started := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+	d := &Daemon{
+		tunnels:   map[string]Tunnel{},
+		isRemote:  true,
+		startTime: started,
+	}
+
+	resp := d.getStatus()
+	if resp.Daemon == nil {
+		t.Fatal("expected daemon metadata in STATUS response")
+	}
+	if resp.Daemon.Version != core.Version {
+		t.Errorf("expected version %q, got %q", core.Version, resp.Daemon.Version)
+	}
+	if resp.Daemon.Pid != os.Getpid() {
+		t.Errorf("expected pid %d, got %d", os.Getpid(), resp.Daemon.Pid)
+	}
+	if !resp.Daemon.IsRemote {
+		t.Error("expected is_remote=true")
+	}
+	startTime, err := time.Parse(time.RFC3339, resp.Daemon.StartTime)
+	if err != nil {
+		t.Fatalf("expected RFC3339 start_time, got %q: %v", resp.Daemon.StartTime, err)
+	}
+	if !startTime.Equal(started) {
+		t.Errorf("expected start_time %v, got %v", started, startTime)
+	}
+
+	// Metadata must sit beside data so existing clients still decode the tunnel list
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(resp.ToJSON()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded["daemon"]; !ok {
+		t.Error("expected 'daemon' key in serialized STATUS response")
+	}
+	if string(decoded["data"]) != "[]" {
+		t.Errorf("expected data to remain the tunnel array, got %s", decoded["data"])
+	}
 }
 
 func TestGetVersion(t *testing.T) {
@@ -489,6 +600,186 @@ func TestHandleAskpass(t *testing.T) {
 			t.Errorf("expected ERROR for wrong alias, got %q", resp.Messages[0].Status)
 		}
 	})
+
+	t.Run("one-time password is answered once", func(t *testing.T) {
+		d := &Daemon{
+			askpassTokens: map[string]string{
+				"valid-token": "otp-no-password",
+			},
+			askpassOTPs: map[string]*askpassOTP{
+				"valid-token": {code: "123456"},
+			},
+		}
+
+		resp := d.handleAskpass("otp-no-password", "valid-token")
+		if resp.Messages[0].Status != "INFO" || resp.Messages[0].Message != "123456" {
+			t.Fatalf("expected the one-time password, got %+v", resp.Messages[0])
+		}
+		if _, exists := d.askpassOTPs["valid-token"]; exists {
+			t.Error("expected the one-time password to be invalidated after use")
+		}
+
+		// No password is stored, so a second prompt gets nothing
+		resp = d.handleAskpass("otp-no-password", "valid-token")
+		if resp.Messages[0].Status != "ERROR" || resp.Messages[0].Message == "123456" {
+			t.Errorf("expected the one-time password not to be answered twice, got %+v", resp.Messages[0])
+		}
+	})
+
+	t.Run("one-time password after stored password", func(t *testing.T) {
+		d := &Daemon{
+			askpassTokens: map[string]string{
+				"valid-token": "otp-no-password",
+			},
+			askpassOTPs: map[string]*askpassOTP{
+				"valid-token": {code: "123456", afterPassword: true},
+			},
+		}
+
+		// The first prompt is for the stored password
+		resp := d.handleAskpass("otp-no-password", "valid-token")
+		if resp.Messages[0].Message == "123456" {
+			t.Fatal("expected the first prompt not to get the one-time password")
+		}
+
+		resp = d.handleAskpass("otp-no-password", "valid-token")
+		if resp.Messages[0].Status != "INFO" || resp.Messages[0].Message != "123456" {
+			t.Errorf("expected the one-time password on the second prompt, got %+v", resp.Messages[0])
+		}
+	})
+
+	t.Run("password from password_command", func(t *testing.T) {
+		oldConfig := core.Config
+		t.Cleanup(func() { core.Config = oldConfig })
+
+		// A fake password manager that prints the password with surrounding whitespace
+		manager := filepath.Join(t.TempDir(), "fake-pass")
+		script := "#!/bin/sh\n[ \"$1\" = show ] || exit 1\nprintf '  s3cret for %s \\n' \"$OVERSEER_TUNNEL_ALIAS\"\n"
+		if err := os.WriteFile(manager, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		core.Config = &core.Configuration{Tunnels: map[string]*core.TunnelConfig{
+			"vpn":    {Name: "vpn", PasswordCommand: manager + " show ssh/vpn"},
+			"broken": {Name: "broken", PasswordCommand: manager + " fail"},
+		}}
+		d := &Daemon{
+			ctx: context.Background(),
+			askpassTokens: map[string]string{
+				"vpn-token":    "vpn",
+				"broken-token": "broken",
+			},
+		}
+
+		resp := d.handleAskpass("vpn", "vpn-token")
+		if resp.Messages[0].Status != "INFO" || resp.Messages[0].Message != "s3cret for vpn" {
+			t.Errorf("expected the trimmed output of password_command, got %+v", resp.Messages[0])
+		}
+
+		resp = d.handleAskpass("broken", "broken-token")
+		if resp.Messages[0].Status != "ERROR" || resp.Messages[0].Message != "" {
+			t.Errorf("expected an empty ERROR when password_command fails, got %+v", resp.Messages[0])
+		}
+	})
+}
+
+func TestRunPasswordCommand(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := runPasswordCommand(ctx, "vpn", "echo oops >&2; exit 3"); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("expected the command's stderr in the error, got %v", err)
+	}
+	if _, err := runPasswordCommand(ctx, "vpn", "printf '\\n'"); err == nil || !strings.Contains(err.Error(), "no password") {
+		t.Errorf("expected an error for empty output, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := runPasswordCommand(cancelled, "vpn", "sleep 10"); err == nil {
+		t.Error("expected an error when the context is done")
+	}
+}
+
+func TestTunnelHasPassword_PasswordCommand(t *testing.T) {
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+
+	core.Config = &core.Configuration{Tunnels: map[string]*core.TunnelConfig{
+		"vpn": {Name: "vpn", PasswordCommand: "pass show ssh/vpn"},
+	}}
+	if keyring.HasPassword("vpn") {
+		t.Skip("a password is stored in the keyring for vpn")
+	}
+
+	hasPassword, err := tunnelHasPassword("vpn")
+	if err != nil || !hasPassword {
+		t.Errorf("tunnelHasPassword(vpn) = %v, %v, want true, nil", hasPassword, err)
+	}
+}
+
+func TestConfigureAskpass(t *testing.T) {
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+
+	hasEnv := func(cmd *exec.Cmd, prefix string) bool {
+		return slices.ContainsFunc(cmd.Env, func(kv string) bool { return strings.HasPrefix(kv, prefix) })
+	}
+
+	t.Run("custom helper", func(t *testing.T) {
+		helper := filepath.Join(t.TempDir(), "ssh-askpass")
+		if err := os.WriteFile(helper, []byte("#!/bin/sh\necho secret\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		core.Config = &core.Configuration{SSH: core.SSHConfig{Askpass: helper}}
+
+		// The custom helper is used whether or not a password is stored
+		for _, hasPassword := range []bool{false, true} {
+			cmd := exec.Command("ssh")
+			token, err := configureAskpass(cmd, "server1", hasPassword)
+			if err != nil {
+				t.Fatalf("configureAskpass() error: %v", err)
+			}
+			if token != "" {
+				t.Errorf("expected no token for a custom helper, got %q", token)
+			}
+			if !slices.Contains(cmd.Env, "SSH_ASKPASS="+helper) || !slices.Contains(cmd.Env, "SSH_ASKPASS_REQUIRE=force") {
+				t.Errorf("expected the custom helper to be wired, got env %v", cmd.Env)
+			}
+			if hasEnv(cmd, "OVERSEER_TUNNEL_TOKEN=") {
+				t.Error("expected no overseer token in the environment")
+			}
+		}
+	})
+
+	t.Run("custom helper not executable", func(t *testing.T) {
+		helper := filepath.Join(t.TempDir(), "ssh-askpass")
+		if err := os.WriteFile(helper, []byte("not a program"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		core.Config = &core.Configuration{SSH: core.SSHConfig{Askpass: helper}}
+
+		if _, err := configureAskpass(exec.Command("ssh"), "server1", false); err == nil {
+			t.Error("expected an error for a helper that isn't executable")
+		}
+	})
+
+	t.Run("overseer helper", func(t *testing.T) {
+		core.Config = &core.Configuration{}
+
+		cmd := exec.Command("ssh")
+		token, err := configureAskpass(cmd, "server1", true)
+		if err != nil {
+			t.Fatalf("configureAskpass() error: %v", err)
+		}
+		if token == "" || !slices.Contains(cmd.Env, "OVERSEER_TUNNEL_TOKEN="+token) {
+			t.Errorf("expected overseer askpass with token, got token %q env %v", token, cmd.Env)
+		}
+
+		// Nothing is configured without a stored password
+		cmd = exec.Command("ssh")
+		if token, err := configureAskpass(cmd, "server1", false); err != nil || token != "" || hasEnv(cmd, "SSH_ASKPASS=") {
+			t.Errorf("expected no askpass without a stored password, got token %q err %v env %v", token, err, cmd.Env)
+		}
+	})
 }
 
 func TestResetRetries(t *testing.T) {
@@ -597,6 +888,33 @@ func TestHandleCompanionInit(t *testing.T) {
 		}
 	})
 
+	t.Run("valid token, command given as a list", func(t *testing.T) {
+		core.Config = &core.Configuration{
+			Tunnels: map[string]*core.TunnelConfig{
+				"server1": {
+					Companions: []core.CompanionConfig{
+						{Name: "comp1", Command: `/usr/bin/proxy --config "/etc/my proxy.conf"`, Args: []string{"/usr/bin/proxy", "--config", "/etc/my proxy.conf"}},
+					},
+				},
+			},
+		}
+
+		d := &Daemon{
+			askpassTokens: map[string]string{
+				"valid-token": "server1",
+			},
+		}
+
+		resp := d.handleCompanionInit("server1", "comp1", "valid-token")
+		if resp.Messages[0].Status != "INFO" {
+			t.Errorf("expected INFO, got %q", resp.Messages[0].Status)
+		}
+		want := []string{"/usr/bin/proxy", "--config", "/etc/my proxy.conf"}
+		if !reflect.DeepEqual(resp.Data, want) {
+			t.Errorf("expected args %q as data, got %v", want, resp.Data)
+		}
+	})
+
 	t.Run("valid token, tunnel not in config", func(t *testing.T) {
 		core.Config = &core.Configuration{
 			Tunnels: map[string]*core.TunnelConfig{},
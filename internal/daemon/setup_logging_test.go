@@ -1,8 +1,16 @@
 package daemon
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/core"
 )
 
 func TestSetupLogging(t *testing.T) {
@@ -40,3 +48,96 @@ func TestSetupLogging_DoesNotPanic(t *testing.T) {
 		t.Error("expected non-nil slog handler after setupLogging")
 	}
 }
+
+func TestSetupLogging_ForegroundJSON(t *testing.T) {
+	old := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(old) })
+
+	d := &Daemon{logBroadcast: NewLogBroadcaster(100)}
+	if err := d.SetForeground(LogFormatJSON); err != nil {
+		t.Fatalf("SetForeground failed: %v", err)
+	}
+	var out bytes.Buffer
+	d.logOutput = &out
+
+	ch := d.logBroadcast.Subscribe()
+	defer d.logBroadcast.Unsubscribe(ch)
+
+	d.setupLogging()
+	slog.Info("tunnel connected", "alias", "db")
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", out.String(), err)
+	}
+	if record["msg"] != "tunnel connected" || record["alias"] != "db" {
+		t.Errorf("unexpected record: %v", record)
+	}
+
+	// Clients still get the text output
+	if line := <-ch; strings.HasPrefix(line, "{") || !strings.Contains(line, "tunnel connected") {
+		t.Errorf("expected a text line for clients, got %q", line)
+	}
+}
+
+func TestSetForeground_InvalidFormat(t *testing.T) {
+	d := &Daemon{}
+	if err := d.SetForeground("xml"); err == nil {
+		t.Error("expected an error for an unknown log format")
+	}
+	if d.foreground {
+		t.Error("expected the daemon to stay in the background on error")
+	}
+}
+
+func TestRun_ForegroundReturnsSocketError(t *testing.T) {
+	old := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(old) })
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		// The socket can't be bound in a directory that doesn't exist
+		ConfigPath: filepath.Join(t.TempDir(), "missing"),
+	}
+
+	d := New()
+	if err := d.SetForeground(LogFormatText); err != nil {
+		t.Fatalf("SetForeground failed: %v", err)
+	}
+	d.logOutput = &bytes.Buffer{}
+
+	done := make(chan error, 1)
+	go func() { done <- d.Run() }()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "could not create socket listener") {
+			t.Errorf("expected a socket listener error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
+
+func TestRun_ReturnsErrorWhenAlreadyRunning(t *testing.T) {
+	old := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(old) })
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{ConfigPath: t.TempDir()}
+
+	// Another daemon owns the socket
+	listener, err := listenSocket(core.GetSocketPath())
+	if err != nil {
+		t.Fatalf("listenSocket failed: %v", err)
+	}
+	defer listener.Close()
+
+	d := New()
+	d.logOutput = &bytes.Buffer{}
+	if err := d.Run(); !errors.Is(err, ErrDaemonAlreadyRunning) {
+		t.Errorf("expected ErrDaemonAlreadyRunning, got %v", err)
+	}
+}
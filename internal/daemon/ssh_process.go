@@ -0,0 +1,276 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errSSHTerminated is reported by verifyConnection when stderr closes before
+// the connection could be verified
+var errSSHTerminated = errors.New("SSH process terminated unexpectedly")
+
+// sshFailure is a connection failure recognised in SSH's output. A fatal
+// failure, such as a rejected key or a changed host key, won't go away by
+// retrying, unlike e.g. a timeout while the network is flaky.
+type sshFailure struct {
+	message string
+	fatal   bool
+}
+
+func (e *sshFailure) Error() string {
+	return e.message
+}
+
+// sshFailures maps the SSH output verifyConnection looks for to the failure it indicates
+var sshFailures = []struct {
+	output  string
+	failure *sshFailure
+}{
+	{"Permission denied", &sshFailure{"authentication failed", true}},
+	{"Connection refused", &sshFailure{"connection refused", false}},
+	{"No route to host", &sshFailure{"no route to host", false}},
+	{"Connection timed out", &sshFailure{"connection timed out", false}},
+	{"Could not resolve hostname", &sshFailure{"could not resolve hostname", false}},
+	{"Host key verification failed", &sshFailure{"host key verification failed", true}},
+	{"Too many authentication failures", &sshFailure{"too many authentication failures", true}},
+}
+
+// sshSessionSuccesses is SSH output showing that the session of a tunnel
+// without a remote command (-N) is established
+var sshSessionSuccesses = []string{
+	"Entering interactive session",
+	"pledge: network",
+}
+
+// sshCommandSuccesses is SSH output showing that the remote command of a
+// tunnel was sent. SSH prints the -N session lines before the command is
+// requested, so they don't tell whether the session accepted it.
+var sshCommandSuccesses = []string{
+	"Sending command:",
+	"Sending subsystem:",
+}
+
+// sshMuxSuccesses is SSH output showing that the tunnel joined an existing
+// ControlMaster as a mux client. The master already authenticated, so these
+// take the place of the usual "Authenticated to" and session lines.
+var sshMuxSuccesses = []string{
+	"mux_client_request_session: master session id",
+}
+
+// isFatalSSHError reports whether err is a failure that retrying can't fix
+func isFatalSSHError(err error) bool {
+	var failure *sshFailure
+	return errors.As(err, &failure) && failure.fatal
+}
+
+// sshFailureCategory names the kind of failure for a failed connection
+// attempt. Failures not recognised in SSH's output count as the SSH process
+// exiting.
+func sshFailureCategory(err error) string {
+	var failure *sshFailure
+	if errors.As(err, &failure) {
+		return failure.message
+	}
+	return "ssh exited"
+}
+
+// earlyExitGrace is how long to wait for the stderr reader (or the exit
+// status) to catch up once the other side has noticed SSH is gone
+const earlyExitGrace = 500 * time.Millisecond
+
+// stderrTailLines is the number of stderr lines kept for error reporting
+const stderrTailLines = 5
+
+// sshProcess owns a launched SSH process. Its stderr is read through a plain
+// pipe (not cmd.StderrPipe) so that cmd.Wait can run in the background
+// straight after Start without closing the reader. This lets the connect path
+// notice an SSH that exits before verification, and lets monitorTunnel reuse
+// the exit status instead of calling cmd.Wait a second time.
+type sshProcess struct {
+	cmd     *exec.Cmd
+	stderr  *os.File // Read end of the stderr pipe
+	writer  *os.File // Write end, handed to SSH and closed in the daemon after Start
+	tail    *stderrTail
+	done    chan struct{}
+	waitErr error // Result of cmd.Wait, valid once done is closed
+}
+
+// newSSHProcess connects a pipe to cmd's stderr. Must be called before start.
+func newSSHProcess(cmd *exec.Cmd) (*sshProcess, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = w
+	return &sshProcess{
+		cmd:    cmd,
+		stderr: r,
+		writer: w,
+		tail:   &stderrTail{max: stderrTailLines},
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// start launches SSH and begins waiting for it to exit in the background
+func (p *sshProcess) start() error {
+	if err := p.cmd.Start(); err != nil {
+		p.writer.Close()
+		p.stderr.Close()
+		return err
+	}
+	// SSH holds its own copy; closing ours lets the reader see EOF when it exits
+	p.writer.Close()
+
+	go func() {
+		p.waitErr = p.cmd.Wait()
+		close(p.done)
+	}()
+	return nil
+}
+
+// Stderr returns SSH's stderr for verifyConnection. Everything read through
+// it is also recorded in the tail used by exitError.
+func (p *sshProcess) Stderr() io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(p.stderr, p.tail), p.stderr}
+}
+
+// exited reports whether SSH has exited
+func (p *sshProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait blocks until SSH exits and returns the result of cmd.Wait
+func (p *sshProcess) wait() error {
+	<-p.done
+	return p.waitErr
+}
+
+// kill kills SSH unless it has already exited
+func (p *sshProcess) kill() {
+	if p.exited() || p.cmd.Process == nil {
+		return
+	}
+	p.cmd.Process.Kill()
+}
+
+// awaitVerification waits for verifyConnection to report on the connection.
+// If SSH exits before that, the returned error carries the exit status and
+// the last lines SSH wrote to stderr rather than a generic termination error.
+func (p *sshProcess) awaitVerification(result <-chan error) error {
+	select {
+	case err := <-result:
+		if !errors.Is(err, errSSHTerminated) {
+			return err
+		}
+		// stderr closed, so SSH is on its way out - pick up its exit status
+		select {
+		case <-p.done:
+		case <-time.After(earlyExitGrace):
+			return err
+		}
+	case <-p.done:
+		// Let the reader process what SSH wrote before exiting, as it may
+		// recognise a more specific failure (e.g. authentication)
+		select {
+		case err := <-result:
+			if !errors.Is(err, errSSHTerminated) {
+				return err
+			}
+		case <-time.After(earlyExitGrace):
+		}
+	}
+	return p.exitError()
+}
+
+// exitError describes an SSH process that exited before it was verified.
+// Must only be called once SSH has exited.
+func (p *sshProcess) exitError() error {
+	status := "exit status 0"
+	if p.waitErr != nil {
+		status = p.waitErr.Error()
+	}
+	msg := fmt.Sprintf("SSH exited before the connection was verified (%s)", status)
+	if lines := p.tail.Lines(); len(lines) > 0 {
+		msg += ": " + strings.Join(lines, " / ")
+	}
+	return errors.New(msg)
+}
+
+// failureDetails describes a failed connection attempt: the error followed by
+// the last lines SSH wrote to stderr. An exit error carries them already.
+func (p *sshProcess) failureDetails(err error) string {
+	var failure *sshFailure
+	if errors.As(err, &failure) {
+		if lines := p.tail.Lines(); len(lines) > 0 {
+			return err.Error() + ": " + strings.Join(lines, " / ")
+		}
+	}
+	return err.Error()
+}
+
+// stderrTail keeps the last few meaningful lines written to it
+type stderrTail struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial string
+}
+
+func (t *stderrTail) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := t.partial + string(b)
+	parts := strings.Split(data, "\n")
+	t.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		t.add(line)
+	}
+	return len(b), nil
+}
+
+func (t *stderrTail) add(line string) {
+	line = strings.TrimSpace(line)
+	if isStderrNoise(line) {
+		return
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+// Lines returns the recorded lines, including an unterminated last line
+func (t *stderrTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := append([]string(nil), t.lines...)
+	if last := strings.TrimSpace(t.partial); !isStderrNoise(last) {
+		lines = append(lines, last)
+		if len(lines) > t.max {
+			lines = lines[len(lines)-t.max:]
+		}
+	}
+	return lines
+}
+
+// isStderrNoise reports whether a stderr line is verbose output (version
+// banner, debug messages) that would drown out the actual error
+func isStderrNoise(line string) bool {
+	return line == "" || strings.HasPrefix(line, "debug") || strings.HasPrefix(line, "OpenSSH_")
+}
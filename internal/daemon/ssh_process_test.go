@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/core"
+)
+
+func TestSSHProcess_ExitsBeforeVerification(t *testing.T) {
+	quietLogger(t)
+	d := setupDaemonForVerify(t, "early-exit")
+
+	cmd := exec.Command("sh", "-c", `echo "debug1: Reading configuration data" >&2; echo "command-line: Bad configuration option: bogus" >&2; exit 3`)
+	proc, err := newSSHProcess(cmd)
+	if err != nil {
+		t.Fatalf("newSSHProcess() error: %v", err)
+	}
+	if err := proc.start(); err != nil {
+		t.Fatalf("start() error: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go d.verifyConnection(proc.Stderr(), "early-exit", result)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- proc.awaitVerification(result) }()
+
+	select {
+	case err = <-errCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for early exit to be detected")
+	}
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "exit status 3") {
+		t.Errorf("expected exit status in error, got %q", msg)
+	}
+	if !strings.Contains(msg, "Bad configuration option: bogus") {
+		t.Errorf("expected stderr tail in error, got %q", msg)
+	}
+	if strings.Contains(msg, "debug1") {
+		t.Errorf("expected debug output to be left out of error, got %q", msg)
+	}
+
+	// Killing an exited process is a no-op, and the exit status stays available
+	proc.kill()
+	if err := proc.wait(); err == nil {
+		t.Error("expected wait() to return the exit error")
+	}
+}
+
+func TestStartTunnel_SSHExitsImmediately(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		Tunnels:   map[string]*core.TunnelConfig{},
+		SSH:       core.SSHConfig{},
+	}
+
+	// An unknown option makes ssh exit before it tries to connect
+	sshConfig := filepath.Join(t.TempDir(), "ssh_config")
+	if err := os.WriteFile(sshConfig, []byte("Host *\n  BogusOption yes\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := New()
+	d.ctx, d.cancelFunc = context.WithCancel(context.Background())
+	t.Cleanup(d.cancelFunc)
+	d.SetSSHConfigFile(sshConfig)
+
+	resp := d.startTunnel("bad-option", nil, nil, ReasonManual)
+
+	var failure string
+	for _, m := range resp.Messages {
+		if m.Status == "ERROR" && strings.Contains(m.Message, "failed to connect") {
+			failure = m.Message
+		}
+	}
+	if failure == "" {
+		t.Fatalf("expected a connect failure message, got %+v", resp.Messages)
+	}
+	if !strings.Contains(failure, "exit status 255") {
+		t.Errorf("expected ssh exit status in %q", failure)
+	}
+	if !strings.Contains(failure, "Bad configuration option") {
+		t.Errorf("expected ssh stderr in %q", failure)
+	}
+	if strings.Contains(failure, "OpenSSH_") {
+		t.Errorf("expected version banner to be left out of %q", failure)
+	}
+
+	d.mu.Lock()
+	_, exists := d.tunnels["bad-option"]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected failed tunnel to be removed")
+	}
+}
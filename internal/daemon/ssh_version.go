@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sshVersionTimeout bounds `ssh -V`, which only prints the version and exits
+const sshVersionTimeout = 5 * time.Second
+
+// sshVersionRe matches the implementation and version at the start of the
+// `ssh -V` output, e.g. "OpenSSH_9.6p1 Ubuntu-3ubuntu13.5, OpenSSL 3.0.13",
+// "OpenSSH_for_Windows_9.5p1, LibreSSL 3.8.2" or "Dropbear v2022.83".
+var sshVersionRe = regexp.MustCompile(`^([A-Za-z]+)(?:_for_Windows)?[_ ]v?([0-9][0-9A-Za-z.]*)`)
+
+// sshClientVersion is the ssh client tunnels are run with, as reported by `ssh -V`
+type sshClientVersion struct {
+	Implementation string // e.g. "OpenSSH", empty when the output wasn't recognised
+	Version        string // e.g. "9.6p1"
+	Raw            string // First line of the `ssh -V` output
+}
+
+func (v sshClientVersion) String() string {
+	if v.Implementation == "" {
+		return v.Raw
+	}
+	return v.Implementation + " " + v.Version
+}
+
+// compatible reports whether verifyConnection understands the client's
+// output. The lines it looks for are OpenSSH's debug output.
+func (v sshClientVersion) compatible() bool {
+	return v.Implementation == "OpenSSH"
+}
+
+// parseSSHVersion parses the output of `ssh -V`
+func parseSSHVersion(output string) sshClientVersion {
+	var version sshClientVersion
+	for line := range strings.Lines(output) {
+		if line = strings.TrimSpace(line); line != "" {
+			version.Raw = line
+			break
+		}
+	}
+	if matches := sshVersionRe.FindStringSubmatch(version.Raw); len(matches) == 3 {
+		version.Implementation = matches[1]
+		version.Version = matches[2]
+	}
+	return version
+}
+
+// probeSSHVersion runs `ssh -V` and parses its output. OpenSSH prints the
+// version on stderr.
+func probeSSHVersion() (sshClientVersion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sshVersionTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "ssh", "-V").CombinedOutput()
+	version := parseSSHVersion(string(output))
+	if version.Raw == "" {
+		if err == nil {
+			err = fmt.Errorf("no output from ssh -V")
+		}
+		return version, err
+	}
+	return version, nil
+}
+
+// detectSSHVersion records the version of the ssh client for diagnostics,
+// warning when it isn't one whose output verifyConnection understands
+func (d *Daemon) detectSSHVersion() {
+	version, err := probeSSHVersion()
+	if err != nil {
+		slog.Warn("Could not determine the SSH client version", "error", err)
+		return
+	}
+	d.sshVersion = version
+	slog.Info(fmt.Sprintf("SSH client: %s", version.Raw))
+
+	if !version.compatible() {
+		slog.Warn(fmt.Sprintf("SSH client %s is not OpenSSH, connections may not be recognised as established", version))
+	}
+}
@@ -0,0 +1,74 @@
+package daemon
+
+import "testing"
+
+func TestParseSSHVersion(t *testing.T) {
+	tests := []struct {
+		name           string
+		output         string
+		implementation string
+		version        string
+		compatible     bool
+	}{
+		{
+			name:           "OpenSSH on Linux",
+			output:         "OpenSSH_9.6p1 Ubuntu-3ubuntu13.5, OpenSSL 3.0.13 30 Jan 2024\n",
+			implementation: "OpenSSH",
+			version:        "9.6p1",
+			compatible:     true,
+		},
+		{
+			name:           "OpenSSH on macOS",
+			output:         "OpenSSH_9.8p1, LibreSSL 3.3.6\n",
+			implementation: "OpenSSH",
+			version:        "9.8p1",
+			compatible:     true,
+		},
+		{
+			name:           "OpenSSH for Windows",
+			output:         "OpenSSH_for_Windows_9.5p1, LibreSSL 3.8.2\r\n",
+			implementation: "OpenSSH",
+			version:        "9.5p1",
+			compatible:     true,
+		},
+		{
+			name:           "Dropbear",
+			output:         "\nDropbear v2022.83\n",
+			implementation: "Dropbear",
+			version:        "2022.83",
+			compatible:     false,
+		},
+		{
+			name:       "unrecognised output",
+			output:     "plink: Release 0.78\n",
+			compatible: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSSHVersion(tt.output)
+			if got.Implementation != tt.implementation || got.Version != tt.version {
+				t.Errorf("parseSSHVersion(%q) = %q %q, want %q %q", tt.output, got.Implementation, got.Version, tt.implementation, tt.version)
+			}
+			if got.Raw == "" {
+				t.Errorf("expected the first line to be kept, got %+v", got)
+			}
+			if got.compatible() != tt.compatible {
+				t.Errorf("compatible() = %v, want %v", got.compatible(), tt.compatible)
+			}
+		})
+	}
+}
+
+func TestGetVersion_IncludesSSHVersion(t *testing.T) {
+	d := &Daemon{sshVersion: parseSSHVersion("OpenSSH_9.8p1, LibreSSL 3.3.6")}
+
+	data, ok := d.getVersion().Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("expected version data")
+	}
+	if data["ssh_version"] != "OpenSSH_9.8p1, LibreSSL 3.3.6" {
+		t.Errorf("ssh_version = %v", data["ssh_version"])
+	}
+}
@@ -2,7 +2,9 @@ package daemon
 
 import (
 	"context"
+	"maps"
 	"os/exec"
+	"slices"
 	"strings"
 	"syscall"
 	"testing"
@@ -37,6 +39,7 @@ func TestStartCompanions_AlreadyRunning(t *testing.T) {
 			output:      broadcaster,
 			ctx:         ctx,
 			cancel:      cancel,
+			Config:      core.CompanionConfig{Name: "running-comp", Command: "echo hello"},
 		},
 	}
 
@@ -88,6 +91,7 @@ func TestStartCompanions_AlreadyReady(t *testing.T) {
 			output:      broadcaster,
 			ctx:         ctx,
 			cancel:      cancel,
+			Config:      core.CompanionConfig{Name: "ready-comp", Command: "echo hello"},
 		},
 	}
 
@@ -114,6 +118,100 @@ func TestStartCompanions_AlreadyReady(t *testing.T) {
 }
 
 
+func TestStartCompanions_AlreadyRunning_ConfigChanged(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	cm := NewCompanionManager()
+	var restarted []string
+	cm.restart = func(proc *CompanionProcess) error {
+		restarted = append(restarted, proc.Name+":"+proc.Config.Command)
+		return nil
+	}
+
+	running := func(config core.CompanionConfig) *CompanionProcess {
+		return &CompanionProcess{
+			Name:        config.Name,
+			TunnelAlias: "my-tunnel",
+			Pid:         99999,
+			State:       CompanionStateRunning,
+			output:      NewLogBroadcaster(100),
+			Config:      config,
+		}
+	}
+
+	// Adopted with the config they were started with
+	cm.companions["my-tunnel"] = map[string]*CompanionProcess{
+		"auth":  running(core.CompanionConfig{Name: "auth", Command: "vpn-auth"}),
+		"proxy": running(core.CompanionConfig{Name: "proxy", Command: "proxy", Timeout: 10 * time.Second}),
+	}
+
+	var progressMessages []string
+	onProgress := func(p CompanionProgress) {
+		progressMessages = append(progressMessages, p.Message)
+	}
+
+	configs := []core.CompanionConfig{
+		{Name: "auth", Command: "vpn-auth --mfa"},
+		// A changed timeout doesn't affect the running process
+		{Name: "proxy", Command: "proxy", Timeout: 30 * time.Second},
+	}
+
+	if err := cm.StartCompanions("my-tunnel", configs, onProgress); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	if len(restarted) != 1 || restarted[0] != "auth:vpn-auth --mfa" {
+		t.Errorf("expected only auth to be restarted with the new command, got %v", restarted)
+	}
+	if !slices.ContainsFunc(progressMessages, func(m string) bool { return strings.Contains(m, "'auth' configuration changed") }) {
+		t.Errorf("expected a configuration changed message for auth, got %q", progressMessages)
+	}
+	if !slices.ContainsFunc(progressMessages, func(m string) bool { return strings.Contains(m, "'proxy' already running") }) {
+		t.Errorf("expected proxy to be skipped, got %q", progressMessages)
+	}
+}
+
+func TestCompanionConfigDrifted(t *testing.T) {
+	base := core.CompanionConfig{
+		Name:        "proxy",
+		Command:     "proxy",
+		Workdir:     "/srv",
+		Environment: map[string]string{"PORT": "8080"},
+		Timeout:     10 * time.Second,
+	}
+
+	tests := []struct {
+		name   string
+		change func(*core.CompanionConfig)
+		want   bool
+	}{
+		{"unchanged", func(c *core.CompanionConfig) {}, false},
+		{"timeout", func(c *core.CompanionConfig) { c.Timeout = time.Minute }, false},
+		{"auto_restart", func(c *core.CompanionConfig) { c.AutoRestart = true }, false},
+		{"command", func(c *core.CompanionConfig) { c.Command = "proxy -v" }, true},
+		{"args", func(c *core.CompanionConfig) { c.Args = []string{"proxy", "-v"} }, true},
+		{"pre_start", func(c *core.CompanionConfig) { c.PreStart = "setup" }, true},
+		{"workdir", func(c *core.CompanionConfig) { c.Workdir = "/tmp" }, true},
+		{"env_file", func(c *core.CompanionConfig) { c.EnvFile = "/srv/.env" }, true},
+		{"environment", func(c *core.CompanionConfig) { c.Environment = map[string]string{"PORT": "9090"} }, true},
+	}
+
+	for _, tt := range tests {
+		config := base
+		config.Environment = maps.Clone(base.Environment)
+		tt.change(&config)
+		if got := companionConfigDrifted(base, config); got != tt.want {
+			t.Errorf("%s: companionConfigDrifted() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestStartCompanions_ExistingStoppedCompanion_RestartFails_Continue(t *testing.T) {
 	quietLogger(t)
 
@@ -263,6 +361,7 @@ func TestStartCompanions_NilProgressCallback(t *testing.T) {
 			output:      broadcaster,
 			ctx:         ctx,
 			cancel:      cancel,
+			Config:      core.CompanionConfig{Name: "comp", Command: "echo hello"},
 		},
 	}
 
@@ -319,7 +418,7 @@ func TestRestartCompanions_WithCompletionWait(t *testing.T) {
 		},
 	}
 
-	err := cm.RestartCompanions("my-tunnel")
+	err := cm.RestartCompanions("my-tunnel", "", nil)
 	// Will try to restart, which may fail (os.Executable not a real companion),
 	// but the code paths for restart + completion wait are exercised
 	_ = err
@@ -368,7 +467,7 @@ func TestRestartCompanions_WithStringWait(t *testing.T) {
 		},
 	}
 
-	err := cm.RestartCompanions("my-tunnel")
+	err := cm.RestartCompanions("my-tunnel", "", nil)
 	// Will fail because string won't be found in time after restart
 	_ = err
 }
@@ -416,6 +515,50 @@ func TestRestartCompanions_WithReadyDelay(t *testing.T) {
 		},
 	}
 
-	err := cm.RestartCompanions("my-tunnel")
+	err := cm.RestartCompanions("my-tunnel", "", nil)
 	_ = err
 }
+
+func TestStartCompanions_PreStartFailureBlocks(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+	}
+
+	cm := NewCompanionManager()
+
+	var progressMessages []string
+	onProgress := func(p CompanionProgress) {
+		progressMessages = append(progressMessages, p.Message)
+	}
+
+	configs := []core.CompanionConfig{
+		{
+			Name:      "prepare",
+			PreStart:  "echo 'cannot create /run/x' >&2; exit 1",
+			Command:   "echo hello",
+			Timeout:   5 * time.Second,
+			OnFailure: "block",
+		},
+	}
+
+	err := cm.StartCompanions("my-tunnel", configs, onProgress)
+	if err == nil {
+		t.Fatal("expected error when pre_start fails with on_failure=block")
+	}
+	if !strings.Contains(err.Error(), "pre_start failed") || !strings.Contains(err.Error(), "cannot create /run/x") {
+		t.Errorf("expected pre_start failure with its output, got: %v", err)
+	}
+
+	if proc := cm.GetCompanion("my-tunnel", "prepare"); proc != nil {
+		t.Errorf("expected companion not to be started, got state %q", proc.State)
+	}
+
+	last := progressMessages[len(progressMessages)-1]
+	if !strings.Contains(last, "failed") {
+		t.Errorf("expected failure progress message, got: %q", last)
+	}
+}
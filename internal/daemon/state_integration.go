@@ -3,6 +3,10 @@ package daemon
 import (
 	"fmt"
 	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"sync"
 	"time"
 
 	"go.olrik.dev/overseer/internal/core"
@@ -11,8 +15,13 @@ import (
 	"go.olrik.dev/overseer/internal/awareness/state"
 )
 
-// stateOrchestrator is the state management system
-var stateOrchestrator *state.Orchestrator
+// stateOrchestrator is the state management system. The sensor watchdog
+// replaces it while other goroutines use it, so read it with
+// GetStateOrchestrator.
+var (
+	stateOrchestratorMu sync.RWMutex
+	stateOrchestrator   *state.Orchestrator
+)
 
 // initStateOrchestrator initializes the new state orchestrator
 func (d *Daemon) initStateOrchestrator() error {
@@ -20,10 +29,11 @@ func (d *Daemon) initStateOrchestrator() error {
 	locations := make(map[string]state.Location)
 	for name, loc := range core.Config.Locations {
 		stateLoc := state.Location{
-			Name:        loc.Name,
-			DisplayName: loc.DisplayName,
-			Conditions:  loc.Conditions,
-			Environment: loc.Environment,
+			Name:                       loc.Name,
+			DisplayName:                loc.DisplayName,
+			Conditions:                 loc.Conditions,
+			Environment:                loc.Environment,
+			OverrideContextEnvironment: loc.OverrideContextEnvironment,
 		}
 		// Convert structured condition if present
 		if loc.Condition != nil {
@@ -58,77 +68,13 @@ func (d *Daemon) initStateOrchestrator() error {
 		locations["unknown"] = defaultUnknown
 	}
 
-	// Convert rules
-	rules := make([]state.Rule, 0, len(core.Config.Contexts)+1)
-	var userUntrusted *state.Rule
-
-	defaultUntrusted := state.Rule{
-		Name:        "untrusted",
-		DisplayName: "Untrusted",
-		Conditions:  map[string][]string{},
-		Environment: make(map[string]string),
-		Actions: state.RuleActions{
-			Connect:    []string{},
-			Disconnect: []string{},
-		},
-	}
-
-	for _, contextRule := range core.Config.Contexts {
-		stateRule := state.Rule{
-			Name:        contextRule.Name,
-			DisplayName: contextRule.DisplayName,
-			Locations:   contextRule.Locations,
-			Conditions:  contextRule.Conditions,
-			Environment: contextRule.Environment,
-			Actions: state.RuleActions{
-				Connect:    contextRule.Actions.Connect,
-				Disconnect: contextRule.Actions.Disconnect,
-			},
-		}
-		if contextRule.Condition != nil {
-			stateRule.Condition = convertCondition(contextRule.Condition)
-		}
-
-		if stateRule.Name == "untrusted" {
-			userUntrusted = &stateRule
-			continue
-		}
-		rules = append(rules, stateRule)
-	}
-
-	// Add untrusted fallback at the end
-	if userUntrusted != nil {
-		rules = append(rules, mergeStateRule(defaultUntrusted, *userUntrusted))
-	} else {
-		rules = append(rules, defaultUntrusted)
-	}
+	rules := buildStateRules()
 
 	// Create env writers
-	var envWriters []state.EnvWriter
-	for _, exportCfg := range core.Config.Exports {
-		var writer state.EnvWriter
-		var err error
-
-		switch exportCfg.Type {
-		case "dotenv":
-			writer, err = state.NewDotenvWriter(exportCfg.Path)
-		case "context":
-			writer, err = state.NewContextWriter(exportCfg.Path)
-		case "location":
-			writer, err = state.NewLocationWriter(exportCfg.Path)
-		case "public_ip":
-			writer, err = state.NewPublicIPWriter(exportCfg.Path)
-		default:
-			slog.Warn("Unknown export type", "type", exportCfg.Type)
-			continue
-		}
+	envWriters := buildEnvWriters(core.Config.Exports)
 
-		if err != nil {
-			slog.Error("Failed to create export writer", "type", exportCfg.Type, "path", exportCfg.Path, "error", err)
-			continue
-		}
-		envWriters = append(envWriters, writer)
-	}
+	// Create writers for context-scoped exports
+	contextEnvWriters := buildContextEnvWriters(core.Config.Contexts)
 
 	// Collect tracked env vars from all rules, locations, and global environment
 	trackedVars := collectTrackedEnvVars(rules, locations, core.Config.Environment)
@@ -166,18 +112,24 @@ func (d *Daemon) initStateOrchestrator() error {
 	}
 
 	// Create orchestrator
-	stateOrchestrator = state.NewOrchestrator(state.OrchestratorConfig{
+	orch := state.NewOrchestrator(state.OrchestratorConfig{
 		Rules:             rules,
 		Locations:         locations,
 		GlobalEnvironment: core.Config.Environment,
 		EnvWriters:        envWriters,
+		ContextEnvWriters: contextEnvWriters,
 		TrackedEnvVars:    trackedVars,
 		PreferredIP:    core.Config.PreferredIP,
+		PublicIPProviders: core.Config.PublicIP.Providers,
+		PublicIPTimeout:   core.Config.PublicIP.Timeout,
+		PublicIPCacheTTL:  core.Config.PublicIP.CacheTTL,
 		OnContextChange: func(from, to state.StateSnapshot, rule *state.Rule) {
 			d.handleNewContextChange(from, to, rule)
 		},
 		OnOnlineChange:      d.handleOnlineChange,
 		DatabaseLogger:      dbLogger,
+		SensorDebounce:      core.Config.SensorDebounce,
+		SensorEnvFile:       core.Config.SensorEnvFile,
 		HistorySize:         200,
 		Logger:              slog.Default(),
 		LocationHooks:       locationHooks,
@@ -188,7 +140,7 @@ func (d *Daemon) initStateOrchestrator() error {
 
 	// Set up hook event logger if database is available
 	if d.database != nil {
-		stateOrchestrator.SetHookEventLogger(func(identifier, eventType, details string) error {
+		orch.SetHookEventLogger(func(identifier, eventType, details string) error {
 			return d.database.LogTunnelEvent(identifier, eventType, details)
 		})
 	}
@@ -198,19 +150,151 @@ func (d *Daemon) initStateOrchestrator() error {
 		slog.Warn("Failed to load sensor state", "error", err)
 	} else if sensorState != nil {
 		slog.Info("Restoring sensor state from hot reload", "sensors", len(sensorState.Sensors))
-		stateOrchestrator.RestoreSensorCache(sensorState.Sensors)
+		orch.RestoreSensorCache(sensorState.Sensors)
 		// Remove the state file after successful restoration
 		if err := RemoveSensorStateFile(); err != nil {
 			slog.Warn("Failed to remove sensor state file", "error", err)
 		}
 	}
 
-	stateOrchestrator.Start()
+	setStateOrchestrator(orch)
+	orch.Start()
+	if d.foreground {
+		d.streamStateLogs(orch)
+	}
 
 	slog.Info("New state orchestrator started")
 	return nil
 }
 
+// buildStateRules converts the configured contexts into state rules.
+// A user-defined "offline" context is matched whenever we are offline and is
+// evaluated first; "untrusted" is always present as the final fallback. Both
+// keep their built-in matching and take display name, environment and actions
+// from the user's definition.
+func buildStateRules() []state.Rule {
+	rules := make([]state.Rule, 0, len(core.Config.Contexts)+1)
+	var userOffline, userUntrusted *state.Rule
+
+	defaultOffline := state.Rule{
+		Name:        "offline",
+		DisplayName: "Offline",
+		Condition:   state.NewBooleanCondition("online", false),
+		Environment: make(map[string]string),
+		Actions: state.RuleActions{
+			Connect:    []string{},
+			Disconnect: []string{},
+		},
+	}
+	defaultUntrusted := state.Rule{
+		Name:        "untrusted",
+		DisplayName: "Untrusted",
+		Conditions:  map[string][]string{},
+		Environment: make(map[string]string),
+		Actions: state.RuleActions{
+			Connect:    []string{},
+			Disconnect: []string{},
+		},
+	}
+
+	for _, contextRule := range core.Config.Contexts {
+		if contextRule.Disabled {
+			slog.Debug("Skipping disabled context", "context", contextRule.Name)
+			continue
+		}
+
+		stateRule := state.Rule{
+			Name:          contextRule.Name,
+			DisplayName:   contextRule.DisplayName,
+			Locations:     contextRule.Locations,
+			LocationsMode: contextRule.LocationsMode,
+			Conditions:    contextRule.Conditions,
+			Environment:   contextRule.Environment,
+			Actions: state.RuleActions{
+				Connect:    contextRule.Actions.Connect,
+				Disconnect: contextRule.Actions.Disconnect,
+			},
+			PreserveExisting: contextRule.PreserveExisting,
+			Reassert:         contextRule.Reassert,
+			DryRun:           contextRule.DryRun,
+		}
+		if contextRule.Condition != nil {
+			stateRule.Condition = convertCondition(contextRule.Condition)
+		}
+
+		switch stateRule.Name {
+		case "offline":
+			userOffline = &stateRule
+		case "untrusted":
+			userUntrusted = &stateRule
+		default:
+			rules = append(rules, stateRule)
+		}
+	}
+
+	// The offline context takes precedence over everything else
+	if userOffline != nil {
+		rules = append([]state.Rule{mergeStateRule(defaultOffline, *userOffline)}, rules...)
+	}
+
+	// Add untrusted fallback at the end
+	if userUntrusted != nil {
+		rules = append(rules, mergeStateRule(defaultUntrusted, *userUntrusted))
+	} else {
+		rules = append(rules, defaultUntrusted)
+	}
+
+	return rules
+}
+
+// buildContextEnvWriters creates the writers of the exports of each context
+func buildContextEnvWriters(contexts []*core.ContextRule) map[string][]state.EnvWriter {
+	contextEnvWriters := make(map[string][]state.EnvWriter)
+	for _, ctx := range contexts {
+		if writers := buildEnvWriters(ctx.Exports); len(writers) > 0 {
+			contextEnvWriters[ctx.Name] = writers
+		}
+	}
+	return contextEnvWriters
+}
+
+// buildEnvWriters creates a writer for each export, skipping those that fail
+func buildEnvWriters(exports []core.ExportConfig) []state.EnvWriter {
+	var envWriters []state.EnvWriter
+	for _, exportCfg := range exports {
+		var writer state.EnvWriter
+		var err error
+
+		switch exportCfg.Type {
+		case "dotenv":
+			writer, err = state.NewDotenvWriter(exportCfg.Path)
+		case "context":
+			writer, err = state.NewContextWriter(exportCfg.Path)
+		case "location":
+			writer, err = state.NewLocationWriter(exportCfg.Path)
+		case "public_ip":
+			writer, err = state.NewPublicIPWriter(exportCfg.Path)
+		case "public_ipv4":
+			writer, err = state.NewPublicIPFamilyWriter(exportCfg.Path, "ipv4")
+		case "public_ipv6":
+			writer, err = state.NewPublicIPFamilyWriter(exportCfg.Path, "ipv6")
+		case "template":
+			writer, err = state.NewTemplateWriter(exportCfg.Template, exportCfg.Path)
+		default:
+			slog.Warn("Unknown export type", "type", exportCfg.Type)
+			continue
+		}
+
+		if err != nil {
+			slog.Error("Failed to create export writer", "type", exportCfg.Type, "path", exportCfg.Path, "error", err)
+			continue
+		}
+		writer.SetInPlace(exportCfg.InPlace)
+		envWriters = append(envWriters, writer)
+	}
+	return envWriters
+}
+
 // handleNewContextChange is the callback for the new state system
 func (d *Daemon) handleNewContextChange(from, to state.StateSnapshot, rule *state.Rule) {
 	slog.Info("Security context changed (new system)",
@@ -261,8 +345,35 @@ func (d *Daemon) handleNewContextChange(from, to state.StateSnapshot, rule *stat
 			"tunnel_count", len(rule.Actions.Connect))
 	}
 
+	// SSH overrides apply to tunnels connected because of this context
+	sshOverrides := contextSSHOverrides(rule.Name)
+	reason := ContextReason(rule.Name)
+
+	// Glob patterns in the action lists are expanded to the matching tunnels
+	connects, unmatched := expandConnects(rule.Actions.Connect)
+	disconnects, unmatchedDisconnects := d.expandDisconnects(rule.Actions.Disconnect, connects)
+	for _, pattern := range append(unmatched, unmatchedDisconnects...) {
+		slog.Warn("Tunnel pattern in context actions matches no tunnel",
+			"pattern", pattern,
+			"context", to.Context)
+	}
+
+	// A context that preserves existing tunnels only ever adds tunnels
+	if rule.PreserveExisting && len(disconnects) > 0 {
+		slog.Info("Skipping tunnel disconnections - context preserves existing tunnels",
+			"context", to.Context,
+			"tunnel_count", len(disconnects))
+		disconnects = nil
+	}
+
+	// A dry run only reports what the actions would do
+	if rule.DryRun {
+		d.logDryRunActions(to, rule, connects, disconnects, reason)
+		return
+	}
+
 	// Execute disconnect actions first (always, even when offline)
-	for _, alias := range rule.Actions.Disconnect {
+	for _, alias := range disconnects {
 		d.mu.Lock()
 		_, exists := d.tunnels[alias]
 		d.mu.Unlock()
@@ -271,13 +382,26 @@ func (d *Daemon) handleNewContextChange(from, to state.StateSnapshot, rule *stat
 			slog.Info("Auto-disconnecting tunnel due to context change",
 				"tunnel", alias,
 				"context", to.Context)
-			d.stopTunnel(alias, false)
+			d.stopTunnel(alias, false, reason)
 		}
 	}
 
 	// Only execute connect actions if we're online
 	if isOnline {
-		for _, alias := range rule.Actions.Connect {
+		for _, alias := range connects {
+			if d.isManuallyStopped(alias) {
+				slog.Info("Skipping tunnel - manually stopped",
+					"tunnel", alias,
+					"context", to.Context)
+				continue
+			}
+			if tunnelDisabled(alias) {
+				slog.Info("Skipping tunnel - disabled in the configuration",
+					"tunnel", alias,
+					"context", to.Context)
+				continue
+			}
+
 			d.mu.Lock()
 			tunnel, exists := d.tunnels[alias]
 			d.mu.Unlock()
@@ -295,7 +419,7 @@ func (d *Daemon) handleNewContextChange(from, to state.StateSnapshot, rule *stat
 					"context", to.Context,
 					"previous_state", tunnel.State,
 					"previous_retry_count", tunnel.RetryCount)
-				d.stopTunnel(alias, true) // forReconnect=true to preserve companions
+				d.stopTunnel(alias, true, reason) // forReconnect=true to preserve companions
 			} else {
 				slog.Debug("Skipping tunnel - already connected",
 					"tunnel", alias,
@@ -305,7 +429,7 @@ func (d *Daemon) handleNewContextChange(from, to state.StateSnapshot, rule *stat
 
 			if shouldConnect {
 				if d.isPublicIPKnown() {
-					resp := d.startTunnel(alias, nil) // Config environment is applied inside startTunnel
+					resp := d.startTunnel(alias, nil, sshOverrides, reason) // Config environment is applied inside startTunnel
 					for _, msg := range resp.Messages {
 						if msg.Status == "ERROR" {
 							slog.Error("Failed to start tunnel during context change",
@@ -315,11 +439,174 @@ func (d *Daemon) handleNewContextChange(from, to state.StateSnapshot, rule *stat
 						}
 					}
 				} else {
-					go d.startTunnelWhenIPReady(alias, to.Context)
+					go d.startTunnelWhenIPReady(alias, to.Context, sshOverrides, reason)
+				}
+			}
+		}
+	}
+}
+
+// logDryRunActions logs the tunnels a context change would disconnect and
+// connect, and records them as dry_run_disconnect and dry_run_connect events,
+// without touching any tunnel. The same tunnels are skipped as for a real run.
+func (d *Daemon) logDryRunActions(to state.StateSnapshot, rule *state.Rule, connects, disconnects []string, reason string) {
+	logEvent := func(alias, eventType, details string) {
+		if d.database == nil {
+			return
+		}
+		if err := d.database.LogTunnelEventWithReason(alias, eventType, details, reason); err != nil {
+			slog.Error("Failed to log dry run event", "tunnel", alias, "error", err)
+		}
+	}
+
+	for _, alias := range disconnects {
+		d.mu.Lock()
+		_, exists := d.tunnels[alias]
+		d.mu.Unlock()
+
+		if exists {
+			slog.Info("Dry run: would disconnect tunnel due to context change",
+				"tunnel", alias,
+				"context", to.Context)
+			logEvent(alias, "dry_run_disconnect", fmt.Sprintf("Would disconnect on entering context %s", rule.Name))
+		}
+	}
+
+	if !to.Online {
+		return
+	}
+	for _, alias := range connects {
+		if d.isManuallyStopped(alias) || tunnelDisabled(alias) {
+			continue
+		}
+
+		d.mu.Lock()
+		tunnel, exists := d.tunnels[alias]
+		d.mu.Unlock()
+
+		if exists && tunnel.State != StateDisconnected && tunnel.State != StateReconnecting {
+			continue
+		}
+		slog.Info("Dry run: would connect tunnel due to context change",
+			"tunnel", alias,
+			"context", to.Context)
+		logEvent(alias, "dry_run_connect", fmt.Sprintf("Would connect on entering context %s", rule.Name))
+	}
+}
+
+// reassertContext reconnects tunnels from the connect list of a context with
+// reassert set that are no longer up, e.g. after reconnecting gave up. Tunnels
+// that are still being reconnected, manually stopped or disabled are left alone.
+func (d *Daemon) reassertContext(current state.StateSnapshot, rule *state.Rule) {
+	if rule == nil || !rule.Reassert || rule.DryRun || !current.Online {
+		return
+	}
+
+	// Tunnels are retried on the next pass once the public IP is known
+	if !d.isPublicIPKnown() {
+		return
+	}
+
+	sshOverrides := contextSSHOverrides(rule.Name)
+	reason := ContextReason(rule.Name)
+
+	connects, _ := expandConnects(rule.Actions.Connect)
+	for _, alias := range connects {
+		if d.isManuallyStopped(alias) || tunnelDisabled(alias) {
+			continue
+		}
+
+		d.mu.Lock()
+		tunnel, exists := d.tunnels[alias]
+		d.mu.Unlock()
+
+		if exists && tunnel.State != StateDisconnected {
+			continue
+		}
+
+		slog.Info("Reasserting tunnel for active context",
+			"tunnel", alias,
+			"context", rule.Name)
+		if exists {
+			d.stopTunnel(alias, true, reason) // forReconnect=true to preserve companions
+		}
+
+		resp := d.startTunnel(alias, nil, sshOverrides, reason)
+		for _, msg := range resp.Messages {
+			if msg.Status == "ERROR" {
+				slog.Error("Failed to reassert tunnel",
+					"tunnel", alias,
+					"context", rule.Name,
+					"error", msg.Message)
+			}
+		}
+	}
+}
+
+// expandTunnelPatterns returns the tunnels named by entries, with glob
+// patterns (path.Match syntax) expanded against candidates, in order and
+// without duplicates. Patterns don't match the tunnels in exclude. Patterns
+// that match none of the candidates are returned as unmatched.
+func expandTunnelPatterns(entries, candidates, exclude []string) (aliases, unmatched []string) {
+	add := func(alias string) {
+		if !slices.Contains(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	for _, entry := range entries {
+		if !core.IsTunnelPattern(entry) {
+			add(entry)
+			continue
+		}
+
+		matched := false
+		for _, alias := range candidates {
+			if ok, _ := path.Match(entry, alias); ok {
+				matched = true
+				if !slices.Contains(exclude, alias) {
+					add(alias)
 				}
 			}
 		}
+		if !matched {
+			unmatched = append(unmatched, entry)
+		}
+	}
+	return aliases, unmatched
+}
+
+// expandConnects expands the patterns of a connect list against the
+// configured tunnels
+func expandConnects(connect []string) (aliases, unmatched []string) {
+	return expandTunnelPatterns(connect, slices.Sorted(maps.Keys(core.Config.Tunnels)), nil)
+}
+
+// expandDisconnects expands the patterns of a disconnect list against the
+// configured and running tunnels. Patterns don't match the tunnels the
+// context connects, so disconnect = ["*"] disconnects every other tunnel.
+func (d *Daemon) expandDisconnects(disconnect, connects []string) (aliases, unmatched []string) {
+	candidates := slices.Collect(maps.Keys(core.Config.Tunnels))
+	d.mu.Lock()
+	for alias := range d.tunnels {
+		if !slices.Contains(candidates, alias) {
+			candidates = append(candidates, alias)
+		}
+	}
+	d.mu.Unlock()
+	slices.Sort(candidates)
+
+	return expandTunnelPatterns(disconnect, candidates, connects)
+}
+
+// contextSSHOverrides returns the SSH overrides configured for the named context (nil if none)
+func contextSSHOverrides(name string) *core.SSHOverrides {
+	for _, contextRule := range core.Config.Contexts {
+		if contextRule.Name == name {
+			return contextRule.SSH
+		}
 	}
+	return nil
 }
 
 // databaseLoggerAdapter adapts the database to the state.DatabaseLogger interface
@@ -335,6 +622,10 @@ func (a *databaseLoggerAdapter) LogSensorChange(sensor, sensorType, oldValue, ne
 	return a.db.LogSensorChange(sensor, sensorType, oldValue, newValue)
 }
 
+func (a *databaseLoggerAdapter) LogSensorChangeAt(sensor, sensorType, oldValue, newValue string, at time.Time) error {
+	return a.db.LogSensorChangeAt(sensor, sensorType, oldValue, newValue, at)
+}
+
 func (a *databaseLoggerAdapter) LogContextChange(fromContext, toContext, fromLocation, toLocation, trigger string) error {
 	// Log location first, then context (location determines context)
 	if fromLocation != toLocation {
@@ -347,7 +638,7 @@ func (a *databaseLoggerAdapter) LogContextChange(fromContext, toContext, fromLoc
 			return err
 		}
 	}
-	return nil
+	return a.db.LogContextChange(fromContext, toContext, fromLocation, toLocation, trigger)
 }
 
 // convertCondition converts from awareness.Condition interface to state.Condition
@@ -373,6 +664,9 @@ func convertCondition(cond interface{}) state.Condition {
 		}
 		return state.NewAllCondition(conditions...)
 
+	case *awareness.NotCondition:
+		return state.NewNotCondition(convertCondition(c.Condition))
+
 	case awareness.Condition:
 		// Try to convert via type assertion on methods
 		// This handles cases where we have the interface but not the concrete type
@@ -389,6 +683,7 @@ func mergeStateLocation(defaultLoc, userLoc state.Location) state.Location {
 	if userLoc.DisplayName != "" {
 		merged.DisplayName = userLoc.DisplayName
 	}
+	merged.OverrideContextEnvironment = userLoc.OverrideContextEnvironment
 	if len(userLoc.Environment) > 0 {
 		if merged.Environment == nil {
 			merged.Environment = make(map[string]string)
@@ -417,6 +712,15 @@ func mergeStateRule(defaultRule, userRule state.Rule) state.Rule {
 	if len(userRule.Actions.Connect) > 0 || len(userRule.Actions.Disconnect) > 0 {
 		merged.Actions = userRule.Actions
 	}
+	if userRule.PreserveExisting {
+		merged.PreserveExisting = true
+	}
+	if userRule.Reassert {
+		merged.Reassert = true
+	}
+	if userRule.DryRun {
+		merged.DryRun = true
+	}
 	return merged
 }
 
@@ -465,20 +769,81 @@ func collectTrackedEnvVars(rules []state.Rule, locations map[string]state.Locati
 
 // GetStateOrchestrator returns the current state orchestrator
 func GetStateOrchestrator() *state.Orchestrator {
+	stateOrchestratorMu.RLock()
+	defer stateOrchestratorMu.RUnlock()
 	return stateOrchestrator
 }
 
+// setStateOrchestrator replaces the current state orchestrator
+func setStateOrchestrator(orch *state.Orchestrator) {
+	stateOrchestratorMu.Lock()
+	stateOrchestrator = orch
+	stateOrchestratorMu.Unlock()
+}
+
 // stopStateOrchestrator stops the state orchestrator
 func stopStateOrchestrator() {
-	if stateOrchestrator != nil {
-		stateOrchestrator.Stop()
-		stateOrchestrator = nil
+	if orch := GetStateOrchestrator(); orch != nil {
+		setStateOrchestrator(nil)
+		orch.Stop()
+	}
+}
+
+// restartStateOrchestrator replaces the state orchestrator with a fresh one
+// built from the current config. It runs under reloadMu, so a reload can't
+// reconfigure the old one meanwhile. The old one is stopped in the background
+// once the new one is in place, as Stop never returns while its manager is
+// wedged.
+func (d *Daemon) restartStateOrchestrator() error {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	old := GetStateOrchestrator()
+	if err := d.initStateOrchestrator(); err != nil {
+		return err
+	}
+	if old != nil {
+		go old.Stop()
+	}
+	return nil
+}
+
+// checkSensorWatchdog restarts the state orchestrator when its manager hasn't
+// evaluated a sensor reading for longer than sensor_watchdog, e.g. because a
+// sensor or subscriber stalled it. Probes are quiet while suppressed around
+// sleep, so that time doesn't count. Returns true if it was restarted.
+func (d *Daemon) checkSensorWatchdog(now time.Time) bool {
+	maxAge := core.Config.SensorWatchdog
+	orch := GetStateOrchestrator()
+	if maxAge <= 0 || orch == nil {
+		return false
+	}
+
+	if orch.IsSuppressed() {
+		d.sensorsSuppressedAt = now
+		return false
+	}
+
+	last := orch.LastEvaluation()
+	if d.sensorsSuppressedAt.After(last) {
+		last = d.sensorsSuppressedAt
+	}
+	if now.Sub(last) <= maxAge {
+		return false
+	}
+
+	slog.Warn("State manager stopped evaluating sensor readings, restarting it",
+		"last_evaluation", last.Format(time.RFC3339), "sensor_watchdog", maxAge)
+	if err := d.restartStateOrchestrator(); err != nil {
+		slog.Error("Failed to restart state orchestrator", "error", err)
 	}
+	return true
 }
 
 // reloadStateOrchestrator reloads the state orchestrator with new config
 func (d *Daemon) reloadStateOrchestrator() error {
-	if stateOrchestrator == nil {
+	orch := GetStateOrchestrator()
+	if orch == nil {
 		return fmt.Errorf("state orchestrator not initialized")
 	}
 
@@ -486,10 +851,11 @@ func (d *Daemon) reloadStateOrchestrator() error {
 	locations := make(map[string]state.Location)
 	for name, loc := range core.Config.Locations {
 		stateLoc := state.Location{
-			Name:        loc.Name,
-			DisplayName: loc.DisplayName,
-			Conditions:  loc.Conditions,
-			Environment: loc.Environment,
+			Name:                       loc.Name,
+			DisplayName:                loc.DisplayName,
+			Conditions:                 loc.Conditions,
+			Environment:                loc.Environment,
+			OverrideContextEnvironment: loc.OverrideContextEnvironment,
 		}
 		if loc.Condition != nil {
 			stateLoc.Condition = convertCondition(loc.Condition)
@@ -512,49 +878,30 @@ func (d *Daemon) reloadStateOrchestrator() error {
 		}
 	}
 
-	rules := make([]state.Rule, 0, len(core.Config.Contexts)+1)
-	for _, contextRule := range core.Config.Contexts {
-		stateRule := state.Rule{
-			Name:        contextRule.Name,
-			DisplayName: contextRule.DisplayName,
-			Locations:   contextRule.Locations,
-			Conditions:  contextRule.Conditions,
-			Environment: contextRule.Environment,
-			Actions: state.RuleActions{
-				Connect:    contextRule.Actions.Connect,
-				Disconnect: contextRule.Actions.Disconnect,
-			},
-		}
-		if contextRule.Condition != nil {
-			stateRule.Condition = convertCondition(contextRule.Condition)
-		}
-		if stateRule.Name != "untrusted" {
-			rules = append(rules, stateRule)
-		}
-	}
+	rules := buildStateRules()
 
-	// Add untrusted fallback
-	rules = append(rules, state.Rule{
-		Name:        "untrusted",
-		DisplayName: "Untrusted",
-	})
+	// Update public IP settings before Reload triggers a fresh check
+	orch.SetPublicIPProviders(core.Config.PublicIP.Providers, core.Config.PublicIP.Timeout)
+	orch.SetPublicIPCacheTTL(core.Config.PublicIP.CacheTTL)
+	orch.SetSensorEnvFile(core.Config.SensorEnvFile)
+	orch.SetContextEnvWriters(buildContextEnvWriters(core.Config.Contexts))
 
-	stateOrchestrator.Reload(rules, locations, core.Config.Environment)
+	orch.Reload(rules, locations, core.Config.Environment)
 	return nil
 }
 
 // checkOnlineStatusNew checks online status using the new state system
 func (d *Daemon) checkOnlineStatusNew() bool {
-	if stateOrchestrator != nil {
-		return stateOrchestrator.IsOnline()
+	if orch := GetStateOrchestrator(); orch != nil {
+		return orch.IsOnline()
 	}
 	return d.checkOnlineStatus()
 }
 
 // getContextStatusNew returns context status from new state system
 func (d *Daemon) getContextStatusNew() (context, location string) {
-	if stateOrchestrator != nil {
-		state := stateOrchestrator.GetCurrentState()
+	if orch := GetStateOrchestrator(); orch != nil {
+		state := orch.GetCurrentState()
 		return state.Context, state.Location
 	}
 	return "", ""
@@ -566,24 +913,45 @@ func convertHooksConfig(hooks *core.HooksConfig) *state.HooksConfig {
 		return nil
 	}
 
-	result := &state.HooksConfig{
-		OnEnter: make([]state.HookConfig, len(hooks.OnEnter)),
-		OnLeave: make([]state.HookConfig, len(hooks.OnLeave)),
+	return &state.HooksConfig{
+		OnEnter: convertHookConfigs(hooks.OnEnter),
+		OnLeave: convertHookConfigs(hooks.OnLeave),
 	}
+}
 
-	for i, h := range hooks.OnEnter {
-		result.OnEnter[i] = state.HookConfig{
+// convertHookConfigs converts a list of core hook commands to state hook commands
+func convertHookConfigs(hooks []core.HookConfig) []state.HookConfig {
+	result := make([]state.HookConfig, len(hooks))
+	for i, h := range hooks {
+		result[i] = state.HookConfig{
 			Command: h.Command,
 			Timeout: h.Timeout,
 		}
 	}
+	return result
+}
 
-	for i, h := range hooks.OnLeave {
-		result.OnLeave[i] = state.HookConfig{
-			Command: h.Command,
-			Timeout: h.Timeout,
+// reloadHookWait is how long on_reload hooks wait for the reloaded rules to
+// be evaluated before running with the state as it is
+const reloadHookWait = 10 * time.Second
+
+// runReloadHooks runs the on_reload context hooks once the state manager has
+// evaluated a sensor reading after reloaded, so the hooks see the context the
+// new rules resolve to
+func (d *Daemon) runReloadHooks(hooks []state.HookConfig, reloaded time.Time) {
+	orch := GetStateOrchestrator()
+	if orch == nil {
+		return
+	}
+
+	deadline := time.Now().Add(reloadHookWait)
+	for !orch.LastEvaluation().After(reloaded) && time.Now().Before(deadline) {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(50 * time.Millisecond):
 		}
 	}
 
-	return result
+	orch.RunReloadHooks(hooks)
 }
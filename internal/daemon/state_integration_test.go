@@ -96,6 +96,22 @@ func TestConvertCondition(t *testing.T) {
 		}
 	})
 
+	t.Run("NotCondition", func(t *testing.T) {
+		cond := awareness.NewNotCondition(
+			&awareness.SensorCondition{SensorName: "env:VPN", Pattern: "on"},
+		)
+		result := convertCondition(cond)
+		if _, ok := result.(*state.NotCondition); !ok {
+			t.Fatalf("expected *state.NotCondition, got %T", result)
+		}
+		readings := map[string]state.SensorReading{
+			"env:VPN": {Sensor: "env:VPN", Value: "off"},
+		}
+		if !result.Evaluate(readings, true) {
+			t.Error("expected negated condition to evaluate to true")
+		}
+	})
+
 	t.Run("unknown type returns nil", func(t *testing.T) {
 		result := convertCondition("not a condition")
 		if result != nil {
@@ -444,8 +460,61 @@ func TestGetContextStatus_WithOrchestrator(t *testing.T) {
 	if resp.Messages[0].Status != "INFO" {
 		t.Errorf("expected INFO status, got %q", resp.Messages[0].Status)
 	}
-	if resp.Data == nil {
-		t.Error("expected non-nil Data with context status")
+	status, ok := resp.Data.(ContextStatus)
+	if !ok {
+		t.Fatalf("expected ContextStatus data, got %T", resp.Data)
+	}
+	if got := status.SensorKinds["tcp"]; got != "bool" {
+		t.Errorf("expected tcp sensor kind bool, got %q", got)
+	}
+	if got := status.SensorKinds["public_ipv4"]; got != "ip" {
+		t.Errorf("expected public_ipv4 sensor kind ip, got %q", got)
+	}
+}
+
+func TestGetSensors(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		ConfigPath: t.TempDir(),
+		Companion:  core.CompanionSettings{HistorySize: 50},
+		Locations:  map[string]*core.Location{},
+		Contexts:   []*core.ContextRule{},
+	}
+
+	old := stateOrchestrator
+	t.Cleanup(func() {
+		stopStateOrchestrator()
+		stateOrchestrator = old
+	})
+	stateOrchestrator = nil
+
+	d := New()
+	if resp := d.getSensors(); len(resp.Messages) == 0 || resp.Messages[0].Status != "ERROR" {
+		t.Errorf("expected ERROR without an orchestrator, got %+v", resp.Messages)
+	}
+
+	if err := d.initStateOrchestrator(); err != nil {
+		t.Fatalf("initStateOrchestrator failed: %v", err)
+	}
+
+	resp := d.getSensors()
+	if len(resp.Messages) == 0 || resp.Messages[0].Status != "INFO" {
+		t.Fatalf("expected INFO status, got %+v", resp.Messages)
+	}
+	sensors, ok := resp.Data.(map[string]string)
+	if !ok {
+		t.Fatalf("expected map[string]string data, got %T", resp.Data)
+	}
+	for _, name := range []string{"online", "public_ipv4", "context", "location", "tcp"} {
+		if _, exists := sensors[name]; !exists {
+			t.Errorf("expected sensor %q in %v", name, sensors)
+		}
+	}
+	if got := sensors["online"]; got != "true" && got != "false" {
+		t.Errorf("expected online to be true or false, got %q", got)
 	}
 }
 
@@ -820,6 +889,99 @@ func TestHandleNewContextChange_DisconnectAction(t *testing.T) {
 	}
 }
 
+func TestBuildStateRules_OfflineContext(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		Tunnels:   map[string]*core.TunnelConfig{},
+		Contexts: []*core.ContextRule{
+			{
+				Name:       "anywhere",
+				Conditions: map[string][]string{"env:ALWAYS": {"*"}},
+				Actions:    core.ContextActions{Connect: []string{"vpn"}},
+			},
+			{
+				Name:        "offline",
+				DisplayName: "No Network",
+				Actions:     core.ContextActions{Disconnect: []string{"home-lab"}},
+			},
+		},
+	}
+
+	rules := buildStateRules()
+	if len(rules) != 3 || rules[0].Name != "offline" || rules[2].Name != "untrusted" {
+		t.Fatalf("expected offline first and untrusted last, got %+v", rules)
+	}
+
+	engine := state.NewRuleEngine(rules, nil, nil)
+	readings := map[string]state.SensorReading{
+		"env:ALWAYS": {Sensor: "env:ALWAYS", Value: "yes"},
+	}
+
+	if result := engine.Evaluate(readings, true); result.Context != "anywhere" {
+		t.Errorf("expected anywhere while online, got %q", result.Context)
+	}
+	result := engine.Evaluate(readings, false)
+	if result.Context != "offline" || result.ContextDisplayName != "No Network" {
+		t.Fatalf("expected offline context while offline, got %+v", result)
+	}
+
+	// The offline context's disconnect actions are carried out
+	d := New()
+	d.tunnels["home-lab"] = Tunnel{Hostname: "lab.example.com", State: StateConnected}
+
+	from := state.StateSnapshot{Context: "anywhere", Location: "unknown", Online: true}
+	to := state.StateSnapshot{Context: "offline", Location: "offline", Online: false}
+	d.handleNewContextChange(from, to, &rules[0])
+
+	d.mu.Lock()
+	_, exists := d.tunnels["home-lab"]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected home-lab to be disconnected when going offline")
+	}
+}
+
+func TestBuildStateRules_DisabledContext(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		Tunnels:   map[string]*core.TunnelConfig{},
+		Contexts: []*core.ContextRule{
+			{
+				Name:       "testing",
+				Conditions: map[string][]string{"env:ALWAYS": {"*"}},
+				Disabled:   true,
+			},
+			{
+				Name:       "anywhere",
+				Conditions: map[string][]string{"env:ALWAYS": {"*"}},
+			},
+		},
+	}
+
+	rules := buildStateRules()
+	for _, rule := range rules {
+		if rule.Name == "testing" {
+			t.Fatalf("expected disabled context to be skipped, got %+v", rules)
+		}
+	}
+
+	engine := state.NewRuleEngine(rules, nil, nil)
+	readings := map[string]state.SensorReading{
+		"env:ALWAYS": {Sensor: "env:ALWAYS", Value: "yes"},
+	}
+	if result := engine.Evaluate(readings, true); result.Context != "anywhere" {
+		t.Errorf("expected anywhere with testing disabled, got %q", result.Context)
+	}
+}
+
 func TestHandleNewContextChange_NilRule(t *testing.T) {
 	quietLogger(t)
 
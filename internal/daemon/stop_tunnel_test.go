@@ -34,7 +34,7 @@ func TestStopTunnel_AdoptedTunnel(t *testing.T) {
 		companionMgr:  NewCompanionManager(),
 	}
 
-	resp := d.stopTunnel("adopted-tunnel", false)
+	resp := d.stopTunnel("adopted-tunnel", false, ReasonManual)
 	if len(resp.Messages) == 0 {
 		t.Fatal("expected at least one message")
 	}
@@ -64,7 +64,7 @@ func TestStopTunnel_NoProcessReference(t *testing.T) {
 		companionMgr:  NewCompanionManager(),
 	}
 
-	resp := d.stopTunnel("broken-tunnel", false)
+	resp := d.stopTunnel("broken-tunnel", false, ReasonManual)
 	if len(resp.Messages) == 0 {
 		t.Fatal("expected at least one message")
 	}
@@ -105,7 +105,7 @@ func TestStopTunnel_CleansAskpassToken(t *testing.T) {
 		companionMgr: NewCompanionManager(),
 	}
 
-	d.stopTunnel("token-tunnel", false)
+	d.stopTunnel("token-tunnel", false, ReasonManual)
 
 	if _, exists := d.askpassTokens["secret-token"]; exists {
 		t.Error("expected askpass token to be cleaned up")
@@ -148,7 +148,7 @@ func TestStopTunnel_ForReconnect_ClearsHistory(t *testing.T) {
 		companionMgr:  cm,
 	}
 
-	resp := d.stopTunnel("test-tunnel", true)
+	resp := d.stopTunnel("test-tunnel", true, ReasonManual)
 	if len(resp.Messages) == 0 {
 		t.Fatal("expected at least one message")
 	}
@@ -271,3 +271,73 @@ func TestHandleOnlineChange_Offline(t *testing.T) {
 	// wasOnline=true, isOnline=false (transition to offline)
 	d.handleOnlineChange(true, false)
 }
+
+func TestStopTunnel_MarksManuallyStopped(t *testing.T) {
+	quietLogger(t)
+
+	startTunnel := func(d *Daemon, alias string) {
+		cmd := exec.Command("sleep", "60")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		go cmd.Wait()
+		t.Cleanup(func() { cmd.Process.Kill() })
+		d.tunnels[alias] = Tunnel{Hostname: alias, Pid: cmd.Process.Pid, Cmd: cmd, State: StateConnected}
+	}
+
+	d := &Daemon{
+		tunnels:       make(map[string]Tunnel),
+		askpassTokens: make(map[string]string),
+		companionMgr:  NewCompanionManager(),
+	}
+
+	startTunnel(d, "manual-tunnel")
+	d.stopTunnel("manual-tunnel", false, ReasonManual)
+	if !d.isManuallyStopped("manual-tunnel") {
+		t.Error("expected a manual disconnect to suppress auto-connect")
+	}
+
+	startTunnel(d, "reconnect-tunnel")
+	d.stopTunnel("reconnect-tunnel", true, ReasonManual)
+	if d.isManuallyStopped("reconnect-tunnel") {
+		t.Error("expected a stop for reconnect not to suppress auto-connect")
+	}
+
+	startTunnel(d, "context-tunnel")
+	d.stopTunnel("context-tunnel", false, ContextReason("untrusted"))
+	if d.isManuallyStopped("context-tunnel") {
+		t.Error("expected a context disconnect not to suppress auto-connect")
+	}
+}
+
+func TestKillTunnel_StopsPersistentCompanions(t *testing.T) {
+	quietLogger(t)
+
+	d := &Daemon{
+		tunnels:       make(map[string]Tunnel),
+		askpassTokens: make(map[string]string),
+		companionMgr:  NewCompanionManager(),
+	}
+	d.companionMgr.companions["server1"] = map[string]*CompanionProcess{
+		"persistent-comp": {
+			Name:   "persistent-comp",
+			State:  CompanionStateRunning,
+			Config: core.CompanionConfig{Persistent: true},
+		},
+	}
+
+	// The tunnel is already down, only the persistent companion is left
+	resp := d.killTunnel("server1")
+	if len(resp.Messages) != 1 || resp.Messages[0].Status != "INFO" {
+		t.Fatalf("expected a single INFO message, got %+v", resp.Messages)
+	}
+	if state := d.companionMgr.companions["server1"]["persistent-comp"].State; state != CompanionStateStopped {
+		t.Errorf("expected persistent companion to be stopped, got state %q", state)
+	}
+
+	// Nothing left to kill
+	resp = d.killTunnel("server1")
+	if len(resp.Messages) != 1 || resp.Messages[0].Status != "ERROR" {
+		t.Errorf("expected a single ERROR message, got %+v", resp.Messages)
+	}
+}
@@ -0,0 +1,282 @@
+package daemon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trafficSampleInterval is how often the bytes transferred by tunnels are sampled
+const trafficSampleInterval = 10 * time.Second
+
+// errTrafficUnsupported is returned by sampleProcessTraffic on platforms
+// where the traffic of a process can't be read
+var errTrafficUnsupported = errors.New("traffic sampling is not supported on this platform")
+
+// trafficSample is a reading of the cumulative byte counters of one
+// connection of an SSH process, or of the whole process where the platform
+// only reports per process
+type trafficSample struct {
+	Key      string // Identifies the connection (or process) the counters belong to
+	BytesIn  uint64
+	BytesOut uint64
+}
+
+// trafficCounter aggregates the samples of one tunnel into totals. The
+// counters of a connection only grow while it's open, so the last sample of
+// a connection that has gone away, e.g. because the tunnel reconnected, is
+// kept in the totals.
+type trafficCounter struct {
+	closedIn  uint64                   // Bytes received by connections that have gone away
+	closedOut uint64                   // Bytes sent by connections that have gone away
+	last      map[string]trafficSample // Latest sample of each open connection
+}
+
+// add records the samples of one round of sampling
+func (c *trafficCounter) add(samples []trafficSample) {
+	current := make(map[string]trafficSample, len(samples))
+	for _, sample := range samples {
+		current[sample.Key] = sample
+	}
+
+	for key, previous := range c.last {
+		sample, open := current[key]
+		// A counter going backwards is a new connection reusing the key
+		if !open || sample.BytesIn < previous.BytesIn || sample.BytesOut < previous.BytesOut {
+			c.closedIn += previous.BytesIn
+			c.closedOut += previous.BytesOut
+		}
+	}
+	c.last = current
+}
+
+// totals returns the bytes received and sent over all connections so far
+func (c *trafficCounter) totals() (bytesIn, bytesOut uint64) {
+	bytesIn, bytesOut = c.closedIn, c.closedOut
+	for _, sample := range c.last {
+		bytesIn += sample.BytesIn
+		bytesOut += sample.BytesOut
+	}
+	return bytesIn, bytesOut
+}
+
+// startTrafficSampler starts a goroutine that periodically samples the bytes
+// transferred by each tunnel, see sampleTraffic. Sampling is best-effort: it
+// stops for good when the platform or the tools it relies on don't support it,
+// and skips a round on any other error.
+func (d *Daemon) startTrafficSampler() {
+	if err := d.sampleTraffic(); err != nil && trafficUnsupported(err) {
+		slog.Debug("Tunnel traffic sampling unavailable", "error", err)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(trafficSampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.sampleTraffic(); err != nil {
+					if trafficUnsupported(err) {
+						slog.Debug("Stopped tunnel traffic sampling", "error", err)
+						return
+					}
+					slog.Debug("Failed to sample tunnel traffic", "error", err)
+				}
+			}
+		}
+	}()
+	slog.Info("Started tunnel traffic sampler", "interval", trafficSampleInterval.String())
+}
+
+// trafficUnsupported reports whether a sampling error means traffic can't be
+// sampled at all, rather than that one round failed
+func trafficUnsupported(err error) bool {
+	return errors.Is(err, errTrafficUnsupported) || errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// sampleTraffic reads the byte counters of the SSH processes of all tunnels
+// and adds them to the tunnels' totals. Totals are kept across reconnects and
+// dropped once a tunnel is stopped. Nothing is read while no tunnel runs.
+func (d *Daemon) sampleTraffic() error {
+	d.mu.Lock()
+	for alias := range d.traffic {
+		if _, exists := d.tunnels[alias]; !exists {
+			delete(d.traffic, alias)
+		}
+	}
+	pids := make(map[string]int, len(d.tunnels))
+	for alias, tunnel := range d.tunnels {
+		if tunnel.Pid > 0 {
+			pids[alias] = tunnel.Pid
+		}
+	}
+	d.mu.Unlock()
+	if len(pids) == 0 {
+		return nil
+	}
+
+	samples, err := sampleProcessTraffic()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for alias, pid := range pids {
+		if _, exists := d.tunnels[alias]; !exists {
+			continue
+		}
+		counter := d.traffic[alias]
+		if counter == nil {
+			counter = &trafficCounter{}
+			d.traffic[alias] = counter
+		}
+		counter.add(samples[pid])
+	}
+	return nil
+}
+
+// tunnelTraffic returns the totals of a tunnel, and false when its traffic
+// hasn't been sampled. The caller must hold d.mu.
+func (d *Daemon) tunnelTraffic(alias string) (bytesIn, bytesOut uint64, ok bool) {
+	counter := d.traffic[alias]
+	if counter == nil {
+		return 0, 0, false
+	}
+	bytesIn, bytesOut = counter.totals()
+	return bytesIn, bytesOut, true
+}
+
+var (
+	ssPidRe      = regexp.MustCompile(`pid=(\d+)`)
+	ssBytesInRe  = regexp.MustCompile(`\bbytes_received:(\d+)`)
+	ssBytesOutRe = regexp.MustCompile(`\bbytes_acked:(\d+)`)
+
+	// nettopProcessRe matches the process column, the time column has colons
+	nettopProcessRe = regexp.MustCompile(`^[^:]+\.(\d+)$`)
+)
+
+// parseSSTraffic parses the output of `ss -tinpH` into samples per pid, one
+// per established TCP connection. Connections to loopback addresses are left
+// out, they carry the forwarded traffic that is also counted on the
+// connection to the SSH server.
+func parseSSTraffic(output string) map[int][]trafficSample {
+	samples := make(map[int][]trafficSample)
+
+	var pids []int
+	var key string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Connection lines start in the first column, their TCP info follows
+		// on an indented line
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			pids, key = nil, ""
+			fields := strings.Fields(line)
+			if len(fields) < 6 || fields[0] != "ESTAB" || isLoopbackAddr(fields[4]) {
+				continue
+			}
+			for _, match := range ssPidRe.FindAllStringSubmatch(line, -1) {
+				if pid, err := strconv.Atoi(match[1]); err == nil {
+					pids = append(pids, pid)
+				}
+			}
+			key = fields[3] + "->" + fields[4]
+			continue
+		}
+
+		if len(pids) == 0 {
+			continue
+		}
+		sample := trafficSample{Key: key}
+		if match := ssBytesInRe.FindStringSubmatch(line); match != nil {
+			sample.BytesIn, _ = strconv.ParseUint(match[1], 10, 64)
+		}
+		if match := ssBytesOutRe.FindStringSubmatch(line); match != nil {
+			sample.BytesOut, _ = strconv.ParseUint(match[1], 10, 64)
+		}
+		for _, pid := range pids {
+			samples[pid] = append(samples[pid], sample)
+		}
+		pids = nil
+	}
+	return samples
+}
+
+// isLoopbackAddr reports whether an address:port from ss is a loopback address
+func isLoopbackAddr(hostPort string) bool {
+	host, _, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return false
+	}
+	// Strip the interface of link local addresses, e.g. fe80::1%eth0
+	host, _, _ = strings.Cut(host, "%")
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	return addr.Unmap().IsLoopback()
+}
+
+// parseNettopTraffic parses the CSV output of
+// `nettop -P -x -J bytes_in,bytes_out` into one sample per pid. nettop names
+// processes "<name>.<pid>".
+func parseNettopTraffic(output string) (map[int][]trafficSample, error) {
+	samples := make(map[int][]trafficSample)
+
+	inColumn, outColumn := -1, -1
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+
+		if inColumn < 0 {
+			for i, field := range fields {
+				switch field {
+				case "bytes_in":
+					inColumn = i
+				case "bytes_out":
+					outColumn = i
+				}
+			}
+			continue
+		}
+		if outColumn < 0 || inColumn >= len(fields) || outColumn >= len(fields) {
+			continue
+		}
+
+		for _, field := range fields {
+			match := nettopProcessRe.FindStringSubmatch(field)
+			if match == nil {
+				continue
+			}
+			pid, err := strconv.Atoi(match[1])
+			if err != nil {
+				continue
+			}
+			sample := trafficSample{Key: field}
+			sample.BytesIn, _ = strconv.ParseUint(fields[inColumn], 10, 64)
+			sample.BytesOut, _ = strconv.ParseUint(fields[outColumn], 10, 64)
+			samples[pid] = append(samples[pid], sample)
+			break
+		}
+	}
+
+	if inColumn < 0 || outColumn < 0 {
+		return nil, fmt.Errorf("no bytes_in and bytes_out columns in nettop output")
+	}
+	return samples, nil
+}
@@ -0,0 +1,19 @@
+//go:build darwin
+
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// sampleProcessTraffic reads the byte counters of all processes with nettop,
+// which reports them per process. Only external interfaces are counted, so
+// forwarded traffic on loopback isn't counted twice.
+func sampleProcessTraffic() (map[int][]trafficSample, error) {
+	output, err := exec.Command("/usr/bin/nettop", "-P", "-L", "1", "-x", "-t", "external", "-J", "bytes_in,bytes_out").Output()
+	if err != nil {
+		return nil, fmt.Errorf("nettop failed: %w", err)
+	}
+	return parseNettopTraffic(string(output))
+}
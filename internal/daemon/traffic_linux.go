@@ -0,0 +1,19 @@
+//go:build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// sampleProcessTraffic reads the byte counters of the TCP connections of all
+// processes with ss, which reports them per connection from the kernel's
+// TCP info
+func sampleProcessTraffic() (map[int][]trafficSample, error) {
+	output, err := exec.Command("ss", "-tinpH").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ss failed: %w", err)
+	}
+	return parseSSTraffic(string(output)), nil
+}
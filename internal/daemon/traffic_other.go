@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package daemon
+
+// sampleProcessTraffic isn't supported on this platform
+func sampleProcessTraffic() (map[int][]trafficSample, error) {
+	return nil, errTrafficUnsupported
+}
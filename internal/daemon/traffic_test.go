@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestTrafficCounter(t *testing.T) {
+	var counter trafficCounter
+
+	steps := []struct {
+		name    string
+		samples []trafficSample
+		wantIn  uint64
+		wantOut uint64
+	}{
+		{"first sample", []trafficSample{{"a", 100, 10}}, 100, 10},
+		{"counters grow", []trafficSample{{"a", 150, 20}}, 150, 20},
+		{"second connection", []trafficSample{{"a", 200, 30}, {"b", 5, 5}}, 205, 35},
+		{"closed connection is kept", []trafficSample{{"b", 10, 8}}, 210, 38},
+		{"reconnect with a new connection", []trafficSample{{"c", 1, 1}}, 211, 39},
+		{"counter going backwards is a new connection", []trafficSample{{"c", 0, 2}}, 211, 41},
+		{"no connections", nil, 211, 41},
+		{"connection after a gap", []trafficSample{{"d", 9, 9}}, 220, 50},
+	}
+
+	for _, step := range steps {
+		counter.add(step.samples)
+		bytesIn, bytesOut := counter.totals()
+		if bytesIn != step.wantIn || bytesOut != step.wantOut {
+			t.Errorf("%s: totals = %d in, %d out, want %d in, %d out", step.name, bytesIn, bytesOut, step.wantIn, step.wantOut)
+		}
+	}
+}
+
+func TestSampleTraffic_KeptPerTunnel(t *testing.T) {
+	d := New()
+	d.tunnels["corp"] = Tunnel{Hostname: "corp", Pid: 100}
+	d.traffic["corp"] = &trafficCounter{}
+	d.traffic["gone"] = &trafficCounter{}
+
+	d.traffic["corp"].add([]trafficSample{{"x", 42, 7}})
+	bytesIn, bytesOut, ok := d.tunnelTraffic("corp")
+	if !ok || bytesIn != 42 || bytesOut != 7 {
+		t.Errorf("tunnelTraffic(corp) = %d, %d, %v, want 42, 7, true", bytesIn, bytesOut, ok)
+	}
+	if _, _, ok := d.tunnelTraffic("home"); ok {
+		t.Error("expected no traffic for a tunnel that hasn't been sampled")
+	}
+
+	if err := d.sampleTraffic(); err != nil {
+		t.Skipf("traffic sampling unavailable: %v", err)
+	}
+	if _, exists := d.traffic["gone"]; exists {
+		t.Error("expected the totals of a stopped tunnel to be dropped")
+	}
+	// pid 100 has no connections, so the last sample counts as closed
+	if bytesIn, bytesOut, _ := d.tunnelTraffic("corp"); bytesIn != 42 || bytesOut != 7 {
+		t.Errorf("expected totals to be kept, got %d in, %d out", bytesIn, bytesOut)
+	}
+}
+
+func TestSampleTraffic_NoTunnels(t *testing.T) {
+	d := New()
+	d.traffic["gone"] = &trafficCounter{}
+
+	// Nothing to sample, which can't fail, but totals are still dropped
+	if err := d.sampleTraffic(); err != nil {
+		t.Errorf("expected no error without tunnels, got %v", err)
+	}
+	if _, exists := d.traffic["gone"]; exists {
+		t.Error("expected the totals of a stopped tunnel to be dropped")
+	}
+}
+
+func TestTrafficUnsupported(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errTrafficUnsupported, true},
+		{fmt.Errorf("ss failed: %w", &exec.Error{Name: "ss", Err: exec.ErrNotFound}), true},
+		{fmt.Errorf("nettop failed: %w", &fs.PathError{Op: "fork/exec", Path: "/usr/bin/nettop", Err: fs.ErrNotExist}), true},
+		{fmt.Errorf("ss failed: %w", errors.New("exit status 1")), false},
+	}
+	for _, tt := range tests {
+		if got := trafficUnsupported(tt.err); got != tt.want {
+			t.Errorf("trafficUnsupported(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseSSTraffic(t *testing.T) {
+	output := `ESTAB 0      0          192.168.1.5:51234     203.0.113.7:22    users:(("ssh",pid=1234,fd=3))
+	 cubic wscale:7,7 rto:204 rtt:1.5/0.75 bytes_sent:5100 bytes_retrans:100 bytes_acked:5000 bytes_received:90000 segs_out:40
+ESTAB 0      0            127.0.0.1:8080        127.0.0.1:40112 users:(("ssh",pid=1234,fd=7))
+	 cubic bytes_acked:700 bytes_received:800
+ESTAB 0      0      [2001:db8::5]:40000  [2001:db8::7]:22    users:(("ssh",pid=1234,fd=4))
+	 cubic bytes_acked:10 bytes_received:20
+ESTAB 0      0          192.168.1.5:51300     198.51.100.2:443  users:(("curl",pid=999,fd=5))
+	 cubic bytes_acked:1 bytes_received:2
+ESTAB 0      0          192.168.1.5:51400     198.51.100.3:22
+	 cubic bytes_acked:3 bytes_received:4
+`
+
+	want := map[int][]trafficSample{
+		1234: {
+			{Key: "192.168.1.5:51234->203.0.113.7:22", BytesIn: 90000, BytesOut: 5000},
+			{Key: "[2001:db8::5]:40000->[2001:db8::7]:22", BytesIn: 20, BytesOut: 10},
+		},
+		999: {
+			{Key: "192.168.1.5:51300->198.51.100.2:443", BytesIn: 2, BytesOut: 1},
+		},
+	}
+	if got := parseSSTraffic(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSSTraffic() = %v, want %v", got, want)
+	}
+}
+
+func TestParseNettopTraffic(t *testing.T) {
+	output := `time,,bytes_in,bytes_out,
+12:00:01.123456,ssh.1234,90000,5000,
+12:00:01.123456,Google Chrome H.999,1,2,
+`
+
+	got, err := parseNettopTraffic(output)
+	if err != nil {
+		t.Fatalf("parseNettopTraffic() error: %v", err)
+	}
+	want := map[int][]trafficSample{
+		1234: {{Key: "ssh.1234", BytesIn: 90000, BytesOut: 5000}},
+		999:  {{Key: "Google Chrome H.999", BytesIn: 1, BytesOut: 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNettopTraffic() = %v, want %v", got, want)
+	}
+
+	if _, err := parseNettopTraffic("time,,rx_dupe\n"); err == nil {
+		t.Error("expected an error without bytes_in and bytes_out columns")
+	}
+}
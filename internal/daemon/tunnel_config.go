@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"slices"
+	"strings"
+
+	"go.olrik.dev/overseer/internal/core"
+)
+
+// TunnelConfigInfo is the configuration a tunnel connects with, as returned
+// by TUNNEL_CONFIG: the tunnel block with the global SSH settings, the
+// keepalive profile and the context's SSH overrides applied
+type TunnelConfigInfo struct {
+	Alias            string            `json:"alias"`
+	Configured       bool              `json:"configured"` // Defined by a tunnel block, any SSH alias can be connected
+	Connected        bool              `json:"connected"`
+	Description      string            `json:"description,omitempty"`
+	Disabled         bool              `json:"disabled,omitempty"`
+	ConnectOnStartup bool              `json:"connect_on_startup,omitempty"`
+	MaxLifetime      string            `json:"max_lifetime,omitempty"`
+	OverridesFrom    string            `json:"ssh_overrides_from,omitempty"` // Context whose SSH overrides are applied
+	SSH              TunnelSSHInfo     `json:"ssh"`
+	SSHArgs          []string          `json:"ssh_args"`
+	Environment      map[string]string `json:"environment"`
+	LocalForwards    []int             `json:"local_forwards,omitempty"` // Local ports from LocalForward and DynamicForward
+	Companions       []string          `json:"companions,omitempty"`
+	Sources          []string          `json:"sources,omitempty"`
+}
+
+// TunnelSSHInfo holds the effective SSH settings of a tunnel
+type TunnelSSHInfo struct {
+	ServerAliveInterval int    `json:"server_alive_interval"`
+	ServerAliveCountMax int    `json:"server_alive_count_max"`
+	ControlMaster       bool   `json:"control_master"`
+	VerboseAfterConnect bool   `json:"verbose_after_connect"`
+	ReconnectEnabled    bool   `json:"reconnect_enabled"`
+	InitialBackoff      string `json:"initial_backoff"`
+	MaxBackoff          string `json:"max_backoff"`
+	BackoffFactor       int    `json:"backoff_factor"`
+	MaxRetries          int    `json:"max_retries"`
+	StableReset         string `json:"stable_reset"`
+	Askpass             string `json:"askpass,omitempty"`
+}
+
+// getTunnelConfig answers TUNNEL_CONFIG <alias>
+func (d *Daemon) getTunnelConfig(alias string) Response {
+	response := Response{}
+	response.AddMessage("OK", "INFO")
+	response.AddData(d.resolveTunnelConfig(alias))
+	return response
+}
+
+// resolveTunnelConfig returns the configuration alias connects with. A
+// connected tunnel reports the SSH overrides and environment it was connected
+// with. Otherwise the overrides of the active context apply when the context
+// connects the tunnel, as they would when it is connected next.
+func (d *Daemon) resolveTunnelConfig(alias string) TunnelConfigInfo {
+	d.mu.Lock()
+	tunnel, connected := d.tunnels[alias]
+	d.mu.Unlock()
+
+	info := TunnelConfigInfo{Alias: alias, Connected: connected}
+
+	var overrides *core.SSHOverrides
+	if connected {
+		overrides = tunnel.SSHOverrides
+		info.Environment = tunnel.Environment
+		if context, ok := strings.CutPrefix(tunnel.ConnectReason, ContextReason("")); ok && overrides != nil {
+			info.OverridesFrom = context
+		}
+	} else {
+		if context := activeContextConnecting(alias); context != "" {
+			overrides = contextSSHOverrides(context)
+			if overrides != nil {
+				info.OverridesFrom = context
+			}
+		}
+		info.Environment = tunnelEnvironment(alias, nil)
+	}
+
+	settings := effectiveSSHSettings(overrides)
+	info.SSH = TunnelSSHInfo{
+		ServerAliveInterval: settings.ServerAliveInterval,
+		ServerAliveCountMax: settings.ServerAliveCountMax,
+		ControlMaster:       !settings.NoControlMaster,
+		VerboseAfterConnect: !settings.QuietAfterConnect,
+		ReconnectEnabled:    settings.ReconnectEnabled,
+		InitialBackoff:      settings.InitialBackoff,
+		MaxBackoff:          settings.MaxBackoff,
+		BackoffFactor:       settings.BackoffFactor,
+		MaxRetries:          settings.MaxRetries,
+		StableReset:         settings.StableReset.String(),
+		Askpass:             settings.Askpass,
+	}
+	info.SSHArgs = buildTunnelSSHArgs(alias, d.sshConfigFile, settings.ServerAliveInterval, settings.ServerAliveCountMax, settings.NoControlMaster)
+	info.LocalForwards = extractLocalForwardPorts(alias, info.Environment, d.sshConfigFile)
+
+	if tunnelConfig := core.Config.Tunnels[alias]; tunnelConfig != nil {
+		info.Configured = true
+		info.Description = tunnelConfig.Description
+		info.Disabled = tunnelConfig.Disabled
+		info.ConnectOnStartup = tunnelConfig.ConnectOnStartup
+		if tunnelConfig.MaxLifetime > 0 {
+			info.MaxLifetime = tunnelConfig.MaxLifetime.String()
+		}
+		for _, companion := range tunnelConfig.Companions {
+			info.Companions = append(info.Companions, companion.Name)
+		}
+		info.Sources = tunnelConfig.Sources
+	}
+
+	return info
+}
+
+// activeContextConnecting returns the active context if its connect action
+// includes alias, and an empty string otherwise
+func activeContextConnecting(alias string) string {
+	orch := GetStateOrchestrator()
+	if orch == nil {
+		return ""
+	}
+	current := orch.GetCurrentState().Context
+	for _, contextRule := range core.Config.Contexts {
+		if contextRule.Name != current {
+			continue
+		}
+		if connects, _ := expandConnects(contextRule.Actions.Connect); slices.Contains(connects, alias) {
+			return current
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go.olrik.dev/overseer/internal/core"
+)
+
+func setupTunnelConfigTest(t *testing.T) *Daemon {
+	t.Helper()
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Environment: map[string]string{"REGION": "eu"},
+		SSH: core.SSHConfig{
+			ServerAliveInterval: 15,
+			ServerAliveCountMax: 3,
+			ReconnectEnabled:    true,
+			MaxRetries:          10,
+			StableReset:         5 * time.Minute,
+		},
+		Tunnels: map[string]*core.TunnelConfig{
+			"db": {
+				Name:        "db",
+				Description: "Production database",
+				Environment: map[string]string{"REGION": "us"},
+				MaxLifetime: time.Hour,
+				Companions:  []core.CompanionConfig{{Name: "proxy"}},
+			},
+		},
+	}
+
+	// An ssh config of its own, so the forwards don't depend on ~/.ssh/config
+	sshConfig := filepath.Join(t.TempDir(), "ssh_config")
+	if err := os.WriteFile(sshConfig, []byte("Host db\n  LocalForward 15432 localhost:5432\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := New()
+	d.SetSSHConfigFile(sshConfig)
+	return d
+}
+
+func TestResolveTunnelConfig_NotConnected(t *testing.T) {
+	d := setupTunnelConfigTest(t)
+
+	info := d.resolveTunnelConfig("db")
+	if !info.Configured || info.Connected {
+		t.Errorf("expected a configured, disconnected tunnel, got %+v", info)
+	}
+	if info.Environment["REGION"] != "us" {
+		t.Errorf("expected the tunnel environment to override the global one, got %v", info.Environment)
+	}
+	if info.SSH.ServerAliveInterval != 15 || info.OverridesFrom != "" {
+		t.Errorf("expected the global SSH settings, got %+v (overrides from %q)", info.SSH, info.OverridesFrom)
+	}
+	if info.MaxLifetime != "1h0m0s" || info.SSH.StableReset != "5m0s" {
+		t.Errorf("unexpected durations: max_lifetime=%q stable_reset=%q", info.MaxLifetime, info.SSH.StableReset)
+	}
+	if !slices.Equal(info.Companions, []string{"proxy"}) {
+		t.Errorf("Companions = %v", info.Companions)
+	}
+	if !slices.Equal(info.LocalForwards, []int{15432}) {
+		t.Errorf("LocalForwards = %v, want [15432]", info.LocalForwards)
+	}
+}
+
+func TestResolveTunnelConfig_ContextOverrides(t *testing.T) {
+	d := setupTunnelConfigTest(t)
+	d.tunnels["db"] = Tunnel{
+		Hostname:      "db",
+		State:         StateConnected,
+		Environment:   map[string]string{"REGION": "us", "EXTRA": "from-cli"},
+		SSHOverrides:  &core.SSHOverrides{ServerAliveInterval: 5},
+		ConnectReason: ContextReason("office"),
+	}
+
+	info := d.resolveTunnelConfig("db")
+	if !info.Connected {
+		t.Fatal("expected the tunnel to be reported as connected")
+	}
+	if info.OverridesFrom != "office" {
+		t.Errorf("OverridesFrom = %q, want office", info.OverridesFrom)
+	}
+	if info.SSH.ServerAliveInterval != 5 || info.SSH.ServerAliveCountMax != 3 {
+		t.Errorf("expected the override on top of the global settings, got %+v", info.SSH)
+	}
+	if !slices.Contains(info.SSHArgs, "ServerAliveInterval=5") {
+		t.Errorf("expected the overridden interval in the ssh arguments, got %v", info.SSHArgs)
+	}
+	if info.Environment["EXTRA"] != "from-cli" {
+		t.Errorf("expected the environment the tunnel was connected with, got %v", info.Environment)
+	}
+}
+
+func TestTunnelConfigCommand(t *testing.T) {
+	d := setupTunnelConfigTest(t)
+
+	resp := sendIPCCommand(t, d, "TUNNEL_CONFIG db")
+	jsonBytes, _ := json.Marshal(resp.Data)
+	var info TunnelConfigInfo
+	if err := json.Unmarshal(jsonBytes, &info); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+	if info.Alias != "db" || info.Description != "Production database" {
+		t.Errorf("unexpected tunnel config: %+v", info)
+	}
+
+	resp = sendIPCCommand(t, d, "TUNNEL_CONFIG")
+	if len(resp.Messages) == 0 || resp.Messages[0].Status != "ERROR" {
+		t.Errorf("expected a usage error without an alias, got %+v", resp.Messages)
+	}
+}
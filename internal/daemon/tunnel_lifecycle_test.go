@@ -2,17 +2,23 @@ package daemon
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"syscall"
 	"testing"
 	"time"
 
 	"go.olrik.dev/overseer/internal/core"
+	"go.olrik.dev/overseer/internal/db"
 	"go.olrik.dev/overseer/internal/testutil/sshserver"
 )
 
@@ -72,6 +78,18 @@ func setupTestDaemon(t *testing.T) (*Daemon, *sshserver.Server, string) {
 	d := New()
 	d.SetSSHConfigFile(srv.SSHConfigPath())
 
+	// Runs before the config is restored: stop the tunnels a test left running
+	// and wait for their monitors, which read core.Config
+	t.Cleanup(func() {
+		d.mu.Lock()
+		aliases := slices.Collect(maps.Keys(d.tunnels))
+		d.mu.Unlock()
+		for _, alias := range aliases {
+			d.stopTunnel(alias, false, ReasonManual)
+		}
+		d.monitors.Wait()
+	})
+
 	return d, srv, alias
 }
 
@@ -79,7 +97,7 @@ func TestStartTunnel_PubKeySuccess(t *testing.T) {
 	d, srv, alias := setupTestDaemon(t)
 	defer srv.Stop()
 
-	resp := d.startTunnel(alias, nil)
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
 
 	// Check for success
 	for _, msg := range resp.Messages {
@@ -107,14 +125,166 @@ func TestStartTunnel_PubKeySuccess(t *testing.T) {
 	}
 
 	// Cleanup
-	d.stopTunnel(alias, false)
+	d.stopTunnel(alias, false, ReasonManual)
+}
+
+func TestStartTunnel_CompanionPhases(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+
+	realSSH, err := exec.LookPath("ssh")
+	if err != nil {
+		t.Skip("ssh not available")
+	}
+
+	// Wrap ssh to record which companions had been restarted when the tunnel's SSH
+	// command (the one with -N) was launched
+	markers := t.TempDir()
+	binDir := t.TempDir()
+	wrapper := fmt.Sprintf(`#!/bin/sh
+for arg in "$@"; do
+  if [ "$arg" = "-N" ]; then
+    ls %[1]s > %[2]s
+  fi
+done
+exec %[3]s "$@"
+`, markers, filepath.Join(binDir, "launch"), realSSH)
+	if err := os.WriteFile(filepath.Join(binDir, "ssh"), []byte(wrapper), 0755); err != nil {
+		t.Fatalf("failed to write ssh wrapper: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	vpn := core.CompanionConfig{Name: "vpn", Command: "true", Timeout: 5 * time.Second, Phase: "pre_connect"}
+	forwarder := core.CompanionConfig{Name: "forwarder", Command: "true", Timeout: 5 * time.Second, Phase: "post_connect"}
+	core.Config.Tunnels[alias] = &core.TunnelConfig{Name: alias, Companions: []core.CompanionConfig{forwarder, vpn}}
+
+	// Reconnect with the pre_connect companion running and the post_connect
+	// one stopped along with the tunnel, restarting a companion leaves a
+	// marker named after it
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	d.companionMgr.companions[alias] = map[string]*CompanionProcess{}
+	for _, config := range []core.CompanionConfig{forwarder, vpn} {
+		state := CompanionStateRunning
+		if config.Phase == "post_connect" {
+			state = CompanionStateStopped
+		}
+		d.companionMgr.companions[alias][config.Name] = &CompanionProcess{
+			Name:        config.Name,
+			TunnelAlias: alias,
+			State:       state,
+			Config:      config,
+			output:      NewLogBroadcaster(100),
+			ctx:         ctx,
+			cancel:      cancel,
+		}
+	}
+	d.companionMgr.restart = func(proc *CompanionProcess) error {
+		if err := os.WriteFile(filepath.Join(markers, proc.Name), nil, 0644); err != nil {
+			return err
+		}
+		cmd := exec.Command("true")
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		proc.mu.Lock()
+		proc.Cmd = cmd
+		proc.Pid = cmd.Process.Pid
+		proc.State = CompanionStateRunning
+		proc.mu.Unlock()
+		return nil
+	}
+
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
+	defer d.stopTunnel(alias, false, ReasonManual)
+	for _, msg := range resp.Messages {
+		if msg.Status == "ERROR" {
+			t.Fatalf("startTunnel returned error: %s", msg.Message)
+		}
+	}
+
+	launch, err := os.ReadFile(filepath.Join(binDir, "launch"))
+	if err != nil {
+		t.Fatalf("SSH command was not launched through the wrapper: %v", err)
+	}
+	if got := strings.Fields(string(launch)); !reflect.DeepEqual(got, []string{"vpn"}) {
+		t.Errorf("companions started when SSH was launched = %v, want [vpn]", got)
+	}
+	if _, err := os.Stat(filepath.Join(markers, "forwarder")); err != nil {
+		t.Errorf("expected post_connect companion to run once connected: %v", err)
+	}
+}
+
+func TestStartTunnel_ReconnectPostConnectFailureStopsTunnel(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+
+	vpn := core.CompanionConfig{Name: "vpn", Command: "true", Timeout: 5 * time.Second, Phase: "pre_connect"}
+	forwarder := core.CompanionConfig{Name: "forwarder", Command: "true", Timeout: 5 * time.Second, Phase: "post_connect"}
+	core.Config.Tunnels[alias] = &core.TunnelConfig{Name: alias, Companions: []core.CompanionConfig{vpn, forwarder}}
+
+	// Reconnect with the pre_connect companion running and the blocking
+	// post_connect one stopped, restarting it fails
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	d.companionMgr.companions[alias] = map[string]*CompanionProcess{}
+	for _, config := range []core.CompanionConfig{vpn, forwarder} {
+		state := CompanionStateRunning
+		if config.Phase == "post_connect" {
+			state = CompanionStateStopped
+		}
+		d.companionMgr.companions[alias][config.Name] = &CompanionProcess{
+			Name:        config.Name,
+			TunnelAlias: alias,
+			State:       state,
+			Config:      config,
+			output:      NewLogBroadcaster(100),
+			ctx:         ctx,
+			cancel:      cancel,
+		}
+	}
+	d.companionMgr.restart = func(proc *CompanionProcess) error {
+		if proc.Config.Phase == "post_connect" {
+			return fmt.Errorf("exited with code 1")
+		}
+		cmd := exec.Command("true")
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		proc.mu.Lock()
+		proc.Cmd = cmd
+		proc.Pid = cmd.Process.Pid
+		proc.State = CompanionStateRunning
+		proc.mu.Unlock()
+		return nil
+	}
+
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
+	defer d.stopTunnel(alias, false, ReasonManual)
+
+	failed := false
+	for _, msg := range resp.Messages {
+		if msg.Status == "ERROR" && strings.Contains(msg.Message, "forwarder") {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Errorf("expected an error naming the failed companion, got %+v", resp.Messages)
+	}
+
+	d.mu.Lock()
+	_, exists := d.tunnels[alias]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected the tunnel to be stopped after the post_connect companion failed")
+	}
 }
 
 func TestStartTunnel_AlreadyRunning(t *testing.T) {
 	d, srv, alias := setupTestDaemon(t)
 	defer srv.Stop()
 
-	resp := d.startTunnel(alias, nil)
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
 	for _, msg := range resp.Messages {
 		if msg.Status == "ERROR" {
 			t.Fatalf("first startTunnel failed: %s", msg.Message)
@@ -122,7 +292,7 @@ func TestStartTunnel_AlreadyRunning(t *testing.T) {
 	}
 
 	// Try starting the same alias again
-	resp2 := d.startTunnel(alias, nil)
+	resp2 := d.startTunnel(alias, nil, nil, ReasonManual)
 
 	found := false
 	for _, msg := range resp2.Messages {
@@ -134,7 +304,7 @@ func TestStartTunnel_AlreadyRunning(t *testing.T) {
 		t.Errorf("expected 'already running' error, got messages: %+v", resp2.Messages)
 	}
 
-	d.stopTunnel(alias, false)
+	d.stopTunnel(alias, false, ReasonManual)
 }
 
 func TestStartTunnel_AuthFailure(t *testing.T) {
@@ -173,7 +343,14 @@ func TestStartTunnel_AuthFailure(t *testing.T) {
 	d := New()
 	d.SetSSHConfigFile(srv.SSHConfigPath())
 
-	resp := d.startTunnel(alias, nil)
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	d.database = database
+
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
 
 	found := false
 	for _, msg := range resp.Messages {
@@ -184,13 +361,45 @@ func TestStartTunnel_AuthFailure(t *testing.T) {
 	if !found {
 		t.Errorf("expected 'authentication failed' error, got messages: %+v", resp.Messages)
 	}
+
+	// The classified failure is kept for post-mortems, with SSH's own words
+	events, err := database.GetRecentTunnelEvents(10)
+	if err != nil {
+		t.Fatalf("failed to read tunnel events: %v", err)
+	}
+	var failure *db.TunnelEvent
+	for i, e := range events {
+		if e.EventType == "connect_failed" {
+			if failure != nil {
+				t.Fatalf("expected the failure to be recorded once, got %+v", events)
+			}
+			failure = &events[i]
+		}
+	}
+	if failure == nil {
+		t.Fatalf("expected a connect_failed event, got %+v", events)
+	}
+	if failure.Reason != "authentication failed" {
+		t.Errorf("reason = %q, want 'authentication failed'", failure.Reason)
+	}
+	if !strings.Contains(failure.Details, "Permission denied") {
+		t.Errorf("expected details to include the stderr tail, got %q", failure.Details)
+	}
+
+	counts, err := database.GetConnectionFailureCounts(alias, time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("failed to count failures: %v", err)
+	}
+	if want := []db.FailureCount{{Reason: "authentication failed", Count: 1}}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("failure counts = %+v, want %+v", counts, want)
+	}
 }
 
 func TestStopTunnel_Running(t *testing.T) {
 	d, srv, alias := setupTestDaemon(t)
 	defer srv.Stop()
 
-	resp := d.startTunnel(alias, nil)
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
 	for _, msg := range resp.Messages {
 		if msg.Status == "ERROR" {
 			t.Fatalf("startTunnel failed: %s", msg.Message)
@@ -206,7 +415,7 @@ func TestStopTunnel_Running(t *testing.T) {
 	// stopTunnel holds the mutex and monitorTunnel can't reap the zombie.
 	time.Sleep(200 * time.Millisecond)
 
-	stopResp := d.stopTunnel(alias, false)
+	stopResp := d.stopTunnel(alias, false, ReasonManual)
 	for _, msg := range stopResp.Messages {
 		if msg.Status == "ERROR" {
 			t.Errorf("stopTunnel error: %s", msg.Message)
@@ -236,7 +445,7 @@ func TestStopTunnel_NotRunning(t *testing.T) {
 	d, srv, _ := setupTestDaemon(t)
 	defer srv.Stop()
 
-	resp := d.stopTunnel("nonexistent", false)
+	resp := d.stopTunnel("nonexistent", false, ReasonManual)
 
 	found := false
 	for _, msg := range resp.Messages {
@@ -253,7 +462,7 @@ func TestStopTunnel_ForReconnect(t *testing.T) {
 	d, srv, alias := setupTestDaemon(t)
 	defer srv.Stop()
 
-	resp := d.startTunnel(alias, nil)
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
 	for _, msg := range resp.Messages {
 		if msg.Status == "ERROR" {
 			t.Fatalf("startTunnel failed: %s", msg.Message)
@@ -264,7 +473,7 @@ func TestStopTunnel_ForReconnect(t *testing.T) {
 	time.Sleep(200 * time.Millisecond)
 
 	// Stop with forReconnect=true
-	stopResp := d.stopTunnel(alias, true)
+	stopResp := d.stopTunnel(alias, true, ReasonManual)
 	for _, msg := range stopResp.Messages {
 		if msg.Status == "ERROR" {
 			t.Errorf("stopTunnel error: %s", msg.Message)
@@ -280,11 +489,219 @@ func TestStopTunnel_ForReconnect(t *testing.T) {
 	}
 }
 
+func TestRestartTunnelForCompanion(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+
+	overrides := &core.SSHOverrides{ServerAliveInterval: 5}
+	resp := d.startTunnel(alias, nil, overrides, ReasonManual)
+	for _, msg := range resp.Messages {
+		if msg.Status == "ERROR" {
+			t.Fatalf("startTunnel returned error: %s", msg.Message)
+		}
+	}
+	d.mu.Lock()
+	oldPid := d.tunnels[alias].Pid
+	d.mu.Unlock()
+
+	d.restartTunnelForCompanion(alias, "auth")
+
+	d.mu.Lock()
+	tunnel, exists := d.tunnels[alias]
+	d.mu.Unlock()
+	if !exists || tunnel.State != StateConnected {
+		t.Fatalf("expected the tunnel to be connected again, got %+v", tunnel)
+	}
+	if tunnel.Pid == oldPid {
+		t.Errorf("expected a new SSH process, still PID %d", oldPid)
+	}
+	if _, stopped := d.manuallyStopped[alias]; stopped {
+		t.Error("expected a companion restart not to count as a manual stop")
+	}
+	if tunnel.SSHOverrides != overrides {
+		t.Error("expected the restarted tunnel to keep the SSH overrides of its context")
+	}
+
+	d.stopTunnel(alias, false, ReasonManual)
+
+	// Nothing to restart once the tunnel is gone
+	d.restartTunnelForCompanion(alias, "auth")
+	d.mu.Lock()
+	_, exists = d.tunnels[alias]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected a stopped tunnel not to be started by a companion failure")
+	}
+}
+
+func TestRestartTunnelForCompanion_Backoff(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+	core.Config.SSH.InitialBackoff = "300ms"
+	core.Config.SSH.MaxBackoff = "1s"
+	core.Config.SSH.BackoffFactor = 2
+
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
+	for _, msg := range resp.Messages {
+		if msg.Status == "ERROR" {
+			t.Fatalf("startTunnel returned error: %s", msg.Message)
+		}
+	}
+
+	// The first restart happens straight away
+	start := time.Now()
+	d.restartTunnelForCompanion(alias, "auth")
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("expected the first restart not to wait, took %v", elapsed)
+	}
+
+	// A companion failing again soon after waits for the backoff
+	d.mu.Lock()
+	pid := d.tunnels[alias].Pid
+	d.mu.Unlock()
+	start = time.Now()
+	d.restartTunnelForCompanion(alias, "auth")
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("expected the second restart to wait for the backoff, took %v", elapsed)
+	}
+	d.mu.Lock()
+	tunnel, exists := d.tunnels[alias]
+	d.mu.Unlock()
+	if !exists || tunnel.Pid == pid {
+		t.Fatalf("expected the tunnel to be restarted after the backoff, got %+v", tunnel)
+	}
+
+	// A tunnel disconnected during the backoff stays disconnected
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.restartTunnelForCompanion(alias, "auth")
+	}()
+	time.Sleep(100 * time.Millisecond)
+	d.stopTunnel(alias, false, ReasonManual)
+	<-done
+	d.mu.Lock()
+	_, exists = d.tunnels[alias]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected a tunnel stopped during the backoff not to be restarted")
+	}
+}
+
+func TestStartStartupTunnels(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+	defer d.stopTunnel(alias, false, ReasonShutdown)
+
+	core.Config.Tunnels[alias] = &core.TunnelConfig{Name: alias, ConnectOnStartup: true}
+	core.Config.Tunnels["not-on-startup"] = &core.TunnelConfig{Name: "not-on-startup"}
+
+	d.startStartupTunnels()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		d.mu.Lock()
+		tunnel, exists := d.tunnels[alias]
+		d.mu.Unlock()
+		if exists && tunnel.State == StateConnected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s to be connected on startup, got %+v", alias, tunnel)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	d.mu.Lock()
+	_, exists := d.tunnels["not-on-startup"]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected a tunnel without connect_on_startup not to be connected")
+	}
+}
+
+func TestTunnelEvents_RecordReason(t *testing.T) {
+	d, srv, alias := setupTestDaemon(t)
+	defer srv.Stop()
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	d.database = database
+
+	resp := d.startTunnel(alias, nil, nil, ContextReason("office"))
+	for _, msg := range resp.Messages {
+		if msg.Status == "ERROR" {
+			t.Fatalf("startTunnel failed: %s", msg.Message)
+		}
+	}
+
+	// Give monitorTunnel goroutine time to reach cmd.Wait()
+	time.Sleep(200 * time.Millisecond)
+
+	d.stopTunnel(alias, false, ReasonManual)
+
+	events, err := database.GetRecentTunnelEvents(10)
+	if err != nil {
+		t.Fatalf("failed to read tunnel events: %v", err)
+	}
+
+	reasons := make(map[string]string)
+	for _, e := range events {
+		if e.TunnelAlias == alias {
+			reasons[e.EventType] = e.Reason
+		}
+	}
+	if reasons["connect"] != "context:office" {
+		t.Errorf("connect reason = %q, want %q", reasons["connect"], "context:office")
+	}
+	if reasons["manual_disconnect"] != ReasonManual {
+		t.Errorf("manual_disconnect reason = %q, want %q", reasons["manual_disconnect"], ReasonManual)
+	}
+
+	// The event type of a stop follows its reason
+	d.startTunnel(alias, nil, nil, ContextReason("office"))
+	d.stopTunnel(alias, false, ContextReason("home"))
+
+	events, err = database.GetRecentTunnelEvents(10)
+	if err != nil {
+		t.Fatalf("failed to read tunnel events: %v", err)
+	}
+	var stopType string
+	for _, e := range events {
+		if e.TunnelAlias == alias && e.Reason == "context:home" {
+			stopType = e.EventType
+		}
+	}
+	if stopType != "context_disconnect" {
+		t.Errorf("context stop recorded as %q, want %q", stopType, "context_disconnect")
+	}
+}
+
+func TestStopEventType(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{ReasonManual, "manual_disconnect"},
+		{ReasonCompanion, "companion_disconnect"},
+		{ContextReason("office"), "context_disconnect"},
+		{ReasonShutdown, "disconnect"},
+	}
+	for _, tt := range tests {
+		if got := stopEventType(tt.reason); got != tt.want {
+			t.Errorf("stopEventType(%q) = %q, want %q", tt.reason, got, tt.want)
+		}
+	}
+}
+
 func TestMonitorTunnel_ServerStop(t *testing.T) {
 	d, srv, alias := setupTestDaemon(t)
 	// Don't defer srv.Stop() — we stop it mid-test
 
-	resp := d.startTunnel(alias, nil)
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
 	for _, msg := range resp.Messages {
 		if msg.Status == "ERROR" {
 			t.Fatalf("startTunnel failed: %s", msg.Message)
@@ -313,11 +730,74 @@ func TestMonitorTunnel_ServerStop(t *testing.T) {
 	}
 }
 
+func TestMonitorTunnel_GiveUpRunsHooks(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	markers := t.TempDir()
+	core.Config = &core.Configuration{
+		SSH:       core.SSHConfig{ReconnectEnabled: true, MaxRetries: 3},
+		Companion: core.CompanionSettings{HistorySize: 50},
+		Tunnels: map[string]*core.TunnelConfig{
+			"flaky": {Name: "flaky", Hooks: &core.TunnelHooksConfig{
+				OnGiveUp: []core.HookConfig{{Command: `echo "$OVERSEER_HOOK_TYPE $OVERSEER_TUNNEL_ALIAS" > ` + filepath.Join(markers, "tunnel"), Timeout: 5 * time.Second}},
+			}},
+		},
+		GlobalTunnelHooks: &core.TunnelHooksConfig{
+			OnGiveUp: []core.HookConfig{{Command: "touch " + filepath.Join(markers, "global"), Timeout: 5 * time.Second}},
+		},
+	}
+
+	d := New()
+
+	// The last allowed attempt has been used, so the next exit gives up
+	cmd := exec.Command("sh", "-c", "exit 255")
+	proc, err := newSSHProcess(cmd)
+	if err != nil {
+		t.Fatalf("newSSHProcess() error: %v", err)
+	}
+	if err := proc.start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	d.tunnels["flaky"] = Tunnel{
+		Hostname:      "flaky",
+		Pid:           cmd.Process.Pid,
+		Cmd:           cmd,
+		Process:       proc,
+		State:         StateConnected,
+		AutoReconnect: true,
+		RetryCount:    3,
+	}
+
+	d.monitorTunnel("flaky")
+
+	if _, exists := d.tunnels["flaky"]; exists {
+		t.Fatal("expected the tunnel to be removed after giving up")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tunnelHook, err := os.ReadFile(filepath.Join(markers, "tunnel"))
+		_, globalErr := os.Stat(filepath.Join(markers, "global"))
+		if err == nil && globalErr == nil && len(tunnelHook) > 0 {
+			if got := strings.TrimSpace(string(tunnelHook)); got != "on_give_up flaky" {
+				t.Errorf("hook environment = %q, want %q", got, "on_give_up flaky")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the on_give_up hooks to run")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
 func TestMonitorTunnel_ManualStop(t *testing.T) {
 	d, srv, alias := setupTestDaemon(t)
 	defer srv.Stop()
 
-	resp := d.startTunnel(alias, nil)
+	resp := d.startTunnel(alias, nil, nil, ReasonManual)
 	for _, msg := range resp.Messages {
 		if msg.Status == "ERROR" {
 			t.Fatalf("startTunnel failed: %s", msg.Message)
@@ -325,7 +805,7 @@ func TestMonitorTunnel_ManualStop(t *testing.T) {
 	}
 
 	// Manually stop the tunnel — monitor goroutine should exit cleanly
-	d.stopTunnel(alias, false)
+	d.stopTunnel(alias, false, ReasonManual)
 
 	// Give monitor goroutine time to notice and exit
 	time.Sleep(1 * time.Second)
@@ -339,6 +819,127 @@ func TestMonitorTunnel_ManualStop(t *testing.T) {
 	}
 }
 
+// setupReconnectingTestDaemon is setupTestDaemon with auto-reconnect enabled
+// and an online state orchestrator, so monitorTunnel reconnects a dropped
+// tunnel straight away
+func setupReconnectingTestDaemon(t *testing.T) (*Daemon, *sshserver.Server, string) {
+	t.Helper()
+	d, srv, alias := setupTestDaemon(t)
+	setOnlineOrchestrator(t)
+	// Stopped, the orchestrator keeps reporting online without probing the
+	// network every time a tunnel connects
+	stateOrchestrator.Stop()
+	// Runs before the orchestrator is restored, which monitors read
+	t.Cleanup(d.monitors.Wait)
+
+	core.Config.SSH = core.SSHConfig{
+		ReconnectEnabled: true,
+		InitialBackoff:   "10ms",
+		MaxBackoff:       "1s",
+		BackoffFactor:    2,
+		MaxRetries:       2,
+	}
+	return d, srv, alias
+}
+
+// dropTunnel kills the SSH process of alias and waits for monitorTunnel to
+// reconnect it
+func dropTunnel(t *testing.T, d *Daemon, alias string) Tunnel {
+	t.Helper()
+	d.mu.Lock()
+	dropped := d.tunnels[alias].Cmd
+	d.mu.Unlock()
+	dropped.Process.Kill()
+
+	deadline := time.After(20 * time.Second)
+	for {
+		d.mu.Lock()
+		tunnel, exists := d.tunnels[alias]
+		d.mu.Unlock()
+		if !exists {
+			t.Fatal("expected the dropped tunnel to be reconnected, it was removed")
+		}
+		if tunnel.State == StateConnected && tunnel.Cmd != dropped {
+			return tunnel
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for the tunnel to reconnect, state %q", tunnel.State)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestMonitorTunnel_ReconnectUsesTunnelSSHArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		remoteCommand string
+	}{
+		{"port forwarding", ""},
+		{"remote command", "tail -f /var/log/app.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, srv, alias := setupReconnectingTestDaemon(t)
+			defer srv.Stop()
+			core.Config.Tunnels[alias] = &core.TunnelConfig{Name: alias, RemoteCommand: tt.remoteCommand}
+
+			resp := d.startTunnel(alias, nil, nil, ReasonManual)
+			for _, msg := range resp.Messages {
+				if msg.Status == "ERROR" {
+					t.Fatalf("startTunnel failed: %s", msg.Message)
+				}
+			}
+			defer d.stopTunnel(alias, false, ReasonManual)
+
+			tunnel := dropTunnel(t, d, alias)
+
+			// The reconnect runs ssh exactly like the first connect, including
+			// ControlPersist=no and the remote command passed only once
+			settings := effectiveSSHSettings(nil)
+			want := buildTunnelSSHArgs(alias, d.sshConfigFile, settings.ServerAliveInterval, settings.ServerAliveCountMax, settings.NoControlMaster)
+			if got := tunnel.Cmd.Args[1:]; !slices.Equal(got, want) {
+				t.Errorf("reconnect args = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMonitorTunnel_FlappingTunnelRetryCount(t *testing.T) {
+	tests := []struct {
+		name        string
+		stableReset time.Duration
+		wantRetries []int // RetryCount after each successful reconnect
+	}{
+		// More drops than max_retries, each followed by a good reconnect
+		{"retries start over on every reconnect", 0, []int{0, 0, 0}},
+		{"stable_reset keeps backing off", time.Hour, []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, srv, alias := setupReconnectingTestDaemon(t)
+			defer srv.Stop()
+			core.Config.SSH.StableReset = tt.stableReset
+
+			resp := d.startTunnel(alias, nil, nil, ReasonManual)
+			for _, msg := range resp.Messages {
+				if msg.Status == "ERROR" {
+					t.Fatalf("startTunnel failed: %s", msg.Message)
+				}
+			}
+			defer d.stopTunnel(alias, false, ReasonManual)
+
+			for i, want := range tt.wantRetries {
+				if tunnel := dropTunnel(t, d, alias); tunnel.RetryCount != want {
+					t.Fatalf("drop %d: RetryCount = %d, want %d", i+1, tunnel.RetryCount, want)
+				}
+			}
+		})
+	}
+}
+
 func TestGracefulTerminate_Process(t *testing.T) {
 	// Start a long-running process in its own session (matches SSH tunnel behavior).
 	cmd := exec.Command("sleep", "60")
@@ -64,6 +64,9 @@ func (d *Daemon) SaveTunnelState() error {
 		// Note: We can't get the full cmdline from exec.Cmd after Start(),
 		// so we reconstruct it based on our config
 		cmdline := []string{"ssh", alias, "-N", "-o", "IgnoreUnknown=overseer-daemon", "-o", "overseer-daemon=" + core.ProcessTag(), "-o", "ExitOnForwardFailure=yes", "-v"}
+		if command := tunnelRemoteCommand(alias); command != "" {
+			cmdline = withRemoteCommand(cmdline, command)
+		}
 
 		info := TunnelInfo{
 			PID:               tunnel.Pid,
@@ -2,11 +2,15 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"go.olrik.dev/overseer/internal/awareness/state"
 	"go.olrik.dev/overseer/internal/core"
 	"go.olrik.dev/overseer/internal/db"
 )
@@ -39,7 +43,7 @@ func TestStartTunnelStreaming_AlreadyRunningHealthy(t *testing.T) {
 
 	// Should return error because tunnel is "already running"
 	// (health check uses signal check + TCP - signal will pass for our sleep process)
-	resp := d.startTunnelStreaming("running-tunnel", nil, nil, false)
+	resp := d.startTunnelStreaming("running-tunnel", nil, nil, false, "", nil, ReasonManual)
 
 	// Even if TCP check fails (making health check fail), the stale cleanup path is exercised
 	_ = resp
@@ -69,7 +73,7 @@ func TestStartTunnelStreaming_StaleTunnelCleanup(t *testing.T) {
 
 	// startTunnelStreaming should clean up the stale entry and try to connect
 	// It will fail to connect (no valid SSH target), but the cleanup path is exercised
-	resp := d.startTunnelStreaming("stale-tunnel", nil, nil, false)
+	resp := d.startTunnelStreaming("stale-tunnel", nil, nil, false, "", nil, ReasonManual)
 
 	// The stale token should be cleaned up
 	if _, exists := d.askpassTokens["stale-token-123"]; exists {
@@ -108,7 +112,7 @@ func TestStartTunnelStreaming_StaleTunnelWithDatabase(t *testing.T) {
 	}
 
 	// Should clean up stale entry and log to database
-	resp := d.startTunnelStreaming("stale-db", nil, nil, false)
+	resp := d.startTunnelStreaming("stale-db", nil, nil, false, "", nil, ReasonManual)
 	_ = resp
 }
 
@@ -127,12 +131,47 @@ func TestStartTunnelStreaming_NoTunnelConfig(t *testing.T) {
 
 	// No tunnel in config, but SSH alias exists on the system
 	// startTunnelStreaming should skip companion section and go straight to SSH
-	resp := d.startTunnelStreaming("no-config-alias", nil, nil, false)
+	resp := d.startTunnelStreaming("no-config-alias", nil, nil, false, "", nil, ReasonManual)
 
 	// Will fail because SSH can't connect, but the no-companions code path is exercised
 	_ = resp
 }
 
+func TestStartTunnelStreaming_DisabledTunnel(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		Tunnels: map[string]*core.TunnelConfig{
+			"disabled-tunnel": {Name: "disabled-tunnel", Disabled: true},
+		},
+	}
+
+	d := New()
+
+	resp := d.startTunnelStreaming("disabled-tunnel", nil, nil, false, "", nil, ReasonManual)
+	if len(resp.Messages) != 1 || resp.Messages[0].Status != "ERROR" ||
+		!strings.Contains(resp.Messages[0].Message, "disabled") {
+		t.Errorf("expected a disabled error, got %+v", resp.Messages)
+	}
+
+	// Contexts don't auto-connect it either
+	d.handleNewContextChange(
+		state.StateSnapshot{Context: "untrusted", Location: "unknown", Online: true},
+		state.StateSnapshot{Context: "home", Location: "home", Online: true},
+		&state.Rule{Name: "home", Actions: state.RuleActions{Connect: []string{"disabled-tunnel"}}},
+	)
+
+	d.mu.Lock()
+	_, exists := d.tunnels["disabled-tunnel"]
+	d.mu.Unlock()
+	if exists {
+		t.Error("expected disabled tunnel not to be connected")
+	}
+}
+
 func TestStartTunnelStreaming_WithEnvironment(t *testing.T) {
 	quietLogger(t)
 
@@ -152,7 +191,7 @@ func TestStartTunnelStreaming_WithEnvironment(t *testing.T) {
 	d := New()
 
 	// Test with CLI env that overrides config env
-	resp := d.startTunnelStreaming("env-tunnel", map[string]string{"OVERSEER_TAG": "cli-tag"}, nil, false)
+	resp := d.startTunnelStreaming("env-tunnel", map[string]string{"OVERSEER_TAG": "cli-tag"}, nil, false, "", nil, ReasonManual)
 	_ = resp
 }
 
@@ -174,7 +213,7 @@ func TestStartTunnelStreaming_WithSSHConfig(t *testing.T) {
 	d := New()
 	d.sshConfigFile = sshConfigPath
 
-	resp := d.startTunnelStreaming("config-test", nil, nil, false)
+	resp := d.startTunnelStreaming("config-test", nil, nil, false, "", nil, ReasonManual)
 	_ = resp
 }
 
@@ -194,7 +233,7 @@ func TestStartTunnelStreaming_WithServerAliveInterval(t *testing.T) {
 
 	d := New()
 
-	resp := d.startTunnelStreaming("alive-test", nil, nil, false)
+	resp := d.startTunnelStreaming("alive-test", nil, nil, false, "", nil, ReasonManual)
 	_ = resp
 }
 
@@ -214,6 +253,99 @@ func TestStartTunnel_NoConfig(t *testing.T) {
 	t.Cleanup(d.cancelFunc)
 
 	// startTunnel calls startTunnelStreaming with nil stream
-	resp := d.startTunnel("basic-alias", nil)
+	resp := d.startTunnel("basic-alias", nil, nil, ReasonManual)
 	_ = resp
 }
+
+// lineWriter sends every line written to it on a channel
+type lineWriter chan string
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestStartTunnelStreaming_StreamsCompanionProgress(t *testing.T) {
+	quietLogger(t)
+
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	companion := core.CompanionConfig{Name: "vpn", Command: "true", Timeout: 5 * time.Second}
+	core.Config = &core.Configuration{
+		Companion: core.CompanionSettings{HistorySize: 50},
+		Tunnels: map[string]*core.TunnelConfig{
+			"comp-tunnel": {Name: "comp-tunnel", Companions: []core.CompanionConfig{companion}},
+		},
+		SSH: core.SSHConfig{},
+	}
+
+	d := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	d.companionMgr.companions["comp-tunnel"] = map[string]*CompanionProcess{
+		"vpn": {
+			Name:        "vpn",
+			TunnelAlias: "comp-tunnel",
+			State:       CompanionStateRunning,
+			Config:      companion,
+			output:      NewLogBroadcaster(100),
+			ctx:         ctx,
+			cancel:      cancel,
+		},
+	}
+	// The restarted companion takes a while to complete
+	d.companionMgr.restart = func(proc *CompanionProcess) error {
+		cmd := exec.Command("sleep", "0.3")
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		proc.mu.Lock()
+		proc.Cmd = cmd
+		proc.Pid = cmd.Process.Pid
+		proc.State = CompanionStateRunning
+		proc.mu.Unlock()
+		return nil
+	}
+
+	lines := make(lineWriter, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.startTunnelStreaming("comp-tunnel", nil, NewStreamingResponse(lines), false, "", nil, ReasonManual)
+	}()
+
+	// Progress must reach the client while the companion is still starting,
+	// not only once the connect has finished
+	want := []string{"Restarting companion scripts...", "Restarting companion 'vpn'...", "Companion 'vpn' ready"}
+	for i, message := range want {
+		select {
+		case line := <-lines:
+			var msg ResponseMessage
+			if err := json.Unmarshal([]byte(line), &msg); err != nil {
+				t.Fatalf("invalid stream line %q: %v", line, err)
+			}
+			if msg.Message != message || msg.Status != "INFO" {
+				t.Fatalf("line %d = %s %q, want INFO %q", i, msg.Status, msg.Message, message)
+			}
+			if i < 2 {
+				select {
+				case <-done:
+					t.Fatalf("%q was only streamed after the connect finished", message)
+				default:
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", message)
+		}
+	}
+
+	// Drain the rest of the connect attempt
+	for {
+		select {
+		case <-lines:
+		case <-done:
+			return
+		}
+	}
+}
@@ -3,9 +3,12 @@ package daemon
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"strings"
 	"testing"
 	"time"
+
+	"go.olrik.dev/overseer/internal/core"
 )
 
 // setupDaemonForVerify creates a Daemon with a tunnel entry so verifyConnection can update ResolvedHost.
@@ -80,6 +83,95 @@ func TestVerifyConnection_PledgeNetwork(t *testing.T) {
 	}
 }
 
+func TestVerifyConnection_MuxClient(t *testing.T) {
+	quietLogger(t)
+	d := setupDaemonForVerify(t, "muxhost")
+
+	r, w := io.Pipe()
+	result := make(chan error, 1)
+	go d.verifyConnection(r, "muxhost", result)
+
+	// Joining an existing ControlMaster never prints "Authenticated to"
+	go writeLines(w,
+		"debug1: auto-mux: Trying existing master at '/tmp/cm-muxhost'",
+		"debug1: mux_client_request_session: master session id: 2",
+	)
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for verifyConnection result")
+	}
+}
+
+func TestVerifyConnection_RemoteCommand(t *testing.T) {
+	quietLogger(t)
+	oldConfig := core.Config
+	t.Cleanup(func() { core.Config = oldConfig })
+	core.Config = &core.Configuration{Tunnels: map[string]*core.TunnelConfig{
+		"cmdhost": {Name: "cmdhost", RemoteCommand: "tail -f /var/log/app.log"},
+	}}
+
+	t.Run("command sent", func(t *testing.T) {
+		d := setupDaemonForVerify(t, "cmdhost")
+
+		r, w := io.Pipe()
+		result := make(chan error, 1)
+		go d.verifyConnection(r, "cmdhost", result)
+
+		// SSH prints the -N session lines before it requests the command
+		fmt.Fprintln(w, "debug1: Authenticated to cmdhost ([1.2.3.4]:22).")
+		fmt.Fprintln(w, "debug1: Entering interactive session.")
+		fmt.Fprintln(w, "debug1: pledge: network")
+		select {
+		case err := <-result:
+			t.Fatalf("expected no result before the command is sent, got %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		go writeLines(w,
+			"debug1: Sending environment.",
+			"debug1: Sending command: tail -f /var/log/app.log",
+		)
+
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for verifyConnection result")
+		}
+	})
+
+	t.Run("session refused", func(t *testing.T) {
+		d := setupDaemonForVerify(t, "cmdhost")
+
+		r, w := io.Pipe()
+		result := make(chan error, 1)
+		go d.verifyConnection(r, "cmdhost", result)
+
+		go writeLines(w,
+			"debug1: Authenticated to cmdhost ([1.2.3.4]:22).",
+			"debug1: Entering interactive session.",
+			"debug1: pledge: network",
+			"channel 0: open failed: administratively prohibited: open failed",
+		)
+
+		select {
+		case err := <-result:
+			if err == nil {
+				t.Fatal("expected an error when the command is never sent")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for verifyConnection result")
+		}
+	})
+}
+
 func TestVerifyConnection_PermissionDenied(t *testing.T) {
 	quietLogger(t)
 	d := setupDaemonForVerify(t, "denied")
@@ -239,3 +331,95 @@ func TestVerifyConnection_ProxyHop(t *testing.T) {
 		t.Errorf("expected ResolvedHost 'jump.example.com:2222', got %q", resolved)
 	}
 }
+
+func TestVerifyConnection_FailureCategories(t *testing.T) {
+	quietLogger(t)
+
+	tests := []struct {
+		line  string
+		fatal bool
+	}{
+		{"user@host: Permission denied (publickey).", true},
+		{"Host key verification failed.", true},
+		{"Received disconnect from 1.2.3.4 port 22:2: Too many authentication failures", true},
+		{"ssh: connect to host 1.2.3.4 port 22: Connection refused", false},
+		{"ssh: connect to host 1.2.3.4 port 22: No route to host", false},
+		{"ssh: connect to host 1.2.3.4 port 22: Connection timed out", false},
+		{"ssh: Could not resolve hostname nowhere: Name or service not known", false},
+	}
+
+	for _, tt := range tests {
+		d := setupDaemonForVerify(t, "host")
+		r, w := io.Pipe()
+		result := make(chan error, 1)
+		go d.verifyConnection(r, "host", result)
+		go writeLines(w, tt.line)
+
+		select {
+		case err := <-result:
+			if err == nil {
+				t.Fatalf("%q: expected error, got nil", tt.line)
+			}
+			if got := isFatalSSHError(err); got != tt.fatal {
+				t.Errorf("%q: isFatalSSHError() = %v, want %v", tt.line, got, tt.fatal)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q: timed out waiting for verifyConnection result", tt.line)
+		}
+	}
+
+	if isFatalSSHError(errSSHTerminated) {
+		t.Error("expected an unexpected termination to be retried")
+	}
+}
+
+func TestVerifyConnection_VerboseAfterConnect(t *testing.T) {
+	for _, quiet := range []bool{false, true} {
+		t.Run(fmt.Sprintf("quiet=%v", quiet), func(t *testing.T) {
+			oldConfig := core.Config
+			t.Cleanup(func() { core.Config = oldConfig })
+			core.Config = &core.Configuration{SSH: core.SSHConfig{QuietAfterConnect: quiet}}
+
+			// Log debug output to a broadcaster, as the daemon does
+			oldLogger := slog.Default()
+			t.Cleanup(func() { slog.SetDefault(oldLogger) })
+			broadcaster := NewLogBroadcaster(100)
+			slog.SetDefault(slog.New(slog.NewTextHandler(&LogWriter{broadcaster: broadcaster}, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+			d := setupDaemonForVerify(t, "myhost")
+			r, w := io.Pipe()
+			result := make(chan error, 1)
+			done := make(chan struct{})
+			go func() {
+				d.verifyConnection(r, "myhost", result)
+				close(done)
+			}()
+
+			go writeLines(w,
+				"debug1: Authenticated to myhost ([1.2.3.4]:22).",
+				"debug1: Entering interactive session.",
+				"debug1: client_input_global_request: rtype keepalive@openssh.com",
+				"debug1: channel 1: new [port listener]",
+			)
+
+			if err := <-result; err != nil {
+				t.Fatalf("expected nil error, got %v", err)
+			}
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("verifyConnection did not drain stderr")
+			}
+
+			broadcast := broadcaster.FilterHistory(func(line string) bool {
+				return strings.Contains(line, "keepalive@openssh.com") || strings.Contains(line, "port listener")
+			})
+			if quiet && len(broadcast) != 0 {
+				t.Errorf("expected no lines after connect to be broadcast, got %q", broadcast)
+			}
+			if !quiet && len(broadcast) != 2 {
+				t.Errorf("expected the lines after connect to be broadcast, got %q", broadcast)
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@ package daemon
 import (
 	"context"
 	"net"
+	"os/exec"
 	"testing"
 	"time"
+
+	"go.olrik.dev/overseer/internal/core"
 )
 
 func TestHandleWrapperConnection_NormalOutput(t *testing.T) {
@@ -157,3 +160,141 @@ func TestHandleWrapperConnection_CtxCancelled(t *testing.T) {
 		t.Fatal("timeout waiting for handler to return after ctx cancel")
 	}
 }
+
+func TestHandleWrapperConnection_ExitMarker(t *testing.T) {
+	quietLogger(t)
+
+	cm := NewCompanionManager()
+	broadcaster := NewLogBroadcaster(100)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// The wrapper itself exits cleanly, masking its child's exit code
+	cmd := exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	proc := &CompanionProcess{
+		Name:         "test-comp",
+		TunnelAlias:  "test-tunnel",
+		Cmd:          cmd,
+		State:        CompanionStateRunning,
+		Config:       core.CompanionConfig{Name: "test-comp"},
+		exitReported: make(chan struct{}),
+		token:        "secret",
+		output:       broadcaster,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	client, server := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cm.handleWrapperConnection(proc, server)
+	}()
+
+	ch := broadcaster.Subscribe()
+	defer broadcaster.Unsubscribe(ch)
+
+	// Only the marker with the wrapper's token counts, one without it is
+	// output like any other line
+	if _, err := client.Write([]byte("EXIT 0\nEXIT wrong 0\n" + CompanionExitMarker("secret", 3) + "\nlast line\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	for _, want := range []string{"EXIT 0\n", "EXIT wrong 0\n", "last line\n"} {
+		select {
+		case msg := <-ch:
+			if msg != want {
+				t.Errorf("expected %q (the marker shouldn't be broadcast), got %q", want, msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for broadcast message")
+		}
+	}
+
+	client.Close()
+	<-done
+
+	cm.monitorCompanion(proc)
+
+	proc.mu.RLock()
+	exitCode := proc.ExitCode
+	exitError := proc.ExitError
+	proc.mu.RUnlock()
+	if exitCode == nil || *exitCode != 3 {
+		t.Errorf("expected reported exit code 3, got %v", exitCode)
+	}
+	if exitError == "" {
+		t.Error("expected non-empty exit error for a non-zero reported code")
+	}
+}
+
+func TestMonitorCompanion_WaitsForExitMarker(t *testing.T) {
+	quietLogger(t)
+
+	cm := NewCompanionManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// The wrapper has exited, but its marker is still on its way
+	cmd := exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	proc := &CompanionProcess{
+		Name:         "test-comp",
+		TunnelAlias:  "test-tunnel",
+		Cmd:          cmd,
+		State:        CompanionStateRunning,
+		Config:       core.CompanionConfig{Name: "test-comp"},
+		exitReported: make(chan struct{}),
+		token:        "secret",
+		output:       NewLogBroadcaster(100),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	client, server := net.Pipe()
+	go cm.handleWrapperConnection(proc, server)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		client.Write([]byte(CompanionExitMarker("secret", 4) + "\n"))
+		client.Close()
+	}()
+
+	cm.monitorCompanion(proc)
+
+	proc.mu.RLock()
+	exitCode := proc.ExitCode
+	proc.mu.RUnlock()
+	if exitCode == nil || *exitCode != 4 {
+		t.Errorf("expected the exit code reported after the wrapper exited, got %v", exitCode)
+	}
+}
+
+func TestParseExitMarker(t *testing.T) {
+	tests := []struct {
+		line   string
+		token  string
+		code   int
+		wantOK bool
+	}{
+		{CompanionExitMarker("secret", 3), "secret", 3, true},
+		{CompanionExitMarker("secret", 0), "secret", 0, true},
+		{CompanionExitMarker("other", 3), "secret", 0, false},
+		{"EXIT 3", "secret", 0, false},
+		{"EXIT secret", "secret", 0, false},
+		{"EXIT secret three", "secret", 0, false},
+		{"EXIT  3", "", 0, false},
+	}
+	for _, tt := range tests {
+		code, ok := parseExitMarker(tt.line, tt.token)
+		if ok != tt.wantOK || code != tt.code {
+			t.Errorf("parseExitMarker(%q, %q) = %d, %v, want %d, %v", tt.line, tt.token, code, ok, tt.code, tt.wantOK)
+		}
+	}
+}
@@ -90,6 +90,7 @@ func (db *DB) initSchema() error {
 		tunnel_alias TEXT NOT NULL,
 		event_type TEXT NOT NULL,
 		details TEXT,
+		reason TEXT NOT NULL DEFAULT '',
 		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
@@ -101,16 +102,72 @@ func (db *DB) initSchema() error {
 		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
+	-- Context transitions and what triggered them
+	CREATE TABLE IF NOT EXISTS context_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_context TEXT NOT NULL DEFAULT '',
+		to_context TEXT NOT NULL DEFAULT '',
+		from_location TEXT NOT NULL DEFAULT '',
+		to_location TEXT NOT NULL DEFAULT '',
+		trigger TEXT NOT NULL DEFAULT '',
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
 	-- Indexes for common queries
 	CREATE INDEX IF NOT EXISTS idx_sensor_changes_timestamp ON sensor_changes(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_sensor_changes_name ON sensor_changes(sensor_name);
 	CREATE INDEX IF NOT EXISTS idx_tunnel_events_timestamp ON tunnel_events(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_tunnel_events_alias ON tunnel_events(tunnel_alias);
 	CREATE INDEX IF NOT EXISTS idx_daemon_events_timestamp ON daemon_events(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_context_changes_timestamp ON context_changes(timestamp);
 	`
 
-	_, err := db.conn.Exec(schema)
-	return err
+	if _, err := db.conn.Exec(schema); err != nil {
+		return err
+	}
+
+	return db.migrateSchema()
+}
+
+// migrateSchema adds columns introduced after a table was first created
+func (db *DB) migrateSchema() error {
+	hasReason, err := db.hasColumn("tunnel_events", "reason")
+	if err != nil {
+		return err
+	}
+	if !hasReason {
+		if _, err := db.conn.Exec(`ALTER TABLE tunnel_events ADD COLUMN reason TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("failed to add reason column to tunnel_events: %w", err)
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether table has a column with the given name
+func (db *DB) hasColumn(table, column string) (bool, error) {
+	rows, err := db.conn.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
 }
 
 // SensorChange represents a sensor state change
@@ -187,19 +244,31 @@ type TunnelEvent struct {
 	TunnelAlias string
 	EventType   string
 	Details     string
+	Reason      string // What triggered the event (empty if unknown)
 	Timestamp   time.Time
 }
 
 // LogTunnelEvent logs a tunnel lifecycle event to the database
 func (db *DB) LogTunnelEvent(tunnelAlias, eventType, details string) error {
+	return db.LogTunnelEventWithReason(tunnelAlias, eventType, details, "")
+}
+
+// LogTunnelEventWithReason logs a tunnel lifecycle event along with what
+// triggered it (e.g. "manual", "reconnect", "context:office")
+func (db *DB) LogTunnelEventWithReason(tunnelAlias, eventType, details, reason string) error {
+	return db.LogTunnelEventAt(tunnelAlias, eventType, details, reason, time.Now())
+}
+
+// LogTunnelEventAt logs a tunnel lifecycle event at a specific timestamp
+func (db *DB) LogTunnelEventAt(tunnelAlias, eventType, details, reason string, timestamp time.Time) error {
 	// Retry briefly if database is locked (3 attempts, 5ms between)
 	// This is best-effort - we don't want to block daemon shutdown
 	maxRetries := 3
 	for i := 0; i < maxRetries; i++ {
 		_, err := db.conn.Exec(
-			`INSERT INTO tunnel_events (tunnel_alias, event_type, details, timestamp)
-			 VALUES (?, ?, ?, ?)`,
-			tunnelAlias, eventType, details, time.Now(),
+			`INSERT INTO tunnel_events (tunnel_alias, event_type, details, reason, timestamp)
+			 VALUES (?, ?, ?, ?, ?)`,
+			tunnelAlias, eventType, details, reason, timestamp,
 		)
 		if err == nil {
 			return nil
@@ -234,6 +303,52 @@ func (db *DB) LogDaemonEvent(eventType, details string) error {
 	return err
 }
 
+// ContextChange represents a transition between contexts and/or locations
+type ContextChange struct {
+	ID           int64
+	FromContext  string
+	ToContext    string
+	FromLocation string
+	ToLocation   string
+	Trigger      string // What caused the change (e.g. "sensor_change", "manual")
+	Timestamp    time.Time
+}
+
+// LogContextChange logs a context and/or location transition to the database
+func (db *DB) LogContextChange(fromContext, toContext, fromLocation, toLocation, trigger string) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO context_changes (from_context, to_context, from_location, to_location, trigger, timestamp)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		fromContext, toContext, fromLocation, toLocation, trigger, time.Now(),
+	)
+	return err
+}
+
+// GetContextChanges retrieves context changes within [start, end) in chronological order
+func (db *DB) GetContextChanges(start, end time.Time) ([]ContextChange, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, from_context, to_context, from_location, to_location, trigger, timestamp
+		 FROM context_changes
+		 WHERE timestamp >= ? AND timestamp < ?
+		 ORDER BY timestamp ASC, id ASC`,
+		start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []ContextChange
+	for rows.Next() {
+		var c ContextChange
+		if err := rows.Scan(&c.ID, &c.FromContext, &c.ToContext, &c.FromLocation, &c.ToLocation, &c.Trigger, &c.Timestamp); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
 // GetRecentSensorChanges retrieves recent sensor changes
 func (db *DB) GetRecentSensorChanges(limit int) ([]SensorChange, error) {
 	rows, err := db.conn.Query(
@@ -262,7 +377,7 @@ func (db *DB) GetRecentSensorChanges(limit int) ([]SensorChange, error) {
 // GetRecentTunnelEvents retrieves recent tunnel events
 func (db *DB) GetRecentTunnelEvents(limit int) ([]TunnelEvent, error) {
 	rows, err := db.conn.Query(
-		`SELECT id, tunnel_alias, event_type, details, timestamp
+		`SELECT id, tunnel_alias, event_type, details, reason, timestamp
 		 FROM tunnel_events
 		 ORDER BY timestamp DESC
 		 LIMIT ?`,
@@ -276,7 +391,7 @@ func (db *DB) GetRecentTunnelEvents(limit int) ([]TunnelEvent, error) {
 	var events []TunnelEvent
 	for rows.Next() {
 		var e TunnelEvent
-		if err := rows.Scan(&e.ID, &e.TunnelAlias, &e.EventType, &e.Details, &e.Timestamp); err != nil {
+		if err := rows.Scan(&e.ID, &e.TunnelAlias, &e.EventType, &e.Details, &e.Reason, &e.Timestamp); err != nil {
 			return nil, err
 		}
 		events = append(events, e)
@@ -284,6 +399,67 @@ func (db *DB) GetRecentTunnelEvents(limit int) ([]TunnelEvent, error) {
 	return events, rows.Err()
 }
 
+// GetTunnelEvents retrieves all events for one tunnel that happened before end,
+// in chronological order. Events before the range of interest are included so
+// callers can determine the tunnel's state at the start of the range.
+func (db *DB) GetTunnelEvents(tunnelAlias string, end time.Time) ([]TunnelEvent, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, tunnel_alias, event_type, details, reason, timestamp
+		 FROM tunnel_events
+		 WHERE tunnel_alias = ? AND timestamp < ?
+		 ORDER BY timestamp ASC, id ASC`,
+		tunnelAlias, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []TunnelEvent
+	for rows.Next() {
+		var e TunnelEvent
+		if err := rows.Scan(&e.ID, &e.TunnelAlias, &e.EventType, &e.Details, &e.Reason, &e.Timestamp); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// FailureCount is how often connection attempts failed for one reason
+type FailureCount struct {
+	Reason string
+	Count  int
+}
+
+// GetConnectionFailureCounts counts the failed connect and reconnect attempts
+// within the given time range by reason, the kind of failure, most frequent
+// first. An empty tunnelAlias counts the failures of all tunnels.
+func (db *DB) GetConnectionFailureCounts(tunnelAlias string, start, end time.Time) ([]FailureCount, error) {
+	rows, err := db.conn.Query(
+		`SELECT reason, COUNT(*) AS failures
+		 FROM tunnel_events
+		 WHERE event_type IN ('connect_failed', 'reconnect_failed') AND timestamp >= ? AND timestamp < ? AND (? = '' OR tunnel_alias = ?)
+		 GROUP BY reason
+		 ORDER BY failures DESC, reason ASC`,
+		start, end, tunnelAlias, tunnelAlias,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []FailureCount
+	for rows.Next() {
+		var c FailureCount
+		if err := rows.Scan(&c.Reason, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
 // GetRecentDaemonEvents retrieves recent daemon events
 func (db *DB) GetRecentDaemonEvents(limit int) ([]DaemonEvent, error) {
 	rows, err := db.conn.Query(
@@ -312,7 +488,7 @@ func (db *DB) GetRecentDaemonEvents(limit int) ([]DaemonEvent, error) {
 // GetLastTunnelEventPerAlias retrieves the most recent event for each tunnel alias
 func (db *DB) GetLastTunnelEventPerAlias() ([]TunnelEvent, error) {
 	rows, err := db.conn.Query(
-		`SELECT id, tunnel_alias, event_type, details, timestamp
+		`SELECT id, tunnel_alias, event_type, details, reason, timestamp
 		 FROM tunnel_events
 		 WHERE id IN (
 			 SELECT MAX(id)
@@ -329,7 +505,7 @@ func (db *DB) GetLastTunnelEventPerAlias() ([]TunnelEvent, error) {
 	var events []TunnelEvent
 	for rows.Next() {
 		var e TunnelEvent
-		if err := rows.Scan(&e.ID, &e.TunnelAlias, &e.EventType, &e.Details, &e.Timestamp); err != nil {
+		if err := rows.Scan(&e.ID, &e.TunnelAlias, &e.EventType, &e.Details, &e.Reason, &e.Timestamp); err != nil {
 			return nil, err
 		}
 		events = append(events, e)
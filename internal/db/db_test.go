@@ -1,8 +1,10 @@
 package db
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -127,6 +129,201 @@ func TestDB_LogTunnelEvent(t *testing.T) {
 	}
 }
 
+func TestDB_LogTunnelEventWithReason(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := db.LogTunnelEventWithReason("work-vpn", "connect", "PID: 123", "context:office"); err != nil {
+		t.Fatalf("Failed to log tunnel event: %v", err)
+	}
+	if err := db.LogTunnelEvent("work-vpn", "disconnect", ""); err != nil {
+		t.Fatalf("Failed to log tunnel event: %v", err)
+	}
+
+	events, err := db.GetRecentTunnelEvents(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reasons := make(map[string]string)
+	for _, e := range events {
+		reasons[e.EventType] = e.Reason
+	}
+	if reasons["connect"] != "context:office" {
+		t.Errorf("expected reason='context:office', got %q", reasons["connect"])
+	}
+	if reasons["disconnect"] != "" {
+		t.Errorf("expected empty reason, got %q", reasons["disconnect"])
+	}
+}
+
+func TestDB_MigrateTunnelEventsReason(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// Create a database with the tunnel_events schema from before reasons were recorded
+	conn, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = conn.Exec(`CREATE TABLE tunnel_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tunnel_alias TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		details TEXT,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO tunnel_events (tunnel_alias, event_type, details) VALUES ('vpn', 'connect', 'old');`)
+	conn.Close()
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.LogTunnelEventWithReason("vpn", "connect", "new", "manual"); err != nil {
+		t.Fatalf("Failed to log tunnel event after migration: %v", err)
+	}
+
+	events, err := db.GetRecentTunnelEvents(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	for _, e := range events {
+		want := map[string]string{"old": "", "new": "manual"}[e.Details]
+		if e.Reason != want {
+			t.Errorf("event %q: expected reason %q, got %q", e.Details, want, e.Reason)
+		}
+	}
+}
+
+func TestDB_GetTunnelEvents(t *testing.T) {
+	db := openTestDB(t)
+
+	base := time.Now().Add(-time.Hour)
+	insert := func(alias, eventType string, at time.Time) {
+		t.Helper()
+		_, err := db.conn.Exec(
+			`INSERT INTO tunnel_events (tunnel_alias, event_type, details, timestamp) VALUES (?, ?, '', ?)`,
+			alias, eventType, at,
+		)
+		if err != nil {
+			t.Fatalf("Failed to insert tunnel event: %v", err)
+		}
+	}
+	insert("vpn", "disconnect", base.Add(20*time.Minute))
+	insert("vpn", "connect", base)
+	insert("homelab", "connect", base.Add(5*time.Minute))
+	insert("vpn", "reconnect", base.Add(50*time.Minute))
+
+	got, err := db.GetTunnelEvents("vpn", base.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"connect", "disconnect"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(got))
+	}
+	for i, e := range got {
+		if e.TunnelAlias != "vpn" {
+			t.Errorf("event %d: expected alias 'vpn', got %q", i, e.TunnelAlias)
+		}
+		if e.EventType != want[i] {
+			t.Errorf("event %d: expected %q, got %q", i, want[i], e.EventType)
+		}
+	}
+}
+
+func TestDB_GetConnectionFailureCounts(t *testing.T) {
+	db := openTestDB(t)
+
+	base := time.Now().Add(-time.Hour)
+	insert := func(alias, eventType, reason string, at time.Time) {
+		t.Helper()
+		_, err := db.conn.Exec(
+			`INSERT INTO tunnel_events (tunnel_alias, event_type, details, reason, timestamp) VALUES (?, ?, '', ?, ?)`,
+			alias, eventType, reason, at,
+		)
+		if err != nil {
+			t.Fatalf("Failed to insert tunnel event: %v", err)
+		}
+	}
+	insert("vpn", "reconnect_failed", "connection timed out", base.Add(-time.Hour)) // Before the range
+	insert("vpn", "connect_failed", "authentication failed", base.Add(5*time.Minute))
+	insert("vpn", "reconnect_failed", "connection timed out", base.Add(10*time.Minute))
+	insert("vpn", "reconnect_failed", "connection timed out", base.Add(15*time.Minute))
+	insert("vpn", "disconnect", "reconnect", base.Add(15*time.Minute))
+	insert("homelab", "reconnect_failed", "no route to host", base.Add(20*time.Minute))
+	insert("homelab", "reconnect_failed", "no route to host", base.Add(2*time.Hour)) // After the range
+
+	end := base.Add(time.Hour)
+	got, err := db.GetConnectionFailureCounts("", base, end)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []FailureCount{
+		{Reason: "connection timed out", Count: 2},
+		{Reason: "authentication failed", Count: 1},
+		{Reason: "no route to host", Count: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("all tunnels: expected %+v, got %+v", want, got)
+	}
+
+	got, err = db.GetConnectionFailureCounts("homelab", base, end)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []FailureCount{{Reason: "no route to host", Count: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("homelab: expected %+v, got %+v", want, got)
+	}
+}
+
+func TestDB_ContextChanges(t *testing.T) {
+	db := openTestDB(t)
+
+	before := time.Now().Add(-time.Second)
+	if err := db.LogContextChange("untrusted", "home", "", "home", "sensor_change"); err != nil {
+		t.Fatalf("Failed to log context change: %v", err)
+	}
+	if err := db.LogContextChange("home", "office", "home", "office", "manual"); err != nil {
+		t.Fatalf("Failed to log context change: %v", err)
+	}
+	after := time.Now().Add(time.Second)
+
+	got, err := db.GetContextChanges(before, after)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 context changes, got %d", len(got))
+	}
+
+	// Chronological order, all fields round-trip
+	first := got[0]
+	if first.FromContext != "untrusted" || first.ToContext != "home" ||
+		first.FromLocation != "" || first.ToLocation != "home" || first.Trigger != "sensor_change" {
+		t.Errorf("unexpected first change: %+v", first)
+	}
+	if got[1].ToContext != "office" || got[1].Trigger != "manual" {
+		t.Errorf("unexpected second change: %+v", got[1])
+	}
+
+	// Nothing outside the range
+	got, err = db.GetContextChanges(after, after.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no context changes after range, got %d", len(got))
+	}
+}
+
 func TestDB_LogDaemonEvent(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -248,6 +445,7 @@ func TestDB_TablesCreated(t *testing.T) {
 		"sensor_changes",
 		"tunnel_events",
 		"daemon_events",
+		"context_changes",
 	}
 
 	for _, tableName := range expectedTables {
@@ -284,6 +482,7 @@ func TestDB_Indexes(t *testing.T) {
 		"idx_tunnel_events_timestamp",
 		"idx_tunnel_events_alias",
 		"idx_daemon_events_timestamp",
+		"idx_context_changes_timestamp",
 	}
 
 	for _, indexName := range expectedIndexes {
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 )
 
 // generateToken generates a random authentication token
@@ -53,3 +55,33 @@ func ConfigureSSHAskpass(cmd *exec.Cmd, alias string) (string, error) {
 
 	return token, nil
 }
+
+// ConfigureCustomAskpass configures an SSH command to use the given program as
+// askpass helper instead of the overseer binary, e.g. a GUI prompt. The program
+// doesn't call back into the daemon, so no token is involved.
+func ConfigureCustomAskpass(cmd *exec.Cmd, program string) error {
+	info, err := os.Stat(program)
+	if err != nil {
+		return fmt.Errorf("askpass helper: %w", err)
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("askpass helper %s is not an executable file", program)
+	}
+
+	cmd.Env = append(cmd.Env, fmt.Sprintf("SSH_ASKPASS=%s", program))
+	cmd.Env = append(cmd.Env, "SSH_ASKPASS_REQUIRE=force")
+
+	// Keep the user's display for GUI prompts, older OpenSSH versions only
+	// need DISPLAY to be set at all
+	hasDisplay := slices.ContainsFunc(cmd.Env, func(kv string) bool {
+		return strings.HasPrefix(kv, "DISPLAY=")
+	})
+	if !hasDisplay {
+		cmd.Env = append(cmd.Env, "DISPLAY=:0")
+	}
+
+	// Detach from terminal by setting stdin to /dev/null
+	cmd.Stdin = nil
+
+	return nil
+}
@@ -3,6 +3,7 @@ package keyring
 import (
 	"fmt"
 	"os"
+	"strings"
 	"syscall"
 
 	"golang.org/x/term"
@@ -22,6 +23,25 @@ func PromptPassword(alias string) (string, error) {
 	return string(passwordBytes), nil
 }
 
+// PromptOTP prompts the user to enter a one-time password securely (no echo)
+func PromptOTP(alias string) (string, error) {
+	fmt.Fprintf(os.Stderr, "Enter one-time password for '%s': ", alias)
+
+	otpBytes, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr) // Print newline after input
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read one-time password: %w", err)
+	}
+
+	otp := strings.TrimSpace(string(otpBytes))
+	if otp == "" || strings.ContainsAny(otp, " \t") {
+		return "", fmt.Errorf("invalid one-time password")
+	}
+
+	return otp, nil
+}
+
 // PromptAndConfirmPassword prompts for a password twice and confirms they match
 func PromptAndConfirmPassword(alias string) (string, error) {
 	password1, err := PromptPassword(alias)